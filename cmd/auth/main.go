@@ -1,13 +1,24 @@
 package main
 
 import (
+	"auth/internal/bots"
 	"auth/internal/config"
 	"auth/internal/db"
+	"auth/internal/jobs"
+	"auth/internal/notifications"
+	"auth/internal/quests"
 	"auth/internal/repository"
 	"auth/internal/rest/handlers"
+	"auth/internal/rest/models"
 	"auth/internal/rest/routers"
+	"auth/internal/shop"
 	"auth/pkg/logger"
+	"auth/pkg/middleware"
+	"auth/pkg/oauth"
+	"auth/pkg/payments"
+	"auth/pkg/utils"
 	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"log"
@@ -15,6 +26,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -62,17 +74,300 @@ func initializeEmail() config.EmailConfig {
 	return emailConfig
 }
 
+func initializeOIDC() []config.OIDCProvider {
+	var providers []config.OIDCProvider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, config.OIDCProvider{
+			Name:         name,
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			AuthURL:      os.Getenv(prefix + "AUTH_URL"),
+			TokenURL:     os.Getenv(prefix + "TOKEN_URL"),
+			UserInfoURL:  os.Getenv(prefix + "USERINFO_URL"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		})
+	}
+	return providers
+}
+
+func initializeJWT() config.JWTConfig {
+	jwtConfig := config.JWTConfig{
+		Issuer:      os.Getenv("JWT_ISSUER"),
+		Audience:    os.Getenv("JWT_AUDIENCE"),
+		ActiveKeyID: os.Getenv("JWT_ACTIVE_KID"),
+	}
+	jwtConfig.AllowEphemeralKey, _ = strconv.ParseBool(os.Getenv("JWT_ALLOW_EPHEMERAL_KEY"))
+	for _, entry := range strings.Split(os.Getenv("JWT_KEYS"), ",") {
+		id, path, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			continue
+		}
+		jwtConfig.Keys = append(jwtConfig.Keys, config.JWTKey{ID: id, Path: path})
+	}
+	return jwtConfig
+}
+
+// loadTokenKeys reads the configured JWT keys. Without any, it only starts
+// when JWT_ALLOW_EPHEMERAL_KEY allows signing with a key generated at startup.
+func loadTokenKeys(jwtConfig config.JWTConfig) (*utils.TokenKeySet, error) {
+	if len(jwtConfig.Keys) == 0 {
+		if !jwtConfig.AllowEphemeralKey {
+			return nil, fmt.Errorf("no JWT keys configured, set JWT_KEYS or JWT_ALLOW_EPHEMERAL_KEY=true for development")
+		}
+		logger.GetLogger().Warn("No JWT keys configured, using an ephemeral signing key")
+		return utils.NewEphemeralTokenKeySet(jwtConfig.Issuer, jwtConfig.Audience)
+	}
+
+	keys := make([]utils.TokenKey, 0, len(jwtConfig.Keys))
+	for _, key := range jwtConfig.Keys {
+		data, err := os.ReadFile(key.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT key %q: %v", key.ID, err)
+		}
+		keys = append(keys, utils.TokenKey{ID: key.ID, PEM: data})
+	}
+	return utils.NewTokenKeySet(jwtConfig.Issuer, jwtConfig.Audience, jwtConfig.ActiveKeyID, keys)
+}
+
+func initializeAccount() config.AccountConfig {
+	graceDays, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"))
+	if err != nil {
+		graceDays = 30
+	}
+	return config.AccountConfig{
+		DeletionGrace: time.Duration(graceDays) * 24 * time.Hour,
+		PurgeInterval: time.Hour,
+	}
+}
+
+func initializeMatch() config.MatchConfig {
+	ttlMinutes, err := strconv.Atoi(os.Getenv("CHALLENGE_TTL_MINUTES"))
+	if err != nil {
+		ttlMinutes = 10
+	}
+	return config.MatchConfig{
+		ChallengeTTL:   time.Duration(ttlMinutes) * time.Minute,
+		ExpiryInterval: time.Minute,
+	}
+}
+
+func initializeClan() config.ClanConfig {
+	cooldownHours, err := strconv.Atoi(os.Getenv("CLAN_REQUEST_COOLDOWN_HOURS"))
+	if err != nil {
+		cooldownHours = 7
+	}
+	return config.ClanConfig{
+		MaxMembers:      50,
+		RequestCooldown: time.Duration(cooldownHours) * time.Hour,
+		RequestSize:     5,
+		DonationGold: map[string]int64{
+			"Common":    5,
+			"Rare":      50,
+			"Epic":      500,
+			"Legendary": 1000,
+		},
+		DefaultDonationGold: 5,
+	}
+}
+
+func initializeChat() config.ChatConfig {
+	var bannedWords []string
+	for _, word := range strings.Split(os.Getenv("CHAT_BANNED_WORDS"), ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			bannedWords = append(bannedWords, word)
+		}
+	}
+	return config.ChatConfig{
+		MaxMessageLength: 500,
+		HistoryPageSize:  50,
+		BannedWords:      bannedWords,
+	}
+}
+
+func initializeNotification() config.NotificationConfig {
+	digestHours, err := strconv.Atoi(os.Getenv("NOTIFICATION_DIGEST_HOURS"))
+	if err != nil {
+		digestHours = 24
+	}
+	return config.NotificationConfig{
+		DigestInterval: time.Duration(digestHours) * time.Hour,
+		DigestCheck:    time.Hour,
+		DigestSize:     20,
+	}
+}
+
+func initializeQuest() config.QuestConfig {
+	resetHour, err := strconv.Atoi(os.Getenv("QUEST_RESET_HOUR"))
+	if err != nil || resetHour < 0 || resetHour > 23 {
+		resetHour = 0
+	}
+	dailyCount, err := strconv.Atoi(os.Getenv("QUEST_DAILY_COUNT"))
+	if err != nil || dailyCount <= 0 {
+		dailyCount = 3
+	}
+	return config.QuestConfig{
+		ResetOffset: time.Duration(resetHour) * time.Hour,
+		DailyCount:  dailyCount,
+	}
+}
+
+func initializeShop() config.ShopConfig {
+	resetHour, err := strconv.Atoi(os.Getenv("SHOP_RESET_HOUR"))
+	if err != nil || resetHour < 0 || resetHour > 23 {
+		resetHour = 0
+	}
+	dailyOffers, err := strconv.Atoi(os.Getenv("SHOP_DAILY_OFFERS"))
+	if err != nil || dailyOffers <= 0 {
+		dailyOffers = 4
+	}
+	discount, err := strconv.Atoi(os.Getenv("SHOP_DAILY_DISCOUNT_PERCENT"))
+	if err != nil || discount < 0 || discount > 100 {
+		discount = 20
+	}
+	return config.ShopConfig{
+		ResetOffset:   time.Duration(resetHour) * time.Hour,
+		DailyOffers:   dailyOffers,
+		DailyDiscount: discount,
+	}
+}
+
+func initializeWallet() config.WalletConfig {
+	goldPerGem, err := strconv.ParseInt(os.Getenv("GEMS_GOLD_RATE"), 10, 64)
+	if err != nil || goldPerGem <= 0 {
+		goldPerGem = 20
+	}
+	return config.WalletConfig{
+		GoldPerGem: goldPerGem,
+	}
+}
+
+func initializeChest() config.ChestConfig {
+	gemsPerHour, err := strconv.ParseInt(os.Getenv("CHEST_SPEEDUP_GEMS_PER_HOUR"), 10, 64)
+	if err != nil || gemsPerHour < 0 {
+		gemsPerHour = 6
+	}
+	return config.ChestConfig{
+		SpeedUpGemsPerHour: gemsPerHour,
+	}
+}
+
+func initializePayment() config.PaymentConfig {
+	paymentConfig := config.PaymentConfig{
+		Products:   make(map[string]int64),
+		FakeSecret: os.Getenv("IAP_FAKE_SECRET"),
+	}
+	for _, entry := range strings.Split(os.Getenv("IAP_PRODUCTS"), ",") {
+		productID, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			continue
+		}
+		gems, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || gems <= 0 {
+			continue
+		}
+		paymentConfig.Products[productID] = gems
+	}
+	return paymentConfig
+}
+
+func initializeSeason() config.SeasonConfig {
+	return config.SeasonConfig{
+		CloseCheck: time.Minute,
+	}
+}
+
+func initializeTournament() config.TournamentConfig {
+	roundMinutes, err := strconv.Atoi(os.Getenv("TOURNAMENT_ROUND_MINUTES"))
+	if err != nil || roundMinutes <= 0 {
+		roundMinutes = 30
+	}
+	maxPlayers, err := strconv.Atoi(os.Getenv("TOURNAMENT_MAX_PLAYERS"))
+	if err != nil || maxPlayers < 2 {
+		maxPlayers = 64
+	}
+	return config.TournamentConfig{
+		Check:        time.Minute,
+		RoundMinutes: roundMinutes,
+		MaxPlayers:   maxPlayers,
+	}
+}
+
+func initializeDraft() config.DraftConfig {
+	deckSize, err := strconv.Atoi(os.Getenv("DRAFT_DECK_SIZE"))
+	if err != nil || deckSize <= 0 {
+		deckSize = 8
+	}
+	pickSeconds, err := strconv.Atoi(os.Getenv("DRAFT_PICK_SECONDS"))
+	if err != nil || pickSeconds <= 0 {
+		pickSeconds = 30
+	}
+	return config.DraftConfig{
+		Check:       5 * time.Second,
+		DeckSize:    deckSize,
+		PickSeconds: pickSeconds,
+	}
+}
+
+func initializeBot() config.BotConfig {
+	timeoutSeconds, err := strconv.Atoi(os.Getenv("BOT_QUEUE_TIMEOUT_SECONDS"))
+	if err != nil || timeoutSeconds <= 0 {
+		timeoutSeconds = 60
+	}
+	difficulty := os.Getenv("BOT_QUEUE_DIFFICULTY")
+	if !bots.ValidDifficulty(difficulty) {
+		difficulty = models.BotNormal
+	}
+	deckSize, err := strconv.Atoi(os.Getenv("BOT_DECK_SIZE"))
+	if err != nil || deckSize <= 0 {
+		deckSize = 8
+	}
+	return config.BotConfig{
+		Check:           10 * time.Second,
+		QueueTimeout:    time.Duration(timeoutSeconds) * time.Second,
+		QueueDifficulty: difficulty,
+		DeckSize:        deckSize,
+	}
+}
+
 var appConfig config.App
 
 func main() {
 	logger.InitLogger()
 
 	appConfig = config.App{
-		PORT:  os.Getenv("APP_PORT"),
-		DB:    initializeDB(),
-		Redis: initializeRedis(),
-		Email: initializeEmail(),
+		PORT:         os.Getenv("APP_PORT"),
+		DB:           initializeDB(),
+		Redis:        initializeRedis(),
+		Email:        initializeEmail(),
+		OIDC:         initializeOIDC(),
+		JWT:          initializeJWT(),
+		Account:      initializeAccount(),
+		Match:        initializeMatch(),
+		Clan:         initializeClan(),
+		Chat:         initializeChat(),
+		Notification: initializeNotification(),
+		Quest:        initializeQuest(),
+		Season:       initializeSeason(),
+		Shop:         initializeShop(),
+		Wallet:       initializeWallet(),
+		Payment:      initializePayment(),
+		Tournament:   initializeTournament(),
+		Draft:        initializeDraft(),
+		Bot:          initializeBot(),
+		Chest:        initializeChest(),
+	}
+
+	tokenKeys, err := loadTokenKeys(appConfig.JWT)
+	if err != nil {
+		logger.GetLogger().Fatal("Error loading JWT keys:", err)
 	}
+	utils.InitTokens(tokenKeys)
 
 	db, err := db.GetDBInstance(appConfig.DB)
 	if err != nil {
@@ -81,11 +376,76 @@ func main() {
 
 	userRepo := repository.NewUserRepository(db)
 	gameRepo := repository.NewGameRepository(db)
-	authHandlers := handlers.NewAuthHandlers(userRepo, appConfig.Redis, appConfig.Email)
-	var gameHandlers = handlers.NewGameHandlers(userRepo, *gameRepo)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+
+	var providers []oauth.Provider
+	for _, providerConfig := range appConfig.OIDC {
+		providers = append(providers, oauth.NewOIDCProvider(providerConfig))
+	}
+
+	authHandlers := handlers.NewAuthHandlers(userRepo, twoFactorRepo, identityRepo, providers, appConfig.Redis, appConfig.Email, appConfig.Account, utils.SystemClock{})
+	notificationRepo := repository.NewNotificationRepository(db)
+	notifier := notifications.NewService(notificationRepo, appConfig.Redis)
+	notificationHandlers := handlers.NewNotificationHandlers(notificationRepo, notifier, utils.SystemClock{})
+	questRepo := repository.NewQuestRepository(db)
+	questEngine := quests.NewEngine(questRepo, appConfig.Quest, utils.SystemClock{}, notifier)
+	questHandlers := handlers.NewQuestHandlers(questRepo, gameRepo, appConfig.Quest, utils.SystemClock{})
+	arenaRepo := repository.NewArenaRepository(db)
+	arenaHandlers := handlers.NewArenaHandlers(arenaRepo, gameRepo, userRepo)
+	var gameHandlers = handlers.NewGameHandlers(userRepo, *gameRepo, arenaRepo, notifier, questEngine)
+	playerRepo := repository.NewPlayerRepository(db)
+	playerHandlers := handlers.NewPlayerHandlers(userRepo, gameRepo, playerRepo)
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandlers := handlers.NewFriendHandlers(userRepo, friendRepo, appConfig.Redis, notifier)
+	matchRepo := repository.NewMatchRepository(db)
+	matchHandlers := handlers.NewMatchHandlers(userRepo, gameRepo, friendRepo, matchRepo, playerRepo, appConfig.Match, utils.SystemClock{}, notifier, questEngine)
+	clanRepo := repository.NewClanRepository(db)
+	clanHandlers := handlers.NewClanHandlers(clanRepo, gameRepo, userRepo, appConfig.Clan, utils.SystemClock{}, notifier)
+	chatRepo := repository.NewChatRepository(db)
+	chatHandlers := handlers.NewChatHandlers(clanRepo, friendRepo, chatRepo, appConfig.Chat, appConfig.Redis, utils.SystemClock{})
+	moderationHandlers := handlers.NewModerationHandlers(userRepo, chatRepo, appConfig.Redis, utils.SystemClock{})
+	seasonRepo := repository.NewSeasonRepository(db)
+	shopRepo := repository.NewShopRepository(db)
+	shopService := shop.NewService(shopRepo, appConfig.Shop, utils.SystemClock{}, notifier, questEngine)
+	shopHandlers := handlers.NewShopHandlers(shopService, shopRepo, gameRepo, userRepo)
+	walletRepo := repository.NewWalletRepository(db)
+	walletHandlers := handlers.NewWalletHandlers(walletRepo, userRepo, appConfig.Wallet)
+	chestRepo := repository.NewChestRepository(db)
+	chestHandlers := handlers.NewChestHandlers(chestRepo, appConfig.Chest, utils.SystemClock{})
+	var stores []payments.Provider
+	if appConfig.Payment.FakeSecret != "" {
+		stores = append(stores, payments.NewFakeProvider(appConfig.Payment.FakeSecret))
+	}
+	paymentRepo := repository.NewPaymentRepository(db)
+	paymentHandlers := handlers.NewPaymentHandlers(stores, paymentRepo, walletRepo, appConfig.Payment, utils.SystemClock{}, notifier)
+	promoRepo := repository.NewPromoRepository(db)
+	promoHandlers := handlers.NewPromoHandlers(promoRepo, gameRepo, utils.SystemClock{})
+	tournamentRepo := repository.NewTournamentRepository(db)
+	tournamentHandlers := handlers.NewTournamentHandlers(tournamentRepo, gameRepo, appConfig.Tournament, utils.SystemClock{}, notifier)
+	partyRepo := repository.NewPartyRepository(db)
+	partyHandlers := handlers.NewPartyHandlers(partyRepo, userRepo, gameRepo, friendRepo, notifier)
+	draftRepo := repository.NewDraftRepository(db)
+	draftHandlers := handlers.NewDraftHandlers(draftRepo, appConfig.Draft, appConfig.Redis, utils.SystemClock{}, notifier)
+	replayRepo := repository.NewReplayRepository(db)
+	replayHandlers := handlers.NewReplayHandlers(replayRepo, matchRepo, utils.SystemClock{})
+	botHandlers := handlers.NewBotHandlers(matchRepo, gameRepo, appConfig.Bot, utils.SystemClock{})
+	seasonHandlers := handlers.NewSeasonHandlers(seasonRepo, userRepo, utils.SystemClock{})
+	accountHandlers := handlers.NewAccountHandlers(userRepo, gameRepo, identityRepo, walletRepo, paymentRepo, appConfig.Account, utils.SystemClock{})
+
+	go jobs.RunAccountPurge(context.Background(), userRepo, appConfig.Account, utils.SystemClock{})
+	go jobs.RunChallengeExpiry(context.Background(), matchRepo, appConfig.Match, utils.SystemClock{})
+	go jobs.RunSeasonClose(context.Background(), seasonRepo, appConfig.Season, notifier, utils.SystemClock{})
+	go jobs.RunTournaments(context.Background(), tournamentRepo, appConfig.Tournament, notifier, utils.SystemClock{})
+	go jobs.RunBotFill(context.Background(), partyRepo, gameRepo, appConfig.Bot, notifier, utils.SystemClock{})
+	go jobs.RunDraftPicks(context.Background(), draftRepo, appConfig.Draft, appConfig.Redis, notifier, utils.SystemClock{})
+	if appConfig.Email.From != "" {
+		go jobs.RunNotificationDigest(context.Background(), notificationRepo, appConfig.Notification, appConfig.Email, utils.SystemClock{})
+	}
 
 	r := gin.Default()
-	router := routers.NewRouters(*authHandlers, *gameHandlers)
+	r.Use(middleware.TrackPresence(appConfig.Redis))
+	router := routers.NewRouters(*authHandlers, *gameHandlers, *accountHandlers, *playerHandlers, *friendHandlers, *matchHandlers, *clanHandlers, *chatHandlers, *moderationHandlers, *notificationHandlers, *questHandlers, *seasonHandlers, *shopHandlers, *walletHandlers, *chestHandlers, *paymentHandlers, *promoHandlers, *arenaHandlers, *tournamentHandlers, *partyHandlers, *draftHandlers, *botHandlers, *replayHandlers, userRepo)
 	router.SetupRoutes(r)
 	r.Use(rateLimitMiddleware())
 
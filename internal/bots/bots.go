@@ -0,0 +1,261 @@
+// Package bots plays matches in place of a player. The game server runs the
+// battle and asks for the bot's next move the same way it takes a player's:
+// which card to deploy, in which lane and how far up it. Everything here is
+// decided from the board the game server describes, so the package holds no
+// state of its own.
+package bots
+
+import (
+	"auth/internal/rest/models"
+	"math/rand"
+)
+
+// Lanes is how many lanes the arena has.
+const Lanes = 2
+
+// DefaultSpellElixir is what a spell costs when the board doesn't say:
+// catalog spells have a gold price but no elixir cost.
+const DefaultSpellElixir = 3
+
+// Board is the battle as the bot sees it. Positions run along a lane from 0
+// at the bot's own towers to 1 at the opponent's.
+type Board struct {
+	Elixir int32
+	// Heroes and Spells are the cards in the bot's hand.
+	Heroes []models.Hero
+	Spells []models.Spell
+	// SpellElixir is the elixir cost of each spell in hand by id.
+	SpellElixir map[uint]int32
+	Units       []Unit
+	Towers      []Tower
+}
+
+// Unit is a hero on the board. Enemy units belong to the bot's opponents.
+type Unit struct {
+	Enemy    bool
+	Lane     int
+	Position float64
+	Hitpoint int32
+	Damage   int32
+}
+
+type Tower struct {
+	Enemy    bool
+	Lane     int
+	Hitpoint int32
+}
+
+// Play is a card to deploy: Kind is models.DraftCardHero or
+// models.DraftCardSpell, like a player's pick.
+type Play struct {
+	Kind     string
+	CardID   uint
+	Lane     int
+	Position float64
+}
+
+// profile is how a difficulty plays.
+type profile struct {
+	// attackElixir is the elixir the bot saves up before it attacks.
+	attackElixir int32
+	// mistakes is the chance of playing a random affordable card anywhere.
+	mistakes float64
+	// defends makes the bot answer units in its half of the board.
+	defends bool
+	// spells makes the bot aim spells at groups of enemy units.
+	spells bool
+	// focus makes the bot attack the weakest enemy tower rather than a
+	// random lane.
+	focus bool
+}
+
+var profiles = map[string]profile{
+	models.BotEasy:   {attackElixir: 9, mistakes: 0.4},
+	models.BotNormal: {attackElixir: 7, mistakes: 0.15, defends: true},
+	models.BotHard:   {attackElixir: 6, defends: true, spells: true, focus: true},
+}
+
+// ValidDifficulty reports whether bots can play at the difficulty.
+func ValidDifficulty(difficulty string) bool {
+	_, ok := profiles[difficulty]
+	return ok
+}
+
+// clusterRange is how close enemy units must be for one spell to hit them all.
+const clusterRange = 0.1
+
+// Decide picks the bot's next move, or reports false to wait for more
+// elixir. rng drives the mistakes and random choices of the easier bots.
+func Decide(board Board, difficulty string, rng *rand.Rand) (Play, bool) {
+	bot, ok := profiles[difficulty]
+	if !ok {
+		bot = profiles[models.BotNormal]
+	}
+
+	heroes, spells := affordable(board)
+	if len(heroes)+len(spells) == 0 {
+		return Play{}, false
+	}
+
+	if rng.Float64() < bot.mistakes {
+		lane, position := rng.Intn(Lanes), 0.1+rng.Float64()*0.4
+		pick := rng.Intn(len(heroes) + len(spells))
+		if pick < len(heroes) {
+			return Play{Kind: models.DraftCardHero, CardID: heroes[pick].ID, Lane: lane, Position: position}, true
+		}
+		return Play{Kind: models.DraftCardSpell, CardID: spells[pick-len(heroes)].ID, Lane: lane, Position: position}, true
+	}
+
+	if bot.defends {
+		if lane, front, threat := threatened(board); threat > 0 {
+			if bot.spells && len(spells) > 0 {
+				if target, hit := cluster(board, lane); hit >= 2 {
+					spell := strongest(spells)
+					return Play{Kind: models.DraftCardSpell, CardID: spell.ID, Lane: lane, Position: target}, true
+				}
+			}
+			if len(heroes) > 0 {
+				hero := best(heroes, defenceValue)
+				position := front - 0.1
+				if position < 0.05 {
+					position = 0.05
+				}
+				return Play{Kind: models.DraftCardHero, CardID: hero.ID, Lane: lane, Position: position}, true
+			}
+		}
+	}
+
+	if board.Elixir < bot.attackElixir || len(heroes) == 0 {
+		return Play{}, false
+	}
+	lane := rng.Intn(Lanes)
+	if bot.focus {
+		lane = weakestTower(board)
+	}
+	hero := best(heroes, attackValue)
+	return Play{Kind: models.DraftCardHero, CardID: hero.ID, Lane: lane, Position: 0.45}, true
+}
+
+func spellElixir(board Board, spell models.Spell) int32 {
+	if cost, ok := board.SpellElixir[spell.ID]; ok {
+		return cost
+	}
+	return DefaultSpellElixir
+}
+
+func affordable(board Board) ([]models.Hero, []models.Spell) {
+	var heroes []models.Hero
+	for _, hero := range board.Heroes {
+		if hero.CostElixir <= board.Elixir {
+			heroes = append(heroes, hero)
+		}
+	}
+	var spells []models.Spell
+	for _, spell := range board.Spells {
+		if spellElixir(board, spell) <= board.Elixir {
+			spells = append(spells, spell)
+		}
+	}
+	return heroes, spells
+}
+
+// threatened finds the lane where enemy units in the bot's half do the most
+// damage, weighting the ones closest to its towers, and how far the leading
+// one has come.
+func threatened(board Board) (int, float64, float64) {
+	var threats [Lanes]float64
+	var fronts [Lanes]float64
+	for lane := range fronts {
+		fronts[lane] = 1
+	}
+	for _, unit := range board.Units {
+		if !unit.Enemy || unit.Position >= 0.5 || unit.Lane < 0 || unit.Lane >= Lanes {
+			continue
+		}
+		threats[unit.Lane] += float64(unit.Damage) * (1 - unit.Position)
+		if unit.Position < fronts[unit.Lane] {
+			fronts[unit.Lane] = unit.Position
+		}
+	}
+	lane := 0
+	for i := range threats {
+		if threats[i] > threats[lane] {
+			lane = i
+		}
+	}
+	return lane, fronts[lane], threats[lane]
+}
+
+// cluster finds the spot in the lane where a spell hits the most enemy
+// units, and how many it hits.
+func cluster(board Board, lane int) (float64, int) {
+	var target float64
+	hit := 0
+	for _, center := range board.Units {
+		if !center.Enemy || center.Lane != lane {
+			continue
+		}
+		count := 0
+		for _, unit := range board.Units {
+			if unit.Enemy && unit.Lane == lane && unit.Position >= center.Position-clusterRange && unit.Position <= center.Position+clusterRange {
+				count++
+			}
+		}
+		if count > hit {
+			target, hit = center.Position, count
+		}
+	}
+	return target, hit
+}
+
+func weakestTower(board Board) int {
+	lane, weakest := 0, int32(-1)
+	for _, tower := range board.Towers {
+		if !tower.Enemy || tower.Hitpoint <= 0 || tower.Lane < 0 || tower.Lane >= Lanes {
+			continue
+		}
+		if weakest == -1 || tower.Hitpoint < weakest {
+			lane, weakest = tower.Lane, tower.Hitpoint
+		}
+	}
+	return lane
+}
+
+func perElixir(value float64, cost int32) float64 {
+	if cost < 1 {
+		cost = 1
+	}
+	return value / float64(cost)
+}
+
+func defenceValue(hero models.Hero) float64 {
+	return perElixir(float64(hero.Damage)+float64(hero.Hitpoint)/10, hero.CostElixir)
+}
+
+func attackValue(hero models.Hero) float64 {
+	return perElixir(float64(hero.DamageTower)+float64(hero.Hitpoint)/10+float64(hero.Speed), hero.CostElixir)
+}
+
+func best(heroes []models.Hero, value func(models.Hero) float64) models.Hero {
+	best := heroes[0]
+	for _, hero := range heroes[1:] {
+		if value(hero) > value(best) {
+			best = hero
+		}
+	}
+	return best
+}
+
+func spellValue(spell models.Spell) float64 {
+	return float64(spell.Damage) * float64(1+spell.Area)
+}
+
+func strongest(spells []models.Spell) models.Spell {
+	best := spells[0]
+	for _, spell := range spells[1:] {
+		if spellValue(spell) > spellValue(best) {
+			best = spell
+		}
+	}
+	return best
+}
@@ -0,0 +1,202 @@
+package bots
+
+import (
+	"auth/internal/rest/models"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// Archers defend best per elixir, giants attack best and the fireball is the
+// strongest spell.
+var (
+	knight = models.Hero{ID: 1, Hitpoint: 1000, Damage: 100, DamageTower: 50, CostElixir: 3, Speed: 5}
+	giant  = models.Hero{ID: 2, Hitpoint: 3000, Damage: 50, DamageTower: 300, CostElixir: 5, Speed: 3}
+	archer = models.Hero{ID: 3, Hitpoint: 300, Damage: 120, DamageTower: 40, CostElixir: 2, Speed: 6}
+
+	zap      = models.Spell{ID: 11, Damage: 100, Area: 2}
+	fireball = models.Spell{ID: 12, Damage: 300, Area: 3}
+)
+
+// board is a hand of every test card with elixir to spend. The fireball
+// costs 4, the zap the default 3.
+func board(elixir int32, units ...Unit) Board {
+	return Board{
+		Elixir:      elixir,
+		Heroes:      []models.Hero{knight, giant, archer},
+		Spells:      []models.Spell{zap, fireball},
+		SpellElixir: map[uint]int32{fireball.ID: 4},
+		Units:       units,
+	}
+}
+
+func enemy(lane int, position float64, damage int32) Unit {
+	return Unit{Enemy: true, Lane: lane, Position: position, Hitpoint: 500, Damage: damage}
+}
+
+// pushed has two enemy units close together in lane 1 and a weaker one in
+// lane 0, all in the bot's half.
+var pushed = []Unit{enemy(1, 0.3, 100), enemy(1, 0.35, 100), enemy(0, 0.45, 50)}
+
+func samePlay(a, b Play) bool {
+	return a.Kind == b.Kind && a.CardID == b.CardID && a.Lane == b.Lane && math.Abs(a.Position-b.Position) < 1e-9
+}
+
+func hero(card models.Hero, lane int, position float64) Play {
+	return Play{Kind: models.DraftCardHero, CardID: card.ID, Lane: lane, Position: position}
+}
+
+func spell(card models.Spell, lane int, position float64) Play {
+	return Play{Kind: models.DraftCardSpell, CardID: card.ID, Lane: lane, Position: position}
+}
+
+// Seed 1 makes no mistakes at any difficulty and picks lane 1 when the lane
+// is random.
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name       string
+		difficulty string
+		board      Board
+		want       Play
+		wantOK     bool
+	}{
+		{"easy ignores threats and attacks", models.BotEasy, board(10, pushed...), hero(giant, 1, 0.45), true},
+		{"normal defends with a hero, not a spell", models.BotNormal, board(10, pushed...), hero(archer, 1, 0.2), true},
+		{"hard aims a spell at a cluster", models.BotHard, board(10, pushed...), spell(fireball, 1, 0.3), true},
+		{"hard casts the strongest spell it can afford", models.BotHard, board(3, pushed...), spell(zap, 1, 0.3), true},
+		{"hard defends with a hero when spells cost too much", models.BotHard, board(2, pushed...), hero(archer, 1, 0.2), true},
+		{"hard defends a lone unit with a hero", models.BotHard, board(10, enemy(0, 0.45, 50)), hero(archer, 0, 0.35), true},
+		{"defence stays off the towers", models.BotHard, board(10, enemy(0, 0.1, 50)), hero(archer, 0, 0.05), true},
+		{"units past the middle are no threat", models.BotNormal, board(6, enemy(0, 0.6, 500)), Play{}, false},
+		{"own units are no threat", models.BotNormal, board(6, Unit{Lane: 0, Position: 0.2, Damage: 500}), Play{}, false},
+		{"easy saves below 9 elixir", models.BotEasy, board(8), Play{}, false},
+		{"easy attacks at 9 elixir", models.BotEasy, board(9), hero(giant, 1, 0.45), true},
+		{"normal saves below 7 elixir", models.BotNormal, board(6), Play{}, false},
+		{"normal attacks at 7 elixir", models.BotNormal, board(7), hero(giant, 1, 0.45), true},
+		{"hard saves below 6 elixir", models.BotHard, board(5), Play{}, false},
+		{"nothing affordable", models.BotHard, board(1, pushed...), Play{}, false},
+		{"unknown difficulty plays like normal", "IMPOSSIBLE", board(10, pushed...), hero(archer, 1, 0.2), true},
+	}
+	for _, tt := range tests {
+		got, ok := Decide(tt.board, tt.difficulty, rand.New(rand.NewSource(1)))
+		if ok != tt.wantOK || !samePlay(got, tt.want) {
+			t.Errorf("%s: Decide = %+v, %t, want %+v, %t", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestDecideHardAttacksWeakestTower(t *testing.T) {
+	tests := []struct {
+		name   string
+		towers []Tower
+		want   int
+	}{
+		{"weakest enemy tower", []Tower{{Enemy: true, Lane: 0, Hitpoint: 500}, {Enemy: true, Lane: 1, Hitpoint: 2000}}, 0},
+		{"own towers don't count", []Tower{{Lane: 1, Hitpoint: 100}, {Enemy: true, Lane: 0, Hitpoint: 2000}, {Enemy: true, Lane: 1, Hitpoint: 500}}, 1},
+		{"fallen towers don't count", []Tower{{Enemy: true, Lane: 0, Hitpoint: 2000}, {Enemy: true, Lane: 1, Hitpoint: 0}}, 0},
+	}
+	for _, tt := range tests {
+		b := board(6)
+		b.Towers = tt.towers
+		got, ok := Decide(b, models.BotHard, rand.New(rand.NewSource(1)))
+		if !ok || !samePlay(got, hero(giant, tt.want, 0.45)) {
+			t.Errorf("%s: Decide = %+v, %t, want the giant in lane %d", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+// Seed 9 rolls a mistake for the difficulties that make them.
+func TestDecideMistakes(t *testing.T) {
+	for _, difficulty := range []string{models.BotEasy, models.BotNormal} {
+		got, ok := Decide(board(5), difficulty, rand.New(rand.NewSource(9)))
+		if !ok {
+			t.Errorf("%s: saved elixir instead of making a mistake", difficulty)
+			continue
+		}
+		if got.Lane < 0 || got.Lane >= Lanes || got.Position < 0.1 || got.Position >= 0.5 {
+			t.Errorf("%s: mistake %+v is off the bot's half", difficulty, got)
+		}
+		if again, _ := Decide(board(5), difficulty, rand.New(rand.NewSource(9))); again != got {
+			t.Errorf("%s: the same seed played %+v then %+v", difficulty, got, again)
+		}
+	}
+
+	if got, ok := Decide(board(5), models.BotHard, rand.New(rand.NewSource(9))); ok {
+		t.Errorf("hard made a mistake: %+v", got)
+	}
+}
+
+// catalog has heroes 1 to 8 and spells 11 to 14, each better than the one
+// before.
+func catalog() ([]models.Hero, []models.Spell) {
+	var heroes []models.Hero
+	for i := int32(1); i <= 8; i++ {
+		heroes = append(heroes, models.Hero{ID: uint(i), Hitpoint: 100 * i, Damage: 10 * i, DamageTower: 10 * i, CostElixir: 3, Speed: 1})
+	}
+	var spells []models.Spell
+	for i := int32(1); i <= 4; i++ {
+		spells = append(spells, models.Spell{ID: uint(10 + i), Damage: 100 * i, Area: 1})
+	}
+	return heroes, spells
+}
+
+func heroIDs(deck models.DeckSnapshot) []uint {
+	var ids []uint
+	for _, hero := range deck.Heroes {
+		ids = append(ids, hero.ID)
+	}
+	return ids
+}
+
+func spellIDs(deck models.DeckSnapshot) []uint {
+	var ids []uint
+	for _, spell := range deck.Spells {
+		ids = append(ids, spell.ID)
+	}
+	return ids
+}
+
+func TestBuildDeck(t *testing.T) {
+	tests := []struct {
+		name       string
+		difficulty string
+		size       int
+		heroes     int
+		spells     int
+		// minHero is the lowest hero id the deck may hold.
+		minHero uint
+	}{
+		{"easy takes any cards", models.BotEasy, 4, 3, 1, 1},
+		{"normal takes from the better half", models.BotNormal, 4, 3, 1, 5},
+		{"normal takes more when the half is too small", models.BotNormal, 8, 6, 2, 1},
+		{"hard takes the best", models.BotHard, 4, 3, 1, 6},
+		{"spells fill in for missing heroes", models.BotHard, 12, 8, 4, 1},
+	}
+	for _, tt := range tests {
+		for seed := int64(1); seed <= 5; seed++ {
+			heroes, spells := catalog()
+			deck := BuildDeck(heroes, spells, tt.difficulty, tt.size, rand.New(rand.NewSource(seed)))
+			if len(deck.Heroes) != tt.heroes || len(deck.Spells) != tt.spells {
+				t.Errorf("%s, seed %d: %d heroes and %d spells, want %d and %d", tt.name, seed, len(deck.Heroes), len(deck.Spells), tt.heroes, tt.spells)
+			}
+			for _, id := range heroIDs(deck) {
+				if id < tt.minHero {
+					t.Errorf("%s, seed %d: deck has hero %d, want none below %d", tt.name, seed, id, tt.minHero)
+				}
+			}
+			if again := BuildDeck(heroes, spells, tt.difficulty, tt.size, rand.New(rand.NewSource(seed))); !reflect.DeepEqual(heroIDs(again), heroIDs(deck)) || !reflect.DeepEqual(spellIDs(again), spellIDs(deck)) {
+				t.Errorf("%s, seed %d: the same seed built %v then %v", tt.name, seed, heroIDs(deck), heroIDs(again))
+			}
+			if heroes[0].ID != 1 || spells[0].ID != 11 {
+				t.Errorf("%s, seed %d: BuildDeck reordered the catalog", tt.name, seed)
+			}
+		}
+	}
+
+	heroes, spells := catalog()
+	deck := BuildDeck(heroes, spells, models.BotHard, 4, rand.New(rand.NewSource(1)))
+	if !reflect.DeepEqual(heroIDs(deck), []uint{8, 7, 6}) || !reflect.DeepEqual(spellIDs(deck), []uint{14}) {
+		t.Errorf("hard deck is %v and %v, want heroes [8 7 6] and spell [14]", heroIDs(deck), spellIDs(deck))
+	}
+}
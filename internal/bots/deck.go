@@ -0,0 +1,39 @@
+package bots
+
+import (
+	"auth/internal/rest/models"
+	"math/rand"
+	"sort"
+)
+
+// BuildDeck makes a bot's deck of size cards from the catalog, at most a
+// quarter of them spells. Easy bots take random cards, normal bots random
+// ones from the better half and hard bots the best the catalog has.
+func BuildDeck(heroes []models.Hero, spells []models.Spell, difficulty string, size int, rng *rand.Rand) models.DeckSnapshot {
+	heroes = append([]models.Hero(nil), heroes...)
+	spells = append([]models.Spell(nil), spells...)
+	sort.SliceStable(heroes, func(i, j int) bool { return heroValue(heroes[i]) > heroValue(heroes[j]) })
+	sort.SliceStable(spells, func(i, j int) bool { return spellValue(spells[i]) > spellValue(spells[j]) })
+
+	switch difficulty {
+	case models.BotEasy:
+		rng.Shuffle(len(heroes), func(i, j int) { heroes[i], heroes[j] = heroes[j], heroes[i] })
+		rng.Shuffle(len(spells), func(i, j int) { spells[i], spells[j] = spells[j], spells[i] })
+	case models.BotHard:
+	default:
+		heroes = heroes[:max((len(heroes)+1)/2, min(size, len(heroes)))]
+		rng.Shuffle(len(heroes), func(i, j int) { heroes[i], heroes[j] = heroes[j], heroes[i] })
+		rng.Shuffle(len(spells), func(i, j int) { spells[i], spells[j] = spells[j], spells[i] })
+	}
+
+	spellCount := min(size/4, len(spells))
+	heroCount := min(size-spellCount, len(heroes))
+	if heroCount+spellCount < size {
+		spellCount = min(size-heroCount, len(spells))
+	}
+	return models.DeckSnapshot{Name: "Bot", Heroes: heroes[:heroCount], Spells: spells[:spellCount]}
+}
+
+func heroValue(hero models.Hero) float64 {
+	return defenceValue(hero) + attackValue(hero)
+}
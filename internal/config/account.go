@@ -0,0 +1,8 @@
+package config
+
+import "time"
+
+type AccountConfig struct {
+	DeletionGrace time.Duration `env:"ACCOUNT_DELETION_GRACE_DAYS" envDefault:"30"`
+	PurgeInterval time.Duration
+}
@@ -1,8 +1,24 @@
 package config
 
 type App struct {
-	PORT  string `env:"APP_PORT" envDefault:"8080"`
-	DB    Database
-	Redis RedisConfig
-	Email EmailConfig
+	PORT         string `env:"APP_PORT" envDefault:"8080"`
+	DB           Database
+	Redis        RedisConfig
+	Email        EmailConfig
+	OIDC         []OIDCProvider
+	JWT          JWTConfig
+	Account      AccountConfig
+	Match        MatchConfig
+	Clan         ClanConfig
+	Chat         ChatConfig
+	Notification NotificationConfig
+	Quest        QuestConfig
+	Season       SeasonConfig
+	Shop         ShopConfig
+	Wallet       WalletConfig
+	Payment      PaymentConfig
+	Tournament   TournamentConfig
+	Draft        DraftConfig
+	Bot          BotConfig
+	Chest        ChestConfig
 }
@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+type BotConfig struct {
+	// Check is how often the 2v2 queue is looked at for players who have
+	// waited too long.
+	Check time.Duration
+	// QueueTimeout is how long a player waits in the 2v2 queue before bots
+	// take the empty seats.
+	QueueTimeout time.Duration `env:"BOT_QUEUE_TIMEOUT_SECONDS" envDefault:"60"`
+	// QueueDifficulty is how well the bots filling the queue play.
+	QueueDifficulty string `env:"BOT_QUEUE_DIFFICULTY" envDefault:"NORMAL"`
+	// DeckSize is how many cards a bot's deck holds.
+	DeckSize int `env:"BOT_DECK_SIZE" envDefault:"8"`
+}
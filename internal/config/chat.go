@@ -0,0 +1,8 @@
+package config
+
+type ChatConfig struct {
+	MaxMessageLength int
+	HistoryPageSize  int
+	// BannedWords are masked out of every message before it is stored.
+	BannedWords []string `env:"CHAT_BANNED_WORDS"`
+}
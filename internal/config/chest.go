@@ -0,0 +1,7 @@
+package config
+
+type ChestConfig struct {
+	// SpeedUpGemsPerHour is what opening a chest early costs for every hour
+	// of its timer left.
+	SpeedUpGemsPerHour int64 `env:"CHEST_SPEEDUP_GEMS_PER_HOUR" envDefault:"6"`
+}
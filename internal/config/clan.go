@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+type ClanConfig struct {
+	MaxMembers      int
+	RequestCooldown time.Duration `env:"CLAN_REQUEST_COOLDOWN_HOURS" envDefault:"7"`
+	RequestSize     int32
+	// DonationGold is paid to the donor per card, keyed by hero rarity;
+	// spells and unknown rarities use DefaultDonationGold.
+	DonationGold        map[string]int64
+	DefaultDonationGold int64
+}
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+type DraftConfig struct {
+	// Check is how often drafts are looked at for picks past their deadline.
+	Check time.Duration
+	// DeckSize is how many cards each player drafts.
+	DeckSize int `env:"DRAFT_DECK_SIZE" envDefault:"8"`
+	// PickSeconds is how long a player has for each pick before one of the
+	// offered cards is picked for them.
+	PickSeconds int `env:"DRAFT_PICK_SECONDS" envDefault:"30"`
+}
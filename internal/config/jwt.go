@@ -0,0 +1,18 @@
+package config
+
+type JWTConfig struct {
+	Issuer      string `env:"JWT_ISSUER"`
+	Audience    string `env:"JWT_AUDIENCE"`
+	Keys        []JWTKey
+	ActiveKeyID string `env:"JWT_ACTIVE_KID"`
+	// AllowEphemeralKey lets a development server without keys sign with a
+	// key generated at startup.
+	AllowEphemeralKey bool
+}
+
+// JWTKey points at a PEM file. Private keys can sign and verify; public keys
+// are kept only to verify tokens signed by a retired key.
+type JWTKey struct {
+	ID   string
+	Path string
+}
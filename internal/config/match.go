@@ -0,0 +1,8 @@
+package config
+
+import "time"
+
+type MatchConfig struct {
+	ChallengeTTL   time.Duration `env:"CHALLENGE_TTL_MINUTES" envDefault:"10"`
+	ExpiryInterval time.Duration
+}
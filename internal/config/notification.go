@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+type NotificationConfig struct {
+	// DigestInterval is the least time between two digest emails to a player.
+	DigestInterval time.Duration `env:"NOTIFICATION_DIGEST_HOURS" envDefault:"24"`
+	DigestCheck    time.Duration
+	DigestSize     int
+}
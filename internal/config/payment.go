@@ -0,0 +1,9 @@
+package config
+
+type PaymentConfig struct {
+	// Products maps store product ids to the gems they grant, for example
+	// IAP_PRODUCTS=gems_small=80,gems_large=500.
+	Products map[string]int64 `env:"IAP_PRODUCTS"`
+	// FakeSecret enables the offline fake store when set.
+	FakeSecret string `env:"IAP_FAKE_SECRET"`
+}
@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+type QuestConfig struct {
+	// ResetOffset is how long after midnight UTC daily quests rotate.
+	ResetOffset time.Duration `env:"QUEST_RESET_HOUR" envDefault:"0"`
+	DailyCount  int           `env:"QUEST_DAILY_COUNT" envDefault:"3"`
+}
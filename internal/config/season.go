@@ -0,0 +1,8 @@
+package config
+
+import "time"
+
+type SeasonConfig struct {
+	// CloseCheck is how often ended seasons are looked for and settled.
+	CloseCheck time.Duration
+}
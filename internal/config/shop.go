@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+type ShopConfig struct {
+	// ResetOffset is how long after midnight UTC daily offers rotate.
+	ResetOffset   time.Duration `env:"SHOP_RESET_HOUR" envDefault:"0"`
+	DailyOffers   int           `env:"SHOP_DAILY_OFFERS" envDefault:"4"`
+	DailyDiscount int           `env:"SHOP_DAILY_DISCOUNT_PERCENT" envDefault:"20"`
+}
@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+type TournamentConfig struct {
+	// Check is how often running tournaments are looked at to close
+	// registration, settle rounds and pair the next ones.
+	Check time.Duration
+	// RoundMinutes is how long a round lasts when the creator doesn't say.
+	RoundMinutes int `env:"TOURNAMENT_ROUND_MINUTES" envDefault:"30"`
+	// MaxPlayers caps the players of any one tournament.
+	MaxPlayers int `env:"TOURNAMENT_MAX_PLAYERS" envDefault:"64"`
+}
@@ -0,0 +1,6 @@
+package config
+
+type WalletConfig struct {
+	// GoldPerGem is how much gold one gem buys on the exchange.
+	GoldPerGem int64 `env:"GEMS_GOLD_RATE" envDefault:"20"`
+}
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    user_id INT REFERENCES users(id),
+    provider VARCHAR(255),
+    subject VARCHAR(255),
+    email VARCHAR(255),
+    UNIQUE (provider, subject)
+);
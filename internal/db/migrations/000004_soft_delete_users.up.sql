@@ -0,0 +1,4 @@
+-- Accounts used to be created with a zero deleted_at instead of NULL.
+UPDATE users SET deleted_at = NULL WHERE deleted_at < '1900-01-01';
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS player_profiles (
+    user_id INT PRIMARY KEY REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    favorite_hero_id INT REFERENCES heros(id),
+    current_deck_id INT REFERENCES decks(id),
+    show_stats BOOLEAN DEFAULT TRUE,
+    show_collection BOOLEAN DEFAULT TRUE,
+    show_deck BOOLEAN DEFAULT TRUE,
+    show_favorite BOOLEAN DEFAULT TRUE,
+    wins INT DEFAULT 0,
+    losses INT DEFAULT 0
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_blocks;
+DROP TABLE IF EXISTS friendships;
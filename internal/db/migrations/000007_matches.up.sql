@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS matches (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    mode VARCHAR(20) NOT NULL,
+    ranked BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'STARTED',
+    winner_id INT REFERENCES users(id),
+    finished_at TIMESTAMP
+);
+
+-- Decks are copied when the match starts so later deck edits don't change it.
+CREATE TABLE IF NOT EXISTS match_players (
+    id SERIAL PRIMARY KEY,
+    match_id INT NOT NULL REFERENCES matches(id),
+    user_id INT NOT NULL REFERENCES users(id),
+    deck JSONB NOT NULL,
+    UNIQUE (match_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_players_user ON match_players (user_id);
+
+CREATE TABLE IF NOT EXISTS challenges (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    challenger_id INT NOT NULL REFERENCES users(id),
+    challenger_deck_id INT NOT NULL REFERENCES decks(id),
+    opponent_id INT REFERENCES users(id),
+    join_code VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    expires_at TIMESTAMP NOT NULL,
+    match_id INT REFERENCES matches(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_challenges_pending_code ON challenges (join_code) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_challenges_opponent ON challenges (opponent_id);
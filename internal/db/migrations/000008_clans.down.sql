@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS clan_donations;
+DROP TABLE IF EXISTS clan_card_requests;
+DROP TABLE IF EXISTS clan_join_requests;
+DROP TABLE IF EXISTS clan_members;
+DROP TABLE IF EXISTS clans;
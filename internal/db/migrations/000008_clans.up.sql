@@ -0,0 +1,56 @@
+CREATE TABLE IF NOT EXISTS clans (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    name VARCHAR(255) NOT NULL,
+    tag VARCHAR(20) NOT NULL UNIQUE,
+    description VARCHAR(255) DEFAULT '',
+    membership VARCHAR(20) NOT NULL DEFAULT 'OPEN',
+    min_trophies INT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clans_name ON clans (LOWER(name));
+
+-- A player belongs to at most one clan.
+CREATE TABLE IF NOT EXISTS clan_members (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    clan_id INT NOT NULL REFERENCES clans(id),
+    user_id INT NOT NULL UNIQUE REFERENCES users(id),
+    role VARCHAR(20) NOT NULL DEFAULT 'MEMBER',
+    donations INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_clan_members_clan ON clan_members (clan_id);
+
+CREATE TABLE IF NOT EXISTS clan_join_requests (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    clan_id INT NOT NULL REFERENCES clans(id),
+    user_id INT NOT NULL REFERENCES users(id),
+    UNIQUE (clan_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS clan_card_requests (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    clan_id INT NOT NULL REFERENCES clans(id),
+    user_id INT NOT NULL REFERENCES users(id),
+    card_kind VARCHAR(10) NOT NULL,
+    card_id INT NOT NULL,
+    requested INT NOT NULL,
+    donated INT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'OPEN'
+);
+
+CREATE INDEX IF NOT EXISTS idx_clan_card_requests_clan ON clan_card_requests (clan_id, status);
+
+CREATE TABLE IF NOT EXISTS clan_donations (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    request_id INT NOT NULL REFERENCES clan_card_requests(id),
+    donor_id INT NOT NULL REFERENCES users(id),
+    gold BIGINT NOT NULL
+);
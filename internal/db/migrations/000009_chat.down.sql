@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS moderation_audit_log;
+DROP TABLE IF EXISTS chat_sanctions;
+DROP TABLE IF EXISTS chat_reports;
+DROP TABLE IF EXISTS chat_messages;
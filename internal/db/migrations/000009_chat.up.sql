@@ -0,0 +1,55 @@
+-- Clan messages set clan_id, direct messages set recipient_id. clan_id is
+-- cleared when a clan disbands so reported messages stay reviewable.
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    channel VARCHAR(10) NOT NULL,
+    clan_id INT REFERENCES clans(id),
+    sender_id INT NOT NULL REFERENCES users(id),
+    recipient_id INT REFERENCES users(id),
+    body TEXT NOT NULL,
+    hidden_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_clan ON chat_messages (clan_id, id) WHERE clan_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_chat_messages_direct ON chat_messages (LEAST(sender_id, recipient_id), GREATEST(sender_id, recipient_id), id) WHERE recipient_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS chat_reports (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    message_id INT NOT NULL REFERENCES chat_messages(id),
+    reporter_id INT NOT NULL REFERENCES users(id),
+    reason VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'OPEN',
+    resolved_by INT REFERENCES users(id),
+    resolved_at TIMESTAMP,
+    UNIQUE (message_id, reporter_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_reports_status ON chat_reports (status, id);
+
+-- A sanction without expires_at lasts until a moderator revokes it.
+CREATE TABLE IF NOT EXISTS chat_sanctions (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL REFERENCES users(id),
+    kind VARCHAR(10) NOT NULL,
+    reason VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_by INT REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_sanctions_user ON chat_sanctions (user_id);
+
+CREATE TABLE IF NOT EXISTS moderation_audit_log (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    moderator_id INT REFERENCES users(id),
+    action VARCHAR(30) NOT NULL,
+    target_user_id INT REFERENCES users(id),
+    message_id INT REFERENCES chat_messages(id),
+    report_id INT REFERENCES chat_reports(id),
+    sanction_id INT REFERENCES chat_sanctions(id),
+    details VARCHAR(255) DEFAULT ''
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_quests;
+DROP TABLE IF EXISTS quest_definitions;
@@ -0,0 +1,45 @@
+-- A quest counts one kind of event (optionally only cards of one rarity)
+-- until it reaches target. DAILY quests are drawn from the active pool each
+-- day; ACHIEVEMENT quests are tracked once per player forever.
+CREATE TABLE IF NOT EXISTS quest_definitions (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    kind VARCHAR(20) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description VARCHAR(255) DEFAULT '',
+    event VARCHAR(30) NOT NULL,
+    rarity VARCHAR(20) NOT NULL DEFAULT '',
+    target INT NOT NULL,
+    reward_gold BIGINT NOT NULL DEFAULT 0,
+    reward_hero_id INT REFERENCES heros(id),
+    reward_spell_id INT REFERENCES spells(id),
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+-- period is the day (YYYY-MM-DD) a daily quest belongs to, or ALL for
+-- achievements.
+CREATE TABLE IF NOT EXISTS user_quests (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL REFERENCES users(id),
+    definition_id INT NOT NULL REFERENCES quest_definitions(id),
+    period VARCHAR(10) NOT NULL,
+    progress INT NOT NULL DEFAULT 0,
+    completed_at TIMESTAMP,
+    claimed_at TIMESTAMP,
+    UNIQUE (user_id, definition_id, period)
+);
+
+INSERT INTO quest_definitions (code, kind, title, description, event, rarity, target, reward_gold) VALUES
+    ('daily_win_3', 'DAILY', 'Win 3 matches', 'Win 3 matches today', 'MATCH_WON', '', 3, 100),
+    ('daily_play_5', 'DAILY', 'Play 5 matches', 'Finish 5 matches today', 'MATCH_PLAYED', '', 5, 75),
+    ('daily_spells_20', 'DAILY', 'Play 20 spells', 'Cast 20 spells in matches today', 'SPELL_PLAYED', '', 20, 80),
+    ('daily_buy_hero', 'DAILY', 'Recruit a hero', 'Buy any hero today', 'HERO_BOUGHT', '', 1, 50),
+    ('daily_buy_spell', 'DAILY', 'Learn a spell', 'Buy any spell today', 'SPELL_BOUGHT', '', 1, 50),
+    ('achievement_first_win', 'ACHIEVEMENT', 'First victory', 'Win your first match', 'MATCH_WON', '', 1, 200),
+    ('achievement_wins_100', 'ACHIEVEMENT', 'Centurion', 'Win 100 matches', 'MATCH_WON', '', 100, 5000),
+    ('achievement_legendary', 'ACHIEVEMENT', 'Legend hunter', 'Buy a Legendary hero', 'HERO_BOUGHT', 'Legendary', 1, 1000)
+ON CONFLICT (code) DO NOTHING;
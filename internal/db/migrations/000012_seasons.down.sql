@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS trophy_road_claims;
+DROP TABLE IF EXISTS trophy_road_milestones;
+DROP TABLE IF EXISTS season_results;
+DROP TABLE IF EXISTS season_rewards;
+DROP TABLE IF EXISTS seasons;
@@ -0,0 +1,71 @@
+-- A season runs from starts_at to ends_at. When it is closed, trophies above
+-- reset_threshold are cut to reset_threshold plus reset_keep_percent of the
+-- excess. closed_at is set in the same transaction that pays the rewards, so
+-- a season is never settled twice.
+CREATE TABLE IF NOT EXISTS seasons (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    name VARCHAR(100) NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    reset_threshold INT NOT NULL DEFAULT 4000,
+    reset_keep_percent INT NOT NULL DEFAULT 50,
+    closed_at TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+-- Players finishing between min_rank and max_rank (inclusive) earn gold.
+CREATE TABLE IF NOT EXISTS season_rewards (
+    id SERIAL PRIMARY KEY,
+    season_id INT NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+    min_rank INT NOT NULL,
+    max_rank INT NOT NULL,
+    reward_gold BIGINT NOT NULL,
+    CHECK (max_rank >= min_rank)
+);
+
+CREATE INDEX IF NOT EXISTS idx_season_rewards_season ON season_rewards (season_id);
+
+-- One row per ranked player and season, written when the season closes.
+CREATE TABLE IF NOT EXISTS season_results (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    season_id INT NOT NULL REFERENCES seasons(id),
+    user_id INT NOT NULL REFERENCES users(id),
+    rank INT NOT NULL,
+    trophies INT NOT NULL,
+    reset_trophies INT NOT NULL,
+    reward_gold BIGINT NOT NULL DEFAULT 0,
+    UNIQUE (season_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_season_results_user ON season_results (user_id);
+
+CREATE TABLE IF NOT EXISTS trophy_road_milestones (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    trophies INT NOT NULL UNIQUE,
+    reward_gold BIGINT NOT NULL DEFAULT 0,
+    reward_hero_id INT REFERENCES heros(id),
+    reward_spell_id INT REFERENCES spells(id)
+);
+
+CREATE TABLE IF NOT EXISTS trophy_road_claims (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL REFERENCES users(id),
+    milestone_id INT NOT NULL REFERENCES trophy_road_milestones(id),
+    UNIQUE (user_id, milestone_id)
+);
+
+INSERT INTO trophy_road_milestones (trophies, reward_gold) VALUES
+    (100, 100),
+    (300, 200),
+    (600, 300),
+    (1000, 500),
+    (1500, 750),
+    (2000, 1000),
+    (3000, 1500),
+    (4000, 2500)
+ON CONFLICT (trophies) DO NOTHING;
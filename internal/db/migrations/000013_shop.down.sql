@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS shop_purchases;
+DROP TABLE IF EXISTS shop_offer_items;
+DROP TABLE IF EXISTS shop_offers;
+DROP TABLE IF EXISTS daily_offers;
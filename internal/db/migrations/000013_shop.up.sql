@@ -0,0 +1,57 @@
+-- Daily offers are drawn for a player the first time they open the shop in
+-- a period and kept, so catalog changes don't reshuffle the day's shop. Each
+-- offer can be bought once.
+CREATE TABLE IF NOT EXISTS daily_offers (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL REFERENCES users(id),
+    period VARCHAR(10) NOT NULL,
+    hero_id INT REFERENCES heros(id),
+    spell_id INT REFERENCES spells(id),
+    list_price BIGINT NOT NULL,
+    price BIGINT NOT NULL,
+    purchased_at TIMESTAMP,
+    CHECK ((hero_id IS NULL) <> (spell_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_offers_user_period ON daily_offers (user_id, period);
+
+-- Offers set up by admins: a bundle of cards and gold sold for price between
+-- starts_at and ends_at. stock caps how many are sold in total and
+-- per_user_limit how many one player may buy; NULL means no limit.
+CREATE TABLE IF NOT EXISTS shop_offers (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    title VARCHAR(100) NOT NULL,
+    description VARCHAR(255) DEFAULT '',
+    price BIGINT NOT NULL,
+    reward_gold BIGINT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    stock INT,
+    sold INT NOT NULL DEFAULT 0,
+    per_user_limit INT,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE TABLE IF NOT EXISTS shop_offer_items (
+    id SERIAL PRIMARY KEY,
+    offer_id INT NOT NULL REFERENCES shop_offers(id) ON DELETE CASCADE,
+    hero_id INT REFERENCES heros(id),
+    spell_id INT REFERENCES spells(id),
+    CHECK ((hero_id IS NULL) <> (spell_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_shop_offer_items_offer ON shop_offer_items (offer_id);
+
+CREATE TABLE IF NOT EXISTS shop_purchases (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL REFERENCES users(id),
+    offer_id INT NOT NULL REFERENCES shop_offers(id),
+    price BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_shop_purchases_offer_user ON shop_purchases (offer_id, user_id);
@@ -0,0 +1,9 @@
+ALTER TABLE shop_offers DROP COLUMN IF EXISTS currency;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bank BIGINT;
+
+UPDATE users u SET bank = w.balance
+FROM wallets w
+WHERE w.user_id = u.id AND w.currency = 'GOLD';
+
+DROP TABLE IF EXISTS wallet_transactions;
+DROP TABLE IF EXISTS wallets;
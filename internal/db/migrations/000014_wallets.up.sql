@@ -0,0 +1,40 @@
+-- Each player has one balance per currency: GOLD, earned in game, and GEMS,
+-- the premium currency. Balances can't go negative, so a debit that would
+-- overdraw fails in the database as well as in code.
+CREATE TABLE IF NOT EXISTS wallets (
+    user_id INT NOT NULL REFERENCES users(id),
+    currency VARCHAR(10) NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, currency)
+);
+
+-- Every change to a balance, positive for credits and negative for debits.
+-- reference ties an entry to what caused it, such as a quest or offer id.
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL REFERENCES users(id),
+    currency VARCHAR(10) NOT NULL,
+    amount BIGINT NOT NULL,
+    reason VARCHAR(30) NOT NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_transactions_user ON wallet_transactions (user_id, id);
+
+INSERT INTO wallets (user_id, currency, balance)
+SELECT id, 'GOLD', GREATEST(COALESCE(bank, 0), 0) FROM users
+ON CONFLICT (user_id, currency) DO NOTHING;
+
+INSERT INTO wallet_transactions (user_id, currency, amount, reason)
+SELECT id, 'GOLD', bank, 'MIGRATION' FROM users WHERE COALESCE(bank, 0) > 0;
+
+INSERT INTO wallets (user_id, currency, balance)
+SELECT id, 'GEMS', 0 FROM users
+ON CONFLICT (user_id, currency) DO NOTHING;
+
+ALTER TABLE users DROP COLUMN IF EXISTS bank;
+
+-- Bundles can be priced in either currency.
+ALTER TABLE shop_offers ADD COLUMN IF NOT EXISTS currency VARCHAR(10) NOT NULL DEFAULT 'GOLD';
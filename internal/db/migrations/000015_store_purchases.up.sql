@@ -0,0 +1,18 @@
+-- Gems bought through an app store. A store transaction is credited at most
+-- once; refunds and chargebacks reverse it and record how many gems were
+-- clawed back, which can be fewer than granted if the player spent them.
+CREATE TABLE IF NOT EXISTS store_purchases (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL REFERENCES users(id),
+    provider VARCHAR(30) NOT NULL,
+    transaction_id VARCHAR(255) NOT NULL,
+    product_id VARCHAR(100) NOT NULL,
+    gems BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'COMPLETED',
+    reversed_at TIMESTAMP,
+    clawed_back BIGINT NOT NULL DEFAULT 0,
+    UNIQUE (provider, transaction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_store_purchases_user ON store_purchases (user_id, id);
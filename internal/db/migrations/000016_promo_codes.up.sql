@@ -0,0 +1,36 @@
+-- Codes handed out by marketing. max_uses caps redemptions in total (1 for a
+-- single-use code, NULL for no cap) and per_user_limit how often one player
+-- may redeem the same code.
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    description VARCHAR(255) DEFAULT '',
+    reward_gold BIGINT NOT NULL DEFAULT 0,
+    reward_gems BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    max_uses INT,
+    uses INT NOT NULL DEFAULT 0,
+    per_user_limit INT NOT NULL DEFAULT 1,
+    active BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS promo_code_items (
+    id SERIAL PRIMARY KEY,
+    code_id INT NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+    hero_id INT REFERENCES heros(id),
+    spell_id INT REFERENCES spells(id),
+    CHECK ((hero_id IS NULL) <> (spell_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_code_items_code ON promo_code_items (code_id);
+
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    code_id INT NOT NULL REFERENCES promo_codes(id),
+    user_id INT NOT NULL REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_code_user ON promo_redemptions (code_id, user_id);
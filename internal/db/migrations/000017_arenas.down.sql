@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS arena_cards;
+DROP TABLE IF EXISTS arenas;
@@ -0,0 +1,29 @@
+-- Arenas unlock cards as players climb: a player is in the highest arena
+-- whose min_trophies they have reached and can buy the cards of that arena
+-- and every arena below it. Cards in no arena are available to everyone.
+CREATE TABLE IF NOT EXISTS arenas (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    min_trophies INT NOT NULL UNIQUE CHECK (min_trophies >= 0)
+);
+
+-- A card is unlocked by at most one arena.
+CREATE TABLE IF NOT EXISTS arena_cards (
+    id SERIAL PRIMARY KEY,
+    arena_id INT NOT NULL REFERENCES arenas(id) ON DELETE CASCADE,
+    hero_id INT UNIQUE REFERENCES heros(id),
+    spell_id INT UNIQUE REFERENCES spells(id),
+    CHECK ((hero_id IS NULL) <> (spell_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_arena_cards_arena ON arena_cards (arena_id);
+
+INSERT INTO arenas (name, min_trophies) VALUES
+    ('Training Grounds', 0),
+    ('Stone Keep', 400),
+    ('Frost Peak', 1000),
+    ('Ember Forge', 1800),
+    ('Sky Citadel', 3000)
+ON CONFLICT DO NOTHING;
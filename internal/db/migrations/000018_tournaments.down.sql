@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS tournament_matches;
+DROP TABLE IF EXISTS tournament_players;
+DROP TABLE IF EXISTS tournament_prizes;
+DROP TABLE IF EXISTS tournaments;
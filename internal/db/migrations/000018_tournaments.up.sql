@@ -0,0 +1,71 @@
+-- Tournaments are created by players or, as official ones, by admins. Players
+-- register between registration_opens_at and registration_closes_at, paying
+-- entry_fee; the first round is paired when registration closes. rounds is
+-- fixed when the tournament starts unless set for a Swiss tournament.
+CREATE TABLE IF NOT EXISTS tournaments (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    name VARCHAR(100) NOT NULL,
+    format VARCHAR(20) NOT NULL,
+    created_by INT REFERENCES users(id),
+    official BOOLEAN NOT NULL DEFAULT FALSE,
+    entry_fee BIGINT NOT NULL DEFAULT 0,
+    bonus_pool BIGINT NOT NULL DEFAULT 0,
+    max_players INT NOT NULL,
+    rounds INT NOT NULL DEFAULT 0,
+    round_minutes INT NOT NULL,
+    registration_opens_at TIMESTAMP NOT NULL,
+    registration_closes_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'REGISTRATION',
+    current_round INT NOT NULL DEFAULT 0,
+    round_ends_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    CHECK (registration_closes_at > registration_opens_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tournaments_status ON tournaments (status);
+
+-- The share of the prize pool, entry fees plus bonus_pool, paid to each place.
+CREATE TABLE IF NOT EXISTS tournament_prizes (
+    id SERIAL PRIMARY KEY,
+    tournament_id INT NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    place INT NOT NULL,
+    percent INT NOT NULL CHECK (percent > 0 AND percent <= 100),
+    UNIQUE (tournament_id, place)
+);
+
+-- deck is the snapshot of the deck the player registered with, used for all
+-- of their tournament matches.
+CREATE TABLE IF NOT EXISTS tournament_players (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    tournament_id INT NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id),
+    deck JSONB NOT NULL,
+    fee_paid BIGINT NOT NULL DEFAULT 0,
+    seed INT,
+    points INT NOT NULL DEFAULT 0,
+    eliminated_round INT,
+    place INT,
+    prize_gold BIGINT NOT NULL DEFAULT 0,
+    UNIQUE (tournament_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tournament_players_user ON tournament_players (user_id);
+
+-- One pairing of a round. player2_id is NULL for a bye, which player1 wins
+-- without a match. Players are set to NULL when their account is deleted.
+-- The unique key keeps a round from being paired twice.
+CREATE TABLE IF NOT EXISTS tournament_matches (
+    id SERIAL PRIMARY KEY,
+    tournament_id INT NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    round INT NOT NULL,
+    position INT NOT NULL,
+    player1_id INT REFERENCES users(id),
+    player2_id INT REFERENCES users(id),
+    match_id INT REFERENCES matches(id),
+    winner_id INT REFERENCES users(id),
+    finished_at TIMESTAMP,
+    UNIQUE (tournament_id, round, position)
+);
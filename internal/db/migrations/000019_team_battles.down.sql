@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS team_queue;
+DROP TABLE IF EXISTS parties;
+ALTER TABLE matches DROP COLUMN IF EXISTS winning_team;
+ALTER TABLE match_players DROP COLUMN IF EXISTS team;
@@ -0,0 +1,27 @@
+-- Players of team modes are put on team 1 or 2; 0 means no teams, as in 1v1
+-- matches. Team matches record the team that won rather than a winner.
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS team INT NOT NULL DEFAULT 0;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS winning_team INT;
+
+-- A party is two friends who queue for 2v2 together. The leader invites a
+-- friend, who joins with one of their decks; decks are snapshots taken when
+-- each player picks them.
+CREATE TABLE IF NOT EXISTS parties (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    leader_id INT NOT NULL UNIQUE REFERENCES users(id),
+    leader_deck JSONB NOT NULL,
+    invited_id INT REFERENCES users(id),
+    member_id INT UNIQUE REFERENCES users(id),
+    member_deck JSONB
+);
+
+-- Players waiting for a 2v2 match. The members of a party share party_id and
+-- are always put on the same team.
+CREATE TABLE IF NOT EXISTS team_queue (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL UNIQUE REFERENCES users(id),
+    party_id INT REFERENCES parties(id) ON DELETE CASCADE,
+    deck JSONB NOT NULL
+);
@@ -0,0 +1,40 @@
+-- Players waiting for a draft opponent. Nobody brings a deck to a draft.
+CREATE TABLE IF NOT EXISTS draft_queue (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL UNIQUE REFERENCES users(id)
+);
+
+-- A draft has the two players alternately pick one of two offered cards,
+-- player1 first, until each holds deck_size cards. offer is the pair on the
+-- table for pick number pick_number (counted from 0); when pick_deadline
+-- passes one of them is picked at random. The drafted decks start match_id.
+CREATE TABLE IF NOT EXISTS drafts (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    player1_id INT REFERENCES users(id),
+    player2_id INT REFERENCES users(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'DRAFTING',
+    deck_size INT NOT NULL,
+    pick_seconds INT NOT NULL,
+    pick_number INT NOT NULL DEFAULT 0,
+    offer JSONB NOT NULL,
+    pick_deadline TIMESTAMP NOT NULL,
+    match_id INT REFERENCES matches(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_drafts_drafting ON drafts (pick_deadline) WHERE status = 'DRAFTING';
+
+-- Picked cards are copied like deck snapshots so catalog edits made during
+-- the draft don't change them.
+CREATE TABLE IF NOT EXISTS draft_picks (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    draft_id INT NOT NULL REFERENCES drafts(id),
+    user_id INT REFERENCES users(id),
+    pick_number INT NOT NULL,
+    card JSONB NOT NULL,
+    auto BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (draft_id, pick_number)
+);
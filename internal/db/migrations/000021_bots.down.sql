@@ -0,0 +1,4 @@
+DELETE FROM match_players WHERE bot IS NOT NULL;
+ALTER TABLE match_players DROP CONSTRAINT IF EXISTS match_players_user_or_bot;
+ALTER TABLE match_players DROP COLUMN IF EXISTS bot;
+ALTER TABLE match_players ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,5 @@
+-- A bot takes a player's place in a match without an account: its row has
+-- no user_id and bot holds its difficulty.
+ALTER TABLE match_players ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE match_players ADD COLUMN IF NOT EXISTS bot VARCHAR(20);
+ALTER TABLE match_players ADD CONSTRAINT match_players_user_or_bot CHECK ((user_id IS NULL) <> (bot IS NULL));
@@ -0,0 +1,18 @@
+-- A replay is what it takes to play a match again: its seed and the log of
+-- inputs, deflated varints (see internal/replays), next to the deck snapshots
+-- match_players already keeps. stats_version fingerprints the card stats in
+-- those snapshots. verified is NULL until the replay has been re-simulated,
+-- then whether it reproduced the reported result; verify_error says why not.
+CREATE TABLE IF NOT EXISTS replays (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    match_id INT NOT NULL UNIQUE REFERENCES matches(id) ON DELETE CASCADE,
+    seed BIGINT NOT NULL,
+    stats_version VARCHAR(64) NOT NULL,
+    duration_ms INT NOT NULL,
+    input_count INT NOT NULL,
+    inputs BYTEA NOT NULL,
+    verified BOOLEAN,
+    verified_at TIMESTAMP,
+    verify_error TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,4 @@
+DELETE FROM wallet_transactions WHERE user_id IS NULL;
+ALTER TABLE wallet_transactions ALTER COLUMN user_id SET NOT NULL;
+DELETE FROM store_purchases WHERE user_id IS NULL;
+ALTER TABLE store_purchases ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,5 @@
+-- Deleting an account keeps its store purchases and ledger entries with the
+-- user cleared. The purchase row is what stops a store transaction from being
+-- redeemed twice, so it has to outlive the account that redeemed it.
+ALTER TABLE store_purchases ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE wallet_transactions ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,6 @@
+-- Usernames are unique regardless of case. Accounts that already share a
+-- name with an older one get their id appended first.
+UPDATE users u SET username = u.username || u.id
+WHERE EXISTS (SELECT 1 FROM users o WHERE LOWER(o.username) = LOWER(u.username) AND o.id < u.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username));
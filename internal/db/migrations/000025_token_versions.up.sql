@@ -0,0 +1,3 @@
+-- Tokens carry the version they were issued at. Changing the password or
+-- email bumps it, which revokes every token issued before.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INT NOT NULL DEFAULT 0;
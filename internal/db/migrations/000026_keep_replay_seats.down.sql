@@ -0,0 +1,3 @@
+DELETE FROM match_players WHERE user_id IS NULL AND bot IS NULL;
+ALTER TABLE match_players DROP CONSTRAINT IF EXISTS match_players_user_or_bot;
+ALTER TABLE match_players ADD CONSTRAINT match_players_user_or_bot CHECK ((user_id IS NULL) <> (bot IS NULL));
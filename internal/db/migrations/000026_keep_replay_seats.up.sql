@@ -0,0 +1,5 @@
+-- Deleting an account keeps its seats in past matches with the user cleared,
+-- so the opponents' replays still have every seat and deck snapshot to play
+-- the match again. A seat with neither a user nor a bot is a deleted player's.
+ALTER TABLE match_players DROP CONSTRAINT IF EXISTS match_players_user_or_bot;
+ALTER TABLE match_players ADD CONSTRAINT match_players_user_or_bot CHECK (user_id IS NULL OR bot IS NULL);
@@ -0,0 +1,7 @@
+-- Emails are unique regardless of case. Accounts that already share an
+-- address with an older one are set aside with their id in front of it, so
+-- support can sort them out.
+UPDATE users u SET email = 'duplicate-' || u.id || '-' || u.email
+WHERE EXISTS (SELECT 1 FROM users o WHERE LOWER(o.email) = LOWER(u.email) AND o.id < u.id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
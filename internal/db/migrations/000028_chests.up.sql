@@ -0,0 +1,13 @@
+-- Chests a player holds. A chest is locked until its unlock is started,
+-- which sets unlocks_at, and is opened once that time has passed or the
+-- player pays gems to skip the rest of the wait.
+CREATE TABLE IF NOT EXISTS chests (
+    id SERIAL PRIMARY KEY,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INT NOT NULL REFERENCES users(id),
+    kind VARCHAR(20) NOT NULL,
+    unlocks_at TIMESTAMP,
+    opened_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_chests_unopened ON chests (user_id) WHERE opened_at IS NULL;
@@ -0,0 +1,3 @@
+UPDATE wallets SET balance = 0 WHERE balance < 0;
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_balance_check;
+ALTER TABLE wallets ADD CONSTRAINT wallets_balance_check CHECK (balance >= 0);
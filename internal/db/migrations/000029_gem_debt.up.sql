@@ -0,0 +1,5 @@
+-- A refunded or charged back gem purchase takes all its gems back, so a
+-- player who already spent them is left owing: their gem balance goes below
+-- zero until later gems repay it. Gold still can't go negative.
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_balance_check;
+ALTER TABLE wallets ADD CONSTRAINT wallets_balance_check CHECK (balance >= 0 OR currency = 'GEMS');
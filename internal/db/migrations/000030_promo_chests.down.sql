@@ -0,0 +1 @@
+ALTER TABLE promo_codes DROP COLUMN IF EXISTS reward_chest;
@@ -0,0 +1,2 @@
+-- A code may also grant a locked chest of reward_chest's kind.
+ALTER TABLE promo_codes ADD COLUMN IF NOT EXISTS reward_chest VARCHAR(20);
@@ -0,0 +1,82 @@
+package redis2
+
+import (
+	"auth/internal/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+)
+
+func ClanChatChannel(clanID uint) string {
+	return fmt.Sprintf("chat:clan:%d", clanID)
+}
+
+// UserChatChannel carries the direct messages a player sends and receives.
+func UserChatChannel(userID uint) string {
+	return fmt.Sprintf("chat:user:%d", userID)
+}
+
+// NotificationChannel carries a single player's notifications;
+// BroadcastNotificationChannel carries notifications sent to everyone.
+func NotificationChannel(userID uint) string {
+	return fmt.Sprintf("notifications:user:%d", userID)
+}
+
+const BroadcastNotificationChannel = "notifications:all"
+
+// DraftChannel carries the state of a player's draft after every pick.
+func DraftChannel(userID uint) string {
+	return fmt.Sprintf("draft:user:%d", userID)
+}
+
+// PublishEvent fans an event out to every server instance with a listener
+// on the channel. Delivery is best effort; history is always read from
+// Postgres.
+func PublishEvent(ctx context.Context, rdbConfig config.RedisConfig, channel string, event interface{}) error {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+	defer rdb.Close()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return rdb.Publish(ctx, channel, payload).Err()
+}
+
+// Subscription holds its own connection for as long as the listener
+// stays subscribed, so it must be closed.
+type Subscription struct {
+	rdb    *redis.Client
+	pubsub *redis.PubSub
+}
+
+func Subscribe(ctx context.Context, rdbConfig config.RedisConfig, channels ...string) (*Subscription, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+
+	pubsub := rdb.Subscribe(ctx, channels...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		rdb.Close()
+		return nil, err
+	}
+	return &Subscription{rdb: rdb, pubsub: pubsub}, nil
+}
+
+// Events yields the raw JSON payloads published to the subscribed channels.
+func (s *Subscription) Events() <-chan *redis.Message {
+	return s.pubsub.Channel()
+}
+
+func (s *Subscription) Close() error {
+	s.pubsub.Close()
+	return s.rdb.Close()
+}
@@ -0,0 +1,51 @@
+package redis2
+
+import (
+	"auth/internal/config"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"time"
+)
+
+// PresenceTTL is how long a player counts as online after their last request.
+const PresenceTTL = time.Minute * 5
+
+func TouchPresence(ctx context.Context, rdbConfig config.RedisConfig, userID uint) error {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+	defer rdb.Close()
+
+	return rdb.Set(ctx, fmt.Sprintf("presence:%d", userID), 1, PresenceTTL).Err()
+}
+
+// GetOnlineUsers reports which of the given users have been seen recently.
+func GetOnlineUsers(ctx context.Context, rdbConfig config.RedisConfig, userIDs []uint) (map[uint]bool, error) {
+	online := make(map[uint]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return online, nil
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+	defer rdb.Close()
+
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = fmt.Sprintf("presence:%d", id)
+	}
+	values, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		online[userIDs[i]] = value != nil
+	}
+	return online, nil
+}
@@ -3,6 +3,7 @@ package redis2
 import (
 	"auth/internal/config"
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"time"
@@ -16,6 +17,7 @@ func SaveVerificationCodeToRedis(ctx context.Context, rdbConfig config.RedisConf
 		Password: rdbConfig.Password,
 		DB:       rdbConfig.DB,
 	})
+	defer rdb.Close()
 	err := rdb.Set(ctx, fmt.Sprintf("verification_code:%s", email), verificationCode, time.Minute*10).Err()
 	if err != nil {
 		return err
@@ -29,6 +31,7 @@ func CheckVerificationCode(ctx context.Context, rdbConfig config.RedisConfig, em
 		Password: rdbConfig.Password,
 		DB:       rdbConfig.DB,
 	})
+	defer rdb.Close()
 
 	val, err := rdb.Get(ctx, fmt.Sprintf("verification_code:%s", email)).Result()
 	if err != nil {
@@ -45,3 +48,149 @@ func CheckVerificationCode(ctx context.Context, rdbConfig config.RedisConfig, em
 
 	return false, nil
 }
+
+func SaveTwoFactorChallenge(ctx context.Context, rdbConfig config.RedisConfig, challenge string, userID uint) error {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+	defer rdb.Close()
+	return rdb.Set(ctx, fmt.Sprintf("two_factor_challenge:%s", challenge), userID, time.Minute*5).Err()
+}
+
+// TakeTwoFactorChallenge returns the user the challenge was issued for and
+// deletes it, so each password login allows a single second-factor attempt.
+func TakeTwoFactorChallenge(ctx context.Context, rdbConfig config.RedisConfig, challenge string) (uint, bool, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+	defer rdb.Close()
+
+	val, err := rdb.GetDel(ctx, fmt.Sprintf("two_factor_challenge:%s", challenge)).Uint64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return uint(val), true, nil
+}
+
+// OAuthState is kept between the authorize redirect and the provider callback.
+// LinkUserID is set when a signed-in user is attaching another identity, and
+// Restore when the login should also restore an account scheduled for deletion.
+type OAuthState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"codeVerifier"`
+	LinkUserID   uint   `json:"linkUserId"`
+	Restore      bool   `json:"restore"`
+}
+
+func SaveOAuthState(ctx context.Context, rdbConfig config.RedisConfig, state string, data OAuthState) error {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+	defer rdb.Close()
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return rdb.Set(ctx, fmt.Sprintf("oauth_state:%s", state), payload, time.Minute*10).Err()
+}
+
+func TakeOAuthState(ctx context.Context, rdbConfig config.RedisConfig, state string) (*OAuthState, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+	defer rdb.Close()
+
+	val, err := rdb.GetDel(ctx, fmt.Sprintf("oauth_state:%s", state)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var data OAuthState
+	if err := json.Unmarshal(val, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+type emailChange struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+// EmailChangeAttempts is how many wrong codes cancel a pending email change.
+const EmailChangeAttempts = 5
+
+func SaveEmailChangeCode(ctx context.Context, rdbConfig config.RedisConfig, userID uint, newEmail, verificationCode string) error {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+	defer rdb.Close()
+	payload, err := json.Marshal(emailChange{Email: newEmail, Code: verificationCode})
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("email_change:%d", userID)
+	if err := rdb.Del(ctx, key+":attempts").Err(); err != nil {
+		return err
+	}
+	return rdb.Set(ctx, key, payload, time.Minute*10).Err()
+}
+
+// CheckEmailChangeCode returns the pending new email when the code matches.
+// After EmailChangeAttempts wrong codes the pending change is deleted, so the
+// six digits can't be guessed within its lifetime.
+func CheckEmailChangeCode(ctx context.Context, rdbConfig config.RedisConfig, userID uint, verificationCode string) (string, bool, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     rdbConfig.Addr,
+		Password: rdbConfig.Password,
+		DB:       rdbConfig.DB,
+	})
+	defer rdb.Close()
+
+	key := fmt.Sprintf("email_change:%d", userID)
+	attemptsKey := key + ":attempts"
+	val, err := rdb.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	var pending emailChange
+	if err := json.Unmarshal(val, &pending); err != nil {
+		return "", false, err
+	}
+	if pending.Code != verificationCode {
+		attempts, err := rdb.Incr(ctx, attemptsKey).Result()
+		if err != nil {
+			return "", false, err
+		}
+		if attempts == 1 {
+			_ = rdb.Expire(ctx, attemptsKey, time.Minute*10)
+		}
+		if attempts >= EmailChangeAttempts {
+			_ = rdb.Del(ctx, key, attemptsKey)
+		}
+		return "", false, nil
+	}
+
+	_ = rdb.Del(ctx, key, attemptsKey)
+	return pending.Email, true, nil
+}
@@ -0,0 +1,160 @@
+// Package redistest runs an in-memory stand-in for Redis, so code that keeps
+// state in Redis can be tested offline. It speaks just enough of the
+// protocol for the commands this service sends, and ignores expiry.
+package redistest
+
+import (
+	"auth/internal/config"
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type Server struct {
+	listener net.Listener
+	mu       sync.Mutex
+	values   map[string]string
+}
+
+// Start serves on a local port until the test ends and returns the config
+// that points a client at it.
+func Start(t testing.TB) (*Server, config.RedisConfig) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start redis stub: %v", err)
+	}
+	server := &Server{listener: listener, values: make(map[string]string)}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server, config.RedisConfig{Addr: listener.Addr().String()}
+}
+
+// Get returns a stored value, for tests that inspect what was saved.
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.run(args)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args = append(args, string(data[:size]))
+	}
+	return args, nil
+}
+
+func bulk(value string, ok bool) string {
+	if !ok {
+		return "$-1\r\n"
+	}
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func integer(n int) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+func (s *Server) run(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToLower(args[0]) {
+	case "ping":
+		return "+PONG\r\n"
+	case "set":
+		s.values[args[1]] = args[2]
+		return "+OK\r\n"
+	case "get":
+		value, ok := s.values[args[1]]
+		return bulk(value, ok)
+	case "getdel":
+		value, ok := s.values[args[1]]
+		delete(s.values, args[1])
+		return bulk(value, ok)
+	case "del":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.values[key]; ok {
+				delete(s.values, key)
+				deleted++
+			}
+		}
+		return integer(deleted)
+	case "incr":
+		n, _ := strconv.Atoi(s.values[args[1]])
+		n++
+		s.values[args[1]] = strconv.Itoa(n)
+		return integer(n)
+	case "expire":
+		_, ok := s.values[args[1]]
+		if ok {
+			return integer(1)
+		}
+		return integer(0)
+	case "mget":
+		reply := fmt.Sprintf("*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			value, ok := s.values[key]
+			reply += bulk(value, ok)
+		}
+		return reply
+	case "publish":
+		return integer(0)
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/pkg/logger"
+	"auth/pkg/utils"
+	"context"
+	"time"
+)
+
+// RunAccountPurge permanently deletes accounts whose grace period has ended.
+// It runs once at start and then every PurgeInterval until ctx is cancelled.
+func RunAccountPurge(ctx context.Context, repo repository.UserRepo, accountConfig config.AccountConfig, clock utils.Clock) {
+	interval := accountConfig.PurgeInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		PurgeDeletedAccounts(repo, accountConfig, clock)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func PurgeDeletedAccounts(repo repository.UserRepo, accountConfig config.AccountConfig, clock utils.Clock) int {
+	cutoff := clock.Now().Add(-accountConfig.DeletionGrace)
+	ids, err := repo.GetUsersDeletedBefore(cutoff)
+	if err != nil {
+		logger.GetLogger().Error("Failed to list accounts to purge:", err)
+		return 0
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := repo.DeleteUser(id); err != nil {
+			logger.GetLogger().Error("Failed to purge account:", err)
+			continue
+		}
+		purged++
+	}
+	if purged > 0 {
+		logger.GetLogger().Info("Purged deleted accounts: ", purged)
+	}
+	return purged
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"auth/internal/bots"
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/utils"
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RunBotFill starts a 2v2 match with bots in the empty seats whenever a
+// player has waited QueueTimeout in the queue, checking every Check until
+// ctx is cancelled.
+func RunBotFill(ctx context.Context, partyRepo repository.PartyRepo, gameRepo repository.GameRepo, botConfig config.BotConfig, notifier notifications.Notifier, clock utils.Clock) {
+	interval := botConfig.Check
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		FillQueueWithBots(ctx, partyRepo, gameRepo, botConfig, notifier, clock)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func FillQueueWithBots(ctx context.Context, partyRepo repository.PartyRepo, gameRepo repository.GameRepo, botConfig config.BotConfig, notifier notifications.Notifier, clock utils.Clock) bool {
+	queuedBefore := clock.Now().Add(-botConfig.QueueTimeout)
+	since, waiting, err := partyRepo.GetQueueWaitStart()
+	if err != nil {
+		logger.GetLogger().Error("Failed to check team queue:", err)
+		return false
+	}
+	if !waiting || since.After(queuedBefore) {
+		return false
+	}
+
+	heroes, spells, err := gameRepo.GetCatalog()
+	if err != nil {
+		logger.GetLogger().Error("Failed to get catalog:", err)
+		return false
+	}
+	// A lone player needs a teammate and two opponents.
+	rng := rand.New(rand.NewSource(clock.Now().UnixNano()))
+	var players []models.MatchPlayer
+	for i := 0; i < 3; i++ {
+		deck := bots.BuildDeck(heroes, spells, botConfig.QueueDifficulty, botConfig.DeckSize, rng)
+		if len(deck.Heroes) == 0 {
+			logger.GetLogger().Warn("Can't fill the team queue, the catalog has no heroes")
+			return false
+		}
+		players = append(players, models.MatchPlayer{Username: "Bot", Bot: botConfig.QueueDifficulty, Deck: deck})
+	}
+
+	match, started, err := partyRepo.FillWithBots(queuedBefore, players)
+	if err != nil {
+		logger.GetLogger().Error("Failed to fill team queue with bots:", err)
+		return false
+	}
+	if !started {
+		return false
+	}
+
+	logger.GetLogger().Info("Team battle started with bots: ", match.ID)
+	for _, player := range match.Players {
+		if player.Bot != "" {
+			continue
+		}
+		notifier.Notify(ctx, models.Notification{
+			UserID: player.UserID,
+			Kind:   models.NotificationMatchFound,
+			Title:  "Your 2v2 battle is ready",
+			Body:   "Bots took the seats nobody else did. The battle doesn't count towards quests.",
+			Data:   map[string]interface{}{"matchId": match.ID},
+		})
+	}
+	return true
+}
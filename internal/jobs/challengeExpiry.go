@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/pkg/logger"
+	"auth/pkg/utils"
+	"context"
+	"time"
+)
+
+// RunChallengeExpiry marks unanswered friendly challenges as expired every
+// ExpiryInterval until ctx is cancelled. Accepting already checks the expiry
+// time, so this only keeps the stored status accurate.
+func RunChallengeExpiry(ctx context.Context, repo repository.MatchRepo, matchConfig config.MatchConfig, clock utils.Clock) {
+	interval := matchConfig.ExpiryInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := repo.ExpireChallenges(clock.Now())
+		if err != nil {
+			logger.GetLogger().Error("Failed to expire challenges:", err)
+		} else if expired > 0 {
+			logger.GetLogger().Info("Expired challenges: ", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/pkg/logger"
+	"auth/pkg/utils"
+	"context"
+	"time"
+)
+
+// RunDraftPicks picks a random offered card for every draft player whose
+// pick time has run out, every Check until ctx is cancelled, so that a
+// draft always finishes even when a player walks away.
+func RunDraftPicks(ctx context.Context, repo repository.DraftRepo, draftConfig config.DraftConfig, redisConfig config.RedisConfig, notifier notifications.Notifier, clock utils.Clock) {
+	interval := draftConfig.Check
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		AutoPickDrafts(ctx, repo, redisConfig, notifier, clock)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func AutoPickDrafts(ctx context.Context, repo repository.DraftRepo, redisConfig config.RedisConfig, notifier notifications.Notifier, clock utils.Clock) int {
+	now := clock.Now()
+	ids, err := repo.GetOverdueDrafts(now)
+	if err != nil {
+		logger.GetLogger().Error("Failed to list overdue drafts:", err)
+		return 0
+	}
+
+	picked := 0
+	for _, id := range ids {
+		draft, ok, err := repo.AutoPick(id, now)
+		if err != nil {
+			logger.GetLogger().Error("Failed to auto-pick draft card:", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		picked++
+		notifications.PublishDraft(ctx, notifier, redisConfig, draft)
+	}
+	return picked
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/pkg/email"
+	"auth/pkg/logger"
+	"auth/pkg/utils"
+	"context"
+	"time"
+)
+
+// RunNotificationDigest emails opted-in players their unread notifications
+// every DigestCheck until ctx is cancelled.
+func RunNotificationDigest(ctx context.Context, repo repository.NotificationRepo, notificationConfig config.NotificationConfig, emailConfig config.EmailConfig, clock utils.Clock) {
+	interval := notificationConfig.DigestCheck
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		SendNotificationDigests(repo, notificationConfig, emailConfig, clock)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendNotificationDigests claims each digest before mailing it, so with
+// several instances running only one sends it. A digest whose email fails
+// is not retried before the next interval.
+func SendNotificationDigests(repo repository.NotificationRepo, notificationConfig config.NotificationConfig, emailConfig config.EmailConfig, clock utils.Clock) int {
+	now := clock.Now()
+	cutoff := now.Add(-notificationConfig.DigestInterval)
+	digests, err := repo.GetDueDigests(cutoff, notificationConfig.DigestSize)
+	if err != nil {
+		logger.GetLogger().Error("Failed to list notification digests:", err)
+		return 0
+	}
+
+	sent := 0
+	for _, digest := range digests {
+		claimed, err := repo.ClaimDigest(digest.UserID, cutoff, now)
+		if err != nil {
+			logger.GetLogger().Error("Failed to claim notification digest:", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		titles := make([]string, 0, len(digest.Notifications))
+		for _, notification := range digest.Notifications {
+			titles = append(titles, notification.Title)
+		}
+		if err := email.SendNotificationDigestEmail(digest.Email, digest.Username, titles, emailConfig); err != nil {
+			logger.GetLogger().Error("Failed to send notification digest:", err)
+			continue
+		}
+		sent++
+	}
+	if sent > 0 {
+		logger.GetLogger().Info("Sent notification digests: ", sent)
+	}
+	return sent
+}
@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RunSeasonClose settles seasons once they end, every CloseCheck until ctx is
+// cancelled. Closing is idempotent, so several servers can run it at once.
+func RunSeasonClose(ctx context.Context, repo repository.SeasonRepo, seasonConfig config.SeasonConfig, notifier notifications.Notifier, clock utils.Clock) {
+	interval := seasonConfig.CloseCheck
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		CloseEndedSeasons(ctx, repo, notifier, clock)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func CloseEndedSeasons(ctx context.Context, repo repository.SeasonRepo, notifier notifications.Notifier, clock utils.Clock) int {
+	now := clock.Now()
+	seasons, err := repo.GetEndedSeasons(now)
+	if err != nil {
+		logger.GetLogger().Error("Failed to list ended seasons:", err)
+		return 0
+	}
+
+	closed := 0
+	for _, season := range seasons {
+		rewarded, err := repo.CloseSeason(season.ID, now)
+		if errors.Is(err, helper.ErrSeasonClosed) {
+			continue
+		} else if err != nil {
+			logger.GetLogger().Error("Failed to close season:", err)
+			continue
+		}
+		closed++
+		logger.GetLogger().Info("Closed season: ", season.Name)
+
+		for _, result := range rewarded {
+			notifier.Notify(ctx, models.Notification{
+				UserID: result.UserID,
+				Kind:   models.NotificationSeasonEnded,
+				Title:  fmt.Sprintf("%s ended: you finished #%d", result.SeasonName, result.Rank),
+				Body:   fmt.Sprintf("You earned %d gold.", result.RewardGold),
+				Data:   map[string]interface{}{"seasonId": result.SeasonID},
+			})
+		}
+	}
+	return closed
+}
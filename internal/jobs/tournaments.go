@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/utils"
+	"context"
+	"fmt"
+	"time"
+)
+
+// RunTournaments advances tournaments every Check until ctx is cancelled.
+// Advancing is idempotent, so several servers can run it at once and a
+// restart never pairs or pays out a round twice.
+func RunTournaments(ctx context.Context, repo repository.TournamentRepo, tournamentConfig config.TournamentConfig, notifier notifications.Notifier, clock utils.Clock) {
+	interval := tournamentConfig.Check
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		AdvanceTournaments(ctx, repo, notifier, clock)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func AdvanceTournaments(ctx context.Context, repo repository.TournamentRepo, notifier notifications.Notifier, clock utils.Clock) int {
+	now := clock.Now()
+	ids, err := repo.GetDueTournaments(now)
+	if err != nil {
+		logger.GetLogger().Error("Failed to list due tournaments:", err)
+		return 0
+	}
+
+	advanced := 0
+	for _, id := range ids {
+		update, err := repo.AdvanceTournament(id, now)
+		if err != nil {
+			logger.GetLogger().Error("Failed to advance tournament:", err)
+			continue
+		}
+		if len(update.Pairings) == 0 && len(update.Standings) == 0 && update.Tournament.Status != models.TournamentCancelled {
+			continue
+		}
+		advanced++
+		logger.GetLogger().Info("Advanced tournament: ", update.Tournament.Name)
+		notifyTournamentUpdate(ctx, notifier, update)
+	}
+	return advanced
+}
+
+// notifyTournamentUpdate tells players about new pairings, their final place
+// or a cancellation.
+func notifyTournamentUpdate(ctx context.Context, notifier notifications.Notifier, update models.TournamentUpdate) {
+	tournament := update.Tournament
+	data := map[string]interface{}{"tournamentId": tournament.ID}
+
+	for _, pairing := range update.Pairings {
+		if !pairing.Player2ID.Valid {
+			notifier.Notify(ctx, models.Notification{
+				UserID: uint(pairing.Player1ID.Int64),
+				Kind:   models.NotificationTournament,
+				Title:  fmt.Sprintf("%s round %d: you have a bye", tournament.Name, pairing.Round),
+				Body:   "You go through this round without playing.",
+				Data:   data,
+			})
+			continue
+		}
+		matchData := map[string]interface{}{"tournamentId": tournament.ID, "matchId": pairing.MatchID.Int64}
+		notifier.Notify(ctx, models.Notification{
+			UserID: uint(pairing.Player1ID.Int64),
+			Kind:   models.NotificationTournament,
+			Title:  fmt.Sprintf("%s round %d: you play %s", tournament.Name, pairing.Round, pairing.Player2Name),
+			Data:   matchData,
+		})
+		notifier.Notify(ctx, models.Notification{
+			UserID: uint(pairing.Player2ID.Int64),
+			Kind:   models.NotificationTournament,
+			Title:  fmt.Sprintf("%s round %d: you play %s", tournament.Name, pairing.Round, pairing.Player1Name),
+			Data:   matchData,
+		})
+	}
+
+	for _, player := range update.Standings {
+		notification := models.Notification{
+			UserID: player.UserID,
+			Kind:   models.NotificationTournament,
+			Title:  fmt.Sprintf("%s is over: you finished #%d", tournament.Name, player.Place.Int64),
+			Data:   data,
+		}
+		if player.PrizeGold > 0 {
+			notification.Body = fmt.Sprintf("You won %d gold.", player.PrizeGold)
+		}
+		notifier.Notify(ctx, notification)
+	}
+
+	for _, userID := range update.Refunded {
+		notifier.Notify(ctx, models.Notification{
+			UserID: userID,
+			Kind:   models.NotificationTournament,
+			Title:  fmt.Sprintf("%s was cancelled", tournament.Name),
+			Body:   "Your entry fee has been refunded.",
+			Data:   data,
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package notifications
+
+import (
+	"auth/internal/config"
+	redis "auth/internal/db/redis"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"context"
+	"database/sql"
+)
+
+// PublishDraft pushes the draft to both players' live streams after a pick.
+// Picks come too often to be stored as notifications; only the end of the
+// draft, when the match is ready, is.
+func PublishDraft(ctx context.Context, notifier Notifier, redisConfig config.RedisConfig, draft models.Draft) {
+	event := dto.NewDraft(draft)
+	for _, player := range []sql.NullInt64{draft.Player1ID, draft.Player2ID} {
+		if !player.Valid {
+			continue
+		}
+		userID := uint(player.Int64)
+		if err := redis.PublishEvent(ctx, redisConfig, redis.DraftChannel(userID), event); err != nil {
+			logger.GetLogger().Warn("Failed to push draft:", err)
+		}
+		if draft.Status == models.DraftFinished {
+			notifier.Notify(ctx, models.Notification{
+				UserID: userID,
+				Kind:   models.NotificationMatchFound,
+				Title:  "Your draft is done, the battle is ready",
+				Data:   map[string]interface{}{"draftId": draft.ID, "matchId": draft.MatchID.Int64},
+			})
+		}
+	}
+}
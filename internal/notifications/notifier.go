@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"auth/internal/config"
+	redis "auth/internal/db/redis"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"context"
+)
+
+// Notifier is what the rest of the app uses to tell a player something
+// happened. Notifying is best effort: a failure is logged and never undoes
+// the action that caused it.
+type Notifier interface {
+	Notify(ctx context.Context, notification models.Notification)
+}
+
+// Service stores notifications and pushes them to the player's live stream
+// when one is open.
+type Service struct {
+	Repo        repository.NotificationRepo
+	RedisConfig config.RedisConfig
+}
+
+func NewService(repo repository.NotificationRepo, redisConfig config.RedisConfig) *Service {
+	return &Service{Repo: repo, RedisConfig: redisConfig}
+}
+
+func (s *Service) Notify(ctx context.Context, notification models.Notification) {
+	if err := s.Repo.CreateNotification(&notification); err != nil {
+		logger.GetLogger().Error("Failed to create notification:", err)
+		return
+	}
+	if err := redis.PublishEvent(ctx, s.RedisConfig, redis.NotificationChannel(notification.UserID), dto.NewNotification(notification)); err != nil {
+		logger.GetLogger().Warn("Failed to push notification:", err)
+	}
+}
+
+// Broadcast sends the notification to every player and returns how many
+// received it.
+func (s *Service) Broadcast(ctx context.Context, notification models.Notification) (int64, error) {
+	sent, err := s.Repo.Broadcast(notification)
+	if err != nil {
+		return 0, err
+	}
+	// Streams get the content only; each player's stored copy has its own id.
+	if err := redis.PublishEvent(ctx, s.RedisConfig, redis.BroadcastNotificationChannel, dto.NewNotification(notification)); err != nil {
+		logger.GetLogger().Warn("Failed to push broadcast notification:", err)
+	}
+	return sent, nil
+}
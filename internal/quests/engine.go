@@ -0,0 +1,52 @@
+package quests
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/utils"
+	"context"
+)
+
+// Recorder is how the rest of the app reports things players did. Recording
+// is best effort: a failure is logged and never undoes the action itself.
+type Recorder interface {
+	Record(ctx context.Context, event models.QuestEvent)
+}
+
+// Engine counts events toward the matching daily quests and achievements
+// and tells the player when one is ready to claim.
+type Engine struct {
+	Repo     repository.QuestRepo
+	Config   config.QuestConfig
+	Clock    utils.Clock
+	Notifier notifications.Notifier
+}
+
+func NewEngine(repo repository.QuestRepo, questConfig config.QuestConfig, clock utils.Clock, notifier notifications.Notifier) *Engine {
+	return &Engine{Repo: repo, Config: questConfig, Clock: clock, Notifier: notifier}
+}
+
+func (e *Engine) Record(ctx context.Context, event models.QuestEvent) {
+	if event.Count <= 0 {
+		event.Count = 1
+	}
+
+	now := e.Clock.Now()
+	completed, err := e.Repo.RecordEvent(event, utils.DailyPeriod(now, e.Config.ResetOffset), e.Config.DailyCount, now)
+	if err != nil {
+		logger.GetLogger().Error("Failed to record quest event:", err)
+		return
+	}
+
+	for _, definition := range completed {
+		e.Notifier.Notify(ctx, models.Notification{
+			UserID: event.UserID,
+			Kind:   models.NotificationQuestCompleted,
+			Title:  "Quest complete: " + definition.Title,
+			Data:   map[string]interface{}{"questCode": definition.Code},
+		})
+	}
+}
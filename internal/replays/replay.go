@@ -0,0 +1,181 @@
+// Package replays stores and replays matches. A replay is the match's seed
+// and the timestamped log of every card its players deployed; together with
+// the deck snapshots the match already keeps, that is all Simulate needs to
+// play the match again, tick for tick, on the server or on a client.
+package replays
+
+import (
+	"auth/internal/rest/models"
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Input is a card deployed during the match. At is the time since the match
+// started, Seat the player's index in the match's player list and Position
+// how far up the lane in thousandths, counted from the player's own towers.
+type Input struct {
+	At       uint32
+	Seat     int
+	Kind     string
+	CardID   uint
+	Lane     int
+	Position int
+}
+
+// logFormat is the first byte of every encoded log, so the format can change
+// without breaking stored replays.
+const logFormat = 1
+
+// Encode packs the inputs into the compact form replays are stored in: each
+// input as varints, its time as the delta from the previous one, deflated.
+// The inputs must be in time order.
+func Encode(inputs []Input) ([]byte, error) {
+	raw := []byte{logFormat}
+	var last uint32
+	for i, input := range inputs {
+		if input.At < last {
+			return nil, fmt.Errorf("input %d is out of time order", i)
+		}
+		kind, err := kindByte(input.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %v", i, err)
+		}
+		if input.Seat < 0 || input.Lane < 0 || input.Position < 0 {
+			return nil, fmt.Errorf("input %d has a negative seat, lane or position", i)
+		}
+		raw = binary.AppendUvarint(raw, uint64(input.At-last))
+		raw = binary.AppendUvarint(raw, uint64(input.Seat))
+		raw = append(raw, kind)
+		raw = binary.AppendUvarint(raw, uint64(input.CardID))
+		raw = binary.AppendUvarint(raw, uint64(input.Lane))
+		raw = binary.AppendUvarint(raw, uint64(input.Position))
+		last = input.At
+	}
+
+	var out bytes.Buffer
+	writer, err := flate.NewWriter(&out, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Decode unpacks a log written by Encode.
+func Decode(log []byte) ([]Input, error) {
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(log)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate replay log: %v", err)
+	}
+	if len(raw) == 0 || raw[0] != logFormat {
+		return nil, fmt.Errorf("unknown replay log format")
+	}
+
+	reader := bytes.NewReader(raw[1:])
+	var inputs []Input
+	var at uint32
+	for reader.Len() > 0 {
+		var fields [6]uint64
+		for i := range fields {
+			if i == 2 {
+				kind, err := reader.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("truncated replay log")
+				}
+				fields[i] = uint64(kind)
+				continue
+			}
+			value, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return nil, fmt.Errorf("truncated replay log")
+			}
+			fields[i] = value
+		}
+		kind, ok := kindName(byte(fields[2]))
+		if !ok {
+			return nil, fmt.Errorf("unknown card kind in replay log")
+		}
+		at += uint32(fields[0])
+		inputs = append(inputs, Input{
+			At:       at,
+			Seat:     int(fields[1]),
+			Kind:     kind,
+			CardID:   uint(fields[3]),
+			Lane:     int(fields[4]),
+			Position: int(fields[5]),
+		})
+	}
+	return inputs, nil
+}
+
+func kindByte(kind string) (byte, error) {
+	switch kind {
+	case models.DraftCardHero:
+		return 0, nil
+	case models.DraftCardSpell:
+		return 1, nil
+	}
+	return 0, fmt.Errorf("unknown card kind %q", kind)
+}
+
+func kindName(kind byte) (string, bool) {
+	switch kind {
+	case 0:
+		return models.DraftCardHero, true
+	case 1:
+		return models.DraftCardSpell, true
+	}
+	return "", false
+}
+
+// cardStats are the stats of a card that the simulation uses. Names and
+// prices can change without changing how a match plays.
+type cardStats struct {
+	Kind        string `json:"kind"`
+	ID          uint   `json:"id"`
+	Hitpoint    int32  `json:"hitpoint,omitempty"`
+	Damage      int32  `json:"damage"`
+	DamageTower int32  `json:"damageTower,omitempty"`
+	CostElixir  int32  `json:"costElixir,omitempty"`
+	Speed       int32  `json:"speed,omitempty"`
+	Area        int32  `json:"area,omitempty"`
+}
+
+// StatsVersion fingerprints the card stats in the players' deck snapshots.
+// Matches played with the same stats share a version, so a client can tell
+// whether the cards it has cached still replay the match the same way.
+func StatsVersion(players []models.MatchPlayer) string {
+	var stats [][]cardStats
+	for _, player := range players {
+		var deck []cardStats
+		for _, hero := range player.Deck.Heroes {
+			deck = append(deck, cardStats{
+				Kind:        models.DraftCardHero,
+				ID:          hero.ID,
+				Hitpoint:    hero.Hitpoint,
+				Damage:      hero.Damage,
+				DamageTower: hero.DamageTower,
+				CostElixir:  hero.CostElixir,
+				Speed:       hero.Speed,
+			})
+		}
+		for _, spell := range player.Deck.Spells {
+			deck = append(deck, cardStats{Kind: models.DraftCardSpell, ID: spell.ID, Damage: spell.Damage, Area: spell.Area})
+		}
+		stats = append(stats, deck)
+	}
+	encoded, _ := json.Marshal(stats)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:8])
+}
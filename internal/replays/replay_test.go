@@ -0,0 +1,127 @@
+package replays
+
+import (
+	"auth/internal/rest/models"
+	"bytes"
+	"compress/flate"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// deflate compresses raw the way Encode does, for logs Encode won't write.
+func deflate(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	writer, err := flate.NewWriter(&out, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []Input
+	}{
+		{"empty", nil},
+		{"one hero", []Input{{At: 0, Seat: 0, Kind: models.DraftCardHero, CardID: 1, Lane: 0, Position: 500}}},
+		{"same tick", []Input{
+			{At: 1200, Seat: 0, Kind: models.DraftCardHero, CardID: 3, Lane: 1, Position: 250},
+			{At: 1200, Seat: 1, Kind: models.DraftCardSpell, CardID: 7, Lane: 0, Position: 900},
+		}},
+		{"whole match", []Input{
+			{At: 0, Seat: 1, Kind: models.DraftCardSpell, CardID: 2, Lane: 2, Position: 1000},
+			{At: 90000, Seat: 3, Kind: models.DraftCardHero, CardID: 300, Lane: 1, Position: 0},
+			{At: MatchMs, Seat: 0, Kind: models.DraftCardHero, CardID: math.MaxUint32, Lane: 0, Position: 499},
+		}},
+		{"large values", []Input{
+			{At: math.MaxUint32, Seat: math.MaxInt32, Kind: models.DraftCardSpell, CardID: math.MaxUint32, Lane: math.MaxInt32, Position: math.MaxInt32},
+		}},
+	}
+	for _, tt := range tests {
+		log, err := Encode(tt.inputs)
+		if err != nil {
+			t.Errorf("%s: Encode: %v", tt.name, err)
+			continue
+		}
+		got, err := Decode(log)
+		if err != nil {
+			t.Errorf("%s: Decode: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.inputs) {
+			t.Errorf("%s: Decode(Encode(inputs)) = %+v, want %+v", tt.name, got, tt.inputs)
+		}
+	}
+}
+
+func TestEncodeRejectsInvalidInputs(t *testing.T) {
+	valid := Input{At: 100, Seat: 0, Kind: models.DraftCardHero, CardID: 1, Lane: 0, Position: 100}
+	with := func(change func(*Input)) Input {
+		input := valid
+		change(&input)
+		return input
+	}
+
+	tests := []struct {
+		name   string
+		inputs []Input
+		want   string
+	}{
+		{"out of order", []Input{valid, with(func(in *Input) { in.At = 99 })}, "input 1 is out of time order"},
+		{"unknown kind", []Input{with(func(in *Input) { in.Kind = "TROOP" })}, `input 0: unknown card kind "TROOP"`},
+		{"negative seat", []Input{with(func(in *Input) { in.Seat = -1 })}, "input 0 has a negative"},
+		{"negative lane", []Input{valid, with(func(in *Input) { in.Lane = -1 })}, "input 1 has a negative"},
+		{"negative position", []Input{with(func(in *Input) { in.Position = -5 })}, "input 0 has a negative"},
+	}
+	for _, tt := range tests {
+		_, err := Encode(tt.inputs)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Encode error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestDecodeRejectsBrokenLogs(t *testing.T) {
+	tests := []struct {
+		name string
+		log  []byte
+		want string
+	}{
+		{"not deflated", []byte("not a replay"), "failed to inflate replay log"},
+		{"empty", deflate(t, nil), "unknown replay log format"},
+		{"future format", deflate(t, []byte{logFormat + 1, 0, 0, 0, 1, 0, 0}), "unknown replay log format"},
+		{"truncated input", deflate(t, []byte{logFormat, 0, 0, 0, 1}), "truncated replay log"},
+		{"missing kind", deflate(t, []byte{logFormat, 0, 0}), "truncated replay log"},
+		{"unterminated varint", deflate(t, []byte{logFormat, 0x80}), "truncated replay log"},
+		{"unknown kind", deflate(t, []byte{logFormat, 0, 0, 9, 1, 0, 0}), "unknown card kind in replay log"},
+	}
+	for _, tt := range tests {
+		_, err := Decode(tt.log)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Decode error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestStatsVersionFollowsCardStats(t *testing.T) {
+	players := testPlayers()
+	version := StatsVersion(players)
+	if again := StatsVersion(testPlayers()); again != version {
+		t.Errorf("StatsVersion changed between identical decks: %s and %s", version, again)
+	}
+
+	players[1].Deck.Heroes[0].Damage++
+	if changed := StatsVersion(players); changed == version {
+		t.Error("StatsVersion didn't change when a hero's damage did")
+	}
+}
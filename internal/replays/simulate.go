@@ -0,0 +1,408 @@
+package replays
+
+import (
+	"auth/internal/bots"
+	"auth/internal/rest/models"
+	"fmt"
+)
+
+// The rules of the simulated battle. Clients that re-simulate a replay must
+// use the same numbers. Times are in milliseconds, elixir in thousandths and
+// positions in thousandths of a lane, from side 1's towers at 0 to side 2's
+// at LaneLength.
+const (
+	TickMs        = 100
+	MatchMs       = 180000
+	LaneLength    = 1000
+	StartElixir   = 5000
+	MaxElixir     = 10000
+	ElixirPerTick = 36
+	AttackMs      = 1000
+	UnitRange     = 60
+	TowerRange    = 250
+	TowerDamage   = 100
+	TowerHitpoint = 3000
+	KingHitpoint  = 4000
+)
+
+// king is the index of a side's king tower, after its lane towers.
+const king = bots.Lanes
+
+// Outcome is how a simulated match ended. WinningSide is 1 or 2, or 0 for a
+// draw; Crowns counts the enemy towers each side destroyed.
+type Outcome struct {
+	WinningSide int
+	Crowns      [2]int
+	EndedAt     uint32
+}
+
+// rng is xorshift64*, simple enough for any client to reproduce exactly.
+type rng struct {
+	state uint64
+}
+
+func newRNG(seed int64) *rng {
+	state := uint64(seed)
+	if state == 0 {
+		state = 0x9E3779B97F4A7C15
+	}
+	return &rng{state}
+}
+
+func (r *rng) next() uint64 {
+	r.state ^= r.state >> 12
+	r.state ^= r.state << 25
+	r.state ^= r.state >> 27
+	return r.state * 0x2545F4914F6CDD1D
+}
+
+// roll varies damage by up to a tenth either way.
+func (r *rng) roll(damage int32) int32 {
+	return damage * int32(90+r.next()%21) / 100
+}
+
+type unit struct {
+	side        int
+	lane        int
+	position    int
+	hitpoint    int32
+	damage      int32
+	damageTower int32
+	step        int
+	cooldown    int
+}
+
+type tower struct {
+	hitpoint int32
+	cooldown int
+}
+
+type battle struct {
+	rng    *rng
+	sides  []int
+	decks  []models.DeckSnapshot
+	elixir []int
+	units  []*unit
+	towers [2][bots.Lanes + 1]tower
+}
+
+// Sides returns the side each seat plays on: the team in team matches and
+// otherwise side 1 for the first player and side 2 for the second.
+func Sides(players []models.MatchPlayer) ([]int, error) {
+	sides := make([]int, len(players))
+	for seat, player := range players {
+		side := seat + 1
+		if player.Team != 0 {
+			side = int(player.Team)
+		}
+		if side != 1 && side != 2 {
+			return nil, fmt.Errorf("seat %d has no side", seat)
+		}
+		sides[seat] = side
+	}
+	return sides, nil
+}
+
+// Simulate plays the match out from its seed, its players' deck snapshots
+// and the inputs, and reports how it ended. It fails on an input the rules
+// don't allow, such as a card the player doesn't hold or can't afford yet.
+func Simulate(seed int64, players []models.MatchPlayer, inputs []Input) (Outcome, error) {
+	sides, err := Sides(players)
+	if err != nil {
+		return Outcome{}, err
+	}
+	b := &battle{rng: newRNG(seed), sides: sides}
+	for _, player := range players {
+		b.decks = append(b.decks, player.Deck)
+		b.elixir = append(b.elixir, StartElixir)
+	}
+	for side := range b.towers {
+		for i := range b.towers[side] {
+			b.towers[side][i].hitpoint = TowerHitpoint
+		}
+		b.towers[side][king].hitpoint = KingHitpoint
+	}
+
+	next := 0
+	for now := 0; now < MatchMs; now += TickMs {
+		for ; next < len(inputs) && int(inputs[next].At) <= now; next++ {
+			if err := b.apply(inputs[next]); err != nil {
+				return Outcome{}, fmt.Errorf("input %d at %dms: %v", next, inputs[next].At, err)
+			}
+		}
+		b.fight()
+		for seat := range b.elixir {
+			b.elixir[seat] = min(b.elixir[seat]+ElixirPerTick, MaxElixir)
+		}
+
+		lost1, lost2 := b.towers[0][king].hitpoint <= 0, b.towers[1][king].hitpoint <= 0
+		if lost1 || lost2 {
+			outcome := b.outcome(uint32(now + TickMs))
+			switch {
+			case lost1 && lost2:
+				outcome.WinningSide = 0
+			case lost1:
+				outcome.WinningSide = 2
+			default:
+				outcome.WinningSide = 1
+			}
+			if next < len(inputs) {
+				return Outcome{}, fmt.Errorf("input %d comes after the match ended", next)
+			}
+			return outcome, nil
+		}
+	}
+	if next < len(inputs) {
+		return Outcome{}, fmt.Errorf("input %d comes after the match ended", next)
+	}
+
+	outcome := b.outcome(MatchMs)
+	switch {
+	case outcome.Crowns[0] > outcome.Crowns[1]:
+		outcome.WinningSide = 1
+	case outcome.Crowns[1] > outcome.Crowns[0]:
+		outcome.WinningSide = 2
+	}
+	return outcome, nil
+}
+
+func (b *battle) outcome(endedAt uint32) Outcome {
+	outcome := Outcome{EndedAt: endedAt}
+	for side := range b.towers {
+		for _, t := range b.towers[side] {
+			if t.hitpoint <= 0 {
+				outcome.Crowns[1-side]++
+			}
+		}
+	}
+	return outcome
+}
+
+// absolute turns a position counted from the side's own towers into one
+// counted from side 1's.
+func absolute(side, position int) int {
+	if side == 1 {
+		return position
+	}
+	return LaneLength - position
+}
+
+// enemyTowers is where the towers the side attacks stand.
+func enemyTowers(side int) int {
+	if side == 1 {
+		return LaneLength
+	}
+	return 0
+}
+
+func distance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func (b *battle) apply(input Input) error {
+	if input.Seat < 0 || input.Seat >= len(b.decks) {
+		return fmt.Errorf("no player in seat %d", input.Seat)
+	}
+	if input.Lane < 0 || input.Lane >= bots.Lanes {
+		return fmt.Errorf("no lane %d", input.Lane)
+	}
+	side, deck := b.sides[input.Seat], b.decks[input.Seat]
+
+	switch input.Kind {
+	case models.DraftCardHero:
+		var hero *models.Hero
+		for i := range deck.Heroes {
+			if deck.Heroes[i].ID == input.CardID {
+				hero = &deck.Heroes[i]
+			}
+		}
+		if hero == nil {
+			return fmt.Errorf("hero %d is not in the deck", input.CardID)
+		}
+		if input.Position < 0 || input.Position > LaneLength/2 {
+			return fmt.Errorf("heroes are deployed in the player's own half")
+		}
+		if err := b.spend(input.Seat, int(hero.CostElixir)); err != nil {
+			return err
+		}
+		b.units = append(b.units, &unit{
+			side:        side,
+			lane:        input.Lane,
+			position:    absolute(side, input.Position),
+			hitpoint:    hero.Hitpoint,
+			damage:      hero.Damage,
+			damageTower: hero.DamageTower,
+			step:        max(1, min(int(hero.Speed), 20)),
+		})
+	case models.DraftCardSpell:
+		var spell *models.Spell
+		for i := range deck.Spells {
+			if deck.Spells[i].ID == input.CardID {
+				spell = &deck.Spells[i]
+			}
+		}
+		if spell == nil {
+			return fmt.Errorf("spell %d is not in the deck", input.CardID)
+		}
+		if input.Position < 0 || input.Position > LaneLength {
+			return fmt.Errorf("position %d is off the lane", input.Position)
+		}
+		if err := b.spend(input.Seat, bots.DefaultSpellElixir); err != nil {
+			return err
+		}
+		b.cast(side, input.Lane, absolute(side, input.Position), *spell)
+	default:
+		return fmt.Errorf("unknown card kind %q", input.Kind)
+	}
+	return nil
+}
+
+func (b *battle) spend(seat, cost int) error {
+	if b.elixir[seat] < cost*1000 {
+		return fmt.Errorf("seat %d can't afford the card yet", seat)
+	}
+	b.elixir[seat] -= cost * 1000
+	return nil
+}
+
+// cast hits every enemy unit in the spell's area, and a quarter as hard the
+// enemy tower guarding the lane if it stands in the area too.
+func (b *battle) cast(side, lane, position int, spell models.Spell) {
+	radius := max(20, int(spell.Area)*10)
+	for _, u := range b.units {
+		if u.side != side && u.lane == lane && distance(u.position, position) <= radius {
+			u.hitpoint -= b.rng.roll(spell.Damage)
+		}
+	}
+	if distance(enemyTowers(side), position) <= radius {
+		target := b.target(side, lane)
+		target.hitpoint -= b.rng.roll(spell.Damage) / 4
+	}
+	b.bury()
+}
+
+// target is the enemy tower a side attacks in the lane: the lane's tower
+// while it stands, then the king.
+func (b *battle) target(side, lane int) *tower {
+	enemy := &b.towers[2-side]
+	if enemy[lane].hitpoint > 0 {
+		return &enemy[lane]
+	}
+	return &enemy[king]
+}
+
+// fight plays one tick: units attack the nearest enemy unit in reach, or the
+// enemy tower once they stand at it, and otherwise march on; then towers
+// shoot the nearest enemy unit in range, the king only in lanes whose tower
+// has fallen.
+func (b *battle) fight() {
+	for _, u := range b.units {
+		if u.hitpoint <= 0 {
+			continue
+		}
+		u.cooldown -= TickMs
+		if enemy := b.nearest(u.side, u.lane, u.position, UnitRange); enemy != nil {
+			if u.cooldown <= 0 {
+				enemy.hitpoint -= b.rng.roll(u.damage)
+				u.cooldown = AttackMs
+			}
+			continue
+		}
+		goal := enemyTowers(u.side)
+		if distance(u.position, goal) <= UnitRange {
+			if u.cooldown <= 0 {
+				b.target(u.side, u.lane).hitpoint -= b.rng.roll(u.damageTower)
+				u.cooldown = AttackMs
+			}
+			continue
+		}
+		if goal > u.position {
+			u.position = min(u.position+u.step, goal)
+		} else {
+			u.position = max(u.position-u.step, goal)
+		}
+	}
+
+	for side := range b.towers {
+		for i := range b.towers[side] {
+			t := &b.towers[side][i]
+			if t.hitpoint <= 0 {
+				continue
+			}
+			t.cooldown -= TickMs
+			if t.cooldown > 0 {
+				continue
+			}
+			position := absolute(side+1, 0)
+			var enemy *unit
+			if i == king {
+				for lane := 0; lane < bots.Lanes && enemy == nil; lane++ {
+					if b.towers[side][lane].hitpoint <= 0 {
+						enemy = b.nearest(side+1, lane, position, TowerRange)
+					}
+				}
+			} else {
+				enemy = b.nearest(side+1, i, position, TowerRange)
+			}
+			if enemy != nil {
+				enemy.hitpoint -= b.rng.roll(TowerDamage)
+				t.cooldown = AttackMs
+			}
+		}
+	}
+	b.bury()
+}
+
+// nearest finds the living unit of the other side closest to position in
+// the lane, within reach; the earliest deployed wins a tie.
+func (b *battle) nearest(side, lane, position, reach int) *unit {
+	var found *unit
+	for _, u := range b.units {
+		if u.side == side || u.lane != lane || u.hitpoint <= 0 || distance(u.position, position) > reach {
+			continue
+		}
+		if found == nil || distance(u.position, position) < distance(found.position, position) {
+			found = u
+		}
+	}
+	return found
+}
+
+func (b *battle) bury() {
+	alive := b.units[:0]
+	for _, u := range b.units {
+		if u.hitpoint > 0 {
+			alive = append(alive, u)
+		}
+	}
+	b.units = alive
+}
+
+// Verify re-runs the replay and checks the simulated result against the one
+// reported for the match.
+func Verify(seed int64, match models.Match, inputs []Input) (Outcome, error) {
+	outcome, err := Simulate(seed, match.Players, inputs)
+	if err != nil {
+		return outcome, err
+	}
+	sides, _ := Sides(match.Players)
+
+	reported := 0
+	switch {
+	case match.WinningTeam.Valid:
+		reported = int(match.WinningTeam.Int64)
+	case match.WinnerID.Valid:
+		for seat, player := range match.Players {
+			if int64(player.UserID) == match.WinnerID.Int64 {
+				reported = sides[seat]
+			}
+		}
+	}
+	if outcome.WinningSide != reported {
+		return outcome, fmt.Errorf("replay ends with side %d winning but the match reported side %d (0 is a draw)", outcome.WinningSide, reported)
+	}
+	return outcome, nil
+}
@@ -0,0 +1,176 @@
+package replays
+
+import (
+	"auth/internal/rest/models"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	giantID = iota + 1
+	goblinID
+	fireballID
+	nukeID
+)
+
+func testDeck() models.DeckSnapshot {
+	return models.DeckSnapshot{
+		Heroes: []models.Hero{
+			{ID: giantID, Hitpoint: 4000, Damage: 100, DamageTower: 400, CostElixir: 5, Speed: 10},
+			{ID: goblinID, Hitpoint: 200, Damage: 50, DamageTower: 50, CostElixir: 2, Speed: 15},
+		},
+		Spells: []models.Spell{
+			{ID: fireballID, Damage: 600, Area: 5},
+			{ID: nukeID, Damage: 10000, Area: 5},
+		},
+	}
+}
+
+func testPlayers() []models.MatchPlayer {
+	return []models.MatchPlayer{
+		{UserID: 1, Username: "alice", Deck: testDeck()},
+		{UserID: 2, Username: "bob", Deck: testDeck()},
+	}
+}
+
+func hero(at uint32, seat int, id uint, lane, position int) Input {
+	return Input{At: at, Seat: seat, Kind: models.DraftCardHero, CardID: id, Lane: lane, Position: position}
+}
+
+func spell(at uint32, seat int, id uint, lane, position int) Input {
+	return Input{At: at, Seat: seat, Kind: models.DraftCardSpell, CardID: id, Lane: lane, Position: position}
+}
+
+// The outcomes are pinned exactly: clients re-simulate replays with the same
+// rules, so any change to them shows up here first.
+func TestSimulateOutcomes(t *testing.T) {
+	tests := []struct {
+		name   string
+		seed   int64
+		inputs []Input
+		want   Outcome
+	}{
+		{"no inputs is a draw", 1, nil, Outcome{WinningSide: 0, EndedAt: MatchMs}},
+		{"unanswered giant takes lane and king", 1, []Input{hero(0, 0, giantID, 0, 500)},
+			Outcome{WinningSide: 1, Crowns: [2]int{2, 0}, EndedAt: 21500}},
+		{"damage rolls follow the seed", 42, []Input{hero(0, 0, giantID, 0, 500)},
+			Outcome{WinningSide: 1, Crowns: [2]int{2, 0}, EndedAt: 22500}},
+		{"second seat plays side 2", 1, []Input{hero(0, 1, giantID, 1, 500)},
+			Outcome{WinningSide: 2, Crowns: [2]int{0, 2}, EndedAt: 21500}},
+		{"goblin falls to the tower", 1, []Input{hero(0, 0, goblinID, 0, 500)},
+			Outcome{WinningSide: 0, EndedAt: MatchMs}},
+		{"giants cancel out", 1, []Input{hero(0, 0, giantID, 0, 500), hero(0, 1, giantID, 0, 500)},
+			Outcome{WinningSide: 0, EndedAt: MatchMs}},
+		{"spell kills the giant as it lands", 1, []Input{hero(0, 1, giantID, 0, 500), spell(0, 0, nukeID, 0, 500)},
+			Outcome{WinningSide: 0, EndedAt: MatchMs}},
+		{"spell misses another lane", 1, []Input{hero(0, 1, giantID, 0, 500), spell(0, 0, nukeID, 1, 500)},
+			Outcome{WinningSide: 2, Crowns: [2]int{0, 2}, EndedAt: 21500}},
+	}
+	for _, tt := range tests {
+		got, err := Simulate(tt.seed, testPlayers(), tt.inputs)
+		if err != nil {
+			t.Errorf("%s: Simulate: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Simulate = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if again, _ := Simulate(tt.seed, testPlayers(), tt.inputs); again != got {
+			t.Errorf("%s: Simulate isn't deterministic: %+v then %+v", tt.name, got, again)
+		}
+	}
+}
+
+func TestSimulateRejectsIllegalInputs(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []Input
+		want   string
+	}{
+		{"empty seat", []Input{hero(0, 2, giantID, 0, 100)}, "no player in seat 2"},
+		{"missing lane", []Input{hero(0, 0, giantID, 2, 100)}, "no lane 2"},
+		{"hero not in the deck", []Input{hero(0, 0, 99, 0, 100)}, "hero 99 is not in the deck"},
+		{"spell not in the deck", []Input{spell(0, 0, giantID, 0, 100)}, "spell 1 is not in the deck"},
+		{"hero in the enemy half", []Input{hero(0, 0, goblinID, 0, 501)}, "own half"},
+		{"spell off the lane", []Input{spell(0, 0, fireballID, 0, 1001)}, "off the lane"},
+		{"unknown kind", []Input{{Seat: 0, Kind: "TROOP", CardID: giantID}}, `unknown card kind "TROOP"`},
+		{"elixir spent", []Input{hero(0, 0, giantID, 0, 100), hero(0, 0, goblinID, 1, 100)}, "input 1 at 0ms: seat 0 can't afford"},
+		{"elixir not back yet", []Input{hero(0, 0, giantID, 0, 100), hero(5000, 0, giantID, 1, 100)}, "input 1 at 5000ms: seat 0 can't afford"},
+		{"after the king fell", []Input{hero(0, 0, giantID, 0, 500), hero(30000, 1, goblinID, 0, 100)}, "input 1 comes after the match ended"},
+		{"after the time ran out", []Input{hero(MatchMs, 0, goblinID, 0, 100)}, "input 0 comes after the match ended"},
+	}
+	for _, tt := range tests {
+		_, err := Simulate(1, testPlayers(), tt.inputs)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Simulate error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSides(t *testing.T) {
+	teams := func(teams ...int32) []models.MatchPlayer {
+		var players []models.MatchPlayer
+		for _, team := range teams {
+			players = append(players, models.MatchPlayer{Team: team})
+		}
+		return players
+	}
+
+	tests := []struct {
+		name    string
+		players []models.MatchPlayer
+		want    []int
+		wantErr bool
+	}{
+		{"duel", teams(0, 0), []int{1, 2}, false},
+		{"teams", teams(2, 1, 2, 1), []int{2, 1, 2, 1}, false},
+		{"third player without a team", teams(0, 0, 0), nil, true},
+		{"unknown team", teams(1, 3), nil, true},
+	}
+	for _, tt := range tests {
+		got, err := Sides(tt.players)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Sides = %v, %v, want %v (error %t)", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	win := []Input{hero(0, 0, giantID, 0, 500)}
+	winner := func(id int64) sql.NullInt64 { return sql.NullInt64{Int64: id, Valid: true} }
+
+	teamMatch := models.Match{Players: testPlayers()}
+	for i := range teamMatch.Players {
+		teamMatch.Players[i].Team = 2 - int32(i)
+	}
+
+	tests := []struct {
+		name    string
+		match   models.Match
+		inputs  []Input
+		wantErr bool
+	}{
+		{"winner agrees", models.Match{Players: testPlayers(), WinnerID: winner(1)}, win, false},
+		{"wrong winner", models.Match{Players: testPlayers(), WinnerID: winner(2)}, win, true},
+		{"reported a draw", models.Match{Players: testPlayers()}, win, true},
+		{"winner outside the match", models.Match{Players: testPlayers(), WinnerID: winner(3)}, win, true},
+		{"draw agrees", models.Match{Players: testPlayers()}, nil, false},
+		{"draw with a winner", models.Match{Players: testPlayers(), WinnerID: winner(1)}, nil, true},
+		{"team agrees", withTeam(teamMatch, 2), win, false},
+		{"wrong team", withTeam(teamMatch, 1), win, true},
+		{"illegal input", models.Match{Players: testPlayers(), WinnerID: winner(1)}, []Input{hero(0, 0, 99, 0, 100)}, true},
+	}
+	for _, tt := range tests {
+		outcome, err := Verify(7, tt.match, tt.inputs)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Verify = %+v, %v, want error %t", tt.name, outcome, err, tt.wantErr)
+		}
+	}
+}
+
+func withTeam(match models.Match, team int64) models.Match {
+	match.WinningTeam = sql.NullInt64{Int64: team, Valid: true}
+	return match
+}
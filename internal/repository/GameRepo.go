@@ -16,17 +16,24 @@ type GameRepo interface {
 	CreateDeck(deck *models.Deck) error
 	AddHeroToUser(userID, heroID uint) error
 	AddSpellToUser(userID, spellID uint) error
+	PurchaseHero(userID uint, hero models.Hero) error
+	PurchaseSpell(userID uint, spell models.Spell) error
 	AddHeroToDeck(deckID, heroID uint) ([]models.Deck, error)
 	DeleteHeroFromDeck(deckID, heroID uint) ([]models.Deck, error)
 	AddSpellToDeck(deckID, spellID uint) ([]models.Deck, error)
 	DeleteSpellFromDeck(deckID, spellID uint) ([]models.Deck, error)
 	GetDecksForUser(userID uint) ([]models.Deck, error)
+	GetDeckHeros(deckID uint) ([]models.Hero, error)
+	GetDeckSpells(deckID uint) ([]models.Spell, error)
+	GetPurchaseHistory(userID uint) ([]models.Purchase, error)
 
 	GetAllSpells(sortBy, sortOrder, filterName string, page, pageSize int) ([]models.Spell, error)
 	GetAllHeros(sortBy, sortOrder, filterName string, page, pageSize int) ([]models.Hero, error)
+	GetCatalog() ([]models.Hero, []models.Spell, error)
 	GetMySpells(userID uint) ([]models.Spell, error)
 	GetMyHeros(userID uint) ([]models.Hero, error)
 	HasUserBoughtHero(userID, heroID uint) (bool, error)
+	HasUserBoughtSpell(userID, spellID uint) (bool, error)
 }
 
 type GameRepository struct {
@@ -52,6 +59,21 @@ func (repo *GameRepository) HasUserBoughtHero(userID, heroID uint) (bool, error)
 
 	return count > 0, nil
 }
+func (repo *GameRepository) HasUserBoughtSpell(userID, spellID uint) (bool, error) {
+	query := `
+        SELECT COUNT(*)
+        FROM user_spells
+        WHERE user_id = $1 AND spell_id = $2
+    `
+
+	var count int
+	err := repo.db.QueryRow(query, userID, spellID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if user has bought spell: %v", err)
+	}
+
+	return count > 0, nil
+}
 func (repo *GameRepository) GetSpellByID(id uint) (models.Spell, error) {
 	query := `
 		SELECT * FROM spells WHERE id = $1
@@ -203,6 +225,57 @@ func (repo *GameRepository) AddSpellToUser(userID, spellID uint) error {
 
 	return nil
 }
+
+// PurchaseHero charges the hero's price and adds it to the collection in one
+// transaction. It returns helper.ErrCardLocked when the hero's arena is above
+// the player and helper.ErrInsufficientBalance when they can't afford it.
+func (repo *GameRepository) PurchaseHero(userID uint, hero models.Hero) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCardUnlocked(tx, userID, sql.NullInt64{Int64: int64(hero.ID), Valid: true}, sql.NullInt64{}); err != nil {
+		return err
+	}
+	if err := debitWallet(tx, userID, models.CurrencyGold, hero.Price, models.ReasonCardPurchase, fmt.Sprintf("hero:%d", hero.ID)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO user_heros (user_id, hero_id) VALUES ($1, $2)", userID, hero.ID); err != nil {
+		return fmt.Errorf("failed to add hero to user: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit hero purchase: %v", err)
+	}
+	return nil
+}
+
+// PurchaseSpell is PurchaseHero for spells.
+func (repo *GameRepository) PurchaseSpell(userID uint, spell models.Spell) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCardUnlocked(tx, userID, sql.NullInt64{}, sql.NullInt64{Int64: int64(spell.ID), Valid: true}); err != nil {
+		return err
+	}
+	if err := debitWallet(tx, userID, models.CurrencyGold, spell.Price, models.ReasonCardPurchase, fmt.Sprintf("spell:%d", spell.ID)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO user_spells (user_id, spell_id) VALUES ($1, $2)", userID, spell.ID); err != nil {
+		return fmt.Errorf("failed to add spell to user: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit spell purchase: %v", err)
+	}
+	return nil
+}
+
 func (repo *GameRepository) AddHeroToDeck(deckID, heroID uint) ([]models.Deck, error) {
 	existingDeck, err := repo.GetDeckByID(deckID)
 	if err != nil {
@@ -456,8 +529,6 @@ func (repo *GameRepository) GetAllHeros(sortBy, sortOrder, filterName string, pa
 			&hero.Speed,
 			&hero.Price,
 		)
-		fmt.Println("test:", hero.ID, hero.DeletedAt, hero.Name)
-		fmt.Errorf("%e,errrorrrrrr", err)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan hero: %v", err)
 		}
@@ -567,3 +638,191 @@ func (repo *GameRepository) GetMyHeros(userID uint) ([]models.Hero, error) {
 
 	return myHeros, nil
 }
+
+func (repo *GameRepository) GetDeckHeros(deckID uint) ([]models.Hero, error) {
+	query := `
+		SELECT h.* FROM heros h
+		JOIN deck_heros dh ON h.id = dh.hero_id
+		WHERE dh.deck_id = $1
+		ORDER BY dh.id
+	`
+
+	rows, err := repo.db.Query(query, deckID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deck heros: %v", err)
+	}
+	defer rows.Close()
+
+	var heros []models.Hero
+	for rows.Next() {
+		var hero models.Hero
+		err := rows.Scan(
+			&hero.ID,
+			&hero.CreatedAt,
+			&hero.UpdatedAt,
+			&hero.DeletedAt,
+			&hero.Name,
+			&hero.Description,
+			&hero.Rarity,
+			&hero.DamageType,
+			&hero.Effect,
+			&hero.Hitpoint,
+			&hero.Damage,
+			&hero.CostElixir,
+			&hero.DamageTower,
+			&hero.Speed,
+			&hero.Price,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan hero: %v", err)
+		}
+		heros = append(heros, hero)
+	}
+
+	return heros, nil
+}
+
+func (repo *GameRepository) GetDeckSpells(deckID uint) ([]models.Spell, error) {
+	query := `
+		SELECT s.* FROM spells s
+		JOIN deck_spells ds ON s.id = ds.spell_id
+		WHERE ds.deck_id = $1
+		ORDER BY ds.id
+	`
+
+	rows, err := repo.db.Query(query, deckID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deck spells: %v", err)
+	}
+	defer rows.Close()
+
+	var spells []models.Spell
+	for rows.Next() {
+		var spell models.Spell
+		err := rows.Scan(
+			&spell.ID,
+			&spell.CreatedAt,
+			&spell.UpdatedAt,
+			&spell.DeletedAt,
+			&spell.Name,
+			&spell.Description,
+			&spell.Area,
+			&spell.DamageType,
+			&spell.Damage,
+			&spell.Duration,
+			&spell.Effect,
+			&spell.Price,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan spell: %v", err)
+		}
+		spells = append(spells, spell)
+	}
+
+	return spells, nil
+}
+
+// GetPurchaseHistory lists bought cards with the catalog price; the store
+// keeps no separate ledger, so the collection rows are the record.
+func (repo *GameRepository) GetPurchaseHistory(userID uint) ([]models.Purchase, error) {
+	query := `
+		SELECT 'hero', h.id, h.name, h.price, uh.created_at
+		FROM user_heros uh JOIN heros h ON h.id = uh.hero_id
+		WHERE uh.user_id = $1
+		UNION ALL
+		SELECT 'spell', s.id, s.name, s.price, us.created_at
+		FROM user_spells us JOIN spells s ON s.id = us.spell_id
+		WHERE us.user_id = $1
+		ORDER BY 5
+	`
+
+	rows, err := repo.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase history: %v", err)
+	}
+	defer rows.Close()
+
+	var purchases []models.Purchase
+	for rows.Next() {
+		var purchase models.Purchase
+		err := rows.Scan(
+			&purchase.Kind,
+			&purchase.ItemID,
+			&purchase.Name,
+			&purchase.Price,
+			&purchase.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan purchase: %v", err)
+		}
+		purchases = append(purchases, purchase)
+	}
+
+	return purchases, nil
+}
+
+// GetCatalog returns every hero and spell that hasn't been removed, for
+// building bot decks.
+func (repo *GameRepository) GetCatalog() ([]models.Hero, []models.Spell, error) {
+	rows, err := repo.db.Query("SELECT * FROM heros WHERE deleted_at IS NULL ORDER BY id")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get heros: %v", err)
+	}
+	var heros []models.Hero
+	for rows.Next() {
+		var hero models.Hero
+		err := rows.Scan(
+			&hero.ID,
+			&hero.CreatedAt,
+			&hero.UpdatedAt,
+			&hero.DeletedAt,
+			&hero.Name,
+			&hero.Description,
+			&hero.Rarity,
+			&hero.DamageType,
+			&hero.Effect,
+			&hero.Hitpoint,
+			&hero.Damage,
+			&hero.CostElixir,
+			&hero.DamageTower,
+			&hero.Speed,
+			&hero.Price,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan hero: %v", err)
+		}
+		heros = append(heros, hero)
+	}
+	rows.Close()
+
+	rows, err = repo.db.Query("SELECT * FROM spells WHERE deleted_at IS NULL ORDER BY id")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get spells: %v", err)
+	}
+	defer rows.Close()
+	var spells []models.Spell
+	for rows.Next() {
+		var spell models.Spell
+		err := rows.Scan(
+			&spell.ID,
+			&spell.CreatedAt,
+			&spell.UpdatedAt,
+			&spell.DeletedAt,
+			&spell.Name,
+			&spell.Description,
+			&spell.Area,
+			&spell.DamageType,
+			&spell.Damage,
+			&spell.Duration,
+			&spell.Effect,
+			&spell.Price,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan spell: %v", err)
+		}
+		spells = append(spells, spell)
+	}
+
+	return heros, spells, nil
+}
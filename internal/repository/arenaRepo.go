@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+type ArenaRepo interface {
+	GetArenas() ([]models.Arena, error)
+	GetArena(id uint) (models.Arena, error)
+	CreateArena(arena *models.Arena) error
+	UpdateArena(arena models.Arena, replaceCards bool) error
+	GetCardLocks(userID uint) (models.CardLocks, error)
+}
+
+type ArenaRepository struct {
+	db *sql.DB
+}
+
+func NewArenaRepository(db *sql.DB) *ArenaRepository {
+	return &ArenaRepository{db}
+}
+
+// lockedCardsQuery selects the cards of the arenas above the player's
+// trophies, with the trophies each one needs. It expects the user id as $1
+// and is formatted with the card column, hero_id or spell_id.
+const lockedCardsQuery = `
+	SELECT c.%[1]s, a.min_trophies
+	FROM arena_cards c
+	JOIN arenas a ON a.id = c.arena_id
+	WHERE c.%[1]s IS NOT NULL AND a.min_trophies > COALESCE((SELECT awards FROM users WHERE id = $1), 0)
+`
+
+type rowQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// checkCardUnlocked returns helper.ErrCardLocked when the card belongs to an
+// arena the player hasn't reached. Every way of buying a card goes through it;
+// rewards don't, so quests and events can hand out cards early.
+func checkCardUnlocked(db rowQueryer, userID uint, heroID, spellID sql.NullInt64) error {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM arena_cards c
+			JOIN arenas a ON a.id = c.arena_id
+			WHERE (c.hero_id = $2 OR c.spell_id = $3)
+			  AND a.min_trophies > COALESCE((SELECT awards FROM users WHERE id = $1), 0)
+		)
+	`
+	var locked bool
+	if err := db.QueryRow(query, userID, heroID, spellID).Scan(&locked); err != nil {
+		return fmt.Errorf("failed to check card arena: %v", err)
+	}
+	if locked {
+		return helper.ErrCardLocked
+	}
+	return nil
+}
+
+func (repo *ArenaRepository) getCards(arena *models.Arena) error {
+	query := `
+		SELECT c.hero_id, c.spell_id, COALESCE(h.name, s.name, '')
+		FROM arena_cards c
+		LEFT JOIN heros h ON h.id = c.hero_id
+		LEFT JOIN spells s ON s.id = c.spell_id
+		WHERE c.arena_id = $1
+		ORDER BY c.id
+	`
+	rows, err := repo.db.Query(query, arena.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get arena cards: %v", err)
+	}
+	defer rows.Close()
+
+	arena.Cards = nil
+	for rows.Next() {
+		var card models.ArenaCard
+		if err := rows.Scan(&card.HeroID, &card.SpellID, &card.Name); err != nil {
+			return fmt.Errorf("failed to scan arena card: %v", err)
+		}
+		arena.Cards = append(arena.Cards, card)
+	}
+	return nil
+}
+
+func (repo *ArenaRepository) GetArenas() ([]models.Arena, error) {
+	rows, err := repo.db.Query("SELECT id, created_at, updated_at, name, min_trophies FROM arenas ORDER BY min_trophies")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get arenas: %v", err)
+	}
+
+	var arenas []models.Arena
+	for rows.Next() {
+		var arena models.Arena
+		if err := rows.Scan(&arena.ID, &arena.CreatedAt, &arena.UpdatedAt, &arena.Name, &arena.MinTrophies); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan arena: %v", err)
+		}
+		arenas = append(arenas, arena)
+	}
+	rows.Close()
+
+	for i := range arenas {
+		if err := repo.getCards(&arenas[i]); err != nil {
+			return nil, err
+		}
+	}
+	return arenas, nil
+}
+
+func (repo *ArenaRepository) GetArena(id uint) (models.Arena, error) {
+	var arena models.Arena
+	err := repo.db.QueryRow("SELECT id, created_at, updated_at, name, min_trophies FROM arenas WHERE id = $1", id).
+		Scan(&arena.ID, &arena.CreatedAt, &arena.UpdatedAt, &arena.Name, &arena.MinTrophies)
+	if err == sql.ErrNoRows {
+		return models.Arena{}, helper.ErrArenaNotFound
+	} else if err != nil {
+		return models.Arena{}, fmt.Errorf("failed to get arena: %v", err)
+	}
+	if err := repo.getCards(&arena); err != nil {
+		return models.Arena{}, err
+	}
+	return arena, nil
+}
+
+// arenaError turns unique violations into helper.ErrCardInArena for cards and
+// helper.ErrArenaTaken for the arena itself.
+func arenaError(err error, action string) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		if pqErr.Table == "arena_cards" {
+			return helper.ErrCardInArena
+		}
+		return helper.ErrArenaTaken
+	}
+	return fmt.Errorf("failed to %s: %v", action, err)
+}
+
+func insertArenaCards(tx *sql.Tx, arenaID uint, cards []models.ArenaCard) error {
+	for _, card := range cards {
+		if _, err := tx.Exec("INSERT INTO arena_cards (arena_id, hero_id, spell_id) VALUES ($1, $2, $3)", arenaID, card.HeroID, card.SpellID); err != nil {
+			return arenaError(err, "add arena card")
+		}
+	}
+	return nil
+}
+
+func (repo *ArenaRepository) CreateArena(arena *models.Arena) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("INSERT INTO arenas (name, min_trophies) VALUES ($1, $2) RETURNING id, created_at, updated_at", arena.Name, arena.MinTrophies).
+		Scan(&arena.ID, &arena.CreatedAt, &arena.UpdatedAt)
+	if err != nil {
+		return arenaError(err, "create arena")
+	}
+	if err := insertArenaCards(tx, arena.ID, arena.Cards); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit arena: %v", err)
+	}
+	return nil
+}
+
+// UpdateArena renames or moves an arena, replacing its cards when
+// replaceCards is set.
+func (repo *ArenaRepository) UpdateArena(arena models.Arena, replaceCards bool) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := "UPDATE arenas SET name = $1, min_trophies = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3"
+	if _, err := tx.Exec(query, arena.Name, arena.MinTrophies, arena.ID); err != nil {
+		return arenaError(err, "update arena")
+	}
+	if replaceCards {
+		if _, err := tx.Exec("DELETE FROM arena_cards WHERE arena_id = $1", arena.ID); err != nil {
+			return fmt.Errorf("failed to clear arena cards: %v", err)
+		}
+		if err := insertArenaCards(tx, arena.ID, arena.Cards); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit arena: %v", err)
+	}
+	return nil
+}
+
+func (repo *ArenaRepository) getLocks(column string, userID uint) (map[uint]int32, error) {
+	rows, err := repo.db.Query(fmt.Sprintf(lockedCardsQuery, column), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get locked cards: %v", err)
+	}
+	defer rows.Close()
+
+	locks := make(map[uint]int32)
+	for rows.Next() {
+		var id uint
+		var trophies int32
+		if err := rows.Scan(&id, &trophies); err != nil {
+			return nil, fmt.Errorf("failed to scan locked card: %v", err)
+		}
+		locks[id] = trophies
+	}
+	return locks, nil
+}
+
+func (repo *ArenaRepository) GetCardLocks(userID uint) (models.CardLocks, error) {
+	heroes, err := repo.getLocks("hero_id", userID)
+	if err != nil {
+		return models.CardLocks{}, err
+	}
+	spells, err := repo.getLocks("spell_id", userID)
+	if err != nil {
+		return models.CardLocks{}, err
+	}
+	return models.CardLocks{Heroes: heroes, Spells: spells}, nil
+}
@@ -2,8 +2,11 @@ package repository
 
 import (
 	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
 	"database/sql"
 	"fmt"
+	"github.com/lib/pq"
+	"time"
 )
 
 type UserRepo interface {
@@ -13,8 +16,10 @@ type UserRepo interface {
 	GetAllUsers() ([]models.User, error)
 	UpdateUser(user *models.User) error
 	DeleteUser(id uint) error
+	SoftDeleteUser(id uint) error
+	RestoreUser(id uint) error
+	GetUsersDeletedBefore(cutoff time.Time) ([]uint, error)
 	CreateUser(user *models.User) error
-	UpdateUserBalance(userID uint, newBalance int64) error
 }
 
 type UserRepository struct {
@@ -25,11 +30,20 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db}
 }
 
+// userColumns reads the user with its wallet balances in place of the old
+// bank column, so callers see Bank and Gems on the user as before.
+var userColumns = `
+	id, created_at, updated_at, deleted_at, username, email, password,
+	` + fmt.Sprintf(walletBalanceQuery, models.CurrencyGold) + `,
+	` + fmt.Sprintf(walletBalanceQuery, models.CurrencyGems) + `,
+	awards, userType, token_version
+`
+
 func (ur *UserRepository) GetUserByID(id uint) (*models.User, error) {
 	var user models.User
-	err := ur.db.QueryRow("SELECT * FROM users WHERE id = $1", id).Scan(
+	err := ur.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = $1", id).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
-		&user.Username, &user.Email, &user.Password, &user.Bank, &user.Awards, &user.UserType,
+		&user.Username, &user.Email, &user.Password, &user.Bank, &user.Gems, &user.Awards, &user.UserType, &user.TokenVersion,
 	)
 	if err != nil {
 		return nil, err
@@ -39,9 +53,9 @@ func (ur *UserRepository) GetUserByID(id uint) (*models.User, error) {
 
 func (ur *UserRepository) GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
-	err := ur.db.QueryRow("SELECT * FROM users WHERE username = $1", username).Scan(
+	err := ur.db.QueryRow("SELECT "+userColumns+" FROM users WHERE LOWER(username) = LOWER($1)", username).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
-		&user.Username, &user.Email, &user.Password, &user.Bank, &user.Awards, &user.UserType,
+		&user.Username, &user.Email, &user.Password, &user.Bank, &user.Gems, &user.Awards, &user.UserType, &user.TokenVersion,
 	)
 	if err != nil {
 		return nil, err
@@ -49,11 +63,13 @@ func (ur *UserRepository) GetUserByUsername(username string) (*models.User, erro
 	return &user, nil
 }
 
+// GetUserByEmail ignores case, as identity providers and players don't
+// always agree on it.
 func (ur *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
-	err := ur.db.QueryRow("SELECT * FROM users WHERE email = $1", email).Scan(
+	err := ur.db.QueryRow("SELECT "+userColumns+" FROM users WHERE LOWER(email) = LOWER($1)", email).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
-		&user.Username, &user.Email, &user.Password, &user.Bank, &user.Awards, &user.UserType,
+		&user.Username, &user.Email, &user.Password, &user.Bank, &user.Gems, &user.Awards, &user.UserType, &user.TokenVersion,
 	)
 	if err != nil {
 		return nil, err
@@ -62,7 +78,7 @@ func (ur *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 }
 
 func (ur *UserRepository) GetAllUsers() ([]models.User, error) {
-	rows, err := ur.db.Query("SELECT * FROM users")
+	rows, err := ur.db.Query("SELECT " + userColumns + " FROM users")
 	if err != nil {
 		return nil, err
 	}
@@ -73,7 +89,7 @@ func (ur *UserRepository) GetAllUsers() ([]models.User, error) {
 		var user models.User
 		err := rows.Scan(
 			&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
-			&user.Username, &user.Email, &user.Password, &user.Bank, &user.Awards,
+			&user.Username, &user.Email, &user.Password, &user.Bank, &user.Gems, &user.Awards, &user.UserType, &user.TokenVersion,
 		)
 		if err != nil {
 			return nil, err
@@ -84,31 +100,179 @@ func (ur *UserRepository) GetAllUsers() ([]models.User, error) {
 	return users, nil
 }
 
+// UpdateUser saves the account fields only. Balance and trophies change through
+// their own methods so a stale copy of the user cannot overwrite them. A new
+// password or email bumps the token version, revoking every earlier token.
+// It returns helper.ErrUsernameTaken or helper.ErrEmailTaken when another
+// account has the username or email.
 func (ur *UserRepository) UpdateUser(user *models.User) error {
-	_, err := ur.db.Exec(
-		"UPDATE users SET username=$1, email=$2, password=$3, bank=$4, awards=$5 WHERE id=$6",
-		user.Username, user.Email, user.Password, user.Bank, user.Awards, user.ID,
-	)
+	query := `
+		UPDATE users SET
+			token_version = token_version + CASE WHEN password IS DISTINCT FROM $3 OR email IS DISTINCT FROM $2 THEN 1 ELSE 0 END,
+			username = $1, email = $2, password = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING token_version
+	`
+	err := ur.db.QueryRow(query, user.Username, user.Email, user.Password, user.ID).Scan(&user.TokenVersion)
+	return takenError(err)
+}
+
+// takenError turns a unique violation on the username or email index into
+// helper.ErrUsernameTaken or helper.ErrEmailTaken.
+func takenError(err error) error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok || pqErr.Code != "23505" {
+		return err
+	}
+	switch pqErr.Constraint {
+	case "idx_users_username_lower":
+		return helper.ErrUsernameTaken
+	case "idx_users_email_lower":
+		return helper.ErrEmailTaken
+	}
 	return err
 }
 
+// DeleteUser permanently removes the user together with every row that
+// references it, in one transaction.
 func (ur *UserRepository) DeleteUser(id uint) error {
-	_, err := ur.db.Exec("DELETE FROM users WHERE id = $1", id)
-	return err
+	tx, err := ur.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"DELETE FROM player_profiles WHERE user_id = $1",
+		"DELETE FROM notifications WHERE user_id = $1",
+		"DELETE FROM notification_settings WHERE user_id = $1",
+		"DELETE FROM user_quests WHERE user_id = $1",
+		"DELETE FROM season_results WHERE user_id = $1",
+		"DELETE FROM trophy_road_claims WHERE user_id = $1",
+		"DELETE FROM daily_offers WHERE user_id = $1",
+		"DELETE FROM shop_purchases WHERE user_id = $1",
+		"DELETE FROM promo_redemptions WHERE user_id = $1",
+		"UPDATE store_purchases SET user_id = NULL WHERE user_id = $1",
+		"UPDATE wallet_transactions SET user_id = NULL WHERE user_id = $1",
+		"DELETE FROM wallets WHERE user_id = $1",
+		"DELETE FROM chests WHERE user_id = $1",
+		"UPDATE moderation_audit_log SET moderator_id = NULL WHERE moderator_id = $1",
+		"UPDATE moderation_audit_log SET target_user_id = NULL WHERE target_user_id = $1",
+		"UPDATE moderation_audit_log SET message_id = NULL WHERE message_id IN (SELECT id FROM chat_messages WHERE sender_id = $1 OR recipient_id = $1)",
+		`UPDATE moderation_audit_log SET report_id = NULL WHERE report_id IN (
+			SELECT id FROM chat_reports
+			WHERE reporter_id = $1 OR message_id IN (SELECT id FROM chat_messages WHERE sender_id = $1 OR recipient_id = $1)
+		)`,
+		"UPDATE moderation_audit_log SET sanction_id = NULL WHERE sanction_id IN (SELECT id FROM chat_sanctions WHERE user_id = $1)",
+		"UPDATE chat_reports SET resolved_by = NULL WHERE resolved_by = $1",
+		"DELETE FROM chat_reports WHERE reporter_id = $1 OR message_id IN (SELECT id FROM chat_messages WHERE sender_id = $1 OR recipient_id = $1)",
+		"UPDATE chat_sanctions SET created_by = NULL WHERE created_by = $1",
+		"DELETE FROM chat_sanctions WHERE user_id = $1",
+		"DELETE FROM chat_messages WHERE sender_id = $1 OR recipient_id = $1",
+		`UPDATE clan_members SET role = 'LEADER' WHERE id = (
+			SELECT id FROM clan_members
+			WHERE clan_id = (SELECT clan_id FROM clan_members WHERE user_id = $1 AND role = 'LEADER') AND user_id <> $1
+			ORDER BY CASE role WHEN 'CO_LEADER' THEN 1 WHEN 'ELDER' THEN 2 ELSE 3 END, created_at
+			LIMIT 1
+		)`,
+		"DELETE FROM clan_donations WHERE donor_id = $1 OR request_id IN (SELECT id FROM clan_card_requests WHERE user_id = $1)",
+		"DELETE FROM clan_card_requests WHERE user_id = $1",
+		"DELETE FROM clan_join_requests WHERE user_id = $1",
+		"DELETE FROM clan_members WHERE user_id = $1",
+		"UPDATE tournaments SET created_by = NULL WHERE created_by = $1",
+		"UPDATE tournament_matches SET player1_id = NULL WHERE player1_id = $1",
+		"UPDATE tournament_matches SET player2_id = NULL WHERE player2_id = $1",
+		"UPDATE tournament_matches SET winner_id = NULL WHERE winner_id = $1",
+		"DELETE FROM tournament_players WHERE user_id = $1",
+		"DELETE FROM team_queue WHERE user_id = $1 OR party_id IN (SELECT id FROM parties WHERE leader_id = $1 OR member_id = $1)",
+		"DELETE FROM parties WHERE leader_id = $1",
+		"UPDATE parties SET member_id = NULL, member_deck = NULL WHERE member_id = $1",
+		"UPDATE parties SET invited_id = NULL WHERE invited_id = $1",
+		"DELETE FROM draft_queue WHERE user_id = $1",
+		"UPDATE drafts SET status = 'CANCELLED' WHERE status = 'DRAFTING' AND (player1_id = $1 OR player2_id = $1)",
+		"UPDATE drafts SET player1_id = NULL WHERE player1_id = $1",
+		"UPDATE drafts SET player2_id = NULL WHERE player2_id = $1",
+		"UPDATE draft_picks SET user_id = NULL WHERE user_id = $1",
+		"DELETE FROM challenges WHERE challenger_id = $1 OR opponent_id = $1",
+		"UPDATE matches SET winner_id = NULL WHERE winner_id = $1",
+		"UPDATE match_players SET user_id = NULL WHERE user_id = $1",
+		"DELETE FROM friendships WHERE requester_id = $1 OR addressee_id = $1",
+		"DELETE FROM user_blocks WHERE blocker_id = $1 OR blocked_id = $1",
+		"DELETE FROM deck_heros WHERE deck_id IN (SELECT id FROM decks WHERE user_id = $1)",
+		"DELETE FROM deck_spells WHERE deck_id IN (SELECT id FROM decks WHERE user_id = $1)",
+		"DELETE FROM decks WHERE user_id = $1",
+		"DELETE FROM user_heros WHERE user_id = $1",
+		"DELETE FROM user_spells WHERE user_id = $1",
+		"DELETE FROM user_recovery_codes WHERE user_id = $1",
+		"DELETE FROM user_totp WHERE user_id = $1",
+		"DELETE FROM user_identities WHERE user_id = $1",
+		"DELETE FROM users WHERE id = $1",
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return fmt.Errorf("failed to delete user %d: %v", id, err)
+		}
+	}
+	return tx.Commit()
 }
 
-func (ur *UserRepository) CreateUser(user *models.User) error {
-	_, err := ur.db.Exec(
-		"INSERT INTO users (created_at, updated_at, deleted_at, username, email, password, bank, awards, userType) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
-		user.CreatedAt, user.UpdatedAt, user.DeletedAt, user.Username, user.Email, user.Password, user.Bank, user.Awards, user.UserType,
-	)
-	return err
+func (ur *UserRepository) SoftDeleteUser(id uint) error {
+	_, err := ur.db.Exec("UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("failed to soft delete user: %v", err)
+	}
+	return nil
 }
-func (repo *UserRepository) UpdateUserBalance(userID uint, newBalance int64) error {
-	query := "UPDATE users SET bank = $1 WHERE id = $2"
-	_, err := repo.db.Exec(query, newBalance, userID)
+
+func (ur *UserRepository) RestoreUser(id uint) error {
+	_, err := ur.db.Exec("UPDATE users SET deleted_at = NULL WHERE id = $1", id)
 	if err != nil {
-		return fmt.Errorf("failed to update user balance: %v", err)
+		return fmt.Errorf("failed to restore user: %v", err)
 	}
 	return nil
 }
+
+func (ur *UserRepository) GetUsersDeletedBefore(cutoff time.Time) ([]uint, error) {
+	rows, err := ur.db.Query("SELECT id FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1", cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted users: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// CreateUser inserts the user together with their wallets, seeding the gold
+// balance with user.Bank. Like UpdateUser it returns helper.ErrUsernameTaken
+// or helper.ErrEmailTaken when the username or email is in use.
+func (ur *UserRepository) CreateUser(user *models.User) error {
+	tx, err := ur.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		"INSERT INTO users (username, email, password, awards, userType) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		user.Username, user.Email, user.Password, user.Awards, user.UserType,
+	).Scan(&user.ID)
+	if err != nil {
+		return takenError(err)
+	}
+	if err := creditWallet(tx, user.ID, models.CurrencyGold, user.Bank, models.ReasonSignup, ""); err != nil {
+		return err
+	}
+	if err := creditWallet(tx, user.ID, models.CurrencyGems, user.Gems, models.ReasonSignup, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,467 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type ChatRepo interface {
+	CreateMessage(message *models.ChatMessage) error
+	GetMessage(id uint) (models.ChatMessage, error)
+	GetClanMessages(clanID, before uint, limit int) ([]models.ChatMessage, error)
+	GetDirectMessages(userID, otherID, before uint, limit int) ([]models.ChatMessage, error)
+	CreateReport(report *models.ChatReport) error
+	GetReport(id uint) (models.ChatReport, error)
+	GetReports(status string, before uint, limit int) ([]models.ChatReport, error)
+	ResolveReport(reportID, moderatorID uint, status string) error
+	HideMessage(messageID, moderatorID uint, reason string, now time.Time) error
+	GetActiveSanctions(userID uint, now time.Time) ([]models.ChatSanction, error)
+	CreateSanction(sanction *models.ChatSanction) error
+	RevokeSanction(id, moderatorID uint, now time.Time) (models.ChatSanction, error)
+	GetAuditLog(before uint, limit int) ([]models.ModerationAction, error)
+}
+
+type ChatRepository struct {
+	db *sql.DB
+}
+
+func NewChatRepository(db *sql.DB) *ChatRepository {
+	return &ChatRepository{db}
+}
+
+const chatMessageQuery = `
+	SELECT m.id, m.created_at, m.channel, m.clan_id, m.sender_id, u.username, m.recipient_id, m.body, m.hidden_at
+	FROM chat_messages m
+	JOIN users u ON u.id = m.sender_id
+`
+
+func scanChatMessage(row rowScanner) (models.ChatMessage, error) {
+	var message models.ChatMessage
+	err := row.Scan(
+		&message.ID,
+		&message.CreatedAt,
+		&message.Channel,
+		&message.ClanID,
+		&message.SenderID,
+		&message.SenderName,
+		&message.RecipientID,
+		&message.Body,
+		&message.HiddenAt,
+	)
+	return message, err
+}
+
+// cursorID turns an empty cursor into one past the newest row.
+func cursorID(before uint) int64 {
+	if before == 0 {
+		return 1<<31 - 1
+	}
+	return int64(before)
+}
+
+func (repo *ChatRepository) CreateMessage(message *models.ChatMessage) error {
+	query := `
+		INSERT INTO chat_messages (channel, clan_id, sender_id, recipient_id, body)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := repo.db.QueryRow(query, message.Channel, message.ClanID, message.SenderID, message.RecipientID, message.Body).
+		Scan(&message.ID, &message.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create chat message: %v", err)
+	}
+	return nil
+}
+
+func (repo *ChatRepository) GetMessage(id uint) (models.ChatMessage, error) {
+	message, err := scanChatMessage(repo.db.QueryRow(chatMessageQuery+" WHERE m.id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.ChatMessage{}, helper.ErrMessageNotFound
+	} else if err != nil {
+		return models.ChatMessage{}, fmt.Errorf("failed to get chat message: %v", err)
+	}
+	return message, nil
+}
+
+// GetClanMessages returns up to limit visible messages older than the before
+// cursor, newest first. A zero cursor starts from the latest message.
+func (repo *ChatRepository) GetClanMessages(clanID, before uint, limit int) ([]models.ChatMessage, error) {
+	query := chatMessageQuery + `
+		WHERE m.clan_id = $1 AND m.id < $2 AND m.hidden_at IS NULL
+		ORDER BY m.id DESC
+		LIMIT $3
+	`
+	return repo.queryMessages(query, clanID, cursorID(before), limit)
+}
+
+func (repo *ChatRepository) GetDirectMessages(userID, otherID, before uint, limit int) ([]models.ChatMessage, error) {
+	query := chatMessageQuery + `
+		WHERE m.recipient_id IS NOT NULL
+		  AND LEAST(m.sender_id, m.recipient_id) = LEAST($1::int, $2::int)
+		  AND GREATEST(m.sender_id, m.recipient_id) = GREATEST($1::int, $2::int)
+		  AND m.id < $3 AND m.hidden_at IS NULL
+		ORDER BY m.id DESC
+		LIMIT $4
+	`
+	return repo.queryMessages(query, userID, otherID, cursorID(before), limit)
+}
+
+func (repo *ChatRepository) queryMessages(query string, args ...interface{}) ([]models.ChatMessage, error) {
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat messages: %v", err)
+	}
+	defer rows.Close()
+
+	var messages []models.ChatMessage
+	for rows.Next() {
+		message, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %v", err)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// CreateReport returns helper.ErrAlreadyReported when the reporter has
+// already flagged this message.
+func (repo *ChatRepository) CreateReport(report *models.ChatReport) error {
+	query := `
+		INSERT INTO chat_reports (message_id, reporter_id, reason, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err := repo.db.QueryRow(query, report.MessageID, report.ReporterID, report.Reason, models.ChatReportOpen).
+		Scan(&report.ID, &report.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrAlreadyReported
+	} else if err != nil {
+		return fmt.Errorf("failed to create chat report: %v", err)
+	}
+	report.Status = models.ChatReportOpen
+	return nil
+}
+
+const chatReportQuery = `
+	SELECT r.id, r.created_at, r.message_id, r.reporter_id, ru.username, r.reason, r.status, r.resolved_by, r.resolved_at,
+	       m.id, m.created_at, m.channel, m.clan_id, m.sender_id, su.username, m.recipient_id, m.body, m.hidden_at
+	FROM chat_reports r
+	JOIN users ru ON ru.id = r.reporter_id
+	JOIN chat_messages m ON m.id = r.message_id
+	JOIN users su ON su.id = m.sender_id
+`
+
+func scanChatReport(row rowScanner) (models.ChatReport, error) {
+	var report models.ChatReport
+	err := row.Scan(
+		&report.ID,
+		&report.CreatedAt,
+		&report.MessageID,
+		&report.ReporterID,
+		&report.ReporterName,
+		&report.Reason,
+		&report.Status,
+		&report.ResolvedBy,
+		&report.ResolvedAt,
+		&report.Message.ID,
+		&report.Message.CreatedAt,
+		&report.Message.Channel,
+		&report.Message.ClanID,
+		&report.Message.SenderID,
+		&report.Message.SenderName,
+		&report.Message.RecipientID,
+		&report.Message.Body,
+		&report.Message.HiddenAt,
+	)
+	return report, err
+}
+
+func (repo *ChatRepository) GetReport(id uint) (models.ChatReport, error) {
+	report, err := scanChatReport(repo.db.QueryRow(chatReportQuery+" WHERE r.id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.ChatReport{}, helper.ErrReportNotFound
+	} else if err != nil {
+		return models.ChatReport{}, fmt.Errorf("failed to get chat report: %v", err)
+	}
+	return report, nil
+}
+
+// GetReports pages through reports with the given status, newest first. An
+// empty status returns every report.
+func (repo *ChatRepository) GetReports(status string, before uint, limit int) ([]models.ChatReport, error) {
+	query := chatReportQuery + `
+		WHERE ($1 = '' OR r.status = $1) AND r.id < $2
+		ORDER BY r.id DESC
+		LIMIT $3
+	`
+	rows, err := repo.db.Query(query, status, cursorID(before), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat reports: %v", err)
+	}
+	defer rows.Close()
+
+	var reports []models.ChatReport
+	for rows.Next() {
+		report, err := scanChatReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat report: %v", err)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// ResolveReport closes an open report and records it in the audit log. It
+// returns helper.ErrReportResolved if another moderator got there first.
+func (repo *ChatRepository) ResolveReport(reportID, moderatorID uint, status string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var messageID, senderID uint
+	query := `
+		UPDATE chat_reports r SET status = $1, resolved_by = $2, resolved_at = CURRENT_TIMESTAMP
+		FROM chat_messages m
+		WHERE r.id = $3 AND r.status = $4 AND m.id = r.message_id
+		RETURNING r.message_id, m.sender_id
+	`
+	err = tx.QueryRow(query, status, moderatorID, reportID, models.ChatReportOpen).Scan(&messageID, &senderID)
+	if err == sql.ErrNoRows {
+		return helper.ErrReportResolved
+	} else if err != nil {
+		return fmt.Errorf("failed to resolve chat report: %v", err)
+	}
+
+	action := models.ModerationAction{Action: models.ModerationResolveReport, Details: status}
+	action.ModeratorID = nullID(moderatorID)
+	action.TargetUserID = nullID(senderID)
+	action.MessageID = nullID(messageID)
+	action.ReportID = nullID(reportID)
+	if err := insertModerationAction(tx, action); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chat report: %v", err)
+	}
+	return nil
+}
+
+// HideMessage takes a message out of chat history, actions any open reports
+// against it and records it in the audit log. Hiding a hidden message is a
+// no-op.
+func (repo *ChatRepository) HideMessage(messageID, moderatorID uint, reason string, now time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var senderID uint
+	var hiddenAt pq.NullTime
+	err = tx.QueryRow("SELECT sender_id, hidden_at FROM chat_messages WHERE id = $1 FOR UPDATE", messageID).Scan(&senderID, &hiddenAt)
+	if err == sql.ErrNoRows {
+		return helper.ErrMessageNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get chat message: %v", err)
+	}
+	if hiddenAt.Valid {
+		return nil
+	}
+
+	if _, err := tx.Exec("UPDATE chat_messages SET hidden_at = $1 WHERE id = $2", now, messageID); err != nil {
+		return fmt.Errorf("failed to hide chat message: %v", err)
+	}
+
+	reportsQuery := `
+		UPDATE chat_reports SET status = $1, resolved_by = $2, resolved_at = CURRENT_TIMESTAMP
+		WHERE message_id = $3 AND status = $4
+	`
+	if _, err := tx.Exec(reportsQuery, models.ChatReportActioned, moderatorID, messageID, models.ChatReportOpen); err != nil {
+		return fmt.Errorf("failed to resolve chat reports: %v", err)
+	}
+
+	action := models.ModerationAction{Action: models.ModerationHideMessage, Details: reason}
+	action.ModeratorID = nullID(moderatorID)
+	action.TargetUserID = nullID(senderID)
+	action.MessageID = nullID(messageID)
+	if err := insertModerationAction(tx, action); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit hidden message: %v", err)
+	}
+	return nil
+}
+
+const chatSanctionColumns = "id, created_at, user_id, kind, reason, expires_at, revoked_at, COALESCE(created_by, 0)"
+
+func scanChatSanction(row rowScanner) (models.ChatSanction, error) {
+	var sanction models.ChatSanction
+	err := row.Scan(
+		&sanction.ID,
+		&sanction.CreatedAt,
+		&sanction.UserID,
+		&sanction.Kind,
+		&sanction.Reason,
+		&sanction.ExpiresAt,
+		&sanction.RevokedAt,
+		&sanction.CreatedBy,
+	)
+	return sanction, err
+}
+
+func (repo *ChatRepository) GetActiveSanctions(userID uint, now time.Time) ([]models.ChatSanction, error) {
+	query := `
+		SELECT ` + chatSanctionColumns + ` FROM chat_sanctions
+		WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY id DESC
+	`
+	rows, err := repo.db.Query(query, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat sanctions: %v", err)
+	}
+	defer rows.Close()
+
+	var sanctions []models.ChatSanction
+	for rows.Next() {
+		sanction, err := scanChatSanction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat sanction: %v", err)
+		}
+		sanctions = append(sanctions, sanction)
+	}
+
+	return sanctions, nil
+}
+
+// CreateSanction mutes or bans a player and records it in the audit log.
+func (repo *ChatRepository) CreateSanction(sanction *models.ChatSanction) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO chat_sanctions (user_id, kind, reason, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(query, sanction.UserID, sanction.Kind, sanction.Reason, sanction.ExpiresAt, sanction.CreatedBy).
+		Scan(&sanction.ID, &sanction.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create chat sanction: %v", err)
+	}
+
+	action := models.ModerationAction{Action: sanction.Kind, Details: sanction.Reason}
+	action.ModeratorID = nullID(sanction.CreatedBy)
+	action.TargetUserID = nullID(sanction.UserID)
+	action.SanctionID = nullID(sanction.ID)
+	if err := insertModerationAction(tx, action); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chat sanction: %v", err)
+	}
+	return nil
+}
+
+// RevokeSanction lifts a mute or ban early and records it in the audit log.
+func (repo *ChatRepository) RevokeSanction(id, moderatorID uint, now time.Time) (models.ChatSanction, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.ChatSanction{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE chat_sanctions SET revoked_at = $1
+		WHERE id = $2 AND revoked_at IS NULL
+		RETURNING ` + chatSanctionColumns
+	sanction, err := scanChatSanction(tx.QueryRow(query, now, id))
+	if err == sql.ErrNoRows {
+		return models.ChatSanction{}, helper.ErrSanctionNotFound
+	} else if err != nil {
+		return models.ChatSanction{}, fmt.Errorf("failed to revoke chat sanction: %v", err)
+	}
+
+	action := models.ModerationAction{Action: models.ModerationRevoke, Details: sanction.Kind}
+	action.ModeratorID = nullID(moderatorID)
+	action.TargetUserID = nullID(sanction.UserID)
+	action.SanctionID = nullID(sanction.ID)
+	if err := insertModerationAction(tx, action); err != nil {
+		return models.ChatSanction{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ChatSanction{}, fmt.Errorf("failed to commit revoked sanction: %v", err)
+	}
+	return sanction, nil
+}
+
+func nullID(id uint) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+func insertModerationAction(tx *sql.Tx, action models.ModerationAction) error {
+	query := `
+		INSERT INTO moderation_audit_log (moderator_id, action, target_user_id, message_id, report_id, sanction_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := tx.Exec(query, action.ModeratorID, action.Action, action.TargetUserID, action.MessageID, action.ReportID, action.SanctionID, action.Details)
+	if err != nil {
+		return fmt.Errorf("failed to write moderation audit log: %v", err)
+	}
+	return nil
+}
+
+func (repo *ChatRepository) GetAuditLog(before uint, limit int) ([]models.ModerationAction, error) {
+	query := `
+		SELECT a.id, a.created_at, a.moderator_id, COALESCE(u.username, ''), a.action,
+		       a.target_user_id, a.message_id, a.report_id, a.sanction_id, a.details
+		FROM moderation_audit_log a
+		LEFT JOIN users u ON u.id = a.moderator_id
+		WHERE a.id < $1
+		ORDER BY a.id DESC
+		LIMIT $2
+	`
+	rows, err := repo.db.Query(query, cursorID(before), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation audit log: %v", err)
+	}
+	defer rows.Close()
+
+	var actions []models.ModerationAction
+	for rows.Next() {
+		var action models.ModerationAction
+		if err := rows.Scan(
+			&action.ID,
+			&action.CreatedAt,
+			&action.ModeratorID,
+			&action.ModeratorName,
+			&action.Action,
+			&action.TargetUserID,
+			&action.MessageID,
+			&action.ReportID,
+			&action.SanctionID,
+			&action.Details,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan moderation action: %v", err)
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
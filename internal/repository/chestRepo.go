@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type ChestRepo interface {
+	GetChests(userID uint) ([]models.Chest, error)
+	StartUnlock(userID, chestID uint, now time.Time) (models.Chest, error)
+	OpenChest(userID, chestID uint, now time.Time) (models.ChestReward, error)
+	SpeedUpChest(userID, chestID uint, gemsPerHour int64, now time.Time) (models.ChestReward, error)
+	BuyChest(userID uint, kind string, now time.Time) (models.ChestReward, error)
+}
+
+type ChestRepository struct {
+	db *sql.DB
+}
+
+func NewChestRepository(db *sql.DB) *ChestRepository {
+	return &ChestRepository{db}
+}
+
+const chestColumns = "id, created_at, user_id, kind, unlocks_at, opened_at"
+
+func scanChest(row rowScanner) (models.Chest, error) {
+	var chest models.Chest
+	err := row.Scan(&chest.ID, &chest.CreatedAt, &chest.UserID, &chest.Kind, &chest.UnlocksAt, &chest.OpenedAt)
+	return chest, err
+}
+
+// addChest gives the player a locked chest of kind. Everything that hands
+// out chests goes through it.
+func addChest(tx rowQueryer, userID uint, kind string) (models.Chest, error) {
+	query := "INSERT INTO chests (user_id, kind) VALUES ($1, $2) RETURNING " + chestColumns
+	chest, err := scanChest(tx.QueryRow(query, userID, kind))
+	if err != nil {
+		return models.Chest{}, fmt.Errorf("failed to add chest: %v", err)
+	}
+	return chest, nil
+}
+
+// getUnopenedChest locks one of the player's chests for the rest of tx.
+func getUnopenedChest(tx *sql.Tx, userID, chestID uint) (models.Chest, error) {
+	query := "SELECT " + chestColumns + " FROM chests WHERE id = $1 AND user_id = $2 FOR UPDATE"
+	chest, err := scanChest(tx.QueryRow(query, chestID, userID))
+	if err == sql.ErrNoRows {
+		return models.Chest{}, helper.ErrChestNotFound
+	} else if err != nil {
+		return models.Chest{}, fmt.Errorf("failed to get chest: %v", err)
+	}
+	if chest.OpenedAt.Valid {
+		return models.Chest{}, helper.ErrChestOpened
+	}
+	return chest, nil
+}
+
+// openChest marks the chest opened and grants its gold and cards. The cards
+// are drawn at random from the unlocked ones the player doesn't own yet.
+func openChest(tx *sql.Tx, chest models.Chest, now time.Time) (models.ChestReward, error) {
+	kind := models.ChestKinds[chest.Kind]
+	reward := models.ChestReward{ChestID: chest.ID, Kind: chest.Kind, Gold: kind.Gold}
+
+	if _, err := tx.Exec("UPDATE chests SET opened_at = $1 WHERE id = $2", now, chest.ID); err != nil {
+		return models.ChestReward{}, fmt.Errorf("failed to open chest: %v", err)
+	}
+	reference := fmt.Sprintf("chest:%d", chest.ID)
+	if err := creditWallet(tx, chest.UserID, models.CurrencyGold, kind.Gold, models.ReasonChest, reference); err != nil {
+		return models.ChestReward{}, err
+	}
+
+	query := `
+		SELECT c.hero_id, c.spell_id, c.name
+		FROM (
+			SELECT id AS hero_id, NULL::int AS spell_id, name FROM heros
+			WHERE deleted_at IS NULL AND id NOT IN (SELECT hero_id FROM user_heros WHERE user_id = $1)
+			  AND id NOT IN (SELECT hero_id FROM (%s) locked)
+			UNION ALL
+			SELECT NULL::int, id, name FROM spells
+			WHERE deleted_at IS NULL AND id NOT IN (SELECT spell_id FROM user_spells WHERE user_id = $1)
+			  AND id NOT IN (SELECT spell_id FROM (%s) locked)
+		) c
+		ORDER BY random()
+		LIMIT $2
+	`
+	query = fmt.Sprintf(query, fmt.Sprintf(lockedCardsQuery, "hero_id"), fmt.Sprintf(lockedCardsQuery, "spell_id"))
+	rows, err := tx.Query(query, chest.UserID, kind.Cards)
+	if err != nil {
+		return models.ChestReward{}, fmt.Errorf("failed to draw chest cards: %v", err)
+	}
+	for rows.Next() {
+		var card models.ChestCard
+		if err := rows.Scan(&card.HeroID, &card.SpellID, &card.Name); err != nil {
+			rows.Close()
+			return models.ChestReward{}, fmt.Errorf("failed to scan chest card: %v", err)
+		}
+		reward.Cards = append(reward.Cards, card)
+	}
+	rows.Close()
+
+	for _, card := range reward.Cards {
+		if err := grantShopItem(tx, chest.UserID, models.ShopOfferItem{HeroID: card.HeroID, SpellID: card.SpellID}); err != nil {
+			return models.ChestReward{}, err
+		}
+	}
+	return reward, nil
+}
+
+func (repo *ChestRepository) GetChests(userID uint) ([]models.Chest, error) {
+	query := "SELECT " + chestColumns + " FROM chests WHERE user_id = $1 AND opened_at IS NULL ORDER BY id"
+	rows, err := repo.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chests: %v", err)
+	}
+	defer rows.Close()
+
+	var chests []models.Chest
+	for rows.Next() {
+		chest, err := scanChest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chest: %v", err)
+		}
+		chests = append(chests, chest)
+	}
+	return chests, rows.Err()
+}
+
+// StartUnlock starts the chest's unlock timer. The user row is locked so two
+// chests can't start at once; starting the chest that is already unlocking
+// again changes nothing.
+func (repo *ChestRepository) StartUnlock(userID, chestID uint, now time.Time) (models.Chest, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.Chest{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT id FROM users WHERE id = $1 FOR UPDATE", userID); err != nil {
+		return models.Chest{}, fmt.Errorf("failed to lock user: %v", err)
+	}
+	chest, err := getUnopenedChest(tx, userID, chestID)
+	if err != nil {
+		return models.Chest{}, err
+	}
+	if chest.UnlocksAt.Valid {
+		return chest, nil
+	}
+
+	var unlocking bool
+	query := "SELECT EXISTS (SELECT 1 FROM chests WHERE user_id = $1 AND opened_at IS NULL AND unlocks_at > $2)"
+	if err := tx.QueryRow(query, userID, now).Scan(&unlocking); err != nil {
+		return models.Chest{}, fmt.Errorf("failed to check unlocking chests: %v", err)
+	}
+	if unlocking {
+		return models.Chest{}, helper.ErrChestUnlocking
+	}
+
+	unlocksAt := now.Add(models.ChestKinds[chest.Kind].UnlockTime)
+	if _, err := tx.Exec("UPDATE chests SET unlocks_at = $1 WHERE id = $2", unlocksAt, chest.ID); err != nil {
+		return models.Chest{}, fmt.Errorf("failed to start chest unlock: %v", err)
+	}
+	chest.UnlocksAt.Time, chest.UnlocksAt.Valid = unlocksAt, true
+
+	if err := tx.Commit(); err != nil {
+		return models.Chest{}, fmt.Errorf("failed to commit chest unlock: %v", err)
+	}
+	return chest, nil
+}
+
+// OpenChest opens a chest whose timer has run out, and returns
+// helper.ErrChestLocked for any other.
+func (repo *ChestRepository) OpenChest(userID, chestID uint, now time.Time) (models.ChestReward, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.ChestReward{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	chest, err := getUnopenedChest(tx, userID, chestID)
+	if err != nil {
+		return models.ChestReward{}, err
+	}
+	if !chest.UnlocksAt.Valid || chest.UnlocksAt.Time.After(now) {
+		return models.ChestReward{}, helper.ErrChestLocked
+	}
+	reward, err := openChest(tx, chest, now)
+	if err != nil {
+		return models.ChestReward{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ChestReward{}, fmt.Errorf("failed to commit chest opening: %v", err)
+	}
+	return reward, nil
+}
+
+// SpeedUpChest opens a chest before its timer has run out, for gemsPerHour
+// gems per hour left. A chest that was never started costs its whole unlock
+// time and one that has finished costs nothing.
+func (repo *ChestRepository) SpeedUpChest(userID, chestID uint, gemsPerHour int64, now time.Time) (models.ChestReward, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.ChestReward{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	chest, err := getUnopenedChest(tx, userID, chestID)
+	if err != nil {
+		return models.ChestReward{}, err
+	}
+	if cost := chest.SpeedUpCost(now, gemsPerHour); cost > 0 {
+		reference := fmt.Sprintf("chest:%d", chest.ID)
+		if err := debitWallet(tx, userID, models.CurrencyGems, cost, models.ReasonChestSpeedUp, reference); err != nil {
+			return models.ChestReward{}, err
+		}
+	}
+	reward, err := openChest(tx, chest, now)
+	if err != nil {
+		return models.ChestReward{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ChestReward{}, fmt.Errorf("failed to commit chest speed-up: %v", err)
+	}
+	return reward, nil
+}
+
+// BuyChest sells a chest of kind for its gem price and opens it right away.
+func (repo *ChestRepository) BuyChest(userID uint, kind string, now time.Time) (models.ChestReward, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.ChestReward{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	chest, err := addChest(tx, userID, kind)
+	if err != nil {
+		return models.ChestReward{}, err
+	}
+	reference := fmt.Sprintf("chest:%d", chest.ID)
+	if err := debitWallet(tx, userID, models.CurrencyGems, models.ChestKinds[kind].GemPrice, models.ReasonChestPurchase, reference); err != nil {
+		return models.ChestReward{}, err
+	}
+	reward, err := openChest(tx, chest, now)
+	if err != nil {
+		return models.ChestReward{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.ChestReward{}, fmt.Errorf("failed to commit chest purchase: %v", err)
+	}
+	return reward, nil
+}
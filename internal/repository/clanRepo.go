@@ -0,0 +1,566 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type ClanRepo interface {
+	CreateClan(clan *models.Clan, leaderID uint) error
+	GetClan(id uint) (models.Clan, error)
+	GetClans(sortBy, sortOrder, filterName string, page, pageSize int) ([]models.Clan, error)
+	UpdateClan(clan models.Clan) error
+	GetMembership(userID uint) (models.ClanMember, error)
+	GetMembers(clanID uint) ([]models.ClanMember, error)
+	AddMember(clanID, userID uint, maxMembers int) error
+	LeaveClan(clanID, userID uint) error
+	SetRole(clanID, userID uint, role string) error
+	TransferLeadership(clanID, leaderID, newLeaderID uint) error
+	CreateJoinRequest(clanID, userID uint) error
+	GetJoinRequests(clanID uint) ([]models.ClanJoinRequest, error)
+	GetJoinRequest(id uint) (models.ClanJoinRequest, error)
+	DeleteJoinRequest(id uint) error
+	CreateCardRequest(request *models.CardRequest) error
+	GetCardRequest(id uint) (models.CardRequest, error)
+	GetOpenCardRequests(clanID uint) ([]models.CardRequest, error)
+	GetLastCardRequestTime(userID uint) (time.Time, bool, error)
+	Donate(requestID, donorID uint, gold int64) error
+}
+
+type ClanRepository struct {
+	db *sql.DB
+}
+
+func NewClanRepository(db *sql.DB) *ClanRepository {
+	return &ClanRepository{db}
+}
+
+const clanQuery = `
+	SELECT c.id, c.created_at, c.updated_at, c.name, c.tag, c.description, c.membership, c.min_trophies,
+	       COUNT(m.id), COALESCE(SUM(u.awards), 0)
+	FROM clans c
+	LEFT JOIN clan_members m ON m.clan_id = c.id
+	LEFT JOIN users u ON u.id = m.user_id
+`
+
+func scanClan(row rowScanner) (models.Clan, error) {
+	var clan models.Clan
+	err := row.Scan(
+		&clan.ID,
+		&clan.CreatedAt,
+		&clan.UpdatedAt,
+		&clan.Name,
+		&clan.Tag,
+		&clan.Description,
+		&clan.Membership,
+		&clan.MinTrophies,
+		&clan.MemberCount,
+		&clan.Trophies,
+	)
+	return clan, err
+}
+
+// CreateClan inserts the clan and makes leaderID its leader. It returns
+// helper.ErrClanNameTaken or helper.ErrAlreadyInClan on conflicts.
+func (repo *ClanRepository) CreateClan(clan *models.Clan, leaderID uint) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO clans (name, tag, description, membership, min_trophies)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRow(query, clan.Name, clan.Tag, clan.Description, clan.Membership, clan.MinTrophies).
+		Scan(&clan.ID, &clan.CreatedAt, &clan.UpdatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrClanNameTaken
+	} else if err != nil {
+		return fmt.Errorf("failed to create clan: %v", err)
+	}
+
+	_, err = tx.Exec("INSERT INTO clan_members (clan_id, user_id, role) VALUES ($1, $2, $3)", clan.ID, leaderID, models.ClanRoleLeader)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrAlreadyInClan
+	} else if err != nil {
+		return fmt.Errorf("failed to add clan leader: %v", err)
+	}
+	if _, err := tx.Exec("DELETE FROM clan_join_requests WHERE user_id = $1", leaderID); err != nil {
+		return fmt.Errorf("failed to clear join requests: %v", err)
+	}
+
+	clan.MemberCount = 1
+	return tx.Commit()
+}
+
+func (repo *ClanRepository) GetClan(id uint) (models.Clan, error) {
+	clan, err := scanClan(repo.db.QueryRow(clanQuery+" WHERE c.id = $1 GROUP BY c.id", id))
+	if err == sql.ErrNoRows {
+		return models.Clan{}, helper.ErrClanNotFound
+	} else if err != nil {
+		return models.Clan{}, fmt.Errorf("failed to get clan: %v", err)
+	}
+	return clan, nil
+}
+
+func (repo *ClanRepository) GetClans(sortBy, sortOrder, filterName string, page, pageSize int) ([]models.Clan, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	query := clanQuery + `
+		WHERE ($1 = '' OR c.name ILIKE $1 OR c.tag = UPPER($4))
+		GROUP BY c.id
+		ORDER BY %s %s, c.id
+		LIMIT $2 OFFSET $3
+	`
+
+	sortColumns := map[string]string{"name": "c.name", "members": "COUNT(m.id)", "trophies": "COALESCE(SUM(u.awards), 0)", "minTrophies": "c.min_trophies"}
+	column, ok := sortColumns[sortBy]
+	if !ok {
+		column = "c.id"
+	}
+	if sortOrder != "asc" && sortOrder != "desc" {
+		sortOrder = "asc"
+	}
+
+	query = fmt.Sprintf(query, column, sortOrder)
+
+	pattern := ""
+	if filterName != "" {
+		pattern = "%" + escapeLike(filterName) + "%"
+	}
+	rows, err := repo.db.Query(query, pattern, pageSize, (page-1)*pageSize, filterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clans: %v", err)
+	}
+	defer rows.Close()
+
+	var clans []models.Clan
+	for rows.Next() {
+		clan, err := scanClan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan clan: %v", err)
+		}
+		clans = append(clans, clan)
+	}
+
+	return clans, nil
+}
+
+func (repo *ClanRepository) UpdateClan(clan models.Clan) error {
+	query := `
+		UPDATE clans SET description = $1, membership = $2, min_trophies = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+	`
+	_, err := repo.db.Exec(query, clan.Description, clan.Membership, clan.MinTrophies, clan.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update clan: %v", err)
+	}
+	return nil
+}
+
+const clanMemberQuery = `
+	SELECT m.clan_id, m.user_id, u.username, u.awards, m.role, m.donations, m.created_at
+	FROM clan_members m
+	JOIN users u ON u.id = m.user_id
+`
+
+func scanClanMember(row rowScanner) (models.ClanMember, error) {
+	var member models.ClanMember
+	err := row.Scan(
+		&member.ClanID,
+		&member.UserID,
+		&member.Username,
+		&member.Awards,
+		&member.Role,
+		&member.Donations,
+		&member.JoinedAt,
+	)
+	return member, err
+}
+
+func (repo *ClanRepository) GetMembership(userID uint) (models.ClanMember, error) {
+	member, err := scanClanMember(repo.db.QueryRow(clanMemberQuery+" WHERE m.user_id = $1", userID))
+	if err == sql.ErrNoRows {
+		return models.ClanMember{}, helper.ErrNotInClan
+	} else if err != nil {
+		return models.ClanMember{}, fmt.Errorf("failed to get clan membership: %v", err)
+	}
+	return member, nil
+}
+
+func (repo *ClanRepository) GetMembers(clanID uint) ([]models.ClanMember, error) {
+	rows, err := repo.db.Query(clanMemberQuery+" WHERE m.clan_id = $1 ORDER BY u.awards DESC, u.username", clanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clan members: %v", err)
+	}
+	defer rows.Close()
+
+	var members []models.ClanMember
+	for rows.Next() {
+		member, err := scanClanMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan clan member: %v", err)
+		}
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// AddMember locks the clan row so two joins can't both take the last slot.
+// It also drops the player's other pending join requests.
+func (repo *ClanRepository) AddMember(clanID, userID uint, maxMembers int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var lockedID uint
+	if err := tx.QueryRow("SELECT id FROM clans WHERE id = $1 FOR UPDATE", clanID).Scan(&lockedID); err == sql.ErrNoRows {
+		return helper.ErrClanNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to lock clan: %v", err)
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM clan_members WHERE clan_id = $1", clanID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count clan members: %v", err)
+	}
+	if count >= maxMembers {
+		return helper.ErrClanFull
+	}
+
+	_, err = tx.Exec("INSERT INTO clan_members (clan_id, user_id, role) VALUES ($1, $2, $3)", clanID, userID, models.ClanRoleMember)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrAlreadyInClan
+	} else if err != nil {
+		return fmt.Errorf("failed to add clan member: %v", err)
+	}
+	if _, err := tx.Exec("DELETE FROM clan_join_requests WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to clear join requests: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// LeaveClan removes the member. A leaving leader hands the clan to the
+// highest-ranked, longest-serving member; the last member leaving disbands it.
+func (repo *ClanRepository) LeaveClan(clanID, userID uint) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRow("DELETE FROM clan_members WHERE clan_id = $1 AND user_id = $2 RETURNING role", clanID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return helper.ErrNotInClan
+	} else if err != nil {
+		return fmt.Errorf("failed to leave clan: %v", err)
+	}
+	// Nobody in the clan can fill the requests of someone who left.
+	if _, err := tx.Exec("UPDATE clan_card_requests SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE user_id = $2 AND status = $3", models.CardRequestCancelled, userID, models.CardRequestOpen); err != nil {
+		return fmt.Errorf("failed to cancel card requests: %v", err)
+	}
+
+	var remaining int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM clan_members WHERE clan_id = $1", clanID).Scan(&remaining); err != nil {
+		return fmt.Errorf("failed to count clan members: %v", err)
+	}
+
+	if remaining == 0 {
+		statements := []string{
+			"DELETE FROM clan_donations WHERE request_id IN (SELECT id FROM clan_card_requests WHERE clan_id = $1)",
+			"DELETE FROM clan_card_requests WHERE clan_id = $1",
+			"DELETE FROM clan_join_requests WHERE clan_id = $1",
+			"UPDATE chat_messages SET clan_id = NULL WHERE clan_id = $1",
+			"DELETE FROM clans WHERE id = $1",
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement, clanID); err != nil {
+				return fmt.Errorf("failed to disband clan: %v", err)
+			}
+		}
+	} else if role == models.ClanRoleLeader {
+		successorQuery := `
+			UPDATE clan_members SET role = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = (
+				SELECT id FROM clan_members WHERE clan_id = $2
+				ORDER BY CASE role WHEN 'CO_LEADER' THEN 1 WHEN 'ELDER' THEN 2 ELSE 3 END, created_at
+				LIMIT 1
+			)
+		`
+		if _, err := tx.Exec(successorQuery, models.ClanRoleLeader, clanID); err != nil {
+			return fmt.Errorf("failed to pick new leader: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo *ClanRepository) SetRole(clanID, userID uint, role string) error {
+	query := `UPDATE clan_members SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE clan_id = $2 AND user_id = $3`
+	_, err := repo.db.Exec(query, role, clanID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set clan role: %v", err)
+	}
+	return nil
+}
+
+// TransferLeadership makes newLeaderID the leader and the old leader a co-leader.
+func (repo *ClanRepository) TransferLeadership(clanID, leaderID, newLeaderID uint) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE clan_members SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE clan_id = $2 AND user_id = $3`
+	if _, err := tx.Exec(query, models.ClanRoleCoLeader, clanID, leaderID); err != nil {
+		return fmt.Errorf("failed to demote leader: %v", err)
+	}
+	if _, err := tx.Exec(query, models.ClanRoleLeader, clanID, newLeaderID); err != nil {
+		return fmt.Errorf("failed to promote leader: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (repo *ClanRepository) CreateJoinRequest(clanID, userID uint) error {
+	query := `
+		INSERT INTO clan_join_requests (clan_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (clan_id, user_id) DO NOTHING
+	`
+	_, err := repo.db.Exec(query, clanID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to create join request: %v", err)
+	}
+	return nil
+}
+
+const clanJoinRequestQuery = `
+	SELECT r.id, r.created_at, r.clan_id, r.user_id, u.username, u.awards
+	FROM clan_join_requests r
+	JOIN users u ON u.id = r.user_id
+`
+
+func scanClanJoinRequest(row rowScanner) (models.ClanJoinRequest, error) {
+	var request models.ClanJoinRequest
+	err := row.Scan(&request.ID, &request.CreatedAt, &request.ClanID, &request.UserID, &request.Username, &request.Awards)
+	return request, err
+}
+
+func (repo *ClanRepository) GetJoinRequests(clanID uint) ([]models.ClanJoinRequest, error) {
+	rows, err := repo.db.Query(clanJoinRequestQuery+" WHERE r.clan_id = $1 AND u.deleted_at IS NULL ORDER BY r.created_at", clanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join requests: %v", err)
+	}
+	defer rows.Close()
+
+	var requests []models.ClanJoinRequest
+	for rows.Next() {
+		request, err := scanClanJoinRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan join request: %v", err)
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+func (repo *ClanRepository) GetJoinRequest(id uint) (models.ClanJoinRequest, error) {
+	request, err := scanClanJoinRequest(repo.db.QueryRow(clanJoinRequestQuery+" WHERE r.id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.ClanJoinRequest{}, helper.ErrJoinRequestNotFound
+	} else if err != nil {
+		return models.ClanJoinRequest{}, fmt.Errorf("failed to get join request: %v", err)
+	}
+	return request, nil
+}
+
+func (repo *ClanRepository) DeleteJoinRequest(id uint) error {
+	_, err := repo.db.Exec("DELETE FROM clan_join_requests WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete join request: %v", err)
+	}
+	return nil
+}
+
+func (repo *ClanRepository) CreateCardRequest(request *models.CardRequest) error {
+	query := `
+		INSERT INTO clan_card_requests (clan_id, user_id, card_kind, card_id, requested, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	request.Status = models.CardRequestOpen
+	err := repo.db.QueryRow(query, request.ClanID, request.UserID, request.CardKind, request.CardID, request.Requested, request.Status).
+		Scan(&request.ID, &request.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create card request: %v", err)
+	}
+	return nil
+}
+
+const cardRequestQuery = `
+	SELECT r.id, r.created_at, r.clan_id, r.user_id, u.username, r.card_kind, r.card_id,
+	       COALESCE(h.name, s.name, ''), r.requested, r.donated, r.status
+	FROM clan_card_requests r
+	JOIN users u ON u.id = r.user_id
+	LEFT JOIN heros h ON r.card_kind = 'HERO' AND h.id = r.card_id
+	LEFT JOIN spells s ON r.card_kind = 'SPELL' AND s.id = r.card_id
+`
+
+func scanCardRequest(row rowScanner) (models.CardRequest, error) {
+	var request models.CardRequest
+	err := row.Scan(
+		&request.ID,
+		&request.CreatedAt,
+		&request.ClanID,
+		&request.UserID,
+		&request.Username,
+		&request.CardKind,
+		&request.CardID,
+		&request.CardName,
+		&request.Requested,
+		&request.Donated,
+		&request.Status,
+	)
+	return request, err
+}
+
+func (repo *ClanRepository) GetCardRequest(id uint) (models.CardRequest, error) {
+	request, err := scanCardRequest(repo.db.QueryRow(cardRequestQuery+" WHERE r.id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.CardRequest{}, helper.ErrCardRequestNotFound
+	} else if err != nil {
+		return models.CardRequest{}, fmt.Errorf("failed to get card request: %v", err)
+	}
+	return request, nil
+}
+
+func (repo *ClanRepository) GetOpenCardRequests(clanID uint) ([]models.CardRequest, error) {
+	rows, err := repo.db.Query(cardRequestQuery+" WHERE r.clan_id = $1 AND r.status = $2 ORDER BY r.created_at", clanID, models.CardRequestOpen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get card requests: %v", err)
+	}
+	defer rows.Close()
+
+	var requests []models.CardRequest
+	for rows.Next() {
+		request, err := scanCardRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan card request: %v", err)
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+func (repo *ClanRepository) GetLastCardRequestTime(userID uint) (time.Time, bool, error) {
+	var last pq.NullTime
+	err := repo.db.QueryRow("SELECT MAX(created_at) FROM clan_card_requests WHERE user_id = $1", userID).Scan(&last)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get last card request: %v", err)
+	}
+	return last.Time, last.Valid, nil
+}
+
+// Donate moves one copy of the requested card from the donor to the
+// requester and pays the donor. The donor must keep at least one copy.
+// The request row is locked so concurrent donations can't overfill it, and
+// the donor's copies so two donations can't both give away the last spare.
+func (repo *ClanRepository) Donate(requestID, donorID uint, gold int64) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var request models.CardRequest
+	lockQuery := `
+		SELECT user_id, card_kind, card_id, requested, donated, status
+		FROM clan_card_requests WHERE id = $1 FOR UPDATE
+	`
+	err = tx.QueryRow(lockQuery, requestID).Scan(&request.UserID, &request.CardKind, &request.CardID, &request.Requested, &request.Donated, &request.Status)
+	if err == sql.ErrNoRows {
+		return helper.ErrCardRequestNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to lock card request: %v", err)
+	}
+	if request.Status == models.CardRequestCancelled {
+		return helper.ErrCardRequestCancelled
+	}
+	if request.Status != models.CardRequestOpen || request.Donated >= request.Requested {
+		return helper.ErrCardRequestFilled
+	}
+
+	table, column := "user_heros", "hero_id"
+	if request.CardKind == models.CardKindSpell {
+		table, column = "user_spells", "spell_id"
+	}
+
+	// Locking the donor's copies makes concurrent donations of the same card
+	// wait for each other, so the second one counts what the first left.
+	lockCopies := fmt.Sprintf("SELECT id FROM %s WHERE user_id = $1 AND %s = $2 ORDER BY id DESC FOR UPDATE", table, column)
+	rows, err := tx.Query(lockCopies, donorID, request.CardID)
+	if err != nil {
+		return fmt.Errorf("failed to lock donor cards: %v", err)
+	}
+	var copies []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan donor card: %v", err)
+		}
+		copies = append(copies, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to lock donor cards: %v", err)
+	}
+	if len(copies) < 2 {
+		return helper.ErrNoSpareCard
+	}
+
+	moveQuery := fmt.Sprintf("UPDATE %s SET user_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", table)
+	if _, err := tx.Exec(moveQuery, request.UserID, copies[0]); err != nil {
+		return fmt.Errorf("failed to move card: %v", err)
+	}
+
+	if err := creditWallet(tx, donorID, models.CurrencyGold, gold, models.ReasonClanDonation, fmt.Sprintf("request:%d", requestID)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE clan_members SET donations = donations + 1 WHERE user_id = $1", donorID); err != nil {
+		return fmt.Errorf("failed to count donation: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO clan_donations (request_id, donor_id, gold) VALUES ($1, $2, $3)", requestID, donorID, gold); err != nil {
+		return fmt.Errorf("failed to record donation: %v", err)
+	}
+
+	status := models.CardRequestOpen
+	if request.Donated+1 >= request.Requested {
+		status = models.CardRequestFilled
+	}
+	updateQuery := `UPDATE clan_card_requests SET donated = donated + 1, status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	if _, err := tx.Exec(updateQuery, status, requestID); err != nil {
+		return fmt.Errorf("failed to update card request: %v", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,495 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"math/rand"
+	"time"
+)
+
+type DraftRepo interface {
+	QueueDraft(userID uint, deckSize, pickSeconds int32, now time.Time) (models.Draft, bool, error)
+	LeaveDraftQueue(userID uint) error
+	GetDraft(id uint) (models.Draft, error)
+	GetActiveDraft(userID uint) (models.Draft, error)
+	Pick(draftID, userID uint, kind string, cardID uint, now time.Time) (models.Draft, error)
+	GetOverdueDrafts(now time.Time) ([]uint, error)
+	AutoPick(id uint, now time.Time) (models.Draft, bool, error)
+}
+
+type DraftRepository struct {
+	db *sql.DB
+}
+
+func NewDraftRepository(db *sql.DB) *DraftRepository {
+	return &DraftRepository{db}
+}
+
+const draftQuery = `
+	SELECT d.id, d.created_at, d.player1_id, COALESCE(u1.username, ''), d.player2_id, COALESCE(u2.username, ''),
+	       d.status, d.deck_size, d.pick_seconds, d.pick_number, d.offer, d.pick_deadline, d.match_id
+	FROM drafts d
+	LEFT JOIN users u1 ON u1.id = d.player1_id
+	LEFT JOIN users u2 ON u2.id = d.player2_id
+`
+
+func scanDraft(row rowScanner) (models.Draft, error) {
+	var draft models.Draft
+	var offer []byte
+	err := row.Scan(
+		&draft.ID,
+		&draft.CreatedAt,
+		&draft.Player1ID,
+		&draft.Player1Name,
+		&draft.Player2ID,
+		&draft.Player2Name,
+		&draft.Status,
+		&draft.DeckSize,
+		&draft.PickSeconds,
+		&draft.PickNumber,
+		&offer,
+		&draft.PickDeadline,
+		&draft.MatchID,
+	)
+	if err != nil {
+		return models.Draft{}, err
+	}
+	if err := json.Unmarshal(offer, &draft.Offer); err != nil {
+		return models.Draft{}, fmt.Errorf("failed to decode draft offer: %v", err)
+	}
+	return draft, nil
+}
+
+// getDraftPicks fills in the draft's picks in the order they were made.
+func getDraftPicks(db queryer, draft *models.Draft) error {
+	rows, err := db.Query("SELECT created_at, user_id, pick_number, card, auto FROM draft_picks WHERE draft_id = $1 ORDER BY pick_number", draft.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get draft picks: %v", err)
+	}
+	defer rows.Close()
+
+	draft.Picks = nil
+	for rows.Next() {
+		var pick models.DraftPick
+		var card []byte
+		if err := rows.Scan(&pick.CreatedAt, &pick.UserID, &pick.PickNumber, &card, &pick.Auto); err != nil {
+			return fmt.Errorf("failed to scan draft pick: %v", err)
+		}
+		if err := json.Unmarshal(card, &pick.Card); err != nil {
+			return fmt.Errorf("failed to decode draft pick: %v", err)
+		}
+		draft.Picks = append(draft.Picks, pick)
+	}
+	return nil
+}
+
+func (repo *DraftRepository) getDraft(where string, args ...interface{}) (models.Draft, error) {
+	draft, err := scanDraft(repo.db.QueryRow(draftQuery+where, args...))
+	if err == sql.ErrNoRows {
+		return models.Draft{}, helper.ErrDraftNotFound
+	} else if err != nil {
+		return models.Draft{}, fmt.Errorf("failed to get draft: %v", err)
+	}
+	if err := getDraftPicks(repo.db, &draft); err != nil {
+		return models.Draft{}, err
+	}
+	return draft, nil
+}
+
+func (repo *DraftRepository) GetDraft(id uint) (models.Draft, error) {
+	return repo.getDraft(" WHERE d.id = $1", id)
+}
+
+// GetActiveDraft returns the draft the player is picking in right now.
+func (repo *DraftRepository) GetActiveDraft(userID uint) (models.Draft, error) {
+	return repo.getDraft(" WHERE d.status = $1 AND (d.player1_id = $2 OR d.player2_id = $2)", models.DraftDrafting, userID)
+}
+
+// QueueDraft puts the player in the draft queue, or starts a draft against
+// the player who has waited longest, who picks first. It reports whether a
+// draft started.
+func (repo *DraftRepository) QueueDraft(userID uint, deckSize, pickSeconds int32, now time.Time) (models.Draft, bool, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Same as the team queue: players queueing at the same time must see
+	// each other, and each is matched once.
+	if _, err := tx.Exec("LOCK TABLE draft_queue IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to lock queue: %v", err)
+	}
+
+	var drafting bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM drafts WHERE status = $1 AND (player1_id = $2 OR player2_id = $2))", models.DraftDrafting, userID).Scan(&drafting)
+	if err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to check drafts: %v", err)
+	}
+	if drafting {
+		return models.Draft{}, false, helper.ErrAlreadyDrafting
+	}
+
+	// A player's last pick still needs two cards to choose from.
+	var cards int32
+	err = tx.QueryRow("SELECT (SELECT COUNT(*) FROM heros WHERE deleted_at IS NULL) + (SELECT COUNT(*) FROM spells WHERE deleted_at IS NULL)").Scan(&cards)
+	if err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to count catalog: %v", err)
+	}
+	if cards <= deckSize {
+		return models.Draft{}, false, helper.ErrDraftPoolTooSmall
+	}
+
+	var queueID, opponentID uint
+	err = tx.QueryRow("SELECT id, user_id FROM draft_queue WHERE user_id <> $1 ORDER BY id LIMIT 1", userID).Scan(&queueID, &opponentID)
+	if err == sql.ErrNoRows {
+		_, err = tx.Exec("INSERT INTO draft_queue (user_id) VALUES ($1)", userID)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return models.Draft{}, false, helper.ErrAlreadyQueued
+		} else if err != nil {
+			return models.Draft{}, false, fmt.Errorf("failed to join queue: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return models.Draft{}, false, fmt.Errorf("failed to commit queue: %v", err)
+		}
+		return models.Draft{}, false, nil
+	} else if err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to get queue: %v", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM draft_queue WHERE id = $1", queueID); err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to leave queue: %v", err)
+	}
+
+	draft := models.Draft{
+		Player1ID:    sql.NullInt64{Int64: int64(opponentID), Valid: true},
+		Player2ID:    sql.NullInt64{Int64: int64(userID), Valid: true},
+		Status:       models.DraftDrafting,
+		DeckSize:     deckSize,
+		PickSeconds:  pickSeconds,
+		PickDeadline: now.Add(time.Duration(pickSeconds) * time.Second),
+	}
+	if draft.Offer, err = drawOffer(tx, draft); err != nil {
+		return models.Draft{}, false, err
+	}
+	offer, err := json.Marshal(draft.Offer)
+	if err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to encode draft offer: %v", err)
+	}
+	query := `
+		INSERT INTO drafts (player1_id, player2_id, status, deck_size, pick_seconds, offer, pick_deadline)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	err = tx.QueryRow(query, draft.Player1ID, draft.Player2ID, draft.Status, draft.DeckSize, draft.PickSeconds, offer, draft.PickDeadline).Scan(&draft.ID)
+	if err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to create draft: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to commit draft: %v", err)
+	}
+	draft, err = repo.GetDraft(draft.ID)
+	return draft, err == nil, err
+}
+
+func (repo *DraftRepository) LeaveDraftQueue(userID uint) error {
+	result, err := repo.db.Exec("DELETE FROM draft_queue WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("failed to leave queue: %v", err)
+	}
+	if left, _ := result.RowsAffected(); left == 0 {
+		return helper.ErrNotQueued
+	}
+	return nil
+}
+
+// drawOffer deals two random catalog cards for the draft's next pick,
+// leaving out the ones the picking player already has.
+func drawOffer(tx *sql.Tx, draft models.Draft) ([]models.DraftCard, error) {
+	picking := draft.Picking()
+	heroIDs, spellIDs := []int64{}, []int64{}
+	for _, pick := range draft.Picks {
+		if pick.UserID != picking {
+			continue
+		}
+		if pick.Card.Hero != nil {
+			heroIDs = append(heroIDs, int64(pick.Card.Hero.ID))
+		} else if pick.Card.Spell != nil {
+			spellIDs = append(spellIDs, int64(pick.Card.Spell.ID))
+		}
+	}
+
+	query := `
+		SELECT kind, id FROM (
+			SELECT $1::text AS kind, id FROM heros WHERE deleted_at IS NULL AND id <> ALL($3)
+			UNION ALL
+			SELECT $2::text AS kind, id FROM spells WHERE deleted_at IS NULL AND id <> ALL($4)
+		) cards
+		ORDER BY random()
+		LIMIT 2
+	`
+	rows, err := tx.Query(query, models.DraftCardHero, models.DraftCardSpell, pq.Array(heroIDs), pq.Array(spellIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to draw draft offer: %v", err)
+	}
+	type drawn struct {
+		kind string
+		id   uint
+	}
+	var cards []drawn
+	for rows.Next() {
+		var card drawn
+		if err := rows.Scan(&card.kind, &card.id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan draft offer: %v", err)
+		}
+		cards = append(cards, card)
+	}
+	rows.Close()
+	if len(cards) == 0 {
+		return nil, helper.ErrDraftPoolTooSmall
+	}
+
+	offer := make([]models.DraftCard, 0, len(cards))
+	for _, card := range cards {
+		offered := models.DraftCard{Kind: card.kind}
+		if card.kind == models.DraftCardHero {
+			offered.Hero, err = getDraftHero(tx, card.id)
+		} else {
+			offered.Spell, err = getDraftSpell(tx, card.id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		offer = append(offer, offered)
+	}
+	return offer, nil
+}
+
+func getDraftHero(tx *sql.Tx, id uint) (*models.Hero, error) {
+	query := `
+		SELECT id, created_at, updated_at, name, description, rarity, damage_type, effect,
+		       hitpoint, damage, cost_elixir, damage_tower, speed, price
+		FROM heros WHERE id = $1
+	`
+	var hero models.Hero
+	err := tx.QueryRow(query, id).Scan(
+		&hero.ID,
+		&hero.CreatedAt,
+		&hero.UpdatedAt,
+		&hero.Name,
+		&hero.Description,
+		&hero.Rarity,
+		&hero.DamageType,
+		&hero.Effect,
+		&hero.Hitpoint,
+		&hero.Damage,
+		&hero.CostElixir,
+		&hero.DamageTower,
+		&hero.Speed,
+		&hero.Price,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hero by ID: %v", err)
+	}
+	return &hero, nil
+}
+
+func getDraftSpell(tx *sql.Tx, id uint) (*models.Spell, error) {
+	query := `
+		SELECT id, created_at, updated_at, name, description, area, damage_type, damage, duration, effect, price
+		FROM spells WHERE id = $1
+	`
+	var spell models.Spell
+	err := tx.QueryRow(query, id).Scan(
+		&spell.ID,
+		&spell.CreatedAt,
+		&spell.UpdatedAt,
+		&spell.Name,
+		&spell.Description,
+		&spell.Area,
+		&spell.DamageType,
+		&spell.Damage,
+		&spell.Duration,
+		&spell.Effect,
+		&spell.Price,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spell by ID: %v", err)
+	}
+	return &spell, nil
+}
+
+func lockDraft(tx *sql.Tx, id uint) (models.Draft, error) {
+	draft, err := scanDraft(tx.QueryRow(draftQuery+" WHERE d.id = $1 FOR UPDATE OF d", id))
+	if err == sql.ErrNoRows {
+		return models.Draft{}, helper.ErrDraftNotFound
+	} else if err != nil {
+		return models.Draft{}, fmt.Errorf("failed to lock draft: %v", err)
+	}
+	if err := getDraftPicks(tx, &draft); err != nil {
+		return models.Draft{}, err
+	}
+	return draft, nil
+}
+
+// Pick takes one of the offered cards for the player on turn. Picks are only
+// taken until the deadline; after it the card is picked for them.
+func (repo *DraftRepository) Pick(draftID, userID uint, kind string, cardID uint, now time.Time) (models.Draft, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.Draft{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	draft, err := lockDraft(tx, draftID)
+	if err != nil {
+		return models.Draft{}, err
+	}
+	if draft.Player1ID.Int64 != int64(userID) && draft.Player2ID.Int64 != int64(userID) {
+		return models.Draft{}, helper.ErrDraftNotFound
+	}
+	if draft.Status != models.DraftDrafting || draft.Picking().Int64 != int64(userID) {
+		return models.Draft{}, helper.ErrNotYourPick
+	}
+	if now.After(draft.PickDeadline) {
+		return models.Draft{}, helper.ErrPickTimedOut
+	}
+
+	offered := -1
+	for i, card := range draft.Offer {
+		if card.Kind == kind && card.CardID() == cardID {
+			offered = i
+			break
+		}
+	}
+	if offered == -1 {
+		return models.Draft{}, helper.ErrCardNotOffered
+	}
+
+	if err := applyPick(tx, &draft, draft.Offer[offered], false, now); err != nil {
+		return models.Draft{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Draft{}, fmt.Errorf("failed to commit pick: %v", err)
+	}
+	return draft, nil
+}
+
+// GetOverdueDrafts returns the drafts whose pick deadline has passed.
+func (repo *DraftRepository) GetOverdueDrafts(now time.Time) ([]uint, error) {
+	rows, err := repo.db.Query("SELECT id FROM drafts WHERE status = $1 AND pick_deadline < $2 ORDER BY id", models.DraftDrafting, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overdue drafts: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan draft id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// AutoPick picks one of the offered cards at random for a player who let the
+// deadline pass. The draft is locked and its deadline checked again, so
+// running it twice, or on several servers, picks once. It reports whether a
+// card was picked.
+func (repo *DraftRepository) AutoPick(id uint, now time.Time) (models.Draft, bool, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	draft, err := lockDraft(tx, id)
+	if err != nil {
+		return models.Draft{}, false, err
+	}
+	if draft.Status != models.DraftDrafting || !now.After(draft.PickDeadline) || len(draft.Offer) == 0 {
+		return draft, false, nil
+	}
+
+	if err := applyPick(tx, &draft, draft.Offer[rand.Intn(len(draft.Offer))], true, now); err != nil {
+		return models.Draft{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Draft{}, false, fmt.Errorf("failed to commit pick: %v", err)
+	}
+	return draft, true, nil
+}
+
+// applyPick records the pick and deals the next offer. The last pick ends the
+// draft and starts the match with the drafted decks.
+func applyPick(tx *sql.Tx, draft *models.Draft, card models.DraftCard, auto bool, now time.Time) error {
+	encoded, err := json.Marshal(card)
+	if err != nil {
+		return fmt.Errorf("failed to encode draft pick: %v", err)
+	}
+	pick := models.DraftPick{CreatedAt: now, UserID: draft.Picking(), PickNumber: draft.PickNumber, Card: card, Auto: auto}
+	_, err = tx.Exec(
+		"INSERT INTO draft_picks (created_at, draft_id, user_id, pick_number, card, auto) VALUES ($1, $2, $3, $4, $5, $6)",
+		pick.CreatedAt, draft.ID, pick.UserID, pick.PickNumber, encoded, pick.Auto,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record pick: %v", err)
+	}
+	draft.Picks = append(draft.Picks, pick)
+	draft.PickNumber++
+
+	if draft.PickNumber < 2*draft.DeckSize {
+		if draft.Offer, err = drawOffer(tx, *draft); err != nil {
+			return err
+		}
+		draft.PickDeadline = now.Add(time.Duration(draft.PickSeconds) * time.Second)
+	} else {
+		match := models.Match{Mode: models.MatchModeDraft, Ranked: false}
+		for _, player := range []struct {
+			id   sql.NullInt64
+			name string
+		}{{draft.Player1ID, draft.Player1Name}, {draft.Player2ID, draft.Player2Name}} {
+			deck := models.DeckSnapshot{Name: "Draft"}
+			for _, pick := range draft.Picks {
+				if pick.UserID != player.id {
+					continue
+				}
+				if pick.Card.Hero != nil {
+					deck.Heroes = append(deck.Heroes, *pick.Card.Hero)
+				} else if pick.Card.Spell != nil {
+					deck.Spells = append(deck.Spells, *pick.Card.Spell)
+				}
+			}
+			match.Players = append(match.Players, models.MatchPlayer{UserID: uint(player.id.Int64), Username: player.name, Deck: deck})
+		}
+		if err := insertMatch(tx, &match); err != nil {
+			return err
+		}
+		draft.Status = models.DraftFinished
+		draft.Offer = []models.DraftCard{}
+		draft.MatchID = sql.NullInt64{Int64: int64(match.ID), Valid: true}
+	}
+
+	offer, err := json.Marshal(draft.Offer)
+	if err != nil {
+		return fmt.Errorf("failed to encode draft offer: %v", err)
+	}
+	query := `
+		UPDATE drafts
+		SET status = $1, pick_number = $2, offer = $3, pick_deadline = $4, match_id = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+	`
+	_, err = tx.Exec(query, draft.Status, draft.PickNumber, offer, draft.PickDeadline, draft.MatchID, draft.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update draft: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strings"
+)
+
+type FriendRepo interface {
+	GetFriendship(userID, otherID uint) (models.Friendship, error)
+	GetFriendshipByID(id uint) (models.Friendship, error)
+	CreateFriendRequest(requesterID, addresseeID uint) (models.Friendship, error)
+	AcceptFriendRequest(id uint) error
+	DeleteFriendship(id uint) error
+	GetFriends(userID uint) ([]models.Friend, error)
+	AreFriends(userID, otherID uint) (bool, error)
+	GetFriendRequests(userID uint) ([]models.FriendRequest, error)
+	BlockUser(blockerID, blockedID uint) error
+	UnblockUser(blockerID, blockedID uint) error
+	IsBlocked(userID, otherID uint) (bool, error)
+	GetBlockedUsers(userID uint) ([]models.BlockedUser, error)
+	SearchPlayers(userID uint, username string, limit int) ([]models.PlayerSearchResult, error)
+}
+
+type FriendRepository struct {
+	db *sql.DB
+}
+
+func NewFriendRepository(db *sql.DB) *FriendRepository {
+	return &FriendRepository{db}
+}
+
+const friendshipColumns = "id, created_at, updated_at, requester_id, addressee_id, status"
+
+func scanFriendship(row *sql.Row) (models.Friendship, error) {
+	var friendship models.Friendship
+	err := row.Scan(
+		&friendship.ID,
+		&friendship.CreatedAt,
+		&friendship.UpdatedAt,
+		&friendship.RequesterID,
+		&friendship.AddresseeID,
+		&friendship.Status,
+	)
+	if err == sql.ErrNoRows {
+		return models.Friendship{}, helper.ErrFriendshipNotFound
+	} else if err != nil {
+		return models.Friendship{}, fmt.Errorf("failed to get friendship: %w", err)
+	}
+	return friendship, nil
+}
+
+// GetFriendship finds the request or friendship between two players,
+// whichever of them started it.
+func (repo *FriendRepository) GetFriendship(userID, otherID uint) (models.Friendship, error) {
+	query := `
+		SELECT ` + friendshipColumns + ` FROM friendships
+		WHERE (requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1)
+	`
+	return scanFriendship(repo.db.QueryRow(query, userID, otherID))
+}
+
+func (repo *FriendRepository) GetFriendshipByID(id uint) (models.Friendship, error) {
+	query := `SELECT ` + friendshipColumns + ` FROM friendships WHERE id = $1`
+	return scanFriendship(repo.db.QueryRow(query, id))
+}
+
+// CreateFriendRequest returns helper.ErrFriendshipExists when the two players
+// already have a request or friendship, e.g. one sent at the same moment.
+func (repo *FriendRepository) CreateFriendRequest(requesterID, addresseeID uint) (models.Friendship, error) {
+	query := `
+		INSERT INTO friendships (requester_id, addressee_id, status)
+		VALUES ($1, $2, $3)
+		RETURNING ` + friendshipColumns
+	friendship, err := scanFriendship(repo.db.QueryRow(query, requesterID, addresseeID, models.FriendshipPending))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return models.Friendship{}, helper.ErrFriendshipExists
+	} else if err != nil {
+		return models.Friendship{}, fmt.Errorf("failed to create friend request: %v", err)
+	}
+	return friendship, nil
+}
+
+func (repo *FriendRepository) AcceptFriendRequest(id uint) error {
+	query := `UPDATE friendships SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	_, err := repo.db.Exec(query, models.FriendshipAccepted, id)
+	if err != nil {
+		return fmt.Errorf("failed to accept friend request: %v", err)
+	}
+	return nil
+}
+
+func (repo *FriendRepository) DeleteFriendship(id uint) error {
+	_, err := repo.db.Exec("DELETE FROM friendships WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete friendship: %v", err)
+	}
+	return nil
+}
+
+func (repo *FriendRepository) GetFriends(userID uint) ([]models.Friend, error) {
+	query := `
+		SELECT u.id, u.username, u.awards, f.updated_at
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.requester_id = $1 THEN f.addressee_id ELSE f.requester_id END
+		WHERE (f.requester_id = $1 OR f.addressee_id = $1) AND f.status = $2 AND u.deleted_at IS NULL
+		ORDER BY u.username
+	`
+
+	rows, err := repo.db.Query(query, userID, models.FriendshipAccepted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friends: %v", err)
+	}
+	defer rows.Close()
+
+	var friends []models.Friend
+	for rows.Next() {
+		var friend models.Friend
+		if err := rows.Scan(&friend.UserID, &friend.Username, &friend.Awards, &friend.Since); err != nil {
+			return nil, fmt.Errorf("failed to scan friend: %v", err)
+		}
+		friends = append(friends, friend)
+	}
+
+	return friends, nil
+}
+
+func (repo *FriendRepository) AreFriends(userID, otherID uint) (bool, error) {
+	friendship, err := repo.GetFriendship(userID, otherID)
+	if err == helper.ErrFriendshipNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return friendship.Status == models.FriendshipAccepted, nil
+}
+
+// GetFriendRequests returns pending requests the user sent or received.
+func (repo *FriendRepository) GetFriendRequests(userID uint) ([]models.FriendRequest, error) {
+	query := `
+		SELECT f.id, f.created_at, f.requester_id, r.username, f.addressee_id, a.username
+		FROM friendships f
+		JOIN users r ON r.id = f.requester_id
+		JOIN users a ON a.id = f.addressee_id
+		WHERE (f.requester_id = $1 OR f.addressee_id = $1) AND f.status = $2
+		  AND r.deleted_at IS NULL AND a.deleted_at IS NULL
+		ORDER BY f.created_at DESC
+	`
+
+	rows, err := repo.db.Query(query, userID, models.FriendshipPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friend requests: %v", err)
+	}
+	defer rows.Close()
+
+	var requests []models.FriendRequest
+	for rows.Next() {
+		var request models.FriendRequest
+		if err := rows.Scan(&request.ID, &request.CreatedAt, &request.RequesterID, &request.RequesterName, &request.AddresseeID, &request.AddresseeName); err != nil {
+			return nil, fmt.Errorf("failed to scan friend request: %v", err)
+		}
+		requests = append(requests, request)
+	}
+
+	return requests, nil
+}
+
+// BlockUser records the block and drops any friendship or pending request
+// between the two players.
+func (repo *FriendRepository) BlockUser(blockerID, blockedID uint) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	blockQuery := `
+		INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`
+	if _, err := tx.Exec(blockQuery, blockerID, blockedID); err != nil {
+		return fmt.Errorf("failed to block user: %v", err)
+	}
+	deleteQuery := `
+		DELETE FROM friendships
+		WHERE (requester_id = $1 AND addressee_id = $2) OR (requester_id = $2 AND addressee_id = $1)
+	`
+	if _, err := tx.Exec(deleteQuery, blockerID, blockedID); err != nil {
+		return fmt.Errorf("failed to remove friendship: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (repo *FriendRepository) UnblockUser(blockerID, blockedID uint) error {
+	_, err := repo.db.Exec("DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2", blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user: %v", err)
+	}
+	return nil
+}
+
+// IsBlocked reports whether either player has blocked the other.
+func (repo *FriendRepository) IsBlocked(userID, otherID uint) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+		)
+	`
+	var blocked bool
+	if err := repo.db.QueryRow(query, userID, otherID).Scan(&blocked); err != nil {
+		return false, fmt.Errorf("failed to check block: %v", err)
+	}
+	return blocked, nil
+}
+
+func (repo *FriendRepository) GetBlockedUsers(userID uint) ([]models.BlockedUser, error) {
+	query := `
+		SELECT u.id, u.username, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC
+	`
+
+	rows, err := repo.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocked users: %v", err)
+	}
+	defer rows.Close()
+
+	var blocked []models.BlockedUser
+	for rows.Next() {
+		var user models.BlockedUser
+		if err := rows.Scan(&user.UserID, &user.Username, &user.BlockedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked user: %v", err)
+		}
+		blocked = append(blocked, user)
+	}
+
+	return blocked, nil
+}
+
+// SearchPlayers matches usernames by prefix, leaving out the searcher and
+// anyone on either side of a block with them.
+func (repo *FriendRepository) SearchPlayers(userID uint, username string, limit int) ([]models.PlayerSearchResult, error) {
+	query := `
+		SELECT u.id, u.username, u.awards
+		FROM users u
+		WHERE u.username ILIKE $2 || '%' AND u.id <> $1 AND u.deleted_at IS NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM user_blocks b
+			WHERE (b.blocker_id = $1 AND b.blocked_id = u.id) OR (b.blocker_id = u.id AND b.blocked_id = $1)
+		  )
+		ORDER BY LENGTH(u.username), u.username
+		LIMIT $3
+	`
+
+	rows, err := repo.db.Query(query, userID, escapeLike(username), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search players: %v", err)
+	}
+	defer rows.Close()
+
+	var players []models.PlayerSearchResult
+	for rows.Next() {
+		var player models.PlayerSearchResult
+		if err := rows.Scan(&player.UserID, &player.Username, &player.Awards); err != nil {
+			return nil, fmt.Errorf("failed to scan player: %v", err)
+		}
+		players = append(players, player)
+	}
+
+	return players, nil
+}
+
+// escapeLike makes user input match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
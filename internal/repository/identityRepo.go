@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+)
+
+type IdentityRepo interface {
+	GetIdentity(provider, subject string) (*models.Identity, error)
+	GetIdentitiesForUser(userID uint) ([]models.Identity, error)
+	CreateIdentity(identity *models.Identity) error
+	DeleteIdentity(userID, identityID uint) error
+}
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) *IdentityRepository {
+	return &IdentityRepository{db}
+}
+
+// GetIdentity returns helper.ErrIdentityNotFound when the identity isn't
+// linked to any account.
+func (repo *IdentityRepository) GetIdentity(provider, subject string) (*models.Identity, error) {
+	query := `
+		SELECT id, created_at, updated_at, deleted_at, user_id, provider, subject, email
+		FROM user_identities WHERE provider = $1 AND subject = $2
+	`
+	var identity models.Identity
+	err := repo.db.QueryRow(query, provider, subject).Scan(
+		&identity.ID,
+		&identity.CreatedAt,
+		&identity.UpdatedAt,
+		&identity.DeletedAt,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+	)
+	if err == sql.ErrNoRows {
+		return nil, helper.ErrIdentityNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %v", err)
+	}
+	return &identity, nil
+}
+
+func (repo *IdentityRepository) GetIdentitiesForUser(userID uint) ([]models.Identity, error) {
+	query := `
+		SELECT id, created_at, updated_at, deleted_at, user_id, provider, subject, email
+		FROM user_identities WHERE user_id = $1
+		ORDER BY id
+	`
+	rows, err := repo.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identities for user: %v", err)
+	}
+	defer rows.Close()
+
+	var identities []models.Identity
+	for rows.Next() {
+		var identity models.Identity
+		err := rows.Scan(
+			&identity.ID,
+			&identity.CreatedAt,
+			&identity.UpdatedAt,
+			&identity.DeletedAt,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %v", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, nil
+}
+
+func (repo *IdentityRepository) CreateIdentity(identity *models.Identity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+	err := repo.db.QueryRow(
+		query,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+	).Scan(&identity.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create identity: %v", err)
+	}
+	return nil
+}
+
+// DeleteIdentity unlinks one of the user's identities. It returns
+// helper.ErrIdentityNotFound when the user has no identity with the id.
+func (repo *IdentityRepository) DeleteIdentity(userID, identityID uint) error {
+	result, err := repo.db.Exec("DELETE FROM user_identities WHERE id = $1 AND user_id = $2", identityID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %v", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return helper.ErrIdentityNotFound
+	}
+	return nil
+}
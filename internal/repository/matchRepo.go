@@ -0,0 +1,360 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type MatchRepo interface {
+	CreateChallenge(challenge *models.Challenge) error
+	GetChallenge(id uint) (models.Challenge, error)
+	GetPendingChallengeByCode(code string) (models.Challenge, error)
+	GetChallengesForUser(userID uint, now time.Time) ([]models.Challenge, error)
+	StartChallenge(challengeID, opponentID uint, now time.Time, players []models.MatchPlayer) (models.Match, error)
+	CloseChallenge(id uint, status string) error
+	ExpireChallenges(now time.Time) (int64, error)
+	GetMatch(id uint) (models.Match, error)
+	GetMatchesForUser(userID, before uint, limit int) ([]models.Match, error)
+	CreateMatch(match *models.Match) error
+	FinishMatch(id uint, winnerID, winningTeam sql.NullInt64, now time.Time) error
+}
+
+type MatchRepository struct {
+	db *sql.DB
+}
+
+func NewMatchRepository(db *sql.DB) *MatchRepository {
+	return &MatchRepository{db}
+}
+
+const challengeQuery = `
+	SELECT c.id, c.created_at, c.updated_at, c.challenger_id, cu.username, c.challenger_deck_id,
+	       c.opponent_id, COALESCE(ou.username, ''), c.join_code, c.status, c.expires_at, c.match_id
+	FROM challenges c
+	JOIN users cu ON cu.id = c.challenger_id
+	LEFT JOIN users ou ON ou.id = c.opponent_id
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanChallenge(row rowScanner) (models.Challenge, error) {
+	var challenge models.Challenge
+	err := row.Scan(
+		&challenge.ID,
+		&challenge.CreatedAt,
+		&challenge.UpdatedAt,
+		&challenge.ChallengerID,
+		&challenge.ChallengerName,
+		&challenge.ChallengerDeckID,
+		&challenge.OpponentID,
+		&challenge.OpponentName,
+		&challenge.JoinCode,
+		&challenge.Status,
+		&challenge.ExpiresAt,
+		&challenge.MatchID,
+	)
+	return challenge, err
+}
+
+// CreateChallenge returns helper.ErrJoinCodeTaken when another pending
+// challenge already uses the code, so the caller can pick a new one.
+func (repo *MatchRepository) CreateChallenge(challenge *models.Challenge) error {
+	query := `
+		INSERT INTO challenges (challenger_id, challenger_deck_id, opponent_id, join_code, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	err := repo.db.QueryRow(query,
+		challenge.ChallengerID,
+		challenge.ChallengerDeckID,
+		challenge.OpponentID,
+		challenge.JoinCode,
+		models.ChallengePending,
+		challenge.ExpiresAt,
+	).Scan(&challenge.ID, &challenge.CreatedAt, &challenge.UpdatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrJoinCodeTaken
+	} else if err != nil {
+		return fmt.Errorf("failed to create challenge: %v", err)
+	}
+	challenge.Status = models.ChallengePending
+	return nil
+}
+
+func (repo *MatchRepository) GetChallenge(id uint) (models.Challenge, error) {
+	challenge, err := scanChallenge(repo.db.QueryRow(challengeQuery+" WHERE c.id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.Challenge{}, helper.ErrChallengeNotFound
+	} else if err != nil {
+		return models.Challenge{}, fmt.Errorf("failed to get challenge: %v", err)
+	}
+	return challenge, nil
+}
+
+func (repo *MatchRepository) GetPendingChallengeByCode(code string) (models.Challenge, error) {
+	query := challengeQuery + " WHERE c.join_code = $1 AND c.status = $2"
+	challenge, err := scanChallenge(repo.db.QueryRow(query, code, models.ChallengePending))
+	if err == sql.ErrNoRows {
+		return models.Challenge{}, helper.ErrChallengeNotFound
+	} else if err != nil {
+		return models.Challenge{}, fmt.Errorf("failed to get challenge by code: %v", err)
+	}
+	return challenge, nil
+}
+
+// GetChallengesForUser lists the pending, unexpired challenges the user sent
+// or was sent.
+func (repo *MatchRepository) GetChallengesForUser(userID uint, now time.Time) ([]models.Challenge, error) {
+	query := challengeQuery + `
+		WHERE (c.challenger_id = $1 OR c.opponent_id = $1) AND c.status = $2 AND c.expires_at > $3
+		ORDER BY c.created_at DESC
+	`
+
+	rows, err := repo.db.Query(query, userID, models.ChallengePending, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenges: %v", err)
+	}
+	defer rows.Close()
+
+	var challenges []models.Challenge
+	for rows.Next() {
+		challenge, err := scanChallenge(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan challenge: %v", err)
+		}
+		challenges = append(challenges, challenge)
+	}
+
+	return challenges, nil
+}
+
+// StartChallenge claims a pending challenge for the opponent and creates the
+// unranked match with both locked-in decks. It returns
+// helper.ErrChallengeUnavailable if the challenge was already answered,
+// expired, or is addressed to someone else.
+func (repo *MatchRepository) StartChallenge(challengeID, opponentID uint, now time.Time, players []models.MatchPlayer) (models.Match, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.Match{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	claimQuery := `
+		UPDATE challenges SET status = $1, opponent_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND status = $4 AND expires_at > $5 AND (opponent_id IS NULL OR opponent_id = $2)
+	`
+	result, err := tx.Exec(claimQuery, models.ChallengeAccepted, opponentID, challengeID, models.ChallengePending, now)
+	if err != nil {
+		return models.Match{}, fmt.Errorf("failed to accept challenge: %v", err)
+	}
+	if claimed, _ := result.RowsAffected(); claimed == 0 {
+		return models.Match{}, helper.ErrChallengeUnavailable
+	}
+
+	match := models.Match{Mode: models.MatchModeFriendly, Ranked: false, Players: players}
+	if err := insertMatch(tx, &match); err != nil {
+		return models.Match{}, err
+	}
+
+	if _, err := tx.Exec("UPDATE challenges SET match_id = $1 WHERE id = $2", match.ID, challengeID); err != nil {
+		return models.Match{}, fmt.Errorf("failed to link match to challenge: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Match{}, fmt.Errorf("failed to commit challenge: %v", err)
+	}
+	return match, nil
+}
+
+// CreateMatch starts a match that needs no challenge, such as practice
+// against a bot.
+func (repo *MatchRepository) CreateMatch(match *models.Match) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertMatch(tx, match); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit match: %v", err)
+	}
+	return nil
+}
+
+func insertMatch(tx *sql.Tx, match *models.Match) error {
+	query := `
+		INSERT INTO matches (mode, ranked, status)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`
+	match.Status = models.MatchStarted
+	err := tx.QueryRow(query, match.Mode, match.Ranked, match.Status).Scan(&match.ID, &match.CreatedAt, &match.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create match: %v", err)
+	}
+
+	for _, player := range match.Players {
+		deck, err := json.Marshal(player.Deck)
+		if err != nil {
+			return fmt.Errorf("failed to encode deck snapshot: %v", err)
+		}
+		bot := sql.NullString{String: player.Bot, Valid: player.Bot != ""}
+		if _, err := tx.Exec("INSERT INTO match_players (match_id, user_id, team, bot, deck) VALUES ($1, $2, $3, $4, $5)", match.ID, nullID(player.UserID), player.Team, bot, deck); err != nil {
+			return fmt.Errorf("failed to add match player: %v", err)
+		}
+	}
+	return nil
+}
+
+// CloseChallenge moves a pending challenge to a final status such as
+// declined or cancelled.
+func (repo *MatchRepository) CloseChallenge(id uint, status string) error {
+	query := `UPDATE challenges SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND status = $3`
+	result, err := repo.db.Exec(query, status, id, models.ChallengePending)
+	if err != nil {
+		return fmt.Errorf("failed to close challenge: %v", err)
+	}
+	if closed, _ := result.RowsAffected(); closed == 0 {
+		return helper.ErrChallengeUnavailable
+	}
+	return nil
+}
+
+func (repo *MatchRepository) ExpireChallenges(now time.Time) (int64, error) {
+	query := `UPDATE challenges SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE status = $2 AND expires_at <= $3`
+	result, err := repo.db.Exec(query, models.ChallengeExpired, models.ChallengePending, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire challenges: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+const matchColumns = "id, created_at, updated_at, mode, ranked, status, winner_id, winning_team, finished_at"
+
+func scanMatch(row rowScanner) (models.Match, error) {
+	var match models.Match
+	err := row.Scan(
+		&match.ID,
+		&match.CreatedAt,
+		&match.UpdatedAt,
+		&match.Mode,
+		&match.Ranked,
+		&match.Status,
+		&match.WinnerID,
+		&match.WinningTeam,
+		&match.FinishedAt,
+	)
+	return match, err
+}
+
+// getMatchPlayers loads the match's players in seat order: by team, then in
+// the order they joined.
+func getMatchPlayers(db queryer, match *models.Match) error {
+	query := `
+		SELECT COALESCE(mp.user_id, 0), COALESCE(u.username, CASE WHEN mp.bot IS NULL THEN 'Deleted player' ELSE 'Bot' END), mp.team, COALESCE(mp.bot, ''), mp.deck
+		FROM match_players mp
+		LEFT JOIN users u ON u.id = mp.user_id
+		WHERE mp.match_id = $1
+		ORDER BY mp.team, mp.id
+	`
+	rows, err := db.Query(query, match.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get match players: %v", err)
+	}
+	defer rows.Close()
+
+	match.Players = nil
+	for rows.Next() {
+		var player models.MatchPlayer
+		var deck []byte
+		if err := rows.Scan(&player.UserID, &player.Username, &player.Team, &player.Bot, &deck); err != nil {
+			return fmt.Errorf("failed to scan match player: %v", err)
+		}
+		if err := json.Unmarshal(deck, &player.Deck); err != nil {
+			return fmt.Errorf("failed to decode deck snapshot: %v", err)
+		}
+		match.Players = append(match.Players, player)
+	}
+	return nil
+}
+
+func (repo *MatchRepository) GetMatch(id uint) (models.Match, error) {
+	match, err := scanMatch(repo.db.QueryRow("SELECT "+matchColumns+" FROM matches WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.Match{}, helper.ErrMatchNotFound
+	} else if err != nil {
+		return models.Match{}, fmt.Errorf("failed to get match: %v", err)
+	}
+	if err := getMatchPlayers(repo.db, &match); err != nil {
+		return models.Match{}, err
+	}
+	return match, nil
+}
+
+// GetMatchesForUser pages through the player's matches newest first,
+// starting below the before cursor.
+func (repo *MatchRepository) GetMatchesForUser(userID, before uint, limit int) ([]models.Match, error) {
+	query := `
+		SELECT ` + matchColumns + ` FROM matches
+		WHERE id IN (SELECT match_id FROM match_players WHERE user_id = $1) AND id < $2
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	rows, err := repo.db.Query(query, userID, cursorID(before), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matches: %v", err)
+	}
+
+	var matches []models.Match
+	for rows.Next() {
+		match, err := scanMatch(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan match: %v", err)
+		}
+		matches = append(matches, match)
+	}
+	rows.Close()
+
+	for i := range matches {
+		if err := getMatchPlayers(repo.db, &matches[i]); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// FinishMatch records the result of a started match: the winner, or for team
+// matches the winning team. It returns helper.ErrMatchFinished if a result
+// was already recorded, so each match pays out at most once.
+func (repo *MatchRepository) FinishMatch(id uint, winnerID, winningTeam sql.NullInt64, now time.Time) error {
+	query := `
+		UPDATE matches SET status = $1, winner_id = $2, winning_team = $3, finished_at = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND status = $6
+	`
+	result, err := repo.db.Exec(query, models.MatchFinished, winnerID, winningTeam, now, id, models.MatchStarted)
+	if err != nil {
+		return fmt.Errorf("failed to finish match: %v", err)
+	}
+	if finished, _ := result.RowsAffected(); finished == 0 {
+		var exists bool
+		if err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM matches WHERE id = $1)", id).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check match: %v", err)
+		}
+		if !exists {
+			return helper.ErrMatchNotFound
+		}
+		return helper.ErrMatchFinished
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type NotificationRepo interface {
+	CreateNotification(notification *models.Notification) error
+	Broadcast(notification models.Notification) (int64, error)
+	GetNotifications(userID, before uint, limit int, unreadOnly bool) ([]models.Notification, error)
+	CountUnread(userID uint) (int, error)
+	MarkRead(userID, id uint, now time.Time) error
+	MarkAllRead(userID uint, now time.Time) (int64, error)
+	GetSettings(userID uint) (models.NotificationSettings, error)
+	UpdateSettings(settings models.NotificationSettings) error
+	GetDueDigests(cutoff time.Time, maxPerDigest int) ([]models.NotificationDigest, error)
+	ClaimDigest(userID uint, cutoff, now time.Time) (bool, error)
+}
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db}
+}
+
+const notificationColumns = "id, created_at, user_id, kind, title, body, data, read_at"
+
+func scanNotification(row rowScanner) (models.Notification, error) {
+	var notification models.Notification
+	var data []byte
+	err := row.Scan(
+		&notification.ID,
+		&notification.CreatedAt,
+		&notification.UserID,
+		&notification.Kind,
+		&notification.Title,
+		&notification.Body,
+		&data,
+		&notification.ReadAt,
+	)
+	if err != nil {
+		return models.Notification{}, err
+	}
+	if err := json.Unmarshal(data, &notification.Data); err != nil {
+		return models.Notification{}, fmt.Errorf("failed to decode notification data: %v", err)
+	}
+	return notification, nil
+}
+
+func encodeNotificationData(data map[string]interface{}) ([]byte, error) {
+	if data == nil {
+		return []byte("{}"), nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode notification data: %v", err)
+	}
+	return encoded, nil
+}
+
+func (repo *NotificationRepository) CreateNotification(notification *models.Notification) error {
+	data, err := encodeNotificationData(notification.Data)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, kind, title, body, data)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err = repo.db.QueryRow(query, notification.UserID, notification.Kind, notification.Title, notification.Body, data).
+		Scan(&notification.ID, &notification.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %v", err)
+	}
+	return nil
+}
+
+// Broadcast gives every account that isn't scheduled for deletion its own
+// copy of the notification, so read state stays per player.
+func (repo *NotificationRepository) Broadcast(notification models.Notification) (int64, error) {
+	data, err := encodeNotificationData(notification.Data)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, kind, title, body, data)
+		SELECT id, $1, $2, $3, $4 FROM users WHERE deleted_at IS NULL
+	`
+	result, err := repo.db.Exec(query, notification.Kind, notification.Title, notification.Body, data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to broadcast notification: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetNotifications pages through a player's notifications newest first,
+// starting below the before cursor.
+func (repo *NotificationRepository) GetNotifications(userID, before uint, limit int, unreadOnly bool) ([]models.Notification, error) {
+	query := `
+		SELECT ` + notificationColumns + ` FROM notifications
+		WHERE user_id = $1 AND id < $2 AND (NOT $3 OR read_at IS NULL)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+	rows, err := repo.db.Query(query, userID, cursorID(before), unreadOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %v", err)
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %v", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+func (repo *NotificationRepository) CountUnread(userID uint) (int, error) {
+	var count int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %v", err)
+	}
+	return count, nil
+}
+
+// MarkRead returns helper.ErrNotificationNotFound when the notification
+// doesn't belong to the user. Marking a read notification again is a no-op.
+func (repo *NotificationRepository) MarkRead(userID, id uint, now time.Time) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, $1) WHERE id = $2 AND user_id = $3`
+	result, err := repo.db.Exec(query, now, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %v", err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return helper.ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (repo *NotificationRepository) MarkAllRead(userID uint, now time.Time) (int64, error) {
+	result, err := repo.db.Exec("UPDATE notifications SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL", now, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetSettings returns the defaults for players who never changed them.
+func (repo *NotificationRepository) GetSettings(userID uint) (models.NotificationSettings, error) {
+	settings := models.NotificationSettings{UserID: userID}
+	query := `SELECT email_digest, last_digest_at FROM notification_settings WHERE user_id = $1`
+	err := repo.db.QueryRow(query, userID).Scan(&settings.EmailDigest, &settings.LastDigestAt)
+	if err != nil && err != sql.ErrNoRows {
+		return models.NotificationSettings{}, fmt.Errorf("failed to get notification settings: %v", err)
+	}
+	return settings, nil
+}
+
+func (repo *NotificationRepository) UpdateSettings(settings models.NotificationSettings) error {
+	query := `
+		INSERT INTO notification_settings (user_id, email_digest)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET email_digest = EXCLUDED.email_digest
+	`
+	if _, err := repo.db.Exec(query, settings.UserID, settings.EmailDigest); err != nil {
+		return fmt.Errorf("failed to update notification settings: %v", err)
+	}
+	return nil
+}
+
+// GetDueDigests finds players who opted in to email digests, haven't had one
+// since cutoff, and have unread notifications newer than their last digest.
+// Each digest holds at most maxPerDigest of the newest notifications.
+func (repo *NotificationRepository) GetDueDigests(cutoff time.Time, maxPerDigest int) ([]models.NotificationDigest, error) {
+	query := `
+		SELECT u.id, u.email, u.username, s.last_digest_at
+		FROM notification_settings s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.email_digest AND u.deleted_at IS NULL
+		  AND (s.last_digest_at IS NULL OR s.last_digest_at <= $1)
+		  AND EXISTS (
+			SELECT 1 FROM notifications n
+			WHERE n.user_id = u.id AND n.read_at IS NULL
+			  AND (s.last_digest_at IS NULL OR n.created_at > s.last_digest_at)
+		  )
+	`
+	rows, err := repo.db.Query(query, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest recipients: %v", err)
+	}
+
+	type recipient struct {
+		digest       models.NotificationDigest
+		lastDigestAt pq.NullTime
+	}
+	var recipients []recipient
+	for rows.Next() {
+		var r recipient
+		if err := rows.Scan(&r.digest.UserID, &r.digest.Email, &r.digest.Username, &r.lastDigestAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan digest recipient: %v", err)
+		}
+		recipients = append(recipients, r)
+	}
+	rows.Close()
+
+	notificationsQuery := `
+		SELECT ` + notificationColumns + ` FROM notifications
+		WHERE user_id = $1 AND read_at IS NULL AND ($2::timestamp IS NULL OR created_at > $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	digests := make([]models.NotificationDigest, 0, len(recipients))
+	for _, r := range recipients {
+		rows, err := repo.db.Query(notificationsQuery, r.digest.UserID, r.lastDigestAt, maxPerDigest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get digest notifications: %v", err)
+		}
+		for rows.Next() {
+			notification, err := scanNotification(rows)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan notification: %v", err)
+			}
+			r.digest.Notifications = append(r.digest.Notifications, notification)
+		}
+		rows.Close()
+		digests = append(digests, r.digest)
+	}
+
+	return digests, nil
+}
+
+// ClaimDigest records a digest as sent unless another instance has sent one
+// since cutoff, and reports whether this caller got to send it.
+func (repo *NotificationRepository) ClaimDigest(userID uint, cutoff, now time.Time) (bool, error) {
+	query := `
+		UPDATE notification_settings SET last_digest_at = $1
+		WHERE user_id = $2 AND (last_digest_at IS NULL OR last_digest_at <= $3)
+		RETURNING user_id
+	`
+	err := repo.db.QueryRow(query, now, userID, cutoff).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to claim digest: %v", err)
+	}
+	return true, nil
+}
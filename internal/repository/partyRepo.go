@@ -0,0 +1,507 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type PartyRepo interface {
+	CreateParty(party *models.Party) error
+	GetParty(id uint) (models.Party, error)
+	GetPartyForUser(userID uint) (models.Party, error)
+	InviteToParty(partyID, userID uint) error
+	JoinParty(partyID, userID uint, deck models.DeckSnapshot) error
+	LeaveParty(userID uint) (models.Party, error)
+	QueueSolo(userID uint, deck models.DeckSnapshot) (models.Match, bool, error)
+	QueueParty(leaderID uint) (models.Match, bool, error)
+	LeaveQueue(userID uint) error
+	GetQueueEntry(userID uint) (models.TeamQueueEntry, error)
+	GetQueueWaitStart() (time.Time, bool, error)
+	FillWithBots(queuedBefore time.Time, bots []models.MatchPlayer) (models.Match, bool, error)
+}
+
+type PartyRepository struct {
+	db *sql.DB
+}
+
+func NewPartyRepository(db *sql.DB) *PartyRepository {
+	return &PartyRepository{db}
+}
+
+const partyQuery = `
+	SELECT p.id, p.created_at, p.leader_id, lu.username, p.leader_deck, p.invited_id, COALESCE(iu.username, ''),
+	       p.member_id, COALESCE(mu.username, ''), p.member_deck,
+	       EXISTS (SELECT 1 FROM team_queue q WHERE q.party_id = p.id)
+	FROM parties p
+	JOIN users lu ON lu.id = p.leader_id
+	LEFT JOIN users iu ON iu.id = p.invited_id
+	LEFT JOIN users mu ON mu.id = p.member_id
+`
+
+func scanParty(row rowScanner) (models.Party, error) {
+	var party models.Party
+	var leaderDeck, memberDeck []byte
+	err := row.Scan(
+		&party.ID,
+		&party.CreatedAt,
+		&party.LeaderID,
+		&party.LeaderName,
+		&leaderDeck,
+		&party.InvitedID,
+		&party.InvitedName,
+		&party.MemberID,
+		&party.MemberName,
+		&memberDeck,
+		&party.Queued,
+	)
+	if err != nil {
+		return models.Party{}, err
+	}
+	if err := json.Unmarshal(leaderDeck, &party.LeaderDeck); err != nil {
+		return models.Party{}, fmt.Errorf("failed to decode deck snapshot: %v", err)
+	}
+	if memberDeck != nil {
+		if err := json.Unmarshal(memberDeck, &party.MemberDeck); err != nil {
+			return models.Party{}, fmt.Errorf("failed to decode deck snapshot: %v", err)
+		}
+	}
+	return party, nil
+}
+
+func partyError(err error) error {
+	if err == sql.ErrNoRows {
+		return helper.ErrPartyNotFound
+	}
+	return fmt.Errorf("failed to get party: %v", err)
+}
+
+// checkFree returns helper.ErrAlreadyInParty or helper.ErrAlreadyQueued when
+// the player is taken by a party or the queue.
+func checkFree(tx *sql.Tx, userID uint) error {
+	var inParty, queued bool
+	query := `
+		SELECT EXISTS (SELECT 1 FROM parties WHERE leader_id = $1 OR member_id = $1),
+		       EXISTS (SELECT 1 FROM team_queue WHERE user_id = $1)
+	`
+	if err := tx.QueryRow(query, userID).Scan(&inParty, &queued); err != nil {
+		return fmt.Errorf("failed to check player: %v", err)
+	}
+	if inParty {
+		return helper.ErrAlreadyInParty
+	}
+	if queued {
+		return helper.ErrAlreadyQueued
+	}
+	return nil
+}
+
+func (repo *PartyRepository) CreateParty(party *models.Party) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkFree(tx, party.LeaderID); err != nil {
+		return err
+	}
+	deck, err := json.Marshal(party.LeaderDeck)
+	if err != nil {
+		return fmt.Errorf("failed to encode deck snapshot: %v", err)
+	}
+	err = tx.QueryRow("INSERT INTO parties (leader_id, leader_deck) VALUES ($1, $2) RETURNING id, created_at", party.LeaderID, deck).
+		Scan(&party.ID, &party.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrAlreadyInParty
+	} else if err != nil {
+		return fmt.Errorf("failed to create party: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit party: %v", err)
+	}
+	return nil
+}
+
+func (repo *PartyRepository) GetParty(id uint) (models.Party, error) {
+	party, err := scanParty(repo.db.QueryRow(partyQuery+" WHERE p.id = $1", id))
+	if err != nil {
+		return models.Party{}, partyError(err)
+	}
+	return party, nil
+}
+
+// GetPartyForUser returns the party the player leads or has joined.
+func (repo *PartyRepository) GetPartyForUser(userID uint) (models.Party, error) {
+	party, err := scanParty(repo.db.QueryRow(partyQuery+" WHERE p.leader_id = $1 OR p.member_id = $1", userID))
+	if err != nil {
+		return models.Party{}, partyError(err)
+	}
+	return party, nil
+}
+
+// InviteToParty invites a player in place of anyone invited before. It
+// returns helper.ErrPartyFull once a member has joined.
+func (repo *PartyRepository) InviteToParty(partyID, userID uint) error {
+	result, err := repo.db.Exec("UPDATE parties SET invited_id = $1 WHERE id = $2 AND member_id IS NULL", userID, partyID)
+	if err != nil {
+		return fmt.Errorf("failed to invite to party: %v", err)
+	}
+	if invited, _ := result.RowsAffected(); invited == 0 {
+		return helper.ErrPartyFull
+	}
+	return nil
+}
+
+func (repo *PartyRepository) JoinParty(partyID, userID uint, deck models.DeckSnapshot) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	party, err := scanParty(tx.QueryRow(partyQuery+" WHERE p.id = $1 FOR UPDATE OF p", partyID))
+	if err != nil {
+		return partyError(err)
+	}
+	if party.MemberID.Valid {
+		return helper.ErrPartyFull
+	}
+	if uint(party.InvitedID.Int64) != userID {
+		return helper.ErrNotInvited
+	}
+	if err := checkFree(tx, userID); err != nil {
+		return err
+	}
+
+	snapshot, err := json.Marshal(deck)
+	if err != nil {
+		return fmt.Errorf("failed to encode deck snapshot: %v", err)
+	}
+	_, err = tx.Exec("UPDATE parties SET member_id = $1, member_deck = $2, invited_id = NULL WHERE id = $3", userID, snapshot, partyID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrAlreadyInParty
+	} else if err != nil {
+		return fmt.Errorf("failed to join party: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit party: %v", err)
+	}
+	return nil
+}
+
+// LeaveParty takes the player out of their party and the party out of the
+// queue. A leader leaving disbands the party. It returns the party as it was.
+func (repo *PartyRepository) LeaveParty(userID uint) (models.Party, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.Party{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	party, err := scanParty(tx.QueryRow(partyQuery+" WHERE p.leader_id = $1 OR p.member_id = $1 FOR UPDATE OF p", userID))
+	if err != nil {
+		return models.Party{}, partyError(err)
+	}
+	if _, err := tx.Exec("DELETE FROM team_queue WHERE party_id = $1", party.ID); err != nil {
+		return models.Party{}, fmt.Errorf("failed to leave queue: %v", err)
+	}
+	if party.LeaderID == userID {
+		_, err = tx.Exec("DELETE FROM parties WHERE id = $1", party.ID)
+	} else {
+		_, err = tx.Exec("UPDATE parties SET member_id = NULL, member_deck = NULL WHERE id = $1", party.ID)
+	}
+	if err != nil {
+		return models.Party{}, fmt.Errorf("failed to leave party: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Party{}, fmt.Errorf("failed to commit party: %v", err)
+	}
+	return party, nil
+}
+
+// QueueSolo queues a player without a party and tries to start a match.
+func (repo *PartyRepository) QueueSolo(userID uint, deck models.DeckSnapshot) (models.Match, bool, error) {
+	tx, err := lockTeamQueue(repo.db)
+	if err != nil {
+		return models.Match{}, false, err
+	}
+	defer tx.Rollback()
+
+	if err := checkFree(tx, userID); err != nil {
+		return models.Match{}, false, err
+	}
+	if err := enqueue(tx, userID, sql.NullInt64{}, deck); err != nil {
+		return models.Match{}, false, err
+	}
+	return matchTeams(tx)
+}
+
+// QueueParty queues the leader's party, which must be full, and tries to
+// start a match.
+func (repo *PartyRepository) QueueParty(leaderID uint) (models.Match, bool, error) {
+	tx, err := lockTeamQueue(repo.db)
+	if err != nil {
+		return models.Match{}, false, err
+	}
+	defer tx.Rollback()
+
+	party, err := scanParty(tx.QueryRow(partyQuery+" WHERE p.leader_id = $1 FOR UPDATE OF p", leaderID))
+	if err != nil {
+		return models.Match{}, false, partyError(err)
+	}
+	if !party.MemberID.Valid {
+		return models.Match{}, false, helper.ErrPartyNotFull
+	}
+	if party.Queued {
+		return models.Match{}, false, helper.ErrAlreadyQueued
+	}
+	partyID := sql.NullInt64{Int64: int64(party.ID), Valid: true}
+	if err := enqueue(tx, party.LeaderID, partyID, party.LeaderDeck); err != nil {
+		return models.Match{}, false, err
+	}
+	if err := enqueue(tx, uint(party.MemberID.Int64), partyID, party.MemberDeck); err != nil {
+		return models.Match{}, false, err
+	}
+	return matchTeams(tx)
+}
+
+// lockTeamQueue starts a transaction holding the queue lock. Queueing takes
+// it so that players who queue at the same time always see each other and
+// each player is matched once.
+func lockTeamQueue(db *sql.DB) (*sql.Tx, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	if _, err := tx.Exec("LOCK TABLE team_queue IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to lock queue: %v", err)
+	}
+	return tx, nil
+}
+
+func enqueue(tx *sql.Tx, userID uint, partyID sql.NullInt64, deck models.DeckSnapshot) error {
+	snapshot, err := json.Marshal(deck)
+	if err != nil {
+		return fmt.Errorf("failed to encode deck snapshot: %v", err)
+	}
+	_, err = tx.Exec("INSERT INTO team_queue (user_id, party_id, deck) VALUES ($1, $2, $3)", userID, partyID, snapshot)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrAlreadyQueued
+	} else if err != nil {
+		return fmt.Errorf("failed to join queue: %v", err)
+	}
+	return nil
+}
+
+// formTeams makes the first two teams it can out of the queue, in queue
+// order: a party is a team of its own and players queueing alone are paired
+// with each other. It also returns the player left waiting for a teammate.
+func formTeams(entries []models.TeamQueueEntry) ([][]models.TeamQueueEntry, []models.TeamQueueEntry) {
+	var teams [][]models.TeamQueueEntry
+	parties := make(map[int64][]models.TeamQueueEntry)
+	var waiting []models.TeamQueueEntry
+	for _, entry := range entries {
+		if entry.PartyID.Valid {
+			party := append(parties[entry.PartyID.Int64], entry)
+			parties[entry.PartyID.Int64] = party
+			if len(party) == 2 {
+				teams = append(teams, party)
+			}
+		} else if waiting = append(waiting, entry); len(waiting) == 2 {
+			teams = append(teams, waiting)
+			waiting = nil
+		}
+		if len(teams) == 2 {
+			return teams, nil
+		}
+	}
+	return teams, waiting
+}
+
+// matchTeams starts a 2v2 match when the queue holds two teams, takes its
+// players off the queue and commits tx. It reports whether a match started.
+func matchTeams(tx *sql.Tx) (models.Match, bool, error) {
+	entries, err := getTeamQueue(tx)
+	if err != nil {
+		return models.Match{}, false, err
+	}
+
+	teams, _ := formTeams(entries)
+	var match models.Match
+	started := len(teams) == 2
+	if started {
+		if match, err = startTeamMatch(tx, teams, nil); err != nil {
+			return models.Match{}, false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Match{}, false, fmt.Errorf("failed to commit queue: %v", err)
+	}
+	return match, started, nil
+}
+
+func getTeamQueue(tx *sql.Tx) ([]models.TeamQueueEntry, error) {
+	query := `
+		SELECT q.id, q.created_at, q.user_id, u.username, q.party_id, q.deck
+		FROM team_queue q
+		JOIN users u ON u.id = q.user_id
+		ORDER BY q.id
+	`
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []models.TeamQueueEntry
+	for rows.Next() {
+		var entry models.TeamQueueEntry
+		var deck []byte
+		if err := rows.Scan(&entry.ID, &entry.CreatedAt, &entry.UserID, &entry.Username, &entry.PartyID, &deck); err != nil {
+			return nil, fmt.Errorf("failed to scan queue entry: %v", err)
+		}
+		if err := json.Unmarshal(deck, &entry.Deck); err != nil {
+			return nil, fmt.Errorf("failed to decode deck snapshot: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// startTeamMatch creates the match of the teams, with bots filling the seats
+// no player took, and takes the players off the queue.
+func startTeamMatch(tx *sql.Tx, teams [][]models.TeamQueueEntry, bots [][]models.MatchPlayer) (models.Match, error) {
+	match := models.Match{Mode: models.MatchModeTeam2v2, Ranked: false}
+	var queued []int64
+	for i := 0; i < 2; i++ {
+		var players []models.MatchPlayer
+		if i < len(teams) {
+			for _, entry := range teams[i] {
+				players = append(players, models.MatchPlayer{UserID: entry.UserID, Username: entry.Username, Deck: entry.Deck})
+				queued = append(queued, int64(entry.ID))
+			}
+		}
+		if i < len(bots) {
+			players = append(players, bots[i]...)
+		}
+		for _, player := range players {
+			player.Team = int32(i + 1)
+			match.Players = append(match.Players, player)
+		}
+	}
+	if err := insertMatch(tx, &match); err != nil {
+		return models.Match{}, err
+	}
+	if _, err := tx.Exec("DELETE FROM team_queue WHERE id = ANY($1)", pq.Array(queued)); err != nil {
+		return models.Match{}, fmt.Errorf("failed to leave queue: %v", err)
+	}
+	return match, nil
+}
+
+// GetQueueWaitStart returns when the longest waiting player queued, and
+// false when the queue is empty.
+func (repo *PartyRepository) GetQueueWaitStart() (time.Time, bool, error) {
+	var since pq.NullTime
+	if err := repo.db.QueryRow("SELECT MIN(created_at) FROM team_queue").Scan(&since); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get queue: %v", err)
+	}
+	return since.Time, since.Valid, nil
+}
+
+// FillWithBots starts a match for players who queued before queuedBefore
+// and still have no one to play: the seats the queue can't fill are taken by
+// bots, up to three of them. It reports whether a match started.
+func (repo *PartyRepository) FillWithBots(queuedBefore time.Time, bots []models.MatchPlayer) (models.Match, bool, error) {
+	tx, err := lockTeamQueue(repo.db)
+	if err != nil {
+		return models.Match{}, false, err
+	}
+	defer tx.Rollback()
+
+	entries, err := getTeamQueue(tx)
+	if err != nil {
+		return models.Match{}, false, err
+	}
+	if len(entries) == 0 || entries[0].CreatedAt.After(queuedBefore) {
+		return models.Match{}, false, nil
+	}
+
+	teams, waiting := formTeams(entries)
+	if len(teams) == 2 {
+		// Enough players queued since the last check; no bots needed.
+		match, err := startTeamMatch(tx, teams, nil)
+		if err != nil {
+			return models.Match{}, false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return models.Match{}, false, fmt.Errorf("failed to commit queue: %v", err)
+		}
+		return match, true, nil
+	}
+
+	// Each team is two seats; count how many bots every team gets.
+	seats := make([][]models.MatchPlayer, 2)
+	if len(waiting) == 1 {
+		teams = append(teams, waiting)
+	}
+	for i := range seats {
+		filled := 0
+		if i < len(teams) {
+			filled = len(teams[i])
+		}
+		for ; filled < 2; filled++ {
+			if len(bots) == 0 {
+				return models.Match{}, false, fmt.Errorf("failed to fill queue: not enough bots")
+			}
+			seats[i], bots = append(seats[i], bots[0]), bots[1:]
+		}
+	}
+
+	match, err := startTeamMatch(tx, teams, seats)
+	if err != nil {
+		return models.Match{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Match{}, false, fmt.Errorf("failed to commit queue: %v", err)
+	}
+	return match, true, nil
+}
+
+// LeaveQueue takes the player out of the queue, with their party if they
+// queued with one.
+func (repo *PartyRepository) LeaveQueue(userID uint) error {
+	query := `
+		DELETE FROM team_queue
+		WHERE user_id = $1 OR party_id IN (SELECT party_id FROM team_queue WHERE user_id = $1)
+	`
+	result, err := repo.db.Exec(query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to leave queue: %v", err)
+	}
+	if left, _ := result.RowsAffected(); left == 0 {
+		return helper.ErrNotQueued
+	}
+	return nil
+}
+
+func (repo *PartyRepository) GetQueueEntry(userID uint) (models.TeamQueueEntry, error) {
+	var entry models.TeamQueueEntry
+	err := repo.db.QueryRow("SELECT id, created_at, user_id, party_id FROM team_queue WHERE user_id = $1", userID).
+		Scan(&entry.ID, &entry.CreatedAt, &entry.UserID, &entry.PartyID)
+	if err == sql.ErrNoRows {
+		return models.TeamQueueEntry{}, helper.ErrNotQueued
+	} else if err != nil {
+		return models.TeamQueueEntry{}, fmt.Errorf("failed to get queue entry: %v", err)
+	}
+	return entry, nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type PaymentRepo interface {
+	CreditPurchase(purchase *models.StorePurchase) (bool, error)
+	ReversePurchase(provider, transactionID, status string, now time.Time) (models.StorePurchase, error)
+	GetPurchases(userID uint) ([]models.StorePurchase, error)
+}
+
+type PaymentRepository struct {
+	db *sql.DB
+}
+
+func NewPaymentRepository(db *sql.DB) *PaymentRepository {
+	return &PaymentRepository{db}
+}
+
+const storePurchaseColumns = "id, created_at, user_id, provider, transaction_id, product_id, gems, status, reversed_at, clawed_back"
+
+func scanStorePurchase(row rowScanner) (models.StorePurchase, error) {
+	var purchase models.StorePurchase
+	err := row.Scan(
+		&purchase.ID,
+		&purchase.CreatedAt,
+		&purchase.UserID,
+		&purchase.Provider,
+		&purchase.TransactionID,
+		&purchase.ProductID,
+		&purchase.Gems,
+		&purchase.Status,
+		&purchase.ReversedAt,
+		&purchase.ClawedBack,
+	)
+	return purchase, err
+}
+
+func purchaseReference(provider, transactionID string) string {
+	return provider + ":" + transactionID
+}
+
+// CreditPurchase records a verified store purchase and credits its gems. It
+// reports false without crediting again when the player already redeemed the
+// same transaction, filling purchase in with the stored one, and returns
+// helper.ErrReceiptClaimed when another player did.
+func (repo *PaymentRepository) CreditPurchase(purchase *models.StorePurchase) (bool, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO store_purchases (user_id, provider, transaction_id, product_id, gems)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, transaction_id) DO NOTHING
+		RETURNING id, created_at, status
+	`
+	err = tx.QueryRow(query, purchase.UserID, purchase.Provider, purchase.TransactionID, purchase.ProductID, purchase.Gems).
+		Scan(&purchase.ID, &purchase.CreatedAt, &purchase.Status)
+	if err == sql.ErrNoRows {
+		existing, err := scanStorePurchase(tx.QueryRow(
+			"SELECT "+storePurchaseColumns+" FROM store_purchases WHERE provider = $1 AND transaction_id = $2",
+			purchase.Provider, purchase.TransactionID,
+		))
+		if err != nil {
+			return false, fmt.Errorf("failed to get store purchase: %v", err)
+		}
+		if existing.UserID != purchase.UserID {
+			return false, helper.ErrReceiptClaimed
+		}
+		*purchase = existing
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to record store purchase: %v", err)
+	}
+
+	reference := purchaseReference(purchase.Provider, purchase.TransactionID)
+	if err := creditWallet(tx, uint(purchase.UserID.Int64), models.CurrencyGems, purchase.Gems, models.ReasonStore, reference); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit store purchase: %v", err)
+	}
+	return true, nil
+}
+
+// ReversePurchase marks a purchase refunded or charged back and claws its gems
+// back in full, nothing when the account was deleted. Gems the player already
+// spent leave the balance below zero, a debt the next gems they get repay and
+// that keeps them from spending until then. Reversing an
+// already reversed purchase changes nothing, as stores may notify more than
+// once.
+func (repo *PaymentRepository) ReversePurchase(provider, transactionID, status string, now time.Time) (models.StorePurchase, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.StorePurchase{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	purchase, err := scanStorePurchase(tx.QueryRow(
+		"SELECT "+storePurchaseColumns+" FROM store_purchases WHERE provider = $1 AND transaction_id = $2 FOR UPDATE",
+		provider, transactionID,
+	))
+	if err == sql.ErrNoRows {
+		return models.StorePurchase{}, helper.ErrPurchaseNotFound
+	} else if err != nil {
+		return models.StorePurchase{}, fmt.Errorf("failed to get store purchase: %v", err)
+	}
+	if purchase.Status != models.PurchaseCompleted {
+		return purchase, nil
+	}
+
+	// debitWallet refuses to overdraw, so the gems are taken as a negative
+	// credit.
+	var clawedBack int64
+	if purchase.UserID.Valid {
+		reason := models.ReasonRefund
+		if status == models.PurchaseChargedBack {
+			reason = models.ReasonChargeback
+		}
+		reference := purchaseReference(purchase.Provider, purchase.TransactionID)
+		if err := creditWallet(tx, uint(purchase.UserID.Int64), models.CurrencyGems, -purchase.Gems, reason, reference); err != nil {
+			return models.StorePurchase{}, err
+		}
+		clawedBack = purchase.Gems
+	}
+
+	if _, err := tx.Exec("UPDATE store_purchases SET status = $1, reversed_at = $2, clawed_back = $3 WHERE id = $4", status, now, clawedBack, purchase.ID); err != nil {
+		return models.StorePurchase{}, fmt.Errorf("failed to reverse store purchase: %v", err)
+	}
+	purchase.Status = status
+	purchase.ReversedAt.Time, purchase.ReversedAt.Valid = now, true
+	purchase.ClawedBack = clawedBack
+
+	if err := tx.Commit(); err != nil {
+		return models.StorePurchase{}, fmt.Errorf("failed to commit reversal: %v", err)
+	}
+	return purchase, nil
+}
+
+func (repo *PaymentRepository) GetPurchases(userID uint) ([]models.StorePurchase, error) {
+	rows, err := repo.db.Query("SELECT "+storePurchaseColumns+" FROM store_purchases WHERE user_id = $1 ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get store purchases: %v", err)
+	}
+	defer rows.Close()
+
+	var purchases []models.StorePurchase
+	for rows.Next() {
+		purchase, err := scanStorePurchase(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan store purchase: %v", err)
+		}
+		purchases = append(purchases, purchase)
+	}
+
+	return purchases, nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"database/sql"
+	"fmt"
+)
+
+type PlayerRepo interface {
+	GetPlayerProfile(userID uint) (models.PlayerProfile, error)
+	SavePrivacy(profile models.PlayerProfile) error
+	SetFavoriteHero(userID uint, heroID sql.NullInt64) error
+	SetCurrentDeck(userID uint, deckID sql.NullInt64) error
+	RecordMatchResult(winnerID, loserID uint) error
+	GetCollectionCompletion(userID uint) ([]models.CollectionCompletion, error)
+}
+
+type PlayerRepository struct {
+	db *sql.DB
+}
+
+func NewPlayerRepository(db *sql.DB) *PlayerRepository {
+	return &PlayerRepository{db}
+}
+
+func (repo *PlayerRepository) GetPlayerProfile(userID uint) (models.PlayerProfile, error) {
+	query := `
+		SELECT user_id, created_at, updated_at, favorite_hero_id, current_deck_id,
+		       show_stats, show_collection, show_deck, show_favorite, wins, losses
+		FROM player_profiles WHERE user_id = $1
+	`
+	var profile models.PlayerProfile
+	err := repo.db.QueryRow(query, userID).Scan(
+		&profile.UserID,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+		&profile.FavoriteHeroID,
+		&profile.CurrentDeckID,
+		&profile.ShowStats,
+		&profile.ShowCollection,
+		&profile.ShowDeck,
+		&profile.ShowFavorite,
+		&profile.Wins,
+		&profile.Losses,
+	)
+	if err == sql.ErrNoRows {
+		return models.DefaultPlayerProfile(userID), nil
+	} else if err != nil {
+		return models.PlayerProfile{}, fmt.Errorf("failed to get player profile: %v", err)
+	}
+	return profile, nil
+}
+
+func (repo *PlayerRepository) SavePrivacy(profile models.PlayerProfile) error {
+	query := `
+		INSERT INTO player_profiles (user_id, show_stats, show_collection, show_deck, show_favorite)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET show_stats = EXCLUDED.show_stats,
+		    show_collection = EXCLUDED.show_collection,
+		    show_deck = EXCLUDED.show_deck,
+		    show_favorite = EXCLUDED.show_favorite,
+		    updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := repo.db.Exec(query, profile.UserID, profile.ShowStats, profile.ShowCollection, profile.ShowDeck, profile.ShowFavorite)
+	if err != nil {
+		return fmt.Errorf("failed to save privacy settings: %v", err)
+	}
+	return nil
+}
+
+func (repo *PlayerRepository) SetFavoriteHero(userID uint, heroID sql.NullInt64) error {
+	query := `
+		INSERT INTO player_profiles (user_id, favorite_hero_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET favorite_hero_id = EXCLUDED.favorite_hero_id, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := repo.db.Exec(query, userID, heroID)
+	if err != nil {
+		return fmt.Errorf("failed to set favorite hero: %v", err)
+	}
+	return nil
+}
+
+func (repo *PlayerRepository) SetCurrentDeck(userID uint, deckID sql.NullInt64) error {
+	query := `
+		INSERT INTO player_profiles (user_id, current_deck_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET current_deck_id = EXCLUDED.current_deck_id, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := repo.db.Exec(query, userID, deckID)
+	if err != nil {
+		return fmt.Errorf("failed to set current deck: %v", err)
+	}
+	return nil
+}
+
+func (repo *PlayerRepository) RecordMatchResult(winnerID, loserID uint) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	winQuery := `
+		INSERT INTO player_profiles (user_id, wins) VALUES ($1, 1)
+		ON CONFLICT (user_id) DO UPDATE SET wins = player_profiles.wins + 1, updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := tx.Exec(winQuery, winnerID); err != nil {
+		return fmt.Errorf("failed to record win: %v", err)
+	}
+	lossQuery := `
+		INSERT INTO player_profiles (user_id, losses) VALUES ($1, 1)
+		ON CONFLICT (user_id) DO UPDATE SET losses = player_profiles.losses + 1, updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := tx.Exec(lossQuery, loserID); err != nil {
+		return fmt.Errorf("failed to record loss: %v", err)
+	}
+	return tx.Commit()
+}
+
+// GetCollectionCompletion counts distinct owned cards per hero rarity, plus
+// one "Spell" row since spells have no rarity. Deleted cards count neither as
+// owned nor toward the total.
+func (repo *PlayerRepository) GetCollectionCompletion(userID uint) ([]models.CollectionCompletion, error) {
+	query := `
+		SELECT COALESCE(h.rarity, ''), COUNT(h.id), COUNT(owned.hero_id)
+		FROM heros h
+		LEFT JOIN (SELECT DISTINCT hero_id FROM user_heros WHERE user_id = $1) owned ON owned.hero_id = h.id
+		WHERE h.deleted_at IS NULL
+		GROUP BY h.rarity
+		UNION ALL
+		SELECT 'Spell', COUNT(s.id), COUNT(owned.spell_id)
+		FROM spells s
+		LEFT JOIN (SELECT DISTINCT spell_id FROM user_spells WHERE user_id = $1) owned ON owned.spell_id = s.id
+		WHERE s.deleted_at IS NULL
+	`
+
+	rows, err := repo.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection completion: %v", err)
+	}
+	defer rows.Close()
+
+	var completion []models.CollectionCompletion
+	for rows.Next() {
+		var row models.CollectionCompletion
+		if err := rows.Scan(&row.Category, &row.Total, &row.Owned); err != nil {
+			return nil, fmt.Errorf("failed to scan collection completion: %v", err)
+		}
+		completion = append(completion, row)
+	}
+
+	return completion, nil
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type PromoRepo interface {
+	CreateCodes(codes []models.PromoCode) error
+	GetCodes() ([]models.PromoCode, error)
+	GetCode(id uint) (models.PromoCode, error)
+	UpdateCode(code models.PromoCode) error
+	Redeem(userID uint, code string, now time.Time) (models.PromoCode, error)
+	GetRedemptions(codeID uint) ([]models.PromoRedemption, error)
+}
+
+type PromoRepository struct {
+	db *sql.DB
+}
+
+func NewPromoRepository(db *sql.DB) *PromoRepository {
+	return &PromoRepository{db}
+}
+
+const promoCodeColumns = `
+	id, created_at, updated_at, code, description, reward_gold, reward_gems, reward_chest, expires_at,
+	max_uses, uses, per_user_limit, active
+`
+
+func scanPromoCode(row rowScanner) (models.PromoCode, error) {
+	var code models.PromoCode
+	err := row.Scan(
+		&code.ID,
+		&code.CreatedAt,
+		&code.UpdatedAt,
+		&code.Code,
+		&code.Description,
+		&code.RewardGold,
+		&code.RewardGems,
+		&code.RewardChest,
+		&code.ExpiresAt,
+		&code.MaxUses,
+		&code.Uses,
+		&code.PerUserLimit,
+		&code.Active,
+	)
+	return code, err
+}
+
+func getPromoCodeItems(db queryer, code *models.PromoCode) error {
+	query := `
+		SELECT i.hero_id, i.spell_id, COALESCE(h.name, s.name, '')
+		FROM promo_code_items i
+		LEFT JOIN heros h ON h.id = i.hero_id
+		LEFT JOIN spells s ON s.id = i.spell_id
+		WHERE i.code_id = $1
+		ORDER BY i.id
+	`
+	rows, err := db.Query(query, code.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get promo code items: %v", err)
+	}
+	defer rows.Close()
+
+	code.Items = nil
+	for rows.Next() {
+		var item models.PromoCodeItem
+		if err := rows.Scan(&item.HeroID, &item.SpellID, &item.Name); err != nil {
+			return fmt.Errorf("failed to scan promo code item: %v", err)
+		}
+		code.Items = append(code.Items, item)
+	}
+	return nil
+}
+
+// CreateCodes saves a batch of codes in one transaction, so a batch is either
+// created whole or not at all. It returns helper.ErrPromoCodeTaken when any of
+// the codes already exists.
+func (repo *PromoRepository) CreateCodes(codes []models.PromoCode) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO promo_codes (code, description, reward_gold, reward_gems, reward_chest, expires_at, max_uses, per_user_limit, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`
+	for i := range codes {
+		code := &codes[i]
+		err := tx.QueryRow(query,
+			code.Code,
+			code.Description,
+			code.RewardGold,
+			code.RewardGems,
+			code.RewardChest,
+			code.ExpiresAt,
+			code.MaxUses,
+			code.PerUserLimit,
+			code.Active,
+		).Scan(&code.ID, &code.CreatedAt, &code.UpdatedAt)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return helper.ErrPromoCodeTaken
+		} else if err != nil {
+			return fmt.Errorf("failed to create promo code: %v", err)
+		}
+
+		for _, item := range code.Items {
+			if _, err := tx.Exec("INSERT INTO promo_code_items (code_id, hero_id, spell_id) VALUES ($1, $2, $3)", code.ID, item.HeroID, item.SpellID); err != nil {
+				return fmt.Errorf("failed to add promo code item: %v", err)
+			}
+		}
+		if err := getPromoCodeItems(tx, code); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit promo codes: %v", err)
+	}
+	return nil
+}
+
+func (repo *PromoRepository) GetCodes() ([]models.PromoCode, error) {
+	rows, err := repo.db.Query("SELECT " + promoCodeColumns + " FROM promo_codes ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get promo codes: %v", err)
+	}
+
+	var codes []models.PromoCode
+	for rows.Next() {
+		code, err := scanPromoCode(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan promo code: %v", err)
+		}
+		codes = append(codes, code)
+	}
+	rows.Close()
+
+	for i := range codes {
+		if err := getPromoCodeItems(repo.db, &codes[i]); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+func (repo *PromoRepository) GetCode(id uint) (models.PromoCode, error) {
+	code, err := scanPromoCode(repo.db.QueryRow("SELECT "+promoCodeColumns+" FROM promo_codes WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.PromoCode{}, helper.ErrPromoCodeNotFound
+	} else if err != nil {
+		return models.PromoCode{}, fmt.Errorf("failed to get promo code: %v", err)
+	}
+	if err := getPromoCodeItems(repo.db, &code); err != nil {
+		return models.PromoCode{}, err
+	}
+	return code, nil
+}
+
+// UpdateCode changes a code's terms. Its code and rewards are fixed once
+// created because players may already have redeemed it.
+func (repo *PromoRepository) UpdateCode(code models.PromoCode) error {
+	query := `
+		UPDATE promo_codes
+		SET description = $1, expires_at = $2, max_uses = $3, per_user_limit = $4, active = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+	`
+	_, err := repo.db.Exec(query, code.Description, code.ExpiresAt, code.MaxUses, code.PerUserLimit, code.Active, code.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update promo code: %v", err)
+	}
+	return nil
+}
+
+// Redeem grants a code's rewards to the player and logs the redemption. The
+// code row is locked first, so concurrent redemptions of the same code queue
+// up and the usage cap and per-player limit hold. Inactive and unknown codes
+// both return helper.ErrPromoCodeNotFound so codes can't be probed.
+func (repo *PromoRepository) Redeem(userID uint, code string, now time.Time) (models.PromoCode, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.PromoCode{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	promo, err := scanPromoCode(tx.QueryRow("SELECT "+promoCodeColumns+" FROM promo_codes WHERE code = $1 FOR UPDATE", code))
+	if err == sql.ErrNoRows {
+		return models.PromoCode{}, helper.ErrPromoCodeNotFound
+	} else if err != nil {
+		return models.PromoCode{}, fmt.Errorf("failed to get promo code: %v", err)
+	}
+	if !promo.Active {
+		return models.PromoCode{}, helper.ErrPromoCodeNotFound
+	}
+	if promo.ExpiresAt.Valid && !now.Before(promo.ExpiresAt.Time) {
+		return models.PromoCode{}, helper.ErrPromoCodeExpired
+	}
+	if promo.MaxUses.Valid && promo.Uses >= promo.MaxUses.Int64 {
+		return models.PromoCode{}, helper.ErrPromoCodeUsedUp
+	}
+
+	var redeemed int64
+	if err := tx.QueryRow("SELECT COUNT(*) FROM promo_redemptions WHERE code_id = $1 AND user_id = $2", promo.ID, userID).Scan(&redeemed); err != nil {
+		return models.PromoCode{}, fmt.Errorf("failed to count redemptions: %v", err)
+	}
+	if redeemed >= promo.PerUserLimit {
+		return models.PromoCode{}, helper.ErrPromoCodeLimitReached
+	}
+
+	if err := getPromoCodeItems(tx, &promo); err != nil {
+		return models.PromoCode{}, err
+	}
+	reference := fmt.Sprintf("promo:%d", promo.ID)
+	if promo.RewardGold > 0 {
+		if err := creditWallet(tx, userID, models.CurrencyGold, promo.RewardGold, models.ReasonPromoCode, reference); err != nil {
+			return models.PromoCode{}, err
+		}
+	}
+	if promo.RewardGems > 0 {
+		if err := creditWallet(tx, userID, models.CurrencyGems, promo.RewardGems, models.ReasonPromoCode, reference); err != nil {
+			return models.PromoCode{}, err
+		}
+	}
+	if promo.RewardChest.Valid {
+		if _, err := addChest(tx, userID, promo.RewardChest.String); err != nil {
+			return models.PromoCode{}, err
+		}
+	}
+	for _, item := range promo.Items {
+		if item.HeroID.Valid {
+			if _, err := tx.Exec("INSERT INTO user_heros (user_id, hero_id) VALUES ($1, $2)", userID, item.HeroID.Int64); err != nil {
+				return models.PromoCode{}, fmt.Errorf("failed to add hero to user: %v", err)
+			}
+		}
+		if item.SpellID.Valid {
+			if _, err := tx.Exec("INSERT INTO user_spells (user_id, spell_id) VALUES ($1, $2)", userID, item.SpellID.Int64); err != nil {
+				return models.PromoCode{}, fmt.Errorf("failed to add spell to user: %v", err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE promo_codes SET uses = uses + 1 WHERE id = $1", promo.ID); err != nil {
+		return models.PromoCode{}, fmt.Errorf("failed to count promo code use: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO promo_redemptions (code_id, user_id, created_at) VALUES ($1, $2, $3)", promo.ID, userID, now); err != nil {
+		return models.PromoCode{}, fmt.Errorf("failed to record redemption: %v", err)
+	}
+	promo.Uses++
+
+	if err := tx.Commit(); err != nil {
+		return models.PromoCode{}, fmt.Errorf("failed to commit redemption: %v", err)
+	}
+	return promo, nil
+}
+
+func (repo *PromoRepository) GetRedemptions(codeID uint) ([]models.PromoRedemption, error) {
+	query := `
+		SELECT r.id, r.created_at, r.code_id, r.user_id, u.username
+		FROM promo_redemptions r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.code_id = $1
+		ORDER BY r.id DESC
+	`
+	rows, err := repo.db.Query(query, codeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemptions: %v", err)
+	}
+	defer rows.Close()
+
+	var redemptions []models.PromoRedemption
+	for rows.Next() {
+		var redemption models.PromoRedemption
+		if err := rows.Scan(&redemption.ID, &redemption.CreatedAt, &redemption.CodeID, &redemption.UserID, &redemption.Username); err != nil {
+			return nil, fmt.Errorf("failed to scan redemption: %v", err)
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	return redemptions, nil
+}
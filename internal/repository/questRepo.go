@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type QuestRepo interface {
+	GetDefinitions() ([]models.QuestDefinition, error)
+	GetDefinition(id uint) (models.QuestDefinition, error)
+	CreateDefinition(definition *models.QuestDefinition) error
+	UpdateDefinition(definition models.QuestDefinition) error
+	GetUserQuests(userID uint, period string, dailyCount int) ([]models.QuestProgress, error)
+	RecordEvent(event models.QuestEvent, period string, dailyCount int, now time.Time) ([]models.QuestDefinition, error)
+	ClaimQuest(userID, questID uint, now time.Time) (models.QuestProgress, error)
+}
+
+type QuestRepository struct {
+	db *sql.DB
+}
+
+func NewQuestRepository(db *sql.DB) *QuestRepository {
+	return &QuestRepository{db}
+}
+
+const questDefinitionColumns = `
+	d.id, d.created_at, d.updated_at, d.code, d.kind, d.title, d.description, d.event, d.rarity,
+	d.target, d.reward_gold, d.reward_hero_id, d.reward_spell_id, d.active
+`
+
+// dailyQuestsCTE picks the day's daily quests from the active pool. Hashing
+// the code with the period gives every server the same rotation without
+// storing it. It expects the period as $2 and the count as $3.
+const dailyQuestsCTE = `
+	today AS (
+		SELECT id FROM quest_definitions
+		WHERE kind = 'DAILY' AND active
+		ORDER BY md5(code || $2), id
+		LIMIT $3
+	)
+`
+
+func questDefinitionFields(definition *models.QuestDefinition) []interface{} {
+	return []interface{}{
+		&definition.ID,
+		&definition.CreatedAt,
+		&definition.UpdatedAt,
+		&definition.Code,
+		&definition.Kind,
+		&definition.Title,
+		&definition.Description,
+		&definition.Event,
+		&definition.Rarity,
+		&definition.Target,
+		&definition.RewardGold,
+		&definition.RewardHeroID,
+		&definition.RewardSpellID,
+		&definition.Active,
+	}
+}
+
+func (repo *QuestRepository) GetDefinitions() ([]models.QuestDefinition, error) {
+	rows, err := repo.db.Query("SELECT " + questDefinitionColumns + " FROM quest_definitions d ORDER BY d.kind, d.id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quest definitions: %v", err)
+	}
+	defer rows.Close()
+
+	var definitions []models.QuestDefinition
+	for rows.Next() {
+		var definition models.QuestDefinition
+		if err := rows.Scan(questDefinitionFields(&definition)...); err != nil {
+			return nil, fmt.Errorf("failed to scan quest definition: %v", err)
+		}
+		definitions = append(definitions, definition)
+	}
+
+	return definitions, nil
+}
+
+func (repo *QuestRepository) GetDefinition(id uint) (models.QuestDefinition, error) {
+	var definition models.QuestDefinition
+	err := repo.db.QueryRow("SELECT "+questDefinitionColumns+" FROM quest_definitions d WHERE d.id = $1", id).
+		Scan(questDefinitionFields(&definition)...)
+	if err == sql.ErrNoRows {
+		return models.QuestDefinition{}, helper.ErrQuestNotFound
+	} else if err != nil {
+		return models.QuestDefinition{}, fmt.Errorf("failed to get quest definition: %v", err)
+	}
+	return definition, nil
+}
+
+// CreateDefinition returns helper.ErrQuestCodeTaken when the code is in use.
+func (repo *QuestRepository) CreateDefinition(definition *models.QuestDefinition) error {
+	query := `
+		INSERT INTO quest_definitions (code, kind, title, description, event, rarity, target, reward_gold, reward_hero_id, reward_spell_id, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at
+	`
+	err := repo.db.QueryRow(query,
+		definition.Code,
+		definition.Kind,
+		definition.Title,
+		definition.Description,
+		definition.Event,
+		definition.Rarity,
+		definition.Target,
+		definition.RewardGold,
+		definition.RewardHeroID,
+		definition.RewardSpellID,
+		definition.Active,
+	).Scan(&definition.ID, &definition.CreatedAt, &definition.UpdatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrQuestCodeTaken
+	} else if err != nil {
+		return fmt.Errorf("failed to create quest definition: %v", err)
+	}
+	return nil
+}
+
+// UpdateDefinition changes what a quest pays and whether it is offered.
+// Progress already made keeps counting against the new target.
+func (repo *QuestRepository) UpdateDefinition(definition models.QuestDefinition) error {
+	query := `
+		UPDATE quest_definitions
+		SET title = $1, description = $2, target = $3, reward_gold = $4, reward_hero_id = $5, reward_spell_id = $6,
+		    active = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8
+	`
+	_, err := repo.db.Exec(query,
+		definition.Title,
+		definition.Description,
+		definition.Target,
+		definition.RewardGold,
+		definition.RewardHeroID,
+		definition.RewardSpellID,
+		definition.Active,
+		definition.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update quest definition: %v", err)
+	}
+	return nil
+}
+
+// GetUserQuests lists the day's daily quests and every active achievement
+// with the player's progress, including quests they haven't started.
+func (repo *QuestRepository) GetUserQuests(userID uint, period string, dailyCount int) ([]models.QuestProgress, error) {
+	query := `
+		WITH ` + dailyQuestsCTE + `
+		SELECT ` + questDefinitionColumns + `,
+		       COALESCE(q.id, 0), COALESCE(q.progress, 0), q.completed_at, q.claimed_at,
+		       CASE WHEN d.kind = 'DAILY' THEN $2 ELSE $4 END
+		FROM quest_definitions d
+		LEFT JOIN user_quests q ON q.definition_id = d.id AND q.user_id = $1
+		     AND q.period = CASE WHEN d.kind = 'DAILY' THEN $2 ELSE $4 END
+		WHERE d.id IN (SELECT id FROM today) OR (d.kind = 'ACHIEVEMENT' AND d.active)
+		ORDER BY d.kind DESC, d.id
+	`
+	rows, err := repo.db.Query(query, userID, period, dailyCount, models.AchievementPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quests: %v", err)
+	}
+	defer rows.Close()
+
+	var quests []models.QuestProgress
+	for rows.Next() {
+		var quest models.QuestProgress
+		fields := append(questDefinitionFields(&quest.Definition),
+			&quest.ID, &quest.Progress, &quest.CompletedAt, &quest.ClaimedAt, &quest.Period)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, fmt.Errorf("failed to scan quest: %v", err)
+		}
+		quests = append(quests, quest)
+	}
+
+	return quests, nil
+}
+
+// RecordEvent adds the event to every matching quest the player can progress
+// today and returns the quests it completed. Progress stops at the target and
+// completed quests are left alone, so replaying an event can't complete a
+// quest twice.
+func (repo *QuestRepository) RecordEvent(event models.QuestEvent, period string, dailyCount int, now time.Time) ([]models.QuestDefinition, error) {
+	query := `
+		WITH ` + dailyQuestsCTE + `,
+		matching AS (
+			SELECT d.id, d.target, CASE WHEN d.kind = 'DAILY' THEN $2 ELSE $7 END AS period
+			FROM quest_definitions d
+			WHERE d.event = $4 AND (d.rarity = '' OR d.rarity = $5) AND d.active
+			  AND (d.kind = 'ACHIEVEMENT' OR d.id IN (SELECT id FROM today))
+		),
+		upserted AS (
+			INSERT INTO user_quests (user_id, definition_id, period, progress, completed_at)
+			SELECT $1, m.id, m.period, LEAST(m.target, $6::int), CASE WHEN $6::int >= m.target THEN $8::timestamp END
+			FROM matching m
+			ON CONFLICT (user_id, definition_id, period) DO UPDATE
+			SET progress = LEAST(
+			        (SELECT target FROM quest_definitions WHERE id = user_quests.definition_id),
+			        user_quests.progress + $6::int
+			    ),
+			    completed_at = CASE
+			        WHEN user_quests.progress + $6::int >= (SELECT target FROM quest_definitions WHERE id = user_quests.definition_id)
+			        THEN $8::timestamp
+			    END,
+			    updated_at = CURRENT_TIMESTAMP
+			WHERE user_quests.completed_at IS NULL
+			RETURNING definition_id, completed_at
+		)
+		SELECT d.id, d.code, d.kind, d.title
+		FROM upserted u
+		JOIN quest_definitions d ON d.id = u.definition_id
+		WHERE u.completed_at IS NOT NULL
+	`
+	rows, err := repo.db.Query(query, event.UserID, period, dailyCount, event.Type, event.Rarity, event.Count, models.AchievementPeriod, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record quest event: %v", err)
+	}
+	defer rows.Close()
+
+	var completed []models.QuestDefinition
+	for rows.Next() {
+		var definition models.QuestDefinition
+		if err := rows.Scan(&definition.ID, &definition.Code, &definition.Kind, &definition.Title); err != nil {
+			return nil, fmt.Errorf("failed to scan completed quest: %v", err)
+		}
+		completed = append(completed, definition)
+	}
+
+	return completed, nil
+}
+
+// Card rewards are skipped for players who already own the card.
+const (
+	grantHeroQuery = `
+		INSERT INTO user_heros (user_id, hero_id)
+		SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM user_heros WHERE user_id = $1 AND hero_id = $2)
+	`
+	grantSpellQuery = `
+		INSERT INTO user_spells (user_id, spell_id)
+		SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM user_spells WHERE user_id = $1 AND spell_id = $2)
+	`
+)
+
+// ClaimQuest pays out a completed quest once. The quest row is locked so two
+// claims can't both pay.
+func (repo *QuestRepository) ClaimQuest(userID, questID uint, now time.Time) (models.QuestProgress, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.QuestProgress{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + questDefinitionColumns + `, q.id, q.progress, q.completed_at, q.claimed_at, q.period
+		FROM user_quests q
+		JOIN quest_definitions d ON d.id = q.definition_id
+		WHERE q.id = $1 AND q.user_id = $2
+		FOR UPDATE OF q
+	`
+	var quest models.QuestProgress
+	fields := append(questDefinitionFields(&quest.Definition),
+		&quest.ID, &quest.Progress, &quest.CompletedAt, &quest.ClaimedAt, &quest.Period)
+	err = tx.QueryRow(query, questID, userID).Scan(fields...)
+	if err == sql.ErrNoRows {
+		return models.QuestProgress{}, helper.ErrQuestNotFound
+	} else if err != nil {
+		return models.QuestProgress{}, fmt.Errorf("failed to get quest: %v", err)
+	}
+	if !quest.CompletedAt.Valid {
+		return models.QuestProgress{}, helper.ErrQuestNotCompleted
+	}
+	if quest.ClaimedAt.Valid {
+		return models.QuestProgress{}, helper.ErrQuestClaimed
+	}
+
+	if _, err := tx.Exec("UPDATE user_quests SET claimed_at = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", now, quest.ID); err != nil {
+		return models.QuestProgress{}, fmt.Errorf("failed to claim quest: %v", err)
+	}
+	quest.ClaimedAt = pq.NullTime{Time: now, Valid: true}
+
+	reward := quest.Definition
+	if reward.RewardGold > 0 {
+		reference := fmt.Sprintf("quest:%d", quest.ID)
+		if err := creditWallet(tx, userID, models.CurrencyGold, reward.RewardGold, models.ReasonQuestReward, reference); err != nil {
+			return models.QuestProgress{}, err
+		}
+	}
+	if reward.RewardHeroID.Valid {
+		if _, err := tx.Exec(grantHeroQuery, userID, reward.RewardHeroID.Int64); err != nil {
+			return models.QuestProgress{}, fmt.Errorf("failed to grant quest hero: %v", err)
+		}
+	}
+	if reward.RewardSpellID.Valid {
+		if _, err := tx.Exec(grantSpellQuery, userID, reward.RewardSpellID.Int64); err != nil {
+			return models.QuestProgress{}, fmt.Errorf("failed to grant quest spell: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.QuestProgress{}, fmt.Errorf("failed to commit quest claim: %v", err)
+	}
+	return quest, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type ReplayRepo interface {
+	CreateReplay(replay *models.Replay) error
+	GetReplay(id uint) (models.Replay, error)
+	GetReplaysForUser(userID, before uint, limit int) ([]models.Replay, error)
+	SetVerification(id uint, verified bool, reason string, now time.Time) error
+}
+
+type ReplayRepository struct {
+	db *sql.DB
+}
+
+func NewReplayRepository(db *sql.DB) *ReplayRepository {
+	return &ReplayRepository{db}
+}
+
+const replayColumns = "id, created_at, match_id, seed, stats_version, duration_ms, input_count, inputs, verified, verified_at, verify_error"
+
+func scanReplay(row rowScanner) (models.Replay, error) {
+	var replay models.Replay
+	err := row.Scan(
+		&replay.ID,
+		&replay.CreatedAt,
+		&replay.MatchID,
+		&replay.Seed,
+		&replay.StatsVersion,
+		&replay.DurationMs,
+		&replay.InputCount,
+		&replay.Inputs,
+		&replay.Verified,
+		&replay.VerifiedAt,
+		&replay.VerifyError,
+	)
+	return replay, err
+}
+
+// loadMatch fills in the match the replay records, players and deck
+// snapshots included.
+func (repo *ReplayRepository) loadMatch(replay *models.Replay) error {
+	match, err := scanMatch(repo.db.QueryRow("SELECT "+matchColumns+" FROM matches WHERE id = $1", replay.MatchID))
+	if err != nil {
+		return fmt.Errorf("failed to get replay match: %v", err)
+	}
+	if err := getMatchPlayers(repo.db, &match); err != nil {
+		return err
+	}
+	replay.Match = match
+	return nil
+}
+
+// CreateReplay returns helper.ErrReplayExists when the match already has a
+// replay; a match is only recorded once.
+func (repo *ReplayRepository) CreateReplay(replay *models.Replay) error {
+	query := `
+		INSERT INTO replays (match_id, seed, stats_version, duration_ms, input_count, inputs)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	err := repo.db.QueryRow(query, replay.MatchID, replay.Seed, replay.StatsVersion, replay.DurationMs, replay.InputCount, replay.Inputs).
+		Scan(&replay.ID, &replay.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrReplayExists
+	} else if err != nil {
+		return fmt.Errorf("failed to create replay: %v", err)
+	}
+	return repo.loadMatch(replay)
+}
+
+func (repo *ReplayRepository) GetReplay(id uint) (models.Replay, error) {
+	replay, err := scanReplay(repo.db.QueryRow("SELECT "+replayColumns+" FROM replays WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.Replay{}, helper.ErrReplayNotFound
+	} else if err != nil {
+		return models.Replay{}, fmt.Errorf("failed to get replay: %v", err)
+	}
+	if err := repo.loadMatch(&replay); err != nil {
+		return models.Replay{}, err
+	}
+	return replay, nil
+}
+
+// GetReplaysForUser pages through the replays of the player's matches
+// newest first, starting below the before cursor.
+func (repo *ReplayRepository) GetReplaysForUser(userID, before uint, limit int) ([]models.Replay, error) {
+	query := `
+		SELECT ` + replayColumns + ` FROM replays
+		WHERE match_id IN (SELECT match_id FROM match_players WHERE user_id = $1) AND id < $2
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	rows, err := repo.db.Query(query, userID, cursorID(before), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replays: %v", err)
+	}
+
+	var replays []models.Replay
+	for rows.Next() {
+		replay, err := scanReplay(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan replay: %v", err)
+		}
+		replays = append(replays, replay)
+	}
+	rows.Close()
+
+	for i := range replays {
+		if err := repo.loadMatch(&replays[i]); err != nil {
+			return nil, err
+		}
+	}
+	return replays, nil
+}
+
+// SetVerification records the result of re-simulating the replay; reason
+// says why a replay failed and is cleared when it passes.
+func (repo *ReplayRepository) SetVerification(id uint, verified bool, reason string, now time.Time) error {
+	query := `UPDATE replays SET verified = $1, verify_error = $2, verified_at = $3 WHERE id = $4`
+	result, err := repo.db.Exec(query, verified, reason, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to record replay verification: %v", err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return helper.ErrReplayNotFound
+	}
+	return nil
+}
@@ -0,0 +1,486 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type SeasonRepo interface {
+	GetSeasons() ([]models.Season, error)
+	GetSeason(id uint) (models.Season, error)
+	GetCurrentSeason(now time.Time) (models.Season, error)
+	CreateSeason(season *models.Season) error
+	UpdateSeason(season models.Season) error
+	GetRank(userID uint) (int32, error)
+	GetEndedSeasons(now time.Time) ([]models.Season, error)
+	CloseSeason(id uint, now time.Time) ([]models.SeasonResult, error)
+	GetSeasonResults(userID uint) ([]models.SeasonResult, error)
+	GetBestTrophies(userID uint) (int32, error)
+	GetTrophyRoad(userID uint) ([]models.TrophyRoadMilestone, error)
+	ClaimMilestone(userID, milestoneID uint, now time.Time) (models.TrophyRoadMilestone, error)
+}
+
+type SeasonRepository struct {
+	db *sql.DB
+}
+
+func NewSeasonRepository(db *sql.DB) *SeasonRepository {
+	return &SeasonRepository{db}
+}
+
+const seasonColumns = "id, created_at, updated_at, name, starts_at, ends_at, reset_threshold, reset_keep_percent, closed_at"
+
+// bestTrophiesQuery is the most trophies a player has held: their current
+// count or their finish in any closed season, whichever is higher. It expects
+// the user id as $1.
+const bestTrophiesQuery = `
+	SELECT GREATEST(
+		COALESCE((SELECT awards FROM users WHERE id = $1), 0),
+		COALESCE((SELECT MAX(trophies) FROM season_results WHERE user_id = $1), 0)
+	)
+`
+
+func scanSeason(row rowScanner) (models.Season, error) {
+	var season models.Season
+	err := row.Scan(
+		&season.ID,
+		&season.CreatedAt,
+		&season.UpdatedAt,
+		&season.Name,
+		&season.StartsAt,
+		&season.EndsAt,
+		&season.ResetThreshold,
+		&season.ResetKeepPercent,
+		&season.ClosedAt,
+	)
+	return season, err
+}
+
+func (repo *SeasonRepository) getRewards(season *models.Season) error {
+	rows, err := repo.db.Query("SELECT min_rank, max_rank, reward_gold FROM season_rewards WHERE season_id = $1 ORDER BY min_rank", season.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get season rewards: %v", err)
+	}
+	defer rows.Close()
+
+	season.Rewards = nil
+	for rows.Next() {
+		var reward models.SeasonReward
+		if err := rows.Scan(&reward.MinRank, &reward.MaxRank, &reward.RewardGold); err != nil {
+			return fmt.Errorf("failed to scan season reward: %v", err)
+		}
+		season.Rewards = append(season.Rewards, reward)
+	}
+	return nil
+}
+
+func (repo *SeasonRepository) getSeasons(query string, args ...interface{}) ([]models.Season, error) {
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seasons: %v", err)
+	}
+
+	var seasons []models.Season
+	for rows.Next() {
+		season, err := scanSeason(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan season: %v", err)
+		}
+		seasons = append(seasons, season)
+	}
+	rows.Close()
+
+	for i := range seasons {
+		if err := repo.getRewards(&seasons[i]); err != nil {
+			return nil, err
+		}
+	}
+	return seasons, nil
+}
+
+func (repo *SeasonRepository) GetSeasons() ([]models.Season, error) {
+	return repo.getSeasons("SELECT " + seasonColumns + " FROM seasons ORDER BY starts_at DESC")
+}
+
+func (repo *SeasonRepository) GetSeason(id uint) (models.Season, error) {
+	season, err := scanSeason(repo.db.QueryRow("SELECT "+seasonColumns+" FROM seasons WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.Season{}, helper.ErrSeasonNotFound
+	} else if err != nil {
+		return models.Season{}, fmt.Errorf("failed to get season: %v", err)
+	}
+	if err := repo.getRewards(&season); err != nil {
+		return models.Season{}, err
+	}
+	return season, nil
+}
+
+// GetCurrentSeason returns helper.ErrSeasonNotFound between seasons.
+func (repo *SeasonRepository) GetCurrentSeason(now time.Time) (models.Season, error) {
+	query := "SELECT " + seasonColumns + " FROM seasons WHERE starts_at <= $1 AND ends_at > $1 AND closed_at IS NULL"
+	season, err := scanSeason(repo.db.QueryRow(query, now))
+	if err == sql.ErrNoRows {
+		return models.Season{}, helper.ErrSeasonNotFound
+	} else if err != nil {
+		return models.Season{}, fmt.Errorf("failed to get current season: %v", err)
+	}
+	if err := repo.getRewards(&season); err != nil {
+		return models.Season{}, err
+	}
+	return season, nil
+}
+
+// checkOverlap returns helper.ErrSeasonOverlap when another season shares any
+// time with the given one. The table is locked first so two admins can't
+// schedule overlapping seasons at once.
+func checkOverlap(tx *sql.Tx, season models.Season) error {
+	if _, err := tx.Exec("LOCK TABLE seasons IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return fmt.Errorf("failed to lock seasons: %v", err)
+	}
+	var overlaps bool
+	query := "SELECT EXISTS (SELECT 1 FROM seasons WHERE id <> $1 AND starts_at < $3 AND ends_at > $2)"
+	if err := tx.QueryRow(query, season.ID, season.StartsAt, season.EndsAt).Scan(&overlaps); err != nil {
+		return fmt.Errorf("failed to check season overlap: %v", err)
+	}
+	if overlaps {
+		return helper.ErrSeasonOverlap
+	}
+	return nil
+}
+
+func insertSeasonRewards(tx *sql.Tx, seasonID uint, rewards []models.SeasonReward) error {
+	for _, reward := range rewards {
+		query := "INSERT INTO season_rewards (season_id, min_rank, max_rank, reward_gold) VALUES ($1, $2, $3, $4)"
+		if _, err := tx.Exec(query, seasonID, reward.MinRank, reward.MaxRank, reward.RewardGold); err != nil {
+			return fmt.Errorf("failed to save season reward: %v", err)
+		}
+	}
+	return nil
+}
+
+func (repo *SeasonRepository) CreateSeason(season *models.Season) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkOverlap(tx, *season); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO seasons (name, starts_at, ends_at, reset_threshold, reset_keep_percent)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRow(query, season.Name, season.StartsAt, season.EndsAt, season.ResetThreshold, season.ResetKeepPercent).
+		Scan(&season.ID, &season.CreatedAt, &season.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create season: %v", err)
+	}
+	if err := insertSeasonRewards(tx, season.ID, season.Rewards); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit season: %v", err)
+	}
+	return nil
+}
+
+// UpdateSeason reschedules a season and replaces its rewards. Closed seasons
+// return helper.ErrSeasonClosed since their rewards are already paid, and
+// unknown ones helper.ErrSeasonNotFound.
+func (repo *SeasonRepository) UpdateSeason(season models.Season) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var closedAt pq.NullTime
+	err = tx.QueryRow("SELECT closed_at FROM seasons WHERE id = $1 FOR UPDATE", season.ID).Scan(&closedAt)
+	if err == sql.ErrNoRows {
+		return helper.ErrSeasonNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to get season: %v", err)
+	}
+	if closedAt.Valid {
+		return helper.ErrSeasonClosed
+	}
+
+	if err := checkOverlap(tx, season); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE seasons
+		SET name = $1, starts_at = $2, ends_at = $3, reset_threshold = $4, reset_keep_percent = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+	`
+	if _, err := tx.Exec(query, season.Name, season.StartsAt, season.EndsAt, season.ResetThreshold, season.ResetKeepPercent, season.ID); err != nil {
+		return fmt.Errorf("failed to update season: %v", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM season_rewards WHERE season_id = $1", season.ID); err != nil {
+		return fmt.Errorf("failed to clear season rewards: %v", err)
+	}
+	if err := insertSeasonRewards(tx, season.ID, season.Rewards); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit season: %v", err)
+	}
+	return nil
+}
+
+// GetRank is the player's position on the global trophy ladder. Players on
+// the same trophies share a rank, as they do when a season closes.
+func (repo *SeasonRepository) GetRank(userID uint) (int32, error) {
+	query := `
+		SELECT COUNT(*) + 1 FROM users
+		WHERE deleted_at IS NULL
+		  AND COALESCE(awards, 0) > (SELECT COALESCE(awards, 0) FROM users WHERE id = $1)
+	`
+	var rank int32
+	if err := repo.db.QueryRow(query, userID).Scan(&rank); err != nil {
+		return 0, fmt.Errorf("failed to get rank: %v", err)
+	}
+	return rank, nil
+}
+
+func (repo *SeasonRepository) GetEndedSeasons(now time.Time) ([]models.Season, error) {
+	return repo.getSeasons("SELECT "+seasonColumns+" FROM seasons WHERE ends_at <= $1 AND closed_at IS NULL ORDER BY ends_at", now)
+}
+
+// CloseSeason ranks every player with trophies, pays the season rewards and
+// applies the soft reset, all in one transaction that also marks the season
+// closed. Closing a season twice returns helper.ErrSeasonClosed and changes
+// nothing. It returns the results of the players who earned a reward.
+func (repo *SeasonRepository) CloseSeason(id uint, now time.Time) ([]models.SeasonResult, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var name string
+	var endsAt time.Time
+	var closedAt pq.NullTime
+	err = tx.QueryRow("SELECT name, ends_at, closed_at FROM seasons WHERE id = $1 FOR UPDATE", id).Scan(&name, &endsAt, &closedAt)
+	if err == sql.ErrNoRows {
+		return nil, helper.ErrSeasonNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get season: %v", err)
+	}
+	if closedAt.Valid {
+		return nil, helper.ErrSeasonClosed
+	}
+	if now.Before(endsAt) {
+		return nil, helper.ErrSeasonNotEnded
+	}
+
+	rankQuery := `
+		INSERT INTO season_results (season_id, user_id, rank, trophies, reset_trophies, reward_gold)
+		SELECT s.id, r.id, r.rank, r.trophies,
+		       CASE WHEN r.trophies > s.reset_threshold
+		            THEN s.reset_threshold + (r.trophies - s.reset_threshold) * s.reset_keep_percent / 100
+		            ELSE r.trophies END,
+		       COALESCE((
+		           SELECT MAX(w.reward_gold) FROM season_rewards w
+		           WHERE w.season_id = s.id AND r.rank BETWEEN w.min_rank AND w.max_rank
+		       ), 0)
+		FROM seasons s
+		CROSS JOIN (
+			SELECT id, COALESCE(awards, 0) AS trophies, RANK() OVER (ORDER BY COALESCE(awards, 0) DESC) AS rank
+			FROM users
+			WHERE deleted_at IS NULL AND COALESCE(awards, 0) > 0
+		) r
+		WHERE s.id = $1
+	`
+	if _, err := tx.Exec(rankQuery, id); err != nil {
+		return nil, fmt.Errorf("failed to rank season: %v", err)
+	}
+
+	// Trophies are lowered by the amount cut rather than set outright, so a
+	// match finishing while the season closes isn't lost.
+	settleQuery := `
+		UPDATE users u
+		SET awards = COALESCE(u.awards, 0) - (r.trophies - r.reset_trophies)
+		FROM season_results r
+		WHERE r.season_id = $1 AND r.user_id = u.id AND r.trophies <> r.reset_trophies
+	`
+	if _, err := tx.Exec(settleQuery, id); err != nil {
+		return nil, fmt.Errorf("failed to settle season: %v", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT user_id, rank, trophies, reset_trophies, reward_gold
+		FROM season_results WHERE season_id = $1 AND reward_gold > 0
+		ORDER BY rank
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get season rewards: %v", err)
+	}
+	var rewarded []models.SeasonResult
+	for rows.Next() {
+		result := models.SeasonResult{SeasonID: id, SeasonName: name}
+		if err := rows.Scan(&result.UserID, &result.Rank, &result.Trophies, &result.ResetTrophies, &result.RewardGold); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan season result: %v", err)
+		}
+		rewarded = append(rewarded, result)
+	}
+	rows.Close()
+
+	// Rewards go through creditWallet like every other credit, so each one
+	// has its ledger entry.
+	reference := fmt.Sprintf("season:%d", id)
+	for _, result := range rewarded {
+		if err := creditWallet(tx, result.UserID, models.CurrencyGold, result.RewardGold, models.ReasonSeasonReward, reference); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE seasons SET closed_at = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", now, id); err != nil {
+		return nil, fmt.Errorf("failed to close season: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit season close: %v", err)
+	}
+	return rewarded, nil
+}
+
+func (repo *SeasonRepository) GetSeasonResults(userID uint) ([]models.SeasonResult, error) {
+	query := `
+		SELECT r.season_id, s.name, r.user_id, r.rank, r.trophies, r.reset_trophies, r.reward_gold
+		FROM season_results r
+		JOIN seasons s ON s.id = r.season_id
+		WHERE r.user_id = $1
+		ORDER BY s.ends_at DESC
+	`
+	rows, err := repo.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get season results: %v", err)
+	}
+	defer rows.Close()
+
+	var results []models.SeasonResult
+	for rows.Next() {
+		var result models.SeasonResult
+		err := rows.Scan(&result.SeasonID, &result.SeasonName, &result.UserID, &result.Rank, &result.Trophies, &result.ResetTrophies, &result.RewardGold)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan season result: %v", err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// GetBestTrophies is what the trophy road is unlocked by, so a soft reset
+// never takes away a milestone a player already reached.
+func (repo *SeasonRepository) GetBestTrophies(userID uint) (int32, error) {
+	var trophies int32
+	if err := repo.db.QueryRow(bestTrophiesQuery, userID).Scan(&trophies); err != nil {
+		return 0, fmt.Errorf("failed to get best trophies: %v", err)
+	}
+	return trophies, nil
+}
+
+func (repo *SeasonRepository) GetTrophyRoad(userID uint) ([]models.TrophyRoadMilestone, error) {
+	query := `
+		SELECT m.id, m.trophies, m.reward_gold, m.reward_hero_id, m.reward_spell_id, c.created_at
+		FROM trophy_road_milestones m
+		LEFT JOIN trophy_road_claims c ON c.milestone_id = m.id AND c.user_id = $1
+		ORDER BY m.trophies
+	`
+	rows, err := repo.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trophy road: %v", err)
+	}
+	defer rows.Close()
+
+	var milestones []models.TrophyRoadMilestone
+	for rows.Next() {
+		var milestone models.TrophyRoadMilestone
+		err := rows.Scan(&milestone.ID, &milestone.Trophies, &milestone.RewardGold, &milestone.RewardHeroID, &milestone.RewardSpellID, &milestone.ClaimedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan trophy road milestone: %v", err)
+		}
+		milestones = append(milestones, milestone)
+	}
+
+	return milestones, nil
+}
+
+// ClaimMilestone pays a trophy road reward. The unique claim row makes sure
+// each milestone pays a player only once, even under concurrent claims.
+func (repo *SeasonRepository) ClaimMilestone(userID, milestoneID uint, now time.Time) (models.TrophyRoadMilestone, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.TrophyRoadMilestone{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	milestone := models.TrophyRoadMilestone{ID: milestoneID}
+	err = tx.QueryRow("SELECT trophies, reward_gold, reward_hero_id, reward_spell_id FROM trophy_road_milestones WHERE id = $1", milestoneID).
+		Scan(&milestone.Trophies, &milestone.RewardGold, &milestone.RewardHeroID, &milestone.RewardSpellID)
+	if err == sql.ErrNoRows {
+		return models.TrophyRoadMilestone{}, helper.ErrMilestoneNotFound
+	} else if err != nil {
+		return models.TrophyRoadMilestone{}, fmt.Errorf("failed to get trophy road milestone: %v", err)
+	}
+
+	var best int32
+	if err := tx.QueryRow(bestTrophiesQuery, userID).Scan(&best); err != nil {
+		return models.TrophyRoadMilestone{}, fmt.Errorf("failed to get best trophies: %v", err)
+	}
+	if best < milestone.Trophies {
+		return models.TrophyRoadMilestone{}, helper.ErrMilestoneLocked
+	}
+
+	query := `
+		INSERT INTO trophy_road_claims (user_id, milestone_id, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, milestone_id) DO NOTHING
+		RETURNING id
+	`
+	var claimID uint
+	err = tx.QueryRow(query, userID, milestoneID, now).Scan(&claimID)
+	if err == sql.ErrNoRows {
+		return models.TrophyRoadMilestone{}, helper.ErrMilestoneClaimed
+	} else if err != nil {
+		return models.TrophyRoadMilestone{}, fmt.Errorf("failed to claim trophy road milestone: %v", err)
+	}
+	milestone.ClaimedAt.Time, milestone.ClaimedAt.Valid = now, true
+
+	if milestone.RewardGold > 0 {
+		reference := fmt.Sprintf("milestone:%d", milestone.ID)
+		if err := creditWallet(tx, userID, models.CurrencyGold, milestone.RewardGold, models.ReasonTrophyRoad, reference); err != nil {
+			return models.TrophyRoadMilestone{}, err
+		}
+	}
+	if milestone.RewardHeroID.Valid {
+		if _, err := tx.Exec(grantHeroQuery, userID, milestone.RewardHeroID.Int64); err != nil {
+			return models.TrophyRoadMilestone{}, fmt.Errorf("failed to grant trophy road hero: %v", err)
+		}
+	}
+	if milestone.RewardSpellID.Valid {
+		if _, err := tx.Exec(grantSpellQuery, userID, milestone.RewardSpellID.Int64); err != nil {
+			return models.TrophyRoadMilestone{}, fmt.Errorf("failed to grant trophy road spell: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TrophyRoadMilestone{}, fmt.Errorf("failed to commit trophy road claim: %v", err)
+	}
+	return milestone, nil
+}
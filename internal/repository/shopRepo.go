@@ -0,0 +1,414 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type ShopRepo interface {
+	DrawDailyOffers(userID uint, period string, count, discountPercent int) ([]models.DailyOffer, error)
+	BuyDailyOffer(userID, offerID uint, period string, now time.Time) (models.DailyOffer, error)
+	GetOffers(activeAt *time.Time) ([]models.ShopOffer, error)
+	GetOffer(id uint) (models.ShopOffer, error)
+	CreateOffer(offer *models.ShopOffer) error
+	UpdateOffer(offer models.ShopOffer) error
+	BuyOffer(userID, offerID uint, now time.Time) (models.ShopOffer, error)
+}
+
+type ShopRepository struct {
+	db *sql.DB
+}
+
+func NewShopRepository(db *sql.DB) *ShopRepository {
+	return &ShopRepository{db}
+}
+
+const dailyOfferQuery = `
+	SELECT o.id, o.user_id, o.period, o.hero_id, o.spell_id,
+	       COALESCE(h.name, s.name, ''), COALESCE(h.rarity, ''), o.list_price, o.price, o.purchased_at
+	FROM daily_offers o
+	LEFT JOIN heros h ON h.id = o.hero_id
+	LEFT JOIN spells s ON s.id = o.spell_id
+`
+
+const shopOfferColumns = `
+	id, created_at, updated_at, title, description, price, currency, reward_gold, starts_at, ends_at,
+	stock, sold, per_user_limit, active
+`
+
+func scanDailyOffer(row rowScanner) (models.DailyOffer, error) {
+	var offer models.DailyOffer
+	err := row.Scan(
+		&offer.ID,
+		&offer.UserID,
+		&offer.Period,
+		&offer.HeroID,
+		&offer.SpellID,
+		&offer.Name,
+		&offer.Rarity,
+		&offer.ListPrice,
+		&offer.Price,
+		&offer.PurchasedAt,
+	)
+	return offer, err
+}
+
+func scanShopOffer(row rowScanner) (models.ShopOffer, error) {
+	var offer models.ShopOffer
+	err := row.Scan(
+		&offer.ID,
+		&offer.CreatedAt,
+		&offer.UpdatedAt,
+		&offer.Title,
+		&offer.Description,
+		&offer.Price,
+		&offer.Currency,
+		&offer.RewardGold,
+		&offer.StartsAt,
+		&offer.EndsAt,
+		&offer.Stock,
+		&offer.Sold,
+		&offer.PerUserLimit,
+		&offer.Active,
+	)
+	return offer, err
+}
+
+// DrawDailyOffers returns the player's offers for period, drawing them from
+// the unlocked cards they don't own yet on the first call. The user row is locked
+// while drawing so two first visits can't both draw.
+func (repo *ShopRepository) DrawDailyOffers(userID uint, period string, count, discountPercent int) ([]models.DailyOffer, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT id FROM users WHERE id = $1 FOR UPDATE", userID); err != nil {
+		return nil, fmt.Errorf("failed to lock user: %v", err)
+	}
+
+	var drawn bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM daily_offers WHERE user_id = $1 AND period = $2)", userID, period).Scan(&drawn); err != nil {
+		return nil, fmt.Errorf("failed to check daily offers: %v", err)
+	}
+	if !drawn {
+		query := `
+			INSERT INTO daily_offers (user_id, period, hero_id, spell_id, list_price, price)
+			SELECT $1, $2, c.hero_id, c.spell_id, c.price, c.price * (100 - $4) / 100
+			FROM (
+				SELECT id AS hero_id, NULL::int AS spell_id, COALESCE(price, 0) AS price FROM heros
+				WHERE deleted_at IS NULL AND id NOT IN (SELECT hero_id FROM user_heros WHERE user_id = $1)
+				  AND id NOT IN (SELECT hero_id FROM (%s) locked)
+				UNION ALL
+				SELECT NULL::int, id, COALESCE(price, 0) FROM spells
+				WHERE deleted_at IS NULL AND id NOT IN (SELECT spell_id FROM user_spells WHERE user_id = $1)
+				  AND id NOT IN (SELECT spell_id FROM (%s) locked)
+			) c
+			ORDER BY random()
+			LIMIT $3
+		`
+		query = fmt.Sprintf(query, fmt.Sprintf(lockedCardsQuery, "hero_id"), fmt.Sprintf(lockedCardsQuery, "spell_id"))
+		if _, err := tx.Exec(query, userID, period, count, discountPercent); err != nil {
+			return nil, fmt.Errorf("failed to draw daily offers: %v", err)
+		}
+	}
+
+	rows, err := tx.Query(dailyOfferQuery+" WHERE o.user_id = $1 AND o.period = $2 ORDER BY o.id", userID, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily offers: %v", err)
+	}
+	var offers []models.DailyOffer
+	for rows.Next() {
+		offer, err := scanDailyOffer(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan daily offer: %v", err)
+		}
+		offers = append(offers, offer)
+	}
+	rows.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit daily offers: %v", err)
+	}
+	return offers, nil
+}
+
+// BuyDailyOffer sells one of the player's daily offers. Offers from an
+// earlier period return helper.ErrOfferExpired and bought ones
+// helper.ErrOfferSoldOut.
+func (repo *ShopRepository) BuyDailyOffer(userID, offerID uint, period string, now time.Time) (models.DailyOffer, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.DailyOffer{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	offer, err := scanDailyOffer(tx.QueryRow(dailyOfferQuery+" WHERE o.id = $1 AND o.user_id = $2 FOR UPDATE OF o", offerID, userID))
+	if err == sql.ErrNoRows {
+		return models.DailyOffer{}, helper.ErrOfferNotFound
+	} else if err != nil {
+		return models.DailyOffer{}, fmt.Errorf("failed to get daily offer: %v", err)
+	}
+	if offer.Period != period {
+		return models.DailyOffer{}, helper.ErrOfferExpired
+	}
+	if offer.PurchasedAt.Valid {
+		return models.DailyOffer{}, helper.ErrOfferSoldOut
+	}
+
+	reference := fmt.Sprintf("daily:%d", offer.ID)
+	if err := debitWallet(tx, userID, models.CurrencyGold, offer.Price, models.ReasonDailyOffer, reference); err != nil {
+		return models.DailyOffer{}, err
+	}
+	item := models.ShopOfferItem{HeroID: offer.HeroID, SpellID: offer.SpellID}
+	if err := grantShopItem(tx, userID, item); err != nil {
+		return models.DailyOffer{}, err
+	}
+	if _, err := tx.Exec("UPDATE daily_offers SET purchased_at = $1 WHERE id = $2", now, offer.ID); err != nil {
+		return models.DailyOffer{}, fmt.Errorf("failed to mark daily offer bought: %v", err)
+	}
+	offer.PurchasedAt.Time, offer.PurchasedAt.Valid = now, true
+
+	if err := tx.Commit(); err != nil {
+		return models.DailyOffer{}, fmt.Errorf("failed to commit daily offer purchase: %v", err)
+	}
+	return offer, nil
+}
+
+// grantShopItem adds a bought card to the collection, refusing cards the
+// player hasn't unlocked.
+func grantShopItem(tx *sql.Tx, userID uint, item models.ShopOfferItem) error {
+	if err := checkCardUnlocked(tx, userID, item.HeroID, item.SpellID); err != nil {
+		return err
+	}
+	if item.HeroID.Valid {
+		if _, err := tx.Exec("INSERT INTO user_heros (user_id, hero_id) VALUES ($1, $2)", userID, item.HeroID.Int64); err != nil {
+			return fmt.Errorf("failed to add hero to user: %v", err)
+		}
+	}
+	if item.SpellID.Valid {
+		if _, err := tx.Exec("INSERT INTO user_spells (user_id, spell_id) VALUES ($1, $2)", userID, item.SpellID.Int64); err != nil {
+			return fmt.Errorf("failed to add spell to user: %v", err)
+		}
+	}
+	return nil
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// getOfferItems fills in the offer's items and its value.
+func getOfferItems(db queryer, offer *models.ShopOffer) error {
+	query := `
+		SELECT i.hero_id, i.spell_id, COALESCE(h.name, s.name, ''), COALESCE(h.rarity, ''), COALESCE(h.price, s.price, 0)
+		FROM shop_offer_items i
+		LEFT JOIN heros h ON h.id = i.hero_id
+		LEFT JOIN spells s ON s.id = i.spell_id
+		WHERE i.offer_id = $1
+		ORDER BY i.id
+	`
+	rows, err := db.Query(query, offer.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get offer items: %v", err)
+	}
+	defer rows.Close()
+
+	offer.Items = nil
+	offer.Value = offer.RewardGold
+	for rows.Next() {
+		var item models.ShopOfferItem
+		if err := rows.Scan(&item.HeroID, &item.SpellID, &item.Name, &item.Rarity, &item.Price); err != nil {
+			return fmt.Errorf("failed to scan offer item: %v", err)
+		}
+		offer.Items = append(offer.Items, item)
+		offer.Value += item.Price
+	}
+	return nil
+}
+
+// GetOffers lists every offer, or only those on sale at activeAt when it is
+// set. Sold out offers stay listed so the shop can show them as such.
+func (repo *ShopRepository) GetOffers(activeAt *time.Time) ([]models.ShopOffer, error) {
+	query := "SELECT " + shopOfferColumns + " FROM shop_offers ORDER BY ends_at DESC, id DESC"
+	var args []interface{}
+	if activeAt != nil {
+		query = "SELECT " + shopOfferColumns + " FROM shop_offers WHERE active AND starts_at <= $1 AND ends_at > $1 ORDER BY ends_at, id"
+		args = append(args, *activeAt)
+	}
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shop offers: %v", err)
+	}
+
+	var offers []models.ShopOffer
+	for rows.Next() {
+		offer, err := scanShopOffer(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan shop offer: %v", err)
+		}
+		offers = append(offers, offer)
+	}
+	rows.Close()
+
+	for i := range offers {
+		if err := getOfferItems(repo.db, &offers[i]); err != nil {
+			return nil, err
+		}
+	}
+	return offers, nil
+}
+
+func (repo *ShopRepository) GetOffer(id uint) (models.ShopOffer, error) {
+	offer, err := scanShopOffer(repo.db.QueryRow("SELECT "+shopOfferColumns+" FROM shop_offers WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.ShopOffer{}, helper.ErrOfferNotFound
+	} else if err != nil {
+		return models.ShopOffer{}, fmt.Errorf("failed to get shop offer: %v", err)
+	}
+	if err := getOfferItems(repo.db, &offer); err != nil {
+		return models.ShopOffer{}, err
+	}
+	return offer, nil
+}
+
+func (repo *ShopRepository) CreateOffer(offer *models.ShopOffer) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO shop_offers (title, description, price, currency, reward_gold, starts_at, ends_at, stock, per_user_limit, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`
+	err = tx.QueryRow(query,
+		offer.Title,
+		offer.Description,
+		offer.Price,
+		offer.Currency,
+		offer.RewardGold,
+		offer.StartsAt,
+		offer.EndsAt,
+		offer.Stock,
+		offer.PerUserLimit,
+		offer.Active,
+	).Scan(&offer.ID, &offer.CreatedAt, &offer.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create shop offer: %v", err)
+	}
+
+	for _, item := range offer.Items {
+		if _, err := tx.Exec("INSERT INTO shop_offer_items (offer_id, hero_id, spell_id) VALUES ($1, $2, $3)", offer.ID, item.HeroID, item.SpellID); err != nil {
+			return fmt.Errorf("failed to add offer item: %v", err)
+		}
+	}
+	if err := getOfferItems(tx, offer); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit shop offer: %v", err)
+	}
+	return nil
+}
+
+// UpdateOffer changes an offer's terms. Its contents are fixed once created
+// because players may already have bought it.
+func (repo *ShopRepository) UpdateOffer(offer models.ShopOffer) error {
+	query := `
+		UPDATE shop_offers
+		SET title = $1, description = $2, price = $3, currency = $4, starts_at = $5, ends_at = $6, stock = $7,
+		    per_user_limit = $8, active = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10
+	`
+	_, err := repo.db.Exec(query,
+		offer.Title,
+		offer.Description,
+		offer.Price,
+		offer.Currency,
+		offer.StartsAt,
+		offer.EndsAt,
+		offer.Stock,
+		offer.PerUserLimit,
+		offer.Active,
+		offer.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update shop offer: %v", err)
+	}
+	return nil
+}
+
+// BuyOffer sells one bundle. The offer row is locked so stock and the
+// per-player limit hold under concurrent purchases.
+func (repo *ShopRepository) BuyOffer(userID, offerID uint, now time.Time) (models.ShopOffer, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.ShopOffer{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	offer, err := scanShopOffer(tx.QueryRow("SELECT "+shopOfferColumns+" FROM shop_offers WHERE id = $1 FOR UPDATE", offerID))
+	if err == sql.ErrNoRows {
+		return models.ShopOffer{}, helper.ErrOfferNotFound
+	} else if err != nil {
+		return models.ShopOffer{}, fmt.Errorf("failed to get shop offer: %v", err)
+	}
+	if !offer.Active || now.Before(offer.StartsAt) {
+		return models.ShopOffer{}, helper.ErrOfferNotFound
+	}
+	if !now.Before(offer.EndsAt) {
+		return models.ShopOffer{}, helper.ErrOfferExpired
+	}
+	if offer.Stock.Valid && offer.Sold >= offer.Stock.Int64 {
+		return models.ShopOffer{}, helper.ErrOfferSoldOut
+	}
+	if offer.PerUserLimit.Valid {
+		var bought int64
+		if err := tx.QueryRow("SELECT COUNT(*) FROM shop_purchases WHERE offer_id = $1 AND user_id = $2", offer.ID, userID).Scan(&bought); err != nil {
+			return models.ShopOffer{}, fmt.Errorf("failed to count offer purchases: %v", err)
+		}
+		if bought >= offer.PerUserLimit.Int64 {
+			return models.ShopOffer{}, helper.ErrOfferLimitReached
+		}
+	}
+	if err := getOfferItems(tx, &offer); err != nil {
+		return models.ShopOffer{}, err
+	}
+
+	reference := fmt.Sprintf("offer:%d", offer.ID)
+	if err := debitWallet(tx, userID, offer.Currency, offer.Price, models.ReasonShopOffer, reference); err != nil {
+		return models.ShopOffer{}, err
+	}
+	for _, item := range offer.Items {
+		if err := grantShopItem(tx, userID, item); err != nil {
+			return models.ShopOffer{}, err
+		}
+	}
+	if offer.RewardGold > 0 {
+		if err := creditWallet(tx, userID, models.CurrencyGold, offer.RewardGold, models.ReasonShopOffer, reference); err != nil {
+			return models.ShopOffer{}, err
+		}
+	}
+	if _, err := tx.Exec("UPDATE shop_offers SET sold = sold + 1 WHERE id = $1", offer.ID); err != nil {
+		return models.ShopOffer{}, fmt.Errorf("failed to update offer stock: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO shop_purchases (user_id, offer_id, price, created_at) VALUES ($1, $2, $3, $4)", userID, offer.ID, offer.Price, now); err != nil {
+		return models.ShopOffer{}, fmt.Errorf("failed to record offer purchase: %v", err)
+	}
+	offer.Sold++
+
+	if err := tx.Commit(); err != nil {
+		return models.ShopOffer{}, fmt.Errorf("failed to commit offer purchase: %v", err)
+	}
+	return offer, nil
+}
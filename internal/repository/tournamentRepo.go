@@ -0,0 +1,849 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/internal/tournaments"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"time"
+)
+
+type TournamentRepo interface {
+	CreateTournament(tournament *models.Tournament) error
+	GetTournaments(status string) ([]models.Tournament, error)
+	GetTournament(id uint) (models.Tournament, error)
+	Register(tournamentID uint, player models.TournamentPlayer, now time.Time) error
+	Withdraw(tournamentID, userID uint, now time.Time) error
+	CancelTournament(id uint) (models.TournamentUpdate, error)
+	GetDueTournaments(now time.Time) ([]uint, error)
+	AdvanceTournament(id uint, now time.Time) (models.TournamentUpdate, error)
+	GetBracket(id uint) ([]models.TournamentMatch, error)
+	GetStandings(id uint) ([]models.TournamentPlayer, error)
+}
+
+type TournamentRepository struct {
+	db *sql.DB
+}
+
+func NewTournamentRepository(db *sql.DB) *TournamentRepository {
+	return &TournamentRepository{db}
+}
+
+const tournamentColumns = `
+	id, created_at, updated_at, name, format, created_by, official, entry_fee, bonus_pool, max_players,
+	rounds, round_minutes, registration_opens_at, registration_closes_at, status, current_round,
+	round_ends_at, finished_at,
+	(SELECT COUNT(*) FROM tournament_players p WHERE p.tournament_id = tournaments.id),
+	bonus_pool + (SELECT COALESCE(SUM(fee_paid), 0) FROM tournament_players p WHERE p.tournament_id = tournaments.id)
+`
+
+func scanTournament(row rowScanner) (models.Tournament, error) {
+	var tournament models.Tournament
+	err := row.Scan(
+		&tournament.ID,
+		&tournament.CreatedAt,
+		&tournament.UpdatedAt,
+		&tournament.Name,
+		&tournament.Format,
+		&tournament.CreatedBy,
+		&tournament.Official,
+		&tournament.EntryFee,
+		&tournament.BonusPool,
+		&tournament.MaxPlayers,
+		&tournament.Rounds,
+		&tournament.RoundMinutes,
+		&tournament.RegistrationOpensAt,
+		&tournament.RegistrationClosesAt,
+		&tournament.Status,
+		&tournament.CurrentRound,
+		&tournament.RoundEndsAt,
+		&tournament.FinishedAt,
+		&tournament.Players,
+		&tournament.PrizePool,
+	)
+	return tournament, err
+}
+
+func getTournamentPrizes(db queryer, tournament *models.Tournament) error {
+	rows, err := db.Query("SELECT place, percent FROM tournament_prizes WHERE tournament_id = $1 ORDER BY place", tournament.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get tournament prizes: %v", err)
+	}
+	defer rows.Close()
+
+	tournament.Prizes = nil
+	for rows.Next() {
+		var prize models.TournamentPrize
+		if err := rows.Scan(&prize.Place, &prize.Percent); err != nil {
+			return fmt.Errorf("failed to scan tournament prize: %v", err)
+		}
+		tournament.Prizes = append(tournament.Prizes, prize)
+	}
+	return nil
+}
+
+// getTournamentPlayers returns the tournament's players with their deck
+// snapshots, sorted by orderBy.
+func getTournamentPlayers(db queryer, tournamentID uint, orderBy string) ([]models.TournamentPlayer, error) {
+	query := `
+		SELECT p.user_id, u.username, p.deck, p.fee_paid, p.seed, p.points, p.eliminated_round, p.place, p.prize_gold
+		FROM tournament_players p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.tournament_id = $1
+		ORDER BY ` + orderBy
+	rows, err := db.Query(query, tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournament players: %v", err)
+	}
+	defer rows.Close()
+
+	var players []models.TournamentPlayer
+	for rows.Next() {
+		var player models.TournamentPlayer
+		var deck []byte
+		err := rows.Scan(
+			&player.UserID,
+			&player.Username,
+			&deck,
+			&player.FeePaid,
+			&player.Seed,
+			&player.Points,
+			&player.EliminatedRound,
+			&player.Place,
+			&player.PrizeGold,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tournament player: %v", err)
+		}
+		if err := json.Unmarshal(deck, &player.Deck); err != nil {
+			return nil, fmt.Errorf("failed to decode deck snapshot: %v", err)
+		}
+		players = append(players, player)
+	}
+	return players, nil
+}
+
+func (repo *TournamentRepository) CreateTournament(tournament *models.Tournament) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO tournaments (
+			name, format, created_by, official, entry_fee, bonus_pool, max_players, rounds, round_minutes,
+			registration_opens_at, registration_closes_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at, status
+	`
+	err = tx.QueryRow(query,
+		tournament.Name,
+		tournament.Format,
+		tournament.CreatedBy,
+		tournament.Official,
+		tournament.EntryFee,
+		tournament.BonusPool,
+		tournament.MaxPlayers,
+		tournament.Rounds,
+		tournament.RoundMinutes,
+		tournament.RegistrationOpensAt,
+		tournament.RegistrationClosesAt,
+	).Scan(&tournament.ID, &tournament.CreatedAt, &tournament.UpdatedAt, &tournament.Status)
+	if err != nil {
+		return fmt.Errorf("failed to create tournament: %v", err)
+	}
+
+	for _, prize := range tournament.Prizes {
+		if _, err := tx.Exec("INSERT INTO tournament_prizes (tournament_id, place, percent) VALUES ($1, $2, $3)", tournament.ID, prize.Place, prize.Percent); err != nil {
+			return fmt.Errorf("failed to add tournament prize: %v", err)
+		}
+	}
+	tournament.PrizePool = tournament.BonusPool
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tournament: %v", err)
+	}
+	return nil
+}
+
+// GetTournaments lists tournaments, newest first, only those in status
+// unless it is empty.
+func (repo *TournamentRepository) GetTournaments(status string) ([]models.Tournament, error) {
+	rows, err := repo.db.Query("SELECT "+tournamentColumns+" FROM tournaments WHERE $1 = '' OR status = $1 ORDER BY id DESC", status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournaments: %v", err)
+	}
+
+	var list []models.Tournament
+	for rows.Next() {
+		tournament, err := scanTournament(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan tournament: %v", err)
+		}
+		list = append(list, tournament)
+	}
+	rows.Close()
+
+	for i := range list {
+		if err := getTournamentPrizes(repo.db, &list[i]); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (repo *TournamentRepository) GetTournament(id uint) (models.Tournament, error) {
+	tournament, err := scanTournament(repo.db.QueryRow("SELECT "+tournamentColumns+" FROM tournaments WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return models.Tournament{}, helper.ErrTournamentNotFound
+	} else if err != nil {
+		return models.Tournament{}, fmt.Errorf("failed to get tournament: %v", err)
+	}
+	if err := getTournamentPrizes(repo.db, &tournament); err != nil {
+		return models.Tournament{}, err
+	}
+	return tournament, nil
+}
+
+// lockTournament reads the tournament and locks its row until tx ends. Every
+// change to a tournament's registrations or rounds takes this lock first, so
+// they happen one at a time.
+func lockTournament(tx *sql.Tx, id uint) (models.Tournament, error) {
+	tournament, err := scanTournament(tx.QueryRow("SELECT "+tournamentColumns+" FROM tournaments WHERE id = $1 FOR UPDATE", id))
+	if err == sql.ErrNoRows {
+		return models.Tournament{}, helper.ErrTournamentNotFound
+	} else if err != nil {
+		return models.Tournament{}, fmt.Errorf("failed to lock tournament: %v", err)
+	}
+	return tournament, nil
+}
+
+func registrationOpen(tournament models.Tournament, now time.Time) bool {
+	return tournament.Status == models.TournamentRegistration &&
+		!now.Before(tournament.RegistrationOpensAt) && now.Before(tournament.RegistrationClosesAt)
+}
+
+// Register enters the player with their deck snapshot and takes the entry
+// fee from their gold.
+func (repo *TournamentRepository) Register(tournamentID uint, player models.TournamentPlayer, now time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	tournament, err := lockTournament(tx, tournamentID)
+	if err != nil {
+		return err
+	}
+	if !registrationOpen(tournament, now) {
+		return helper.ErrRegistrationClosed
+	}
+	if tournament.Players >= tournament.MaxPlayers {
+		return helper.ErrTournamentFull
+	}
+
+	deck, err := json.Marshal(player.Deck)
+	if err != nil {
+		return fmt.Errorf("failed to encode deck snapshot: %v", err)
+	}
+	query := "INSERT INTO tournament_players (tournament_id, user_id, deck, fee_paid) VALUES ($1, $2, $3, $4)"
+	_, err = tx.Exec(query, tournament.ID, player.UserID, deck, tournament.EntryFee)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return helper.ErrAlreadyRegistered
+	} else if err != nil {
+		return fmt.Errorf("failed to register for tournament: %v", err)
+	}
+	if tournament.EntryFee > 0 {
+		reference := fmt.Sprintf("tournament:%d", tournament.ID)
+		if err := debitWallet(tx, player.UserID, models.CurrencyGold, tournament.EntryFee, models.ReasonTournamentEntry, reference); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit registration: %v", err)
+	}
+	return nil
+}
+
+// Withdraw takes the player out of a tournament that hasn't closed
+// registration and refunds their entry fee.
+func (repo *TournamentRepository) Withdraw(tournamentID, userID uint, now time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	tournament, err := lockTournament(tx, tournamentID)
+	if err != nil {
+		return err
+	}
+	if !registrationOpen(tournament, now) {
+		return helper.ErrRegistrationClosed
+	}
+
+	var feePaid int64
+	err = tx.QueryRow("DELETE FROM tournament_players WHERE tournament_id = $1 AND user_id = $2 RETURNING fee_paid", tournament.ID, userID).Scan(&feePaid)
+	if err == sql.ErrNoRows {
+		return helper.ErrNotRegistered
+	} else if err != nil {
+		return fmt.Errorf("failed to withdraw from tournament: %v", err)
+	}
+	if feePaid > 0 {
+		reference := fmt.Sprintf("tournament:%d", tournament.ID)
+		if err := creditWallet(tx, userID, models.CurrencyGold, feePaid, models.ReasonTournamentRefund, reference); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit withdrawal: %v", err)
+	}
+	return nil
+}
+
+// cancelTournament refunds every entry fee and cancels the tournament,
+// returning the players who were registered.
+func cancelTournament(tx *sql.Tx, tournament *models.Tournament) ([]uint, error) {
+	players, err := getTournamentPlayers(tx, tournament.ID, "p.created_at")
+	if err != nil {
+		return nil, err
+	}
+
+	reference := fmt.Sprintf("tournament:%d", tournament.ID)
+	var refunded []uint
+	for _, player := range players {
+		if player.FeePaid > 0 {
+			if err := creditWallet(tx, player.UserID, models.CurrencyGold, player.FeePaid, models.ReasonTournamentRefund, reference); err != nil {
+				return nil, err
+			}
+		}
+		refunded = append(refunded, player.UserID)
+	}
+
+	tournament.Status = models.TournamentCancelled
+	if _, err := tx.Exec("UPDATE tournaments SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", tournament.Status, tournament.ID); err != nil {
+		return nil, fmt.Errorf("failed to cancel tournament: %v", err)
+	}
+	return refunded, nil
+}
+
+// CancelTournament calls off a tournament before it starts. It returns
+// helper.ErrTournamentStarted once the first round has been paired.
+func (repo *TournamentRepository) CancelTournament(id uint) (models.TournamentUpdate, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.TournamentUpdate{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	tournament, err := lockTournament(tx, id)
+	if err != nil {
+		return models.TournamentUpdate{}, err
+	}
+	if tournament.Status != models.TournamentRegistration {
+		return models.TournamentUpdate{}, helper.ErrTournamentStarted
+	}
+	refunded, err := cancelTournament(tx, &tournament)
+	if err != nil {
+		return models.TournamentUpdate{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TournamentUpdate{}, fmt.Errorf("failed to commit tournament cancellation: %v", err)
+	}
+	return models.TournamentUpdate{Tournament: tournament, Refunded: refunded}, nil
+}
+
+// GetDueTournaments returns the tournaments that may have something to do:
+// those whose registration has closed and those running.
+func (repo *TournamentRepository) GetDueTournaments(now time.Time) ([]uint, error) {
+	query := `
+		SELECT id FROM tournaments
+		WHERE (status = $1 AND registration_closes_at <= $2) OR status = $3
+		ORDER BY id
+	`
+	rows, err := repo.db.Query(query, models.TournamentRegistration, now, models.TournamentRunning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due tournaments: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan tournament id: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// AdvanceTournament moves a tournament on by at most one step: it starts it
+// once registration closes, settles the current round once all its matches
+// have finished or its time is up and pairs the next one, or pays out the
+// prizes after the last round. The tournament row stays locked throughout and
+// each step checks the state it starts from, so running it again, or on
+// several servers at once, never pairs or settles a round twice.
+func (repo *TournamentRepository) AdvanceTournament(id uint, now time.Time) (models.TournamentUpdate, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.TournamentUpdate{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	tournament, err := lockTournament(tx, id)
+	if err != nil {
+		return models.TournamentUpdate{}, err
+	}
+
+	var update models.TournamentUpdate
+	switch {
+	case tournament.Status == models.TournamentRegistration && !now.Before(tournament.RegistrationClosesAt):
+		if tournament.Players < 2 {
+			update.Refunded, err = cancelTournament(tx, &tournament)
+		} else {
+			update.Pairings, err = startTournament(tx, &tournament, now)
+		}
+	case tournament.Status == models.TournamentRunning:
+		update, err = advanceRound(tx, &tournament, now)
+	}
+	if err != nil {
+		return models.TournamentUpdate{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TournamentUpdate{}, fmt.Errorf("failed to commit tournament: %v", err)
+	}
+	update.Tournament = tournament
+	return update, nil
+}
+
+func playersByID(players []models.TournamentPlayer) map[uint]models.TournamentPlayer {
+	byID := make(map[uint]models.TournamentPlayer, len(players))
+	for _, player := range players {
+		byID[player.UserID] = player
+	}
+	return byID
+}
+
+// startTournament seeds the players by trophies and pairs the first round.
+func startTournament(tx *sql.Tx, tournament *models.Tournament, now time.Time) ([]models.TournamentMatch, error) {
+	query := `
+		UPDATE tournament_players p SET seed = s.seed
+		FROM (
+			SELECT p.id, ROW_NUMBER() OVER (ORDER BY u.awards DESC, p.created_at) AS seed
+			FROM tournament_players p
+			JOIN users u ON u.id = p.user_id
+			WHERE p.tournament_id = $1
+		) s
+		WHERE p.id = s.id
+	`
+	if _, err := tx.Exec(query, tournament.ID); err != nil {
+		return nil, fmt.Errorf("failed to seed tournament: %v", err)
+	}
+	players, err := getTournamentPlayers(tx, tournament.ID, "p.seed")
+	if err != nil {
+		return nil, err
+	}
+
+	var pairings []tournaments.Pairing
+	if tournament.Format == models.TournamentSwiss {
+		if tournament.Rounds == 0 {
+			tournament.Rounds = tournaments.Rounds(len(players))
+		}
+		standings := make([]tournaments.Standing, 0, len(players))
+		for _, player := range players {
+			standings = append(standings, tournaments.Standing{UserID: player.UserID, Seed: int32(player.Seed.Int64)})
+		}
+		pairings = tournaments.PairSwiss(standings, nil)
+	} else {
+		tournament.Rounds = tournaments.Rounds(len(players))
+		seeded := make([]uint, 0, len(players))
+		for _, player := range players {
+			seeded = append(seeded, player.UserID)
+		}
+		pairings = tournaments.SeedBracket(seeded)
+	}
+
+	tournament.Status = models.TournamentRunning
+	return startRound(tx, tournament, 1, playersByID(players), pairings, now)
+}
+
+// startRound stores a round's pairings, starts a match for each one with the
+// players' registered decks and settles the byes straight away.
+func startRound(tx *sql.Tx, tournament *models.Tournament, round int32, players map[uint]models.TournamentPlayer, pairings []tournaments.Pairing, now time.Time) ([]models.TournamentMatch, error) {
+	var matches []models.TournamentMatch
+	for _, pairing := range pairings {
+		player1 := players[pairing.Player1]
+		tournamentMatch := models.TournamentMatch{
+			Round:       round,
+			Position:    pairing.Position,
+			Player1ID:   sql.NullInt64{Int64: int64(pairing.Player1), Valid: true},
+			Player1Name: player1.Username,
+		}
+		if pairing.Player2 != 0 {
+			player2 := players[pairing.Player2]
+			tournamentMatch.Player2ID = sql.NullInt64{Int64: int64(pairing.Player2), Valid: true}
+			tournamentMatch.Player2Name = player2.Username
+			match := models.Match{
+				Mode: models.MatchModeTournament,
+				Players: []models.MatchPlayer{
+					{UserID: player1.UserID, Username: player1.Username, Deck: player1.Deck},
+					{UserID: player2.UserID, Username: player2.Username, Deck: player2.Deck},
+				},
+			}
+			if err := insertMatch(tx, &match); err != nil {
+				return nil, err
+			}
+			tournamentMatch.MatchID = sql.NullInt64{Int64: int64(match.ID), Valid: true}
+		} else {
+			tournamentMatch.WinnerID = tournamentMatch.Player1ID
+			tournamentMatch.FinishedAt = pq.NullTime{Time: now, Valid: true}
+			if tournament.Format == models.TournamentSwiss {
+				if _, err := tx.Exec("UPDATE tournament_players SET points = points + 1 WHERE tournament_id = $1 AND user_id = $2", tournament.ID, pairing.Player1); err != nil {
+					return nil, fmt.Errorf("failed to score bye: %v", err)
+				}
+			}
+		}
+
+		query := `
+			INSERT INTO tournament_matches (tournament_id, round, position, player1_id, player2_id, match_id, winner_id, finished_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`
+		_, err := tx.Exec(query,
+			tournament.ID,
+			round,
+			tournamentMatch.Position,
+			tournamentMatch.Player1ID,
+			tournamentMatch.Player2ID,
+			tournamentMatch.MatchID,
+			tournamentMatch.WinnerID,
+			tournamentMatch.FinishedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pair tournament match: %v", err)
+		}
+		matches = append(matches, tournamentMatch)
+	}
+
+	tournament.CurrentRound = round
+	tournament.RoundEndsAt = pq.NullTime{Time: now.Add(time.Duration(tournament.RoundMinutes) * time.Minute), Valid: true}
+	query := `
+		UPDATE tournaments
+		SET status = $1, rounds = $2, current_round = $3, round_ends_at = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5
+	`
+	_, err := tx.Exec(query, tournament.Status, tournament.Rounds, tournament.CurrentRound, tournament.RoundEndsAt, tournament.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start tournament round: %v", err)
+	}
+	return matches, nil
+}
+
+type pendingPairing struct {
+	id          uint
+	player1ID   sql.NullInt64
+	player2ID   sql.NullInt64
+	matchID     sql.NullInt64
+	matchStatus sql.NullString
+	winnerID    sql.NullInt64
+}
+
+// advanceRound settles the current round when it is over and moves on to the
+// next round or the end of the tournament. Matches still being played when
+// the round's time is up are ended without a winner. In an elimination
+// bracket the better seed goes through from a pairing without a winner; in
+// Swiss neither player scores.
+func advanceRound(tx *sql.Tx, tournament *models.Tournament, now time.Time) (models.TournamentUpdate, error) {
+	query := `
+		SELECT tm.id, tm.player1_id, tm.player2_id, tm.match_id, m.status, m.winner_id
+		FROM tournament_matches tm
+		LEFT JOIN matches m ON m.id = tm.match_id
+		WHERE tm.tournament_id = $1 AND tm.round = $2 AND tm.finished_at IS NULL
+	`
+	rows, err := tx.Query(query, tournament.ID, tournament.CurrentRound)
+	if err != nil {
+		return models.TournamentUpdate{}, fmt.Errorf("failed to get tournament round: %v", err)
+	}
+	var pending []pendingPairing
+	for rows.Next() {
+		var pairing pendingPairing
+		if err := rows.Scan(&pairing.id, &pairing.player1ID, &pairing.player2ID, &pairing.matchID, &pairing.matchStatus, &pairing.winnerID); err != nil {
+			rows.Close()
+			return models.TournamentUpdate{}, fmt.Errorf("failed to scan tournament match: %v", err)
+		}
+		pending = append(pending, pairing)
+	}
+	rows.Close()
+
+	timeUp := tournament.RoundEndsAt.Valid && !now.Before(tournament.RoundEndsAt.Time)
+	for _, pairing := range pending {
+		if pairing.matchStatus.String != models.MatchFinished && !timeUp {
+			return models.TournamentUpdate{}, nil
+		}
+	}
+
+	players, err := getTournamentPlayers(tx, tournament.ID, "p.seed")
+	if err != nil {
+		return models.TournamentUpdate{}, err
+	}
+	byID := playersByID(players)
+
+	for _, pairing := range pending {
+		if pairing.matchID.Valid && pairing.matchStatus.String != models.MatchFinished {
+			query := `
+				UPDATE matches SET status = $1, finished_at = $2, updated_at = CURRENT_TIMESTAMP
+				WHERE id = $3 AND status = $4
+			`
+			if _, err := tx.Exec(query, models.MatchFinished, now, pairing.matchID, models.MatchStarted); err != nil {
+				return models.TournamentUpdate{}, fmt.Errorf("failed to end tournament match: %v", err)
+			}
+		}
+
+		winnerID := pairing.winnerID
+		if !winnerID.Valid && tournament.Format == models.TournamentSingleElimination {
+			winnerID = betterSeed(byID, pairing.player1ID, pairing.player2ID)
+		}
+		if _, err := tx.Exec("UPDATE tournament_matches SET winner_id = $1, finished_at = $2 WHERE id = $3", winnerID, now, pairing.id); err != nil {
+			return models.TournamentUpdate{}, fmt.Errorf("failed to settle tournament match: %v", err)
+		}
+
+		if tournament.Format == models.TournamentSwiss {
+			if winnerID.Valid {
+				if _, err := tx.Exec("UPDATE tournament_players SET points = points + 1 WHERE tournament_id = $1 AND user_id = $2", tournament.ID, winnerID); err != nil {
+					return models.TournamentUpdate{}, fmt.Errorf("failed to score tournament match: %v", err)
+				}
+			}
+			continue
+		}
+		for _, playerID := range []sql.NullInt64{pairing.player1ID, pairing.player2ID} {
+			if playerID.Valid && playerID != winnerID {
+				query := "UPDATE tournament_players SET eliminated_round = $1 WHERE tournament_id = $2 AND user_id = $3"
+				if _, err := tx.Exec(query, tournament.CurrentRound, tournament.ID, playerID); err != nil {
+					return models.TournamentUpdate{}, fmt.Errorf("failed to eliminate tournament player: %v", err)
+				}
+			}
+		}
+	}
+
+	var pairings []tournaments.Pairing
+	if tournament.CurrentRound < tournament.Rounds {
+		if tournament.Format == models.TournamentSwiss {
+			pairings, err = nextSwissRound(tx, tournament)
+		} else {
+			pairings, err = nextBracketRound(tx, tournament)
+		}
+		if err != nil {
+			return models.TournamentUpdate{}, err
+		}
+	}
+	if len(pairings) == 0 {
+		standings, err := finishTournament(tx, tournament, now)
+		if err != nil {
+			return models.TournamentUpdate{}, err
+		}
+		return models.TournamentUpdate{Standings: standings}, nil
+	}
+
+	matches, err := startRound(tx, tournament, tournament.CurrentRound+1, byID, pairings, now)
+	if err != nil {
+		return models.TournamentUpdate{}, err
+	}
+	return models.TournamentUpdate{Pairings: matches}, nil
+}
+
+// betterSeed returns whichever of the players is still registered and seeded
+// higher.
+func betterSeed(players map[uint]models.TournamentPlayer, player1ID, player2ID sql.NullInt64) sql.NullInt64 {
+	var best sql.NullInt64
+	var bestSeed int64
+	for _, playerID := range []sql.NullInt64{player1ID, player2ID} {
+		player, ok := players[uint(playerID.Int64)]
+		if !playerID.Valid || !ok {
+			continue
+		}
+		if !best.Valid || player.Seed.Int64 < bestSeed {
+			best, bestSeed = playerID, player.Seed.Int64
+		}
+	}
+	return best
+}
+
+func nextBracketRound(tx *sql.Tx, tournament *models.Tournament) ([]tournaments.Pairing, error) {
+	query := "SELECT position, winner_id FROM tournament_matches WHERE tournament_id = $1 AND round = $2 AND winner_id IS NOT NULL"
+	rows, err := tx.Query(query, tournament.ID, tournament.CurrentRound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournament winners: %v", err)
+	}
+	defer rows.Close()
+
+	winners := make(map[int32]uint)
+	for rows.Next() {
+		var position int32
+		var winnerID uint
+		if err := rows.Scan(&position, &winnerID); err != nil {
+			return nil, fmt.Errorf("failed to scan tournament winner: %v", err)
+		}
+		winners[position] = winnerID
+	}
+	if len(winners) < 2 {
+		return nil, nil
+	}
+	positions := int32(1) << uint(tournament.Rounds-tournament.CurrentRound)
+	return tournaments.NextBracketRound(winners, positions), nil
+}
+
+// nextSwissRound pairs the next Swiss round from the standings so far. Byes
+// are the pairings that never had a match; a pairing whose opponent deleted
+// their account still counts as played.
+func nextSwissRound(tx *sql.Tx, tournament *models.Tournament) ([]tournaments.Pairing, error) {
+	players, err := getTournamentPlayers(tx, tournament.ID, "p.seed")
+	if err != nil {
+		return nil, err
+	}
+	if len(players) < 2 {
+		return nil, nil
+	}
+
+	rows, err := tx.Query("SELECT player1_id, player2_id, match_id FROM tournament_matches WHERE tournament_id = $1", tournament.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournament history: %v", err)
+	}
+	defer rows.Close()
+
+	played := make(map[[2]uint]bool)
+	hadBye := make(map[uint]bool)
+	for rows.Next() {
+		var player1ID, player2ID, matchID sql.NullInt64
+		if err := rows.Scan(&player1ID, &player2ID, &matchID); err != nil {
+			return nil, fmt.Errorf("failed to scan tournament history: %v", err)
+		}
+		if !matchID.Valid && player1ID.Valid {
+			hadBye[uint(player1ID.Int64)] = true
+		}
+		if player1ID.Valid && player2ID.Valid {
+			played[tournaments.PlayedKey(uint(player1ID.Int64), uint(player2ID.Int64))] = true
+		}
+	}
+
+	standings := make([]tournaments.Standing, 0, len(players))
+	for _, player := range players {
+		standings = append(standings, tournaments.Standing{
+			UserID: player.UserID,
+			Points: player.Points,
+			Seed:   int32(player.Seed.Int64),
+			HadBye: hadBye[player.UserID],
+		})
+	}
+	return tournaments.PairSwiss(standings, played), nil
+}
+
+// finishTournament places every player and pays the prizes out of the pool.
+// Bracket players are placed by the round they went out in, Swiss players by
+// points, and ties go to the better seed.
+func finishTournament(tx *sql.Tx, tournament *models.Tournament, now time.Time) ([]models.TournamentPlayer, error) {
+	order := "eliminated_round DESC NULLS FIRST, seed"
+	if tournament.Format == models.TournamentSwiss {
+		order = "points DESC, seed"
+	}
+	query := `
+		UPDATE tournament_players p SET place = r.place
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY ` + order + `) AS place
+			FROM tournament_players
+			WHERE tournament_id = $1
+		) r
+		WHERE p.id = r.id
+	`
+	if _, err := tx.Exec(query, tournament.ID); err != nil {
+		return nil, fmt.Errorf("failed to place tournament players: %v", err)
+	}
+
+	query = `
+		UPDATE tournament_players p SET prize_gold = $2 * z.percent / 100
+		FROM tournament_prizes z
+		WHERE p.tournament_id = $1 AND z.tournament_id = $1 AND z.place = p.place
+	`
+	if _, err := tx.Exec(query, tournament.ID, tournament.PrizePool); err != nil {
+		return nil, fmt.Errorf("failed to award tournament prizes: %v", err)
+	}
+
+	standings, err := getTournamentPlayers(tx, tournament.ID, "p.place")
+	if err != nil {
+		return nil, err
+	}
+	reference := fmt.Sprintf("tournament:%d", tournament.ID)
+	for _, player := range standings {
+		if player.PrizeGold > 0 {
+			if err := creditWallet(tx, player.UserID, models.CurrencyGold, player.PrizeGold, models.ReasonTournamentPrize, reference); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tournament.Status = models.TournamentFinished
+	tournament.RoundEndsAt = pq.NullTime{}
+	tournament.FinishedAt = pq.NullTime{Time: now, Valid: true}
+	query = `
+		UPDATE tournaments SET status = $1, round_ends_at = NULL, finished_at = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`
+	if _, err := tx.Exec(query, tournament.Status, now, tournament.ID); err != nil {
+		return nil, fmt.Errorf("failed to finish tournament: %v", err)
+	}
+	return standings, nil
+}
+
+func (repo *TournamentRepository) GetBracket(id uint) ([]models.TournamentMatch, error) {
+	query := `
+		SELECT tm.round, tm.position, tm.player1_id, COALESCE(u1.username, ''), tm.player2_id, COALESCE(u2.username, ''),
+			tm.match_id, tm.winner_id, tm.finished_at
+		FROM tournament_matches tm
+		LEFT JOIN users u1 ON u1.id = tm.player1_id
+		LEFT JOIN users u2 ON u2.id = tm.player2_id
+		WHERE tm.tournament_id = $1
+		ORDER BY tm.round, tm.position
+	`
+	rows, err := repo.db.Query(query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournament bracket: %v", err)
+	}
+	defer rows.Close()
+
+	var matches []models.TournamentMatch
+	for rows.Next() {
+		var match models.TournamentMatch
+		err := rows.Scan(
+			&match.Round,
+			&match.Position,
+			&match.Player1ID,
+			&match.Player1Name,
+			&match.Player2ID,
+			&match.Player2Name,
+			&match.MatchID,
+			&match.WinnerID,
+			&match.FinishedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tournament match: %v", err)
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+// GetStandings lists the players in their current order: by final place once
+// the tournament is over, and by points and how far they got before that.
+func (repo *TournamentRepository) GetStandings(id uint) ([]models.TournamentPlayer, error) {
+	return getTournamentPlayers(repo.db, id, "p.place NULLS LAST, p.points DESC, p.eliminated_round DESC NULLS FIRST, p.seed NULLS LAST, p.created_at")
+}
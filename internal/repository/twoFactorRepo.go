@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+)
+
+type TwoFactorRepo interface {
+	GetTwoFactor(userID uint) (*models.TwoFactor, error)
+	SaveTwoFactorSecret(userID uint, secret string) error
+	EnableTwoFactor(userID uint) error
+	ConsumeTOTPStep(userID uint, step int64) (bool, error)
+	DeleteTwoFactor(userID uint) error
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error
+	UseRecoveryCode(userID uint, codeHash string) (bool, error)
+}
+
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db}
+}
+
+// GetTwoFactor returns helper.ErrTwoFactorNotFound when the user never
+// started enrolling, which means two-factor authentication is off.
+func (repo *TwoFactorRepository) GetTwoFactor(userID uint) (*models.TwoFactor, error) {
+	query := `
+		SELECT id, created_at, updated_at, deleted_at, user_id, secret, enabled, last_used_step
+		FROM user_totp WHERE user_id = $1
+	`
+	var tf models.TwoFactor
+	err := repo.db.QueryRow(query, userID).Scan(
+		&tf.ID,
+		&tf.CreatedAt,
+		&tf.UpdatedAt,
+		&tf.DeletedAt,
+		&tf.UserID,
+		&tf.Secret,
+		&tf.Enabled,
+		&tf.LastUsedStep,
+	)
+	if err == sql.ErrNoRows {
+		return nil, helper.ErrTwoFactorNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor: %v", err)
+	}
+	return &tf, nil
+}
+
+// SaveTwoFactorSecret stores a new pending secret. Confirmed secrets are left
+// untouched so an enrolled account cannot be silently re-keyed.
+func (repo *TwoFactorRepository) SaveTwoFactorSecret(userID uint, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, enabled, last_used_step)
+		VALUES ($1, $2, FALSE, 0)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE user_totp.enabled = FALSE
+	`
+	result, err := repo.db.Exec(query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save two-factor secret: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save two-factor secret: %v", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("two-factor authentication is already enabled")
+	}
+	return nil
+}
+
+func (repo *TwoFactorRepository) EnableTwoFactor(userID uint) error {
+	query := "UPDATE user_totp SET enabled = TRUE, updated_at = CURRENT_TIMESTAMP WHERE user_id = $1"
+	_, err := repo.db.Exec(query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor: %v", err)
+	}
+	return nil
+}
+
+// ConsumeTOTPStep records the step of an accepted code and reports false if
+// that step (or a later one) was already used.
+func (repo *TwoFactorRepository) ConsumeTOTPStep(userID uint, step int64) (bool, error) {
+	query := `
+		UPDATE user_totp SET last_used_step = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $2 AND last_used_step < $1
+	`
+	result, err := repo.db.Exec(query, step, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume TOTP step: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume TOTP step: %v", err)
+	}
+	return affected > 0, nil
+}
+
+func (repo *TwoFactorRepository) DeleteTwoFactor(userID uint) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %v", err)
+	}
+	if _, err := tx.Exec("DELETE FROM user_totp WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete two-factor: %v", err)
+	}
+	return tx.Commit()
+}
+
+func (repo *TwoFactorRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %v", err)
+	}
+	for _, hash := range codeHashes {
+		_, err := tx.Exec("INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash)
+		if err != nil {
+			return fmt.Errorf("failed to save recovery code: %v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (repo *TwoFactorRepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	result, err := repo.db.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %v", err)
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"fmt"
+)
+
+type WalletRepo interface {
+	GetWallet(userID uint) (models.Wallet, error)
+	GetTransactions(userID, before uint, limit int) ([]models.WalletTransaction, error)
+	Grant(userID uint, currency string, amount int64, reason, reference string) (models.Wallet, error)
+	ExchangeGems(userID uint, gems, gold int64) (models.Wallet, error)
+}
+
+type WalletRepository struct {
+	db *sql.DB
+}
+
+func NewWalletRepository(db *sql.DB) *WalletRepository {
+	return &WalletRepository{db}
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// walletBalanceQuery reads one balance of the user in the surrounding query's
+// users row.
+const walletBalanceQuery = "COALESCE((SELECT balance FROM wallets WHERE user_id = users.id AND currency = '%s'), 0)"
+
+// creditWallet adds amount to the balance and writes it to the ledger. All
+// currency a player receives goes through it.
+func creditWallet(tx execer, userID uint, currency string, amount int64, reason, reference string) error {
+	query := `
+		INSERT INTO wallets (user_id, currency, balance) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, currency) DO UPDATE
+		SET balance = wallets.balance + EXCLUDED.balance, updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := tx.Exec(query, userID, currency, amount); err != nil {
+		return fmt.Errorf("failed to credit wallet: %v", err)
+	}
+	if amount == 0 {
+		return nil
+	}
+	return recordTransaction(tx, userID, currency, amount, reason, reference)
+}
+
+// debitWallet takes amount from the balance as part of tx. Every purchase goes
+// through it; the check and the debit are one statement, so two purchases at
+// once can't overdraw a balance. Each currency has its own error so callers
+// can tell players which one they are short of.
+func debitWallet(tx execer, userID uint, currency string, amount int64, reason, reference string) error {
+	query := `
+		UPDATE wallets SET balance = balance - $1, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $2 AND currency = $3 AND balance >= $1
+	`
+	result, err := tx.Exec(query, amount, userID, currency)
+	if err != nil {
+		return fmt.Errorf("failed to debit wallet: %v", err)
+	}
+	if debited, _ := result.RowsAffected(); debited == 0 {
+		if currency == models.CurrencyGems {
+			return helper.ErrInsufficientGems
+		}
+		return helper.ErrInsufficientBalance
+	}
+	return recordTransaction(tx, userID, currency, -amount, reason, reference)
+}
+
+func recordTransaction(tx execer, userID uint, currency string, amount int64, reason, reference string) error {
+	query := "INSERT INTO wallet_transactions (user_id, currency, amount, reason, reference) VALUES ($1, $2, $3, $4, $5)"
+	if _, err := tx.Exec(query, userID, currency, amount, reason, reference); err != nil {
+		return fmt.Errorf("failed to record wallet transaction: %v", err)
+	}
+	return nil
+}
+
+func (repo *WalletRepository) GetWallet(userID uint) (models.Wallet, error) {
+	wallet := models.Wallet{UserID: userID}
+	rows, err := repo.db.Query("SELECT currency, balance FROM wallets WHERE user_id = $1", userID)
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to get wallet: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var currency string
+		var balance int64
+		if err := rows.Scan(&currency, &balance); err != nil {
+			return models.Wallet{}, fmt.Errorf("failed to scan wallet: %v", err)
+		}
+		switch currency {
+		case models.CurrencyGold:
+			wallet.Gold = balance
+		case models.CurrencyGems:
+			wallet.Gems = balance
+		}
+	}
+
+	return wallet, nil
+}
+
+// GetTransactions pages through the ledger newest first, starting below the
+// before cursor.
+func (repo *WalletRepository) GetTransactions(userID, before uint, limit int) ([]models.WalletTransaction, error) {
+	query := `
+		SELECT id, created_at, user_id, currency, amount, reason, reference
+		FROM wallet_transactions
+		WHERE user_id = $1 AND id < $2
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	rows, err := repo.db.Query(query, userID, cursorID(before), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet transactions: %v", err)
+	}
+	defer rows.Close()
+
+	var transactions []models.WalletTransaction
+	for rows.Next() {
+		var transaction models.WalletTransaction
+		err := rows.Scan(
+			&transaction.ID,
+			&transaction.CreatedAt,
+			&transaction.UserID,
+			&transaction.Currency,
+			&transaction.Amount,
+			&transaction.Reason,
+			&transaction.Reference,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wallet transaction: %v", err)
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	return transactions, nil
+}
+
+func (repo *WalletRepository) Grant(userID uint, currency string, amount int64, reason, reference string) (models.Wallet, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := creditWallet(tx, userID, currency, amount, reason, reference); err != nil {
+		return models.Wallet{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to commit grant: %v", err)
+	}
+	return repo.GetWallet(userID)
+}
+
+// ExchangeGems turns gems into gold. It returns helper.ErrInsufficientGems
+// when the player doesn't have enough gems.
+func (repo *WalletRepository) ExchangeGems(userID uint, gems, gold int64) (models.Wallet, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := debitWallet(tx, userID, models.CurrencyGems, gems, models.ReasonExchange, ""); err != nil {
+		return models.Wallet{}, err
+	}
+	if err := creditWallet(tx, userID, models.CurrencyGold, gold, models.ReasonExchange, ""); err != nil {
+		return models.Wallet{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to commit exchange: %v", err)
+	}
+	return repo.GetWallet(userID)
+}
@@ -0,0 +1,73 @@
+package dto
+
+import "auth/internal/rest/models"
+
+// CatalogHero is a hero as the catalog lists it to a player. UnlockTrophies
+// is set for locked heroes only.
+type CatalogHero struct {
+	Hero
+	Locked         bool  `json:"Locked"`
+	UnlockTrophies int32 `json:"UnlockTrophies,omitempty"`
+}
+
+type CatalogSpell struct {
+	Spell
+	Locked         bool  `json:"Locked"`
+	UnlockTrophies int32 `json:"UnlockTrophies,omitempty"`
+}
+
+type Arena struct {
+	ID          uint        `json:"id"`
+	Name        string      `json:"name"`
+	MinTrophies int32       `json:"minTrophies"`
+	Cards       []ArenaCard `json:"cards"`
+}
+
+type ArenaCard struct {
+	HeroID  *uint  `json:"heroId,omitempty"`
+	SpellID *uint  `json:"spellId,omitempty"`
+	Name    string `json:"name"`
+}
+
+func NewCatalogHeros(heros []models.Hero, locks map[uint]int32) []CatalogHero {
+	result := make([]CatalogHero, 0, len(heros))
+	for _, hero := range heros {
+		trophies, locked := locks[hero.ID]
+		result = append(result, CatalogHero{Hero: NewHero(hero), Locked: locked, UnlockTrophies: trophies})
+	}
+	return result
+}
+
+func NewCatalogSpells(spells []models.Spell, locks map[uint]int32) []CatalogSpell {
+	result := make([]CatalogSpell, 0, len(spells))
+	for _, spell := range spells {
+		trophies, locked := locks[spell.ID]
+		result = append(result, CatalogSpell{Spell: NewSpell(spell), Locked: locked, UnlockTrophies: trophies})
+	}
+	return result
+}
+
+func NewArena(arena models.Arena) Arena {
+	result := Arena{
+		ID:          arena.ID,
+		Name:        arena.Name,
+		MinTrophies: arena.MinTrophies,
+		Cards:       make([]ArenaCard, 0, len(arena.Cards)),
+	}
+	for _, card := range arena.Cards {
+		result.Cards = append(result.Cards, ArenaCard{
+			HeroID:  optionalID(card.HeroID),
+			SpellID: optionalID(card.SpellID),
+			Name:    card.Name,
+		})
+	}
+	return result
+}
+
+func NewArenas(arenas []models.Arena) []Arena {
+	result := make([]Arena, 0, len(arenas))
+	for _, arena := range arenas {
+		result = append(result, NewArena(arena))
+	}
+	return result
+}
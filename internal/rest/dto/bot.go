@@ -0,0 +1,15 @@
+package dto
+
+import "auth/internal/bots"
+
+// BotPlay is the card a bot deploys, and where.
+type BotPlay struct {
+	Kind     string  `json:"kind"`
+	CardID   uint    `json:"cardId"`
+	Lane     int     `json:"lane"`
+	Position float64 `json:"position"`
+}
+
+func NewBotPlay(play bots.Play) BotPlay {
+	return BotPlay{Kind: play.Kind, CardID: play.CardID, Lane: play.Lane, Position: play.Position}
+}
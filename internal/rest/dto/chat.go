@@ -0,0 +1,164 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"database/sql"
+	"time"
+)
+
+type ChatMessage struct {
+	ID          uint      `json:"id"`
+	Channel     string    `json:"channel"`
+	ClanID      *uint     `json:"clanId,omitempty"`
+	Sender      PlayerRef `json:"sender"`
+	RecipientID *uint     `json:"recipientId,omitempty"`
+	Body        string    `json:"body"`
+	SentAt      time.Time `json:"sentAt"`
+	Hidden      bool      `json:"hidden,omitempty"`
+}
+
+// ChatPage is one page of history. NextCursor is passed back as ?before= to
+// load older messages and is omitted on the last page.
+type ChatPage struct {
+	Messages   []ChatMessage `json:"messages"`
+	NextCursor *uint         `json:"nextCursor,omitempty"`
+}
+
+// ChatEvent is what the stream endpoint sends for each published event.
+type ChatEvent struct {
+	Type      string       `json:"type"`
+	Message   *ChatMessage `json:"message,omitempty"`
+	MessageID uint         `json:"messageId,omitempty"`
+}
+
+type ChatReport struct {
+	ID         uint        `json:"id"`
+	Reporter   PlayerRef   `json:"reporter"`
+	Reason     string      `json:"reason"`
+	Status     string      `json:"status"`
+	ResolvedBy *uint       `json:"resolvedBy,omitempty"`
+	ResolvedAt *time.Time  `json:"resolvedAt,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	Message    ChatMessage `json:"message"`
+}
+
+type ChatSanction struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"userId"`
+	Kind      string     `json:"kind"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	CreatedBy uint       `json:"createdBy,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type ModerationAction struct {
+	ID           uint       `json:"id"`
+	Moderator    *PlayerRef `json:"moderator,omitempty"`
+	Action       string     `json:"action"`
+	TargetUserID *uint      `json:"targetUserId,omitempty"`
+	MessageID    *uint      `json:"messageId,omitempty"`
+	ReportID     *uint      `json:"reportId,omitempty"`
+	SanctionID   *uint      `json:"sanctionId,omitempty"`
+	Details      string     `json:"details,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+func optionalID(id sql.NullInt64) *uint {
+	if !id.Valid {
+		return nil
+	}
+	value := uint(id.Int64)
+	return &value
+}
+
+func NewChatMessage(message models.ChatMessage) ChatMessage {
+	return ChatMessage{
+		ID:          message.ID,
+		Channel:     message.Channel,
+		ClanID:      optionalID(message.ClanID),
+		Sender:      PlayerRef{UserID: message.SenderID, Username: message.SenderName},
+		RecipientID: optionalID(message.RecipientID),
+		Body:        message.Body,
+		SentAt:      message.CreatedAt,
+		Hidden:      message.HiddenAt.Valid,
+	}
+}
+
+// NewChatPage expects the messages newest first, as the repository returns
+// them, and a full page to mean there may be more.
+func NewChatPage(messages []models.ChatMessage, limit int) ChatPage {
+	page := ChatPage{Messages: make([]ChatMessage, 0, len(messages))}
+	for _, message := range messages {
+		page.Messages = append(page.Messages, NewChatMessage(message))
+	}
+	if len(messages) > 0 && len(messages) == limit {
+		cursor := messages[len(messages)-1].ID
+		page.NextCursor = &cursor
+	}
+	return page
+}
+
+func NewChatReport(report models.ChatReport) ChatReport {
+	result := ChatReport{
+		ID:         report.ID,
+		Reporter:   PlayerRef{UserID: report.ReporterID, Username: report.ReporterName},
+		Reason:     report.Reason,
+		Status:     report.Status,
+		ResolvedBy: optionalID(report.ResolvedBy),
+		CreatedAt:  report.CreatedAt,
+		Message:    NewChatMessage(report.Message),
+	}
+	if report.ResolvedAt.Valid {
+		result.ResolvedAt = &report.ResolvedAt.Time
+	}
+	return result
+}
+
+func NewChatReports(reports []models.ChatReport) []ChatReport {
+	result := make([]ChatReport, 0, len(reports))
+	for _, report := range reports {
+		result = append(result, NewChatReport(report))
+	}
+	return result
+}
+
+func NewChatSanction(sanction models.ChatSanction) ChatSanction {
+	result := ChatSanction{
+		ID:        sanction.ID,
+		UserID:    sanction.UserID,
+		Kind:      sanction.Kind,
+		Reason:    sanction.Reason,
+		CreatedBy: sanction.CreatedBy,
+		CreatedAt: sanction.CreatedAt,
+	}
+	if sanction.ExpiresAt.Valid {
+		result.ExpiresAt = &sanction.ExpiresAt.Time
+	}
+	if sanction.RevokedAt.Valid {
+		result.RevokedAt = &sanction.RevokedAt.Time
+	}
+	return result
+}
+
+func NewModerationActions(actions []models.ModerationAction) []ModerationAction {
+	result := make([]ModerationAction, 0, len(actions))
+	for _, action := range actions {
+		entry := ModerationAction{
+			ID:           action.ID,
+			Action:       action.Action,
+			TargetUserID: optionalID(action.TargetUserID),
+			MessageID:    optionalID(action.MessageID),
+			ReportID:     optionalID(action.ReportID),
+			SanctionID:   optionalID(action.SanctionID),
+			Details:      action.Details,
+			CreatedAt:    action.CreatedAt,
+		}
+		if action.ModeratorID.Valid {
+			entry.Moderator = &PlayerRef{UserID: uint(action.ModeratorID.Int64), Username: action.ModeratorName}
+		}
+		result = append(result, entry)
+	}
+	return result
+}
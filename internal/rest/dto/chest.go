@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+// Chest is a chest the player holds. SpeedUpCost is what opening it right
+// now costs in gems.
+type Chest struct {
+	ID          uint       `json:"id"`
+	Kind        string     `json:"kind"`
+	UnlocksAt   *time.Time `json:"unlocksAt,omitempty"`
+	SpeedUpCost int64      `json:"speedUpCost"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type ChestReward struct {
+	ChestID uint        `json:"chestId"`
+	Kind    string      `json:"kind"`
+	Gold    int64       `json:"gold"`
+	Cards   []ChestCard `json:"cards"`
+}
+
+type ChestCard struct {
+	HeroID  *uint  `json:"heroId,omitempty"`
+	SpellID *uint  `json:"spellId,omitempty"`
+	Name    string `json:"name"`
+}
+
+func NewChest(chest models.Chest, now time.Time, gemsPerHour int64) Chest {
+	result := Chest{
+		ID:          chest.ID,
+		Kind:        chest.Kind,
+		SpeedUpCost: chest.SpeedUpCost(now, gemsPerHour),
+		CreatedAt:   chest.CreatedAt,
+	}
+	if chest.UnlocksAt.Valid {
+		unlocksAt := chest.UnlocksAt.Time
+		result.UnlocksAt = &unlocksAt
+	}
+	return result
+}
+
+func NewChests(chests []models.Chest, now time.Time, gemsPerHour int64) []Chest {
+	result := make([]Chest, 0, len(chests))
+	for _, chest := range chests {
+		result = append(result, NewChest(chest, now, gemsPerHour))
+	}
+	return result
+}
+
+func NewChestReward(reward models.ChestReward) ChestReward {
+	cards := make([]ChestCard, 0, len(reward.Cards))
+	for _, card := range reward.Cards {
+		cards = append(cards, ChestCard{
+			HeroID:  optionalID(card.HeroID),
+			SpellID: optionalID(card.SpellID),
+			Name:    card.Name,
+		})
+	}
+	return ChestReward{ChestID: reward.ChestID, Kind: reward.Kind, Gold: reward.Gold, Cards: cards}
+}
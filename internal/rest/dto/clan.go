@@ -0,0 +1,125 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type Clan struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Tag         string `json:"tag"`
+	Description string `json:"description"`
+	Membership  string `json:"membership"`
+	MinTrophies int32  `json:"minTrophies"`
+	MemberCount int    `json:"memberCount"`
+	Trophies    int64  `json:"trophies"`
+}
+
+type ClanDetails struct {
+	Clan
+	Members []ClanMember `json:"members"`
+}
+
+type ClanMember struct {
+	UserID    uint      `json:"userId"`
+	Username  string    `json:"username"`
+	Trophies  int32     `json:"trophies"`
+	Role      string    `json:"role"`
+	Donations int32     `json:"donations"`
+	JoinedAt  time.Time `json:"joinedAt"`
+}
+
+type ClanJoinRequest struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"userId"`
+	Username  string    `json:"username"`
+	Trophies  int32     `json:"trophies"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CardRequest struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"userId"`
+	Username  string    `json:"username"`
+	Kind      string    `json:"kind"`
+	CardID    uint      `json:"cardId"`
+	CardName  string    `json:"cardName"`
+	Requested int32     `json:"requested"`
+	Donated   int32     `json:"donated"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewClan(clan models.Clan) Clan {
+	return Clan{
+		ID:          clan.ID,
+		Name:        clan.Name,
+		Tag:         clan.Tag,
+		Description: clan.Description,
+		Membership:  clan.Membership,
+		MinTrophies: clan.MinTrophies,
+		MemberCount: clan.MemberCount,
+		Trophies:    clan.Trophies,
+	}
+}
+
+func NewClans(clans []models.Clan) []Clan {
+	result := make([]Clan, 0, len(clans))
+	for _, clan := range clans {
+		result = append(result, NewClan(clan))
+	}
+	return result
+}
+
+func NewClanDetails(clan models.Clan, members []models.ClanMember) ClanDetails {
+	details := ClanDetails{Clan: NewClan(clan), Members: make([]ClanMember, 0, len(members))}
+	for _, member := range members {
+		details.Members = append(details.Members, ClanMember{
+			UserID:    member.UserID,
+			Username:  member.Username,
+			Trophies:  member.Awards,
+			Role:      member.Role,
+			Donations: member.Donations,
+			JoinedAt:  member.JoinedAt,
+		})
+	}
+	return details
+}
+
+func NewClanJoinRequests(requests []models.ClanJoinRequest) []ClanJoinRequest {
+	result := make([]ClanJoinRequest, 0, len(requests))
+	for _, request := range requests {
+		result = append(result, ClanJoinRequest{
+			ID:        request.ID,
+			UserID:    request.UserID,
+			Username:  request.Username,
+			Trophies:  request.Awards,
+			CreatedAt: request.CreatedAt,
+		})
+	}
+	return result
+}
+
+func NewCardRequest(request models.CardRequest) CardRequest {
+	return CardRequest{
+		ID:        request.ID,
+		UserID:    request.UserID,
+		Username:  request.Username,
+		Kind:      request.CardKind,
+		CardID:    request.CardID,
+		CardName:  request.CardName,
+		Requested: request.Requested,
+		Donated:   request.Donated,
+		Status:    request.Status,
+		CreatedAt: request.CreatedAt,
+	}
+}
+
+func NewCardRequests(requests []models.CardRequest) []CardRequest {
+	result := make([]CardRequest, 0, len(requests))
+	for _, request := range requests {
+		result = append(result, NewCardRequest(request))
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type Draft struct {
+	ID           uint        `json:"id"`
+	Player1      *PlayerRef  `json:"player1,omitempty"`
+	Player2      *PlayerRef  `json:"player2,omitempty"`
+	Status       string      `json:"status"`
+	DeckSize     int32       `json:"deckSize"`
+	PickNumber   int32       `json:"pickNumber"`
+	PickingID    *uint       `json:"pickingId,omitempty"`
+	Offer        []DraftCard `json:"offer"`
+	PickDeadline *time.Time  `json:"pickDeadline,omitempty"`
+	MatchID      *uint       `json:"matchId,omitempty"`
+	Picks        []DraftPick `json:"picks"`
+}
+
+// DraftCard is an offered card with everything a player needs to choose.
+type DraftCard struct {
+	Kind  string `json:"kind"`
+	Hero  *Hero  `json:"hero,omitempty"`
+	Spell *Spell `json:"spell,omitempty"`
+}
+
+type DraftPick struct {
+	PickNumber int32       `json:"pickNumber"`
+	UserID     *uint       `json:"userId,omitempty"`
+	Card       CardSummary `json:"card"`
+	Auto       bool        `json:"auto"`
+}
+
+func NewDraft(draft models.Draft) Draft {
+	result := Draft{
+		ID:         draft.ID,
+		Status:     draft.Status,
+		DeckSize:   draft.DeckSize,
+		PickNumber: draft.PickNumber,
+		Offer:      make([]DraftCard, 0, len(draft.Offer)),
+		MatchID:    optionalID(draft.MatchID),
+		Picks:      make([]DraftPick, 0, len(draft.Picks)),
+	}
+	if draft.Player1ID.Valid {
+		result.Player1 = &PlayerRef{UserID: uint(draft.Player1ID.Int64), Username: draft.Player1Name}
+	}
+	if draft.Player2ID.Valid {
+		result.Player2 = &PlayerRef{UserID: uint(draft.Player2ID.Int64), Username: draft.Player2Name}
+	}
+	if draft.Status == models.DraftDrafting {
+		result.PickingID = optionalID(draft.Picking())
+		result.PickDeadline = &draft.PickDeadline
+		for _, card := range draft.Offer {
+			offered := DraftCard{Kind: card.Kind}
+			if card.Hero != nil {
+				hero := NewHero(*card.Hero)
+				offered.Hero = &hero
+			}
+			if card.Spell != nil {
+				spell := NewSpell(*card.Spell)
+				offered.Spell = &spell
+			}
+			result.Offer = append(result.Offer, offered)
+		}
+	}
+	for _, pick := range draft.Picks {
+		var card CardSummary
+		if pick.Card.Hero != nil {
+			card = HeroCard(*pick.Card.Hero)
+		} else if pick.Card.Spell != nil {
+			card = SpellCard(*pick.Card.Spell)
+		}
+		result.Picks = append(result.Picks, DraftPick{
+			PickNumber: pick.PickNumber,
+			UserID:     optionalID(pick.UserID),
+			Card:       card,
+			Auto:       pick.Auto,
+		})
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"encoding/json"
+	"github.com/lib/pq"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// keys marshals v and returns the keys of the JSON object, sorted.
+func keys(t *testing.T, v interface{}) []string {
+	t.Helper()
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		t.Fatal(err)
+	}
+	result := make([]string, 0, len(object))
+	for key := range object {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func sorted(keys ...string) []string {
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	now     = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	deleted = pq.NullTime{Time: now, Valid: true}
+	hero    = models.Hero{ID: 1, CreatedAt: now, UpdatedAt: now, DeletedAt: deleted, Name: "Knight", Rarity: "COMMON", Price: 100}
+	spell   = models.Spell{ID: 2, CreatedAt: now, UpdatedAt: now, DeletedAt: deleted, Name: "Fireball", Price: 200}
+)
+
+var heroKeys = sorted("ID", "CreatedAt", "Name", "Description", "Rarity", "DamageType", "Effect",
+	"Hitpoint", "Damage", "CostElixir", "DamageTower", "Speed", "Price")
+
+var spellKeys = sorted("ID", "CreatedAt", "Name", "Description", "Area", "DamageType", "Damage",
+	"Duration", "Effect", "Price")
+
+// The API answers with DTOs rather than models so that internal fields, such
+// as password hashes and soft-delete times, never leak. These tests pin the
+// exact keys each DTO exposes; adding one should be a deliberate change.
+func TestContracts(t *testing.T) {
+	user := &models.User{
+		ID:        1,
+		CreatedAt: now,
+		UpdatedAt: now,
+		DeletedAt: deleted,
+		Username:  "alice",
+		Email:     "alice@example.com",
+		Password:  "$2a$14$hash",
+		Bank:      100,
+		Gems:      5,
+		Awards:    1200,
+		UserType:  "USER",
+	}
+	deck := models.Deck{ID: 3, CreatedAt: now, UpdatedAt: now, DeletedAt: deleted, Name: "Rush", UserID: 1,
+		Heroes: []models.Hero{hero}, Spells: []models.Spell{spell}}
+
+	tests := []struct {
+		name string
+		dto  interface{}
+		want []string
+	}{
+		{"user", NewUser(user), sorted("ID", "CreatedAt", "username", "email", "bank", "gems", "awards", "userType")},
+		{"hero", NewHero(hero), heroKeys},
+		{"spell", NewSpell(spell), spellKeys},
+		{"identity", NewIdentity(models.Identity{ID: 4, CreatedAt: now, UpdatedAt: now, DeletedAt: deleted, UserID: 1, Provider: "google", Subject: "sub-1", Email: "alice@example.com"}),
+			sorted("id", "provider", "email", "linkedAt")},
+		{"deck", NewDeck(deck), sorted("ID", "CreatedAt", "UpdatedAt", "Name", "Description", "heroes", "spells", "UserID")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keys(t, tt.dto); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Cards inside a deck follow the same contracts.
+	encoded := NewDeck(deck)
+	if got := keys(t, encoded.Heroes[0]); !reflect.DeepEqual(got, heroKeys) {
+		t.Errorf("deck hero keys = %v, want %v", got, heroKeys)
+	}
+	if got := keys(t, encoded.Spells[0]); !reflect.DeepEqual(got, spellKeys) {
+		t.Errorf("deck spell keys = %v, want %v", got, spellKeys)
+	}
+}
@@ -0,0 +1,80 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type Friend struct {
+	UserID   uint      `json:"userId"`
+	Username string    `json:"username"`
+	Trophies int32     `json:"trophies"`
+	Online   bool      `json:"online"`
+	Since    time.Time `json:"since"`
+}
+
+// FriendRequest is shown from the point of view of the signed-in player:
+// Direction is "incoming" or "outgoing" and the user fields name the other side.
+type FriendRequest struct {
+	ID        uint      `json:"id"`
+	Direction string    `json:"direction"`
+	UserID    uint      `json:"userId"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type BlockedUser struct {
+	UserID    uint      `json:"userId"`
+	Username  string    `json:"username"`
+	BlockedAt time.Time `json:"blockedAt"`
+}
+
+type PlayerSearchResult struct {
+	UserID   uint   `json:"userId"`
+	Username string `json:"username"`
+	Trophies int32  `json:"trophies"`
+}
+
+func NewFriends(friends []models.Friend, online map[uint]bool) []Friend {
+	result := make([]Friend, 0, len(friends))
+	for _, friend := range friends {
+		result = append(result, Friend{
+			UserID:   friend.UserID,
+			Username: friend.Username,
+			Trophies: friend.Awards,
+			Online:   online[friend.UserID],
+			Since:    friend.Since,
+		})
+	}
+	return result
+}
+
+func NewFriendRequests(requests []models.FriendRequest, userID uint) []FriendRequest {
+	result := make([]FriendRequest, 0, len(requests))
+	for _, request := range requests {
+		item := FriendRequest{ID: request.ID, CreatedAt: request.CreatedAt}
+		if request.AddresseeID == userID {
+			item.Direction, item.UserID, item.Username = "incoming", request.RequesterID, request.RequesterName
+		} else {
+			item.Direction, item.UserID, item.Username = "outgoing", request.AddresseeID, request.AddresseeName
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func NewBlockedUsers(blocked []models.BlockedUser) []BlockedUser {
+	result := make([]BlockedUser, 0, len(blocked))
+	for _, user := range blocked {
+		result = append(result, BlockedUser{UserID: user.UserID, Username: user.Username, BlockedAt: user.BlockedAt})
+	}
+	return result
+}
+
+func NewPlayerSearchResults(players []models.PlayerSearchResult) []PlayerSearchResult {
+	result := make([]PlayerSearchResult, 0, len(players))
+	for _, player := range players {
+		result = append(result, PlayerSearchResult{UserID: player.UserID, Username: player.Username, Trophies: player.Awards})
+	}
+	return result
+}
@@ -0,0 +1,149 @@
+package dto
+
+import (
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"time"
+)
+
+// The card and deck shapes keep the field names the game client was built
+// against when it received the DB models directly.
+
+type Hero struct {
+	ID          uint      `json:"ID"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	Name        string    `json:"Name"`
+	Description string    `json:"Description"`
+	Rarity      string    `json:"Rarity"`
+	DamageType  string    `json:"DamageType"`
+	Effect      string    `json:"Effect"`
+	Hitpoint    int32     `json:"Hitpoint"`
+	Damage      int32     `json:"Damage"`
+	CostElixir  int32     `json:"CostElixir"`
+	DamageTower int32     `json:"DamageTower"`
+	Speed       int32     `json:"Speed"`
+	Price       int64     `json:"Price"`
+}
+
+type Spell struct {
+	ID          uint      `json:"ID"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	Name        string    `json:"Name"`
+	Description string    `json:"Description"`
+	Area        int32     `json:"Area"`
+	DamageType  string    `json:"DamageType"`
+	Damage      int32     `json:"Damage"`
+	Duration    int64     `json:"Duration"`
+	Effect      string    `json:"Effect"`
+	Price       int64     `json:"Price"`
+}
+
+type Deck struct {
+	ID          uint      `json:"ID"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	UpdatedAt   time.Time `json:"UpdatedAt"`
+	Name        string    `json:"Name"`
+	Description string    `json:"Description"`
+	Heroes      []Hero    `json:"heroes"`
+	Spells      []Spell   `json:"spells"`
+	UserID      uint      `json:"UserID"`
+}
+
+func NewHero(hero models.Hero) Hero {
+	return Hero{
+		ID:          hero.ID,
+		CreatedAt:   hero.CreatedAt,
+		Name:        hero.Name,
+		Description: hero.Description,
+		Rarity:      hero.Rarity,
+		DamageType:  hero.DamageType,
+		Effect:      hero.Effect,
+		Hitpoint:    hero.Hitpoint,
+		Damage:      hero.Damage,
+		CostElixir:  hero.CostElixir,
+		DamageTower: hero.DamageTower,
+		Speed:       hero.Speed,
+		Price:       hero.Price,
+	}
+}
+
+func NewHeros(heros []models.Hero) []Hero {
+	result := make([]Hero, 0, len(heros))
+	for _, hero := range heros {
+		result = append(result, NewHero(hero))
+	}
+	return result
+}
+
+func NewSpell(spell models.Spell) Spell {
+	return Spell{
+		ID:          spell.ID,
+		CreatedAt:   spell.CreatedAt,
+		Name:        spell.Name,
+		Description: spell.Description,
+		Area:        spell.Area,
+		DamageType:  spell.DamageType,
+		Damage:      spell.Damage,
+		Duration:    spell.Duration,
+		Effect:      spell.Effect,
+		Price:       spell.Price,
+	}
+}
+
+func NewSpells(spells []models.Spell) []Spell {
+	result := make([]Spell, 0, len(spells))
+	for _, spell := range spells {
+		result = append(result, NewSpell(spell))
+	}
+	return result
+}
+
+func NewDeck(deck models.Deck) Deck {
+	return Deck{
+		ID:          deck.ID,
+		CreatedAt:   deck.CreatedAt,
+		UpdatedAt:   deck.UpdatedAt,
+		Name:        deck.Name,
+		Description: deck.Description,
+		Heroes:      NewHeros(deck.Heroes),
+		Spells:      NewSpells(deck.Spells),
+		UserID:      deck.UserID,
+	}
+}
+
+func NewDecks(decks []models.Deck) []Deck {
+	result := make([]Deck, 0, len(decks))
+	for _, deck := range decks {
+		result = append(result, NewDeck(deck))
+	}
+	return result
+}
+
+func HeroFromForm(form forms.CreateHeroForm) models.Hero {
+	return models.Hero{
+		Name:        form.Name,
+		Description: form.Description,
+		Rarity:      form.Rarity,
+		DamageType:  form.DamageType,
+		Effect:      form.Effect,
+		Hitpoint:    form.Hitpoint,
+		Damage:      form.Damage,
+		CostElixir:  form.CostElixir,
+		DamageTower: form.DamageTower,
+		Speed:       form.Speed,
+		Price:       form.Price,
+	}
+}
+
+func SpellFromForm(form forms.CreateSpellForm) models.Spell {
+	return models.Spell{
+		Name:        form.Name,
+		Description: form.Description,
+		Area:        form.Area,
+		DamageType:  form.DamageType,
+		Damage:      form.Damage,
+		Duration:    form.Duration,
+		Effect:      form.Effect,
+		Price:       form.Price,
+	}
+}
@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+// Identity is an external login linked to the account. The provider's
+// subject and the owning user id stay on the server.
+type Identity struct {
+	ID       uint      `json:"id"`
+	Provider string    `json:"provider"`
+	Email    string    `json:"email"`
+	LinkedAt time.Time `json:"linkedAt"`
+}
+
+func NewIdentity(identity models.Identity) Identity {
+	return Identity{
+		ID:       identity.ID,
+		Provider: identity.Provider,
+		Email:    identity.Email,
+		LinkedAt: identity.CreatedAt,
+	}
+}
+
+func NewIdentities(identities []models.Identity) []Identity {
+	result := make([]Identity, 0, len(identities))
+	for _, identity := range identities {
+		result = append(result, NewIdentity(identity))
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type PlayerRef struct {
+	UserID   uint   `json:"userId"`
+	Username string `json:"username"`
+}
+
+type Challenge struct {
+	ID         uint       `json:"id"`
+	Challenger PlayerRef  `json:"challenger"`
+	Opponent   *PlayerRef `json:"opponent,omitempty"`
+	JoinCode   string     `json:"joinCode"`
+	Status     string     `json:"status"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	MatchID    *uint      `json:"matchId,omitempty"`
+}
+
+type Match struct {
+	ID          uint          `json:"id"`
+	Mode        string        `json:"mode"`
+	Ranked      bool          `json:"ranked"`
+	Status      string        `json:"status"`
+	WinnerID    *uint         `json:"winnerId,omitempty"`
+	WinningTeam *uint         `json:"winningTeam,omitempty"`
+	StartedAt   time.Time     `json:"startedAt"`
+	FinishedAt  *time.Time    `json:"finishedAt,omitempty"`
+	Players     []MatchPlayer `json:"players"`
+}
+
+// MatchPlayer is one player of a match. Team is only set in modes with
+// teams, and Bot, the bot's difficulty, only for bots, which have no userId.
+type MatchPlayer struct {
+	UserID   uint         `json:"userId,omitempty"`
+	Username string       `json:"username"`
+	Team     int32        `json:"team,omitempty"`
+	Bot      string       `json:"bot,omitempty"`
+	Deck     *DeckSummary `json:"deck"`
+}
+
+func NewChallenge(challenge models.Challenge) Challenge {
+	result := Challenge{
+		ID:         challenge.ID,
+		Challenger: PlayerRef{UserID: challenge.ChallengerID, Username: challenge.ChallengerName},
+		JoinCode:   challenge.JoinCode,
+		Status:     challenge.Status,
+		ExpiresAt:  challenge.ExpiresAt,
+	}
+	if challenge.OpponentID.Valid {
+		result.Opponent = &PlayerRef{UserID: uint(challenge.OpponentID.Int64), Username: challenge.OpponentName}
+	}
+	if challenge.MatchID.Valid {
+		matchID := uint(challenge.MatchID.Int64)
+		result.MatchID = &matchID
+	}
+	return result
+}
+
+func NewChallenges(challenges []models.Challenge) []Challenge {
+	result := make([]Challenge, 0, len(challenges))
+	for _, challenge := range challenges {
+		result = append(result, NewChallenge(challenge))
+	}
+	return result
+}
+
+func NewMatch(match models.Match) Match {
+	result := Match{
+		ID:        match.ID,
+		Mode:      match.Mode,
+		Ranked:    match.Ranked,
+		Status:    match.Status,
+		StartedAt: match.CreatedAt,
+		Players:   make([]MatchPlayer, 0, len(match.Players)),
+	}
+	if match.WinnerID.Valid {
+		winnerID := uint(match.WinnerID.Int64)
+		result.WinnerID = &winnerID
+	}
+	result.WinningTeam = optionalID(match.WinningTeam)
+	if match.FinishedAt.Valid {
+		result.FinishedAt = &match.FinishedAt.Time
+	}
+	for _, player := range match.Players {
+		deck := models.Deck{Name: player.Deck.Name, Heroes: player.Deck.Heroes, Spells: player.Deck.Spells}
+		result.Players = append(result.Players, MatchPlayer{
+			UserID:   player.UserID,
+			Username: player.Username,
+			Team:     player.Team,
+			Bot:      player.Bot,
+			Deck:     NewDeckSummary(deck),
+		})
+	}
+	return result
+}
+
+func NewMatches(matches []models.Match) []Match {
+	result := make([]Match, 0, len(matches))
+	for _, match := range matches {
+		result = append(result, NewMatch(match))
+	}
+	return result
+}
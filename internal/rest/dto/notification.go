@@ -0,0 +1,44 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type Notification struct {
+	ID        uint                   `json:"id"`
+	Kind      string                 `json:"kind"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Read      bool                   `json:"read"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+type NotificationSettings struct {
+	EmailDigest bool `json:"emailDigest"`
+}
+
+func NewNotification(notification models.Notification) Notification {
+	return Notification{
+		ID:        notification.ID,
+		Kind:      notification.Kind,
+		Title:     notification.Title,
+		Body:      notification.Body,
+		Data:      notification.Data,
+		Read:      notification.ReadAt.Valid,
+		CreatedAt: notification.CreatedAt,
+	}
+}
+
+func NewNotifications(notifications []models.Notification) []Notification {
+	result := make([]Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		result = append(result, NewNotification(notification))
+	}
+	return result
+}
+
+func NewNotificationSettings(settings models.NotificationSettings) NotificationSettings {
+	return NotificationSettings{EmailDigest: settings.EmailDigest}
+}
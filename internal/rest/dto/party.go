@@ -0,0 +1,26 @@
+package dto
+
+import "auth/internal/rest/models"
+
+type Party struct {
+	ID      uint       `json:"id"`
+	Leader  PlayerRef  `json:"leader"`
+	Invited *PlayerRef `json:"invited,omitempty"`
+	Member  *PlayerRef `json:"member,omitempty"`
+	Queued  bool       `json:"queued"`
+}
+
+func NewParty(party models.Party) Party {
+	result := Party{
+		ID:     party.ID,
+		Leader: PlayerRef{UserID: party.LeaderID, Username: party.LeaderName},
+		Queued: party.Queued,
+	}
+	if party.InvitedID.Valid {
+		result.Invited = &PlayerRef{UserID: uint(party.InvitedID.Int64), Username: party.InvitedName}
+	}
+	if party.MemberID.Valid {
+		result.Member = &PlayerRef{UserID: uint(party.MemberID.Int64), Username: party.MemberName}
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type StorePurchase struct {
+	ID            uint       `json:"id"`
+	Provider      string     `json:"provider"`
+	TransactionID string     `json:"transactionId"`
+	ProductID     string     `json:"productId"`
+	Gems          int64      `json:"gems"`
+	Status        string     `json:"status"`
+	ClawedBack    int64      `json:"clawedBack,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ReversedAt    *time.Time `json:"reversedAt,omitempty"`
+}
+
+func NewStorePurchase(purchase models.StorePurchase) StorePurchase {
+	result := StorePurchase{
+		ID:            purchase.ID,
+		Provider:      purchase.Provider,
+		TransactionID: purchase.TransactionID,
+		ProductID:     purchase.ProductID,
+		Gems:          purchase.Gems,
+		Status:        purchase.Status,
+		ClawedBack:    purchase.ClawedBack,
+		CreatedAt:     purchase.CreatedAt,
+	}
+	if purchase.ReversedAt.Valid {
+		result.ReversedAt = &purchase.ReversedAt.Time
+	}
+	return result
+}
+
+func NewStorePurchases(purchases []models.StorePurchase) []StorePurchase {
+	result := make([]StorePurchase, 0, len(purchases))
+	for _, purchase := range purchases {
+		result = append(result, NewStorePurchase(purchase))
+	}
+	return result
+}
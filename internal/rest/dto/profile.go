@@ -0,0 +1,104 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+// PublicProfile is what any player can see about another one. Sections the
+// owner has hidden are left nil and omitted from the JSON.
+type PublicProfile struct {
+	Username     string               `json:"username"`
+	Trophies     int32                `json:"trophies"`
+	MemberSince  time.Time            `json:"memberSince"`
+	Stats        *PlayerStats         `json:"stats,omitempty"`
+	FavoriteCard *CardSummary         `json:"favoriteCard,omitempty"`
+	CurrentDeck  *DeckSummary         `json:"currentDeck,omitempty"`
+	Collection   []CollectionProgress `json:"collection,omitempty"`
+}
+
+type PlayerStats struct {
+	Wins    int32   `json:"wins"`
+	Losses  int32   `json:"losses"`
+	WinRate float64 `json:"winRate"`
+}
+
+type CardSummary struct {
+	ID         uint   `json:"id"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Rarity     string `json:"rarity,omitempty"`
+	CostElixir int32  `json:"costElixir,omitempty"`
+}
+
+type DeckSummary struct {
+	Name  string        `json:"name"`
+	Cards []CardSummary `json:"cards"`
+}
+
+type CollectionProgress struct {
+	Category string  `json:"category"`
+	Owned    int     `json:"owned"`
+	Total    int     `json:"total"`
+	Percent  float64 `json:"percent"`
+}
+
+type PrivacySettings struct {
+	ShowStats      bool `json:"showStats"`
+	ShowCollection bool `json:"showCollection"`
+	ShowDeck       bool `json:"showDeck"`
+	ShowFavorite   bool `json:"showFavorite"`
+}
+
+func NewPlayerStats(profile models.PlayerProfile) *PlayerStats {
+	stats := &PlayerStats{Wins: profile.Wins, Losses: profile.Losses}
+	if played := profile.Wins + profile.Losses; played > 0 {
+		stats.WinRate = roundPercent(float64(profile.Wins) / float64(played))
+	}
+	return stats
+}
+
+func HeroCard(hero models.Hero) CardSummary {
+	return CardSummary{ID: hero.ID, Kind: "hero", Name: hero.Name, Rarity: hero.Rarity, CostElixir: hero.CostElixir}
+}
+
+func SpellCard(spell models.Spell) CardSummary {
+	return CardSummary{ID: spell.ID, Kind: "spell", Name: spell.Name}
+}
+
+func NewDeckSummary(deck models.Deck) *DeckSummary {
+	summary := &DeckSummary{Name: deck.Name, Cards: []CardSummary{}}
+	for _, hero := range deck.Heroes {
+		summary.Cards = append(summary.Cards, HeroCard(hero))
+	}
+	for _, spell := range deck.Spells {
+		summary.Cards = append(summary.Cards, SpellCard(spell))
+	}
+	return summary
+}
+
+func NewCollectionProgress(rows []models.CollectionCompletion) []CollectionProgress {
+	progress := make([]CollectionProgress, 0, len(rows))
+	for _, row := range rows {
+		item := CollectionProgress{Category: row.Category, Owned: row.Owned, Total: row.Total}
+		if row.Total > 0 {
+			item.Percent = roundPercent(float64(row.Owned) / float64(row.Total))
+		}
+		progress = append(progress, item)
+	}
+	return progress
+}
+
+func NewPrivacySettings(profile models.PlayerProfile) PrivacySettings {
+	return PrivacySettings{
+		ShowStats:      profile.ShowStats,
+		ShowCollection: profile.ShowCollection,
+		ShowDeck:       profile.ShowDeck,
+		ShowFavorite:   profile.ShowFavorite,
+	}
+}
+
+// roundPercent turns a 0..1 ratio into a percentage with one decimal.
+func roundPercent(ratio float64) float64 {
+	return float64(int(ratio*1000+0.5)) / 10
+}
@@ -0,0 +1,104 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type PromoCode struct {
+	ID           uint            `json:"id"`
+	Code         string          `json:"code"`
+	Description  string          `json:"description"`
+	Gold         int64           `json:"gold,omitempty"`
+	Gems         int64           `json:"gems,omitempty"`
+	Chest        string          `json:"chest,omitempty"`
+	Items        []PromoCodeItem `json:"items"`
+	ExpiresAt    *time.Time      `json:"expiresAt,omitempty"`
+	MaxUses      *int64          `json:"maxUses,omitempty"`
+	Uses         int64           `json:"uses"`
+	PerUserLimit int64           `json:"perUserLimit"`
+	Active       bool            `json:"active"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+type PromoCodeItem struct {
+	HeroID  *uint  `json:"heroId,omitempty"`
+	SpellID *uint  `json:"spellId,omitempty"`
+	Name    string `json:"name"`
+}
+
+// PromoReward is what a player received for redeeming a code. Chest is the
+// kind of the locked chest it added, if any.
+type PromoReward struct {
+	Gold  int64           `json:"gold,omitempty"`
+	Gems  int64           `json:"gems,omitempty"`
+	Chest string          `json:"chest,omitempty"`
+	Items []PromoCodeItem `json:"items"`
+}
+
+type PromoRedemption struct {
+	ID         uint      `json:"id"`
+	UserID     uint      `json:"userId"`
+	Username   string    `json:"username"`
+	RedeemedAt time.Time `json:"redeemedAt"`
+}
+
+func newPromoCodeItems(items []models.PromoCodeItem) []PromoCodeItem {
+	result := make([]PromoCodeItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, PromoCodeItem{
+			HeroID:  optionalID(item.HeroID),
+			SpellID: optionalID(item.SpellID),
+			Name:    item.Name,
+		})
+	}
+	return result
+}
+
+func NewPromoCode(code models.PromoCode) PromoCode {
+	result := PromoCode{
+		ID:           code.ID,
+		Code:         code.Code,
+		Description:  code.Description,
+		Gold:         code.RewardGold,
+		Gems:         code.RewardGems,
+		Chest:        code.RewardChest.String,
+		Items:        newPromoCodeItems(code.Items),
+		Uses:         code.Uses,
+		PerUserLimit: code.PerUserLimit,
+		Active:       code.Active,
+		CreatedAt:    code.CreatedAt,
+	}
+	if code.ExpiresAt.Valid {
+		result.ExpiresAt = &code.ExpiresAt.Time
+	}
+	if code.MaxUses.Valid {
+		result.MaxUses = &code.MaxUses.Int64
+	}
+	return result
+}
+
+func NewPromoCodes(codes []models.PromoCode) []PromoCode {
+	result := make([]PromoCode, 0, len(codes))
+	for _, code := range codes {
+		result = append(result, NewPromoCode(code))
+	}
+	return result
+}
+
+func NewPromoReward(code models.PromoCode) PromoReward {
+	return PromoReward{Gold: code.RewardGold, Gems: code.RewardGems, Chest: code.RewardChest.String, Items: newPromoCodeItems(code.Items)}
+}
+
+func NewPromoRedemptions(redemptions []models.PromoRedemption) []PromoRedemption {
+	result := make([]PromoRedemption, 0, len(redemptions))
+	for _, redemption := range redemptions {
+		result = append(result, PromoRedemption{
+			ID:         redemption.ID,
+			UserID:     redemption.UserID,
+			Username:   redemption.Username,
+			RedeemedAt: redemption.CreatedAt,
+		})
+	}
+	return result
+}
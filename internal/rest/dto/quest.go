@@ -0,0 +1,89 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+// Reward is what a quest or trophy road milestone pays out.
+type Reward struct {
+	Gold    int64 `json:"gold,omitempty"`
+	HeroID  *uint `json:"heroId,omitempty"`
+	SpellID *uint `json:"spellId,omitempty"`
+}
+
+// Quest is a player's view of one quest. ID is what /claim takes and is
+// omitted until the player has made progress.
+type Quest struct {
+	ID          uint   `json:"id,omitempty"`
+	Code        string `json:"code"`
+	Kind        string `json:"kind"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Progress    int32  `json:"progress"`
+	Target      int32  `json:"target"`
+	Completed   bool   `json:"completed"`
+	Claimed     bool   `json:"claimed"`
+	Reward      Reward `json:"reward"`
+}
+
+type QuestDefinition struct {
+	ID          uint      `json:"id"`
+	Code        string    `json:"code"`
+	Kind        string    `json:"kind"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Event       string    `json:"event"`
+	Rarity      string    `json:"rarity,omitempty"`
+	Target      int32     `json:"target"`
+	Reward      Reward    `json:"reward"`
+	Active      bool      `json:"active"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func newQuestReward(definition models.QuestDefinition) Reward {
+	return Reward{
+		Gold:    definition.RewardGold,
+		HeroID:  optionalID(definition.RewardHeroID),
+		SpellID: optionalID(definition.RewardSpellID),
+	}
+}
+
+func NewQuest(quest models.QuestProgress) Quest {
+	return Quest{
+		ID:          quest.ID,
+		Code:        quest.Definition.Code,
+		Kind:        quest.Definition.Kind,
+		Title:       quest.Definition.Title,
+		Description: quest.Definition.Description,
+		Progress:    quest.Progress,
+		Target:      quest.Definition.Target,
+		Completed:   quest.CompletedAt.Valid,
+		Claimed:     quest.ClaimedAt.Valid,
+		Reward:      newQuestReward(quest.Definition),
+	}
+}
+
+func NewQuestDefinition(definition models.QuestDefinition) QuestDefinition {
+	return QuestDefinition{
+		ID:          definition.ID,
+		Code:        definition.Code,
+		Kind:        definition.Kind,
+		Title:       definition.Title,
+		Description: definition.Description,
+		Event:       definition.Event,
+		Rarity:      definition.Rarity,
+		Target:      definition.Target,
+		Reward:      newQuestReward(definition),
+		Active:      definition.Active,
+		UpdatedAt:   definition.UpdatedAt,
+	}
+}
+
+func NewQuestDefinitions(definitions []models.QuestDefinition) []QuestDefinition {
+	result := make([]QuestDefinition, 0, len(definitions))
+	for _, definition := range definitions {
+		result = append(result, NewQuestDefinition(definition))
+	}
+	return result
+}
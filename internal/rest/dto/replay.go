@@ -0,0 +1,108 @@
+package dto
+
+import (
+	"auth/internal/replays"
+	"auth/internal/rest/models"
+	"time"
+)
+
+// Replay describes a recorded match. Verified is left out until the replay
+// has been re-simulated.
+type Replay struct {
+	ID           uint       `json:"id"`
+	RecordedAt   time.Time  `json:"recordedAt"`
+	Seed         int64      `json:"seed"`
+	StatsVersion string     `json:"statsVersion"`
+	DurationMs   int32      `json:"durationMs"`
+	InputCount   int32      `json:"inputCount"`
+	Verified     *bool      `json:"verified,omitempty"`
+	VerifiedAt   *time.Time `json:"verifiedAt,omitempty"`
+	VerifyError  string     `json:"verifyError,omitempty"`
+	Match        Match      `json:"match"`
+}
+
+// ReplaySeat is a player as the simulation sees them, with the full card
+// stats of their deck snapshot.
+type ReplaySeat struct {
+	Seat     int                 `json:"seat"`
+	Side     int                 `json:"side"`
+	UserID   uint                `json:"userId,omitempty"`
+	Username string              `json:"username"`
+	Bot      string              `json:"bot,omitempty"`
+	Deck     models.DeckSnapshot `json:"deck"`
+}
+
+type ReplayInput struct {
+	At       uint32 `json:"at"`
+	Seat     int    `json:"seat"`
+	Kind     string `json:"kind"`
+	CardID   uint   `json:"cardId"`
+	Lane     int    `json:"lane"`
+	Position int    `json:"position"`
+}
+
+// ReplayOutcome is how the simulated match ended; winningSide is 0 for a
+// draw.
+type ReplayOutcome struct {
+	WinningSide int    `json:"winningSide"`
+	Crowns      [2]int `json:"crowns"`
+	EndedAt     uint32 `json:"endedAtMs"`
+}
+
+func NewReplay(replay models.Replay) Replay {
+	result := Replay{
+		ID:           replay.ID,
+		RecordedAt:   replay.CreatedAt,
+		Seed:         replay.Seed,
+		StatsVersion: replay.StatsVersion,
+		DurationMs:   replay.DurationMs,
+		InputCount:   replay.InputCount,
+		VerifyError:  replay.VerifyError,
+		Match:        NewMatch(replay.Match),
+	}
+	if replay.Verified.Valid {
+		result.Verified = &replay.Verified.Bool
+	}
+	if replay.VerifiedAt.Valid {
+		result.VerifiedAt = &replay.VerifiedAt.Time
+	}
+	return result
+}
+
+func NewReplays(replays []models.Replay) []Replay {
+	result := make([]Replay, 0, len(replays))
+	for _, replay := range replays {
+		result = append(result, NewReplay(replay))
+	}
+	return result
+}
+
+func NewReplaySeats(players []models.MatchPlayer, sides []int) []ReplaySeat {
+	result := make([]ReplaySeat, 0, len(players))
+	for seat, player := range players {
+		result = append(result, ReplaySeat{
+			Seat:     seat,
+			Side:     sides[seat],
+			UserID:   player.UserID,
+			Username: player.Username,
+			Bot:      player.Bot,
+			Deck:     player.Deck,
+		})
+	}
+	return result
+}
+
+func NewReplayInput(input replays.Input) ReplayInput {
+	return ReplayInput{
+		At:       input.At,
+		Seat:     input.Seat,
+		Kind:     input.Kind,
+		CardID:   input.CardID,
+		Lane:     input.Lane,
+		Position: input.Position,
+	}
+}
+
+func NewReplayOutcome(outcome replays.Outcome) ReplayOutcome {
+	return ReplayOutcome{WinningSide: outcome.WinningSide, Crowns: outcome.Crowns, EndedAt: outcome.EndedAt}
+}
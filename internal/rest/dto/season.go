@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type Season struct {
+	ID               uint           `json:"id"`
+	Name             string         `json:"name"`
+	StartsAt         time.Time      `json:"startsAt"`
+	EndsAt           time.Time      `json:"endsAt"`
+	ResetThreshold   int32          `json:"resetThreshold"`
+	ResetKeepPercent int32          `json:"resetKeepPercent"`
+	ClosedAt         *time.Time     `json:"closedAt,omitempty"`
+	Rewards          []SeasonReward `json:"rewards"`
+}
+
+type SeasonReward struct {
+	MinRank int32 `json:"minRank"`
+	MaxRank int32 `json:"maxRank"`
+	Gold    int64 `json:"gold"`
+}
+
+type SeasonResult struct {
+	SeasonID      uint   `json:"seasonId"`
+	SeasonName    string `json:"seasonName"`
+	Rank          int32  `json:"rank"`
+	Trophies      int32  `json:"trophies"`
+	ResetTrophies int32  `json:"resetTrophies"`
+	RewardGold    int64  `json:"rewardGold"`
+}
+
+// TrophyRoadMilestone is one step of the trophy road. Unlocked means the
+// player has reached its trophies at some point, even if a season reset has
+// since taken them below it.
+type TrophyRoadMilestone struct {
+	ID       uint   `json:"id"`
+	Trophies int32  `json:"trophies"`
+	Reward   Reward `json:"reward"`
+	Unlocked bool   `json:"unlocked"`
+	Claimed  bool   `json:"claimed"`
+}
+
+func NewSeason(season models.Season) Season {
+	result := Season{
+		ID:               season.ID,
+		Name:             season.Name,
+		StartsAt:         season.StartsAt,
+		EndsAt:           season.EndsAt,
+		ResetThreshold:   season.ResetThreshold,
+		ResetKeepPercent: season.ResetKeepPercent,
+		Rewards:          make([]SeasonReward, 0, len(season.Rewards)),
+	}
+	if season.ClosedAt.Valid {
+		result.ClosedAt = &season.ClosedAt.Time
+	}
+	for _, reward := range season.Rewards {
+		result.Rewards = append(result.Rewards, SeasonReward{MinRank: reward.MinRank, MaxRank: reward.MaxRank, Gold: reward.RewardGold})
+	}
+	return result
+}
+
+func NewSeasons(seasons []models.Season) []Season {
+	result := make([]Season, 0, len(seasons))
+	for _, season := range seasons {
+		result = append(result, NewSeason(season))
+	}
+	return result
+}
+
+func NewSeasonResults(results []models.SeasonResult) []SeasonResult {
+	response := make([]SeasonResult, 0, len(results))
+	for _, result := range results {
+		response = append(response, SeasonResult{
+			SeasonID:      result.SeasonID,
+			SeasonName:    result.SeasonName,
+			Rank:          result.Rank,
+			Trophies:      result.Trophies,
+			ResetTrophies: result.ResetTrophies,
+			RewardGold:    result.RewardGold,
+		})
+	}
+	return response
+}
+
+func NewTrophyRoadMilestone(milestone models.TrophyRoadMilestone, bestTrophies int32) TrophyRoadMilestone {
+	return TrophyRoadMilestone{
+		ID:       milestone.ID,
+		Trophies: milestone.Trophies,
+		Reward: Reward{
+			Gold:    milestone.RewardGold,
+			HeroID:  optionalID(milestone.RewardHeroID),
+			SpellID: optionalID(milestone.RewardSpellID),
+		},
+		Unlocked: bestTrophies >= milestone.Trophies,
+		Claimed:  milestone.ClaimedAt.Valid,
+	}
+}
@@ -0,0 +1,119 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type DailyOffer struct {
+	ID              uint   `json:"id"`
+	HeroID          *uint  `json:"heroId,omitempty"`
+	SpellID         *uint  `json:"spellId,omitempty"`
+	Name            string `json:"name"`
+	Rarity          string `json:"rarity,omitempty"`
+	ListPrice       int64  `json:"listPrice"`
+	Price           int64  `json:"price"`
+	DiscountPercent int64  `json:"discountPercent"`
+	Purchased       bool   `json:"purchased"`
+}
+
+// ShopOffer is a bundle as players see it. Remaining is left out for offers
+// without a stock limit.
+type ShopOffer struct {
+	ID              uint            `json:"id"`
+	Title           string          `json:"title"`
+	Description     string          `json:"description"`
+	Price           int64           `json:"price"`
+	Currency        string          `json:"currency"`
+	Value           int64           `json:"value"`
+	DiscountPercent int64           `json:"discountPercent"`
+	Gold            int64           `json:"gold,omitempty"`
+	Items           []ShopOfferItem `json:"items"`
+	StartsAt        time.Time       `json:"startsAt"`
+	EndsAt          time.Time       `json:"endsAt"`
+	Remaining       *int64          `json:"remaining,omitempty"`
+	PerUserLimit    *int64          `json:"perUserLimit,omitempty"`
+	Sold            int64           `json:"sold"`
+	Active          bool            `json:"active"`
+}
+
+type ShopOfferItem struct {
+	HeroID  *uint  `json:"heroId,omitempty"`
+	SpellID *uint  `json:"spellId,omitempty"`
+	Name    string `json:"name"`
+	Price   int64  `json:"price"`
+}
+
+func discountPercent(listPrice, price int64) int64 {
+	if listPrice <= price {
+		return 0
+	}
+	return (listPrice - price) * 100 / listPrice
+}
+
+func NewDailyOffer(offer models.DailyOffer) DailyOffer {
+	return DailyOffer{
+		ID:              offer.ID,
+		HeroID:          optionalID(offer.HeroID),
+		SpellID:         optionalID(offer.SpellID),
+		Name:            offer.Name,
+		Rarity:          offer.Rarity,
+		ListPrice:       offer.ListPrice,
+		Price:           offer.Price,
+		DiscountPercent: discountPercent(offer.ListPrice, offer.Price),
+		Purchased:       offer.PurchasedAt.Valid,
+	}
+}
+
+func NewDailyOffers(offers []models.DailyOffer) []DailyOffer {
+	result := make([]DailyOffer, 0, len(offers))
+	for _, offer := range offers {
+		result = append(result, NewDailyOffer(offer))
+	}
+	return result
+}
+
+func NewShopOffer(offer models.ShopOffer) ShopOffer {
+	result := ShopOffer{
+		ID:              offer.ID,
+		Title:           offer.Title,
+		Description:     offer.Description,
+		Price:           offer.Price,
+		Currency:        offer.Currency,
+		Value:           offer.Value,
+		DiscountPercent: discountPercent(offer.Value, offer.Price),
+		Gold:            offer.RewardGold,
+		Items:           make([]ShopOfferItem, 0, len(offer.Items)),
+		StartsAt:        offer.StartsAt,
+		EndsAt:          offer.EndsAt,
+		Sold:            offer.Sold,
+		Active:          offer.Active,
+	}
+	if offer.Stock.Valid {
+		remaining := offer.Stock.Int64 - offer.Sold
+		if remaining < 0 {
+			remaining = 0
+		}
+		result.Remaining = &remaining
+	}
+	if offer.PerUserLimit.Valid {
+		result.PerUserLimit = &offer.PerUserLimit.Int64
+	}
+	for _, item := range offer.Items {
+		result.Items = append(result.Items, ShopOfferItem{
+			HeroID:  optionalID(item.HeroID),
+			SpellID: optionalID(item.SpellID),
+			Name:    item.Name,
+			Price:   item.Price,
+		})
+	}
+	return result
+}
+
+func NewShopOffers(offers []models.ShopOffer) []ShopOffer {
+	result := make([]ShopOffer, 0, len(offers))
+	for _, offer := range offers {
+		result = append(result, NewShopOffer(offer))
+	}
+	return result
+}
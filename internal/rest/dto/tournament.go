@@ -0,0 +1,136 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type Tournament struct {
+	ID                   uint              `json:"id"`
+	Name                 string            `json:"name"`
+	Format               string            `json:"format"`
+	CreatedBy            *uint             `json:"createdBy,omitempty"`
+	Official             bool              `json:"official"`
+	EntryFee             int64             `json:"entryFee"`
+	PrizePool            int64             `json:"prizePool"`
+	MaxPlayers           int32             `json:"maxPlayers"`
+	Players              int32             `json:"players"`
+	Rounds               int32             `json:"rounds"`
+	RoundMinutes         int32             `json:"roundMinutes"`
+	RegistrationOpensAt  time.Time         `json:"registrationOpensAt"`
+	RegistrationClosesAt time.Time         `json:"registrationClosesAt"`
+	Status               string            `json:"status"`
+	CurrentRound         int32             `json:"currentRound"`
+	RoundEndsAt          *time.Time        `json:"roundEndsAt,omitempty"`
+	FinishedAt           *time.Time        `json:"finishedAt,omitempty"`
+	Prizes               []TournamentPrize `json:"prizes"`
+}
+
+// TournamentPrize is what a place wins, as a share of the pool and, with the
+// pool as it stands, in gold.
+type TournamentPrize struct {
+	Place   int32 `json:"place"`
+	Percent int32 `json:"percent"`
+	Gold    int64 `json:"gold"`
+}
+
+type TournamentMatch struct {
+	Round       int32  `json:"round"`
+	Position    int32  `json:"position"`
+	Player1ID   *uint  `json:"player1Id"`
+	Player1Name string `json:"player1Name"`
+	Player2ID   *uint  `json:"player2Id"`
+	Player2Name string `json:"player2Name"`
+	Bye         bool   `json:"bye"`
+	MatchID     *uint  `json:"matchId,omitempty"`
+	WinnerID    *uint  `json:"winnerId,omitempty"`
+	Finished    bool   `json:"finished"`
+}
+
+type TournamentStanding struct {
+	UserID          uint   `json:"userId"`
+	Username        string `json:"username"`
+	Seed            *uint  `json:"seed,omitempty"`
+	Points          int32  `json:"points"`
+	EliminatedRound *uint  `json:"eliminatedRound,omitempty"`
+	Place           *uint  `json:"place,omitempty"`
+	PrizeGold       int64  `json:"prizeGold"`
+}
+
+func NewTournament(tournament models.Tournament) Tournament {
+	result := Tournament{
+		ID:                   tournament.ID,
+		Name:                 tournament.Name,
+		Format:               tournament.Format,
+		CreatedBy:            optionalID(tournament.CreatedBy),
+		Official:             tournament.Official,
+		EntryFee:             tournament.EntryFee,
+		PrizePool:            tournament.PrizePool,
+		MaxPlayers:           tournament.MaxPlayers,
+		Players:              tournament.Players,
+		Rounds:               tournament.Rounds,
+		RoundMinutes:         tournament.RoundMinutes,
+		RegistrationOpensAt:  tournament.RegistrationOpensAt,
+		RegistrationClosesAt: tournament.RegistrationClosesAt,
+		Status:               tournament.Status,
+		CurrentRound:         tournament.CurrentRound,
+		Prizes:               make([]TournamentPrize, 0, len(tournament.Prizes)),
+	}
+	if tournament.RoundEndsAt.Valid {
+		result.RoundEndsAt = &tournament.RoundEndsAt.Time
+	}
+	if tournament.FinishedAt.Valid {
+		result.FinishedAt = &tournament.FinishedAt.Time
+	}
+	for _, prize := range tournament.Prizes {
+		result.Prizes = append(result.Prizes, TournamentPrize{
+			Place:   prize.Place,
+			Percent: prize.Percent,
+			Gold:    tournament.PrizePool * int64(prize.Percent) / 100,
+		})
+	}
+	return result
+}
+
+func NewTournaments(tournaments []models.Tournament) []Tournament {
+	result := make([]Tournament, 0, len(tournaments))
+	for _, tournament := range tournaments {
+		result = append(result, NewTournament(tournament))
+	}
+	return result
+}
+
+func NewTournamentMatches(matches []models.TournamentMatch) []TournamentMatch {
+	result := make([]TournamentMatch, 0, len(matches))
+	for _, match := range matches {
+		result = append(result, TournamentMatch{
+			Round:       match.Round,
+			Position:    match.Position,
+			Player1ID:   optionalID(match.Player1ID),
+			Player1Name: match.Player1Name,
+			Player2ID:   optionalID(match.Player2ID),
+			Player2Name: match.Player2Name,
+			Bye:         !match.MatchID.Valid,
+			MatchID:     optionalID(match.MatchID),
+			WinnerID:    optionalID(match.WinnerID),
+			Finished:    match.FinishedAt.Valid,
+		})
+	}
+	return result
+}
+
+func NewTournamentStandings(players []models.TournamentPlayer) []TournamentStanding {
+	result := make([]TournamentStanding, 0, len(players))
+	for _, player := range players {
+		result = append(result, TournamentStanding{
+			UserID:          player.UserID,
+			Username:        player.Username,
+			Seed:            optionalID(player.Seed),
+			Points:          player.Points,
+			EliminatedRound: optionalID(player.EliminatedRound),
+			Place:           optionalID(player.Place),
+			PrizeGold:       player.PrizeGold,
+		})
+	}
+	return result
+}
@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+// User is the account as its owner sees it. The password hash and the
+// soft-delete marker never leave the server.
+type User struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Bank      int64     `json:"bank"`
+	Gems      int64     `json:"gems"`
+	Awards    int32     `json:"awards"`
+	UserType  string    `json:"userType"`
+}
+
+func NewUser(user *models.User) User {
+	return User{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		Username:  user.Username,
+		Email:     user.Email,
+		Bank:      user.Bank,
+		Gems:      user.Gems,
+		Awards:    user.Awards,
+		UserType:  user.UserType,
+	}
+}
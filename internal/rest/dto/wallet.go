@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"auth/internal/rest/models"
+	"time"
+)
+
+type Wallet struct {
+	Gold int64 `json:"gold"`
+	Gems int64 `json:"gems"`
+}
+
+type WalletTransaction struct {
+	ID        uint      `json:"id"`
+	Currency  string    `json:"currency"`
+	Amount    int64     `json:"amount"`
+	Reason    string    `json:"reason"`
+	Reference string    `json:"reference,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewWallet(wallet models.Wallet) Wallet {
+	return Wallet{Gold: wallet.Gold, Gems: wallet.Gems}
+}
+
+func NewWalletTransactions(transactions []models.WalletTransaction) []WalletTransaction {
+	result := make([]WalletTransaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		result = append(result, WalletTransaction{
+			ID:        transaction.ID,
+			Currency:  transaction.Currency,
+			Amount:    transaction.Amount,
+			Reason:    transaction.Reason,
+			Reference: transaction.Reference,
+			CreatedAt: transaction.CreatedAt,
+		})
+	}
+	return result
+}
@@ -1,5 +1,7 @@
 package forms
 
+import "time"
+
 type LoginForm struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -10,11 +12,434 @@ type ForgotPasswordForm struct {
 }
 type ResetPasswordForm struct {
 	Email           string `json:"email,omitempty"`
-	Password        string `json:"password" binding:"required" json:"password,omitempty"`
-	PasswordConfirm string `json:"passwordConfirm" binding:"required" json:"password_confirm,omitempty"`
+	Password        string `json:"password" binding:"required"`
+	PasswordConfirm string `json:"passwordConfirm" binding:"required"`
 }
 
 type CheckCode struct {
 	Email string `json:"email,omitempty"`
 	Code  string `json:"code"`
 }
+
+type CodeForm struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginForm struct {
+	Challenge    string `json:"challenge" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+type ChangeUsernameForm struct {
+	Username string `json:"username" binding:"required,min=3,max=32"`
+}
+
+type ChangePasswordForm struct {
+	CurrentPassword string `json:"currentPassword"`
+	Password        string `json:"password" binding:"required,min=6"`
+	PasswordConfirm string `json:"passwordConfirm" binding:"required"`
+}
+
+type ChangeEmailForm struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type UpdatePrivacyForm struct {
+	ShowStats      *bool `json:"showStats"`
+	ShowCollection *bool `json:"showCollection"`
+	ShowDeck       *bool `json:"showDeck"`
+	ShowFavorite   *bool `json:"showFavorite"`
+}
+
+// UpdateShowcaseForm fields are optional; sending 0 clears the choice.
+type UpdateShowcaseForm struct {
+	FavoriteHeroID *uint `json:"favoriteHeroId"`
+	CurrentDeckID  *uint `json:"currentDeckId"`
+}
+
+// RegisterForm only carries what a player chooses at sign-up; balance,
+// trophies and role are set by the server.
+type RegisterForm struct {
+	Username string `json:"username" binding:"required,min=3,max=32"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type AddHeroToDeckForm struct {
+	DeckID uint `json:"deck_id"`
+	HeroID uint `json:"hero_id" binding:"required"`
+}
+
+type CreateHeroForm struct {
+	Name        string `json:"Name" binding:"required"`
+	Description string `json:"Description"`
+	Rarity      string `json:"Rarity" binding:"required"`
+	DamageType  string `json:"DamageType"`
+	Effect      string `json:"Effect"`
+	Hitpoint    int32  `json:"Hitpoint" binding:"min=0"`
+	Damage      int32  `json:"Damage" binding:"min=0"`
+	CostElixir  int32  `json:"CostElixir" binding:"min=0,max=10"`
+	DamageTower int32  `json:"DamageTower" binding:"min=0"`
+	Speed       int32  `json:"Speed" binding:"min=0"`
+	Price       int64  `json:"Price" binding:"min=0"`
+}
+
+type CreateSpellForm struct {
+	Name        string `json:"Name" binding:"required"`
+	Description string `json:"Description"`
+	Area        int32  `json:"Area" binding:"min=0"`
+	DamageType  string `json:"DamageType"`
+	Damage      int32  `json:"Damage" binding:"min=0"`
+	Duration    int64  `json:"Duration" binding:"min=0"`
+	Effect      string `json:"Effect"`
+	Price       int64  `json:"Price" binding:"min=0"`
+}
+
+type UsernameForm struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// CreateChallengeForm challenges a friend by username, or opens a private
+// lobby anyone with the join code can enter when Opponent is empty.
+type CreateChallengeForm struct {
+	Opponent string `json:"opponent"`
+	DeckID   uint   `json:"deckId" binding:"required"`
+}
+
+type AcceptChallengeForm struct {
+	DeckID uint `json:"deckId" binding:"required"`
+}
+
+type JoinChallengeForm struct {
+	Code   string `json:"code" binding:"required"`
+	DeckID uint   `json:"deckId" binding:"required"`
+}
+
+type CreateClanForm struct {
+	Name        string `json:"name" binding:"required,min=3,max=32"`
+	Description string `json:"description" binding:"max=255"`
+	Membership  string `json:"membership" binding:"omitempty,oneof=OPEN INVITE_ONLY CLOSED"`
+	MinTrophies int32  `json:"minTrophies" binding:"min=0"`
+}
+
+type UpdateClanForm struct {
+	Description *string `json:"description" binding:"omitempty,max=255"`
+	Membership  *string `json:"membership" binding:"omitempty,oneof=OPEN INVITE_ONLY CLOSED"`
+	MinTrophies *int32  `json:"minTrophies" binding:"omitempty,min=0"`
+}
+
+type ClanRoleForm struct {
+	Role string `json:"role" binding:"required,oneof=LEADER CO_LEADER ELDER MEMBER"`
+}
+
+type CardRequestForm struct {
+	Kind   string `json:"kind" binding:"required,oneof=HERO SPELL"`
+	CardID uint   `json:"cardId" binding:"required"`
+}
+
+type ChatMessageForm struct {
+	Body string `json:"body" binding:"required"`
+}
+
+type ReportMessageForm struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+type ResolveReportForm struct {
+	Status string `json:"status" binding:"required,oneof=ACTIONED DISMISSED"`
+}
+
+type HideMessageForm struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// SanctionForm mutes or bans a player from chat. Leaving Minutes at zero makes
+// the sanction last until it is revoked.
+type SanctionForm struct {
+	Kind    string `json:"kind" binding:"required,oneof=MUTE BAN"`
+	Reason  string `json:"reason" binding:"required,max=255"`
+	Minutes int    `json:"minutes" binding:"min=0"`
+}
+
+type NotificationSettingsForm struct {
+	EmailDigest *bool `json:"emailDigest" binding:"required"`
+}
+
+// BroadcastForm sends a notification to every player, such as patch notes.
+type BroadcastForm struct {
+	Kind  string `json:"kind" binding:"required,oneof=BALANCE_PATCH ANNOUNCEMENT"`
+	Title string `json:"title" binding:"required,max=255"`
+	Body  string `json:"body" binding:"max=2000"`
+}
+
+type CreateQuestForm struct {
+	Code          string `json:"code" binding:"required,max=50"`
+	Kind          string `json:"kind" binding:"required,oneof=DAILY ACHIEVEMENT"`
+	Title         string `json:"title" binding:"required,max=255"`
+	Description   string `json:"description" binding:"max=255"`
+	Event         string `json:"event" binding:"required,oneof=MATCH_PLAYED MATCH_WON SPELL_PLAYED HERO_BOUGHT SPELL_BOUGHT"`
+	Rarity        string `json:"rarity" binding:"max=20"`
+	Target        int32  `json:"target" binding:"required,min=1"`
+	RewardGold    int64  `json:"rewardGold" binding:"min=0"`
+	RewardHeroID  uint   `json:"rewardHeroId"`
+	RewardSpellID uint   `json:"rewardSpellId"`
+}
+
+type UpdateQuestForm struct {
+	Title         *string `json:"title" binding:"omitempty,max=255"`
+	Description   *string `json:"description" binding:"omitempty,max=255"`
+	Target        *int32  `json:"target" binding:"omitempty,min=1"`
+	RewardGold    *int64  `json:"rewardGold" binding:"omitempty,min=0"`
+	RewardHeroID  *uint   `json:"rewardHeroId"`
+	RewardSpellID *uint   `json:"rewardSpellId"`
+	Active        *bool   `json:"active"`
+}
+
+// MatchResultForm is sent by the game server when a battle ends. Matches
+// with teams, which include practice against a bot, report WinningTeam or a
+// WinnerID from the winning team, and other matches WinnerID; both are left
+// out for a draw.
+type MatchResultForm struct {
+	WinnerID    uint                    `json:"winnerId"`
+	WinningTeam int32                   `json:"winningTeam" binding:"omitempty,oneof=1 2"`
+	Players     []MatchPlayerResultForm `json:"players" binding:"dive"`
+}
+
+type MatchPlayerResultForm struct {
+	UserID       uint  `json:"userId" binding:"required"`
+	SpellsPlayed int32 `json:"spellsPlayed" binding:"min=0"`
+}
+
+// CreateSeasonForm schedules a season. ResetThreshold and ResetKeepPercent
+// default to 4000 trophies and 50 percent when left out.
+type CreateSeasonForm struct {
+	Name             string             `json:"name" binding:"required,max=100"`
+	StartsAt         time.Time          `json:"startsAt" binding:"required"`
+	EndsAt           time.Time          `json:"endsAt" binding:"required"`
+	ResetThreshold   *int32             `json:"resetThreshold" binding:"omitempty,min=0"`
+	ResetKeepPercent *int32             `json:"resetKeepPercent" binding:"omitempty,min=0,max=100"`
+	Rewards          []SeasonRewardForm `json:"rewards" binding:"dive"`
+}
+
+// UpdateSeasonForm changes only the fields that are sent. Sending rewards
+// replaces the whole reward table.
+type UpdateSeasonForm struct {
+	Name             *string            `json:"name" binding:"omitempty,max=100"`
+	StartsAt         *time.Time         `json:"startsAt"`
+	EndsAt           *time.Time         `json:"endsAt"`
+	ResetThreshold   *int32             `json:"resetThreshold" binding:"omitempty,min=0"`
+	ResetKeepPercent *int32             `json:"resetKeepPercent" binding:"omitempty,min=0,max=100"`
+	Rewards          []SeasonRewardForm `json:"rewards" binding:"omitempty,dive"`
+}
+
+type SeasonRewardForm struct {
+	MinRank    int32 `json:"minRank" binding:"required,min=1"`
+	MaxRank    int32 `json:"maxRank" binding:"required,gtefield=MinRank"`
+	RewardGold int64 `json:"rewardGold" binding:"required,min=1"`
+}
+
+// CreateShopOfferForm sets up a bundle. Currency defaults to gold; Stock and
+// PerUserLimit left at zero mean no limit.
+type CreateShopOfferForm struct {
+	Title        string    `json:"title" binding:"required,max=100"`
+	Description  string    `json:"description" binding:"max=255"`
+	Price        int64     `json:"price" binding:"required,min=1"`
+	Currency     string    `json:"currency" binding:"omitempty,oneof=GOLD GEMS"`
+	Gold         int64     `json:"gold" binding:"min=0"`
+	HeroIDs      []uint    `json:"heroIds"`
+	SpellIDs     []uint    `json:"spellIds"`
+	StartsAt     time.Time `json:"startsAt" binding:"required"`
+	EndsAt       time.Time `json:"endsAt" binding:"required"`
+	Stock        int64     `json:"stock" binding:"min=0"`
+	PerUserLimit int64     `json:"perUserLimit" binding:"min=0"`
+}
+
+// UpdateShopOfferForm changes only the fields that are sent. Setting Stock or
+// PerUserLimit to zero removes the limit.
+type UpdateShopOfferForm struct {
+	Title        *string    `json:"title" binding:"omitempty,max=100"`
+	Description  *string    `json:"description" binding:"omitempty,max=255"`
+	Price        *int64     `json:"price" binding:"omitempty,min=1"`
+	Currency     *string    `json:"currency" binding:"omitempty,oneof=GOLD GEMS"`
+	StartsAt     *time.Time `json:"startsAt"`
+	EndsAt       *time.Time `json:"endsAt"`
+	Stock        *int64     `json:"stock" binding:"omitempty,min=0"`
+	PerUserLimit *int64     `json:"perUserLimit" binding:"omitempty,min=0"`
+	Active       *bool      `json:"active"`
+}
+
+type ExchangeGemsForm struct {
+	Gems int64 `json:"gems" binding:"required,min=1"`
+}
+
+type BuyChestForm struct {
+	Kind string `json:"kind" binding:"required,oneof=SILVER GOLDEN MAGICAL"`
+}
+
+// GrantCurrencyForm credits a player's wallet, for support refunds and
+// compensation.
+type GrantCurrencyForm struct {
+	UserID    uint   `json:"userId" binding:"required"`
+	Currency  string `json:"currency" binding:"required,oneof=GOLD GEMS"`
+	Amount    int64  `json:"amount" binding:"required,min=1"`
+	Reference string `json:"reference" binding:"max=100"`
+}
+
+type VerifyPurchaseForm struct {
+	Provider string `json:"provider" binding:"required"`
+	Receipt  string `json:"receipt" binding:"required"`
+}
+
+// CreatePromoCodeForm sets up either one code named by Code or Count codes
+// generated at random with the same rewards. MaxUses left at zero means no
+// cap on total redemptions and PerUserLimit defaults to one.
+type CreatePromoCodeForm struct {
+	Code         string     `json:"code" binding:"omitempty,min=4,max=32,alphanum"`
+	Count        int        `json:"count" binding:"omitempty,min=1,max=1000"`
+	Description  string     `json:"description" binding:"max=255"`
+	Gold         int64      `json:"gold" binding:"min=0"`
+	Gems         int64      `json:"gems" binding:"min=0"`
+	Chest        string     `json:"chest" binding:"omitempty,oneof=SILVER GOLDEN MAGICAL"`
+	HeroIDs      []uint     `json:"heroIds"`
+	SpellIDs     []uint     `json:"spellIds"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+	MaxUses      int64      `json:"maxUses" binding:"min=0"`
+	PerUserLimit int64      `json:"perUserLimit" binding:"min=0"`
+}
+
+// UpdatePromoCodeForm changes only the fields that are sent. Setting MaxUses
+// to zero removes the cap.
+type UpdatePromoCodeForm struct {
+	Description  *string    `json:"description" binding:"omitempty,max=255"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+	MaxUses      *int64     `json:"maxUses" binding:"omitempty,min=0"`
+	PerUserLimit *int64     `json:"perUserLimit" binding:"omitempty,min=1"`
+	Active       *bool      `json:"active"`
+}
+
+type RedeemPromoCodeForm struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type CreateArenaForm struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	MinTrophies int32  `json:"minTrophies" binding:"min=0"`
+	HeroIDs     []uint `json:"heroIds"`
+	SpellIDs    []uint `json:"spellIds"`
+}
+
+// UpdateArenaForm changes only the fields that are sent. Sending HeroIDs or
+// SpellIDs replaces all of the arena's cards.
+type UpdateArenaForm struct {
+	Name        *string `json:"name" binding:"omitempty,max=50"`
+	MinTrophies *int32  `json:"minTrophies" binding:"omitempty,min=0"`
+	HeroIDs     []uint  `json:"heroIds"`
+	SpellIDs    []uint  `json:"spellIds"`
+}
+
+// CreateTournamentForm sets up a tournament. Rounds only applies to Swiss
+// tournaments, which otherwise play enough rounds to find a winner, and
+// RoundMinutes defaults to the server's setting. Registration opens straight
+// away unless RegistrationOpensAt is set. Only admins may add a BonusPool,
+// which makes the tournament official.
+type CreateTournamentForm struct {
+	Name                 string                `json:"name" binding:"required,max=100"`
+	Format               string                `json:"format" binding:"required,oneof=SINGLE_ELIMINATION SWISS"`
+	EntryFee             int64                 `json:"entryFee" binding:"min=0"`
+	BonusPool            int64                 `json:"bonusPool" binding:"min=0"`
+	MaxPlayers           int32                 `json:"maxPlayers" binding:"required,min=2"`
+	Rounds               int32                 `json:"rounds" binding:"min=0,max=20"`
+	RoundMinutes         int32                 `json:"roundMinutes" binding:"min=0,max=1440"`
+	RegistrationOpensAt  *time.Time            `json:"registrationOpensAt"`
+	RegistrationClosesAt time.Time             `json:"registrationClosesAt" binding:"required"`
+	Prizes               []TournamentPrizeForm `json:"prizes" binding:"dive"`
+}
+
+type TournamentPrizeForm struct {
+	Place   int32 `json:"place" binding:"required,min=1"`
+	Percent int32 `json:"percent" binding:"required,min=1,max=100"`
+}
+
+type RegisterTournamentForm struct {
+	DeckID uint `json:"deckId" binding:"required"`
+}
+
+// PartyDeckForm picks the deck a player brings to a party's 2v2 battles.
+type PartyDeckForm struct {
+	DeckID uint `json:"deckId" binding:"required"`
+}
+
+type PartyInviteForm struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// TeamQueueForm queues for a 2v2 battle. DeckID is needed when queueing
+// alone; a party plays with the decks its players joined with.
+type TeamQueueForm struct {
+	DeckID uint `json:"deckId"`
+}
+
+// DraftPickForm takes one of the two cards offered in a draft.
+type DraftPickForm struct {
+	Kind   string `json:"kind" binding:"required,oneof=hero spell"`
+	CardID uint   `json:"cardId" binding:"required"`
+}
+
+// PracticeForm starts a practice match against a bot.
+type PracticeForm struct {
+	DeckID     uint   `json:"deckId" binding:"required"`
+	Difficulty string `json:"difficulty" binding:"required,oneof=EASY NORMAL HARD"`
+}
+
+// BotMoveForm is the board the game server sends when a bot is to move, seen
+// from the bot's side: positions run from 0 at its towers to 1 at its
+// opponent's.
+type BotMoveForm struct {
+	Difficulty string         `json:"difficulty" binding:"required,oneof=EASY NORMAL HARD"`
+	Elixir     int32          `json:"elixir" binding:"min=0"`
+	HeroIDs    []uint         `json:"heroIds"`
+	Spells     []BotSpellForm `json:"spells" binding:"dive"`
+	Units      []BotUnitForm  `json:"units" binding:"dive"`
+	Towers     []BotTowerForm `json:"towers" binding:"dive"`
+}
+
+// BotSpellForm is a spell in the bot's hand. Elixir is its cost, or left out
+// for the default.
+type BotSpellForm struct {
+	SpellID uint  `json:"spellId" binding:"required"`
+	Elixir  int32 `json:"elixir" binding:"min=0"`
+}
+
+type BotUnitForm struct {
+	Enemy    bool    `json:"enemy"`
+	Lane     int     `json:"lane" binding:"min=0,max=1"`
+	Position float64 `json:"position" binding:"min=0,max=1"`
+	Hitpoint int32   `json:"hitpoint" binding:"min=0"`
+	Damage   int32   `json:"damage" binding:"min=0"`
+}
+
+type BotTowerForm struct {
+	Enemy    bool  `json:"enemy"`
+	Lane     int   `json:"lane" binding:"min=0,max=1"`
+	Hitpoint int32 `json:"hitpoint" binding:"min=0"`
+}
+
+// ReplayForm is sent by the game server to record a match: the seed it ran
+// the battle with and every card deployed, in time order. At is milliseconds
+// since the match started, Seat the player's index in the match's player
+// list and Position thousandths of the lane from the player's own towers.
+type ReplayForm struct {
+	Seed       int64             `json:"seed"`
+	DurationMs int32             `json:"durationMs" binding:"required,min=1"`
+	Inputs     []ReplayInputForm `json:"inputs" binding:"dive"`
+}
+
+type ReplayInputForm struct {
+	At       uint32 `json:"at"`
+	Seat     int    `json:"seat" binding:"min=0"`
+	Kind     string `json:"kind" binding:"required,oneof=hero spell"`
+	CardID   uint   `json:"cardId" binding:"required"`
+	Lane     int    `json:"lane" binding:"min=0,max=1"`
+	Position int    `json:"position" binding:"min=0,max=1000"`
+}
@@ -0,0 +1,52 @@
+package forms
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"reflect"
+	"testing"
+)
+
+// RegisterForm is bound straight from the request, so it must not have
+// fields for anything a player isn't allowed to choose.
+func TestRegisterFormBindsOnlyAccountFields(t *testing.T) {
+	body := []byte(`{
+		"username": "alice",
+		"email": "alice@example.com",
+		"password": "secret-password",
+		"bank": 999999,
+		"Bank": 999999,
+		"gems": 999999,
+		"awards": 5000,
+		"Awards": 5000,
+		"userType": "ADMIN",
+		"UserType": "ADMIN"
+	}`)
+
+	var form RegisterForm
+	if err := binding.JSON.BindBody(body, &form); err != nil {
+		t.Fatal(err)
+	}
+	want := RegisterForm{Username: "alice", Email: "alice@example.com", Password: "secret-password"}
+	if form != want {
+		t.Errorf("bound %+v, want %+v", form, want)
+	}
+
+	for _, field := range []string{"Bank", "Gems", "Awards", "UserType"} {
+		if _, ok := reflect.TypeOf(form).FieldByName(field); ok {
+			t.Errorf("RegisterForm has a %s field", field)
+		}
+	}
+}
+
+func TestResetPasswordFormBindsCamelCaseKeys(t *testing.T) {
+	body := []byte(`{"email": "alice@example.com", "password": "new-password", "passwordConfirm": "new-password"}`)
+
+	var form ResetPasswordForm
+	if err := binding.JSON.BindBody(body, &form); err != nil {
+		t.Fatal(err)
+	}
+	want := ResetPasswordForm{Email: "alice@example.com", Password: "new-password", PasswordConfirm: "new-password"}
+	if form != want {
+		t.Errorf("bound %+v, want %+v", form, want)
+	}
+}
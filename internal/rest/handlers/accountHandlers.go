@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+type AccountHandlers struct {
+	UserRepo     repository.UserRepo
+	GameRepo     repository.GameRepo
+	IdentityRepo repository.IdentityRepo
+	WalletRepo   repository.WalletRepo
+	PaymentRepo  repository.PaymentRepo
+	Config       config.AccountConfig
+	Clock        utils.Clock
+}
+
+func NewAccountHandlers(userRepo repository.UserRepo, gameRepo repository.GameRepo, identityRepo repository.IdentityRepo, walletRepo repository.WalletRepo, paymentRepo repository.PaymentRepo, accountConfig config.AccountConfig, clock utils.Clock) *AccountHandlers {
+	return &AccountHandlers{UserRepo: userRepo, GameRepo: gameRepo, IdentityRepo: identityRepo, WalletRepo: walletRepo, PaymentRepo: paymentRepo, Config: accountConfig, Clock: clock}
+}
+
+// exportPageSize is how many ledger entries the export reads at a time.
+const exportPageSize = 500
+
+func (h AccountHandlers) DeleteAccount(context *gin.Context) {
+	logger.GetLogger().Info("Deleting user account")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	if err := h.UserRepo.SoftDeleteUser(user.ID); err != nil {
+		logger.GetLogger().Error("Failed to delete user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to delete user"})
+		return
+	}
+
+	cookie := http.Cookie{
+		Name:     "jwt",
+		Value:    "",
+		Path:     "/app",
+		Expires:  time.Now().Add(-time.Hour),
+		HttpOnly: true,
+	}
+	http.SetCookie(context.Writer, &cookie)
+
+	purgeAt := h.Clock.Now().Add(h.Config.DeletionGrace)
+	logger.GetLogger().Info("User account scheduled for deletion")
+	context.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "User account scheduled for deletion",
+		"purgeAt": purgeAt,
+	})
+}
+
+func (h AccountHandlers) RestoreAccount(context *gin.Context) {
+	logger.GetLogger().Info("Restoring user account")
+
+	var data forms.LoginForm
+	if err := context.BindJSON(&data); err != nil {
+		logger.GetLogger().Error("Invalid restore request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, err := h.UserRepo.GetUserByUsername(data.Username)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get user by username:", err)
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication failed"})
+		return
+	}
+
+	if !utils.CheckPasswordHash(data.Password, user.Password) {
+		logger.GetLogger().Error("Authentication failed for user:", user.Email)
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication failed"})
+		return
+	}
+
+	if !user.DeletedAt.Valid {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Account is not scheduled for deletion"})
+		return
+	}
+
+	if h.Clock.Now().Sub(user.DeletedAt.Time) > h.Config.DeletionGrace {
+		logger.GetLogger().Warn("Restore attempt after grace period for user:", user.ID)
+		context.JSON(http.StatusGone, gin.H{"error": "The grace period for restoring this account has ended"})
+		return
+	}
+
+	if err := h.UserRepo.RestoreUser(user.ID); err != nil {
+		logger.GetLogger().Error("Failed to restore user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore account"})
+		return
+	}
+
+	logger.GetLogger().Info("User account restored")
+	context.JSON(http.StatusOK, gin.H{"message": "Account restored, you can sign in again"})
+}
+
+func (h AccountHandlers) ExportAccount(context *gin.Context) {
+	logger.GetLogger().Info("Exporting user data")
+
+	user, ok := loadCurrentUser(context, h.UserRepo)
+	if !ok {
+		return
+	}
+
+	export := models.AccountExport{
+		ExportedAt: h.Clock.Now(),
+		Profile: models.ExportedUser{
+			ID:        user.ID,
+			CreatedAt: user.CreatedAt,
+			Username:  user.Username,
+			Email:     user.Email,
+			Bank:      user.Bank,
+			Gems:      user.Gems,
+			Awards:    user.Awards,
+			UserType:  user.UserType,
+		},
+	}
+
+	var err error
+	if export.Heros, err = h.GameRepo.GetMyHeros(user.ID); err != nil {
+		h.exportFailed(context, err)
+		return
+	}
+	if export.Spells, err = h.GameRepo.GetMySpells(user.ID); err != nil {
+		h.exportFailed(context, err)
+		return
+	}
+	if export.Decks, err = h.GameRepo.GetDecksForUser(user.ID); err != nil {
+		h.exportFailed(context, err)
+		return
+	}
+	for i := range export.Decks {
+		if export.Decks[i].Heroes, err = h.GameRepo.GetDeckHeros(export.Decks[i].ID); err != nil {
+			h.exportFailed(context, err)
+			return
+		}
+		if export.Decks[i].Spells, err = h.GameRepo.GetDeckSpells(export.Decks[i].ID); err != nil {
+			h.exportFailed(context, err)
+			return
+		}
+	}
+	if export.Purchases, err = h.GameRepo.GetPurchaseHistory(user.ID); err != nil {
+		h.exportFailed(context, err)
+		return
+	}
+	if export.StorePurchases, err = h.PaymentRepo.GetPurchases(user.ID); err != nil {
+		h.exportFailed(context, err)
+		return
+	}
+	if export.Transactions, err = h.allTransactions(user.ID); err != nil {
+		h.exportFailed(context, err)
+		return
+	}
+	if export.Identities, err = h.IdentityRepo.GetIdentitiesForUser(user.ID); err != nil {
+		h.exportFailed(context, err)
+		return
+	}
+
+	filename := fmt.Sprintf("account-%d-%s.json", user.ID, export.ExportedAt.Format("20060102"))
+	context.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	logger.GetLogger().Info("User data exported")
+	context.JSON(http.StatusOK, export)
+}
+
+// allTransactions reads the user's whole wallet ledger, newest first.
+func (h AccountHandlers) allTransactions(userID uint) ([]models.WalletTransaction, error) {
+	var transactions []models.WalletTransaction
+	var before uint
+	for {
+		page, err := h.WalletRepo.GetTransactions(userID, before, exportPageSize)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, page...)
+		if len(page) < exportPageSize {
+			return transactions, nil
+		}
+		before = page[len(page)-1].ID
+	}
+}
+
+func (h AccountHandlers) exportFailed(context *gin.Context, err error) {
+	logger.GetLogger().Error("Failed to export user data:", err)
+	context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user data"})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/internal/rest/models"
+	"auth/pkg/middleware"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fakeGames answers the collection reads of the export with nothing; the
+// embedded interface panics on anything else.
+type fakeGames struct {
+	repository.GameRepo
+}
+
+func (fakeGames) GetMyHeros(userID uint) ([]models.Hero, error)             { return nil, nil }
+func (fakeGames) GetMySpells(userID uint) ([]models.Spell, error)           { return nil, nil }
+func (fakeGames) GetDecksForUser(userID uint) ([]models.Deck, error)        { return nil, nil }
+func (fakeGames) GetPurchaseHistory(userID uint) ([]models.Purchase, error) { return nil, nil }
+
+func TestExportAccountIncludesStorePurchasesAndLedger(t *testing.T) {
+	users := newFakeUsers()
+	payments := newFakePayments()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	h := NewAccountHandlers(users, fakeGames{}, &fakeIdentities{}, payments, payments, config.AccountConfig{}, &fakeClock{now: now})
+	router := gin.New()
+	router.GET("/export", middleware.RequireUser(users), h.ExportAccount)
+
+	alice := users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	bob := users.add(t, models.User{Username: "bob", Email: "bob@example.com"})
+	for _, purchase := range []models.StorePurchase{
+		{UserID: sql.NullInt64{Int64: int64(alice.ID), Valid: true}, Provider: "fake", TransactionID: "tx-1", ProductID: "gems_large", Gems: 1200},
+		{UserID: sql.NullInt64{Int64: int64(bob.ID), Valid: true}, Provider: "fake", TransactionID: "tx-2", ProductID: "gems_small", Gems: 80},
+	} {
+		if _, err := payments.CreditPurchase(&purchase); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// More entries than one page, so the export has to read them all.
+	for i := 0; i < exportPageSize+10; i++ {
+		payments.spend(alice.ID, 1)
+	}
+
+	status, body := call(t, router, http.MethodGet, "/export", tokenFor(t, alice), nil)
+	if status != http.StatusOK {
+		t.Fatalf("export answered %d: %v", status, body)
+	}
+
+	purchases, _ := body["StorePurchases"].([]interface{})
+	if len(purchases) != 1 || purchases[0].(map[string]interface{})["TransactionID"] != "tx-1" {
+		t.Errorf("StorePurchases = %v, want only alice's tx-1", body["StorePurchases"])
+	}
+
+	transactions, _ := body["Transactions"].([]interface{})
+	if len(transactions) != exportPageSize+11 {
+		t.Fatalf("exported %d ledger entries, want %d", len(transactions), exportPageSize+11)
+	}
+	oldest := transactions[len(transactions)-1].(map[string]interface{})
+	if oldest["Reason"] != models.ReasonStore || oldest["Amount"] != float64(1200) {
+		t.Errorf("oldest ledger entry is %v, want the store purchase", oldest)
+	}
+	for _, transaction := range transactions {
+		if userID := transaction.(map[string]interface{})["UserID"]; userID != float64(alice.ID) {
+			t.Fatalf("export has a ledger entry of user %v", userID)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type ArenaHandlers struct {
+	ArenaRepo repository.ArenaRepo
+	GameRepo  repository.GameRepo
+	UserRepo  repository.UserRepo
+}
+
+func NewArenaHandlers(arenaRepo repository.ArenaRepo, gameRepo repository.GameRepo, userRepo repository.UserRepo) *ArenaHandlers {
+	return &ArenaHandlers{ArenaRepo: arenaRepo, GameRepo: gameRepo, UserRepo: userRepo}
+}
+
+// ListArenas returns every arena with the cards it unlocks, and the arena the
+// player is in, which is the highest one their trophies reach.
+func (h ArenaHandlers) ListArenas(context *gin.Context) {
+	logger.GetLogger().Info("Fetching arenas")
+
+	user, ok := loadCurrentUser(context, h.UserRepo)
+	if !ok {
+		return
+	}
+
+	arenas, err := h.ArenaRepo.GetArenas()
+	if err != nil {
+		logger.GetLogger().Error("Failed to get arenas:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	response := gin.H{"arenas": dto.NewArenas(arenas), "trophies": user.Awards}
+	for _, arena := range arenas {
+		if arena.MinTrophies <= user.Awards {
+			response["currentArenaId"] = arena.ID
+		}
+	}
+	context.JSON(http.StatusOK, response)
+}
+
+// arenaCards checks that the cards exist and answers 400 itself when one
+// doesn't.
+func (h ArenaHandlers) arenaCards(context *gin.Context, heroIDs, spellIDs []uint) ([]models.ArenaCard, bool) {
+	var cards []models.ArenaCard
+	for _, heroID := range heroIDs {
+		if _, err := h.GameRepo.GetHeroByID(heroID); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Hero not found"})
+			return nil, false
+		}
+		cards = append(cards, models.ArenaCard{HeroID: sql.NullInt64{Int64: int64(heroID), Valid: true}})
+	}
+	for _, spellID := range spellIDs {
+		if _, err := h.GameRepo.GetSpellByID(spellID); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Spell not found"})
+			return nil, false
+		}
+		cards = append(cards, models.ArenaCard{SpellID: sql.NullInt64{Int64: int64(spellID), Valid: true}})
+	}
+	return cards, true
+}
+
+func writeArenaError(context *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, helper.ErrArenaTaken):
+		context.JSON(http.StatusConflict, gin.H{"error": "Another arena has this name or trophy threshold"})
+	case errors.Is(err, helper.ErrCardInArena):
+		context.JSON(http.StatusConflict, gin.H{"error": "A card already belongs to another arena"})
+	default:
+		logger.GetLogger().Error(message+":", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+func (h ArenaHandlers) CreateArena(context *gin.Context) {
+	logger.GetLogger().Info("Creating arena")
+
+	var form forms.CreateArenaForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid arena:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	cards, ok := h.arenaCards(context, form.HeroIDs, form.SpellIDs)
+	if !ok {
+		return
+	}
+
+	arena := models.Arena{Name: strings.TrimSpace(form.Name), MinTrophies: form.MinTrophies, Cards: cards}
+	if err := h.ArenaRepo.CreateArena(&arena); err != nil {
+		writeArenaError(context, err, "Failed to create arena")
+		return
+	}
+
+	created, err := h.ArenaRepo.GetArena(arena.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get arena:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	context.JSON(http.StatusCreated, gin.H{"arena": dto.NewArena(created)})
+}
+
+func (h ArenaHandlers) UpdateArena(context *gin.Context) {
+	logger.GetLogger().Info("Updating arena")
+
+	var form forms.UpdateArenaForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid arena update:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	arenaID, ok := parseID(context, "id", "arena")
+	if !ok {
+		return
+	}
+
+	arena, err := h.ArenaRepo.GetArena(arenaID)
+	if err != nil {
+		if errors.Is(err, helper.ErrArenaNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Arena not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get arena:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if form.Name != nil {
+		arena.Name = strings.TrimSpace(*form.Name)
+	}
+	if form.MinTrophies != nil {
+		arena.MinTrophies = *form.MinTrophies
+	}
+	replaceCards := form.HeroIDs != nil || form.SpellIDs != nil
+	if replaceCards {
+		if arena.Cards, ok = h.arenaCards(context, form.HeroIDs, form.SpellIDs); !ok {
+			return
+		}
+	}
+
+	if err := h.ArenaRepo.UpdateArena(arena, replaceCards); err != nil {
+		writeArenaError(context, err, "Failed to update arena")
+		return
+	}
+
+	updated, err := h.ArenaRepo.GetArena(arena.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get arena:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"arena": dto.NewArena(updated)})
+}
@@ -4,12 +4,15 @@ import (
 	"auth/internal/config"
 	redis "auth/internal/db/redis"
 	"auth/internal/repository"
+	"auth/internal/rest/dto"
 	"auth/internal/rest/forms"
 	"auth/internal/rest/models"
 	"auth/pkg/email"
 	"auth/pkg/logger"
+	"auth/pkg/oauth"
+	"auth/pkg/rest/helper"
 	"auth/pkg/utils"
-	"fmt"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
@@ -17,29 +20,53 @@ import (
 )
 
 type AuthHandlers struct {
-	Repo        repository.UserRepo
-	RedisConfig config.RedisConfig
-	Email       config.EmailConfig
+	Repo          repository.UserRepo
+	TwoFactorRepo repository.TwoFactorRepo
+	IdentityRepo  repository.IdentityRepo
+	Providers     map[string]oauth.Provider
+	RedisConfig   config.RedisConfig
+	Email         config.EmailConfig
+	Account       config.AccountConfig
+	Clock         utils.Clock
 }
 
-func NewAuthHandlers(repo repository.UserRepo, redisConfig config.RedisConfig, email config.EmailConfig) *AuthHandlers {
-	return &AuthHandlers{Repo: repo, RedisConfig: redisConfig, Email: email}
+func NewAuthHandlers(repo repository.UserRepo, twoFactorRepo repository.TwoFactorRepo, identityRepo repository.IdentityRepo, providers []oauth.Provider, redisConfig config.RedisConfig, email config.EmailConfig, accountConfig config.AccountConfig, clock utils.Clock) *AuthHandlers {
+	providersByName := make(map[string]oauth.Provider, len(providers))
+	for _, provider := range providers {
+		providersByName[provider.Name()] = provider
+	}
+	return &AuthHandlers{
+		Repo:          repo,
+		TwoFactorRepo: twoFactorRepo,
+		IdentityRepo:  identityRepo,
+		Providers:     providersByName,
+		RedisConfig:   redisConfig,
+		Email:         email,
+		Account:       accountConfig,
+		Clock:         clock,
+	}
 }
 
 func (h AuthHandlers) Register(context *gin.Context) {
 	logger.GetLogger().Info("Starting user registration")
 
-	var user models.User
-	if err := context.BindJSON(&user); err != nil {
+	var form forms.RegisterForm
+	if err := context.ShouldBindJSON(&form); err != nil {
 		logger.GetLogger().Error("Invalid registration request:", err)
 		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
+	user := models.User{
+		Username: form.Username,
+		Email:    form.Email,
+		Password: form.Password,
+	}
+
 	_, err := h.Repo.GetUserByEmail(user.Email)
 	if err == nil {
 		logger.GetLogger().Error("Account already registered for email:", user.Email)
-		context.JSON(http.StatusInternalServerError, gin.H{"error": "The account is already registered"})
+		context.JSON(http.StatusConflict, gin.H{"error": "The account is already registered"})
 		return
 	}
 	if user.Password == "qwerty123" && user.Email == "musabecova05@gmail.com" {
@@ -47,18 +74,25 @@ func (h AuthHandlers) Register(context *gin.Context) {
 	} else {
 		user.UserType = "USER"
 	}
-	fmt.Println(user.UserType)
 	hashedPassword, _ := utils.HashPassword(user.Password)
 	user.Password = hashedPassword
 
 	user.Bank = 10000
-	if err := h.Repo.CreateUser(&user); err != nil {
+	if err := h.Repo.CreateUser(&user); errors.Is(err, helper.ErrUsernameTaken) {
+		logger.GetLogger().Warn("Username already taken:", user.Username)
+		context.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	} else if errors.Is(err, helper.ErrEmailTaken) {
+		logger.GetLogger().Warn("Account already registered for email:", user.Email)
+		context.JSON(http.StatusConflict, gin.H{"error": "The account is already registered"})
+		return
+	} else if err != nil {
 		logger.GetLogger().Error("Failed to create user:", err)
 		context.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	signedToken, _ := utils.CreateToken(strconv.Itoa(int(user.ID)), user.Email, user.UserType)
+	signedToken, _ := utils.CreateToken(strconv.Itoa(int(user.ID)), user.Email, user.UserType, user.TokenVersion)
 	cookie := http.Cookie{
 		Name:     "jwt",
 		Value:    signedToken,
@@ -101,12 +135,58 @@ func (h AuthHandlers) Login(context *gin.Context) {
 		user.UserType = "USER"
 	}
 
-	token, err := utils.CreateToken(strconv.Itoa(int(user.ID)), user.Email, user.UserType)
+	h.completeLogin(context, user)
+}
+
+// completeLogin either issues the JWT or, when the account has 2FA enabled,
+// answers with a challenge that must be redeemed at /auth/2fa/verify.
+func (h AuthHandlers) completeLogin(context *gin.Context, user *models.User) {
+	if user.DeletedAt.Valid {
+		logger.GetLogger().Warn("Login attempt for account scheduled for deletion:", user.ID)
+		context.JSON(http.StatusForbidden, gin.H{"error": "Account is scheduled for deletion, restore it to sign in"})
+		return
+	}
+
+	twoFactor, err := h.TwoFactorRepo.GetTwoFactor(user.ID)
+	if err != nil && !errors.Is(err, helper.ErrTwoFactorNotFound) {
+		logger.GetLogger().Error("Failed to get two-factor:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if err == nil && twoFactor.Enabled {
+		challenge, err := utils.GenerateChallengeToken()
+		if err != nil {
+			logger.GetLogger().Error("Failed to generate two-factor challenge:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor login"})
+			return
+		}
+		if err := redis.SaveTwoFactorChallenge(context, h.RedisConfig, challenge, user.ID); err != nil {
+			logger.GetLogger().Error("Failed to save two-factor challenge to Redis:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor login"})
+			return
+		}
+
+		logger.GetLogger().Info("Password accepted, waiting for second factor")
+		context.JSON(http.StatusOK, gin.H{"twoFactorRequired": true, "challenge": challenge})
+		return
+	}
+
+	token, err := h.issueToken(context, user)
 	if err != nil {
 		logger.GetLogger().Error("Failed to create token:", err)
 		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token", "data": token})
 		return
 	}
+
+	logger.GetLogger().Info("User login successful")
+	context.JSON(http.StatusOK, gin.H{"token": token})
+}
+
+func (h AuthHandlers) issueToken(context *gin.Context, user *models.User) (string, error) {
+	token, err := utils.CreateToken(strconv.Itoa(int(user.ID)), user.Email, user.UserType, user.TokenVersion)
+	if err != nil {
+		return "", err
+	}
 	cookie := http.Cookie{
 		Name:     "jwt",
 		Value:    token,
@@ -115,37 +195,19 @@ func (h AuthHandlers) Login(context *gin.Context) {
 		HttpOnly: true,
 	}
 	http.SetCookie(context.Writer, &cookie)
-
-	logger.GetLogger().Info("User login successful")
-	context.JSON(http.StatusOK, gin.H{"token": token})
+	return token, nil
 }
 
 func (h AuthHandlers) Profile(context *gin.Context) {
 	logger.GetLogger().Info("Fetching user profile")
 
-	username, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
-		return
-	}
-
-	emailm, ok := username.(string)
+	user, ok := loadCurrentUser(context, h.Repo)
 	if !ok {
-		logger.GetLogger().Error("Error while retrieving user ID")
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Error while retrieving user ID"})
-		return
-	}
-
-	user, err := h.Repo.GetUserByEmail(emailm)
-	if err != nil {
-		logger.GetLogger().Error("User does not exist:", err)
-		context.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "User does not exist", "data": err})
 		return
 	}
 
 	logger.GetLogger().Info("User profile fetched successfully")
-	context.JSON(http.StatusOK, gin.H{"status": "success", "message": "User profile fetched successfully", "data": user})
+	context.JSON(http.StatusOK, gin.H{"status": "success", "message": "User profile fetched successfully", "data": dto.NewUser(user)})
 }
 
 func (h AuthHandlers) Logout(context *gin.Context) {
@@ -201,50 +263,6 @@ func (h AuthHandlers) ForgotPassword(context *gin.Context) {
 	context.JSON(http.StatusOK, gin.H{"message": "Verification code sent to your email"})
 }
 
-func (h AuthHandlers) DeleteAccount(context *gin.Context) {
-	logger.GetLogger().Info("Deleting user account")
-
-	email, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
-		return
-	}
-
-	emailm, ok := email.(string)
-	if !ok {
-		logger.GetLogger().Error("Error while retrieving user ID")
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Error while retrieving user ID"})
-		return
-	}
-
-	user, err := h.Repo.GetUserByEmail(emailm)
-	if err != nil {
-		logger.GetLogger().Error("User not found:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "User not found"})
-		return
-	}
-
-	err = h.Repo.DeleteUser(user.ID)
-	if err != nil {
-		logger.GetLogger().Error("Failed to delete user:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to delete user"})
-		return
-	}
-
-	cookie := http.Cookie{
-		Name:     "jwt",
-		Value:    "",
-		Path:     "/app",
-		Expires:  time.Now().Add(-time.Hour),
-		HttpOnly: true,
-	}
-	http.SetCookie(context.Writer, &cookie)
-
-	logger.GetLogger().Info("User account deleted successfully")
-	context.JSON(http.StatusOK, gin.H{"status": "success", "message": "User account deleted successfully"})
-}
-
 func (h AuthHandlers) ResetPassword(context *gin.Context) {
 	logger.GetLogger().Info("Resetting user password")
 
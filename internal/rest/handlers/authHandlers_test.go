@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/db/redis/redistest"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRegisterIgnoresPrivilegedFields(t *testing.T) {
+	_, redisConfig := redistest.Start(t)
+	users := newFakeUsers()
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	h := NewAuthHandlers(users, newFakeTwoFactor(), &fakeIdentities{}, nil, redisConfig, config.EmailConfig{}, config.AccountConfig{}, clock)
+	router := gin.New()
+	router.POST("/auth/register", h.Register)
+
+	status, body := call(t, router, http.MethodPost, "/auth/register", "", gin.H{
+		"username": "alice",
+		"email":    "alice@example.com",
+		"password": "secret-password",
+		"bank":     999999,
+		"awards":   5000,
+		"userType": "ADMIN",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("register answered %d: %v", status, body)
+	}
+
+	user, err := users.GetUserByEmail("alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.UserType != "USER" || user.Bank != 10000 || user.Awards != 0 {
+		t.Errorf("registered %s with %d gold and %d trophies", user.UserType, user.Bank, user.Awards)
+	}
+}
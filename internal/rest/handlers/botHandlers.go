@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"auth/internal/bots"
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"math/rand"
+	"net/http"
+)
+
+// BotHandlers start practice matches against bots and tell the game server
+// how a bot moves.
+type BotHandlers struct {
+	MatchRepo repository.MatchRepo
+	GameRepo  repository.GameRepo
+	Config    config.BotConfig
+	Clock     utils.Clock
+}
+
+func NewBotHandlers(matchRepo repository.MatchRepo, gameRepo repository.GameRepo, botConfig config.BotConfig, clock utils.Clock) *BotHandlers {
+	return &BotHandlers{
+		MatchRepo: matchRepo,
+		GameRepo:  gameRepo,
+		Config:    botConfig,
+		Clock:     clock,
+	}
+}
+
+func (h BotHandlers) random() *rand.Rand {
+	return rand.New(rand.NewSource(h.Clock.Now().UnixNano()))
+}
+
+// StartPractice starts an unranked match between the player, on team 1, and
+// a bot of the chosen difficulty on team 2.
+func (h BotHandlers) StartPractice(context *gin.Context) {
+	logger.GetLogger().Info("Starting practice match")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.PracticeForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid practice match:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	deck, err := snapshotDeck(h.GameRepo, user.ID, form.DeckID)
+	if err != nil {
+		logger.GetLogger().Warn("Deck can't be used for a match:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Choose one of your decks with at least one card"})
+		return
+	}
+
+	heroes, spells, err := h.GameRepo.GetCatalog()
+	if err != nil {
+		logger.GetLogger().Error("Failed to get catalog:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	botDeck := bots.BuildDeck(heroes, spells, form.Difficulty, h.Config.DeckSize, h.random())
+	if len(botDeck.Heroes) == 0 {
+		context.JSON(http.StatusServiceUnavailable, gin.H{"error": "Practice is unavailable, the catalog has no heroes"})
+		return
+	}
+
+	match := models.Match{
+		Mode:   models.MatchModePractice,
+		Ranked: false,
+		Players: []models.MatchPlayer{
+			{UserID: user.ID, Username: user.Username, Team: 1, Deck: deck},
+			{Username: "Bot", Team: 2, Bot: form.Difficulty, Deck: botDeck},
+		},
+	}
+	if err := h.MatchRepo.CreateMatch(&match); err != nil {
+		logger.GetLogger().Error("Failed to start practice match:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start match"})
+		return
+	}
+
+	logger.GetLogger().Info("Practice match started")
+	context.JSON(http.StatusCreated, gin.H{"match": dto.NewMatch(match)})
+}
+
+// Move decides a bot's next play from the board the game server sends. It
+// is called by the game server, which authenticates as an admin, whenever a
+// bot may act; a null play means the bot waits for elixir.
+func (h BotHandlers) Move(context *gin.Context) {
+	logger.GetLogger().Info("Deciding bot move")
+
+	var form forms.BotMoveForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid bot board:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	board := bots.Board{Elixir: form.Elixir, SpellElixir: make(map[uint]int32)}
+	for _, id := range form.HeroIDs {
+		hero, err := h.GameRepo.GetHeroByID(id)
+		if err != nil {
+			logger.GetLogger().Error("Failed to get bot hero:", err)
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Unknown hero in hand"})
+			return
+		}
+		board.Heroes = append(board.Heroes, hero)
+	}
+	for _, card := range form.Spells {
+		spell, err := h.GameRepo.GetSpellByID(card.SpellID)
+		if err != nil {
+			logger.GetLogger().Error("Failed to get bot spell:", err)
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Unknown spell in hand"})
+			return
+		}
+		board.Spells = append(board.Spells, spell)
+		if card.Elixir > 0 {
+			board.SpellElixir[spell.ID] = card.Elixir
+		}
+	}
+	for _, unit := range form.Units {
+		board.Units = append(board.Units, bots.Unit{
+			Enemy:    unit.Enemy,
+			Lane:     unit.Lane,
+			Position: unit.Position,
+			Hitpoint: unit.Hitpoint,
+			Damage:   unit.Damage,
+		})
+	}
+	for _, tower := range form.Towers {
+		board.Towers = append(board.Towers, bots.Tower{Enemy: tower.Enemy, Lane: tower.Lane, Hitpoint: tower.Hitpoint})
+	}
+
+	play, ok := bots.Decide(board, form.Difficulty, h.random())
+	if !ok {
+		context.JSON(http.StatusOK, gin.H{"play": nil})
+		return
+	}
+	context.JSON(http.StatusOK, gin.H{"play": dto.NewBotPlay(play)})
+}
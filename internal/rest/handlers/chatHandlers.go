@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	redis "auth/internal/db/redis"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// chatHeartbeat keeps idle streams from being closed by proxies.
+const chatHeartbeat = 30 * time.Second
+
+const (
+	chatEventMessage = "message"
+	chatEventHidden  = "hidden"
+)
+
+type ChatHandlers struct {
+	ClanRepo    repository.ClanRepo
+	FriendRepo  repository.FriendRepo
+	ChatRepo    repository.ChatRepo
+	Config      config.ChatConfig
+	RedisConfig config.RedisConfig
+	Clock       utils.Clock
+	Filter      *utils.ProfanityFilter
+}
+
+func NewChatHandlers(clanRepo repository.ClanRepo, friendRepo repository.FriendRepo, chatRepo repository.ChatRepo, chatConfig config.ChatConfig, redisConfig config.RedisConfig, clock utils.Clock) *ChatHandlers {
+	return &ChatHandlers{
+		ClanRepo:    clanRepo,
+		FriendRepo:  friendRepo,
+		ChatRepo:    chatRepo,
+		Config:      chatConfig,
+		RedisConfig: redisConfig,
+		Clock:       clock,
+		Filter:      utils.NewProfanityFilter(chatConfig.BannedWords),
+	}
+}
+
+func (h ChatHandlers) GetClanHistory(context *gin.Context) {
+	logger.GetLogger().Info("Fetching clan chat")
+
+	user, ok := currentUser(context)
+	if !ok || !h.canChat(context, user.ID, false) {
+		return
+	}
+
+	membership, ok := h.clanMembership(context, user.ID)
+	if !ok {
+		return
+	}
+
+	before, limit, ok := parseCursor(context, h.Config.HistoryPageSize)
+	if !ok {
+		return
+	}
+
+	messages, err := h.ChatRepo.GetClanMessages(membership.ClanID, before, limit)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get clan chat:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, dto.NewChatPage(messages, limit))
+}
+
+func (h ChatHandlers) SendClanMessage(context *gin.Context) {
+	logger.GetLogger().Info("Sending clan chat message")
+
+	var form forms.ChatMessageForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid chat message:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok || !h.canChat(context, user.ID, true) {
+		return
+	}
+
+	membership, ok := h.clanMembership(context, user.ID)
+	if !ok {
+		return
+	}
+
+	body, ok := h.messageBody(context, form.Body)
+	if !ok {
+		return
+	}
+
+	message := models.ChatMessage{
+		Channel:    models.ChatChannelClan,
+		ClanID:     sql.NullInt64{Int64: int64(membership.ClanID), Valid: true},
+		SenderID:   user.ID,
+		SenderName: user.Username,
+		Body:       body,
+	}
+	if err := h.ChatRepo.CreateMessage(&message); err != nil {
+		logger.GetLogger().Error("Failed to save clan chat message:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+
+	result := dto.NewChatMessage(message)
+	publishChatEvent(context, h.RedisConfig, dto.ChatEvent{Type: chatEventMessage, Message: &result}, redis.ClanChatChannel(membership.ClanID))
+	context.JSON(http.StatusCreated, gin.H{"message": result})
+}
+
+func (h ChatHandlers) GetDirectHistory(context *gin.Context) {
+	logger.GetLogger().Info("Fetching direct messages")
+
+	user, ok := currentUser(context)
+	if !ok || !h.canChat(context, user.ID, false) {
+		return
+	}
+
+	otherID, ok := parseID(context, "userID", "user")
+	if !ok {
+		return
+	}
+
+	before, limit, ok := parseCursor(context, h.Config.HistoryPageSize)
+	if !ok {
+		return
+	}
+
+	messages, err := h.ChatRepo.GetDirectMessages(user.ID, otherID, before, limit)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get direct messages:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, dto.NewChatPage(messages, limit))
+}
+
+// SendDirectMessage only delivers between friends, and never across a block
+// in either direction.
+func (h ChatHandlers) SendDirectMessage(context *gin.Context) {
+	logger.GetLogger().Info("Sending direct message")
+
+	var form forms.ChatMessageForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid chat message:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok || !h.canChat(context, user.ID, true) {
+		return
+	}
+
+	recipientID, ok := parseID(context, "userID", "user")
+	if !ok {
+		return
+	}
+	if recipientID == user.ID {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "You can't message yourself"})
+		return
+	}
+
+	blocked, err := h.FriendRepo.IsBlocked(user.ID, recipientID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to check block:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	friends, err := h.FriendRepo.AreFriends(user.ID, recipientID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to check friendship:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if blocked || !friends {
+		context.JSON(http.StatusForbidden, gin.H{"error": "You can only message your friends"})
+		return
+	}
+
+	body, ok := h.messageBody(context, form.Body)
+	if !ok {
+		return
+	}
+
+	message := models.ChatMessage{
+		Channel:     models.ChatChannelDirect,
+		SenderID:    user.ID,
+		SenderName:  user.Username,
+		RecipientID: sql.NullInt64{Int64: int64(recipientID), Valid: true},
+		Body:        body,
+	}
+	if err := h.ChatRepo.CreateMessage(&message); err != nil {
+		logger.GetLogger().Error("Failed to save direct message:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+
+	result := dto.NewChatMessage(message)
+	publishChatEvent(context, h.RedisConfig, dto.ChatEvent{Type: chatEventMessage, Message: &result},
+		redis.UserChatChannel(user.ID), redis.UserChatChannel(recipientID))
+	context.JSON(http.StatusCreated, gin.H{"message": result})
+}
+
+// Stream is the live connection for a signed-in player: it pushes their
+// notifications, their draft's picks and new clan and direct messages as
+// server-sent events until the client disconnects. The clan is the one the
+// player is in when the stream opens, so clients reconnect after joining or
+// leaving a clan. Players banned from chat still get their notifications.
+func (h ChatHandlers) Stream(context *gin.Context) {
+	logger.GetLogger().Info("Opening event stream")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	channels := []string{redis.NotificationChannel(user.ID), redis.BroadcastNotificationChannel, redis.DraftChannel(user.ID)}
+	_, banned, err := h.activeSanction(user.ID, false)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get chat sanctions:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if !banned {
+		channels = append(channels, redis.UserChatChannel(user.ID))
+		membership, err := h.ClanRepo.GetMembership(user.ID)
+		if err == nil {
+			channels = append(channels, redis.ClanChatChannel(membership.ClanID))
+		} else if !errors.Is(err, helper.ErrNotInClan) {
+			logger.GetLogger().Error("Failed to get clan membership:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+	}
+
+	subscription, err := redis.Subscribe(context.Request.Context(), h.RedisConfig, channels...)
+	if err != nil {
+		logger.GetLogger().Error("Failed to subscribe to events:", err)
+		context.JSON(http.StatusServiceUnavailable, gin.H{"error": "Live updates are unavailable, poll instead"})
+		return
+	}
+	defer subscription.Close()
+
+	events := subscription.Events()
+	heartbeat := time.NewTicker(chatHeartbeat)
+	defer heartbeat.Stop()
+
+	context.Stream(func(w io.Writer) bool {
+		select {
+		case <-context.Request.Context().Done():
+			return false
+		case event, open := <-events:
+			if !open {
+				return false
+			}
+			name := "chat"
+			if strings.HasPrefix(event.Channel, "notifications:") {
+				name = "notification"
+			} else if strings.HasPrefix(event.Channel, "draft:") {
+				name = "draft"
+			}
+			context.SSEvent(name, event.Payload)
+			return true
+		case <-heartbeat.C:
+			context.SSEvent("ping", "")
+			return true
+		}
+	})
+}
+
+func (h ChatHandlers) ReportMessage(context *gin.Context) {
+	logger.GetLogger().Info("Reporting chat message")
+
+	var form forms.ReportMessageForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid report request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	messageID, ok := parseID(context, "id", "message")
+	if !ok {
+		return
+	}
+
+	message, err := h.ChatRepo.GetMessage(messageID)
+	if err != nil && !errors.Is(err, helper.ErrMessageNotFound) {
+		logger.GetLogger().Error("Failed to get chat message:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if err != nil || message.HiddenAt.Valid || !h.canSee(user.ID, message) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if message.SenderID == user.ID {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "You can't report your own message"})
+		return
+	}
+
+	report := models.ChatReport{MessageID: message.ID, ReporterID: user.ID, Reason: strings.TrimSpace(form.Reason)}
+	if err := h.ChatRepo.CreateReport(&report); err != nil {
+		if errors.Is(err, helper.ErrAlreadyReported) {
+			context.JSON(http.StatusConflict, gin.H{"error": "You already reported this message"})
+			return
+		}
+		logger.GetLogger().Error("Failed to report chat message:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report message"})
+		return
+	}
+
+	logger.GetLogger().Info("Chat message reported")
+	context.JSON(http.StatusCreated, gin.H{"message": "Message reported", "reportId": report.ID})
+}
+
+// canChat answers 403 when the player is banned from chat, or muted and
+// trying to send.
+func (h ChatHandlers) canChat(context *gin.Context, userID uint, sending bool) bool {
+	sanction, found, err := h.activeSanction(userID, sending)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get chat sanctions:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return false
+	}
+	if !found {
+		return true
+	}
+
+	response := gin.H{"error": "You are muted", "reason": sanction.Reason}
+	if sanction.Kind == models.SanctionBan {
+		response["error"] = "You are banned from chat"
+	}
+	if sanction.ExpiresAt.Valid {
+		response["until"] = sanction.ExpiresAt.Time
+	}
+	context.JSON(http.StatusForbidden, response)
+	return false
+}
+
+// activeSanction finds a ban, or when sending also a mute, in force for the
+// player.
+func (h ChatHandlers) activeSanction(userID uint, sending bool) (models.ChatSanction, bool, error) {
+	sanctions, err := h.ChatRepo.GetActiveSanctions(userID, h.Clock.Now())
+	if err != nil {
+		return models.ChatSanction{}, false, err
+	}
+	for _, sanction := range sanctions {
+		if sanction.Kind == models.SanctionBan || (sanction.Kind == models.SanctionMute && sending) {
+			return sanction, true, nil
+		}
+	}
+	return models.ChatSanction{}, false, nil
+}
+
+func (h ChatHandlers) clanMembership(context *gin.Context, userID uint) (models.ClanMember, bool) {
+	membership, err := h.ClanRepo.GetMembership(userID)
+	if err != nil {
+		if errors.Is(err, helper.ErrNotInClan) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "You are not in a clan"})
+			return models.ClanMember{}, false
+		}
+		logger.GetLogger().Error("Failed to get clan membership:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return models.ClanMember{}, false
+	}
+	return membership, true
+}
+
+// messageBody trims, length-checks and filters a message before it is stored.
+func (h ChatHandlers) messageBody(context *gin.Context, body string) (string, bool) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Message is empty"})
+		return "", false
+	}
+	if h.Config.MaxMessageLength > 0 && utf8.RuneCountInString(body) > h.Config.MaxMessageLength {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Message is too long", "maxLength": h.Config.MaxMessageLength})
+		return "", false
+	}
+	return h.Filter.Clean(body), true
+}
+
+// canSee reports whether the message was sent to a channel the user reads.
+func (h ChatHandlers) canSee(userID uint, message models.ChatMessage) bool {
+	if message.RecipientID.Valid {
+		return message.SenderID == userID || uint(message.RecipientID.Int64) == userID
+	}
+	membership, err := h.ClanRepo.GetMembership(userID)
+	return err == nil && message.ClanID.Valid && uint(message.ClanID.Int64) == membership.ClanID
+}
+
+// publishChatEvent delivers an event to live streams. The change is already
+// stored, so a failure here only delays it until the next history fetch.
+func publishChatEvent(context *gin.Context, redisConfig config.RedisConfig, event dto.ChatEvent, channels ...string) {
+	for _, channel := range channels {
+		if err := redis.PublishEvent(context, redisConfig, channel, event); err != nil {
+			logger.GetLogger().Warn("Failed to publish chat event:", err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ChestHandlers struct {
+	ChestRepo repository.ChestRepo
+	Config    config.ChestConfig
+	Clock     utils.Clock
+}
+
+func NewChestHandlers(chestRepo repository.ChestRepo, chestConfig config.ChestConfig, clock utils.Clock) *ChestHandlers {
+	return &ChestHandlers{ChestRepo: chestRepo, Config: chestConfig, Clock: clock}
+}
+
+// ListChests returns the player's unopened chests with what opening each one
+// right now would cost in gems.
+func (h ChestHandlers) ListChests(context *gin.Context) {
+	logger.GetLogger().Info("Fetching chests")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	chests, err := h.ChestRepo.GetChests(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get chests:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"chests": dto.NewChests(chests, h.Clock.Now(), h.Config.SpeedUpGemsPerHour)})
+}
+
+// BuyChest sells a chest for gems and opens it at once.
+func (h ChestHandlers) BuyChest(context *gin.Context) {
+	logger.GetLogger().Info("Buying chest")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.BuyChestForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid chest purchase:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	reward, err := h.ChestRepo.BuyChest(user.ID, form.Kind, h.Clock.Now())
+	if err != nil {
+		writeChestError(context, err)
+		return
+	}
+
+	logger.GetLogger().Info("Chest bought")
+	context.JSON(http.StatusOK, gin.H{"reward": dto.NewChestReward(reward)})
+}
+
+// UnlockChest starts the chest's unlock timer.
+func (h ChestHandlers) UnlockChest(context *gin.Context) {
+	logger.GetLogger().Info("Unlocking chest")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	chestID, ok := parseID(context, "id", "chest")
+	if !ok {
+		return
+	}
+
+	now := h.Clock.Now()
+	chest, err := h.ChestRepo.StartUnlock(user.ID, chestID, now)
+	if err != nil {
+		writeChestError(context, err)
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"chest": dto.NewChest(chest, now, h.Config.SpeedUpGemsPerHour)})
+}
+
+// OpenChest opens a chest whose timer has run out.
+func (h ChestHandlers) OpenChest(context *gin.Context) {
+	logger.GetLogger().Info("Opening chest")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	chestID, ok := parseID(context, "id", "chest")
+	if !ok {
+		return
+	}
+
+	reward, err := h.ChestRepo.OpenChest(user.ID, chestID, h.Clock.Now())
+	if err != nil {
+		writeChestError(context, err)
+		return
+	}
+
+	logger.GetLogger().Info("Chest opened")
+	context.JSON(http.StatusOK, gin.H{"reward": dto.NewChestReward(reward)})
+}
+
+// SpeedUpChest pays gems for the time left on a chest and opens it.
+func (h ChestHandlers) SpeedUpChest(context *gin.Context) {
+	logger.GetLogger().Info("Speeding up chest")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	chestID, ok := parseID(context, "id", "chest")
+	if !ok {
+		return
+	}
+
+	reward, err := h.ChestRepo.SpeedUpChest(user.ID, chestID, h.Config.SpeedUpGemsPerHour, h.Clock.Now())
+	if err != nil {
+		writeChestError(context, err)
+		return
+	}
+
+	logger.GetLogger().Info("Chest sped up")
+	context.JSON(http.StatusOK, gin.H{"reward": dto.NewChestReward(reward)})
+}
+
+func writeChestError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, helper.ErrChestNotFound):
+		context.JSON(http.StatusNotFound, gin.H{"error": "Chest not found"})
+	case errors.Is(err, helper.ErrChestOpened):
+		context.JSON(http.StatusConflict, gin.H{"error": "Chest already opened"})
+	case errors.Is(err, helper.ErrChestLocked):
+		context.JSON(http.StatusConflict, gin.H{"error": "Chest is still locked"})
+	case errors.Is(err, helper.ErrChestUnlocking):
+		context.JSON(http.StatusConflict, gin.H{"error": "Another chest is already unlocking"})
+	case errors.Is(err, helper.ErrInsufficientGems):
+		context.JSON(http.StatusForbidden, gin.H{"error": "Not enough gems"})
+	default:
+		logger.GetLogger().Error("Failed to handle chest:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/internal/rest/models"
+	"auth/pkg/middleware"
+	"auth/pkg/rest/helper"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fakeChests mirrors ChestRepository for speed-ups: the cost comes from the
+// chest's timer and is taken from the gems, which must cover it. The
+// embedded interface panics on anything else.
+type fakeChests struct {
+	repository.ChestRepo
+	chests map[uint]models.Chest
+	gems   map[uint]int64
+}
+
+func (f *fakeChests) GetChests(userID uint) ([]models.Chest, error) {
+	var chests []models.Chest
+	for _, chest := range f.chests {
+		if chest.UserID == userID && !chest.OpenedAt.Valid {
+			chests = append(chests, chest)
+		}
+	}
+	return chests, nil
+}
+
+func (f *fakeChests) SpeedUpChest(userID, chestID uint, gemsPerHour int64, now time.Time) (models.ChestReward, error) {
+	chest, ok := f.chests[chestID]
+	if !ok || chest.UserID != userID {
+		return models.ChestReward{}, helper.ErrChestNotFound
+	}
+	if chest.OpenedAt.Valid {
+		return models.ChestReward{}, helper.ErrChestOpened
+	}
+	cost := chest.SpeedUpCost(now, gemsPerHour)
+	if f.gems[userID] < cost {
+		return models.ChestReward{}, helper.ErrInsufficientGems
+	}
+	f.gems[userID] -= cost
+	chest.OpenedAt = pq.NullTime{Time: now, Valid: true}
+	f.chests[chestID] = chest
+	return models.ChestReward{ChestID: chest.ID, Kind: chest.Kind, Gold: models.ChestKinds[chest.Kind].Gold}, nil
+}
+
+func TestSpeedUpChestChargesForTheHoursLeft(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	users := newFakeUsers()
+	alice := users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	// A silver chest takes three hours; an hour and a half are left, which
+	// count as two.
+	chests := &fakeChests{
+		chests: map[uint]models.Chest{1: {ID: 1, UserID: alice.ID, Kind: models.ChestSilver,
+			UnlocksAt: pq.NullTime{Time: now.Add(90 * time.Minute), Valid: true}}},
+		gems: map[uint]int64{alice.ID: 11},
+	}
+	h := NewChestHandlers(chests, config.ChestConfig{SpeedUpGemsPerHour: 6}, &fakeClock{now: now})
+	router := gin.New()
+	router.GET("/chests", middleware.RequireUser(users), h.ListChests)
+	router.POST("/chests/:id/speed-up", middleware.RequireUser(users), h.SpeedUpChest)
+	token := tokenFor(t, alice)
+
+	status, body := call(t, router, http.MethodGet, "/chests", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("list answered %d: %v", status, body)
+	}
+	listed := body["chests"].([]interface{})[0].(map[string]interface{})
+	if listed["speedUpCost"] != float64(12) {
+		t.Errorf("speed-up cost = %v, want 12", listed["speedUpCost"])
+	}
+
+	speedUp := "/chests/1/speed-up"
+	if status, body := call(t, router, http.MethodPost, speedUp, token, nil); status != http.StatusForbidden {
+		t.Fatalf("speed-up with 11 gems answered %d: %v", status, body)
+	}
+
+	chests.gems[alice.ID] = 12
+	if status, body := call(t, router, http.MethodPost, speedUp, token, nil); status != http.StatusOK {
+		t.Fatalf("speed-up answered %d: %v", status, body)
+	}
+	if chests.gems[alice.ID] != 0 {
+		t.Errorf("gems left = %d, want 0", chests.gems[alice.ID])
+	}
+	if status, _ := call(t, router, http.MethodPost, speedUp, token, nil); status != http.StatusConflict {
+		t.Errorf("second speed-up answered %d, want 409", status)
+	}
+}
@@ -0,0 +1,639 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const clanTagLength = 8
+
+type ClanHandlers struct {
+	ClanRepo repository.ClanRepo
+	GameRepo repository.GameRepo
+	UserRepo repository.UserRepo
+	Config   config.ClanConfig
+	Clock    utils.Clock
+	Notifier notifications.Notifier
+}
+
+func NewClanHandlers(clanRepo repository.ClanRepo, gameRepo repository.GameRepo, userRepo repository.UserRepo, clanConfig config.ClanConfig, clock utils.Clock, notifier notifications.Notifier) *ClanHandlers {
+	return &ClanHandlers{ClanRepo: clanRepo, GameRepo: gameRepo, UserRepo: userRepo, Config: clanConfig, Clock: clock, Notifier: notifier}
+}
+
+func (h ClanHandlers) GetClans(context *gin.Context) {
+	logger.GetLogger().Info("Fetching clans")
+
+	sortBy := context.DefaultQuery("sortBy", "")
+	sortOrder := context.DefaultQuery("sortOrder", "")
+	filterName := context.DefaultQuery("filterName", "")
+	page, err := strconv.Atoi(context.DefaultQuery("page", "1"))
+	if err != nil {
+		logger.GetLogger().Error("Invalid page parameter:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
+		return
+	}
+	pageSize, err := strconv.Atoi(context.DefaultQuery("pageSize", "10"))
+	if err != nil {
+		logger.GetLogger().Error("Invalid pageSize parameter:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pageSize parameter"})
+		return
+	}
+
+	clans, err := h.ClanRepo.GetClans(sortBy, sortOrder, filterName, page, pageSize)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get clans:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"clans": dto.NewClans(clans)})
+}
+
+func (h ClanHandlers) CreateClan(context *gin.Context) {
+	logger.GetLogger().Info("Creating clan")
+
+	var form forms.CreateClanForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid create clan request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	tag, err := utils.GenerateCode(clanTagLength)
+	if err != nil {
+		logger.GetLogger().Error("Failed to generate clan tag:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create clan"})
+		return
+	}
+
+	clan := models.Clan{
+		Name:        strings.TrimSpace(form.Name),
+		Tag:         tag,
+		Description: form.Description,
+		Membership:  form.Membership,
+		MinTrophies: form.MinTrophies,
+	}
+	if clan.Membership == "" {
+		clan.Membership = models.ClanOpen
+	}
+
+	if err := h.ClanRepo.CreateClan(&clan, user.ID); err != nil {
+		switch {
+		case errors.Is(err, helper.ErrClanNameTaken):
+			context.JSON(http.StatusConflict, gin.H{"error": "Clan name is already taken"})
+		case errors.Is(err, helper.ErrAlreadyInClan):
+			context.JSON(http.StatusConflict, gin.H{"error": "Leave your current clan first"})
+		default:
+			logger.GetLogger().Error("Failed to create clan:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create clan"})
+		}
+		return
+	}
+
+	logger.GetLogger().Info("Clan created")
+	context.JSON(http.StatusCreated, gin.H{"clan": dto.NewClan(clan)})
+}
+
+func (h ClanHandlers) GetClan(context *gin.Context) {
+	logger.GetLogger().Info("Fetching clan")
+
+	clanID, ok := parseID(context, "id", "clan")
+	if !ok {
+		return
+	}
+	h.respondWithClan(context, clanID)
+}
+
+func (h ClanHandlers) GetMyClan(context *gin.Context) {
+	logger.GetLogger().Info("Fetching user's clan")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	membership, err := h.ClanRepo.GetMembership(user.ID)
+	if err != nil {
+		if errors.Is(err, helper.ErrNotInClan) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "You are not in a clan"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get clan membership:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	h.respondWithClan(context, membership.ClanID)
+}
+
+func (h ClanHandlers) UpdateClan(context *gin.Context) {
+	logger.GetLogger().Info("Updating clan settings")
+
+	var form forms.UpdateClanForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid update clan request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	member, ok := h.requireRole(context, models.ClanRoleCoLeader)
+	if !ok {
+		return
+	}
+
+	clan, err := h.ClanRepo.GetClan(member.ClanID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get clan:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if form.Description != nil {
+		clan.Description = *form.Description
+	}
+	if form.Membership != nil {
+		clan.Membership = *form.Membership
+	}
+	if form.MinTrophies != nil {
+		clan.MinTrophies = *form.MinTrophies
+	}
+
+	if err := h.ClanRepo.UpdateClan(clan); err != nil {
+		logger.GetLogger().Error("Failed to update clan:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update clan"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"clan": dto.NewClan(clan)})
+}
+
+// JoinClan joins open clans straight away and files a join request for
+// invite-only ones. Closed clans can't be joined.
+func (h ClanHandlers) JoinClan(context *gin.Context) {
+	logger.GetLogger().Info("Joining clan")
+
+	user, ok := loadCurrentUser(context, h.UserRepo)
+	if !ok {
+		return
+	}
+
+	clanID, ok := parseID(context, "id", "clan")
+	if !ok {
+		return
+	}
+
+	clan, err := h.ClanRepo.GetClan(clanID)
+	if err != nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Clan not found"})
+		return
+	}
+
+	if _, err := h.ClanRepo.GetMembership(user.ID); err == nil {
+		context.JSON(http.StatusConflict, gin.H{"error": "Leave your current clan first"})
+		return
+	}
+	if user.Awards < clan.MinTrophies {
+		context.JSON(http.StatusForbidden, gin.H{"error": "Not enough trophies to join this clan"})
+		return
+	}
+
+	switch clan.Membership {
+	case models.ClanClosed:
+		context.JSON(http.StatusForbidden, gin.H{"error": "This clan is closed"})
+	case models.ClanInviteOnly:
+		if err := h.ClanRepo.CreateJoinRequest(clan.ID, user.ID); err != nil {
+			logger.GetLogger().Error("Failed to create join request:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request to join"})
+			return
+		}
+		context.JSON(http.StatusAccepted, gin.H{"message": "Join request sent"})
+	default:
+		if !h.addMember(context, clan.ID, user.ID) {
+			return
+		}
+		context.JSON(http.StatusOK, gin.H{"message": "Joined clan"})
+	}
+}
+
+func (h ClanHandlers) LeaveClan(context *gin.Context) {
+	logger.GetLogger().Info("Leaving clan")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	membership, err := h.ClanRepo.GetMembership(user.ID)
+	if err != nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "You are not in a clan"})
+		return
+	}
+
+	if err := h.ClanRepo.LeaveClan(membership.ClanID, user.ID); err != nil {
+		logger.GetLogger().Error("Failed to leave clan:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave clan"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Left clan"})
+}
+
+func (h ClanHandlers) GetJoinRequests(context *gin.Context) {
+	logger.GetLogger().Info("Fetching clan join requests")
+
+	member, ok := h.requireRole(context, models.ClanRoleElder)
+	if !ok {
+		return
+	}
+
+	requests, err := h.ClanRepo.GetJoinRequests(member.ClanID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get join requests:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"requests": dto.NewClanJoinRequests(requests)})
+}
+
+func (h ClanHandlers) ApproveJoinRequest(context *gin.Context) {
+	logger.GetLogger().Info("Approving clan join request")
+
+	member, request, ok := h.joinRequest(context)
+	if !ok {
+		return
+	}
+
+	if !h.addMember(context, member.ClanID, request.UserID) {
+		return
+	}
+
+	h.Notifier.Notify(context, models.Notification{
+		UserID: request.UserID,
+		Kind:   models.NotificationClanJoined,
+		Title:  "Your request to join the clan was approved",
+		Data:   map[string]interface{}{"clanId": member.ClanID},
+	})
+
+	context.JSON(http.StatusOK, gin.H{"message": "Join request approved"})
+}
+
+func (h ClanHandlers) DeclineJoinRequest(context *gin.Context) {
+	logger.GetLogger().Info("Declining clan join request")
+
+	_, request, ok := h.joinRequest(context)
+	if !ok {
+		return
+	}
+
+	if err := h.ClanRepo.DeleteJoinRequest(request.ID); err != nil {
+		logger.GetLogger().Error("Failed to delete join request:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline join request"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Join request declined"})
+}
+
+// SetMemberRole promotes or demotes a member. Only a higher-ranked member may
+// change someone's role, and never to a role at or above their own, except
+// that the leader can hand over leadership.
+func (h ClanHandlers) SetMemberRole(context *gin.Context) {
+	logger.GetLogger().Info("Changing clan member role")
+
+	var form forms.ClanRoleForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid clan role request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	actor, target, ok := h.managedMember(context)
+	if !ok {
+		return
+	}
+
+	if form.Role == models.ClanRoleLeader {
+		if actor.Role != models.ClanRoleLeader {
+			context.JSON(http.StatusForbidden, gin.H{"error": "Only the leader can hand over leadership"})
+			return
+		}
+		if err := h.ClanRepo.TransferLeadership(actor.ClanID, actor.UserID, target.UserID); err != nil {
+			logger.GetLogger().Error("Failed to transfer leadership:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change role"})
+			return
+		}
+		context.JSON(http.StatusOK, gin.H{"message": "Leadership transferred"})
+		return
+	}
+
+	if models.ClanRoleRank(form.Role) >= models.ClanRoleRank(actor.Role) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "You can't grant a role at or above your own"})
+		return
+	}
+
+	if err := h.ClanRepo.SetRole(actor.ClanID, target.UserID, form.Role); err != nil {
+		logger.GetLogger().Error("Failed to set clan role:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change role"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Role changed"})
+}
+
+func (h ClanHandlers) KickMember(context *gin.Context) {
+	logger.GetLogger().Info("Kicking clan member")
+
+	actor, target, ok := h.managedMember(context)
+	if !ok {
+		return
+	}
+	if models.ClanRoleRank(actor.Role) < models.ClanRoleRank(models.ClanRoleElder) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "Not enough rights to act"})
+		return
+	}
+
+	if err := h.ClanRepo.LeaveClan(actor.ClanID, target.UserID); err != nil {
+		logger.GetLogger().Error("Failed to kick clan member:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to kick member"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Member kicked"})
+}
+
+func (h ClanHandlers) GetCardRequests(context *gin.Context) {
+	logger.GetLogger().Info("Fetching clan card requests")
+
+	member, ok := h.requireRole(context, models.ClanRoleMember)
+	if !ok {
+		return
+	}
+
+	requests, err := h.ClanRepo.GetOpenCardRequests(member.ClanID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get card requests:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"requests": dto.NewCardRequests(requests)})
+}
+
+func (h ClanHandlers) RequestCards(context *gin.Context) {
+	logger.GetLogger().Info("Requesting cards from clan")
+
+	var form forms.CardRequestForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid card request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	member, ok := h.requireRole(context, models.ClanRoleMember)
+	if !ok {
+		return
+	}
+
+	last, found, err := h.ClanRepo.GetLastCardRequestTime(member.UserID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get last card request:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if next := last.Add(h.Config.RequestCooldown); found && h.Clock.Now().Before(next) {
+		context.JSON(http.StatusTooManyRequests, gin.H{"error": "You can request cards again later", "nextRequestAt": next})
+		return
+	}
+
+	request := models.CardRequest{
+		ClanID:    member.ClanID,
+		UserID:    member.UserID,
+		Username:  member.Username,
+		CardKind:  form.Kind,
+		CardID:    form.CardID,
+		Requested: h.Config.RequestSize,
+	}
+
+	var owned bool
+	if form.Kind == models.CardKindHero {
+		hero, err := h.GameRepo.GetHeroByID(form.CardID)
+		if err != nil {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Hero not found"})
+			return
+		}
+		request.CardName = hero.Name
+		if owned, err = h.GameRepo.HasUserBoughtHero(member.UserID, hero.ID); err != nil {
+			logger.GetLogger().Error("Failed to check hero ownership:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+	} else {
+		spell, err := h.GameRepo.GetSpellByID(form.CardID)
+		if err != nil {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Spell not found"})
+			return
+		}
+		request.CardName = spell.Name
+		if owned, err = h.GameRepo.HasUserBoughtSpell(member.UserID, spell.ID); err != nil {
+			logger.GetLogger().Error("Failed to check spell ownership:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+	}
+	if !owned {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "You can only request cards you have unlocked"})
+		return
+	}
+
+	if err := h.ClanRepo.CreateCardRequest(&request); err != nil {
+		logger.GetLogger().Error("Failed to create card request:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request cards"})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{"request": dto.NewCardRequest(request)})
+}
+
+func (h ClanHandlers) DonateCard(context *gin.Context) {
+	logger.GetLogger().Info("Donating card")
+
+	member, ok := h.requireRole(context, models.ClanRoleMember)
+	if !ok {
+		return
+	}
+
+	requestID, ok := parseID(context, "requestID", "request")
+	if !ok {
+		return
+	}
+
+	request, err := h.ClanRepo.GetCardRequest(requestID)
+	if err != nil || request.ClanID != member.ClanID {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Card request not found"})
+		return
+	}
+	if request.UserID == member.UserID {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "You can't donate to your own request"})
+		return
+	}
+
+	gold := h.Config.DefaultDonationGold
+	if request.CardKind == models.CardKindHero {
+		if hero, err := h.GameRepo.GetHeroByID(request.CardID); err == nil {
+			if rarityGold, ok := h.Config.DonationGold[hero.Rarity]; ok {
+				gold = rarityGold
+			}
+		}
+	}
+
+	if err := h.ClanRepo.Donate(request.ID, member.UserID, gold); err != nil {
+		switch {
+		case errors.Is(err, helper.ErrCardRequestFilled):
+			context.JSON(http.StatusConflict, gin.H{"error": "This request is already filled"})
+		case errors.Is(err, helper.ErrCardRequestCancelled):
+			context.JSON(http.StatusConflict, gin.H{"error": "This request was cancelled"})
+		case errors.Is(err, helper.ErrNoSpareCard):
+			context.JSON(http.StatusBadRequest, gin.H{"error": "You need a spare copy of this card to donate"})
+		default:
+			logger.GetLogger().Error("Failed to donate card:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to donate card"})
+		}
+		return
+	}
+
+	h.Notifier.Notify(context, models.Notification{
+		UserID: request.UserID,
+		Kind:   models.NotificationClanDonation,
+		Title:  member.Username + " donated " + request.CardName,
+		Data:   map[string]interface{}{"clanId": member.ClanID, "requestId": request.ID},
+	})
+
+	logger.GetLogger().Info("Card donated")
+	context.JSON(http.StatusOK, gin.H{"message": "Card donated", "gold": gold})
+}
+
+func (h ClanHandlers) respondWithClan(context *gin.Context, clanID uint) {
+	clan, err := h.ClanRepo.GetClan(clanID)
+	if err != nil {
+		if errors.Is(err, helper.ErrClanNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Clan not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get clan:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	members, err := h.ClanRepo.GetMembers(clanID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get clan members:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"clan": dto.NewClanDetails(clan, members)})
+}
+
+// requireRole loads the current user's membership in the clan named by the
+// :id parameter and checks it is at least minRole.
+func (h ClanHandlers) requireRole(context *gin.Context, minRole string) (models.ClanMember, bool) {
+	user, ok := currentUser(context)
+	if !ok {
+		return models.ClanMember{}, false
+	}
+
+	clanID, ok := parseID(context, "id", "clan")
+	if !ok {
+		return models.ClanMember{}, false
+	}
+
+	member, err := h.ClanRepo.GetMembership(user.ID)
+	if err != nil || member.ClanID != clanID {
+		context.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this clan"})
+		return models.ClanMember{}, false
+	}
+	if models.ClanRoleRank(member.Role) < models.ClanRoleRank(minRole) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "Not enough rights to act"})
+		return models.ClanMember{}, false
+	}
+	return member, true
+}
+
+// managedMember resolves the :userID member an elder or above is acting on,
+// who must rank strictly below them.
+func (h ClanHandlers) managedMember(context *gin.Context) (models.ClanMember, models.ClanMember, bool) {
+	actor, ok := h.requireRole(context, models.ClanRoleElder)
+	if !ok {
+		return models.ClanMember{}, models.ClanMember{}, false
+	}
+
+	targetID, ok := parseID(context, "userID", "user")
+	if !ok {
+		return models.ClanMember{}, models.ClanMember{}, false
+	}
+
+	target, err := h.ClanRepo.GetMembership(targetID)
+	if err != nil || target.ClanID != actor.ClanID {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return models.ClanMember{}, models.ClanMember{}, false
+	}
+	if models.ClanRoleRank(target.Role) >= models.ClanRoleRank(actor.Role) {
+		context.JSON(http.StatusForbidden, gin.H{"error": "You can only manage members ranked below you"})
+		return models.ClanMember{}, models.ClanMember{}, false
+	}
+	return actor, target, true
+}
+
+func (h ClanHandlers) joinRequest(context *gin.Context) (models.ClanMember, models.ClanJoinRequest, bool) {
+	member, ok := h.requireRole(context, models.ClanRoleElder)
+	if !ok {
+		return models.ClanMember{}, models.ClanJoinRequest{}, false
+	}
+
+	requestID, ok := parseID(context, "requestID", "request")
+	if !ok {
+		return models.ClanMember{}, models.ClanJoinRequest{}, false
+	}
+
+	request, err := h.ClanRepo.GetJoinRequest(requestID)
+	if err != nil || request.ClanID != member.ClanID {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Join request not found"})
+		return models.ClanMember{}, models.ClanJoinRequest{}, false
+	}
+	return member, request, true
+}
+
+func (h ClanHandlers) addMember(context *gin.Context, clanID, userID uint) bool {
+	err := h.ClanRepo.AddMember(clanID, userID, h.Config.MaxMembers)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, helper.ErrClanFull):
+		context.JSON(http.StatusConflict, gin.H{"error": "Clan is full"})
+	case errors.Is(err, helper.ErrAlreadyInClan):
+		context.JSON(http.StatusConflict, gin.H{"error": "Player is already in a clan"})
+	case errors.Is(err, helper.ErrClanNotFound):
+		context.JSON(http.StatusNotFound, gin.H{"error": "Clan not found"})
+	default:
+		logger.GetLogger().Error("Failed to add clan member:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join clan"})
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/middleware"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// currentUser returns the principal stored by middleware.RequireUser and
+// answers 401 itself when the route was registered without it.
+func currentUser(context *gin.Context) (*middleware.Principal, bool) {
+	user, ok := middleware.CurrentPrincipal(context)
+	if !ok {
+		logger.GetLogger().Error("User not authenticated")
+		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
+	}
+	return user, ok
+}
+
+// loadCurrentUser loads the whole account of the authenticated user, for
+// handlers that need more than the principal carries.
+func loadCurrentUser(context *gin.Context, users middleware.UserLoader) (*models.User, bool) {
+	principal, ok := currentUser(context)
+	if !ok {
+		return nil, false
+	}
+	user, err := users.GetUserByID(principal.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to load current user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return nil, false
+	}
+	return user, true
+}
+
+// parseID reads a numeric path parameter and answers 400 when it isn't one.
+func parseID(context *gin.Context, param, name string) (uint, bool) {
+	id, err := strconv.ParseUint(context.Param(param), 10, 64)
+	if err != nil {
+		logger.GetLogger().Error("Invalid "+name+" ID format:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " ID format"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// maxPageSize caps the limit clients may ask for on cursor-paginated lists.
+const maxPageSize = 100
+
+// parseCursor reads the ?before= cursor and ?limit= page size used by
+// cursor-paginated lists. An absent cursor starts from the newest row.
+func parseCursor(context *gin.Context, defaultLimit int) (uint, int, bool) {
+	before, err := strconv.ParseUint(context.DefaultQuery("before", "0"), 10, 64)
+	if err != nil {
+		logger.GetLogger().Error("Invalid before parameter:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before parameter"})
+		return 0, 0, false
+	}
+	limit, err := strconv.Atoi(context.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 {
+		logger.GetLogger().Error("Invalid limit parameter:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return 0, 0, false
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return uint(before), limit, true
+}
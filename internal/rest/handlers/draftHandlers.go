@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/middleware"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// DraftHandlers serve draft battles, where neither player brings a deck and
+// both draft one from the catalog instead. Picks are sent here; every pick
+// is pushed to both players over their event stream.
+type DraftHandlers struct {
+	DraftRepo   repository.DraftRepo
+	Config      config.DraftConfig
+	RedisConfig config.RedisConfig
+	Clock       utils.Clock
+	Notifier    notifications.Notifier
+}
+
+func NewDraftHandlers(draftRepo repository.DraftRepo, draftConfig config.DraftConfig, redisConfig config.RedisConfig, clock utils.Clock, notifier notifications.Notifier) *DraftHandlers {
+	return &DraftHandlers{
+		DraftRepo:   draftRepo,
+		Config:      draftConfig,
+		RedisConfig: redisConfig,
+		Clock:       clock,
+		Notifier:    notifier,
+	}
+}
+
+func inDraft(user *middleware.Principal, draft models.Draft) bool {
+	return draft.Player1ID.Int64 == int64(user.ID) || draft.Player2ID.Int64 == int64(user.ID)
+}
+
+// Queue looks for a draft opponent. When another player is already waiting
+// the draft starts at once and both are notified; otherwise the player waits
+// for the next one to queue.
+func (h DraftHandlers) Queue(context *gin.Context) {
+	logger.GetLogger().Info("Queueing for draft")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	draft, started, err := h.DraftRepo.QueueDraft(user.ID, int32(h.Config.DeckSize), int32(h.Config.PickSeconds), h.Clock.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, helper.ErrAlreadyQueued):
+			context.JSON(http.StatusConflict, gin.H{"error": "You are already queued for a draft"})
+		case errors.Is(err, helper.ErrAlreadyDrafting):
+			context.JSON(http.StatusConflict, gin.H{"error": "Finish your current draft first"})
+		case errors.Is(err, helper.ErrDraftPoolTooSmall):
+			context.JSON(http.StatusServiceUnavailable, gin.H{"error": "Drafts are unavailable, the catalog has too few cards"})
+		default:
+			logger.GetLogger().Error("Failed to queue for draft:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join queue"})
+		}
+		return
+	}
+
+	if !started {
+		context.JSON(http.StatusAccepted, gin.H{"queued": true})
+		return
+	}
+
+	for _, player := range []struct {
+		id       int64
+		opponent string
+	}{{draft.Player1ID.Int64, draft.Player2Name}, {draft.Player2ID.Int64, draft.Player1Name}} {
+		h.Notifier.Notify(context, models.Notification{
+			UserID: uint(player.id),
+			Kind:   models.NotificationDraftStarted,
+			Title:  "Your draft against " + player.opponent + " has started",
+			Data:   map[string]interface{}{"draftId": draft.ID},
+		})
+	}
+
+	logger.GetLogger().Info("Draft started")
+	context.JSON(http.StatusCreated, gin.H{"queued": false, "draft": dto.NewDraft(draft)})
+}
+
+func (h DraftHandlers) LeaveQueue(context *gin.Context) {
+	logger.GetLogger().Info("Leaving draft queue")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	if err := h.DraftRepo.LeaveDraftQueue(user.ID); err != nil {
+		if errors.Is(err, helper.ErrNotQueued) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "You are not queued"})
+			return
+		}
+		logger.GetLogger().Error("Failed to leave draft queue:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave queue"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Left queue"})
+}
+
+// GetCurrentDraft returns the draft the player is picking in, so a client
+// can pick up where it left off after reconnecting.
+func (h DraftHandlers) GetCurrentDraft(context *gin.Context) {
+	logger.GetLogger().Info("Fetching current draft")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	draft, err := h.DraftRepo.GetActiveDraft(user.ID)
+	if err != nil {
+		if errors.Is(err, helper.ErrDraftNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "You are not in a draft"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get draft:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"draft": dto.NewDraft(draft)})
+}
+
+// GetDraft shows a draft to its players and to admins.
+func (h DraftHandlers) GetDraft(context *gin.Context) {
+	logger.GetLogger().Info("Fetching draft")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(context, "id", "draft")
+	if !ok {
+		return
+	}
+
+	draft, err := h.DraftRepo.GetDraft(id)
+	if err != nil && !errors.Is(err, helper.ErrDraftNotFound) {
+		logger.GetLogger().Error("Failed to get draft:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if err != nil || (!inDraft(user, draft) && !user.IsAdmin()) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"draft": dto.NewDraft(draft)})
+}
+
+// Pick takes one of the two offered cards. After the last pick the response
+// and the pushed draft carry the id of the match the drafted decks play.
+func (h DraftHandlers) Pick(context *gin.Context) {
+	logger.GetLogger().Info("Picking draft card")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.DraftPickForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid draft pick:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	id, ok := parseID(context, "id", "draft")
+	if !ok {
+		return
+	}
+
+	draft, err := h.DraftRepo.Pick(id, user.ID, form.Kind, form.CardID, h.Clock.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, helper.ErrDraftNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+		case errors.Is(err, helper.ErrNotYourPick):
+			context.JSON(http.StatusConflict, gin.H{"error": "It is not your turn to pick"})
+		case errors.Is(err, helper.ErrPickTimedOut):
+			context.JSON(http.StatusConflict, gin.H{"error": "Time is up, a card is being picked for you"})
+		case errors.Is(err, helper.ErrCardNotOffered):
+			context.JSON(http.StatusBadRequest, gin.H{"error": "That card is not on offer"})
+		default:
+			logger.GetLogger().Error("Failed to pick draft card:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pick card"})
+		}
+		return
+	}
+
+	notifications.PublishDraft(context, h.Notifier, h.RedisConfig, draft)
+
+	context.JSON(http.StatusOK, gin.H{"draft": dto.NewDraft(draft)})
+}
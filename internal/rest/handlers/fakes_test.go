@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	logger.GetLogger().SetOutput(io.Discard)
+	keys, err := utils.NewEphemeralTokenKeySet("", "")
+	if err != nil {
+		panic(err)
+	}
+	utils.InitTokens(keys)
+	os.Exit(m.Run())
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// fakeUsers keeps users in memory. Lookups return copies, like rows read
+// from the database, and like UserRepository it keeps usernames and emails
+// unique regardless of case and bumps the token version on password and
+// email changes.
+type fakeUsers struct {
+	mu     sync.Mutex
+	users  map[uint]*models.User
+	nextID uint
+}
+
+func newFakeUsers() *fakeUsers {
+	return &fakeUsers{users: make(map[uint]*models.User)}
+}
+
+// add stores a user with the password hashed and returns its id.
+func (f *fakeUsers) add(t *testing.T, user models.User) *models.User {
+	t.Helper()
+	if user.Password != "" {
+		// The cheapest cost keeps logins fast; checking doesn't care.
+		hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.Password = string(hashed)
+	}
+	if user.UserType == "" {
+		user.UserType = "USER"
+	}
+	if err := f.CreateUser(&user); err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+func (f *fakeUsers) find(match func(*models.User) bool) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, user := range f.users {
+		if match(user) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeUsers) GetUserByID(id uint) (*models.User, error) {
+	return f.find(func(user *models.User) bool { return user.ID == id })
+}
+
+func (f *fakeUsers) GetUserByUsername(username string) (*models.User, error) {
+	return f.find(func(user *models.User) bool { return strings.EqualFold(user.Username, username) })
+}
+
+func (f *fakeUsers) GetUserByEmail(email string) (*models.User, error) {
+	return f.find(func(user *models.User) bool { return strings.EqualFold(user.Email, email) })
+}
+
+func (f *fakeUsers) GetAllUsers() ([]models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var users []models.User
+	for _, user := range f.users {
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+func (f *fakeUsers) taken(user *models.User) error {
+	for _, other := range f.users {
+		if other.ID == user.ID {
+			continue
+		}
+		if strings.EqualFold(other.Username, user.Username) {
+			return helper.ErrUsernameTaken
+		}
+		if strings.EqualFold(other.Email, user.Email) {
+			return helper.ErrEmailTaken
+		}
+	}
+	return nil
+}
+
+func (f *fakeUsers) UpdateUser(user *models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	current, ok := f.users[user.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if err := f.taken(user); err != nil {
+		return err
+	}
+	user.TokenVersion = current.TokenVersion
+	if user.Password != current.Password || user.Email != current.Email {
+		user.TokenVersion++
+	}
+	stored := *user
+	f.users[user.ID] = &stored
+	return nil
+}
+
+func (f *fakeUsers) DeleteUser(id uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.users, id)
+	return nil
+}
+
+func (f *fakeUsers) SoftDeleteUser(id uint) error {
+	return errors.New("not supported by fakeUsers")
+}
+
+func (f *fakeUsers) RestoreUser(id uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	user.DeletedAt = pq.NullTime{}
+	return nil
+}
+
+func (f *fakeUsers) GetUsersDeletedBefore(cutoff time.Time) ([]uint, error) {
+	return nil, nil
+}
+
+func (f *fakeUsers) CreateUser(user *models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.taken(user); err != nil {
+		return err
+	}
+	f.nextID++
+	user.ID = f.nextID
+	stored := *user
+	f.users[user.ID] = &stored
+	return nil
+}
+
+// tokenFor signs in as the user the way the login handlers do.
+func tokenFor(t *testing.T, user *models.User) string {
+	t.Helper()
+	token, err := utils.CreateToken(strconv.Itoa(int(user.ID)), user.Email, user.UserType, user.TokenVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// call sends a JSON request to the router and decodes the JSON answer.
+func call(t *testing.T, router http.Handler, method, path, token string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var response map[string]interface{}
+	if recorder.Body.Len() > 0 {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %s answered %d with invalid JSON %q", method, path, recorder.Code, recorder.Body.String())
+		}
+	}
+	return recorder.Code, response
+}
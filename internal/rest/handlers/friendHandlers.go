@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	redis "auth/internal/db/redis"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/middleware"
+	"auth/pkg/rest/helper"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const playerSearchLimit = 20
+
+type FriendHandlers struct {
+	UserRepo    repository.UserRepo
+	FriendRepo  repository.FriendRepo
+	RedisConfig config.RedisConfig
+	Notifier    notifications.Notifier
+}
+
+func NewFriendHandlers(userRepo repository.UserRepo, friendRepo repository.FriendRepo, redisConfig config.RedisConfig, notifier notifications.Notifier) *FriendHandlers {
+	return &FriendHandlers{UserRepo: userRepo, FriendRepo: friendRepo, RedisConfig: redisConfig, Notifier: notifier}
+}
+
+func (h FriendHandlers) ListFriends(context *gin.Context) {
+	logger.GetLogger().Info("Fetching friends")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	friends, err := h.FriendRepo.GetFriends(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get friends:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	ids := make([]uint, 0, len(friends))
+	for _, friend := range friends {
+		ids = append(ids, friend.UserID)
+	}
+	online, err := redis.GetOnlineUsers(context, h.RedisConfig, ids)
+	if err != nil {
+		// The list is still useful without presence, everyone just shows offline.
+		logger.GetLogger().Warn("Failed to get online status:", err)
+	}
+
+	context.JSON(http.StatusOK, gin.H{"friends": dto.NewFriends(friends, online)})
+}
+
+func (h FriendHandlers) ListFriendRequests(context *gin.Context) {
+	logger.GetLogger().Info("Fetching friend requests")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	requests, err := h.FriendRepo.GetFriendRequests(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get friend requests:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"requests": dto.NewFriendRequests(requests, user.ID)})
+}
+
+func (h FriendHandlers) SendFriendRequest(context *gin.Context) {
+	logger.GetLogger().Info("Sending friend request")
+
+	var form forms.UsernameForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid friend request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	target, ok := h.targetPlayer(context, user, form.Username)
+	if !ok {
+		return
+	}
+
+	blocked, err := h.FriendRepo.IsBlocked(user.ID, target.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to check block:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if blocked {
+		context.JSON(http.StatusForbidden, gin.H{"error": "You can't send a friend request to this player"})
+		return
+	}
+
+	existing, err := h.FriendRepo.GetFriendship(user.ID, target.ID)
+	switch {
+	case err == nil && existing.Status == models.FriendshipAccepted:
+		context.JSON(http.StatusConflict, gin.H{"error": "You are already friends"})
+		return
+	case err == nil && existing.RequesterID == user.ID:
+		context.JSON(http.StatusConflict, gin.H{"error": "Friend request already sent"})
+		return
+	case err == nil:
+		// They already asked us, so sending one back is the same as accepting.
+		if err := h.FriendRepo.AcceptFriendRequest(existing.ID); err != nil {
+			logger.GetLogger().Error("Failed to accept friend request:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept friend request"})
+			return
+		}
+		h.notifyAccepted(context, existing.RequesterID, user)
+		context.JSON(http.StatusOK, gin.H{"message": "Friend request accepted"})
+		return
+	case !errors.Is(err, helper.ErrFriendshipNotFound):
+		logger.GetLogger().Error("Failed to get friendship:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	friendship, err := h.FriendRepo.CreateFriendRequest(user.ID, target.ID)
+	if errors.Is(err, helper.ErrFriendshipExists) {
+		context.JSON(http.StatusConflict, gin.H{"error": "Friend request already sent"})
+		return
+	} else if err != nil {
+		logger.GetLogger().Error("Failed to create friend request:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send friend request"})
+		return
+	}
+
+	h.Notifier.Notify(context, models.Notification{
+		UserID: target.ID,
+		Kind:   models.NotificationFriendRequest,
+		Title:  user.Username + " sent you a friend request",
+		Data:   map[string]interface{}{"requestId": friendship.ID, "userId": user.ID},
+	})
+
+	logger.GetLogger().Info("Friend request sent")
+	context.JSON(http.StatusCreated, gin.H{"message": "Friend request sent", "requestId": friendship.ID})
+}
+
+func (h FriendHandlers) AcceptFriendRequest(context *gin.Context) {
+	logger.GetLogger().Info("Accepting friend request")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	friendship, ok := h.pendingRequest(context, user.ID)
+	if !ok {
+		return
+	}
+	if friendship.AddresseeID != user.ID {
+		context.JSON(http.StatusForbidden, gin.H{"error": "Only the recipient can accept a friend request"})
+		return
+	}
+
+	if err := h.FriendRepo.AcceptFriendRequest(friendship.ID); err != nil {
+		logger.GetLogger().Error("Failed to accept friend request:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept friend request"})
+		return
+	}
+
+	h.notifyAccepted(context, friendship.RequesterID, user)
+	context.JSON(http.StatusOK, gin.H{"message": "Friend request accepted"})
+}
+
+// DeclineFriendRequest is used both by the recipient to decline and by the
+// sender to cancel a request that hasn't been answered.
+func (h FriendHandlers) DeclineFriendRequest(context *gin.Context) {
+	logger.GetLogger().Info("Declining friend request")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	friendship, ok := h.pendingRequest(context, user.ID)
+	if !ok {
+		return
+	}
+
+	if err := h.FriendRepo.DeleteFriendship(friendship.ID); err != nil {
+		logger.GetLogger().Error("Failed to decline friend request:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline friend request"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Friend request declined"})
+}
+
+func (h FriendHandlers) RemoveFriend(context *gin.Context) {
+	logger.GetLogger().Info("Removing friend")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	friendID, err := strconv.ParseUint(context.Param("userID"), 10, 64)
+	if err != nil {
+		logger.GetLogger().Error("Invalid user ID format:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	friendship, err := h.FriendRepo.GetFriendship(user.ID, uint(friendID))
+	if err != nil || friendship.Status != models.FriendshipAccepted {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Friend not found"})
+		return
+	}
+
+	if err := h.FriendRepo.DeleteFriendship(friendship.ID); err != nil {
+		logger.GetLogger().Error("Failed to remove friend:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove friend"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Friend removed"})
+}
+
+func (h FriendHandlers) BlockUser(context *gin.Context) {
+	logger.GetLogger().Info("Blocking user")
+
+	var form forms.UsernameForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid block request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	target, ok := h.targetPlayer(context, user, form.Username)
+	if !ok {
+		return
+	}
+
+	if err := h.FriendRepo.BlockUser(user.ID, target.ID); err != nil {
+		logger.GetLogger().Error("Failed to block user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "User blocked"})
+}
+
+func (h FriendHandlers) UnblockUser(context *gin.Context) {
+	logger.GetLogger().Info("Unblocking user")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	blockedID, err := strconv.ParseUint(context.Param("userID"), 10, 64)
+	if err != nil {
+		logger.GetLogger().Error("Invalid user ID format:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	if err := h.FriendRepo.UnblockUser(user.ID, uint(blockedID)); err != nil {
+		logger.GetLogger().Error("Failed to unblock user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
+
+func (h FriendHandlers) ListBlockedUsers(context *gin.Context) {
+	logger.GetLogger().Info("Fetching blocked users")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	blocked, err := h.FriendRepo.GetBlockedUsers(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get blocked users:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"blocked": dto.NewBlockedUsers(blocked)})
+}
+
+func (h FriendHandlers) SearchPlayers(context *gin.Context) {
+	logger.GetLogger().Info("Searching players")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	username := strings.TrimSpace(context.Query("username"))
+	if len(username) < 2 {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Search needs at least 2 characters"})
+		return
+	}
+
+	players, err := h.FriendRepo.SearchPlayers(user.ID, username, playerSearchLimit)
+	if err != nil {
+		logger.GetLogger().Error("Failed to search players:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"players": dto.NewPlayerSearchResults(players)})
+}
+
+func (h FriendHandlers) notifyAccepted(context *gin.Context, requesterID uint, user *middleware.Principal) {
+	h.Notifier.Notify(context, models.Notification{
+		UserID: requesterID,
+		Kind:   models.NotificationFriendAccepted,
+		Title:  user.Username + " accepted your friend request",
+		Data:   map[string]interface{}{"userId": user.ID},
+	})
+}
+
+// targetPlayer resolves the player a friend or block request is aimed at.
+func (h FriendHandlers) targetPlayer(context *gin.Context, user *middleware.Principal, username string) (*models.User, bool) {
+	target, err := h.UserRepo.GetUserByUsername(strings.TrimSpace(username))
+	if err != nil || target.DeletedAt.Valid {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+		return nil, false
+	}
+	if target.ID == user.ID {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "You can't do this to yourself"})
+		return nil, false
+	}
+	return target, true
+}
+
+// pendingRequest loads the request named in the URL and checks the user is
+// one of its two sides.
+func (h FriendHandlers) pendingRequest(context *gin.Context, userID uint) (models.Friendship, bool) {
+	requestID, err := strconv.ParseUint(context.Param("id"), 10, 64)
+	if err != nil {
+		logger.GetLogger().Error("Invalid request ID format:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID format"})
+		return models.Friendship{}, false
+	}
+
+	friendship, err := h.FriendRepo.GetFriendshipByID(uint(requestID))
+	if err != nil || friendship.Status != models.FriendshipPending ||
+		(friendship.RequesterID != userID && friendship.AddresseeID != userID) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Friend request not found"})
+		return models.Friendship{}, false
+	}
+	return friendship, true
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"auth/internal/notifications"
+	"auth/internal/quests"
 	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
 	"auth/internal/rest/models"
 	"auth/pkg/logger"
 	"auth/pkg/rest/helper"
@@ -12,42 +16,26 @@ import (
 )
 
 type GameHandlers struct {
-	UserRepo repository.UserRepo
-	GameRepo repository.GameRepository
+	UserRepo  repository.UserRepo
+	GameRepo  repository.GameRepository
+	ArenaRepo repository.ArenaRepo
+	Notifier  notifications.Notifier
+	Quests    quests.Recorder
 }
 
-func NewGameHandlers(userRepo repository.UserRepo, gameRepo repository.GameRepository) *GameHandlers {
-	return &GameHandlers{UserRepo: userRepo, GameRepo: gameRepo}
+func NewGameHandlers(userRepo repository.UserRepo, gameRepo repository.GameRepository, arenaRepo repository.ArenaRepo, notifier notifications.Notifier, recorder quests.Recorder) *GameHandlers {
+	return &GameHandlers{UserRepo: userRepo, GameRepo: gameRepo, ArenaRepo: arenaRepo, Notifier: notifier, Quests: recorder}
 }
 
 func (h GameHandlers) AddHeroToDeck(context *gin.Context) {
-	var input struct {
-		DeckID uint `json:"deck_id"`
-		HeroID uint `json:"hero_id"`
-	}
+	var input forms.AddHeroToDeckForm
 
 	if err := context.ShouldBindJSON(&input); err != nil {
 		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
-	email, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
-		return
-	}
-
-	emailm, ok := email.(string)
+	user, ok := currentUser(context)
 	if !ok {
-		logger.GetLogger().Error("Error while retrieving user ID")
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Error while retrieving user ID"})
-		return
-	}
-
-	user, err := h.UserRepo.GetUserByEmail(emailm)
-	if err != nil {
-		logger.GetLogger().Error("User not found:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "User not found"})
 		return
 	}
 
@@ -87,7 +75,7 @@ func (h GameHandlers) AddHeroToDeck(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"decks": updatedDecks})
+	context.JSON(http.StatusOK, gin.H{"decks": dto.NewDecks(updatedDecks)})
 }
 
 func (h GameHandlers) DeleteHeroToDeсk(context *gin.Context) {
@@ -112,28 +100,12 @@ func (h GameHandlers) DeleteHeroToDeсk(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"message": "Hero successfully delete from deck", "decks": updatedDecks})
+	context.JSON(http.StatusOK, gin.H{"message": "Hero successfully delete from deck", "decks": dto.NewDecks(updatedDecks)})
 }
 
 func (h GameHandlers) GetMyHeros(context *gin.Context) {
-	email, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
-		return
-	}
-
-	emailm, ok := email.(string)
+	user, ok := currentUser(context)
 	if !ok {
-		logger.GetLogger().Error("Error while retrieving user ID")
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Error while retrieving user ID"})
-		return
-	}
-
-	user, err := h.UserRepo.GetUserByEmail(emailm)
-	if err != nil {
-		logger.GetLogger().Error("User not found:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "User not found"})
 		return
 	}
 
@@ -147,7 +119,7 @@ func (h GameHandlers) GetMyHeros(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"heros": heros})
+	context.JSON(http.StatusOK, gin.H{"heros": dto.NewHeros(heros)})
 }
 
 func (h GameHandlers) AddSpellToDeck(context *gin.Context) {
@@ -173,7 +145,7 @@ func (h GameHandlers) AddSpellToDeck(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"message": "Spell successfully added from deck", "decks": updatedDecks})
+	context.JSON(http.StatusOK, gin.H{"message": "Spell successfully added from deck", "decks": dto.NewDecks(updatedDecks)})
 }
 
 func (h GameHandlers) DeleteSpellToDeсk(context *gin.Context) {
@@ -199,28 +171,12 @@ func (h GameHandlers) DeleteSpellToDeсk(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"message": "Spell successfully deleted from deck", "decks": updatedDecks})
+	context.JSON(http.StatusOK, gin.H{"message": "Spell successfully deleted from deck", "decks": dto.NewDecks(updatedDecks)})
 }
 
 func (h GameHandlers) GetMySpell(context *gin.Context) {
-	email, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
-		return
-	}
-
-	emailm, ok := email.(string)
+	user, ok := currentUser(context)
 	if !ok {
-		logger.GetLogger().Error("Error while retrieving user ID")
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Error while retrieving user ID"})
-		return
-	}
-
-	user, err := h.UserRepo.GetUserByEmail(emailm)
-	if err != nil {
-		logger.GetLogger().Error("User not found:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "User not found"})
 		return
 	}
 
@@ -235,28 +191,12 @@ func (h GameHandlers) GetMySpell(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"spells": spells})
+	context.JSON(http.StatusOK, gin.H{"spells": dto.NewSpells(spells)})
 }
 
 func (h GameHandlers) BuyHero(context *gin.Context) {
-	email, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
-		return
-	}
-
-	emailm, ok := email.(string)
+	user, ok := currentUser(context)
 	if !ok {
-		logger.GetLogger().Error("Error while retrieving user ID")
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Error while retrieving user ID"})
-		return
-	}
-
-	user, err := h.UserRepo.GetUserByEmail(emailm)
-	if err != nil {
-		logger.GetLogger().Error("User not found:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "User not found"})
 		return
 	}
 	heroID, err := strconv.ParseUint(context.Param("id"), 10, 64)
@@ -271,45 +211,35 @@ func (h GameHandlers) BuyHero(context *gin.Context) {
 		context.JSON(http.StatusNotFound, gin.H{"error": "Hero not found"})
 		return
 	}
-	if user.Bank < hero.Price {
-		logger.GetLogger().Error("Insufficient balance to buy the hero")
-		context.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "Insufficient balance to buy the hero"})
-		return
-	}
-	user.Bank -= hero.Price
-	if err := h.GameRepo.AddHeroToUser(user.ID, hero.ID); err != nil {
-		logger.GetLogger().Error("Failed to add hero to user's collection:", err)
+	if err := h.GameRepo.PurchaseHero(user.ID, hero); err != nil {
+		if errors.Is(err, helper.ErrCardLocked) {
+			context.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "Reach the hero's arena to unlock it"})
+			return
+		}
+		if errors.Is(err, helper.ErrInsufficientBalance) {
+			logger.GetLogger().Error("Insufficient balance to buy the hero")
+			context.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "Insufficient balance to buy the hero"})
+			return
+		}
+		logger.GetLogger().Error("Failed to buy hero:", err)
 		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to add hero to user's collection"})
 		return
 	}
-	if err := h.UserRepo.UpdateUserBalance(user.ID, user.Bank); err != nil {
-		logger.GetLogger().Error("Failed to update user's balance:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update user's balance"})
-		return
-	}
+
+	h.Notifier.Notify(context, models.Notification{
+		UserID: user.ID,
+		Kind:   models.NotificationPurchase,
+		Title:  "You bought " + hero.Name,
+		Data:   map[string]interface{}{"heroId": hero.ID, "price": hero.Price},
+	})
+	h.Quests.Record(context, models.QuestEvent{UserID: user.ID, Type: models.EventHeroBought, Rarity: hero.Rarity})
 
 	context.JSON(http.StatusOK, gin.H{"status": "success", "message": "Hero bought successfully"})
 }
 
 func (h GameHandlers) BuySpell(context *gin.Context) {
-	email, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
-		return
-	}
-
-	emailm, ok := email.(string)
+	user, ok := currentUser(context)
 	if !ok {
-		logger.GetLogger().Error("Error while retrieving user ID")
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Error while retrieving user ID"})
-		return
-	}
-
-	user, err := h.UserRepo.GetUserByEmail(emailm)
-	if err != nil {
-		logger.GetLogger().Error("User not found:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "User not found"})
 		return
 	}
 	spellID, err := strconv.ParseUint(context.Param("id"), 10, 64)
@@ -325,113 +255,69 @@ func (h GameHandlers) BuySpell(context *gin.Context) {
 		context.JSON(http.StatusNotFound, gin.H{"error": "Spell not found"})
 		return
 	}
-	if user.Bank < spell.Price {
-		logger.GetLogger().Info("Insufficient balance to buy the spell")
-		context.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "Insufficient balance to buy the spell"})
-		return
-	}
-	user.Bank -= spell.Price
-	if err := h.UserRepo.UpdateUserBalance(user.ID, user.Bank); err != nil {
-		logger.GetLogger().Error("Failed to update user's balance:", err)
-		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to update user's balance"})
-		return
-	}
-	if err := h.GameRepo.AddSpellToUser(user.ID, spell.ID); err != nil {
-		logger.GetLogger().Error("Failed to add spell to user's collection:", err)
+	if err := h.GameRepo.PurchaseSpell(user.ID, spell); err != nil {
+		if errors.Is(err, helper.ErrCardLocked) {
+			context.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "Reach the spell's arena to unlock it"})
+			return
+		}
+		if errors.Is(err, helper.ErrInsufficientBalance) {
+			logger.GetLogger().Info("Insufficient balance to buy the spell")
+			context.JSON(http.StatusForbidden, gin.H{"status": "error", "message": "Insufficient balance to buy the spell"})
+			return
+		}
+		logger.GetLogger().Error("Failed to buy spell:", err)
 		context.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to add spell to user's collection"})
 		return
 	}
+	h.Notifier.Notify(context, models.Notification{
+		UserID: user.ID,
+		Kind:   models.NotificationPurchase,
+		Title:  "You bought " + spell.Name,
+		Data:   map[string]interface{}{"spellId": spell.ID, "price": spell.Price},
+	})
+	h.Quests.Record(context, models.QuestEvent{UserID: user.ID, Type: models.EventSpellBought})
+
 	context.JSON(http.StatusOK, gin.H{"status": "success", "message": "Spell purchased successfully"})
 }
 func (h GameHandlers) CreateHero(context *gin.Context) {
 	logger.GetLogger().Info("Creating hero")
 
-	email, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
-		return
-	}
-
-	emailm, ok := email.(string)
-	if !ok {
-		logger.GetLogger().Error("Failed to get user ID from context")
-		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-		return
-	}
-
-	user, err := h.UserRepo.GetUserByEmail(emailm)
-	if err != nil {
-		logger.GetLogger().Error("Failed to get user ID from context")
-		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-		return
-	}
-
-	if user.UserType != "ADMIN" {
-		logger.GetLogger().Warn("Not enough rights to create hero")
-		context.JSON(http.StatusForbidden, gin.H{"error": "Not enough rights to act"})
-		return
-	}
-
-	var hero models.Hero
-	if err := context.BindJSON(&hero); err != nil {
+	var form forms.CreateHeroForm
+	if err := context.ShouldBindJSON(&form); err != nil {
 		logger.GetLogger().Error("Failed to bind JSON for hero creation:", err)
 		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
 		return
 	}
 
+	hero := dto.HeroFromForm(form)
+
 	if err := h.GameRepo.CreateHero(&hero); err != nil {
 		logger.GetLogger().Error("Failed to create hero:", err)
 		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return
 	}
-	context.JSON(http.StatusCreated, gin.H{"hero": hero})
+	context.JSON(http.StatusCreated, gin.H{"hero": dto.NewHero(hero)})
 }
 
 func (h GameHandlers) CreateSpell(context *gin.Context) {
 	logger.GetLogger().Info("Creating spell")
 
-	email, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
-		return
-	}
-
-	emailm, ok := email.(string)
-	if !ok {
-		logger.GetLogger().Error("Failed to get user ID from context")
-		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-		return
-	}
-
-	user, err := h.UserRepo.GetUserByEmail(emailm)
-	if err != nil {
-		logger.GetLogger().Error("Failed to get user ID from context")
-		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-		return
-	}
-
-	if user.UserType != "ADMIN" {
-		logger.GetLogger().Warn("Not enough rights to create spell")
-		context.JSON(http.StatusForbidden, gin.H{"error": "Not enough rights to act"})
-		return
-	}
-
-	var spell models.Spell
-	if err := context.BindJSON(&spell); err != nil {
+	var form forms.CreateSpellForm
+	if err := context.ShouldBindJSON(&form); err != nil {
 		logger.GetLogger().Error("Failed to bind JSON for spell creation:", err)
 		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format"})
 		return
 	}
 
+	spell := dto.SpellFromForm(form)
+
 	if err := h.GameRepo.CreateSpell(&spell); err != nil {
 		logger.GetLogger().Error("Failed to create spell:", err)
 		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return
 	}
 
-	context.JSON(http.StatusCreated, gin.H{"spell": spell})
+	context.JSON(http.StatusCreated, gin.H{"spell": dto.NewSpell(spell)})
 }
 func (h GameHandlers) GetAllHeros(context *gin.Context) {
 	logger.GetLogger().Info("Fetching all heroes")
@@ -459,7 +345,12 @@ func (h GameHandlers) GetAllHeros(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"heroes": heros})
+	locks, ok := h.cardLocks(context)
+	if !ok {
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"heroes": dto.NewCatalogHeros(heros, locks.Heroes)})
 }
 
 func (h GameHandlers) GetAllSpell(context *gin.Context) {
@@ -488,30 +379,36 @@ func (h GameHandlers) GetAllSpell(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"spells": spells})
-}
-
-func (h GameHandlers) GetMyDeck(context *gin.Context) {
-	logger.GetLogger().Info("Fetching user's deck")
-
-	email, exists := context.Get("email")
-	if !exists {
-		logger.GetLogger().Error("User not authenticated")
-		context.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
+	locks, ok := h.cardLocks(context)
+	if !ok {
 		return
 	}
 
-	emailm, ok := email.(string)
+	context.JSON(http.StatusOK, gin.H{"spells": dto.NewCatalogSpells(spells, locks.Spells)})
+}
+
+// cardLocks returns the cards the current player hasn't unlocked yet, so the
+// catalog can mark them.
+func (h GameHandlers) cardLocks(context *gin.Context) (models.CardLocks, bool) {
+	user, ok := currentUser(context)
 	if !ok {
-		logger.GetLogger().Error("Failed to get user ID from context")
-		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-		return
+		return models.CardLocks{}, false
 	}
 
-	user, err := h.UserRepo.GetUserByEmail(emailm)
+	locks, err := h.ArenaRepo.GetCardLocks(user.ID)
 	if err != nil {
-		logger.GetLogger().Error("Failed to get user ID from context")
+		logger.GetLogger().Error("Failed to get card locks:", err)
 		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return models.CardLocks{}, false
+	}
+	return locks, true
+}
+
+func (h GameHandlers) GetMyDeck(context *gin.Context) {
+	logger.GetLogger().Info("Fetching user's deck")
+
+	user, ok := currentUser(context)
+	if !ok {
 		return
 	}
 
@@ -540,7 +437,7 @@ func (h GameHandlers) GetMyDeck(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"deck": deck})
+	context.JSON(http.StatusOK, gin.H{"deck": dto.NewDeck(deck)})
 }
 
 func (h GameHandlers) GetHero(context *gin.Context) {
@@ -565,7 +462,7 @@ func (h GameHandlers) GetHero(context *gin.Context) {
 		return
 	}
 
-	context.JSON(http.StatusOK, gin.H{"hero": hero})
+	context.JSON(http.StatusOK, gin.H{"hero": dto.NewHero(hero)})
 }
 
 func (h GameHandlers) GetSpell(context *gin.Context) {
@@ -589,5 +486,5 @@ func (h GameHandlers) GetSpell(context *gin.Context) {
 		}
 		return
 	}
-	context.JSON(http.StatusOK, gin.H{"spell": spell})
+	context.JSON(http.StatusOK, gin.H{"spell": dto.NewSpell(spell)})
 }
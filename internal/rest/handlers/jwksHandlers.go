@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"auth/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func (h AuthHandlers) JWKS(context *gin.Context) {
+	context.Header("Cache-Control", "public, max-age=300")
+	context.JSON(http.StatusOK, utils.JWKS())
+}
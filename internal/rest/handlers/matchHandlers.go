@@ -0,0 +1,520 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/quests"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/middleware"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+const (
+	joinCodeLength   = 6
+	joinCodeAttempts = 5
+	matchPageSize    = 20
+)
+
+type MatchHandlers struct {
+	UserRepo   repository.UserRepo
+	GameRepo   repository.GameRepo
+	FriendRepo repository.FriendRepo
+	MatchRepo  repository.MatchRepo
+	PlayerRepo repository.PlayerRepo
+	Config     config.MatchConfig
+	Clock      utils.Clock
+	Notifier   notifications.Notifier
+	Quests     quests.Recorder
+}
+
+func NewMatchHandlers(userRepo repository.UserRepo, gameRepo repository.GameRepo, friendRepo repository.FriendRepo, matchRepo repository.MatchRepo, playerRepo repository.PlayerRepo, matchConfig config.MatchConfig, clock utils.Clock, notifier notifications.Notifier, recorder quests.Recorder) *MatchHandlers {
+	return &MatchHandlers{
+		UserRepo:   userRepo,
+		GameRepo:   gameRepo,
+		FriendRepo: friendRepo,
+		MatchRepo:  matchRepo,
+		PlayerRepo: playerRepo,
+		Config:     matchConfig,
+		Clock:      clock,
+		Notifier:   notifier,
+		Quests:     recorder,
+	}
+}
+
+func (h MatchHandlers) CreateChallenge(context *gin.Context) {
+	logger.GetLogger().Info("Creating friendly challenge")
+
+	var form forms.CreateChallengeForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid challenge request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	if _, ok := h.lockDeck(context, user.ID, form.DeckID); !ok {
+		return
+	}
+
+	challenge := models.Challenge{
+		ChallengerID:     user.ID,
+		ChallengerName:   user.Username,
+		ChallengerDeckID: form.DeckID,
+		ExpiresAt:        h.Clock.Now().Add(h.Config.ChallengeTTL),
+	}
+
+	if opponentName := strings.TrimSpace(form.Opponent); opponentName != "" {
+		opponent, err := h.UserRepo.GetUserByUsername(opponentName)
+		if err != nil || opponent.DeletedAt.Valid {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+			return
+		}
+		friends, err := h.FriendRepo.AreFriends(user.ID, opponent.ID)
+		if err != nil {
+			logger.GetLogger().Error("Failed to check friendship:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+		if !friends {
+			context.JSON(http.StatusForbidden, gin.H{"error": "You can only challenge your friends"})
+			return
+		}
+		challenge.OpponentID = sql.NullInt64{Int64: int64(opponent.ID), Valid: true}
+		challenge.OpponentName = opponent.Username
+	}
+
+	var err error
+	for attempt := 0; attempt < joinCodeAttempts; attempt++ {
+		if challenge.JoinCode, err = utils.GenerateCode(joinCodeLength); err != nil {
+			break
+		}
+		if err = h.MatchRepo.CreateChallenge(&challenge); !errors.Is(err, helper.ErrJoinCodeTaken) {
+			break
+		}
+	}
+	if err != nil {
+		logger.GetLogger().Error("Failed to create challenge:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create challenge"})
+		return
+	}
+
+	if challenge.OpponentID.Valid {
+		h.Notifier.Notify(context, models.Notification{
+			UserID: uint(challenge.OpponentID.Int64),
+			Kind:   models.NotificationChallenge,
+			Title:  user.Username + " challenged you to a friendly battle",
+			Data:   map[string]interface{}{"challengeId": challenge.ID},
+		})
+	}
+
+	logger.GetLogger().Info("Friendly challenge created")
+	context.JSON(http.StatusCreated, gin.H{"challenge": dto.NewChallenge(challenge)})
+}
+
+func (h MatchHandlers) ListChallenges(context *gin.Context) {
+	logger.GetLogger().Info("Fetching challenges")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	challenges, err := h.MatchRepo.GetChallengesForUser(user.ID, h.Clock.Now())
+	if err != nil {
+		logger.GetLogger().Error("Failed to get challenges:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"challenges": dto.NewChallenges(challenges)})
+}
+
+// GetLobby shows who is waiting behind a join code before the player enters.
+func (h MatchHandlers) GetLobby(context *gin.Context) {
+	logger.GetLogger().Info("Fetching challenge lobby")
+
+	challenge, err := h.MatchRepo.GetPendingChallengeByCode(strings.ToUpper(context.Param("code")))
+	if err != nil || !challenge.ExpiresAt.After(h.Clock.Now()) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Lobby not found"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"challenge": dto.NewChallenge(challenge)})
+}
+
+func (h MatchHandlers) AcceptChallenge(context *gin.Context) {
+	logger.GetLogger().Info("Accepting challenge")
+
+	var form forms.AcceptChallengeForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid accept challenge request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	challengeID, ok := parseID(context, "id", "challenge")
+	if !ok {
+		return
+	}
+
+	challenge, err := h.MatchRepo.GetChallenge(challengeID)
+	if err != nil || !challenge.OpponentID.Valid || uint(challenge.OpponentID.Int64) != user.ID {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
+		return
+	}
+
+	h.startChallenge(context, user, challenge, form.DeckID)
+}
+
+func (h MatchHandlers) JoinChallenge(context *gin.Context) {
+	logger.GetLogger().Info("Joining challenge lobby")
+
+	var form forms.JoinChallengeForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid join challenge request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	challenge, err := h.MatchRepo.GetPendingChallengeByCode(strings.ToUpper(strings.TrimSpace(form.Code)))
+	if err != nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Lobby not found"})
+		return
+	}
+	if challenge.OpponentID.Valid && uint(challenge.OpponentID.Int64) != user.ID {
+		context.JSON(http.StatusForbidden, gin.H{"error": "This challenge is for another player"})
+		return
+	}
+
+	h.startChallenge(context, user, challenge, form.DeckID)
+}
+
+// DeclineChallenge lets the opponent decline or the challenger cancel.
+func (h MatchHandlers) DeclineChallenge(context *gin.Context) {
+	logger.GetLogger().Info("Declining challenge")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	challengeID, ok := parseID(context, "id", "challenge")
+	if !ok {
+		return
+	}
+
+	challenge, err := h.MatchRepo.GetChallenge(challengeID)
+	if err != nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
+		return
+	}
+
+	status := models.ChallengeDeclined
+	if challenge.ChallengerID == user.ID {
+		status = models.ChallengeCancelled
+	} else if !challenge.OpponentID.Valid || uint(challenge.OpponentID.Int64) != user.ID {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Challenge not found"})
+		return
+	}
+
+	if err := h.MatchRepo.CloseChallenge(challenge.ID, status); err != nil {
+		if errors.Is(err, helper.ErrChallengeUnavailable) {
+			context.JSON(http.StatusConflict, gin.H{"error": "Challenge is no longer pending"})
+			return
+		}
+		logger.GetLogger().Error("Failed to close challenge:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline challenge"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Challenge " + strings.ToLower(status)})
+}
+
+// ListMatches pages through the player's match history, newest first, with
+// every player's team in team modes.
+func (h MatchHandlers) ListMatches(context *gin.Context) {
+	logger.GetLogger().Info("Fetching match history")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	before, limit, ok := parseCursor(context, matchPageSize)
+	if !ok {
+		return
+	}
+
+	matches, err := h.MatchRepo.GetMatchesForUser(user.ID, before, limit)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get match history:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	response := gin.H{"matches": dto.NewMatches(matches)}
+	if len(matches) > 0 && len(matches) == limit {
+		response["nextCursor"] = matches[len(matches)-1].ID
+	}
+	context.JSON(http.StatusOK, response)
+}
+
+func (h MatchHandlers) GetMatch(context *gin.Context) {
+	logger.GetLogger().Info("Fetching match")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	matchID, ok := parseID(context, "id", "match")
+	if !ok {
+		return
+	}
+
+	match, err := h.MatchRepo.GetMatch(matchID)
+	if err != nil {
+		if errors.Is(err, helper.ErrMatchNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get match:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	isPlayer := false
+	for _, player := range match.Players {
+		isPlayer = isPlayer || player.UserID == user.ID
+	}
+	if !isPlayer {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"match": dto.NewMatch(match)})
+}
+
+// ReportResult records how a match ended and feeds the result to quests.
+// It is called by the game server, which authenticates as an admin, and
+// only the first report for a match counts.
+func (h MatchHandlers) ReportResult(context *gin.Context) {
+	logger.GetLogger().Info("Recording match result")
+
+	var form forms.MatchResultForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid match result:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	matchID, ok := parseID(context, "id", "match")
+	if !ok {
+		return
+	}
+
+	match, err := h.MatchRepo.GetMatch(matchID)
+	if err != nil {
+		if errors.Is(err, helper.ErrMatchNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get match:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	// Seats of bots and deleted players have no user, so no result can name
+	// them.
+	inMatch := make(map[uint]bool, len(match.Players))
+	teams := make(map[uint]int32, len(match.Players))
+	for _, player := range match.Players {
+		if player.UserID == 0 {
+			continue
+		}
+		inMatch[player.UserID] = true
+		teams[player.UserID] = player.Team
+	}
+	if form.WinnerID != 0 && !inMatch[form.WinnerID] {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Winner did not play in this match"})
+		return
+	}
+
+	winnerID := sql.NullInt64{Int64: int64(form.WinnerID), Valid: form.WinnerID != 0}
+	var winningTeam sql.NullInt64
+	if match.HasTeams() {
+		team := form.WinningTeam
+		if form.WinnerID != 0 {
+			team = teams[form.WinnerID]
+		}
+		winnerID = sql.NullInt64{}
+		winningTeam = sql.NullInt64{Int64: int64(team), Valid: team != 0}
+	} else if form.WinningTeam != 0 {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Only team matches have a winning team"})
+		return
+	}
+	spellsPlayed := make(map[uint]int32, len(form.Players))
+	for _, player := range form.Players {
+		if !inMatch[player.UserID] {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Player did not play in this match"})
+			return
+		}
+		spellsPlayed[player.UserID] = player.SpellsPlayed
+	}
+
+	if err := h.MatchRepo.FinishMatch(match.ID, winnerID, winningTeam, h.Clock.Now()); err != nil {
+		if errors.Is(err, helper.ErrMatchFinished) {
+			context.JSON(http.StatusConflict, gin.H{"error": "Match result already recorded"})
+			return
+		}
+		logger.GetLogger().Error("Failed to finish match:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record match result"})
+		return
+	}
+
+	// Quests grant rewards, which only ranked matches may do. Matches with
+	// bots don't count at all: practice and queue fill-ins are no real result.
+	if match.Ranked && !match.HasBots() {
+		for _, player := range match.Players {
+			if player.UserID == 0 {
+				continue
+			}
+			h.Quests.Record(context, models.QuestEvent{UserID: player.UserID, Type: models.EventMatchPlayed})
+			won := player.UserID == form.WinnerID
+			if winningTeam.Valid {
+				won = int64(player.Team) == winningTeam.Int64
+			}
+			if won {
+				h.Quests.Record(context, models.QuestEvent{UserID: player.UserID, Type: models.EventMatchWon})
+			}
+			if spells := spellsPlayed[player.UserID]; spells > 0 {
+				h.Quests.Record(context, models.QuestEvent{UserID: player.UserID, Type: models.EventSpellPlayed, Count: spells})
+			}
+		}
+	}
+
+	// Profile stats count 1v1 wins and losses, unless the loser has deleted
+	// their account. The result is already recorded, so failing to count it
+	// doesn't fail the request.
+	if winnerID.Valid && len(match.Players) == 2 && !match.HasBots() {
+		loserID := match.Players[0].UserID
+		if loserID == form.WinnerID {
+			loserID = match.Players[1].UserID
+		}
+		if loserID != 0 {
+			if err := h.PlayerRepo.RecordMatchResult(form.WinnerID, loserID); err != nil {
+				logger.GetLogger().Error("Failed to record win and loss:", err)
+			}
+		}
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Match result recorded"})
+}
+
+// startChallenge locks in both decks and starts the unranked match.
+func (h MatchHandlers) startChallenge(context *gin.Context, user *middleware.Principal, challenge models.Challenge, deckID uint) {
+	if challenge.ChallengerID == user.ID {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "You can't accept your own challenge"})
+		return
+	}
+
+	blocked, err := h.FriendRepo.IsBlocked(user.ID, challenge.ChallengerID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to check block:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if blocked {
+		context.JSON(http.StatusForbidden, gin.H{"error": "You can't join this challenge"})
+		return
+	}
+
+	opponentDeck, ok := h.lockDeck(context, user.ID, deckID)
+	if !ok {
+		return
+	}
+	challengerDeck, err := snapshotDeck(h.GameRepo, challenge.ChallengerID, challenge.ChallengerDeckID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to lock challenger deck:", err)
+		context.JSON(http.StatusConflict, gin.H{"error": "The challenger's deck is no longer available"})
+		return
+	}
+
+	players := []models.MatchPlayer{
+		{UserID: challenge.ChallengerID, Username: challenge.ChallengerName, Deck: challengerDeck},
+		{UserID: user.ID, Username: user.Username, Deck: opponentDeck},
+	}
+	match, err := h.MatchRepo.StartChallenge(challenge.ID, user.ID, h.Clock.Now(), players)
+	if err != nil {
+		if errors.Is(err, helper.ErrChallengeUnavailable) {
+			context.JSON(http.StatusGone, gin.H{"error": "Challenge has expired or was already answered"})
+			return
+		}
+		logger.GetLogger().Error("Failed to start challenge:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start match"})
+		return
+	}
+
+	logger.GetLogger().Info("Friendly match started")
+	context.JSON(http.StatusOK, gin.H{"match": dto.NewMatch(match)})
+}
+
+// lockDeck validates the player's chosen deck and writes the error response
+// itself when it can't be used.
+func (h MatchHandlers) lockDeck(context *gin.Context, userID, deckID uint) (models.DeckSnapshot, bool) {
+	snapshot, err := snapshotDeck(h.GameRepo, userID, deckID)
+	if err != nil {
+		logger.GetLogger().Warn("Deck can't be used for a match:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Choose one of your decks with at least one card"})
+		return models.DeckSnapshot{}, false
+	}
+	return snapshot, true
+}
+
+// snapshotDeck copies one of the player's decks so that later edits to it
+// don't change a match or tournament already under way.
+func snapshotDeck(gameRepo repository.GameRepo, userID, deckID uint) (models.DeckSnapshot, error) {
+	deck, err := gameRepo.GetDeckByID(deckID)
+	if err != nil {
+		return models.DeckSnapshot{}, err
+	}
+	if deck.UserID != userID {
+		return models.DeckSnapshot{}, helper.ErrDeckNotFound
+	}
+
+	snapshot := models.DeckSnapshot{DeckID: deck.ID, Name: deck.Name}
+	if snapshot.Heroes, err = gameRepo.GetDeckHeros(deck.ID); err != nil {
+		return models.DeckSnapshot{}, err
+	}
+	if snapshot.Spells, err = gameRepo.GetDeckSpells(deck.ID); err != nil {
+		return models.DeckSnapshot{}, err
+	}
+	if len(snapshot.Heroes)+len(snapshot.Spells) == 0 {
+		return models.DeckSnapshot{}, errors.New("deck is empty")
+	}
+	return snapshot, nil
+}
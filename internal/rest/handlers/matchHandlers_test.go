@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/internal/rest/models"
+	"auth/pkg/rest/helper"
+	"context"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMatches holds the matches a result can be reported for; the embedded
+// interface panics on anything else.
+type fakeMatches struct {
+	repository.MatchRepo
+	matches map[uint]models.Match
+}
+
+func (f *fakeMatches) GetMatch(id uint) (models.Match, error) {
+	match, ok := f.matches[id]
+	if !ok {
+		return models.Match{}, helper.ErrMatchNotFound
+	}
+	return match, nil
+}
+
+func (f *fakeMatches) FinishMatch(id uint, winnerID, winningTeam sql.NullInt64, now time.Time) error {
+	match := f.matches[id]
+	if match.Status == models.MatchFinished {
+		return helper.ErrMatchFinished
+	}
+	match.Status = models.MatchFinished
+	match.WinnerID = winnerID
+	match.WinningTeam = winningTeam
+	f.matches[id] = match
+	return nil
+}
+
+// fakePlayers counts the 1v1 results recorded for profiles.
+type fakePlayers struct {
+	repository.PlayerRepo
+	results [][2]uint
+}
+
+func (f *fakePlayers) RecordMatchResult(winnerID, loserID uint) error {
+	f.results = append(f.results, [2]uint{winnerID, loserID})
+	return nil
+}
+
+// fakeQuests keeps the events recorded toward quests.
+type fakeQuests struct {
+	mu     sync.Mutex
+	events []models.QuestEvent
+}
+
+func (f *fakeQuests) Record(ctx context.Context, event models.QuestEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+}
+
+type matchTest struct {
+	router  *gin.Engine
+	matches *fakeMatches
+	players *fakePlayers
+	quests  *fakeQuests
+}
+
+func newMatchTest(matches ...models.Match) *matchTest {
+	test := &matchTest{
+		matches: &fakeMatches{matches: make(map[uint]models.Match)},
+		players: &fakePlayers{},
+		quests:  &fakeQuests{},
+	}
+	for _, match := range matches {
+		test.matches.matches[match.ID] = match
+	}
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	h := NewMatchHandlers(nil, nil, nil, test.matches, test.players, config.MatchConfig{}, clock, nil, test.quests)
+
+	test.router = gin.New()
+	test.router.POST("/matches/:id/result", h.ReportResult)
+	return test
+}
+
+func (test *matchTest) report(t *testing.T, matchID uint, result gin.H) (int, map[string]interface{}) {
+	t.Helper()
+	return call(t, test.router, http.MethodPost, "/matches/"+strconv.Itoa(int(matchID))+"/result", "", result)
+}
+
+func TestReportResultAgainstDeletedPlayer(t *testing.T) {
+	// The second seat belonged to a player who has since deleted their account.
+	test := newMatchTest(models.Match{
+		ID:      1,
+		Mode:    models.MatchModeFriendly,
+		Ranked:  true,
+		Status:  models.MatchStarted,
+		Players: []models.MatchPlayer{{UserID: 7, Username: "alice"}, {Username: "Deleted player"}},
+	})
+
+	status, body := test.report(t, 1, gin.H{"winnerId": 7})
+	if status != http.StatusOK {
+		t.Fatalf("report answered %d: %v", status, body)
+	}
+	if len(test.players.results) != 0 {
+		t.Errorf("recorded %v, want no profile result without a loser", test.players.results)
+	}
+	for _, event := range test.quests.events {
+		if event.UserID != 7 {
+			t.Errorf("recorded a quest event for user %d", event.UserID)
+		}
+	}
+	if len(test.quests.events) == 0 {
+		t.Error("recorded no quest events for alice")
+	}
+}
+
+func TestReportResultRecordsQuestsForRankedMatchesOnly(t *testing.T) {
+	players := []models.MatchPlayer{{UserID: 7, Username: "alice"}, {UserID: 8, Username: "bob"}}
+	test := newMatchTest(
+		models.Match{ID: 1, Mode: models.MatchModeFriendly, Status: models.MatchStarted, Players: players},
+		models.Match{ID: 2, Mode: models.MatchModeFriendly, Ranked: true, Status: models.MatchStarted, Players: players},
+	)
+	result := gin.H{"winnerId": 7, "players": []gin.H{{"userId": 7, "spellsPlayed": 3}}}
+
+	if status, body := test.report(t, 1, result); status != http.StatusOK {
+		t.Fatalf("report answered %d: %v", status, body)
+	}
+	if len(test.quests.events) != 0 {
+		t.Errorf("a friendly match recorded quest events %v", test.quests.events)
+	}
+
+	if status, body := test.report(t, 2, result); status != http.StatusOK {
+		t.Fatalf("report answered %d: %v", status, body)
+	}
+	want := []models.QuestEvent{
+		{UserID: 7, Type: models.EventMatchPlayed},
+		{UserID: 7, Type: models.EventMatchWon},
+		{UserID: 7, Type: models.EventSpellPlayed, Count: 3},
+		{UserID: 8, Type: models.EventMatchPlayed},
+	}
+	if !reflect.DeepEqual(test.quests.events, want) {
+		t.Errorf("recorded %v, want %v", test.quests.events, want)
+	}
+}
+
+func TestReportResultRecordsNoQuestsForBotMatches(t *testing.T) {
+	// A ranked 2v2 whose queue was filled with a bot.
+	test := newMatchTest(models.Match{
+		ID:     1,
+		Mode:   models.MatchModeTeam2v2,
+		Ranked: true,
+		Status: models.MatchStarted,
+		Players: []models.MatchPlayer{
+			{UserID: 7, Username: "alice", Team: 1},
+			{UserID: 8, Username: "bob", Team: 1},
+			{UserID: 9, Username: "carol", Team: 2},
+			{Bot: models.BotNormal, Team: 2},
+		},
+	})
+
+	result := gin.H{"winningTeam": 1, "players": []gin.H{{"userId": 7, "spellsPlayed": 3}, {"userId": 9, "spellsPlayed": 2}}}
+	if status, body := test.report(t, 1, result); status != http.StatusOK {
+		t.Fatalf("report answered %d: %v", status, body)
+	}
+	if len(test.quests.events) != 0 {
+		t.Errorf("a match with a bot recorded quest events %v", test.quests.events)
+	}
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	redis "auth/internal/db/redis"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/middleware"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const moderationPageSize = 50
+
+// ModerationHandlers back the moderator tools. Every change they make is
+// written to the moderation audit log by the repository.
+type ModerationHandlers struct {
+	UserRepo    repository.UserRepo
+	ChatRepo    repository.ChatRepo
+	RedisConfig config.RedisConfig
+	Clock       utils.Clock
+}
+
+func NewModerationHandlers(userRepo repository.UserRepo, chatRepo repository.ChatRepo, redisConfig config.RedisConfig, clock utils.Clock) *ModerationHandlers {
+	return &ModerationHandlers{UserRepo: userRepo, ChatRepo: chatRepo, RedisConfig: redisConfig, Clock: clock}
+}
+
+func (h ModerationHandlers) ListReports(context *gin.Context) {
+	logger.GetLogger().Info("Fetching chat reports")
+
+	status := strings.ToUpper(context.DefaultQuery("status", models.ChatReportOpen))
+	if status == "ALL" {
+		status = ""
+	}
+
+	before, limit, ok := parseCursor(context, moderationPageSize)
+	if !ok {
+		return
+	}
+
+	reports, err := h.ChatRepo.GetReports(status, before, limit)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get chat reports:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	response := gin.H{"reports": dto.NewChatReports(reports)}
+	if len(reports) > 0 && len(reports) == limit {
+		response["nextCursor"] = reports[len(reports)-1].ID
+	}
+	context.JSON(http.StatusOK, response)
+}
+
+func (h ModerationHandlers) ResolveReport(context *gin.Context) {
+	logger.GetLogger().Info("Resolving chat report")
+
+	var form forms.ResolveReportForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid resolve report request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	moderator, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	reportID, ok := parseID(context, "id", "report")
+	if !ok {
+		return
+	}
+
+	if err := h.ChatRepo.ResolveReport(reportID, moderator.ID, form.Status); err != nil {
+		if errors.Is(err, helper.ErrReportResolved) {
+			context.JSON(http.StatusConflict, gin.H{"error": "Report is not open"})
+			return
+		}
+		logger.GetLogger().Error("Failed to resolve chat report:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve report"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Report resolved"})
+}
+
+// HideMessage removes a message from chat history and tells live streams to
+// drop it. Open reports against it are marked actioned.
+func (h ModerationHandlers) HideMessage(context *gin.Context) {
+	logger.GetLogger().Info("Hiding chat message")
+
+	var form forms.HideMessageForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid hide message request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	moderator, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	messageID, ok := parseID(context, "id", "message")
+	if !ok {
+		return
+	}
+
+	message, err := h.ChatRepo.GetMessage(messageID)
+	if err != nil {
+		if errors.Is(err, helper.ErrMessageNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get chat message:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if err := h.ChatRepo.HideMessage(message.ID, moderator.ID, strings.TrimSpace(form.Reason), h.Clock.Now()); err != nil {
+		logger.GetLogger().Error("Failed to hide chat message:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hide message"})
+		return
+	}
+
+	event := dto.ChatEvent{Type: chatEventHidden, MessageID: message.ID}
+	if message.RecipientID.Valid {
+		publishChatEvent(context, h.RedisConfig, event,
+			redis.UserChatChannel(message.SenderID), redis.UserChatChannel(uint(message.RecipientID.Int64)))
+	} else if message.ClanID.Valid {
+		publishChatEvent(context, h.RedisConfig, event, redis.ClanChatChannel(uint(message.ClanID.Int64)))
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Message hidden"})
+}
+
+func (h ModerationHandlers) ListSanctions(context *gin.Context) {
+	logger.GetLogger().Info("Fetching chat sanctions")
+
+	userID, ok := parseID(context, "userID", "user")
+	if !ok {
+		return
+	}
+
+	sanctions, err := h.ChatRepo.GetActiveSanctions(userID, h.Clock.Now())
+	if err != nil {
+		logger.GetLogger().Error("Failed to get chat sanctions:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	result := make([]dto.ChatSanction, 0, len(sanctions))
+	for _, sanction := range sanctions {
+		result = append(result, dto.NewChatSanction(sanction))
+	}
+	context.JSON(http.StatusOK, gin.H{"sanctions": result})
+}
+
+// SanctionUser mutes or bans a player from chat. Moderators can't sanction
+// each other; that is left to admins editing accounts directly.
+func (h ModerationHandlers) SanctionUser(context *gin.Context) {
+	logger.GetLogger().Info("Sanctioning chat user")
+
+	var form forms.SanctionForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid sanction request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	moderator, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	userID, ok := parseID(context, "userID", "user")
+	if !ok {
+		return
+	}
+
+	target, err := h.UserRepo.GetUserByID(userID)
+	if err != nil {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+		return
+	}
+	if target.ID == moderator.ID || middleware.NewPrincipal(target).IsModerator() {
+		context.JSON(http.StatusForbidden, gin.H{"error": "This player can't be sanctioned"})
+		return
+	}
+
+	sanction := models.ChatSanction{
+		UserID:    target.ID,
+		Kind:      form.Kind,
+		Reason:    strings.TrimSpace(form.Reason),
+		CreatedBy: moderator.ID,
+	}
+	if form.Minutes > 0 {
+		sanction.ExpiresAt.Time = h.Clock.Now().Add(time.Duration(form.Minutes) * time.Minute)
+		sanction.ExpiresAt.Valid = true
+	}
+
+	if err := h.ChatRepo.CreateSanction(&sanction); err != nil {
+		logger.GetLogger().Error("Failed to create chat sanction:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sanction player"})
+		return
+	}
+
+	logger.GetLogger().Info("Chat sanction created")
+	context.JSON(http.StatusCreated, gin.H{"sanction": dto.NewChatSanction(sanction)})
+}
+
+func (h ModerationHandlers) RevokeSanction(context *gin.Context) {
+	logger.GetLogger().Info("Revoking chat sanction")
+
+	moderator, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	sanctionID, ok := parseID(context, "id", "sanction")
+	if !ok {
+		return
+	}
+
+	sanction, err := h.ChatRepo.RevokeSanction(sanctionID, moderator.ID, h.Clock.Now())
+	if err != nil {
+		if errors.Is(err, helper.ErrSanctionNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Sanction not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to revoke chat sanction:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sanction"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"sanction": dto.NewChatSanction(sanction)})
+}
+
+func (h ModerationHandlers) GetAuditLog(context *gin.Context) {
+	logger.GetLogger().Info("Fetching moderation audit log")
+
+	before, limit, ok := parseCursor(context, moderationPageSize)
+	if !ok {
+		return
+	}
+
+	actions, err := h.ChatRepo.GetAuditLog(before, limit)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get moderation audit log:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	response := gin.H{"actions": dto.NewModerationActions(actions)}
+	if len(actions) > 0 && len(actions) == limit {
+		response["nextCursor"] = actions[len(actions)-1].ID
+	}
+	context.JSON(http.StatusOK, response)
+}
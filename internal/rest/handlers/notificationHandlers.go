@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+const notificationPageSize = 30
+
+type NotificationHandlers struct {
+	NotificationRepo repository.NotificationRepo
+	Notifications    *notifications.Service
+	Clock            utils.Clock
+}
+
+func NewNotificationHandlers(notificationRepo repository.NotificationRepo, service *notifications.Service, clock utils.Clock) *NotificationHandlers {
+	return &NotificationHandlers{NotificationRepo: notificationRepo, Notifications: service, Clock: clock}
+}
+
+func (h NotificationHandlers) ListNotifications(context *gin.Context) {
+	logger.GetLogger().Info("Fetching notifications")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	before, limit, ok := parseCursor(context, notificationPageSize)
+	if !ok {
+		return
+	}
+	unreadOnly := context.Query("unread") == "true"
+
+	list, err := h.NotificationRepo.GetNotifications(user.ID, before, limit, unreadOnly)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get notifications:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	unread, err := h.NotificationRepo.CountUnread(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to count unread notifications:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	response := gin.H{"notifications": dto.NewNotifications(list), "unreadCount": unread}
+	if len(list) > 0 && len(list) == limit {
+		response["nextCursor"] = list[len(list)-1].ID
+	}
+	context.JSON(http.StatusOK, response)
+}
+
+func (h NotificationHandlers) UnreadCount(context *gin.Context) {
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	unread, err := h.NotificationRepo.CountUnread(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to count unread notifications:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"unreadCount": unread})
+}
+
+func (h NotificationHandlers) MarkRead(context *gin.Context) {
+	logger.GetLogger().Info("Marking notification read")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	notificationID, ok := parseID(context, "id", "notification")
+	if !ok {
+		return
+	}
+
+	if err := h.NotificationRepo.MarkRead(user.ID, notificationID, h.Clock.Now()); err != nil {
+		if errors.Is(err, helper.ErrNotificationNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to mark notification read:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+func (h NotificationHandlers) MarkAllRead(context *gin.Context) {
+	logger.GetLogger().Info("Marking all notifications read")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	marked, err := h.NotificationRepo.MarkAllRead(user.ID, h.Clock.Now())
+	if err != nil {
+		logger.GetLogger().Error("Failed to mark notifications read:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read", "marked": marked})
+}
+
+func (h NotificationHandlers) GetSettings(context *gin.Context) {
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	settings, err := h.NotificationRepo.GetSettings(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get notification settings:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"settings": dto.NewNotificationSettings(settings)})
+}
+
+func (h NotificationHandlers) UpdateSettings(context *gin.Context) {
+	logger.GetLogger().Info("Updating notification settings")
+
+	var form forms.NotificationSettingsForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid notification settings:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	settings := models.NotificationSettings{UserID: user.ID, EmailDigest: *form.EmailDigest}
+	if err := h.NotificationRepo.UpdateSettings(settings); err != nil {
+		logger.GetLogger().Error("Failed to update notification settings:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"settings": dto.NewNotificationSettings(settings)})
+}
+
+func (h NotificationHandlers) Broadcast(context *gin.Context) {
+	logger.GetLogger().Info("Broadcasting notification")
+
+	var form forms.BroadcastForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid broadcast request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	notification := models.Notification{
+		Kind:      form.Kind,
+		Title:     strings.TrimSpace(form.Title),
+		Body:      form.Body,
+		CreatedAt: h.Clock.Now(),
+	}
+	sent, err := h.Notifications.Broadcast(context, notification)
+	if err != nil {
+		logger.GetLogger().Error("Failed to broadcast notification:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		return
+	}
+
+	logger.GetLogger().Info("Notification broadcast to players: ", sent)
+	context.JSON(http.StatusCreated, gin.H{"message": "Notification sent", "recipients": sent})
+}
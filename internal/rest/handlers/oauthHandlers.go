@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	redis "auth/internal/db/redis"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/oauth"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const oauthStateCookie = "oauth_state"
+
+func (h AuthHandlers) OAuthLogin(context *gin.Context) {
+	logger.GetLogger().Info("Starting external login")
+
+	provider, ok := h.Providers[context.Param("provider")]
+	if !ok {
+		logger.GetLogger().Error("Unknown identity provider:", context.Param("provider"))
+		context.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	// restore=true lets accounts without a password come back during the
+	// deletion grace period, as RestoreAccount needs one.
+	authURL, err := h.startOAuth(context, provider, 0, context.Query("restore") == "true")
+	if err != nil {
+		logger.GetLogger().Error("Failed to start external login:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start external login"})
+		return
+	}
+
+	context.Redirect(http.StatusFound, authURL)
+}
+
+func (h AuthHandlers) OAuthLink(context *gin.Context) {
+	logger.GetLogger().Info("Starting identity linking")
+
+	provider, ok := h.Providers[context.Param("provider")]
+	if !ok {
+		logger.GetLogger().Error("Unknown identity provider:", context.Param("provider"))
+		context.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	authURL, err := h.startOAuth(context, provider, user.ID, false)
+	if err != nil {
+		logger.GetLogger().Error("Failed to start identity linking:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start identity linking"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"url": authURL})
+}
+
+func (h AuthHandlers) OAuthCallback(context *gin.Context) {
+	logger.GetLogger().Info("Handling identity provider callback")
+
+	provider, ok := h.Providers[context.Param("provider")]
+	if !ok {
+		logger.GetLogger().Error("Unknown identity provider:", context.Param("provider"))
+		context.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	if providerErr := context.Query("error"); providerErr != "" {
+		logger.GetLogger().Warn("Identity provider returned error:", providerErr)
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "External login was cancelled or failed"})
+		return
+	}
+
+	state := context.Query("state")
+	cookieState, err := context.Cookie(oauthStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		logger.GetLogger().Error("OAuth state mismatch")
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid login state"})
+		return
+	}
+	h.clearOAuthCookie(context)
+
+	savedState, err := redis.TakeOAuthState(context, h.RedisConfig, state)
+	if err != nil {
+		logger.GetLogger().Error("Failed to read OAuth state:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete external login"})
+		return
+	}
+	if savedState == nil || savedState.Provider != provider.Name() {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Login state expired, try again"})
+		return
+	}
+
+	identity, err := provider.Exchange(context, context.Query("code"), savedState.CodeVerifier)
+	if err != nil {
+		logger.GetLogger().Error("Failed to exchange authorization code:", err)
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "External login failed"})
+		return
+	}
+
+	if savedState.LinkUserID != 0 {
+		h.linkIdentity(context, provider.Name(), identity, savedState.LinkUserID)
+		return
+	}
+
+	user, err := h.userForIdentity(provider.Name(), identity)
+	if errors.Is(err, helper.ErrEmailNotVerified) {
+		logger.GetLogger().Warn("Identity without a verified email:", provider.Name())
+		context.JSON(http.StatusForbidden, gin.H{"error": "A verified email is required to sign in with " + provider.Name()})
+		return
+	} else if err != nil {
+		logger.GetLogger().Error("Failed to resolve user for identity:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete external login"})
+		return
+	}
+
+	if user.DeletedAt.Valid && savedState.Restore && !h.restoreUser(context, user) {
+		return
+	}
+	h.completeLogin(context, user)
+}
+
+// restoreUser brings back an account scheduled for deletion while the grace
+// period lasts. It answers the request itself when that fails.
+func (h AuthHandlers) restoreUser(context *gin.Context, user *models.User) bool {
+	if h.Clock.Now().Sub(user.DeletedAt.Time) > h.Account.DeletionGrace {
+		logger.GetLogger().Warn("Restore attempt after grace period for user:", user.ID)
+		context.JSON(http.StatusGone, gin.H{"error": "The grace period for restoring this account has ended"})
+		return false
+	}
+	if err := h.Repo.RestoreUser(user.ID); err != nil {
+		logger.GetLogger().Error("Failed to restore user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore account"})
+		return false
+	}
+	logger.GetLogger().Info("User account restored:", user.ID)
+	user.DeletedAt = pq.NullTime{}
+	return true
+}
+
+func (h AuthHandlers) ListIdentities(context *gin.Context) {
+	logger.GetLogger().Info("Fetching linked identities")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	identities, err := h.IdentityRepo.GetIdentitiesForUser(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get identities:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"identities": dto.NewIdentities(identities)})
+}
+
+func (h AuthHandlers) UnlinkIdentity(context *gin.Context) {
+	logger.GetLogger().Info("Unlinking identity")
+
+	user, ok := loadCurrentUser(context, h.Repo)
+	if !ok {
+		return
+	}
+
+	identityID, ok := parseID(context, "id", "identity")
+	if !ok {
+		return
+	}
+
+	identities, err := h.IdentityRepo.GetIdentitiesForUser(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get identities:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	// Accounts created through a provider have no password; keep at least one way in.
+	if user.Password == "" && len(identities) <= 1 {
+		context.JSON(http.StatusConflict, gin.H{"error": "Set a password before removing your last linked identity"})
+		return
+	}
+
+	if err := h.IdentityRepo.DeleteIdentity(user.ID, identityID); err != nil {
+		if errors.Is(err, helper.ErrIdentityNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to unlink identity:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Identity unlinked"})
+}
+
+func (h AuthHandlers) startOAuth(context *gin.Context, provider oauth.Provider, linkUserID uint, restore bool) (string, error) {
+	state, err := utils.GenerateChallengeToken()
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := utils.GenerateChallengeToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = redis.SaveOAuthState(context, h.RedisConfig, state, redis.OAuthState{
+		Provider:     provider.Name(),
+		CodeVerifier: codeVerifier,
+		LinkUserID:   linkUserID,
+		Restore:      restore,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	cookie := http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/app/auth/oauth",
+		Expires:  time.Now().Add(time.Minute * 10),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(context.Writer, &cookie)
+
+	return provider.AuthCodeURL(state, codeVerifier), nil
+}
+
+func (h AuthHandlers) clearOAuthCookie(context *gin.Context) {
+	cookie := http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		Path:     "/app/auth/oauth",
+		Expires:  time.Now().Add(-time.Hour),
+		HttpOnly: true,
+	}
+	http.SetCookie(context.Writer, &cookie)
+}
+
+func (h AuthHandlers) linkIdentity(context *gin.Context, providerName string, identity *oauth.Identity, userID uint) {
+	existing, err := h.IdentityRepo.GetIdentity(providerName, identity.Subject)
+	if err != nil && !errors.Is(err, helper.ErrIdentityNotFound) {
+		logger.GetLogger().Error("Failed to get identity:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link identity"})
+		return
+	}
+	if err == nil {
+		if existing.UserID != userID {
+			logger.GetLogger().Warn("Identity already linked to another account")
+			context.JSON(http.StatusConflict, gin.H{"error": "This identity is linked to another account"})
+			return
+		}
+		context.JSON(http.StatusOK, gin.H{"message": "Identity already linked", "identity": dto.NewIdentity(*existing)})
+		return
+	}
+
+	linked := models.Identity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := h.IdentityRepo.CreateIdentity(&linked); err != nil {
+		logger.GetLogger().Error("Failed to link identity:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link identity"})
+		return
+	}
+
+	logger.GetLogger().Info("Identity linked successfully")
+	context.JSON(http.StatusOK, gin.H{"message": "Identity linked successfully", "identity": dto.NewIdentity(linked)})
+}
+
+// userForIdentity finds the account behind an external identity. Unknown
+// identities are attached to the account with the same email, but only when
+// the provider has verified that email, and helper.ErrEmailNotVerified is
+// returned when it hasn't; otherwise a new account is created.
+func (h AuthHandlers) userForIdentity(providerName string, identity *oauth.Identity) (*models.User, error) {
+	existing, err := h.IdentityRepo.GetIdentity(providerName, identity.Subject)
+	if err == nil {
+		return h.Repo.GetUserByID(existing.UserID)
+	} else if !errors.Is(err, helper.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, helper.ErrEmailNotVerified
+	}
+
+	user, err := h.Repo.GetUserByEmail(identity.Email)
+	if err == sql.ErrNoRows {
+		user, err = h.createUserForIdentity(identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	linked := models.Identity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := h.IdentityRepo.CreateIdentity(&linked); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Usernames are 3 to 32 characters, as the register form requires.
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 32
+)
+
+// usernameBase makes a valid username out of the provider's display name,
+// or else the email's local part, by dropping the characters usernames can't
+// have.
+func usernameBase(identity *oauth.Identity) string {
+	for _, candidate := range []string{identity.Name, strings.Split(identity.Email, "@")[0]} {
+		var base strings.Builder
+		for _, r := range candidate {
+			if validUsername(string(r)) {
+				base.WriteRune(r)
+			}
+		}
+		if name := base.String(); len(name) >= minUsernameLength {
+			return name[:min(len(name), maxUsernameLength)]
+		}
+	}
+	return "player"
+}
+
+// createUserForIdentity creates the account for a new identity. When the
+// name is taken, a random suffix is added until one is free.
+func (h AuthHandlers) createUserForIdentity(identity *oauth.Identity) (*models.User, error) {
+	base := usernameBase(identity)
+	username := base
+	for attempt := 0; attempt < 10; attempt++ {
+		_, err := h.Repo.GetUserByUsername(username)
+		if err == sql.ErrNoRows {
+			user := models.User{
+				Username: username,
+				Email:    identity.Email,
+				Bank:     10000,
+				UserType: "USER",
+			}
+			err = h.Repo.CreateUser(&user)
+			if err == nil {
+				return &user, nil
+			}
+			if !errors.Is(err, helper.ErrUsernameTaken) {
+				return nil, fmt.Errorf("failed to create user: %v", err)
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to check username: %v", err)
+		}
+
+		suffix := utils.GenerateVerificationCode()
+		username = base[:min(len(base), maxUsernameLength-len(suffix)-1)] + "_" + suffix
+	}
+	return nil, fmt.Errorf("failed to find a free username for %q", base)
+}
@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/db/redis/redistest"
+	"auth/internal/rest/models"
+	"auth/pkg/middleware"
+	"auth/pkg/oauth"
+	"auth/pkg/oauth/oauthtest"
+	"auth/pkg/rest/helper"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeIdentities mirrors IdentityRepository: a provider's subject is linked
+// to at most one account.
+type fakeIdentities struct {
+	mu         sync.Mutex
+	identities []models.Identity
+	err        error
+}
+
+func (f *fakeIdentities) GetIdentity(provider, subject string) (*models.Identity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, identity := range f.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			found := identity
+			return &found, nil
+		}
+	}
+	return nil, helper.ErrIdentityNotFound
+}
+
+func (f *fakeIdentities) GetIdentitiesForUser(userID uint) ([]models.Identity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var identities []models.Identity
+	for _, identity := range f.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (f *fakeIdentities) CreateIdentity(identity *models.Identity) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	identity.ID = uint(len(f.identities) + 1)
+	f.identities = append(f.identities, *identity)
+	return nil
+}
+
+func (f *fakeIdentities) DeleteIdentity(userID, identityID uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, identity := range f.identities {
+		if identity.ID == identityID && identity.UserID == userID {
+			f.identities = append(f.identities[:i], f.identities[i+1:]...)
+			return nil
+		}
+	}
+	return helper.ErrIdentityNotFound
+}
+
+const oauthCallbackURL = "http://localhost:8080/app/auth/oauth/stub/callback"
+
+type oauthTest struct {
+	router     *gin.Engine
+	users      *fakeUsers
+	identities *fakeIdentities
+	provider   *oauthtest.Server
+	clock      *fakeClock
+}
+
+func newOAuthTest(t *testing.T) *oauthTest {
+	_, redisConfig := redistest.Start(t)
+	test := &oauthTest{users: newFakeUsers(), identities: &fakeIdentities{}, provider: oauthtest.Start(t)}
+	providers := []oauth.Provider{oauth.NewOIDCProvider(test.provider.Config("stub", oauthCallbackURL))}
+	test.clock = &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	accountConfig := config.AccountConfig{DeletionGrace: 30 * 24 * time.Hour}
+	h := NewAuthHandlers(test.users, newFakeTwoFactor(), test.identities, providers, redisConfig, config.EmailConfig{}, accountConfig, test.clock)
+
+	test.router = gin.New()
+	test.router.GET("/app/auth/oauth/:provider/login", h.OAuthLogin)
+	test.router.GET("/app/auth/oauth/:provider/callback", h.OAuthCallback)
+	test.router.POST("/app/auth/oauth/:provider/link", middleware.RequireUser(test.users), h.OAuthLink)
+	test.router.DELETE("/app/auth/identities/:id", middleware.RequireUser(test.users), h.UnlinkIdentity)
+	return test
+}
+
+// send serves the request and decodes the JSON answer, if there is one.
+func (test *oauthTest) send(t *testing.T, request *http.Request) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	test.router.ServeHTTP(recorder, request)
+	var response map[string]interface{}
+	if recorder.Header().Get("Content-Type") == "application/json; charset=utf-8" {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s answered %d with invalid JSON %q", request.URL, recorder.Code, recorder.Body.String())
+		}
+	}
+	return recorder, response
+}
+
+// start begins an external login and returns the provider's authorization
+// URL and the state cookie set for the browser.
+func (test *oauthTest) start(t *testing.T) (string, *http.Cookie) {
+	t.Helper()
+	return test.startAt(t, "/app/auth/oauth/stub/login")
+}
+
+func (test *oauthTest) startAt(t *testing.T, path string) (string, *http.Cookie) {
+	t.Helper()
+	recorder, body := test.send(t, httptest.NewRequest(http.MethodGet, path, nil))
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("login answered %d: %v", recorder.Code, body)
+	}
+	return recorder.Header().Get("Location"), stateCookie(t, recorder)
+}
+
+// startLink begins linking an identity to the user's account.
+func (test *oauthTest) startLink(t *testing.T, user *models.User) (string, *http.Cookie) {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, "/app/auth/oauth/stub/link", nil)
+	request.Header.Set("Authorization", "Bearer "+tokenFor(t, user))
+	recorder, body := test.send(t, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("link answered %d: %v", recorder.Code, body)
+	}
+	return body["url"].(string), stateCookie(t, recorder)
+}
+
+func stateCookie(t *testing.T, recorder *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == oauthStateCookie {
+			return cookie
+		}
+	}
+	t.Fatal("no state cookie was set")
+	return nil
+}
+
+// callback brings the provider's redirect back to the app with the cookie.
+func (test *oauthTest) callback(t *testing.T, redirect *url.URL, cookie *http.Cookie) (int, map[string]interface{}) {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodGet, "/app/auth/oauth/stub/callback?"+redirect.RawQuery, nil)
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	recorder, body := test.send(t, request)
+	return recorder.Code, body
+}
+
+// signIn runs the whole flow for the identity.
+func (test *oauthTest) signIn(t *testing.T, identity oauth.Identity) (int, map[string]interface{}) {
+	t.Helper()
+	test.provider.SignIn(identity)
+	authURL, cookie := test.start(t)
+	return test.callback(t, test.provider.Authorize(t, authURL), cookie)
+}
+
+func TestOAuthLoginRedirectsWithPKCE(t *testing.T) {
+	test := newOAuthTest(t)
+
+	authURL, cookie := test.start(t)
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             oauthtest.ClientID,
+		"redirect_uri":          oauthCallbackURL,
+		"scope":                 "openid email profile",
+		"state":                 cookie.Value,
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := query.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	// The challenge is a SHA-256 hash, never the verifier itself.
+	if len(query.Get("code_challenge")) != 43 {
+		t.Errorf("code_challenge %q is not a base64url SHA-256 hash", query.Get("code_challenge"))
+	}
+	if !cookie.HttpOnly {
+		t.Error("state cookie is readable from scripts")
+	}
+
+	recorder, _ := test.send(t, httptest.NewRequest(http.MethodGet, "/app/auth/oauth/unknown/login", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown provider answered %d, want 404", recorder.Code)
+	}
+}
+
+func TestOAuthCallbackCreatesAccount(t *testing.T) {
+	test := newOAuthTest(t)
+	identity := oauth.Identity{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true, Name: "Alice Smith"}
+
+	status, body := test.signIn(t, identity)
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("callback answered %d: %v", status, body)
+	}
+	user, err := test.users.GetUserByEmail("alice@example.com")
+	if err != nil {
+		t.Fatal("no account was created")
+	}
+	if user.Username != "AliceSmith" || user.Password != "" {
+		t.Errorf("created %+v", user)
+	}
+
+	// Signing in again finds the same account through the identity.
+	status, body = test.signIn(t, identity)
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("second callback answered %d: %v", status, body)
+	}
+	if users, _ := test.users.GetAllUsers(); len(users) != 1 {
+		t.Errorf("got %d accounts, want 1", len(users))
+	}
+	if len(test.identities.identities) != 1 {
+		t.Errorf("got %d identities, want 1", len(test.identities.identities))
+	}
+}
+
+func TestOAuthCallbackLinksVerifiedEmail(t *testing.T) {
+	test := newOAuthTest(t)
+	alice := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+
+	status, body := test.signIn(t, oauth.Identity{Subject: "sub-1", Email: "Alice@Example.com", EmailVerified: true})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("callback answered %d: %v", status, body)
+	}
+	identities, _ := test.identities.GetIdentitiesForUser(alice.ID)
+	if len(identities) != 1 || identities[0].Subject != "sub-1" {
+		t.Errorf("alice has identities %+v", identities)
+	}
+}
+
+func TestOAuthCallbackRejectsUnverifiedEmail(t *testing.T) {
+	test := newOAuthTest(t)
+	test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+
+	for _, identity := range []oauth.Identity{
+		{Subject: "sub-1", Email: "alice@example.com"},
+		{Subject: "sub-2", Email: "bob@example.com"},
+		{Subject: "sub-3", EmailVerified: true},
+	} {
+		status, body := test.signIn(t, identity)
+		if status != http.StatusForbidden || body["token"] != nil {
+			t.Errorf("callback for %+v answered %d: %v", identity, status, body)
+		}
+	}
+	if len(test.identities.identities) != 0 {
+		t.Errorf("linked %+v", test.identities.identities)
+	}
+	if users, _ := test.users.GetAllUsers(); len(users) != 1 {
+		t.Errorf("got %d accounts, want 1", len(users))
+	}
+}
+
+func TestOAuthCallbackChecksState(t *testing.T) {
+	test := newOAuthTest(t)
+	test.provider.SignIn(oauth.Identity{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true})
+
+	authURL, cookie := test.start(t)
+	redirect := test.provider.Authorize(t, authURL)
+	if status, _ := test.callback(t, redirect, nil); status != http.StatusBadRequest {
+		t.Errorf("callback without the cookie answered %d, want 400", status)
+	}
+	if status, _ := test.callback(t, redirect, &http.Cookie{Name: oauthStateCookie, Value: "forged"}); status != http.StatusBadRequest {
+		t.Errorf("callback with another state answered %d, want 400", status)
+	}
+
+	if status, body := test.callback(t, redirect, cookie); status != http.StatusOK {
+		t.Fatalf("callback answered %d: %v", status, body)
+	}
+	if status, _ := test.callback(t, redirect, cookie); status != http.StatusBadRequest {
+		t.Errorf("reusing the state answered %d, want 400", status)
+	}
+}
+
+func TestOAuthCallbackRequiresMatchingVerifier(t *testing.T) {
+	test := newOAuthTest(t)
+	test.provider.SignIn(oauth.Identity{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true})
+
+	// A code intercepted from one login can't be redeemed through another,
+	// as that login's verifier doesn't match the code's challenge.
+	victimURL, _ := test.start(t)
+	stolen := test.provider.Authorize(t, victimURL)
+	attackerURL, attackerCookie := test.start(t)
+	attacker := test.provider.Authorize(t, attackerURL)
+
+	query := attacker.Query()
+	query.Set("code", stolen.Query().Get("code"))
+	attacker.RawQuery = query.Encode()
+	if status, body := test.callback(t, attacker, attackerCookie); status != http.StatusUnauthorized || body["token"] != nil {
+		t.Errorf("callback with another login's code answered %d: %v", status, body)
+	}
+}
+
+func TestOAuthLinkIdentity(t *testing.T) {
+	test := newOAuthTest(t)
+	alice := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	bob := test.users.add(t, models.User{Username: "bob", Email: "bob@example.com", Password: "secret-password"})
+	// Linking doesn't go by email, so an unverified one is fine.
+	test.provider.SignIn(oauth.Identity{Subject: "sub-1", Email: "someone@example.com"})
+
+	authURL, cookie := test.startLink(t, alice)
+	status, body := test.callback(t, test.provider.Authorize(t, authURL), cookie)
+	if status != http.StatusOK {
+		t.Fatalf("linking answered %d: %v", status, body)
+	}
+	if identities, _ := test.identities.GetIdentitiesForUser(alice.ID); len(identities) != 1 {
+		t.Errorf("alice has %d identities, want 1", len(identities))
+	}
+
+	authURL, cookie = test.startLink(t, bob)
+	status, _ = test.callback(t, test.provider.Authorize(t, authURL), cookie)
+	if status != http.StatusConflict {
+		t.Errorf("linking alice's identity to bob answered %d, want 409", status)
+	}
+}
+
+func TestOAuthCallbackFailsOnLookupError(t *testing.T) {
+	test := newOAuthTest(t)
+	test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	test.identities.err = errors.New("connection refused")
+
+	status, body := test.signIn(t, oauth.Identity{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true})
+	if status != http.StatusInternalServerError || body["token"] != nil {
+		t.Errorf("login answered %d: %v", status, body)
+	}
+
+	alice, _ := test.users.GetUserByEmail("alice@example.com")
+	authURL, cookie := test.startLink(t, alice)
+	if status, _ := test.callback(t, test.provider.Authorize(t, authURL), cookie); status != http.StatusInternalServerError {
+		t.Errorf("linking answered %d, want 500", status)
+	}
+	if len(test.identities.identities) != 0 {
+		t.Errorf("linked %+v", test.identities.identities)
+	}
+}
+
+func TestUsernameBase(t *testing.T) {
+	tests := []struct {
+		identity oauth.Identity
+		want     string
+	}{
+		{oauth.Identity{Name: "Alice Smith", Email: "alice@example.com"}, "AliceSmith"},
+		{oauth.Identity{Name: "Dr. Who?!", Email: "who@example.com"}, "Dr.Who"},
+		{oauth.Identity{Name: "李伟", Email: "li.wei@example.com"}, "li.wei"},
+		{oauth.Identity{Name: "Al", Email: "al-x@example.com"}, "alx"},
+		{oauth.Identity{Name: strings.Repeat("Long", 10), Email: "long@example.com"}, strings.Repeat("Long", 8)},
+		{oauth.Identity{Name: "李", Email: "李@example.com"}, "player"},
+	}
+	for _, tt := range tests {
+		if got := usernameBase(&tt.identity); got != tt.want {
+			t.Errorf("usernameBase(%q, %q) = %q, want %q", tt.identity.Name, tt.identity.Email, got, tt.want)
+		}
+	}
+}
+
+func TestOAuthCallbackPicksFreeUsername(t *testing.T) {
+	test := newOAuthTest(t)
+	test.users.add(t, models.User{Username: "alicesmith", Email: "other@example.com", Password: "secret-password"})
+
+	status, body := test.signIn(t, oauth.Identity{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true, Name: "Alice Smith"})
+	if status != http.StatusOK {
+		t.Fatalf("callback answered %d: %v", status, body)
+	}
+	user, err := test.users.GetUserByEmail("alice@example.com")
+	if err != nil {
+		t.Fatal("no account was created")
+	}
+	if !strings.HasPrefix(user.Username, "AliceSmith_") || len(user.Username) != len("AliceSmith_")+6 || !validUsername(user.Username) {
+		t.Errorf("created username %q", user.Username)
+	}
+}
+
+func TestUnlinkIdentity(t *testing.T) {
+	test := newOAuthTest(t)
+	alice := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	bob := test.users.add(t, models.User{Username: "bob", Email: "bob@example.com", Password: "secret-password"})
+	for _, identity := range []models.Identity{
+		{UserID: alice.ID, Provider: "stub", Subject: "sub-1"},
+		{UserID: bob.ID, Provider: "stub", Subject: "sub-2"},
+	} {
+		if err := test.identities.CreateIdentity(&identity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"invalid id", "/app/auth/identities/first", http.StatusBadRequest},
+		{"unknown id", "/app/auth/identities/99", http.StatusNotFound},
+		{"someone else's identity", "/app/auth/identities/2", http.StatusNotFound},
+		{"own identity", "/app/auth/identities/1", http.StatusOK},
+		{"already unlinked", "/app/auth/identities/1", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if status, body := call(t, test.router, http.MethodDelete, tt.path, tokenFor(t, alice), nil); status != tt.status {
+			t.Errorf("%s: answered %d, want %d: %v", tt.name, status, tt.status, body)
+		}
+	}
+	if identities, _ := test.identities.GetIdentitiesForUser(bob.ID); len(identities) != 1 {
+		t.Errorf("bob has %d identities, want 1", len(identities))
+	}
+}
+
+func TestOAuthLoginRestoresDeletedAccount(t *testing.T) {
+	test := newOAuthTest(t)
+	alice := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	alice.DeletedAt = pq.NullTime{Time: test.clock.Now(), Valid: true}
+	if err := test.users.UpdateUser(alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := test.identities.CreateIdentity(&models.Identity{UserID: alice.ID, Provider: "stub", Subject: "sub-1"}); err != nil {
+		t.Fatal(err)
+	}
+	identity := oauth.Identity{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true}
+
+	if status, body := test.signIn(t, identity); status != http.StatusForbidden {
+		t.Fatalf("plain login answered %d: %v", status, body)
+	}
+
+	test.clock.Advance(31 * 24 * time.Hour)
+	test.provider.SignIn(identity)
+	authURL, cookie := test.startAt(t, "/app/auth/oauth/stub/login?restore=true")
+	if status, body := test.callback(t, test.provider.Authorize(t, authURL), cookie); status != http.StatusGone {
+		t.Fatalf("restoring after the grace period answered %d: %v", status, body)
+	}
+
+	test.clock.Advance(-2 * 24 * time.Hour)
+	authURL, cookie = test.startAt(t, "/app/auth/oauth/stub/login?restore=true")
+	status, body := test.callback(t, test.provider.Authorize(t, authURL), cookie)
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("restoring login answered %d: %v", status, body)
+	}
+	if restored, _ := test.users.GetUserByID(alice.ID); restored.DeletedAt.Valid {
+		t.Error("account is still scheduled for deletion")
+	}
+}
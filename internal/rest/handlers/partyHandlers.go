@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// PartyHandlers serve 2v2 team battles: parties of two friends and the queue
+// that matches them, or players queueing alone, into teams.
+type PartyHandlers struct {
+	PartyRepo  repository.PartyRepo
+	UserRepo   repository.UserRepo
+	GameRepo   repository.GameRepo
+	FriendRepo repository.FriendRepo
+	Notifier   notifications.Notifier
+}
+
+func NewPartyHandlers(partyRepo repository.PartyRepo, userRepo repository.UserRepo, gameRepo repository.GameRepo, friendRepo repository.FriendRepo, notifier notifications.Notifier) *PartyHandlers {
+	return &PartyHandlers{
+		PartyRepo:  partyRepo,
+		UserRepo:   userRepo,
+		GameRepo:   gameRepo,
+		FriendRepo: friendRepo,
+		Notifier:   notifier,
+	}
+}
+
+func writePartyError(context *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, helper.ErrPartyNotFound):
+		context.JSON(http.StatusNotFound, gin.H{"error": "Party not found"})
+	case errors.Is(err, helper.ErrAlreadyInParty):
+		context.JSON(http.StatusConflict, gin.H{"error": "You are already in a party"})
+	case errors.Is(err, helper.ErrAlreadyQueued):
+		context.JSON(http.StatusConflict, gin.H{"error": "You are already queued for a battle"})
+	case errors.Is(err, helper.ErrPartyFull):
+		context.JSON(http.StatusConflict, gin.H{"error": "Party is full"})
+	case errors.Is(err, helper.ErrPartyNotFull):
+		context.JSON(http.StatusConflict, gin.H{"error": "Your party needs a second player"})
+	case errors.Is(err, helper.ErrNotInvited):
+		context.JSON(http.StatusForbidden, gin.H{"error": "You are not invited to this party"})
+	default:
+		logger.GetLogger().Error(message+":", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// partyDeck snapshots the deck chosen in the request body and answers the
+// request itself when it can't be used.
+func (h PartyHandlers) partyDeck(context *gin.Context, userID, deckID uint) (models.DeckSnapshot, bool) {
+	deck, err := snapshotDeck(h.GameRepo, userID, deckID)
+	if err != nil {
+		logger.GetLogger().Warn("Deck can't be used for a team battle:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Choose one of your decks with at least one card"})
+		return models.DeckSnapshot{}, false
+	}
+	return deck, true
+}
+
+func (h PartyHandlers) GetMyParty(context *gin.Context) {
+	logger.GetLogger().Info("Fetching party")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	party, err := h.PartyRepo.GetPartyForUser(user.ID)
+	if err != nil {
+		if errors.Is(err, helper.ErrPartyNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "You are not in a party"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get party:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"party": dto.NewParty(party)})
+}
+
+func (h PartyHandlers) CreateParty(context *gin.Context) {
+	logger.GetLogger().Info("Creating party")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.PartyDeckForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid party:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	deck, ok := h.partyDeck(context, user.ID, form.DeckID)
+	if !ok {
+		return
+	}
+
+	party := models.Party{LeaderID: user.ID, LeaderName: user.Username, LeaderDeck: deck}
+	if err := h.PartyRepo.CreateParty(&party); err != nil {
+		writePartyError(context, err, "Failed to create party")
+		return
+	}
+
+	logger.GetLogger().Info("Party created")
+	context.JSON(http.StatusCreated, gin.H{"party": dto.NewParty(party)})
+}
+
+// InviteToParty invites one of the leader's friends, replacing any earlier
+// invitation.
+func (h PartyHandlers) InviteToParty(context *gin.Context) {
+	logger.GetLogger().Info("Inviting to party")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.PartyInviteForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid party invitation:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	party, err := h.PartyRepo.GetPartyForUser(user.ID)
+	if err != nil {
+		writePartyError(context, err, "Failed to get party")
+		return
+	}
+	if party.LeaderID != user.ID {
+		context.JSON(http.StatusForbidden, gin.H{"error": "Only the party leader can invite"})
+		return
+	}
+
+	friend, err := h.UserRepo.GetUserByUsername(strings.TrimSpace(form.Username))
+	if err != nil || friend.DeletedAt.Valid || friend.ID == user.ID {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+		return
+	}
+	friends, err := h.FriendRepo.AreFriends(user.ID, friend.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to check friendship:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if !friends {
+		context.JSON(http.StatusForbidden, gin.H{"error": "You can only invite your friends"})
+		return
+	}
+
+	if err := h.PartyRepo.InviteToParty(party.ID, friend.ID); err != nil {
+		writePartyError(context, err, "Failed to invite to party")
+		return
+	}
+
+	h.Notifier.Notify(context, models.Notification{
+		UserID: friend.ID,
+		Kind:   models.NotificationPartyInvite,
+		Title:  user.Username + " invited you to a 2v2 party",
+		Data:   map[string]interface{}{"partyId": party.ID},
+	})
+
+	context.JSON(http.StatusOK, gin.H{"message": "Invitation sent"})
+}
+
+func (h PartyHandlers) JoinParty(context *gin.Context) {
+	logger.GetLogger().Info("Joining party")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.PartyDeckForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid party join:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	partyID, ok := parseID(context, "id", "party")
+	if !ok {
+		return
+	}
+
+	deck, ok := h.partyDeck(context, user.ID, form.DeckID)
+	if !ok {
+		return
+	}
+
+	if err := h.PartyRepo.JoinParty(partyID, user.ID, deck); err != nil {
+		writePartyError(context, err, "Failed to join party")
+		return
+	}
+
+	party, err := h.PartyRepo.GetParty(partyID)
+	if err != nil {
+		writePartyError(context, err, "Failed to get party")
+		return
+	}
+
+	h.Notifier.Notify(context, models.Notification{
+		UserID: party.LeaderID,
+		Kind:   models.NotificationPartyInvite,
+		Title:  user.Username + " joined your party",
+		Data:   map[string]interface{}{"partyId": party.ID},
+	})
+
+	logger.GetLogger().Info("Joined party")
+	context.JSON(http.StatusOK, gin.H{"party": dto.NewParty(party)})
+}
+
+// LeaveParty takes the player out of their party, which also leaves the
+// queue. The party is disbanded when its leader leaves.
+func (h PartyHandlers) LeaveParty(context *gin.Context) {
+	logger.GetLogger().Info("Leaving party")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	party, err := h.PartyRepo.LeaveParty(user.ID)
+	if err != nil {
+		writePartyError(context, err, "Failed to leave party")
+		return
+	}
+
+	other := party.LeaderID
+	title := user.Username + " left your party"
+	if party.LeaderID == user.ID {
+		other = uint(party.MemberID.Int64)
+		title = user.Username + " disbanded the party"
+	}
+	if other != 0 {
+		h.Notifier.Notify(context, models.Notification{
+			UserID: other,
+			Kind:   models.NotificationPartyInvite,
+			Title:  title,
+			Data:   map[string]interface{}{"partyId": party.ID},
+		})
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Left party"})
+}
+
+// GetQueue tells the player whether they are waiting for a 2v2 battle. Once
+// matched they are notified with the match instead.
+func (h PartyHandlers) GetQueue(context *gin.Context) {
+	logger.GetLogger().Info("Fetching team queue")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	entry, err := h.PartyRepo.GetQueueEntry(user.ID)
+	if err != nil {
+		if errors.Is(err, helper.ErrNotQueued) {
+			context.JSON(http.StatusOK, gin.H{"queued": false})
+			return
+		}
+		logger.GetLogger().Error("Failed to get queue entry:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"queued": true, "queuedAt": entry.CreatedAt})
+}
+
+// Queue looks for a 2v2 battle: a party leader queues the party, anyone else
+// queues alone with the deck in the body and is teamed up with another
+// player. When the queue already holds enough players the match starts at
+// once and every player is notified.
+func (h PartyHandlers) Queue(context *gin.Context) {
+	logger.GetLogger().Info("Queueing for team battle")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.TeamQueueForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid team queue request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	party, err := h.PartyRepo.GetPartyForUser(user.ID)
+	if err != nil && !errors.Is(err, helper.ErrPartyNotFound) {
+		logger.GetLogger().Error("Failed to get party:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	var match models.Match
+	var matched bool
+	switch {
+	case err == nil && party.LeaderID != user.ID:
+		context.JSON(http.StatusForbidden, gin.H{"error": "Only the party leader can queue the party"})
+		return
+	case err == nil:
+		match, matched, err = h.PartyRepo.QueueParty(user.ID)
+	default:
+		if form.DeckID == 0 {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Choose a deck to queue alone"})
+			return
+		}
+		deck, ok := h.partyDeck(context, user.ID, form.DeckID)
+		if !ok {
+			return
+		}
+		match, matched, err = h.PartyRepo.QueueSolo(user.ID, deck)
+	}
+	if err != nil {
+		writePartyError(context, err, "Failed to join queue")
+		return
+	}
+
+	if !matched {
+		context.JSON(http.StatusAccepted, gin.H{"queued": true})
+		return
+	}
+
+	for _, player := range match.Players {
+		h.Notifier.Notify(context, models.Notification{
+			UserID: player.UserID,
+			Kind:   models.NotificationMatchFound,
+			Title:  "Your 2v2 battle is ready",
+			Data:   map[string]interface{}{"matchId": match.ID},
+		})
+	}
+
+	logger.GetLogger().Info("Team battle started")
+	context.JSON(http.StatusCreated, gin.H{"queued": false, "match": dto.NewMatch(match)})
+}
+
+// LeaveQueue stops looking for a battle, for the player's whole party if
+// they queued with one.
+func (h PartyHandlers) LeaveQueue(context *gin.Context) {
+	logger.GetLogger().Info("Leaving team queue")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	if err := h.PartyRepo.LeaveQueue(user.ID); err != nil {
+		if errors.Is(err, helper.ErrNotQueued) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "You are not queued"})
+			return
+		}
+		logger.GetLogger().Error("Failed to leave queue:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave queue"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Left queue"})
+}
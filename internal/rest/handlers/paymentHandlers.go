@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/payments"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type PaymentHandlers struct {
+	Providers   map[string]payments.Provider
+	PaymentRepo repository.PaymentRepo
+	WalletRepo  repository.WalletRepo
+	Config      config.PaymentConfig
+	Clock       utils.Clock
+	Notifier    notifications.Notifier
+}
+
+func NewPaymentHandlers(providers []payments.Provider, paymentRepo repository.PaymentRepo, walletRepo repository.WalletRepo, paymentConfig config.PaymentConfig, clock utils.Clock, notifier notifications.Notifier) *PaymentHandlers {
+	providersByName := make(map[string]payments.Provider, len(providers))
+	for _, provider := range providers {
+		providersByName[provider.Name()] = provider
+	}
+	return &PaymentHandlers{
+		Providers:   providersByName,
+		PaymentRepo: paymentRepo,
+		WalletRepo:  walletRepo,
+		Config:      paymentConfig,
+		Clock:       clock,
+		Notifier:    notifier,
+	}
+}
+
+// VerifyPurchase checks a store receipt and credits the gems it bought.
+// Sending the same receipt again returns the earlier purchase without
+// crediting it twice, so clients can safely retry.
+func (h PaymentHandlers) VerifyPurchase(context *gin.Context) {
+	logger.GetLogger().Info("Verifying store purchase")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.VerifyPurchaseForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid purchase verification:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	provider, ok := h.Providers[form.Provider]
+	if !ok {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Unknown store"})
+		return
+	}
+
+	verified, err := provider.VerifyReceipt(context, form.Receipt)
+	if err != nil {
+		if errors.Is(err, payments.ErrInvalidReceipt) {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid receipt"})
+			return
+		}
+		logger.GetLogger().Error("Failed to verify receipt:", err)
+		context.JSON(http.StatusBadGateway, gin.H{"error": "Failed to verify receipt with the store"})
+		return
+	}
+
+	gems, ok := h.Config.Products[verified.ProductID]
+	if !ok {
+		logger.GetLogger().Error("Unknown store product:", verified.ProductID)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Unknown product"})
+		return
+	}
+
+	purchase := models.StorePurchase{
+		UserID:        sql.NullInt64{Int64: int64(user.ID), Valid: true},
+		Provider:      provider.Name(),
+		TransactionID: verified.TransactionID,
+		ProductID:     verified.ProductID,
+		Gems:          gems,
+	}
+	credited, err := h.PaymentRepo.CreditPurchase(&purchase)
+	if err != nil {
+		if errors.Is(err, helper.ErrReceiptClaimed) {
+			context.JSON(http.StatusConflict, gin.H{"error": "Receipt was already redeemed"})
+			return
+		}
+		logger.GetLogger().Error("Failed to credit store purchase:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to credit purchase"})
+		return
+	}
+
+	wallet, err := h.WalletRepo.GetWallet(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get wallet:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	status := http.StatusOK
+	if credited {
+		logger.GetLogger().Info("Store purchase credited")
+		h.Notifier.Notify(context, models.Notification{
+			UserID: user.ID,
+			Kind:   models.NotificationPurchase,
+			Title:  "You received " + strconv.FormatInt(purchase.Gems, 10) + " gems",
+			Data:   map[string]interface{}{"purchaseId": purchase.ID, "gems": purchase.Gems},
+		})
+		status = http.StatusCreated
+	}
+	context.JSON(status, gin.H{"purchase": dto.NewStorePurchase(purchase), "wallet": dto.NewWallet(wallet)})
+}
+
+func (h PaymentHandlers) ListPurchases(context *gin.Context) {
+	logger.GetLogger().Info("Fetching store purchases")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	purchases, err := h.PaymentRepo.GetPurchases(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get store purchases:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"purchases": dto.NewStorePurchases(purchases)})
+}
+
+// StoreNotification receives a store's refund and chargeback notifications.
+// The store authenticates them itself, so the route takes no user token.
+func (h PaymentHandlers) StoreNotification(context *gin.Context) {
+	logger.GetLogger().Info("Handling store notification")
+
+	provider, ok := h.Providers[context.Param("provider")]
+	if !ok {
+		logger.GetLogger().Error("Unknown store:", context.Param("provider"))
+		context.JSON(http.StatusNotFound, gin.H{"error": "Unknown store"})
+		return
+	}
+
+	body, err := context.GetRawData()
+	if err != nil {
+		logger.GetLogger().Error("Failed to read store notification:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	event, err := provider.ParseNotification(context, context.Request.Header, body)
+	if err != nil {
+		logger.GetLogger().Error("Rejected store notification:", err)
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid notification"})
+		return
+	}
+
+	status := models.PurchaseRefunded
+	if event.Type == payments.EventChargeback {
+		status = models.PurchaseChargedBack
+	}
+	purchase, err := h.PaymentRepo.ReversePurchase(provider.Name(), event.TransactionID, status, h.Clock.Now())
+	if err != nil {
+		if errors.Is(err, helper.ErrPurchaseNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Purchase not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to reverse store purchase:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reverse purchase"})
+		return
+	}
+
+	logger.GetLogger().Info("Store purchase reversed")
+	context.JSON(http.StatusOK, gin.H{"purchase": dto.NewStorePurchase(purchase)})
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/rest/models"
+	"auth/pkg/middleware"
+	"auth/pkg/payments"
+	"auth/pkg/rest/helper"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePayments mirrors PaymentRepository and the gem side of
+// WalletRepository: a transaction is credited once, to whoever redeemed it
+// first, and a reversal takes back all its gems, leaving the balance below
+// zero when some were spent. Credits and spending go to the ledger.
+type fakePayments struct {
+	mu        sync.Mutex
+	purchases []models.StorePurchase
+	gems      map[uint]int64
+	ledger    []models.WalletTransaction
+}
+
+func newFakePayments() *fakePayments {
+	return &fakePayments{gems: make(map[uint]int64)}
+}
+
+func (f *fakePayments) find(provider, transactionID string) int {
+	for i, purchase := range f.purchases {
+		if purchase.Provider == provider && purchase.TransactionID == transactionID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *fakePayments) CreditPurchase(purchase *models.StorePurchase) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i := f.find(purchase.Provider, purchase.TransactionID); i >= 0 {
+		if f.purchases[i].UserID != purchase.UserID {
+			return false, helper.ErrReceiptClaimed
+		}
+		*purchase = f.purchases[i]
+		return false, nil
+	}
+	purchase.ID = uint(len(f.purchases) + 1)
+	purchase.Status = models.PurchaseCompleted
+	f.purchases = append(f.purchases, *purchase)
+	f.gems[uint(purchase.UserID.Int64)] += purchase.Gems
+	f.record(uint(purchase.UserID.Int64), purchase.Gems, models.ReasonStore, purchase.Provider+":"+purchase.TransactionID)
+	return true, nil
+}
+
+func (f *fakePayments) record(userID uint, gems int64, reason, reference string) {
+	f.ledger = append(f.ledger, models.WalletTransaction{
+		ID:        uint(len(f.ledger) + 1),
+		UserID:    userID,
+		Currency:  models.CurrencyGems,
+		Amount:    gems,
+		Reason:    reason,
+		Reference: reference,
+	})
+}
+
+func (f *fakePayments) ReversePurchase(provider, transactionID, status string, now time.Time) (models.StorePurchase, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.find(provider, transactionID)
+	if i < 0 {
+		return models.StorePurchase{}, helper.ErrPurchaseNotFound
+	}
+	purchase := &f.purchases[i]
+	if purchase.Status != models.PurchaseCompleted {
+		return *purchase, nil
+	}
+	var clawedBack int64
+	if purchase.UserID.Valid {
+		userID := uint(purchase.UserID.Int64)
+		clawedBack = purchase.Gems
+		f.gems[userID] -= clawedBack
+		reason := models.ReasonRefund
+		if status == models.PurchaseChargedBack {
+			reason = models.ReasonChargeback
+		}
+		f.record(userID, -clawedBack, reason, provider+":"+transactionID)
+	}
+	purchase.Status = status
+	purchase.ReversedAt.Time, purchase.ReversedAt.Valid = now, true
+	purchase.ClawedBack = clawedBack
+	return *purchase, nil
+}
+
+func (f *fakePayments) GetPurchases(userID uint) ([]models.StorePurchase, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var purchases []models.StorePurchase
+	for i := len(f.purchases) - 1; i >= 0; i-- {
+		if f.purchases[i].UserID.Valid && uint(f.purchases[i].UserID.Int64) == userID {
+			purchases = append(purchases, f.purchases[i])
+		}
+	}
+	return purchases, nil
+}
+
+// deleteUser does what UserRepository.DeleteUser does to payments.
+func (f *fakePayments) deleteUser(userID uint) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.purchases {
+		if f.purchases[i].UserID.Valid && uint(f.purchases[i].UserID.Int64) == userID {
+			f.purchases[i].UserID = sql.NullInt64{}
+		}
+	}
+	delete(f.gems, userID)
+}
+
+// spend takes gems the way a purchase in the shop would.
+func (f *fakePayments) spend(userID uint, gems int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gems[userID] -= gems
+	f.record(userID, -gems, models.ReasonShopOffer, "")
+}
+
+func (f *fakePayments) GetWallet(userID uint) (models.Wallet, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return models.Wallet{UserID: userID, Gems: f.gems[userID]}, nil
+}
+
+func (f *fakePayments) GetTransactions(userID, before uint, limit int) ([]models.WalletTransaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var transactions []models.WalletTransaction
+	for i := len(f.ledger) - 1; i >= 0 && len(transactions) < limit; i-- {
+		if f.ledger[i].UserID == userID && (before == 0 || f.ledger[i].ID < before) {
+			transactions = append(transactions, f.ledger[i])
+		}
+	}
+	return transactions, nil
+}
+
+func (f *fakePayments) Grant(userID uint, currency string, amount int64, reason, reference string) (models.Wallet, error) {
+	return models.Wallet{}, nil
+}
+
+func (f *fakePayments) ExchangeGems(userID uint, gems, gold int64) (models.Wallet, error) {
+	return models.Wallet{}, nil
+}
+
+type fakeNotifier struct {
+	mu            sync.Mutex
+	notifications []models.Notification
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, notification models.Notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+}
+
+type paymentTest struct {
+	router   *gin.Engine
+	users    *fakeUsers
+	payments *fakePayments
+	notifier *fakeNotifier
+	store    *payments.FakeProvider
+	now      time.Time
+}
+
+func newPaymentTest(t *testing.T) *paymentTest {
+	test := &paymentTest{
+		users:    newFakeUsers(),
+		payments: newFakePayments(),
+		notifier: &fakeNotifier{},
+		store:    payments.NewFakeProvider("store-secret"),
+		now:      time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	paymentConfig := config.PaymentConfig{Products: map[string]int64{"gems_small": 80, "gems_large": 1200}}
+	h := NewPaymentHandlers([]payments.Provider{test.store}, test.payments, test.payments, paymentConfig, &fakeClock{now: test.now}, test.notifier)
+
+	test.router = gin.New()
+	test.router.GET("/payments", middleware.RequireUser(test.users), h.ListPurchases)
+	test.router.POST("/payments/verify", middleware.RequireUser(test.users), h.VerifyPurchase)
+	test.router.POST("/payments/:provider/notifications", h.StoreNotification)
+	return test
+}
+
+func (test *paymentTest) verify(t *testing.T, user *models.User, receipt string) (int, map[string]interface{}) {
+	t.Helper()
+	return call(t, test.router, http.MethodPost, "/payments/verify", tokenFor(t, user), gin.H{"provider": "fake", "receipt": receipt})
+}
+
+// notify sends a store notification signed with signature, or with the
+// store's own signature when it is empty.
+func (test *paymentTest) notify(t *testing.T, eventType, transactionID, signature string) (int, map[string]interface{}) {
+	t.Helper()
+	body, err := json.Marshal(gin.H{"type": eventType, "transactionId": transactionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature == "" {
+		signature = test.store.Sign(body)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/payments/fake/notifications", strings.NewReader(string(body)))
+	request.Header.Set(payments.FakeSignatureHeader, signature)
+	recorder := httptest.NewRecorder()
+	test.router.ServeHTTP(recorder, request)
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("notification answered %d with invalid JSON %q", recorder.Code, recorder.Body.String())
+	}
+	return recorder.Code, response
+}
+
+func gemsIn(body map[string]interface{}) float64 {
+	return body["wallet"].(map[string]interface{})["gems"].(float64)
+}
+
+func TestVerifyPurchase(t *testing.T) {
+	test := newPaymentTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+
+	tests := []struct {
+		name    string
+		receipt string
+		status  int
+	}{
+		{"forged signature", strings.Split(test.store.Receipt("tx-1", "gems_small", test.now), ".")[0] + ".00", http.StatusBadRequest},
+		{"not a receipt", "garbage", http.StatusBadRequest},
+		{"unknown product", test.store.Receipt("tx-2", "gems_huge", test.now), http.StatusBadRequest},
+		{"valid receipt", test.store.Receipt("tx-3", "gems_small", test.now), http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := test.verify(t, user, tt.receipt)
+			if status != tt.status {
+				t.Errorf("answered %d, want %d: %v", status, tt.status, body)
+			}
+		})
+	}
+
+	if gems := test.payments.gems[user.ID]; gems != 80 {
+		t.Errorf("balance is %d gems, want 80", gems)
+	}
+	if len(test.notifier.notifications) != 1 {
+		t.Errorf("sent %d notifications, want 1", len(test.notifier.notifications))
+	}
+}
+
+func TestVerifyPurchaseRetryIsIdempotent(t *testing.T) {
+	test := newPaymentTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	receipt := test.store.Receipt("tx-1", "gems_large", test.now)
+
+	status, body := test.verify(t, user, receipt)
+	if status != http.StatusCreated || gemsIn(body) != 1200 {
+		t.Fatalf("first verification answered %d: %v", status, body)
+	}
+	purchaseID := body["purchase"].(map[string]interface{})["id"]
+
+	status, body = test.verify(t, user, receipt)
+	if status != http.StatusOK {
+		t.Fatalf("retry answered %d: %v", status, body)
+	}
+	if gemsIn(body) != 1200 {
+		t.Errorf("retry left %v gems, want 1200", gemsIn(body))
+	}
+	if id := body["purchase"].(map[string]interface{})["id"]; id != purchaseID {
+		t.Errorf("retry returned purchase %v, want %v", id, purchaseID)
+	}
+	if len(test.notifier.notifications) != 1 {
+		t.Errorf("sent %d notifications, want 1", len(test.notifier.notifications))
+	}
+}
+
+func TestVerifyPurchaseClaimedByAnotherPlayer(t *testing.T) {
+	test := newPaymentTest(t)
+	alice := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	bob := test.users.add(t, models.User{Username: "bob", Email: "bob@example.com"})
+	receipt := test.store.Receipt("tx-1", "gems_small", test.now)
+
+	if status, body := test.verify(t, alice, receipt); status != http.StatusCreated {
+		t.Fatalf("alice's verification answered %d: %v", status, body)
+	}
+	if status, _ := test.verify(t, bob, receipt); status != http.StatusConflict {
+		t.Errorf("bob's verification answered %d, want 409", status)
+	}
+	if gems := test.payments.gems[bob.ID]; gems != 0 {
+		t.Errorf("bob got %d gems, want 0", gems)
+	}
+
+	// Deleting alice keeps her purchase, so the receipt stays redeemed.
+	test.payments.deleteUser(alice.ID)
+	if status, _ := test.verify(t, bob, receipt); status != http.StatusConflict {
+		t.Errorf("verification after alice was deleted answered %d, want 409", status)
+	}
+}
+
+func TestStoreNotificationReversesPurchase(t *testing.T) {
+	test := newPaymentTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	test.verify(t, user, test.store.Receipt("tx-1", "gems_large", test.now))
+	test.verify(t, user, test.store.Receipt("tx-2", "gems_small", test.now))
+	test.payments.spend(user.ID, 1000)
+
+	if status, _ := test.notify(t, payments.EventRefund, "tx-1", "bad-signature"); status != http.StatusUnauthorized {
+		t.Errorf("unsigned notification answered %d, want 401", status)
+	}
+	if status, _ := test.notify(t, payments.EventRefund, "tx-9", ""); status != http.StatusNotFound {
+		t.Errorf("notification for an unknown purchase answered %d, want 404", status)
+	}
+
+	// 1280 gems were bought and 1000 spent, so taking back the 1200 refunded
+	// leaves alice owing 920.
+	status, body := test.notify(t, payments.EventChargeback, "tx-1", "")
+	if status != http.StatusOK {
+		t.Fatalf("chargeback answered %d: %v", status, body)
+	}
+	purchase := body["purchase"].(map[string]interface{})
+	if purchase["status"] != models.PurchaseChargedBack || purchase["clawedBack"] != float64(1200) {
+		t.Errorf("chargeback left %v", purchase)
+	}
+	if gems := test.payments.gems[user.ID]; gems != -920 {
+		t.Errorf("balance is %d gems after the chargeback, want -920", gems)
+	}
+
+	// Stores may send the same notification twice.
+	status, body = test.notify(t, payments.EventChargeback, "tx-1", "")
+	if status != http.StatusOK || body["purchase"].(map[string]interface{})["clawedBack"] != float64(1200) {
+		t.Errorf("repeated chargeback answered %d: %v", status, body)
+	}
+	if gems := test.payments.gems[user.ID]; gems != -920 {
+		t.Errorf("balance is %d gems after the repeated chargeback, want -920", gems)
+	}
+
+	// The next gems bought repay the debt first.
+	status, body = test.verify(t, user, test.store.Receipt("tx-3", "gems_large", test.now))
+	if status != http.StatusCreated || gemsIn(body) != 280 {
+		t.Errorf("purchase after the chargeback answered %d: %v", status, body)
+	}
+}
+
+func TestStoreNotificationAfterAccountDeletion(t *testing.T) {
+	test := newPaymentTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	test.verify(t, user, test.store.Receipt("tx-1", "gems_small", test.now))
+	test.payments.deleteUser(user.ID)
+
+	status, body := test.notify(t, payments.EventRefund, "tx-1", "")
+	if status != http.StatusOK {
+		t.Fatalf("refund answered %d: %v", status, body)
+	}
+	purchase := body["purchase"].(map[string]interface{})
+	if purchase["status"] != models.PurchaseRefunded || purchase["clawedBack"] != nil {
+		t.Errorf("refund left %v", purchase)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/pkg/logger"
+	"database/sql"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type PlayerHandlers struct {
+	UserRepo   repository.UserRepo
+	GameRepo   repository.GameRepo
+	PlayerRepo repository.PlayerRepo
+}
+
+func NewPlayerHandlers(userRepo repository.UserRepo, gameRepo repository.GameRepo, playerRepo repository.PlayerRepo) *PlayerHandlers {
+	return &PlayerHandlers{UserRepo: userRepo, GameRepo: gameRepo, PlayerRepo: playerRepo}
+}
+
+func (h PlayerHandlers) GetPublicProfile(context *gin.Context) {
+	logger.GetLogger().Info("Fetching public profile")
+
+	user, err := h.UserRepo.GetUserByUsername(context.Param("username"))
+	if err != nil || user.DeletedAt.Valid {
+		logger.GetLogger().Warn("Player not found:", context.Param("username"))
+		context.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+		return
+	}
+
+	profile, err := h.PlayerRepo.GetPlayerProfile(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get player profile:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	response := dto.PublicProfile{
+		Username:    user.Username,
+		Trophies:    user.Awards,
+		MemberSince: user.CreatedAt,
+	}
+
+	if profile.ShowStats {
+		response.Stats = dto.NewPlayerStats(profile)
+	}
+
+	if profile.ShowFavorite && profile.FavoriteHeroID.Valid {
+		if hero, err := h.GameRepo.GetHeroByID(uint(profile.FavoriteHeroID.Int64)); err == nil {
+			card := dto.HeroCard(hero)
+			response.FavoriteCard = &card
+		}
+	}
+
+	if profile.ShowDeck && profile.CurrentDeckID.Valid {
+		deck, err := h.GameRepo.GetDeckByID(uint(profile.CurrentDeckID.Int64))
+		if err == nil && deck.UserID == user.ID {
+			if deck.Heroes, err = h.GameRepo.GetDeckHeros(deck.ID); err != nil {
+				logger.GetLogger().Error("Failed to get deck heros:", err)
+			}
+			if deck.Spells, err = h.GameRepo.GetDeckSpells(deck.ID); err != nil {
+				logger.GetLogger().Error("Failed to get deck spells:", err)
+			}
+			response.CurrentDeck = dto.NewDeckSummary(deck)
+		}
+	}
+
+	if profile.ShowCollection {
+		completion, err := h.PlayerRepo.GetCollectionCompletion(user.ID)
+		if err != nil {
+			logger.GetLogger().Error("Failed to get collection completion:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+		response.Collection = dto.NewCollectionProgress(completion)
+	}
+
+	context.JSON(http.StatusOK, gin.H{"profile": response})
+}
+
+func (h PlayerHandlers) GetPrivacy(context *gin.Context) {
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	profile, err := h.PlayerRepo.GetPlayerProfile(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get player profile:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"privacy": dto.NewPrivacySettings(profile)})
+}
+
+func (h PlayerHandlers) UpdatePrivacy(context *gin.Context) {
+	logger.GetLogger().Info("Updating privacy settings")
+
+	var form forms.UpdatePrivacyForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid privacy request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	profile, err := h.PlayerRepo.GetPlayerProfile(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get player profile:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if form.ShowStats != nil {
+		profile.ShowStats = *form.ShowStats
+	}
+	if form.ShowCollection != nil {
+		profile.ShowCollection = *form.ShowCollection
+	}
+	if form.ShowDeck != nil {
+		profile.ShowDeck = *form.ShowDeck
+	}
+	if form.ShowFavorite != nil {
+		profile.ShowFavorite = *form.ShowFavorite
+	}
+
+	if err := h.PlayerRepo.SavePrivacy(profile); err != nil {
+		logger.GetLogger().Error("Failed to save privacy settings:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save privacy settings"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"privacy": dto.NewPrivacySettings(profile)})
+}
+
+func (h PlayerHandlers) UpdateShowcase(context *gin.Context) {
+	logger.GetLogger().Info("Updating profile showcase")
+
+	var form forms.UpdateShowcaseForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid showcase request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	if form.FavoriteHeroID != nil {
+		favorite := sql.NullInt64{}
+		if *form.FavoriteHeroID != 0 {
+			owned, err := h.GameRepo.HasUserBoughtHero(user.ID, *form.FavoriteHeroID)
+			if err != nil {
+				logger.GetLogger().Error("Failed to check hero ownership:", err)
+				context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check if user has bought hero"})
+				return
+			}
+			if !owned {
+				context.JSON(http.StatusBadRequest, gin.H{"error": "User has not bought this hero"})
+				return
+			}
+			favorite = sql.NullInt64{Int64: int64(*form.FavoriteHeroID), Valid: true}
+		}
+		if err := h.PlayerRepo.SetFavoriteHero(user.ID, favorite); err != nil {
+			logger.GetLogger().Error("Failed to set favorite hero:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update showcase"})
+			return
+		}
+	}
+
+	if form.CurrentDeckID != nil {
+		current := sql.NullInt64{}
+		if *form.CurrentDeckID != 0 {
+			deck, err := h.GameRepo.GetDeckByID(*form.CurrentDeckID)
+			if err != nil || deck.UserID != user.ID {
+				context.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+				return
+			}
+			current = sql.NullInt64{Int64: int64(deck.ID), Valid: true}
+		}
+		if err := h.PlayerRepo.SetCurrentDeck(user.ID, current); err != nil {
+			logger.GetLogger().Error("Failed to set current deck:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update showcase"})
+			return
+		}
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Showcase updated"})
+}
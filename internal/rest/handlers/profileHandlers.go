@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	redis "auth/internal/db/redis"
+	"auth/internal/rest/forms"
+	"auth/pkg/email"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+func (h AuthHandlers) ChangeUsername(context *gin.Context) {
+	logger.GetLogger().Info("Changing username")
+
+	var form forms.ChangeUsernameForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid change username request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := loadCurrentUser(context, h.Repo)
+	if !ok {
+		return
+	}
+
+	username := strings.TrimSpace(form.Username)
+	if !validUsername(username) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Username may contain only letters, digits, '_' and '.'"})
+		return
+	}
+	if username == user.Username {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "This is already your username"})
+		return
+	}
+	if existing, err := h.Repo.GetUserByUsername(username); err == nil && existing.ID != user.ID {
+		logger.GetLogger().Warn("Username already taken:", username)
+		context.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	}
+
+	user.Username = username
+	if err := h.Repo.UpdateUser(user); errors.Is(err, helper.ErrUsernameTaken) {
+		logger.GetLogger().Warn("Username already taken:", username)
+		context.JSON(http.StatusConflict, gin.H{"error": "Username is already taken"})
+		return
+	} else if err != nil {
+		logger.GetLogger().Error("Failed to update user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update username"})
+		return
+	}
+
+	h.respondWithRefreshedToken(context, "Username changed successfully", user.ID)
+}
+
+func (h AuthHandlers) ChangePassword(context *gin.Context) {
+	logger.GetLogger().Info("Changing password")
+
+	var form forms.ChangePasswordForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid change password request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := loadCurrentUser(context, h.Repo)
+	if !ok {
+		return
+	}
+
+	// Accounts created through social login have no password yet and may set one directly.
+	if user.Password != "" && !utils.CheckPasswordHash(form.CurrentPassword, user.Password) {
+		logger.GetLogger().Warn("Wrong current password for user:", user.ID)
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	if form.Password != form.PasswordConfirm {
+		logger.GetLogger().Error("Passwords don't match")
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Passwords don't match"})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(form.Password)
+	if err != nil {
+		logger.GetLogger().Error("Failed to hash password:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	user.Password = hashedPassword
+	if err := h.Repo.UpdateUser(user); err != nil {
+		logger.GetLogger().Error("Failed to update user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	h.respondWithRefreshedToken(context, "Password changed successfully", user.ID)
+}
+
+func (h AuthHandlers) RequestEmailChange(context *gin.Context) {
+	logger.GetLogger().Info("Starting email change")
+
+	var form forms.ChangeEmailForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid change email request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := loadCurrentUser(context, h.Repo)
+	if !ok {
+		return
+	}
+
+	newEmail := strings.ToLower(strings.TrimSpace(form.Email))
+	if newEmail == strings.ToLower(user.Email) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "This is already your email"})
+		return
+	}
+	if _, err := h.Repo.GetUserByEmail(newEmail); err == nil {
+		logger.GetLogger().Warn("Email already registered:", newEmail)
+		context.JSON(http.StatusConflict, gin.H{"error": "The account is already registered"})
+		return
+	}
+
+	verificationCode := utils.GenerateVerificationCode()
+	if err := redis.SaveEmailChangeCode(context, h.RedisConfig, user.ID, newEmail, verificationCode); err != nil {
+		logger.GetLogger().Error("Failed to save email change code to Redis:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start email change"})
+		return
+	}
+
+	if err := email.SendVerificationCodeEmail(newEmail, verificationCode, h.Email); err != nil {
+		logger.GetLogger().Error("Failed to send verification code email:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification code"})
+		return
+	}
+
+	logger.GetLogger().Info("Email change code sent")
+	context.JSON(http.StatusOK, gin.H{"message": "Verification code sent to your new email"})
+}
+
+func (h AuthHandlers) ConfirmEmailChange(context *gin.Context) {
+	logger.GetLogger().Info("Confirming email change")
+
+	var form forms.CodeForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid confirm email request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := loadCurrentUser(context, h.Repo)
+	if !ok {
+		return
+	}
+
+	newEmail, valid, err := redis.CheckEmailChangeCode(context, h.RedisConfig, user.ID, form.Code)
+	if err != nil {
+		logger.GetLogger().Error("Failed to verify code:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !valid {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+		return
+	}
+
+	// The address may have been registered while the code was pending.
+	if _, err := h.Repo.GetUserByEmail(newEmail); err == nil {
+		context.JSON(http.StatusConflict, gin.H{"error": "The account is already registered"})
+		return
+	}
+
+	user.Email = newEmail
+	if err := h.Repo.UpdateUser(user); errors.Is(err, helper.ErrEmailTaken) {
+		context.JSON(http.StatusConflict, gin.H{"error": "The account is already registered"})
+		return
+	} else if err != nil {
+		logger.GetLogger().Error("Failed to update user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+
+	h.respondWithRefreshedToken(context, "Email changed successfully", user.ID)
+}
+
+// respondWithRefreshedToken reloads the user so the new token carries the
+// saved values rather than whatever the handler had in memory.
+func (h AuthHandlers) respondWithRefreshedToken(context *gin.Context, message string, userID uint) {
+	user, err := h.Repo.GetUserByID(userID)
+	if err != nil {
+		logger.GetLogger().Error("User not found:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "User not found"})
+		return
+	}
+
+	token, err := h.issueToken(context, user)
+	if err != nil {
+		logger.GetLogger().Error("Failed to create token:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	logger.GetLogger().Info(message)
+	context.JSON(http.StatusOK, gin.H{"message": message, "token": token})
+}
+
+func validUsername(username string) bool {
+	for _, r := range username {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return username != ""
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	redis "auth/internal/db/redis"
+	"auth/internal/db/redis/redistest"
+	"auth/internal/rest/models"
+	"auth/pkg/middleware"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type profileTest struct {
+	router      *gin.Engine
+	users       *fakeUsers
+	redisConfig config.RedisConfig
+}
+
+func newProfileTest(t *testing.T) *profileTest {
+	_, redisConfig := redistest.Start(t)
+	test := &profileTest{users: newFakeUsers(), redisConfig: redisConfig}
+	clock := &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	h := NewAuthHandlers(test.users, newFakeTwoFactor(), &fakeIdentities{}, nil, redisConfig, config.EmailConfig{}, config.AccountConfig{}, clock)
+
+	requireUser := middleware.RequireUser(test.users)
+	test.router = gin.New()
+	test.router.GET("/auth/profile", requireUser, h.Profile)
+	test.router.PATCH("/auth/profile/username", requireUser, h.ChangeUsername)
+	test.router.PATCH("/auth/profile/password", requireUser, h.ChangePassword)
+	test.router.POST("/auth/profile/email/confirm", requireUser, h.ConfirmEmailChange)
+	return test
+}
+
+func TestChangeUsernameIsCaseInsensitivelyUnique(t *testing.T) {
+	test := newProfileTest(t)
+	test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	bob := test.users.add(t, models.User{Username: "bob", Email: "bob@example.com"})
+
+	status, _ := call(t, test.router, http.MethodPatch, "/auth/profile/username", tokenFor(t, bob), gin.H{"username": "ALICE"})
+	if status != http.StatusConflict {
+		t.Errorf("taking alice's name in capitals answered %d, want 409", status)
+	}
+
+	status, body := call(t, test.router, http.MethodPatch, "/auth/profile/username", tokenFor(t, bob), gin.H{"username": "Bob"})
+	if status != http.StatusOK {
+		t.Errorf("changing the case of the own name answered %d: %v", status, body)
+	}
+}
+
+func TestChangePasswordRevokesTokens(t *testing.T) {
+	test := newProfileTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	oldToken := tokenFor(t, user)
+
+	status, body := call(t, test.router, http.MethodPatch, "/auth/profile/password", oldToken, gin.H{
+		"currentPassword": "secret-password",
+		"password":        "new-password",
+		"passwordConfirm": "new-password",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("password change answered %d: %v", status, body)
+	}
+
+	if status, _ := call(t, test.router, http.MethodGet, "/auth/profile", oldToken, nil); status != http.StatusUnauthorized {
+		t.Errorf("the old token answered %d, want 401", status)
+	}
+	if status, _ := call(t, test.router, http.MethodGet, "/auth/profile", body["token"].(string), nil); status != http.StatusOK {
+		t.Errorf("the new token answered %d, want 200", status)
+	}
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	test := newProfileTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	oldToken := tokenFor(t, user)
+	if err := redis.SaveEmailChangeCode(context.Background(), test.redisConfig, user.ID, "new@example.com", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := call(t, test.router, http.MethodPost, "/auth/profile/email/confirm", oldToken, gin.H{"code": "123456"})
+	if status != http.StatusOK {
+		t.Fatalf("confirm answered %d: %v", status, body)
+	}
+	if changed, _ := test.users.GetUserByID(user.ID); changed.Email != "new@example.com" {
+		t.Errorf("email is %q", changed.Email)
+	}
+	if status, _ := call(t, test.router, http.MethodGet, "/auth/profile", oldToken, nil); status != http.StatusUnauthorized {
+		t.Errorf("the old token answered %d, want 401", status)
+	}
+}
+
+func TestConfirmEmailChangeToTakenEmail(t *testing.T) {
+	test := newProfileTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	test.users.add(t, models.User{Username: "bob", Email: "Bob@Example.com"})
+	if err := redis.SaveEmailChangeCode(context.Background(), test.redisConfig, user.ID, "bob@example.com", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	status, _ := call(t, test.router, http.MethodPost, "/auth/profile/email/confirm", tokenFor(t, user), gin.H{"code": "123456"})
+	if status != http.StatusConflict {
+		t.Errorf("taking bob's email in other case answered %d, want 409", status)
+	}
+	if changed, _ := test.users.GetUserByID(user.ID); changed.Email != "alice@example.com" {
+		t.Errorf("email changed to %q", changed.Email)
+	}
+}
+
+func TestConfirmEmailChangeLimitsAttempts(t *testing.T) {
+	test := newProfileTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com"})
+	token := tokenFor(t, user)
+	if err := redis.SaveEmailChangeCode(context.Background(), test.redisConfig, user.ID, "new@example.com", "123456"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < redis.EmailChangeAttempts; i++ {
+		if status, _ := call(t, test.router, http.MethodPost, "/auth/profile/email/confirm", token, gin.H{"code": "000000"}); status != http.StatusBadRequest {
+			t.Fatalf("wrong code %d answered %d, want 400", i+1, status)
+		}
+	}
+
+	if status, _ := call(t, test.router, http.MethodPost, "/auth/profile/email/confirm", token, gin.H{"code": "123456"}); status != http.StatusBadRequest {
+		t.Errorf("the right code after too many wrong ones answered %d, want 400", status)
+	}
+	if changed, _ := test.users.GetUserByID(user.ID); changed.Email != "alice@example.com" {
+		t.Errorf("email changed to %q", changed.Email)
+	}
+}
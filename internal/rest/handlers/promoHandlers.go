@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"net/http"
+	"strings"
+)
+
+// promoCodeLength is the length of generated codes; with the code alphabet it
+// leaves far too many combinations to guess one.
+const promoCodeLength = 12
+
+type PromoHandlers struct {
+	PromoRepo repository.PromoRepo
+	GameRepo  repository.GameRepo
+	Clock     utils.Clock
+}
+
+func NewPromoHandlers(promoRepo repository.PromoRepo, gameRepo repository.GameRepo, clock utils.Clock) *PromoHandlers {
+	return &PromoHandlers{PromoRepo: promoRepo, GameRepo: gameRepo, Clock: clock}
+}
+
+func (h PromoHandlers) Redeem(context *gin.Context) {
+	logger.GetLogger().Info("Redeeming promo code")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.RedeemPromoCodeForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid promo code redemption:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	code, err := h.PromoRepo.Redeem(user.ID, strings.ToUpper(strings.TrimSpace(form.Code)), h.Clock.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, helper.ErrPromoCodeNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"error": "Invalid code"})
+		case errors.Is(err, helper.ErrPromoCodeExpired):
+			context.JSON(http.StatusGone, gin.H{"error": "Code has expired"})
+		case errors.Is(err, helper.ErrPromoCodeUsedUp):
+			context.JSON(http.StatusConflict, gin.H{"error": "Code has already been used"})
+		case errors.Is(err, helper.ErrPromoCodeLimitReached):
+			context.JSON(http.StatusConflict, gin.H{"error": "You already redeemed this code"})
+		default:
+			logger.GetLogger().Error("Failed to redeem promo code:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeem code"})
+		}
+		return
+	}
+
+	logger.GetLogger().Info("Promo code redeemed")
+	context.JSON(http.StatusOK, gin.H{"reward": dto.NewPromoReward(code)})
+}
+
+func (h PromoHandlers) ListCodes(context *gin.Context) {
+	logger.GetLogger().Info("Fetching promo codes")
+
+	codes, err := h.PromoRepo.GetCodes()
+	if err != nil {
+		logger.GetLogger().Error("Failed to get promo codes:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"codes": dto.NewPromoCodes(codes)})
+}
+
+func (h PromoHandlers) CreateCodes(context *gin.Context) {
+	logger.GetLogger().Info("Creating promo codes")
+
+	var form forms.CreatePromoCodeForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid promo code:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if len(form.HeroIDs) == 0 && len(form.SpellIDs) == 0 && form.Gold == 0 && form.Gems == 0 && form.Chest == "" {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Code must grant currency, cards or a chest"})
+		return
+	}
+	if form.Code != "" && form.Count > 1 {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "A named code can't be generated more than once"})
+		return
+	}
+	if form.ExpiresAt != nil && !form.ExpiresAt.After(h.Clock.Now()) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Code must expire in the future"})
+		return
+	}
+
+	template := models.PromoCode{
+		Description:  form.Description,
+		RewardGold:   form.Gold,
+		RewardGems:   form.Gems,
+		RewardChest:  sql.NullString{String: form.Chest, Valid: form.Chest != ""},
+		MaxUses:      sql.NullInt64{Int64: form.MaxUses, Valid: form.MaxUses > 0},
+		PerUserLimit: form.PerUserLimit,
+		Active:       true,
+	}
+	if template.PerUserLimit == 0 {
+		template.PerUserLimit = 1
+	}
+	if form.ExpiresAt != nil {
+		template.ExpiresAt = pq.NullTime{Time: *form.ExpiresAt, Valid: true}
+	}
+	for _, heroID := range form.HeroIDs {
+		if _, err := h.GameRepo.GetHeroByID(heroID); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Hero not found"})
+			return
+		}
+		template.Items = append(template.Items, models.PromoCodeItem{HeroID: sql.NullInt64{Int64: int64(heroID), Valid: true}})
+	}
+	for _, spellID := range form.SpellIDs {
+		if _, err := h.GameRepo.GetSpellByID(spellID); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Spell not found"})
+			return
+		}
+		template.Items = append(template.Items, models.PromoCodeItem{SpellID: sql.NullInt64{Int64: int64(spellID), Valid: true}})
+	}
+
+	var codes []models.PromoCode
+	if form.Code != "" {
+		code := template
+		code.Code = strings.ToUpper(form.Code)
+		codes = append(codes, code)
+	} else {
+		count := form.Count
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			generated, err := utils.GenerateCode(promoCodeLength)
+			if err != nil {
+				logger.GetLogger().Error("Failed to generate promo code:", err)
+				context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create codes"})
+				return
+			}
+			code := template
+			code.Code = generated
+			codes = append(codes, code)
+		}
+	}
+
+	if err := h.PromoRepo.CreateCodes(codes); err != nil {
+		if errors.Is(err, helper.ErrPromoCodeTaken) {
+			context.JSON(http.StatusConflict, gin.H{"error": "Code already exists"})
+			return
+		}
+		logger.GetLogger().Error("Failed to create promo codes:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create codes"})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{"codes": dto.NewPromoCodes(codes)})
+}
+
+func (h PromoHandlers) UpdateCode(context *gin.Context) {
+	logger.GetLogger().Info("Updating promo code")
+
+	var form forms.UpdatePromoCodeForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid promo code update:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	codeID, ok := parseID(context, "id", "promo code")
+	if !ok {
+		return
+	}
+
+	code, err := h.PromoRepo.GetCode(codeID)
+	if err != nil {
+		if errors.Is(err, helper.ErrPromoCodeNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get promo code:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if form.Description != nil {
+		code.Description = *form.Description
+	}
+	if form.ExpiresAt != nil {
+		code.ExpiresAt = pq.NullTime{Time: *form.ExpiresAt, Valid: true}
+	}
+	if form.MaxUses != nil {
+		code.MaxUses = sql.NullInt64{Int64: *form.MaxUses, Valid: *form.MaxUses > 0}
+	}
+	if form.PerUserLimit != nil {
+		code.PerUserLimit = *form.PerUserLimit
+	}
+	if form.Active != nil {
+		code.Active = *form.Active
+	}
+
+	if err := h.PromoRepo.UpdateCode(code); err != nil {
+		logger.GetLogger().Error("Failed to update promo code:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update code"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"code": dto.NewPromoCode(code)})
+}
+
+func (h PromoHandlers) ListRedemptions(context *gin.Context) {
+	logger.GetLogger().Info("Fetching promo code redemptions")
+
+	codeID, ok := parseID(context, "id", "promo code")
+	if !ok {
+		return
+	}
+
+	if _, err := h.PromoRepo.GetCode(codeID); err != nil {
+		if errors.Is(err, helper.ErrPromoCodeNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Promo code not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get promo code:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	redemptions, err := h.PromoRepo.GetRedemptions(codeID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get redemptions:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"redemptions": dto.NewPromoRedemptions(redemptions)})
+}
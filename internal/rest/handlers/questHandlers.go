@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type QuestHandlers struct {
+	QuestRepo repository.QuestRepo
+	GameRepo  repository.GameRepo
+	Config    config.QuestConfig
+	Clock     utils.Clock
+}
+
+func NewQuestHandlers(questRepo repository.QuestRepo, gameRepo repository.GameRepo, questConfig config.QuestConfig, clock utils.Clock) *QuestHandlers {
+	return &QuestHandlers{QuestRepo: questRepo, GameRepo: gameRepo, Config: questConfig, Clock: clock}
+}
+
+func (h QuestHandlers) ListQuests(context *gin.Context) {
+	logger.GetLogger().Info("Fetching quests")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	now := h.Clock.Now()
+	progress, err := h.QuestRepo.GetUserQuests(user.ID, utils.DailyPeriod(now, h.Config.ResetOffset), h.Config.DailyCount)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get quests:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	daily := make([]dto.Quest, 0, h.Config.DailyCount)
+	achievements := make([]dto.Quest, 0, len(progress))
+	for _, quest := range progress {
+		if quest.Definition.Kind == models.QuestDaily {
+			daily = append(daily, dto.NewQuest(quest))
+		} else {
+			achievements = append(achievements, dto.NewQuest(quest))
+		}
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"daily":        daily,
+		"achievements": achievements,
+		"resetsAt":     utils.NextReset(now, h.Config.ResetOffset),
+	})
+}
+
+func (h QuestHandlers) ClaimQuest(context *gin.Context) {
+	logger.GetLogger().Info("Claiming quest reward")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	questID, ok := parseID(context, "id", "quest")
+	if !ok {
+		return
+	}
+
+	quest, err := h.QuestRepo.ClaimQuest(user.ID, questID, h.Clock.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, helper.ErrQuestNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"error": "Quest not found"})
+		case errors.Is(err, helper.ErrQuestNotCompleted):
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Quest is not completed yet"})
+		case errors.Is(err, helper.ErrQuestClaimed):
+			context.JSON(http.StatusConflict, gin.H{"error": "Reward already claimed"})
+		default:
+			logger.GetLogger().Error("Failed to claim quest:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim reward"})
+		}
+		return
+	}
+
+	logger.GetLogger().Info("Quest reward claimed")
+	context.JSON(http.StatusOK, gin.H{"quest": dto.NewQuest(quest)})
+}
+
+func (h QuestHandlers) ListDefinitions(context *gin.Context) {
+	logger.GetLogger().Info("Fetching quest definitions")
+
+	definitions, err := h.QuestRepo.GetDefinitions()
+	if err != nil {
+		logger.GetLogger().Error("Failed to get quest definitions:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"definitions": dto.NewQuestDefinitions(definitions)})
+}
+
+func (h QuestHandlers) CreateDefinition(context *gin.Context) {
+	logger.GetLogger().Info("Creating quest definition")
+
+	var form forms.CreateQuestForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid quest definition:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	definition := models.QuestDefinition{
+		Code:        strings.TrimSpace(form.Code),
+		Kind:        form.Kind,
+		Title:       strings.TrimSpace(form.Title),
+		Description: form.Description,
+		Event:       form.Event,
+		Rarity:      form.Rarity,
+		Target:      form.Target,
+		RewardGold:  form.RewardGold,
+		Active:      true,
+	}
+	if !h.setRewardCards(context, &definition, form.RewardHeroID, form.RewardSpellID) {
+		return
+	}
+
+	if err := h.QuestRepo.CreateDefinition(&definition); err != nil {
+		if errors.Is(err, helper.ErrQuestCodeTaken) {
+			context.JSON(http.StatusConflict, gin.H{"error": "Quest code is already in use"})
+			return
+		}
+		logger.GetLogger().Error("Failed to create quest definition:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create quest"})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{"definition": dto.NewQuestDefinition(definition)})
+}
+
+// UpdateDefinition edits rewards and targets or switches a quest off. The
+// code, kind and event are fixed once players may have progress.
+func (h QuestHandlers) UpdateDefinition(context *gin.Context) {
+	logger.GetLogger().Info("Updating quest definition")
+
+	var form forms.UpdateQuestForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid quest update:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	definitionID, ok := parseID(context, "id", "quest")
+	if !ok {
+		return
+	}
+
+	definition, err := h.QuestRepo.GetDefinition(definitionID)
+	if err != nil {
+		if errors.Is(err, helper.ErrQuestNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Quest not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get quest definition:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if form.Title != nil {
+		definition.Title = strings.TrimSpace(*form.Title)
+	}
+	if form.Description != nil {
+		definition.Description = *form.Description
+	}
+	if form.Target != nil {
+		definition.Target = *form.Target
+	}
+	if form.RewardGold != nil {
+		definition.RewardGold = *form.RewardGold
+	}
+	if form.Active != nil {
+		definition.Active = *form.Active
+	}
+	heroID, spellID := uint(definition.RewardHeroID.Int64), uint(definition.RewardSpellID.Int64)
+	if form.RewardHeroID != nil {
+		heroID = *form.RewardHeroID
+	}
+	if form.RewardSpellID != nil {
+		spellID = *form.RewardSpellID
+	}
+	if !h.setRewardCards(context, &definition, heroID, spellID) {
+		return
+	}
+
+	if err := h.QuestRepo.UpdateDefinition(definition); err != nil {
+		logger.GetLogger().Error("Failed to update quest definition:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update quest"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"definition": dto.NewQuestDefinition(definition)})
+}
+
+// setRewardCards checks the reward cards exist; zero means no card.
+func (h QuestHandlers) setRewardCards(context *gin.Context, definition *models.QuestDefinition, heroID, spellID uint) bool {
+	definition.RewardHeroID = sql.NullInt64{}
+	definition.RewardSpellID = sql.NullInt64{}
+	if heroID != 0 {
+		if _, err := h.GameRepo.GetHeroByID(heroID); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Reward hero not found"})
+			return false
+		}
+		definition.RewardHeroID = sql.NullInt64{Int64: int64(heroID), Valid: true}
+	}
+	if spellID != 0 {
+		if _, err := h.GameRepo.GetSpellByID(spellID); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Reward spell not found"})
+			return false
+		}
+		definition.RewardSpellID = sql.NullInt64{Int64: int64(spellID), Valid: true}
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"auth/internal/replays"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/middleware"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+const (
+	replayPageSize = 20
+	// replayChunk is how many inputs are flushed to the client at a time
+	// while a replay streams.
+	replayChunk = 200
+)
+
+// ReplayHandlers record matches sent by the game server and play them back
+// to their players, whose clients re-simulate them.
+type ReplayHandlers struct {
+	ReplayRepo repository.ReplayRepo
+	MatchRepo  repository.MatchRepo
+	Clock      utils.Clock
+}
+
+func NewReplayHandlers(replayRepo repository.ReplayRepo, matchRepo repository.MatchRepo, clock utils.Clock) *ReplayHandlers {
+	return &ReplayHandlers{
+		ReplayRepo: replayRepo,
+		MatchRepo:  matchRepo,
+		Clock:      clock,
+	}
+}
+
+func playedIn(user *middleware.Principal, match models.Match) bool {
+	for _, player := range match.Players {
+		if player.UserID == user.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// verify re-simulates the replay and records whether it reproduced the
+// match's result. reason is why it didn't; err is only set when the check
+// couldn't be recorded.
+func (h ReplayHandlers) verify(replay models.Replay) (replays.Outcome, string, error) {
+	var outcome replays.Outcome
+	reason := ""
+	if replays.StatsVersion(replay.Match.Players) != replay.StatsVersion {
+		reason = "deck snapshots no longer match the replay's stats version"
+	} else if inputs, err := replays.Decode(replay.Inputs); err != nil {
+		reason = err.Error()
+	} else if outcome, err = replays.Verify(replay.Seed, replay.Match, inputs); err != nil {
+		reason = err.Error()
+	} else if diff := int64(outcome.EndedAt) - int64(replay.DurationMs); diff > replays.TickMs || diff < -replays.TickMs {
+		reason = "replay ends at a different time than the match"
+	}
+
+	if err := h.ReplayRepo.SetVerification(replay.ID, reason == "", reason, h.Clock.Now()); err != nil {
+		return outcome, reason, err
+	}
+	return outcome, reason, nil
+}
+
+// Record stores the replay of a match. It is called by the game server,
+// which authenticates as an admin. A replay of a finished match is verified
+// straight away; one recorded before the result can be verified later.
+func (h ReplayHandlers) Record(context *gin.Context) {
+	logger.GetLogger().Info("Recording replay")
+
+	var form forms.ReplayForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid replay:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	matchID, ok := parseID(context, "id", "match")
+	if !ok {
+		return
+	}
+
+	match, err := h.MatchRepo.GetMatch(matchID)
+	if err != nil {
+		if errors.Is(err, helper.ErrMatchNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Match not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get match:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	inputs := make([]replays.Input, 0, len(form.Inputs))
+	for _, input := range form.Inputs {
+		inputs = append(inputs, replays.Input{
+			At:       input.At,
+			Seat:     input.Seat,
+			Kind:     input.Kind,
+			CardID:   input.CardID,
+			Lane:     input.Lane,
+			Position: input.Position,
+		})
+	}
+	log, err := replays.Encode(inputs)
+	if err != nil {
+		logger.GetLogger().Error("Invalid replay inputs:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Inputs must be in time order"})
+		return
+	}
+
+	replay := models.Replay{
+		MatchID:      match.ID,
+		Seed:         form.Seed,
+		StatsVersion: replays.StatsVersion(match.Players),
+		DurationMs:   form.DurationMs,
+		InputCount:   int32(len(inputs)),
+		Inputs:       log,
+	}
+	if err := h.ReplayRepo.CreateReplay(&replay); err != nil {
+		if errors.Is(err, helper.ErrReplayExists) {
+			context.JSON(http.StatusConflict, gin.H{"error": "This match already has a replay"})
+			return
+		}
+		logger.GetLogger().Error("Failed to record replay:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record replay"})
+		return
+	}
+
+	if replay.Match.Status == models.MatchFinished {
+		_, reason, err := h.verify(replay)
+		if err != nil {
+			logger.GetLogger().Error("Failed to verify replay:", err)
+		} else {
+			replay.Verified.Bool, replay.Verified.Valid, replay.VerifyError = reason == "", true, reason
+		}
+	}
+
+	logger.GetLogger().Info("Replay recorded")
+	context.JSON(http.StatusCreated, gin.H{"replay": dto.NewReplay(replay)})
+}
+
+func (h ReplayHandlers) ListReplays(context *gin.Context) {
+	logger.GetLogger().Info("Fetching replays")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	before, limit, ok := parseCursor(context, replayPageSize)
+	if !ok {
+		return
+	}
+
+	list, err := h.ReplayRepo.GetReplaysForUser(user.ID, before, limit)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get replays:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	response := gin.H{"replays": dto.NewReplays(list)}
+	if len(list) > 0 && len(list) == limit {
+		response["nextCursor"] = list[len(list)-1].ID
+	}
+	context.JSON(http.StatusOK, response)
+}
+
+// getReplay loads a replay for one of its match's players or an admin,
+// writing the error response when it can't.
+func (h ReplayHandlers) getReplay(context *gin.Context, user *middleware.Principal) (models.Replay, bool) {
+	id, ok := parseID(context, "id", "replay")
+	if !ok {
+		return models.Replay{}, false
+	}
+
+	replay, err := h.ReplayRepo.GetReplay(id)
+	if err != nil && !errors.Is(err, helper.ErrReplayNotFound) {
+		logger.GetLogger().Error("Failed to get replay:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return models.Replay{}, false
+	}
+	if err != nil || (!playedIn(user, replay.Match) && !user.IsAdmin()) {
+		context.JSON(http.StatusNotFound, gin.H{"error": "Replay not found"})
+		return models.Replay{}, false
+	}
+	return replay, true
+}
+
+// GetReplay streams a replay as newline-delimited JSON: first the replay
+// with every seat's side and full deck snapshot, then the inputs in time
+// order, so the client can start simulating before the log has arrived.
+func (h ReplayHandlers) GetReplay(context *gin.Context) {
+	logger.GetLogger().Info("Streaming replay")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	replay, ok := h.getReplay(context, user)
+	if !ok {
+		return
+	}
+
+	inputs, err := replays.Decode(replay.Inputs)
+	if err != nil {
+		logger.GetLogger().Error("Failed to decode replay:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Replay is unreadable"})
+		return
+	}
+	sides, err := replays.Sides(replay.Match.Players)
+	if err != nil {
+		logger.GetLogger().Error("Failed to seat replay players:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Replay is unreadable"})
+		return
+	}
+
+	context.Header("Content-Type", "application/x-ndjson")
+	context.Status(http.StatusOK)
+	sent := -1
+	context.Stream(func(w io.Writer) bool {
+		encoder := json.NewEncoder(w)
+		if sent < 0 {
+			sent = 0
+			err := encoder.Encode(gin.H{"replay": dto.NewReplay(replay), "seats": dto.NewReplaySeats(replay.Match.Players, sides)})
+			return err == nil && len(inputs) > 0
+		}
+		end := min(sent+replayChunk, len(inputs))
+		for ; sent < end; sent++ {
+			if err := encoder.Encode(gin.H{"input": dto.NewReplayInput(inputs[sent])}); err != nil {
+				return false
+			}
+		}
+		return sent < len(inputs)
+	})
+}
+
+// Verify re-simulates a replay and checks that it ends the way the match
+// was reported to. The result is stored on the replay.
+func (h ReplayHandlers) Verify(context *gin.Context) {
+	logger.GetLogger().Info("Verifying replay")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	replay, ok := h.getReplay(context, user)
+	if !ok {
+		return
+	}
+	if replay.Match.Status != models.MatchFinished {
+		context.JSON(http.StatusConflict, gin.H{"error": "The match has no result yet"})
+		return
+	}
+
+	outcome, reason, err := h.verify(replay)
+	if err != nil {
+		logger.GetLogger().Error("Failed to verify replay:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify replay"})
+		return
+	}
+	if reason != "" {
+		logger.GetLogger().Warn("Replay failed verification:", reason)
+	}
+
+	context.JSON(http.StatusOK, gin.H{"verified": reason == "", "reason": reason, "outcome": dto.NewReplayOutcome(outcome)})
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultResetThreshold   = 4000
+	defaultResetKeepPercent = 50
+)
+
+type SeasonHandlers struct {
+	SeasonRepo repository.SeasonRepo
+	UserRepo   repository.UserRepo
+	Clock      utils.Clock
+}
+
+func NewSeasonHandlers(seasonRepo repository.SeasonRepo, userRepo repository.UserRepo, clock utils.Clock) *SeasonHandlers {
+	return &SeasonHandlers{SeasonRepo: seasonRepo, UserRepo: userRepo, Clock: clock}
+}
+
+func (h SeasonHandlers) ListSeasons(context *gin.Context) {
+	logger.GetLogger().Info("Fetching seasons")
+
+	seasons, err := h.SeasonRepo.GetSeasons()
+	if err != nil {
+		logger.GetLogger().Error("Failed to get seasons:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"seasons": dto.NewSeasons(seasons)})
+}
+
+// CurrentSeason shows the running season with the player's live rank.
+func (h SeasonHandlers) CurrentSeason(context *gin.Context) {
+	logger.GetLogger().Info("Fetching current season")
+
+	user, ok := loadCurrentUser(context, h.UserRepo)
+	if !ok {
+		return
+	}
+
+	season, err := h.SeasonRepo.GetCurrentSeason(h.Clock.Now())
+	if err != nil {
+		if errors.Is(err, helper.ErrSeasonNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "No season is running"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get current season:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	rank, err := h.SeasonRepo.GetRank(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get rank:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"season":   dto.NewSeason(season),
+		"rank":     rank,
+		"trophies": user.Awards,
+	})
+}
+
+func (h SeasonHandlers) MyResults(context *gin.Context) {
+	logger.GetLogger().Info("Fetching season results")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	results, err := h.SeasonRepo.GetSeasonResults(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get season results:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"results": dto.NewSeasonResults(results)})
+}
+
+func (h SeasonHandlers) CreateSeason(context *gin.Context) {
+	logger.GetLogger().Info("Scheduling season")
+
+	var form forms.CreateSeasonForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid season:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	season := models.Season{
+		Name:             strings.TrimSpace(form.Name),
+		StartsAt:         form.StartsAt,
+		EndsAt:           form.EndsAt,
+		ResetThreshold:   defaultResetThreshold,
+		ResetKeepPercent: defaultResetKeepPercent,
+		Rewards:          seasonRewards(form.Rewards),
+	}
+	if form.ResetThreshold != nil {
+		season.ResetThreshold = *form.ResetThreshold
+	}
+	if form.ResetKeepPercent != nil {
+		season.ResetKeepPercent = *form.ResetKeepPercent
+	}
+	if !h.validSeason(context, season) {
+		return
+	}
+
+	if err := h.SeasonRepo.CreateSeason(&season); err != nil {
+		if errors.Is(err, helper.ErrSeasonOverlap) {
+			context.JSON(http.StatusConflict, gin.H{"error": "Season overlaps another season"})
+			return
+		}
+		logger.GetLogger().Error("Failed to create season:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule season"})
+		return
+	}
+
+	logger.GetLogger().Info("Season scheduled")
+	context.JSON(http.StatusCreated, gin.H{"season": dto.NewSeason(season)})
+}
+
+func (h SeasonHandlers) UpdateSeason(context *gin.Context) {
+	logger.GetLogger().Info("Updating season")
+
+	var form forms.UpdateSeasonForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid season update:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	seasonID, ok := parseID(context, "id", "season")
+	if !ok {
+		return
+	}
+
+	season, err := h.SeasonRepo.GetSeason(seasonID)
+	if err != nil {
+		if errors.Is(err, helper.ErrSeasonNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Season not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get season:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if season.ClosedAt.Valid {
+		context.JSON(http.StatusConflict, gin.H{"error": "Season is already closed"})
+		return
+	}
+
+	if form.Name != nil {
+		season.Name = strings.TrimSpace(*form.Name)
+	}
+	if form.StartsAt != nil {
+		season.StartsAt = *form.StartsAt
+	}
+	if form.EndsAt != nil {
+		season.EndsAt = *form.EndsAt
+	}
+	if form.ResetThreshold != nil {
+		season.ResetThreshold = *form.ResetThreshold
+	}
+	if form.ResetKeepPercent != nil {
+		season.ResetKeepPercent = *form.ResetKeepPercent
+	}
+	if form.Rewards != nil {
+		season.Rewards = seasonRewards(form.Rewards)
+	}
+	if !h.validSeason(context, season) {
+		return
+	}
+
+	if err := h.SeasonRepo.UpdateSeason(season); err != nil {
+		switch {
+		case errors.Is(err, helper.ErrSeasonNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"error": "Season not found"})
+		case errors.Is(err, helper.ErrSeasonOverlap):
+			context.JSON(http.StatusConflict, gin.H{"error": "Season overlaps another season"})
+		case errors.Is(err, helper.ErrSeasonClosed):
+			context.JSON(http.StatusConflict, gin.H{"error": "Season is already closed"})
+		default:
+			logger.GetLogger().Error("Failed to update season:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update season"})
+		}
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"season": dto.NewSeason(season)})
+}
+
+func (h SeasonHandlers) GetTrophyRoad(context *gin.Context) {
+	logger.GetLogger().Info("Fetching trophy road")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	milestones, err := h.SeasonRepo.GetTrophyRoad(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get trophy road:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	best, err := h.SeasonRepo.GetBestTrophies(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get best trophies:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	result := make([]dto.TrophyRoadMilestone, 0, len(milestones))
+	for _, milestone := range milestones {
+		result = append(result, dto.NewTrophyRoadMilestone(milestone, best))
+	}
+	context.JSON(http.StatusOK, gin.H{"bestTrophies": best, "milestones": result})
+}
+
+func (h SeasonHandlers) ClaimMilestone(context *gin.Context) {
+	logger.GetLogger().Info("Claiming trophy road reward")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	milestoneID, ok := parseID(context, "id", "milestone")
+	if !ok {
+		return
+	}
+
+	milestone, err := h.SeasonRepo.ClaimMilestone(user.ID, milestoneID, h.Clock.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, helper.ErrMilestoneNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"error": "Milestone not found"})
+		case errors.Is(err, helper.ErrMilestoneLocked):
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Not enough trophies for this milestone"})
+		case errors.Is(err, helper.ErrMilestoneClaimed):
+			context.JSON(http.StatusConflict, gin.H{"error": "Reward already claimed"})
+		default:
+			logger.GetLogger().Error("Failed to claim trophy road reward:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim reward"})
+		}
+		return
+	}
+
+	logger.GetLogger().Info("Trophy road reward claimed")
+	context.JSON(http.StatusOK, gin.H{"milestone": dto.NewTrophyRoadMilestone(milestone, milestone.Trophies)})
+}
+
+// validSeason checks the dates and that no two reward tiers cover the same
+// rank.
+func (h SeasonHandlers) validSeason(context *gin.Context, season models.Season) bool {
+	if season.Name == "" {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Season name is required"})
+		return false
+	}
+	if !season.EndsAt.After(season.StartsAt) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Season must end after it starts"})
+		return false
+	}
+	if !season.EndsAt.After(h.Clock.Now()) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Season must end in the future"})
+		return false
+	}
+	for i := 1; i < len(season.Rewards); i++ {
+		if season.Rewards[i].MinRank <= season.Rewards[i-1].MaxRank {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Reward ranks overlap"})
+			return false
+		}
+	}
+	return true
+}
+
+// seasonRewards converts the form tiers, sorted by rank.
+func seasonRewards(tiers []forms.SeasonRewardForm) []models.SeasonReward {
+	rewards := make([]models.SeasonReward, 0, len(tiers))
+	for _, tier := range tiers {
+		rewards = append(rewards, models.SeasonReward{MinRank: tier.MinRank, MaxRank: tier.MaxRank, RewardGold: tier.RewardGold})
+	}
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].MinRank < rewards[j].MinRank })
+	return rewards
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/internal/shop"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type ShopHandlers struct {
+	Shop     *shop.Service
+	ShopRepo repository.ShopRepo
+	GameRepo repository.GameRepo
+	UserRepo repository.UserRepo
+}
+
+func NewShopHandlers(shopService *shop.Service, shopRepo repository.ShopRepo, gameRepo repository.GameRepo, userRepo repository.UserRepo) *ShopHandlers {
+	return &ShopHandlers{Shop: shopService, ShopRepo: shopRepo, GameRepo: gameRepo, UserRepo: userRepo}
+}
+
+// GetShop returns the player's daily offers and the bundles on sale, along
+// with both balances so the client can show what the player can afford.
+func (h ShopHandlers) GetShop(context *gin.Context) {
+	logger.GetLogger().Info("Fetching shop")
+
+	user, ok := loadCurrentUser(context, h.UserRepo)
+	if !ok {
+		return
+	}
+
+	daily, resetsAt, err := h.Shop.DailyOffers(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get daily offers:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	offers, err := h.Shop.Offers()
+	if err != nil {
+		logger.GetLogger().Error("Failed to get shop offers:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{
+		"daily":    dto.NewDailyOffers(daily),
+		"offers":   dto.NewShopOffers(offers),
+		"resetsAt": resetsAt,
+		"wallet":   dto.Wallet{Gold: user.Bank, Gems: user.Gems},
+	})
+}
+
+func (h ShopHandlers) BuyDailyOffer(context *gin.Context) {
+	logger.GetLogger().Info("Buying daily offer")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	offerID, ok := parseID(context, "id", "offer")
+	if !ok {
+		return
+	}
+
+	offer, err := h.Shop.BuyDailyOffer(context, user.ID, offerID)
+	if err != nil {
+		writePurchaseError(context, err)
+		return
+	}
+
+	logger.GetLogger().Info("Daily offer bought")
+	context.JSON(http.StatusOK, gin.H{"offer": dto.NewDailyOffer(offer)})
+}
+
+func (h ShopHandlers) BuyOffer(context *gin.Context) {
+	logger.GetLogger().Info("Buying shop offer")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	offerID, ok := parseID(context, "id", "offer")
+	if !ok {
+		return
+	}
+
+	offer, err := h.Shop.BuyOffer(context, user.ID, offerID)
+	if err != nil {
+		writePurchaseError(context, err)
+		return
+	}
+
+	logger.GetLogger().Info("Shop offer bought")
+	context.JSON(http.StatusOK, gin.H{"offer": dto.NewShopOffer(offer)})
+}
+
+func writePurchaseError(context *gin.Context, err error) {
+	switch {
+	case errors.Is(err, helper.ErrOfferNotFound):
+		context.JSON(http.StatusNotFound, gin.H{"error": "Offer not found"})
+	case errors.Is(err, helper.ErrOfferExpired):
+		context.JSON(http.StatusGone, gin.H{"error": "Offer has expired"})
+	case errors.Is(err, helper.ErrOfferSoldOut):
+		context.JSON(http.StatusConflict, gin.H{"error": "Offer is sold out"})
+	case errors.Is(err, helper.ErrOfferLimitReached):
+		context.JSON(http.StatusConflict, gin.H{"error": "You can't buy this offer again"})
+	case errors.Is(err, helper.ErrInsufficientBalance):
+		context.JSON(http.StatusForbidden, gin.H{"error": "Insufficient balance to buy the offer"})
+	case errors.Is(err, helper.ErrCardLocked):
+		context.JSON(http.StatusForbidden, gin.H{"error": "The offer contains cards from an arena you haven't reached"})
+	case errors.Is(err, helper.ErrInsufficientGems):
+		context.JSON(http.StatusForbidden, gin.H{"error": "Not enough gems to buy the offer"})
+	default:
+		logger.GetLogger().Error("Failed to buy offer:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to buy offer"})
+	}
+}
+
+func (h ShopHandlers) ListOffers(context *gin.Context) {
+	logger.GetLogger().Info("Fetching all shop offers")
+
+	offers, err := h.ShopRepo.GetOffers(nil)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get shop offers:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"offers": dto.NewShopOffers(offers)})
+}
+
+func (h ShopHandlers) CreateOffer(context *gin.Context) {
+	logger.GetLogger().Info("Creating shop offer")
+
+	var form forms.CreateShopOfferForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid shop offer:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if len(form.HeroIDs) == 0 && len(form.SpellIDs) == 0 && form.Gold == 0 {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Offer must contain cards or gold"})
+		return
+	}
+	if !form.EndsAt.After(form.StartsAt) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Offer must end after it starts"})
+		return
+	}
+
+	offer := models.ShopOffer{
+		Title:        strings.TrimSpace(form.Title),
+		Description:  form.Description,
+		Price:        form.Price,
+		Currency:     models.CurrencyGold,
+		RewardGold:   form.Gold,
+		StartsAt:     form.StartsAt,
+		EndsAt:       form.EndsAt,
+		Stock:        sql.NullInt64{Int64: form.Stock, Valid: form.Stock > 0},
+		PerUserLimit: sql.NullInt64{Int64: form.PerUserLimit, Valid: form.PerUserLimit > 0},
+		Active:       true,
+	}
+	if form.Currency != "" {
+		offer.Currency = form.Currency
+	}
+	for _, heroID := range form.HeroIDs {
+		if _, err := h.GameRepo.GetHeroByID(heroID); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Hero not found"})
+			return
+		}
+		offer.Items = append(offer.Items, models.ShopOfferItem{HeroID: sql.NullInt64{Int64: int64(heroID), Valid: true}})
+	}
+	for _, spellID := range form.SpellIDs {
+		if _, err := h.GameRepo.GetSpellByID(spellID); err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Spell not found"})
+			return
+		}
+		offer.Items = append(offer.Items, models.ShopOfferItem{SpellID: sql.NullInt64{Int64: int64(spellID), Valid: true}})
+	}
+
+	if err := h.ShopRepo.CreateOffer(&offer); err != nil {
+		logger.GetLogger().Error("Failed to create shop offer:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create offer"})
+		return
+	}
+
+	context.JSON(http.StatusCreated, gin.H{"offer": dto.NewShopOffer(offer)})
+}
+
+func (h ShopHandlers) UpdateOffer(context *gin.Context) {
+	logger.GetLogger().Info("Updating shop offer")
+
+	var form forms.UpdateShopOfferForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid shop offer update:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	offerID, ok := parseID(context, "id", "offer")
+	if !ok {
+		return
+	}
+
+	offer, err := h.ShopRepo.GetOffer(offerID)
+	if err != nil {
+		if errors.Is(err, helper.ErrOfferNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Offer not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get shop offer:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	if form.Title != nil {
+		offer.Title = strings.TrimSpace(*form.Title)
+	}
+	if form.Description != nil {
+		offer.Description = *form.Description
+	}
+	if form.Price != nil {
+		offer.Price = *form.Price
+	}
+	if form.Currency != nil {
+		offer.Currency = *form.Currency
+	}
+	if form.StartsAt != nil {
+		offer.StartsAt = *form.StartsAt
+	}
+	if form.EndsAt != nil {
+		offer.EndsAt = *form.EndsAt
+	}
+	if form.Stock != nil {
+		offer.Stock = sql.NullInt64{Int64: *form.Stock, Valid: *form.Stock > 0}
+	}
+	if form.PerUserLimit != nil {
+		offer.PerUserLimit = sql.NullInt64{Int64: *form.PerUserLimit, Valid: *form.PerUserLimit > 0}
+	}
+	if form.Active != nil {
+		offer.Active = *form.Active
+	}
+	if !offer.EndsAt.After(offer.StartsAt) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Offer must end after it starts"})
+		return
+	}
+
+	if err := h.ShopRepo.UpdateOffer(offer); err != nil {
+		logger.GetLogger().Error("Failed to update shop offer:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update offer"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"offer": dto.NewShopOffer(offer)})
+}
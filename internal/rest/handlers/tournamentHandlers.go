@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+type TournamentHandlers struct {
+	TournamentRepo repository.TournamentRepo
+	GameRepo       repository.GameRepo
+	Config         config.TournamentConfig
+	Clock          utils.Clock
+	Notifier       notifications.Notifier
+}
+
+func NewTournamentHandlers(tournamentRepo repository.TournamentRepo, gameRepo repository.GameRepo, tournamentConfig config.TournamentConfig, clock utils.Clock, notifier notifications.Notifier) *TournamentHandlers {
+	return &TournamentHandlers{
+		TournamentRepo: tournamentRepo,
+		GameRepo:       gameRepo,
+		Config:         tournamentConfig,
+		Clock:          clock,
+		Notifier:       notifier,
+	}
+}
+
+// ListTournaments lists tournaments, newest first, optionally only those with
+// the status given as ?status=.
+func (h TournamentHandlers) ListTournaments(context *gin.Context) {
+	logger.GetLogger().Info("Fetching tournaments")
+
+	status := strings.ToUpper(context.Query("status"))
+	switch status {
+	case "", models.TournamentRegistration, models.TournamentRunning, models.TournamentFinished, models.TournamentCancelled:
+	default:
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	list, err := h.TournamentRepo.GetTournaments(status)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get tournaments:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"tournaments": dto.NewTournaments(list)})
+}
+
+// tournament loads the tournament named by the :id parameter and answers the
+// request itself when it can't.
+func (h TournamentHandlers) tournament(context *gin.Context) (models.Tournament, bool) {
+	tournamentID, ok := parseID(context, "id", "tournament")
+	if !ok {
+		return models.Tournament{}, false
+	}
+
+	tournament, err := h.TournamentRepo.GetTournament(tournamentID)
+	if err != nil {
+		if errors.Is(err, helper.ErrTournamentNotFound) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+			return models.Tournament{}, false
+		}
+		logger.GetLogger().Error("Failed to get tournament:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return models.Tournament{}, false
+	}
+	return tournament, true
+}
+
+func (h TournamentHandlers) GetTournament(context *gin.Context) {
+	logger.GetLogger().Info("Fetching tournament")
+
+	tournament, ok := h.tournament(context)
+	if !ok {
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"tournament": dto.NewTournament(tournament)})
+}
+
+// CreateTournament sets up a tournament run by the player. Admins create
+// official tournaments and may add gold of their own to the prize pool.
+func (h TournamentHandlers) CreateTournament(context *gin.Context) {
+	logger.GetLogger().Info("Creating tournament")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.CreateTournamentForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid tournament:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	official := user.IsAdmin()
+	if form.BonusPool > 0 && !official {
+		context.JSON(http.StatusForbidden, gin.H{"error": "Only admins can add a bonus pool"})
+		return
+	}
+	if int(form.MaxPlayers) > h.Config.MaxPlayers {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Too many players"})
+		return
+	}
+	if form.Rounds > 0 && form.Format != models.TournamentSwiss {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Rounds can only be set for Swiss tournaments"})
+		return
+	}
+
+	now := h.Clock.Now()
+	opensAt := now
+	if form.RegistrationOpensAt != nil {
+		opensAt = *form.RegistrationOpensAt
+	}
+	if !form.RegistrationClosesAt.After(opensAt) || !form.RegistrationClosesAt.After(now) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Registration must close in the future and after it opens"})
+		return
+	}
+
+	var prizes []models.TournamentPrize
+	places := make(map[int32]bool, len(form.Prizes))
+	var percent int32
+	for _, prize := range form.Prizes {
+		if places[prize.Place] || prize.Place > form.MaxPlayers {
+			context.JSON(http.StatusBadRequest, gin.H{"error": "Each prize needs its own place within the player limit"})
+			return
+		}
+		places[prize.Place] = true
+		percent += prize.Percent
+		prizes = append(prizes, models.TournamentPrize{Place: prize.Place, Percent: prize.Percent})
+	}
+	if percent > 100 {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Prizes can't add up to more than the whole pool"})
+		return
+	}
+
+	roundMinutes := form.RoundMinutes
+	if roundMinutes == 0 {
+		roundMinutes = int32(h.Config.RoundMinutes)
+	}
+	tournament := models.Tournament{
+		Name:                 strings.TrimSpace(form.Name),
+		Format:               form.Format,
+		CreatedBy:            sql.NullInt64{Int64: int64(user.ID), Valid: true},
+		Official:             official,
+		EntryFee:             form.EntryFee,
+		BonusPool:            form.BonusPool,
+		MaxPlayers:           form.MaxPlayers,
+		Rounds:               form.Rounds,
+		RoundMinutes:         roundMinutes,
+		RegistrationOpensAt:  opensAt,
+		RegistrationClosesAt: form.RegistrationClosesAt,
+		Prizes:               prizes,
+	}
+	if err := h.TournamentRepo.CreateTournament(&tournament); err != nil {
+		logger.GetLogger().Error("Failed to create tournament:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tournament"})
+		return
+	}
+
+	logger.GetLogger().Info("Tournament created")
+	context.JSON(http.StatusCreated, gin.H{"tournament": dto.NewTournament(tournament)})
+}
+
+// Register enters the player with one of their decks, which is locked in for
+// the whole tournament, and takes the entry fee.
+func (h TournamentHandlers) Register(context *gin.Context) {
+	logger.GetLogger().Info("Registering for tournament")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.RegisterTournamentForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid tournament registration:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	tournamentID, ok := parseID(context, "id", "tournament")
+	if !ok {
+		return
+	}
+
+	deck, err := snapshotDeck(h.GameRepo, user.ID, form.DeckID)
+	if err != nil {
+		logger.GetLogger().Warn("Deck can't be used for a tournament:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Choose one of your decks with at least one card"})
+		return
+	}
+
+	player := models.TournamentPlayer{UserID: user.ID, Deck: deck}
+	if err := h.TournamentRepo.Register(tournamentID, player, h.Clock.Now()); err != nil {
+		switch {
+		case errors.Is(err, helper.ErrTournamentNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		case errors.Is(err, helper.ErrRegistrationClosed):
+			context.JSON(http.StatusConflict, gin.H{"error": "Registration is not open"})
+		case errors.Is(err, helper.ErrTournamentFull):
+			context.JSON(http.StatusConflict, gin.H{"error": "Tournament is full"})
+		case errors.Is(err, helper.ErrAlreadyRegistered):
+			context.JSON(http.StatusConflict, gin.H{"error": "You are already registered"})
+		case errors.Is(err, helper.ErrInsufficientBalance):
+			context.JSON(http.StatusForbidden, gin.H{"error": "Not enough gold for the entry fee"})
+		default:
+			logger.GetLogger().Error("Failed to register for tournament:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		}
+		return
+	}
+
+	logger.GetLogger().Info("Registered for tournament")
+	context.JSON(http.StatusOK, gin.H{"message": "Registered"})
+}
+
+// Withdraw takes the player out while registration is open and refunds the
+// entry fee.
+func (h TournamentHandlers) Withdraw(context *gin.Context) {
+	logger.GetLogger().Info("Withdrawing from tournament")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	tournamentID, ok := parseID(context, "id", "tournament")
+	if !ok {
+		return
+	}
+
+	if err := h.TournamentRepo.Withdraw(tournamentID, user.ID, h.Clock.Now()); err != nil {
+		switch {
+		case errors.Is(err, helper.ErrTournamentNotFound):
+			context.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		case errors.Is(err, helper.ErrNotRegistered):
+			context.JSON(http.StatusNotFound, gin.H{"error": "You are not registered"})
+		case errors.Is(err, helper.ErrRegistrationClosed):
+			context.JSON(http.StatusConflict, gin.H{"error": "Registration has closed"})
+		default:
+			logger.GetLogger().Error("Failed to withdraw from tournament:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw"})
+		}
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"message": "Withdrawn"})
+}
+
+// CancelTournament calls off a tournament that hasn't started and refunds
+// every entry fee. Only its creator or an admin may cancel it.
+func (h TournamentHandlers) CancelTournament(context *gin.Context) {
+	logger.GetLogger().Info("Cancelling tournament")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	tournament, ok := h.tournament(context)
+	if !ok {
+		return
+	}
+	if uint(tournament.CreatedBy.Int64) != user.ID && !user.IsAdmin() {
+		context.JSON(http.StatusForbidden, gin.H{"error": "Only the creator can cancel a tournament"})
+		return
+	}
+
+	update, err := h.TournamentRepo.CancelTournament(tournament.ID)
+	if err != nil {
+		if errors.Is(err, helper.ErrTournamentStarted) {
+			context.JSON(http.StatusConflict, gin.H{"error": "Tournament has already started"})
+			return
+		}
+		logger.GetLogger().Error("Failed to cancel tournament:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel tournament"})
+		return
+	}
+
+	for _, userID := range update.Refunded {
+		h.Notifier.Notify(context, models.Notification{
+			UserID: userID,
+			Kind:   models.NotificationTournament,
+			Title:  update.Tournament.Name + " was cancelled",
+			Body:   "Your entry fee has been refunded.",
+			Data:   map[string]interface{}{"tournamentId": tournament.ID},
+		})
+	}
+
+	logger.GetLogger().Info("Tournament cancelled")
+	context.JSON(http.StatusOK, gin.H{"message": "Tournament cancelled"})
+}
+
+// GetBracket returns every round's pairings so far.
+func (h TournamentHandlers) GetBracket(context *gin.Context) {
+	logger.GetLogger().Info("Fetching tournament bracket")
+
+	tournament, ok := h.tournament(context)
+	if !ok {
+		return
+	}
+
+	matches, err := h.TournamentRepo.GetBracket(tournament.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get tournament bracket:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"tournament": dto.NewTournament(tournament), "matches": dto.NewTournamentMatches(matches)})
+}
+
+func (h TournamentHandlers) GetStandings(context *gin.Context) {
+	logger.GetLogger().Info("Fetching tournament standings")
+
+	tournament, ok := h.tournament(context)
+	if !ok {
+		return
+	}
+
+	standings, err := h.TournamentRepo.GetStandings(tournament.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get tournament standings:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"tournament": dto.NewTournament(tournament), "standings": dto.NewTournamentStandings(standings)})
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	redis "auth/internal/db/redis"
+	"auth/internal/rest/forms"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+const twoFactorIssuer = "AituRoyale"
+
+func (h AuthHandlers) EnrollTwoFactor(context *gin.Context) {
+	logger.GetLogger().Info("Starting two-factor enrollment")
+
+	user, ok := loadCurrentUser(context, h.Repo)
+	if !ok {
+		return
+	}
+
+	twoFactor, err := h.TwoFactorRepo.GetTwoFactor(user.ID)
+	if err != nil && !errors.Is(err, helper.ErrTwoFactorNotFound) {
+		logger.GetLogger().Error("Failed to get two-factor:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if err == nil && twoFactor.Enabled {
+		logger.GetLogger().Warn("Two-factor authentication already enabled for user:", user.ID)
+		context.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		logger.GetLogger().Error("Failed to generate TOTP secret:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	if err := h.TwoFactorRepo.SaveTwoFactorSecret(user.ID, secret); err != nil {
+		logger.GetLogger().Error("Failed to save TOTP secret:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start enrollment"})
+		return
+	}
+
+	logger.GetLogger().Info("Two-factor enrollment started")
+	context.JSON(http.StatusOK, gin.H{
+		"secret":          secret,
+		"provisioningUri": utils.TOTPProvisioningURI(twoFactorIssuer, user.Email, secret),
+	})
+}
+
+func (h AuthHandlers) ConfirmTwoFactor(context *gin.Context) {
+	logger.GetLogger().Info("Confirming two-factor enrollment")
+
+	var form forms.CodeForm
+	if err := context.BindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid two-factor confirm request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	twoFactor, err := h.TwoFactorRepo.GetTwoFactor(user.ID)
+	if errors.Is(err, helper.ErrTwoFactorNotFound) {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor enrollment not started"})
+		return
+	} else if err != nil {
+		logger.GetLogger().Error("Failed to get two-factor:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	if twoFactor.Enabled {
+		context.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	if !h.checkTOTP(user.ID, twoFactor.Secret, form.Code) {
+		logger.GetLogger().Warn("Invalid two-factor code during confirmation for user:", user.ID)
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	recoveryCodes, err := h.resetRecoveryCodes(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to create recovery codes:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recovery codes"})
+		return
+	}
+
+	if err := h.TwoFactorRepo.EnableTwoFactor(user.ID); err != nil {
+		logger.GetLogger().Error("Failed to enable two-factor:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	logger.GetLogger().Info("Two-factor authentication enabled")
+	context.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recoveryCodes": recoveryCodes})
+}
+
+func (h AuthHandlers) VerifyTwoFactor(context *gin.Context) {
+	logger.GetLogger().Info("Verifying second login factor")
+
+	var form forms.TwoFactorLoginForm
+	if err := context.BindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid two-factor login request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if form.Code == "" && form.RecoveryCode == "" {
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Code or recovery code is required"})
+		return
+	}
+
+	userID, found, err := redis.TakeTwoFactorChallenge(context, h.RedisConfig, form.Challenge)
+	if err != nil {
+		logger.GetLogger().Error("Failed to read two-factor challenge:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if !found {
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "Login challenge expired, sign in again"})
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(userID)
+	if err != nil {
+		logger.GetLogger().Error("User not found:", err)
+		context.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	twoFactor, err := h.TwoFactorRepo.GetTwoFactor(user.ID)
+	if err != nil && !errors.Is(err, helper.ErrTwoFactorNotFound) {
+		logger.GetLogger().Error("Failed to get two-factor:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+	if err != nil || !twoFactor.Enabled {
+		logger.GetLogger().Error("Two-factor not enabled for user:", user.ID)
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	var verified bool
+	if form.Code != "" {
+		verified = h.checkTOTP(user.ID, twoFactor.Secret, form.Code)
+	} else {
+		verified, err = h.TwoFactorRepo.UseRecoveryCode(user.ID, utils.HashRecoveryCode(form.RecoveryCode))
+		if err != nil {
+			logger.GetLogger().Error("Failed to check recovery code:", err)
+			context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+			return
+		}
+	}
+	if !verified {
+		logger.GetLogger().Warn("Second factor rejected for user:", user.ID)
+		context.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	token, err := h.issueToken(context, user)
+	if err != nil {
+		logger.GetLogger().Error("Failed to create token:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	logger.GetLogger().Info("User login successful")
+	context.JSON(http.StatusOK, gin.H{"token": token})
+}
+
+func (h AuthHandlers) ResetTwoFactor(context *gin.Context) {
+	logger.GetLogger().Info("Resetting two-factor authentication")
+
+	userID, err := strconv.ParseUint(context.Param("id"), 10, 64)
+	if err != nil {
+		logger.GetLogger().Error("Invalid user ID format:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(uint(userID))
+	if err != nil {
+		logger.GetLogger().Error("User not found:", err)
+		context.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.TwoFactorRepo.DeleteTwoFactor(user.ID); err != nil {
+		logger.GetLogger().Error("Failed to reset two-factor:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset two-factor authentication"})
+		return
+	}
+
+	logger.GetLogger().Info("Two-factor authentication reset by admin for user:", user.ID)
+	context.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
+
+func (h AuthHandlers) checkTOTP(userID uint, secret, code string) bool {
+	step, ok := utils.ValidateTOTPCode(secret, code, h.Clock.Now())
+	if !ok {
+		return false
+	}
+	consumed, err := h.TwoFactorRepo.ConsumeTOTPStep(userID, step)
+	if err != nil {
+		logger.GetLogger().Error("Failed to consume TOTP step:", err)
+		return false
+	}
+	return consumed
+}
+
+func (h AuthHandlers) resetRecoveryCodes(userID uint) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashRecoveryCode(code))
+	}
+	if err := h.TwoFactorRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/db/redis/redistest"
+	"auth/internal/rest/models"
+	"auth/pkg/middleware"
+	"auth/pkg/rest/helper"
+	"auth/pkg/utils"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTwoFactor mirrors TwoFactorRepository: pending secrets can be
+// replaced until confirmed, a TOTP step is only accepted once and later,
+// and each recovery code works once.
+type fakeTwoFactor struct {
+	mu       sync.Mutex
+	byUser   map[uint]*models.TwoFactor
+	recovery map[uint]map[string]bool
+	err      error
+}
+
+func newFakeTwoFactor() *fakeTwoFactor {
+	return &fakeTwoFactor{byUser: make(map[uint]*models.TwoFactor), recovery: make(map[uint]map[string]bool)}
+}
+
+func (f *fakeTwoFactor) GetTwoFactor(userID uint) (*models.TwoFactor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	twoFactor, ok := f.byUser[userID]
+	if !ok {
+		return nil, helper.ErrTwoFactorNotFound
+	}
+	found := *twoFactor
+	return &found, nil
+}
+
+func (f *fakeTwoFactor) SaveTwoFactorSecret(userID uint, secret string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if twoFactor, ok := f.byUser[userID]; ok && twoFactor.Enabled {
+		return errors.New("two-factor authentication is already enabled")
+	}
+	f.byUser[userID] = &models.TwoFactor{UserID: userID, Secret: secret}
+	return nil
+}
+
+func (f *fakeTwoFactor) EnableTwoFactor(userID uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.byUser[userID].Enabled = true
+	return nil
+}
+
+func (f *fakeTwoFactor) ConsumeTOTPStep(userID uint, step int64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	twoFactor := f.byUser[userID]
+	if twoFactor.LastUsedStep >= step {
+		return false, nil
+	}
+	twoFactor.LastUsedStep = step
+	return true, nil
+}
+
+func (f *fakeTwoFactor) DeleteTwoFactor(userID uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.byUser, userID)
+	delete(f.recovery, userID)
+	return nil
+}
+
+func (f *fakeTwoFactor) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recovery[userID] = make(map[string]bool)
+	for _, hash := range codeHashes {
+		f.recovery[userID][hash] = false
+	}
+	return nil
+}
+
+func (f *fakeTwoFactor) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	used, ok := f.recovery[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	f.recovery[userID][codeHash] = true
+	return true, nil
+}
+
+type twoFactorTest struct {
+	router    *gin.Engine
+	users     *fakeUsers
+	twoFactor *fakeTwoFactor
+	clock     *fakeClock
+}
+
+func newTwoFactorTest(t *testing.T) *twoFactorTest {
+	_, redisConfig := redistest.Start(t)
+	test := &twoFactorTest{
+		users:     newFakeUsers(),
+		twoFactor: newFakeTwoFactor(),
+		clock:     &fakeClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	h := NewAuthHandlers(test.users, test.twoFactor, nil, nil, redisConfig, config.EmailConfig{}, config.AccountConfig{}, test.clock)
+
+	requireUser := middleware.RequireUser(test.users)
+	test.router = gin.New()
+	test.router.POST("/auth/login", h.Login)
+	test.router.POST("/auth/2fa/enroll", requireUser, h.EnrollTwoFactor)
+	test.router.POST("/auth/2fa/confirm", requireUser, h.ConfirmTwoFactor)
+	test.router.POST("/auth/2fa/verify", h.VerifyTwoFactor)
+	test.router.POST("/auth/2fa/reset/:id", requireUser, middleware.RequireAdmin, h.ResetTwoFactor)
+	return test
+}
+
+func (test *twoFactorTest) code(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := utils.GenerateTOTPCode(secret, test.clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// enroll turns two-factor authentication on for the user and returns the
+// secret and recovery codes.
+func (test *twoFactorTest) enroll(t *testing.T, user *models.User) (string, []string) {
+	t.Helper()
+	token := tokenFor(t, user)
+	status, body := call(t, test.router, http.MethodPost, "/auth/2fa/enroll", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("enroll answered %d: %v", status, body)
+	}
+	secret := body["secret"].(string)
+
+	status, body = call(t, test.router, http.MethodPost, "/auth/2fa/confirm", token, gin.H{"code": test.code(t, secret)})
+	if status != http.StatusOK {
+		t.Fatalf("confirm answered %d: %v", status, body)
+	}
+	var codes []string
+	for _, code := range body["recoveryCodes"].([]interface{}) {
+		codes = append(codes, code.(string))
+	}
+	// The confirming code's step is spent; later logins need the next one.
+	test.clock.Advance(30 * time.Second)
+	return secret, codes
+}
+
+// login signs in with the password and returns the challenge.
+func (test *twoFactorTest) login(t *testing.T, username string) string {
+	t.Helper()
+	status, body := call(t, test.router, http.MethodPost, "/auth/login", "", gin.H{"username": username, "password": "secret-password"})
+	if status != http.StatusOK || body["twoFactorRequired"] != true {
+		t.Fatalf("login answered %d: %v", status, body)
+	}
+	if _, ok := body["token"]; ok {
+		t.Fatal("login issued a token before the second factor")
+	}
+	return body["challenge"].(string)
+}
+
+func TestEnrollAndConfirmTwoFactor(t *testing.T) {
+	test := newTwoFactorTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	token := tokenFor(t, user)
+
+	status, body := call(t, test.router, http.MethodPost, "/auth/2fa/confirm", token, gin.H{"code": "123456"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("confirm before enrolling answered %d: %v", status, body)
+	}
+
+	status, body = call(t, test.router, http.MethodPost, "/auth/2fa/enroll", token, nil)
+	if status != http.StatusOK {
+		t.Fatalf("enroll answered %d: %v", status, body)
+	}
+	secret, _ := body["secret"].(string)
+	if secret == "" || body["provisioningUri"] == "" {
+		t.Fatalf("enroll answered without a secret: %v", body)
+	}
+
+	status, _ = call(t, test.router, http.MethodPost, "/auth/2fa/confirm", token, gin.H{"code": "000000"})
+	if status != http.StatusUnauthorized {
+		t.Errorf("confirm with a wrong code answered %d, want 401", status)
+	}
+
+	status, body = call(t, test.router, http.MethodPost, "/auth/2fa/confirm", token, gin.H{"code": test.code(t, secret)})
+	if status != http.StatusOK {
+		t.Fatalf("confirm answered %d: %v", status, body)
+	}
+	if codes := body["recoveryCodes"].([]interface{}); len(codes) != 10 {
+		t.Errorf("got %d recovery codes, want 10", len(codes))
+	}
+
+	status, _ = call(t, test.router, http.MethodPost, "/auth/2fa/enroll", token, nil)
+	if status != http.StatusConflict {
+		t.Errorf("enrolling again answered %d, want 409", status)
+	}
+}
+
+func TestLoginWithoutTwoFactorIssuesToken(t *testing.T) {
+	test := newTwoFactorTest(t)
+	test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+
+	status, body := call(t, test.router, http.MethodPost, "/auth/login", "", gin.H{"username": "alice", "password": "secret-password"})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("login answered %d: %v", status, body)
+	}
+}
+
+func TestLoginChallenge(t *testing.T) {
+	test := newTwoFactorTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	secret, _ := test.enroll(t, user)
+
+	challenge := test.login(t, "alice")
+
+	status, _ := call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": "not-a-challenge", "code": test.code(t, secret)})
+	if status != http.StatusUnauthorized {
+		t.Errorf("verify with an unknown challenge answered %d, want 401", status)
+	}
+
+	status, body := call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": challenge, "code": test.code(t, secret)})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("verify answered %d: %v", status, body)
+	}
+
+	test.clock.Advance(30 * time.Second)
+	status, _ = call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": challenge, "code": test.code(t, secret)})
+	if status != http.StatusUnauthorized {
+		t.Errorf("reusing the challenge answered %d, want 401", status)
+	}
+}
+
+func TestTOTPCodeCannotBeReplayed(t *testing.T) {
+	test := newTwoFactorTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	secret, _ := test.enroll(t, user)
+	code := test.code(t, secret)
+
+	status, _ := call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": test.login(t, "alice"), "code": code})
+	if status != http.StatusOK {
+		t.Fatalf("first use of the code answered %d", status)
+	}
+
+	status, _ = call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": test.login(t, "alice"), "code": code})
+	if status != http.StatusUnauthorized {
+		t.Errorf("replaying the code answered %d, want 401", status)
+	}
+
+	// A code from an earlier step is still within the allowed skew, but the
+	// step was passed, so it is rejected too.
+	earlier, err := utils.GenerateTOTPCode(secret, test.clock.Now().Add(-30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, _ = call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": test.login(t, "alice"), "code": earlier})
+	if status != http.StatusUnauthorized {
+		t.Errorf("a code from an earlier step answered %d, want 401", status)
+	}
+
+	test.clock.Advance(30 * time.Second)
+	status, _ = call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": test.login(t, "alice"), "code": test.code(t, secret)})
+	if status != http.StatusOK {
+		t.Errorf("the next step's code answered %d, want 200", status)
+	}
+}
+
+func TestRecoveryCodesWorkOnce(t *testing.T) {
+	test := newTwoFactorTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	_, codes := test.enroll(t, user)
+
+	status, body := call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": test.login(t, "alice"), "recoveryCode": codes[0]})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("recovery code answered %d: %v", status, body)
+	}
+
+	status, _ = call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": test.login(t, "alice"), "recoveryCode": codes[0]})
+	if status != http.StatusUnauthorized {
+		t.Errorf("reusing a recovery code answered %d, want 401", status)
+	}
+
+	status, _ = call(t, test.router, http.MethodPost, "/auth/2fa/verify", "", gin.H{"challenge": test.login(t, "alice"), "recoveryCode": codes[1]})
+	if status != http.StatusOK {
+		t.Errorf("another recovery code answered %d, want 200", status)
+	}
+}
+
+func TestAdminResetsTwoFactor(t *testing.T) {
+	test := newTwoFactorTest(t)
+	user := test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	admin := test.users.add(t, models.User{Username: "admin", Email: "admin@example.com", UserType: "ADMIN"})
+	test.enroll(t, user)
+	path := "/auth/2fa/reset/" + strconv.Itoa(int(user.ID))
+
+	status, _ := call(t, test.router, http.MethodPost, path, tokenFor(t, user), nil)
+	if status != http.StatusForbidden {
+		t.Errorf("reset by the user answered %d, want 403", status)
+	}
+
+	status, _ = call(t, test.router, http.MethodPost, path, tokenFor(t, admin), nil)
+	if status != http.StatusOK {
+		t.Fatalf("reset by an admin answered %d", status)
+	}
+
+	status, body := call(t, test.router, http.MethodPost, "/auth/login", "", gin.H{"username": "alice", "password": "secret-password"})
+	if status != http.StatusOK || body["token"] == nil {
+		t.Errorf("login after the reset answered %d: %v", status, body)
+	}
+}
+
+func TestLoginFailsClosedWhenTwoFactorCannotBeRead(t *testing.T) {
+	test := newTwoFactorTest(t)
+	test.users.add(t, models.User{Username: "alice", Email: "alice@example.com", Password: "secret-password"})
+	test.twoFactor.err = errors.New("connection refused")
+
+	status, body := call(t, test.router, http.MethodPost, "/auth/login", "", gin.H{"username": "alice", "password": "secret-password"})
+	if status != http.StatusInternalServerError {
+		t.Errorf("login answered %d, want 500", status)
+	}
+	if body["token"] != nil {
+		t.Error("login issued a token without checking two-factor")
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"auth/internal/config"
+	"auth/internal/repository"
+	"auth/internal/rest/dto"
+	"auth/internal/rest/forms"
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"auth/pkg/rest/helper"
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const walletPageSize = 50
+
+type WalletHandlers struct {
+	WalletRepo repository.WalletRepo
+	UserRepo   repository.UserRepo
+	Config     config.WalletConfig
+}
+
+func NewWalletHandlers(walletRepo repository.WalletRepo, userRepo repository.UserRepo, walletConfig config.WalletConfig) *WalletHandlers {
+	return &WalletHandlers{WalletRepo: walletRepo, UserRepo: userRepo, Config: walletConfig}
+}
+
+func (h WalletHandlers) GetWallet(context *gin.Context) {
+	logger.GetLogger().Info("Fetching wallet")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	wallet, err := h.WalletRepo.GetWallet(user.ID)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get wallet:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"wallet": dto.NewWallet(wallet), "goldPerGem": h.Config.GoldPerGem})
+}
+
+func (h WalletHandlers) ListTransactions(context *gin.Context) {
+	logger.GetLogger().Info("Fetching wallet transactions")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	before, limit, ok := parseCursor(context, walletPageSize)
+	if !ok {
+		return
+	}
+
+	transactions, err := h.WalletRepo.GetTransactions(user.ID, before, limit)
+	if err != nil {
+		logger.GetLogger().Error("Failed to get wallet transactions:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	response := gin.H{"transactions": dto.NewWalletTransactions(transactions)}
+	if len(transactions) > 0 && len(transactions) == limit {
+		response["nextCursor"] = transactions[len(transactions)-1].ID
+	}
+	context.JSON(http.StatusOK, response)
+}
+
+// ExchangeGems buys gold with gems at the configured rate.
+func (h WalletHandlers) ExchangeGems(context *gin.Context) {
+	logger.GetLogger().Info("Exchanging gems for gold")
+
+	user, ok := currentUser(context)
+	if !ok {
+		return
+	}
+
+	var form forms.ExchangeGemsForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid exchange request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	wallet, err := h.WalletRepo.ExchangeGems(user.ID, form.Gems, form.Gems*h.Config.GoldPerGem)
+	if err != nil {
+		if errors.Is(err, helper.ErrInsufficientGems) {
+			context.JSON(http.StatusForbidden, gin.H{"error": "Not enough gems"})
+			return
+		}
+		logger.GetLogger().Error("Failed to exchange gems:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange gems"})
+		return
+	}
+
+	logger.GetLogger().Info("Gems exchanged")
+	context.JSON(http.StatusOK, gin.H{"wallet": dto.NewWallet(wallet)})
+}
+
+func (h WalletHandlers) Grant(context *gin.Context) {
+	logger.GetLogger().Info("Granting currency")
+
+	var form forms.GrantCurrencyForm
+	if err := context.ShouldBindJSON(&form); err != nil {
+		logger.GetLogger().Error("Invalid grant request:", err)
+		context.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if _, err := h.UserRepo.GetUserByID(form.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			context.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		logger.GetLogger().Error("Failed to get user:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	wallet, err := h.WalletRepo.Grant(form.UserID, form.Currency, form.Amount, models.ReasonAdminGrant, form.Reference)
+	if err != nil {
+		logger.GetLogger().Error("Failed to grant currency:", err)
+		context.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant currency"})
+		return
+	}
+
+	context.JSON(http.StatusOK, gin.H{"wallet": dto.NewWallet(wallet)})
+}
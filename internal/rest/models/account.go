@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Purchase is a card the user bought, read back from the collection tables.
+type Purchase struct {
+	Kind      string    `json:"Kind"`
+	ItemID    uint      `json:"ItemID"`
+	Name      string    `json:"Name"`
+	Price     int64     `json:"Price"`
+	CreatedAt time.Time `json:"CreatedAt"`
+}
+
+// AccountExport is the archive returned by the "download my data" endpoint.
+// Purchases are the cards bought with gold; StorePurchases the gems bought
+// in app stores, and Transactions the whole wallet ledger.
+type AccountExport struct {
+	ExportedAt     time.Time           `json:"ExportedAt"`
+	Profile        ExportedUser        `json:"Profile"`
+	Heros          []Hero              `json:"Heros"`
+	Spells         []Spell             `json:"Spells"`
+	Decks          []Deck              `json:"Decks"`
+	Purchases      []Purchase          `json:"Purchases"`
+	StorePurchases []StorePurchase     `json:"StorePurchases"`
+	Transactions   []WalletTransaction `json:"Transactions"`
+	Identities     []Identity          `json:"Identities"`
+}
+
+type ExportedUser struct {
+	ID        uint       `json:"ID"`
+	CreatedAt time.Time  `json:"CreatedAt"`
+	DeletedAt *time.Time `json:"DeletedAt,omitempty"`
+	Username  string     `json:"Username"`
+	Email     string     `json:"Email"`
+	Bank      int64      `json:"Bank"`
+	Gems      int64      `json:"Gems"`
+	Awards    int32      `json:"Awards"`
+	UserType  string     `json:"UserType"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Arena struct {
+	ID          uint        `json:"ID"`
+	CreatedAt   time.Time   `json:"CreatedAt"`
+	UpdatedAt   time.Time   `json:"UpdatedAt"`
+	Name        string      `json:"Name"`
+	MinTrophies int32       `json:"MinTrophies"`
+	Cards       []ArenaCard `json:"Cards"`
+}
+
+// ArenaCard is a card unlocked by an arena. Exactly one of HeroID and SpellID
+// is set.
+type ArenaCard struct {
+	HeroID  sql.NullInt64 `json:"HeroID"`
+	SpellID sql.NullInt64 `json:"SpellID"`
+	Name    string        `json:"Name"`
+}
+
+// CardLocks maps the ids of the cards a player hasn't unlocked yet to the
+// trophies they need.
+type CardLocks struct {
+	Heroes map[uint]int32
+	Spells map[uint]int32
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	ChatChannelClan   = "CLAN"
+	ChatChannelDirect = "DIRECT"
+
+	ChatReportOpen      = "OPEN"
+	ChatReportActioned  = "ACTIONED"
+	ChatReportDismissed = "DISMISSED"
+
+	// A muted player can still read chat; a banned one can't use it at all.
+	SanctionMute = "MUTE"
+	SanctionBan  = "BAN"
+
+	ModerationHideMessage   = "HIDE_MESSAGE"
+	ModerationResolveReport = "RESOLVE_REPORT"
+	ModerationMute          = "MUTE"
+	ModerationBan           = "BAN"
+	ModerationRevoke        = "REVOKE_SANCTION"
+)
+
+type ChatMessage struct {
+	ID          uint          `json:"ID"`
+	CreatedAt   time.Time     `json:"CreatedAt"`
+	Channel     string        `json:"Channel"`
+	ClanID      sql.NullInt64 `json:"ClanID"`
+	SenderID    uint          `json:"SenderID"`
+	SenderName  string        `json:"SenderName"`
+	RecipientID sql.NullInt64 `json:"RecipientID"`
+	Body        string        `json:"Body"`
+	HiddenAt    pq.NullTime   `json:"HiddenAt"`
+}
+
+type ChatReport struct {
+	ID           uint          `json:"ID"`
+	CreatedAt    time.Time     `json:"CreatedAt"`
+	MessageID    uint          `json:"MessageID"`
+	ReporterID   uint          `json:"ReporterID"`
+	ReporterName string        `json:"ReporterName"`
+	Reason       string        `json:"Reason"`
+	Status       string        `json:"Status"`
+	ResolvedBy   sql.NullInt64 `json:"ResolvedBy"`
+	ResolvedAt   pq.NullTime   `json:"ResolvedAt"`
+	Message      ChatMessage   `json:"Message"`
+}
+
+type ChatSanction struct {
+	ID        uint        `json:"ID"`
+	CreatedAt time.Time   `json:"CreatedAt"`
+	UserID    uint        `json:"UserID"`
+	Kind      string      `json:"Kind"`
+	Reason    string      `json:"Reason"`
+	ExpiresAt pq.NullTime `json:"ExpiresAt"`
+	RevokedAt pq.NullTime `json:"RevokedAt"`
+	CreatedBy uint        `json:"CreatedBy"`
+}
+
+// ModerationAction is one row of the moderation audit log. Only the target
+// columns that apply to Action are set.
+type ModerationAction struct {
+	ID            uint          `json:"ID"`
+	CreatedAt     time.Time     `json:"CreatedAt"`
+	ModeratorID   sql.NullInt64 `json:"ModeratorID"`
+	ModeratorName string        `json:"ModeratorName"`
+	Action        string        `json:"Action"`
+	TargetUserID  sql.NullInt64 `json:"TargetUserID"`
+	MessageID     sql.NullInt64 `json:"MessageID"`
+	ReportID      sql.NullInt64 `json:"ReportID"`
+	SanctionID    sql.NullInt64 `json:"SanctionID"`
+	Details       string        `json:"Details"`
+}
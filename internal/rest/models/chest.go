@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	ChestSilver  = "SILVER"
+	ChestGolden  = "GOLDEN"
+	ChestMagical = "MAGICAL"
+)
+
+// ChestKind is what a kind of chest holds and what it takes to open it:
+// either waiting UnlockTime or paying gems to skip the wait. GemPrice is
+// what the shop sells one for, opened on the spot.
+type ChestKind struct {
+	UnlockTime time.Duration
+	Gold       int64
+	Cards      int
+	GemPrice   int64
+}
+
+var ChestKinds = map[string]ChestKind{
+	ChestSilver:  {UnlockTime: 3 * time.Hour, Gold: 50, Cards: 3, GemPrice: 20},
+	ChestGolden:  {UnlockTime: 8 * time.Hour, Gold: 200, Cards: 8, GemPrice: 60},
+	ChestMagical: {UnlockTime: 12 * time.Hour, Gold: 600, Cards: 20, GemPrice: 150},
+}
+
+// Chest is one chest a player has won. It is locked until an unlock is
+// started, which sets UnlocksAt; only one chest unlocks at a time.
+type Chest struct {
+	ID        uint        `json:"ID"`
+	CreatedAt time.Time   `json:"CreatedAt"`
+	UserID    uint        `json:"UserID"`
+	Kind      string      `json:"Kind"`
+	UnlocksAt pq.NullTime `json:"UnlocksAt"`
+	OpenedAt  pq.NullTime `json:"OpenedAt"`
+}
+
+// TimeLeft is how long the chest still takes to unlock: the whole unlock time
+// when none was started, nothing once it may be opened.
+func (c Chest) TimeLeft(now time.Time) time.Duration {
+	if !c.UnlocksAt.Valid {
+		return ChestKinds[c.Kind].UnlockTime
+	}
+	if left := c.UnlocksAt.Time.Sub(now); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// SpeedUpCost is the gems it takes to open the chest now, gemsPerHour for
+// every hour left, a started hour counting as a whole one.
+func (c Chest) SpeedUpCost(now time.Time, gemsPerHour int64) int64 {
+	left := c.TimeLeft(now)
+	hours := int64((left + time.Hour - 1) / time.Hour)
+	return hours * gemsPerHour
+}
+
+// ChestReward is what opening a chest granted. Cards the player already owns
+// aren't drawn, so a chest holds fewer cards once the collection is nearly
+// complete.
+type ChestReward struct {
+	ChestID uint
+	Kind    string
+	Gold    int64
+	Cards   []ChestCard
+}
+
+// ChestCard is one card drawn from a chest. Exactly one of HeroID and SpellID
+// is set.
+type ChestCard struct {
+	HeroID  sql.NullInt64
+	SpellID sql.NullInt64
+	Name    string
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+const (
+	ClanOpen       = "OPEN"
+	ClanInviteOnly = "INVITE_ONLY"
+	ClanClosed     = "CLOSED"
+
+	ClanRoleLeader   = "LEADER"
+	ClanRoleCoLeader = "CO_LEADER"
+	ClanRoleElder    = "ELDER"
+	ClanRoleMember   = "MEMBER"
+
+	CardKindHero  = "HERO"
+	CardKindSpell = "SPELL"
+
+	CardRequestOpen      = "OPEN"
+	CardRequestFilled    = "FILLED"
+	CardRequestCancelled = "CANCELLED"
+)
+
+var clanRoleRanks = map[string]int{
+	ClanRoleMember:   1,
+	ClanRoleElder:    2,
+	ClanRoleCoLeader: 3,
+	ClanRoleLeader:   4,
+}
+
+// ClanRoleRank orders roles so permission checks can compare them;
+// unknown roles rank below MEMBER.
+func ClanRoleRank(role string) int {
+	return clanRoleRanks[role]
+}
+
+type Clan struct {
+	ID          uint      `json:"ID"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	UpdatedAt   time.Time `json:"UpdatedAt"`
+	Name        string    `json:"Name"`
+	Tag         string    `json:"Tag"`
+	Description string    `json:"Description"`
+	Membership  string    `json:"Membership"`
+	MinTrophies int32     `json:"MinTrophies"`
+	MemberCount int       `json:"MemberCount"`
+	Trophies    int64     `json:"Trophies"`
+}
+
+type ClanMember struct {
+	ClanID    uint      `json:"ClanID"`
+	UserID    uint      `json:"UserID"`
+	Username  string    `json:"Username"`
+	Awards    int32     `json:"Awards"`
+	Role      string    `json:"Role"`
+	Donations int32     `json:"Donations"`
+	JoinedAt  time.Time `json:"JoinedAt"`
+}
+
+type ClanJoinRequest struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	ClanID    uint      `json:"ClanID"`
+	UserID    uint      `json:"UserID"`
+	Username  string    `json:"Username"`
+	Awards    int32     `json:"Awards"`
+}
+
+type CardRequest struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	ClanID    uint      `json:"ClanID"`
+	UserID    uint      `json:"UserID"`
+	Username  string    `json:"Username"`
+	CardKind  string    `json:"CardKind"`
+	CardID    uint      `json:"CardID"`
+	CardName  string    `json:"CardName"`
+	Requested int32     `json:"Requested"`
+	Donated   int32     `json:"Donated"`
+	Status    string    `json:"Status"`
+}
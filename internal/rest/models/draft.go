@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	DraftDrafting  = "DRAFTING"
+	DraftFinished  = "FINISHED"
+	DraftCancelled = "CANCELLED"
+
+	DraftCardHero  = "hero"
+	DraftCardSpell = "spell"
+)
+
+// Draft is a draft between two players who build their decks by alternately
+// picking one of two cards from the catalog, Player1 first. Offer is what the
+// player on turn chooses from until PickDeadline, after which one of the
+// cards is picked for them. A player's IDs are invalid once their account is
+// deleted, which cancels a draft still running.
+type Draft struct {
+	ID           uint          `json:"ID"`
+	CreatedAt    time.Time     `json:"CreatedAt"`
+	Player1ID    sql.NullInt64 `json:"Player1ID"`
+	Player1Name  string        `json:"Player1Name"`
+	Player2ID    sql.NullInt64 `json:"Player2ID"`
+	Player2Name  string        `json:"Player2Name"`
+	Status       string        `json:"Status"`
+	DeckSize     int32         `json:"DeckSize"`
+	PickSeconds  int32         `json:"PickSeconds"`
+	PickNumber   int32         `json:"PickNumber"`
+	Offer        []DraftCard   `json:"Offer"`
+	PickDeadline time.Time     `json:"PickDeadline"`
+	MatchID      sql.NullInt64 `json:"MatchID"`
+	Picks        []DraftPick   `json:"Picks"`
+}
+
+// Picking is the player whose turn it is: picks alternate between the two
+// players, starting with Player1.
+func (d Draft) Picking() sql.NullInt64 {
+	if d.PickNumber%2 == 0 {
+		return d.Player1ID
+	}
+	return d.Player2ID
+}
+
+// DraftCard is a hero or a spell of the catalog as it was when offered.
+type DraftCard struct {
+	Kind  string `json:"kind"`
+	Hero  *Hero  `json:"hero,omitempty"`
+	Spell *Spell `json:"spell,omitempty"`
+}
+
+// CardID is the id of the hero or spell.
+func (c DraftCard) CardID() uint {
+	if c.Hero != nil {
+		return c.Hero.ID
+	}
+	if c.Spell != nil {
+		return c.Spell.ID
+	}
+	return 0
+}
+
+type DraftPick struct {
+	CreatedAt  time.Time     `json:"CreatedAt"`
+	UserID     sql.NullInt64 `json:"UserID"`
+	PickNumber int32         `json:"PickNumber"`
+	Card       DraftCard     `json:"Card"`
+	Auto       bool          `json:"Auto"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+const (
+	FriendshipPending  = "PENDING"
+	FriendshipAccepted = "ACCEPTED"
+)
+
+type Friendship struct {
+	ID          uint      `json:"ID"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	UpdatedAt   time.Time `json:"UpdatedAt"`
+	RequesterID uint      `json:"RequesterID"`
+	AddresseeID uint      `json:"AddresseeID"`
+	Status      string    `json:"Status"`
+}
+
+// OtherUser returns the player on the other side of the friendship.
+func (f Friendship) OtherUser(userID uint) uint {
+	if f.RequesterID == userID {
+		return f.AddresseeID
+	}
+	return f.RequesterID
+}
+
+type Friend struct {
+	UserID   uint      `json:"UserID"`
+	Username string    `json:"Username"`
+	Awards   int32     `json:"Awards"`
+	Since    time.Time `json:"Since"`
+}
+
+type FriendRequest struct {
+	ID            uint      `json:"ID"`
+	CreatedAt     time.Time `json:"CreatedAt"`
+	RequesterID   uint      `json:"RequesterID"`
+	RequesterName string    `json:"RequesterName"`
+	AddresseeID   uint      `json:"AddresseeID"`
+	AddresseeName string    `json:"AddresseeName"`
+}
+
+type BlockedUser struct {
+	UserID    uint      `json:"UserID"`
+	Username  string    `json:"Username"`
+	BlockedAt time.Time `json:"BlockedAt"`
+}
+
+type PlayerSearchResult struct {
+	UserID   uint   `json:"UserID"`
+	Username string `json:"Username"`
+	Awards   int32  `json:"Awards"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+type Identity struct {
+	ID        uint        `json:"ID,omitempty"`
+	CreatedAt time.Time   `json:"CreatedAt"`
+	UpdatedAt time.Time   `json:"UpdatedAt"`
+	DeletedAt pq.NullTime `json:"DeletedAt"`
+	UserID    uint        `json:"UserID"`
+	Provider  string      `json:"Provider"`
+	Subject   string      `json:"Subject"`
+	Email     string      `json:"Email"`
+}
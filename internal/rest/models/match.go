@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	MatchModeFriendly   = "FRIENDLY"
+	MatchModeTournament = "TOURNAMENT"
+	MatchModeTeam2v2    = "TEAM_2V2"
+	MatchModeDraft      = "DRAFT"
+	MatchModePractice   = "PRACTICE"
+
+	MatchStarted  = "STARTED"
+	MatchFinished = "FINISHED"
+
+	ChallengePending   = "PENDING"
+	ChallengeAccepted  = "ACCEPTED"
+	ChallengeDeclined  = "DECLINED"
+	ChallengeCancelled = "CANCELLED"
+	ChallengeExpired   = "EXPIRED"
+
+	BotEasy   = "EASY"
+	BotNormal = "NORMAL"
+	BotHard   = "HARD"
+)
+
+// Match is a single battle. Only ranked matches may change Awards or grant
+// rewards; friendly challenges, team battles, drafts and practice matches
+// are always created with Ranked set to false. Matches with teams set
+// WinningTeam instead of WinnerID.
+type Match struct {
+	ID          uint          `json:"ID"`
+	CreatedAt   time.Time     `json:"CreatedAt"`
+	UpdatedAt   time.Time     `json:"UpdatedAt"`
+	Mode        string        `json:"Mode"`
+	Ranked      bool          `json:"Ranked"`
+	Status      string        `json:"Status"`
+	WinnerID    sql.NullInt64 `json:"WinnerID"`
+	WinningTeam sql.NullInt64 `json:"WinningTeam"`
+	FinishedAt  pq.NullTime   `json:"FinishedAt"`
+	Players     []MatchPlayer `json:"Players"`
+}
+
+// HasTeams reports whether the players are split into teams: in team modes,
+// and in practice so that the bot's side can win.
+func (m Match) HasTeams() bool {
+	return len(m.Players) > 0 && m.Players[0].Team != 0
+}
+
+// HasBots reports whether a bot played. Such matches are for practice and
+// never count as a real result.
+func (m Match) HasBots() bool {
+	for _, player := range m.Players {
+		if player.Bot != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPlayer is one player of a match. Team is 1 or 2 in team modes, where
+// each player still brings their own deck, and 0 otherwise. A bot has no
+// UserID; Bot is its difficulty. A deleted player's seat keeps its deck but
+// has neither.
+type MatchPlayer struct {
+	UserID   uint         `json:"UserID"`
+	Username string       `json:"Username"`
+	Team     int32        `json:"Team"`
+	Bot      string       `json:"Bot"`
+	Deck     DeckSnapshot `json:"Deck"`
+}
+
+// DeckSnapshot is the deck a player locked in when the match started.
+type DeckSnapshot struct {
+	DeckID uint    `json:"deckId"`
+	Name   string  `json:"name"`
+	Heroes []Hero  `json:"heroes"`
+	Spells []Spell `json:"spells"`
+}
+
+type Challenge struct {
+	ID               uint          `json:"ID"`
+	CreatedAt        time.Time     `json:"CreatedAt"`
+	UpdatedAt        time.Time     `json:"UpdatedAt"`
+	ChallengerID     uint          `json:"ChallengerID"`
+	ChallengerName   string        `json:"ChallengerName"`
+	ChallengerDeckID uint          `json:"ChallengerDeckID"`
+	OpponentID       sql.NullInt64 `json:"OpponentID"`
+	OpponentName     string        `json:"OpponentName"`
+	JoinCode         string        `json:"JoinCode"`
+	Status           string        `json:"Status"`
+	ExpiresAt        time.Time     `json:"ExpiresAt"`
+	MatchID          sql.NullInt64 `json:"MatchID"`
+}
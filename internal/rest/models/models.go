@@ -6,19 +6,23 @@ import (
 )
 
 type User struct {
-	ID        uint      `json:"ID,omitempty"`
-	CreatedAt time.Time `json:"CreatedAt"`
-	UpdatedAt time.Time `json:"UpdatedAt"`
-	DeletedAt time.Time `json:"DeletedAt"`
-	Username  string    `json:"username" json:"Username,omitempty"`
-	Email     string    `json:"email" json:"Email,omitempty"`
-	Password  string    `json:"password" json:"Password,omitempty"`
-	Heros     []Hero    `json:"heros" json:"Heros,omitempty"`
-	Spells    []Spell   `json:"spells" json:"Spells,omitempty"`
-	Deck      []Deck    `json:"deck" json:"Deck,omitempty"`
-	Bank      int64     `json:"bank" json:"Bank,omitempty"`
-	Awards    int32     `json:"awards" json:"Awards,omitempty"`
-	UserType  string    `json:"userType"`
+	ID        uint        `json:"ID,omitempty"`
+	CreatedAt time.Time   `json:"CreatedAt"`
+	UpdatedAt time.Time   `json:"UpdatedAt"`
+	DeletedAt pq.NullTime `json:"DeletedAt"`
+	Username  string      `json:"username"`
+	Email     string      `json:"email"`
+	Password  string      `json:"-"`
+	Heros     []Hero      `json:"heros"`
+	Spells    []Spell     `json:"spells"`
+	Deck      []Deck      `json:"deck"`
+	Bank      int64       `json:"bank"`
+	Gems      int64       `json:"gems"`
+	Awards    int32       `json:"awards"`
+	UserType  string      `json:"userType"`
+	// TokenVersion is stamped into every token issued to the user. Tokens
+	// carrying an older version are revoked.
+	TokenVersion int32 `json:"-"`
 }
 
 type Deck struct {
@@ -28,8 +32,8 @@ type Deck struct {
 	DeletedAt   pq.NullTime `json:"DeletedAt"`
 	Name        string      `json:"Name,omitempty"`
 	Description string      `json:"Description,omitempty"`
-	Heroes      []Hero      `json:"heroes" json:"Heroes,omitempty"`
-	Spells      []Spell     `json:"spells" json:"Spells,omitempty"`
+	Heroes      []Hero      `json:"heroes"`
+	Spells      []Spell     `json:"spells"`
 	UserID      uint        `json:"UserID"`
 }
 
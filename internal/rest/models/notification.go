@@ -0,0 +1,52 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	NotificationFriendRequest  = "FRIEND_REQUEST"
+	NotificationFriendAccepted = "FRIEND_ACCEPTED"
+	NotificationChallenge      = "CHALLENGE"
+	NotificationPurchase       = "PURCHASE"
+	NotificationChestUnlocked  = "CHEST_UNLOCKED"
+	NotificationClanJoined     = "CLAN_JOINED"
+	NotificationClanDonation   = "CLAN_DONATION"
+	NotificationBalancePatch   = "BALANCE_PATCH"
+	NotificationAnnouncement   = "ANNOUNCEMENT"
+	NotificationQuestCompleted = "QUEST_COMPLETED"
+	NotificationSeasonEnded    = "SEASON_ENDED"
+	NotificationTournament     = "TOURNAMENT"
+	NotificationPartyInvite    = "PARTY_INVITE"
+	NotificationMatchFound     = "MATCH_FOUND"
+	NotificationDraftStarted   = "DRAFT_STARTED"
+)
+
+// Notification is a message shown in a player's notification center. Data
+// carries whatever ids the client needs to link to the event, such as
+// {"clanId": 3}.
+type Notification struct {
+	ID        uint                   `json:"ID"`
+	CreatedAt time.Time              `json:"CreatedAt"`
+	UserID    uint                   `json:"UserID"`
+	Kind      string                 `json:"Kind"`
+	Title     string                 `json:"Title"`
+	Body      string                 `json:"Body"`
+	Data      map[string]interface{} `json:"Data"`
+	ReadAt    pq.NullTime            `json:"ReadAt"`
+}
+
+type NotificationSettings struct {
+	UserID       uint        `json:"UserID"`
+	EmailDigest  bool        `json:"EmailDigest"`
+	LastDigestAt pq.NullTime `json:"LastDigestAt"`
+}
+
+// NotificationDigest is the unread notifications one player gets by email.
+type NotificationDigest struct {
+	UserID        uint           `json:"UserID"`
+	Email         string         `json:"Email"`
+	Username      string         `json:"Username"`
+	Notifications []Notification `json:"Notifications"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Party is two friends queueing for 2v2 together. It has a member once the
+// invited friend joins.
+type Party struct {
+	ID          uint          `json:"ID"`
+	CreatedAt   time.Time     `json:"CreatedAt"`
+	LeaderID    uint          `json:"LeaderID"`
+	LeaderName  string        `json:"LeaderName"`
+	LeaderDeck  DeckSnapshot  `json:"LeaderDeck"`
+	InvitedID   sql.NullInt64 `json:"InvitedID"`
+	InvitedName string        `json:"InvitedName"`
+	MemberID    sql.NullInt64 `json:"MemberID"`
+	MemberName  string        `json:"MemberName"`
+	MemberDeck  DeckSnapshot  `json:"MemberDeck"`
+	Queued      bool          `json:"Queued"`
+}
+
+// TeamQueueEntry is a player waiting for a 2v2 match, alone or with their
+// party.
+type TeamQueueEntry struct {
+	ID        uint          `json:"ID"`
+	CreatedAt time.Time     `json:"CreatedAt"`
+	UserID    uint          `json:"UserID"`
+	Username  string        `json:"Username"`
+	PartyID   sql.NullInt64 `json:"PartyID"`
+	Deck      DeckSnapshot  `json:"Deck"`
+}
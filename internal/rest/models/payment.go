@@ -0,0 +1,30 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	PurchaseCompleted   = "COMPLETED"
+	PurchaseRefunded    = "REFUNDED"
+	PurchaseChargedBack = "CHARGED_BACK"
+)
+
+// StorePurchase is a gem pack bought through an app store. ClawedBack is how
+// many of its gems were taken back when it was reversed, all of them even when
+// that left the balance negative. UserID is null once the buyer's account is
+// deleted; the purchase stays so its transaction can't be redeemed again.
+type StorePurchase struct {
+	ID            uint          `json:"ID"`
+	CreatedAt     time.Time     `json:"CreatedAt"`
+	UserID        sql.NullInt64 `json:"UserID"`
+	Provider      string        `json:"Provider"`
+	TransactionID string        `json:"TransactionID"`
+	ProductID     string        `json:"ProductID"`
+	Gems          int64         `json:"Gems"`
+	Status        string        `json:"Status"`
+	ReversedAt    pq.NullTime   `json:"ReversedAt"`
+	ClawedBack    int64         `json:"ClawedBack"`
+}
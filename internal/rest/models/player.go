@@ -0,0 +1,38 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PlayerProfile holds the public showcase choices, privacy switches and
+// match counters of a user. Users without a row get DefaultPlayerProfile.
+type PlayerProfile struct {
+	UserID         uint          `json:"UserID"`
+	CreatedAt      time.Time     `json:"CreatedAt"`
+	UpdatedAt      time.Time     `json:"UpdatedAt"`
+	FavoriteHeroID sql.NullInt64 `json:"FavoriteHeroID"`
+	CurrentDeckID  sql.NullInt64 `json:"CurrentDeckID"`
+	ShowStats      bool          `json:"ShowStats"`
+	ShowCollection bool          `json:"ShowCollection"`
+	ShowDeck       bool          `json:"ShowDeck"`
+	ShowFavorite   bool          `json:"ShowFavorite"`
+	Wins           int32         `json:"Wins"`
+	Losses         int32         `json:"Losses"`
+}
+
+func DefaultPlayerProfile(userID uint) PlayerProfile {
+	return PlayerProfile{
+		UserID:         userID,
+		ShowStats:      true,
+		ShowCollection: true,
+		ShowDeck:       true,
+		ShowFavorite:   true,
+	}
+}
+
+type CollectionCompletion struct {
+	Category string `json:"Category"`
+	Owned    int    `json:"Owned"`
+	Total    int    `json:"Total"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// PromoCode grants its rewards to whoever redeems it. MaxUses is null for
+// codes without a cap on total redemptions and RewardChest for codes that
+// grant no chest.
+type PromoCode struct {
+	ID           uint            `json:"ID"`
+	CreatedAt    time.Time       `json:"CreatedAt"`
+	UpdatedAt    time.Time       `json:"UpdatedAt"`
+	Code         string          `json:"Code"`
+	Description  string          `json:"Description"`
+	RewardGold   int64           `json:"RewardGold"`
+	RewardGems   int64           `json:"RewardGems"`
+	RewardChest  sql.NullString  `json:"RewardChest"`
+	ExpiresAt    pq.NullTime     `json:"ExpiresAt"`
+	MaxUses      sql.NullInt64   `json:"MaxUses"`
+	Uses         int64           `json:"Uses"`
+	PerUserLimit int64           `json:"PerUserLimit"`
+	Active       bool            `json:"Active"`
+	Items        []PromoCodeItem `json:"Items"`
+}
+
+type PromoCodeItem struct {
+	HeroID  sql.NullInt64 `json:"HeroID"`
+	SpellID sql.NullInt64 `json:"SpellID"`
+	Name    string        `json:"Name"`
+}
+
+type PromoRedemption struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	CodeID    uint      `json:"CodeID"`
+	UserID    uint      `json:"UserID"`
+	Username  string    `json:"Username"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	QuestDaily       = "DAILY"
+	QuestAchievement = "ACHIEVEMENT"
+
+	// AchievementPeriod is the period key achievements are tracked under.
+	AchievementPeriod = "ALL"
+
+	EventMatchPlayed = "MATCH_PLAYED"
+	EventMatchWon    = "MATCH_WON"
+	EventSpellPlayed = "SPELL_PLAYED"
+	EventHeroBought  = "HERO_BOUGHT"
+	EventSpellBought = "SPELL_BOUGHT"
+)
+
+type QuestDefinition struct {
+	ID            uint          `json:"ID"`
+	CreatedAt     time.Time     `json:"CreatedAt"`
+	UpdatedAt     time.Time     `json:"UpdatedAt"`
+	Code          string        `json:"Code"`
+	Kind          string        `json:"Kind"`
+	Title         string        `json:"Title"`
+	Description   string        `json:"Description"`
+	Event         string        `json:"Event"`
+	Rarity        string        `json:"Rarity"`
+	Target        int32         `json:"Target"`
+	RewardGold    int64         `json:"RewardGold"`
+	RewardHeroID  sql.NullInt64 `json:"RewardHeroID"`
+	RewardSpellID sql.NullInt64 `json:"RewardSpellID"`
+	Active        bool          `json:"Active"`
+}
+
+// QuestProgress is a player's standing on one quest in one period. ID is
+// zero until the player has made progress.
+type QuestProgress struct {
+	ID          uint            `json:"ID"`
+	Definition  QuestDefinition `json:"Definition"`
+	Period      string          `json:"Period"`
+	Progress    int32           `json:"Progress"`
+	CompletedAt pq.NullTime     `json:"CompletedAt"`
+	ClaimedAt   pq.NullTime     `json:"ClaimedAt"`
+}
+
+// QuestEvent is something a player did that quests may count. Rarity is set
+// for card purchases so quests can ask for a specific rarity.
+type QuestEvent struct {
+	UserID uint   `json:"UserID"`
+	Type   string `json:"Type"`
+	Count  int32  `json:"Count"`
+	Rarity string `json:"Rarity"`
+}
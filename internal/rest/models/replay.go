@@ -0,0 +1,25 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// Replay is the recording of a match. Inputs is the encoded input log; the
+// replays package decodes and simulates it. Verified is unset until the
+// replay has been checked against the match's reported result.
+type Replay struct {
+	ID           uint         `json:"ID"`
+	CreatedAt    time.Time    `json:"CreatedAt"`
+	MatchID      uint         `json:"MatchID"`
+	Seed         int64        `json:"Seed"`
+	StatsVersion string       `json:"StatsVersion"`
+	DurationMs   int32        `json:"DurationMs"`
+	InputCount   int32        `json:"InputCount"`
+	Inputs       []byte       `json:"-"`
+	Verified     sql.NullBool `json:"Verified"`
+	VerifiedAt   pq.NullTime  `json:"VerifiedAt"`
+	VerifyError  string       `json:"VerifyError"`
+	Match        Match        `json:"Match"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+type Season struct {
+	ID               uint           `json:"ID"`
+	CreatedAt        time.Time      `json:"CreatedAt"`
+	UpdatedAt        time.Time      `json:"UpdatedAt"`
+	Name             string         `json:"Name"`
+	StartsAt         time.Time      `json:"StartsAt"`
+	EndsAt           time.Time      `json:"EndsAt"`
+	ResetThreshold   int32          `json:"ResetThreshold"`
+	ResetKeepPercent int32          `json:"ResetKeepPercent"`
+	ClosedAt         pq.NullTime    `json:"ClosedAt"`
+	Rewards          []SeasonReward `json:"Rewards"`
+}
+
+// SeasonReward pays RewardGold to every player whose final rank is between
+// MinRank and MaxRank.
+type SeasonReward struct {
+	MinRank    int32 `json:"MinRank"`
+	MaxRank    int32 `json:"MaxRank"`
+	RewardGold int64 `json:"RewardGold"`
+}
+
+// SeasonResult is where a player finished a closed season and what the
+// close did to their account.
+type SeasonResult struct {
+	SeasonID      uint   `json:"SeasonID"`
+	SeasonName    string `json:"SeasonName"`
+	UserID        uint   `json:"UserID"`
+	Rank          int32  `json:"Rank"`
+	Trophies      int32  `json:"Trophies"`
+	ResetTrophies int32  `json:"ResetTrophies"`
+	RewardGold    int64  `json:"RewardGold"`
+}
+
+type TrophyRoadMilestone struct {
+	ID            uint          `json:"ID"`
+	Trophies      int32         `json:"Trophies"`
+	RewardGold    int64         `json:"RewardGold"`
+	RewardHeroID  sql.NullInt64 `json:"RewardHeroID"`
+	RewardSpellID sql.NullInt64 `json:"RewardSpellID"`
+	ClaimedAt     pq.NullTime   `json:"ClaimedAt"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// DailyOffer is one discounted card in a player's daily shop. Exactly one of
+// HeroID and SpellID is set.
+type DailyOffer struct {
+	ID          uint          `json:"ID"`
+	UserID      uint          `json:"UserID"`
+	Period      string        `json:"Period"`
+	HeroID      sql.NullInt64 `json:"HeroID"`
+	SpellID     sql.NullInt64 `json:"SpellID"`
+	Name        string        `json:"Name"`
+	Rarity      string        `json:"Rarity"`
+	ListPrice   int64         `json:"ListPrice"`
+	Price       int64         `json:"Price"`
+	PurchasedAt pq.NullTime   `json:"PurchasedAt"`
+}
+
+// ShopOffer is a bundle sold for a limited time. Value is what its contents
+// would cost bought one by one, which is what its discount is shown against.
+type ShopOffer struct {
+	ID           uint            `json:"ID"`
+	CreatedAt    time.Time       `json:"CreatedAt"`
+	UpdatedAt    time.Time       `json:"UpdatedAt"`
+	Title        string          `json:"Title"`
+	Description  string          `json:"Description"`
+	Price        int64           `json:"Price"`
+	Currency     string          `json:"Currency"`
+	RewardGold   int64           `json:"RewardGold"`
+	StartsAt     time.Time       `json:"StartsAt"`
+	EndsAt       time.Time       `json:"EndsAt"`
+	Stock        sql.NullInt64   `json:"Stock"`
+	Sold         int64           `json:"Sold"`
+	PerUserLimit sql.NullInt64   `json:"PerUserLimit"`
+	Active       bool            `json:"Active"`
+	Items        []ShopOfferItem `json:"Items"`
+	Value        int64           `json:"Value"`
+}
+
+type ShopOfferItem struct {
+	HeroID  sql.NullInt64 `json:"HeroID"`
+	SpellID sql.NullInt64 `json:"SpellID"`
+	Name    string        `json:"Name"`
+	Rarity  string        `json:"Rarity"`
+	Price   int64         `json:"Price"`
+}
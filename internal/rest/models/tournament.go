@@ -0,0 +1,88 @@
+package models
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	TournamentSingleElimination = "SINGLE_ELIMINATION"
+	TournamentSwiss             = "SWISS"
+
+	TournamentRegistration = "REGISTRATION"
+	TournamentRunning      = "RUNNING"
+	TournamentFinished     = "FINISHED"
+	TournamentCancelled    = "CANCELLED"
+)
+
+// Tournament is created by a player, or by an admin as an Official one with a
+// BonusPool added to the entry fees. CreatedBy is NULL once the creator's
+// account is deleted.
+type Tournament struct {
+	ID                   uint              `json:"ID"`
+	CreatedAt            time.Time         `json:"CreatedAt"`
+	UpdatedAt            time.Time         `json:"UpdatedAt"`
+	Name                 string            `json:"Name"`
+	Format               string            `json:"Format"`
+	CreatedBy            sql.NullInt64     `json:"CreatedBy"`
+	Official             bool              `json:"Official"`
+	EntryFee             int64             `json:"EntryFee"`
+	BonusPool            int64             `json:"BonusPool"`
+	MaxPlayers           int32             `json:"MaxPlayers"`
+	Rounds               int32             `json:"Rounds"`
+	RoundMinutes         int32             `json:"RoundMinutes"`
+	RegistrationOpensAt  time.Time         `json:"RegistrationOpensAt"`
+	RegistrationClosesAt time.Time         `json:"RegistrationClosesAt"`
+	Status               string            `json:"Status"`
+	CurrentRound         int32             `json:"CurrentRound"`
+	RoundEndsAt          pq.NullTime       `json:"RoundEndsAt"`
+	FinishedAt           pq.NullTime       `json:"FinishedAt"`
+	Players              int32             `json:"Players"`
+	PrizePool            int64             `json:"PrizePool"`
+	Prizes               []TournamentPrize `json:"Prizes"`
+}
+
+// TournamentPrize is the percent of the prize pool paid to a final place.
+type TournamentPrize struct {
+	Place   int32 `json:"Place"`
+	Percent int32 `json:"Percent"`
+}
+
+// TournamentPlayer is a registration and, once the tournament runs, the
+// player's standing. Seed is set when the tournament starts, and Place and
+// PrizeGold when it finishes.
+type TournamentPlayer struct {
+	UserID          uint          `json:"UserID"`
+	Username        string        `json:"Username"`
+	Deck            DeckSnapshot  `json:"Deck"`
+	FeePaid         int64         `json:"FeePaid"`
+	Seed            sql.NullInt64 `json:"Seed"`
+	Points          int32         `json:"Points"`
+	EliminatedRound sql.NullInt64 `json:"EliminatedRound"`
+	Place           sql.NullInt64 `json:"Place"`
+	PrizeGold       int64         `json:"PrizeGold"`
+}
+
+// TournamentMatch is one pairing of a round. A pairing without Player2ID is a
+// bye and has no match.
+type TournamentMatch struct {
+	Round       int32         `json:"Round"`
+	Position    int32         `json:"Position"`
+	Player1ID   sql.NullInt64 `json:"Player1ID"`
+	Player1Name string        `json:"Player1Name"`
+	Player2ID   sql.NullInt64 `json:"Player2ID"`
+	Player2Name string        `json:"Player2Name"`
+	MatchID     sql.NullInt64 `json:"MatchID"`
+	WinnerID    sql.NullInt64 `json:"WinnerID"`
+	FinishedAt  pq.NullTime   `json:"FinishedAt"`
+}
+
+// TournamentUpdate is what advancing a tournament did, so players can be told
+// about it.
+type TournamentUpdate struct {
+	Tournament Tournament
+	Pairings   []TournamentMatch
+	Standings  []TournamentPlayer
+	Refunded   []uint
+}
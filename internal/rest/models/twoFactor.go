@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+type TwoFactor struct {
+	ID           uint        `json:"ID,omitempty"`
+	CreatedAt    time.Time   `json:"CreatedAt"`
+	UpdatedAt    time.Time   `json:"UpdatedAt"`
+	DeletedAt    pq.NullTime `json:"DeletedAt"`
+	UserID       uint        `json:"UserID"`
+	Secret       string      `json:"-"`
+	Enabled      bool        `json:"Enabled"`
+	LastUsedStep int64       `json:"-"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+const (
+	CurrencyGold = "GOLD"
+	CurrencyGems = "GEMS"
+
+	// Reasons recorded on wallet transactions.
+	ReasonSignup           = "SIGNUP"
+	ReasonCardPurchase     = "CARD_PURCHASE"
+	ReasonDailyOffer       = "DAILY_OFFER"
+	ReasonShopOffer        = "SHOP_OFFER"
+	ReasonQuestReward      = "QUEST_REWARD"
+	ReasonTrophyRoad       = "TROPHY_ROAD"
+	ReasonSeasonReward     = "SEASON_REWARD"
+	ReasonClanDonation     = "CLAN_DONATION"
+	ReasonExchange         = "EXCHANGE"
+	ReasonAdminGrant       = "ADMIN_GRANT"
+	ReasonStore            = "STORE_PURCHASE"
+	ReasonRefund           = "REFUND"
+	ReasonChargeback       = "CHARGEBACK"
+	ReasonPromoCode        = "PROMO_CODE"
+	ReasonTournamentEntry  = "TOURNAMENT_ENTRY"
+	ReasonTournamentRefund = "TOURNAMENT_REFUND"
+	ReasonTournamentPrize  = "TOURNAMENT_PRIZE"
+	ReasonChest            = "CHEST"
+	ReasonChestPurchase    = "CHEST_PURCHASE"
+	ReasonChestSpeedUp     = "CHEST_SPEED_UP"
+)
+
+type Wallet struct {
+	UserID uint  `json:"UserID"`
+	Gold   int64 `json:"Gold"`
+	Gems   int64 `json:"Gems"`
+}
+
+// WalletTransaction is one entry in a player's currency ledger. Amount is
+// negative for debits.
+type WalletTransaction struct {
+	ID        uint      `json:"ID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UserID    uint      `json:"UserID"`
+	Currency  string    `json:"Currency"`
+	Amount    int64     `json:"Amount"`
+	Reason    string    `json:"Reason"`
+	Reference string    `json:"Reference"`
+}
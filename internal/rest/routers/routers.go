@@ -7,42 +7,295 @@ import (
 )
 
 type Routers struct {
-	authHandlers handlers.AuthHandlers
-	gameHandlers handlers.GameHandlers
+	authHandlers         handlers.AuthHandlers
+	gameHandlers         handlers.GameHandlers
+	accountHandlers      handlers.AccountHandlers
+	playerHandlers       handlers.PlayerHandlers
+	friendHandlers       handlers.FriendHandlers
+	matchHandlers        handlers.MatchHandlers
+	clanHandlers         handlers.ClanHandlers
+	chatHandlers         handlers.ChatHandlers
+	moderationHandlers   handlers.ModerationHandlers
+	notificationHandlers handlers.NotificationHandlers
+	questHandlers        handlers.QuestHandlers
+	seasonHandlers       handlers.SeasonHandlers
+	shopHandlers         handlers.ShopHandlers
+	walletHandlers       handlers.WalletHandlers
+	chestHandlers        handlers.ChestHandlers
+	paymentHandlers      handlers.PaymentHandlers
+	promoHandlers        handlers.PromoHandlers
+	arenaHandlers        handlers.ArenaHandlers
+	tournamentHandlers   handlers.TournamentHandlers
+	partyHandlers        handlers.PartyHandlers
+	draftHandlers        handlers.DraftHandlers
+	botHandlers          handlers.BotHandlers
+	replayHandlers       handlers.ReplayHandlers
+	requireUser          gin.HandlerFunc
 }
 
-func NewRouters(authHandlers handlers.AuthHandlers, gameHandlers handlers.GameHandlers) *Routers {
-	return &Routers{authHandlers: authHandlers, gameHandlers: gameHandlers}
+func NewRouters(authHandlers handlers.AuthHandlers, gameHandlers handlers.GameHandlers, accountHandlers handlers.AccountHandlers, playerHandlers handlers.PlayerHandlers, friendHandlers handlers.FriendHandlers, matchHandlers handlers.MatchHandlers, clanHandlers handlers.ClanHandlers, chatHandlers handlers.ChatHandlers, moderationHandlers handlers.ModerationHandlers, notificationHandlers handlers.NotificationHandlers, questHandlers handlers.QuestHandlers, seasonHandlers handlers.SeasonHandlers, shopHandlers handlers.ShopHandlers, walletHandlers handlers.WalletHandlers, chestHandlers handlers.ChestHandlers, paymentHandlers handlers.PaymentHandlers, promoHandlers handlers.PromoHandlers, arenaHandlers handlers.ArenaHandlers, tournamentHandlers handlers.TournamentHandlers, partyHandlers handlers.PartyHandlers, draftHandlers handlers.DraftHandlers, botHandlers handlers.BotHandlers, replayHandlers handlers.ReplayHandlers, users middleware.UserLoader) *Routers {
+	return &Routers{
+		authHandlers:         authHandlers,
+		gameHandlers:         gameHandlers,
+		accountHandlers:      accountHandlers,
+		playerHandlers:       playerHandlers,
+		friendHandlers:       friendHandlers,
+		matchHandlers:        matchHandlers,
+		clanHandlers:         clanHandlers,
+		chatHandlers:         chatHandlers,
+		moderationHandlers:   moderationHandlers,
+		notificationHandlers: notificationHandlers,
+		questHandlers:        questHandlers,
+		seasonHandlers:       seasonHandlers,
+		shopHandlers:         shopHandlers,
+		walletHandlers:       walletHandlers,
+		chestHandlers:        chestHandlers,
+		paymentHandlers:      paymentHandlers,
+		promoHandlers:        promoHandlers,
+		arenaHandlers:        arenaHandlers,
+		tournamentHandlers:   tournamentHandlers,
+		partyHandlers:        partyHandlers,
+		draftHandlers:        draftHandlers,
+		botHandlers:          botHandlers,
+		replayHandlers:       replayHandlers,
+		requireUser:          middleware.RequireUser(users),
+	}
 }
 
 func (r *Routers) SetupRoutes(app *gin.Engine) {
+	app.GET("/.well-known/jwks.json", r.authHandlers.JWKS)
+
 	appRouter := app.Group("/app")
 	{
+		appRouter.GET("/stream", r.requireUser, r.chatHandlers.Stream)
 		authRouter := appRouter.Group("/auth")
 		{
-			authRouter.POST("/register", r.authHandlers.Register)                                               //+
-			authRouter.POST("/login", r.authHandlers.Login)                                                     // +
-			authRouter.POST("/forgotPassword", r.authHandlers.ForgotPassword)                                   // +
-			authRouter.POST("/resetPassword", r.authHandlers.ResetPassword)                                     // +
-			authRouter.POST("/checkVerificationCode", r.authHandlers.CheckCode)                                 // +
-			authRouter.POST("/logout", middleware.RequireAuthMiddleware, r.authHandlers.Logout)                 // +
-			authRouter.DELETE("/deleteAccount", middleware.RequireAuthMiddleware, r.authHandlers.DeleteAccount) // +
-			authRouter.GET("/profile", middleware.RequireAuthMiddleware, r.authHandlers.Profile)                //+
+			authRouter.POST("/register", r.authHandlers.Register)                               //+
+			authRouter.POST("/login", r.authHandlers.Login)                                     // +
+			authRouter.POST("/forgotPassword", r.authHandlers.ForgotPassword)                   // +
+			authRouter.POST("/resetPassword", r.authHandlers.ResetPassword)                     // +
+			authRouter.POST("/checkVerificationCode", r.authHandlers.CheckCode)                 // +
+			authRouter.POST("/logout", r.requireUser, r.authHandlers.Logout)                    // +
+			authRouter.DELETE("/deleteAccount", r.requireUser, r.accountHandlers.DeleteAccount) // +
+			authRouter.POST("/restoreAccount", r.accountHandlers.RestoreAccount)
+			authRouter.GET("/export", r.requireUser, r.accountHandlers.ExportAccount)
+			authRouter.GET("/profile", r.requireUser, r.authHandlers.Profile) //+
+			authRouter.PATCH("/profile/username", r.requireUser, r.authHandlers.ChangeUsername)
+			authRouter.PATCH("/profile/password", r.requireUser, r.authHandlers.ChangePassword)
+			authRouter.PATCH("/profile/email", r.requireUser, r.authHandlers.RequestEmailChange)
+			authRouter.POST("/profile/email/confirm", r.requireUser, r.authHandlers.ConfirmEmailChange)
+			authRouter.GET("/profile/privacy", r.requireUser, r.playerHandlers.GetPrivacy)
+			authRouter.PATCH("/profile/privacy", r.requireUser, r.playerHandlers.UpdatePrivacy)
+			authRouter.PATCH("/profile/showcase", r.requireUser, r.playerHandlers.UpdateShowcase)
+			authRouter.POST("/2fa/enroll", r.requireUser, r.authHandlers.EnrollTwoFactor)
+			authRouter.POST("/2fa/confirm", r.requireUser, r.authHandlers.ConfirmTwoFactor)
+			authRouter.POST("/2fa/verify", r.authHandlers.VerifyTwoFactor)
+			authRouter.POST("/2fa/reset/:id", r.requireUser, middleware.RequireAdmin, r.authHandlers.ResetTwoFactor)
+			authRouter.GET("/oauth/:provider/login", r.authHandlers.OAuthLogin)
+			authRouter.GET("/oauth/:provider/callback", r.authHandlers.OAuthCallback)
+			authRouter.POST("/oauth/:provider/link", r.requireUser, r.authHandlers.OAuthLink)
+			authRouter.GET("/identities", r.requireUser, r.authHandlers.ListIdentities)
+			authRouter.DELETE("/identities/:id", r.requireUser, r.authHandlers.UnlinkIdentity)
+		}
+		playerRouter := appRouter.Group("/players")
+		{
+			playerRouter.GET("/:username", r.playerHandlers.GetPublicProfile)
+		}
+		friendRouter := appRouter.Group("/friends", r.requireUser)
+		{
+			friendRouter.GET("", r.friendHandlers.ListFriends)
+			friendRouter.DELETE("/:userID", r.friendHandlers.RemoveFriend)
+			friendRouter.GET("/search", r.friendHandlers.SearchPlayers)
+			friendRouter.GET("/requests", r.friendHandlers.ListFriendRequests)
+			friendRouter.POST("/requests", r.friendHandlers.SendFriendRequest)
+			friendRouter.POST("/requests/:id/accept", r.friendHandlers.AcceptFriendRequest)
+			friendRouter.POST("/requests/:id/decline", r.friendHandlers.DeclineFriendRequest)
+			friendRouter.GET("/blocks", r.friendHandlers.ListBlockedUsers)
+			friendRouter.POST("/blocks", r.friendHandlers.BlockUser)
+			friendRouter.DELETE("/blocks/:userID", r.friendHandlers.UnblockUser)
+		}
+		challengeRouter := appRouter.Group("/challenges", r.requireUser)
+		{
+			challengeRouter.GET("", r.matchHandlers.ListChallenges)
+			challengeRouter.POST("", r.matchHandlers.CreateChallenge)
+			challengeRouter.GET("/lobby/:code", r.matchHandlers.GetLobby)
+			challengeRouter.POST("/join", r.matchHandlers.JoinChallenge)
+			challengeRouter.POST("/:id/accept", r.matchHandlers.AcceptChallenge)
+			challengeRouter.POST("/:id/decline", r.matchHandlers.DeclineChallenge)
+		}
+		matchRouter := appRouter.Group("/matches", r.requireUser)
+		{
+			matchRouter.GET("", r.matchHandlers.ListMatches)
+			matchRouter.GET("/:id", r.matchHandlers.GetMatch)
+			matchRouter.POST("/:id/result", middleware.RequireAdmin, r.matchHandlers.ReportResult)
+			matchRouter.POST("/:id/replay", middleware.RequireAdmin, r.replayHandlers.Record)
+		}
+		replayRouter := appRouter.Group("/replays", r.requireUser)
+		{
+			replayRouter.GET("", r.replayHandlers.ListReplays)
+			replayRouter.GET("/:id", r.replayHandlers.GetReplay)
+			replayRouter.POST("/:id/verify", middleware.RequireAdmin, r.replayHandlers.Verify)
+		}
+		appRouter.POST("/practice", r.requireUser, r.botHandlers.StartPractice)
+		appRouter.POST("/bots/move", r.requireUser, middleware.RequireAdmin, r.botHandlers.Move)
+		partyRouter := appRouter.Group("/parties", r.requireUser)
+		{
+			partyRouter.GET("/mine", r.partyHandlers.GetMyParty)
+			partyRouter.POST("", r.partyHandlers.CreateParty)
+			partyRouter.POST("/invite", r.partyHandlers.InviteToParty)
+			partyRouter.POST("/leave", r.partyHandlers.LeaveParty)
+			partyRouter.POST("/:id/join", r.partyHandlers.JoinParty)
+		}
+		teamBattleRouter := appRouter.Group("/team-battles", r.requireUser)
+		{
+			teamBattleRouter.GET("/queue", r.partyHandlers.GetQueue)
+			teamBattleRouter.POST("/queue", r.partyHandlers.Queue)
+			teamBattleRouter.DELETE("/queue", r.partyHandlers.LeaveQueue)
+		}
+		draftRouter := appRouter.Group("/drafts", r.requireUser)
+		{
+			draftRouter.POST("/queue", r.draftHandlers.Queue)
+			draftRouter.DELETE("/queue", r.draftHandlers.LeaveQueue)
+			draftRouter.GET("/current", r.draftHandlers.GetCurrentDraft)
+			draftRouter.GET("/:id", r.draftHandlers.GetDraft)
+			draftRouter.POST("/:id/pick", r.draftHandlers.Pick)
+		}
+		clanRouter := appRouter.Group("/clans", r.requireUser)
+		{
+			clanRouter.GET("", r.clanHandlers.GetClans)
+			clanRouter.POST("", r.clanHandlers.CreateClan)
+			clanRouter.GET("/mine", r.clanHandlers.GetMyClan)
+			clanRouter.POST("/leave", r.clanHandlers.LeaveClan)
+			clanRouter.GET("/:id", r.clanHandlers.GetClan)
+			clanRouter.PATCH("/:id", r.clanHandlers.UpdateClan)
+			clanRouter.POST("/:id/join", r.clanHandlers.JoinClan)
+			clanRouter.GET("/:id/join-requests", r.clanHandlers.GetJoinRequests)
+			clanRouter.POST("/:id/join-requests/:requestID/approve", r.clanHandlers.ApproveJoinRequest)
+			clanRouter.POST("/:id/join-requests/:requestID/decline", r.clanHandlers.DeclineJoinRequest)
+			clanRouter.PATCH("/:id/members/:userID", r.clanHandlers.SetMemberRole)
+			clanRouter.DELETE("/:id/members/:userID", r.clanHandlers.KickMember)
+			clanRouter.GET("/:id/card-requests", r.clanHandlers.GetCardRequests)
+			clanRouter.POST("/:id/card-requests", r.clanHandlers.RequestCards)
+			clanRouter.POST("/:id/card-requests/:requestID/donate", r.clanHandlers.DonateCard)
+		}
+		chatRouter := appRouter.Group("/chat", r.requireUser)
+		{
+			chatRouter.GET("/clan", r.chatHandlers.GetClanHistory)
+			chatRouter.POST("/clan", r.chatHandlers.SendClanMessage)
+			chatRouter.GET("/direct/:userID", r.chatHandlers.GetDirectHistory)
+			chatRouter.POST("/direct/:userID", r.chatHandlers.SendDirectMessage)
+			chatRouter.POST("/messages/:id/report", r.chatHandlers.ReportMessage)
+		}
+		moderationRouter := appRouter.Group("/moderation", r.requireUser, middleware.RequireModerator)
+		{
+			moderationRouter.GET("/reports", r.moderationHandlers.ListReports)
+			moderationRouter.POST("/reports/:id/resolve", r.moderationHandlers.ResolveReport)
+			moderationRouter.POST("/messages/:id/hide", r.moderationHandlers.HideMessage)
+			moderationRouter.GET("/users/:userID/sanctions", r.moderationHandlers.ListSanctions)
+			moderationRouter.POST("/users/:userID/sanctions", r.moderationHandlers.SanctionUser)
+			moderationRouter.DELETE("/sanctions/:id", r.moderationHandlers.RevokeSanction)
+			moderationRouter.GET("/audit-log", r.moderationHandlers.GetAuditLog)
+		}
+		notificationRouter := appRouter.Group("/notifications", r.requireUser)
+		{
+			notificationRouter.GET("", r.notificationHandlers.ListNotifications)
+			notificationRouter.GET("/unread-count", r.notificationHandlers.UnreadCount)
+			notificationRouter.POST("/read-all", r.notificationHandlers.MarkAllRead)
+			notificationRouter.POST("/:id/read", r.notificationHandlers.MarkRead)
+			notificationRouter.GET("/settings", r.notificationHandlers.GetSettings)
+			notificationRouter.PATCH("/settings", r.notificationHandlers.UpdateSettings)
+			notificationRouter.POST("/broadcast", middleware.RequireAdmin, r.notificationHandlers.Broadcast)
+		}
+		questRouter := appRouter.Group("/quests", r.requireUser)
+		{
+			questRouter.GET("", r.questHandlers.ListQuests)
+			questRouter.POST("/:id/claim", r.questHandlers.ClaimQuest)
+			questRouter.GET("/definitions", middleware.RequireAdmin, r.questHandlers.ListDefinitions)
+			questRouter.POST("/definitions", middleware.RequireAdmin, r.questHandlers.CreateDefinition)
+			questRouter.PATCH("/definitions/:id", middleware.RequireAdmin, r.questHandlers.UpdateDefinition)
+		}
+		seasonRouter := appRouter.Group("/seasons", r.requireUser)
+		{
+			seasonRouter.GET("", r.seasonHandlers.ListSeasons)
+			seasonRouter.GET("/current", r.seasonHandlers.CurrentSeason)
+			seasonRouter.GET("/results", r.seasonHandlers.MyResults)
+			seasonRouter.POST("", middleware.RequireAdmin, r.seasonHandlers.CreateSeason)
+			seasonRouter.PATCH("/:id", middleware.RequireAdmin, r.seasonHandlers.UpdateSeason)
+		}
+		trophyRoadRouter := appRouter.Group("/trophy-road", r.requireUser)
+		{
+			trophyRoadRouter.GET("", r.seasonHandlers.GetTrophyRoad)
+			trophyRoadRouter.POST("/:id/claim", r.seasonHandlers.ClaimMilestone)
+		}
+		shopRouter := appRouter.Group("/shop", r.requireUser)
+		{
+			shopRouter.GET("", r.shopHandlers.GetShop)
+			shopRouter.POST("/daily/:id/buy", r.shopHandlers.BuyDailyOffer)
+			shopRouter.POST("/offers/:id/buy", r.shopHandlers.BuyOffer)
+			shopRouter.GET("/offers", middleware.RequireAdmin, r.shopHandlers.ListOffers)
+			shopRouter.POST("/offers", middleware.RequireAdmin, r.shopHandlers.CreateOffer)
+			shopRouter.PATCH("/offers/:id", middleware.RequireAdmin, r.shopHandlers.UpdateOffer)
+		}
+		walletRouter := appRouter.Group("/wallet", r.requireUser)
+		{
+			walletRouter.GET("", r.walletHandlers.GetWallet)
+			walletRouter.GET("/transactions", r.walletHandlers.ListTransactions)
+			walletRouter.POST("/exchange", r.walletHandlers.ExchangeGems)
+			walletRouter.POST("/grant", middleware.RequireAdmin, r.walletHandlers.Grant)
+		}
+		chestRouter := appRouter.Group("/chests", r.requireUser)
+		{
+			chestRouter.GET("", r.chestHandlers.ListChests)
+			chestRouter.POST("/buy", r.chestHandlers.BuyChest)
+			chestRouter.POST("/:id/unlock", r.chestHandlers.UnlockChest)
+			chestRouter.POST("/:id/open", r.chestHandlers.OpenChest)
+			chestRouter.POST("/:id/speed-up", r.chestHandlers.SpeedUpChest)
+		}
+		paymentRouter := appRouter.Group("/payments")
+		{
+			paymentRouter.GET("", r.requireUser, r.paymentHandlers.ListPurchases)
+			paymentRouter.POST("/verify", r.requireUser, r.paymentHandlers.VerifyPurchase)
+			paymentRouter.POST("/:provider/notifications", r.paymentHandlers.StoreNotification)
+		}
+		arenaRouter := appRouter.Group("/arenas", r.requireUser)
+		{
+			arenaRouter.GET("", r.arenaHandlers.ListArenas)
+			arenaRouter.POST("", middleware.RequireAdmin, r.arenaHandlers.CreateArena)
+			arenaRouter.PATCH("/:id", middleware.RequireAdmin, r.arenaHandlers.UpdateArena)
+		}
+		promoRouter := appRouter.Group("/promo-codes", r.requireUser)
+		{
+			promoRouter.POST("/redeem", r.promoHandlers.Redeem)
+			promoRouter.GET("", middleware.RequireAdmin, r.promoHandlers.ListCodes)
+			promoRouter.POST("", middleware.RequireAdmin, r.promoHandlers.CreateCodes)
+			promoRouter.PATCH("/:id", middleware.RequireAdmin, r.promoHandlers.UpdateCode)
+			promoRouter.GET("/:id/redemptions", middleware.RequireAdmin, r.promoHandlers.ListRedemptions)
+		}
+		tournamentRouter := appRouter.Group("/tournaments", r.requireUser)
+		{
+			tournamentRouter.GET("", r.tournamentHandlers.ListTournaments)
+			tournamentRouter.POST("", r.tournamentHandlers.CreateTournament)
+			tournamentRouter.GET("/:id", r.tournamentHandlers.GetTournament)
+			tournamentRouter.POST("/:id/register", r.tournamentHandlers.Register)
+			tournamentRouter.DELETE("/:id/register", r.tournamentHandlers.Withdraw)
+			tournamentRouter.POST("/:id/cancel", r.tournamentHandlers.CancelTournament)
+			tournamentRouter.GET("/:id/bracket", r.tournamentHandlers.GetBracket)
+			tournamentRouter.GET("/:id/standings", r.tournamentHandlers.GetStandings)
 		}
-		gameRouter := appRouter.Group("/game", middleware.RequireAuthMiddleware)
+		gameRouter := appRouter.Group("/game", r.requireUser)
 		{
 			gameRouter.POST("/add-hero-to-deck", r.gameHandlers.AddHeroToDeck)
 			gameRouter.POST("/delete-hero-to-deсk", r.gameHandlers.DeleteHeroToDeсk)
 			gameRouter.GET("/get-my-heros", r.gameHandlers.GetMyHeros) //+-
 			gameRouter.POST("/add-spell-to-deck", r.gameHandlers.AddSpellToDeck)
 			gameRouter.POST("/delete-spell-to-deсk", r.gameHandlers.DeleteSpellToDeсk)
-			gameRouter.GET("/get-my-spells", r.gameHandlers.GetMySpell)   // +
-			gameRouter.POST("/hero/:id", r.gameHandlers.BuyHero)          // +
-			gameRouter.POST("/spell/:id", r.gameHandlers.BuySpell)        //+
-			gameRouter.POST("/create-hero", r.gameHandlers.CreateHero)    // +
-			gameRouter.POST("/create-spell", r.gameHandlers.CreateSpell)  // +
-			gameRouter.GET("/get-all-heros", r.gameHandlers.GetAllHeros)  // +
-			gameRouter.GET("/get-all-spells", r.gameHandlers.GetAllSpell) // +
+			gameRouter.GET("/get-my-spells", r.gameHandlers.GetMySpell)                           // +
+			gameRouter.POST("/hero/:id", r.gameHandlers.BuyHero)                                  // +
+			gameRouter.POST("/spell/:id", r.gameHandlers.BuySpell)                                //+
+			gameRouter.POST("/create-hero", middleware.RequireAdmin, r.gameHandlers.CreateHero)   // +
+			gameRouter.POST("/create-spell", middleware.RequireAdmin, r.gameHandlers.CreateSpell) // +
+			gameRouter.GET("/get-all-heros", r.gameHandlers.GetAllHeros)                          // +
+			gameRouter.GET("/get-all-spells", r.gameHandlers.GetAllSpell)                         // +
 			gameRouter.GET("/get-my-deсk/:id", r.gameHandlers.GetMyDeck)
 			gameRouter.GET("/hero/:id", r.gameHandlers.GetHero)   // +
 			gameRouter.GET("/spell/:id", r.gameHandlers.GetSpell) //+
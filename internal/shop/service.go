@@ -0,0 +1,88 @@
+package shop
+
+import (
+	"auth/internal/config"
+	"auth/internal/notifications"
+	"auth/internal/quests"
+	"auth/internal/repository"
+	"auth/internal/rest/models"
+	"auth/pkg/utils"
+	"context"
+	"time"
+)
+
+// Service runs the shop: it draws each player's daily offers, sells them and
+// the limited bundles, and reports purchases to notifications and quests.
+// Gold is always taken through the repository's shared balance debit, the
+// same one the catalog store uses.
+type Service struct {
+	Repo     repository.ShopRepo
+	Config   config.ShopConfig
+	Clock    utils.Clock
+	Notifier notifications.Notifier
+	Quests   quests.Recorder
+}
+
+func NewService(repo repository.ShopRepo, shopConfig config.ShopConfig, clock utils.Clock, notifier notifications.Notifier, recorder quests.Recorder) *Service {
+	return &Service{Repo: repo, Config: shopConfig, Clock: clock, Notifier: notifier, Quests: recorder}
+}
+
+// DailyOffers returns the player's offers for today and when they rotate.
+func (s *Service) DailyOffers(userID uint) ([]models.DailyOffer, time.Time, error) {
+	now := s.Clock.Now()
+	offers, err := s.Repo.DrawDailyOffers(userID, utils.DailyPeriod(now, s.Config.ResetOffset), s.Config.DailyOffers, s.Config.DailyDiscount)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return offers, utils.NextReset(now, s.Config.ResetOffset), nil
+}
+
+// Offers lists the bundles on sale now.
+func (s *Service) Offers() ([]models.ShopOffer, error) {
+	now := s.Clock.Now()
+	return s.Repo.GetOffers(&now)
+}
+
+func (s *Service) BuyDailyOffer(ctx context.Context, userID, offerID uint) (models.DailyOffer, error) {
+	now := s.Clock.Now()
+	offer, err := s.Repo.BuyDailyOffer(userID, offerID, utils.DailyPeriod(now, s.Config.ResetOffset), now)
+	if err != nil {
+		return models.DailyOffer{}, err
+	}
+
+	s.Notifier.Notify(ctx, models.Notification{
+		UserID: userID,
+		Kind:   models.NotificationPurchase,
+		Title:  "You bought " + offer.Name,
+		Data:   map[string]interface{}{"dailyOfferId": offer.ID, "price": offer.Price},
+	})
+	s.recordItem(ctx, userID, models.ShopOfferItem{HeroID: offer.HeroID, SpellID: offer.SpellID, Rarity: offer.Rarity})
+	return offer, nil
+}
+
+func (s *Service) BuyOffer(ctx context.Context, userID, offerID uint) (models.ShopOffer, error) {
+	offer, err := s.Repo.BuyOffer(userID, offerID, s.Clock.Now())
+	if err != nil {
+		return models.ShopOffer{}, err
+	}
+
+	s.Notifier.Notify(ctx, models.Notification{
+		UserID: userID,
+		Kind:   models.NotificationPurchase,
+		Title:  "You bought " + offer.Title,
+		Data:   map[string]interface{}{"offerId": offer.ID, "price": offer.Price},
+	})
+	for _, item := range offer.Items {
+		s.recordItem(ctx, userID, item)
+	}
+	return offer, nil
+}
+
+func (s *Service) recordItem(ctx context.Context, userID uint, item models.ShopOfferItem) {
+	if item.HeroID.Valid {
+		s.Quests.Record(ctx, models.QuestEvent{UserID: userID, Type: models.EventHeroBought, Rarity: item.Rarity})
+	}
+	if item.SpellID.Valid {
+		s.Quests.Record(ctx, models.QuestEvent{UserID: userID, Type: models.EventSpellBought})
+	}
+}
@@ -0,0 +1,149 @@
+// Package tournaments pairs tournament rounds. It only decides who plays whom;
+// the repository stores the pairings and starts their matches.
+package tournaments
+
+import "sort"
+
+// Pairing is one match of a round. Player2 is 0 for a bye, which Player1 wins
+// without playing.
+type Pairing struct {
+	Position int32
+	Player1  uint
+	Player2  uint
+}
+
+// Standing is a player's place in a Swiss tournament going into a round.
+type Standing struct {
+	UserID uint
+	Points int32
+	Seed   int32
+	HadBye bool
+}
+
+// Rounds is how many rounds a tournament of players needs to find a winner:
+// the rounds of a single elimination bracket, and the default for Swiss.
+func Rounds(players int) int32 {
+	var rounds int32
+	for size := 1; size < players; size *= 2 {
+		rounds++
+	}
+	return rounds
+}
+
+// bracketOrder returns the seeds of a bracket of size in the order they are
+// paired, so that the top seeds can only meet in the late rounds: for eight,
+// 1 v 8, 4 v 5, 2 v 7 and 3 v 6.
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// SeedBracket pairs the first round of a single elimination bracket. seeded
+// lists the players from the first seed down. The bracket is filled up to a
+// power of two with byes, which go to the top seeds.
+func SeedBracket(seeded []uint) []Pairing {
+	order := bracketOrder(1 << uint(Rounds(len(seeded))))
+	pairings := make([]Pairing, 0, len(order)/2)
+	for i := 0; i < len(order); i += 2 {
+		pairing := Pairing{Position: int32(i / 2), Player1: seeded[order[i]-1]}
+		if order[i+1] <= len(seeded) {
+			pairing.Player2 = seeded[order[i+1]-1]
+		}
+		pairings = append(pairings, pairing)
+	}
+	return pairings
+}
+
+// NextBracketRound pairs the winners of a bracket round: the winners of
+// positions 2k and 2k+1 meet at position k. winners maps each position of the
+// previous round to its winner, and positions is how many the round had. A
+// position without a winner, because both players left, gives its opponent a
+// bye.
+func NextBracketRound(winners map[int32]uint, positions int32) []Pairing {
+	var pairings []Pairing
+	for position := int32(0); position < (positions+1)/2; position++ {
+		pairing := Pairing{Position: position, Player1: winners[2*position], Player2: winners[2*position+1]}
+		if pairing.Player1 == 0 {
+			pairing.Player1, pairing.Player2 = pairing.Player2, 0
+		}
+		if pairing.Player1 != 0 {
+			pairings = append(pairings, pairing)
+		}
+	}
+	return pairings
+}
+
+// PlayedKey is the key of a pair of players in PairSwiss's played set, the
+// same whichever of them is given first.
+func PlayedKey(a, b uint) [2]uint {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]uint{a, b}
+}
+
+// PairSwiss pairs a Swiss round. Players are ranked by points, then seed, and
+// each is paired with the next ranked player they haven't played yet, falling
+// back to a rematch when there is none. With an odd number of players the
+// lowest ranked one without a bye so far sits the round out. played holds
+// the pairs that already met.
+func PairSwiss(standings []Standing, played map[[2]uint]bool) []Pairing {
+	ranked := make([]Standing, len(standings))
+	copy(ranked, standings)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Points != ranked[j].Points {
+			return ranked[i].Points > ranked[j].Points
+		}
+		return ranked[i].Seed < ranked[j].Seed
+	})
+
+	var bye uint
+	if len(ranked)%2 == 1 {
+		byeAt := len(ranked) - 1
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if !ranked[i].HadBye {
+				byeAt = i
+				break
+			}
+		}
+		bye = ranked[byeAt].UserID
+		ranked = append(ranked[:byeAt], ranked[byeAt+1:]...)
+	}
+
+	var pairings []Pairing
+	paired := make([]bool, len(ranked))
+	for i := range ranked {
+		if paired[i] {
+			continue
+		}
+		opponent := -1
+		for j := i + 1; j < len(ranked); j++ {
+			if paired[j] {
+				continue
+			}
+			if opponent == -1 {
+				opponent = j
+			}
+			if !played[PlayedKey(ranked[i].UserID, ranked[j].UserID)] {
+				opponent = j
+				break
+			}
+		}
+		if opponent == -1 {
+			continue
+		}
+		paired[i], paired[opponent] = true, true
+		pairings = append(pairings, Pairing{Position: int32(len(pairings)), Player1: ranked[i].UserID, Player2: ranked[opponent].UserID})
+	}
+	if bye != 0 {
+		pairings = append(pairings, Pairing{Position: int32(len(pairings)), Player1: bye})
+	}
+	return pairings
+}
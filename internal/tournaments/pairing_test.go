@@ -0,0 +1,136 @@
+package tournaments
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRounds(t *testing.T) {
+	for players, want := range map[int]int32{0: 0, 1: 0, 2: 1, 3: 2, 4: 2, 5: 3, 8: 3, 9: 4, 64: 6} {
+		if got := Rounds(players); got != want {
+			t.Errorf("Rounds(%d) = %d, want %d", players, got, want)
+		}
+	}
+}
+
+func TestSeedBracket(t *testing.T) {
+	tests := []struct {
+		name   string
+		seeded []uint
+		want   []Pairing
+	}{
+		{"two players", []uint{1, 2}, []Pairing{{0, 1, 2}}},
+		{"top seed gets the bye", []uint{1, 2, 3}, []Pairing{{0, 1, 0}, {1, 2, 3}}},
+		{"full bracket", []uint{10, 20, 30, 40}, []Pairing{{0, 10, 40}, {1, 20, 30}}},
+		{"byes go to the top three seeds", []uint{11, 12, 13, 14, 15}, []Pairing{{0, 11, 0}, {1, 14, 15}, {2, 12, 0}, {3, 13, 0}}},
+	}
+	for _, tt := range tests {
+		if got := SeedBracket(tt.seeded); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: SeedBracket = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNextBracketRound(t *testing.T) {
+	tests := []struct {
+		name      string
+		winners   map[int32]uint
+		positions int32
+		want      []Pairing
+	}{
+		{"winners meet", map[int32]uint{0: 1, 1: 4, 2: 2, 3: 3}, 4, []Pairing{{0, 1, 4}, {1, 2, 3}}},
+		{"final", map[int32]uint{0: 1, 1: 2}, 2, []Pairing{{0, 1, 2}}},
+		{"second position has no winner", map[int32]uint{0: 1, 2: 2, 3: 3}, 4, []Pairing{{0, 1, 0}, {1, 2, 3}}},
+		{"first position has no winner", map[int32]uint{1: 4, 2: 2, 3: 3}, 4, []Pairing{{0, 4, 0}, {1, 2, 3}}},
+		{"neither position has a winner", map[int32]uint{2: 2, 3: 3}, 4, []Pairing{{1, 2, 3}}},
+		{"odd number of positions", map[int32]uint{0: 1, 1: 2, 2: 3}, 3, []Pairing{{0, 1, 2}, {1, 3, 0}}},
+	}
+	for _, tt := range tests {
+		if got := NextBracketRound(tt.winners, tt.positions); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: NextBracketRound = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// With the better seed winning every match, a bracket plays out in Rounds
+// rounds and the top two seeds meet in the final.
+func TestBracketAdvancesToFinal(t *testing.T) {
+	seeded := []uint{1, 2, 3, 4, 5, 6}
+	pairings := SeedBracket(seeded)
+	positions := int32(len(pairings))
+	rounds := int32(1)
+	for len(pairings) > 1 {
+		winners := make(map[int32]uint)
+		for _, pairing := range pairings {
+			winner := pairing.Player1
+			if pairing.Player2 != 0 && pairing.Player2 < winner {
+				winner = pairing.Player2
+			}
+			winners[pairing.Position] = winner
+		}
+		pairings = NextBracketRound(winners, positions)
+		positions = (positions + 1) / 2
+		rounds++
+	}
+
+	if want := []Pairing{{0, 1, 2}}; !reflect.DeepEqual(pairings, want) {
+		t.Errorf("final is %v, want %v", pairings, want)
+	}
+	if rounds != Rounds(len(seeded)) {
+		t.Errorf("bracket took %d rounds, want %d", rounds, Rounds(len(seeded)))
+	}
+}
+
+func TestPairSwiss(t *testing.T) {
+	field := func(players int, hadBye ...uint) []Standing {
+		byes := make(map[uint]bool)
+		for _, id := range hadBye {
+			byes[id] = true
+		}
+		standings := make([]Standing, players)
+		for i := range standings {
+			id := uint(i + 1)
+			standings[i] = Standing{UserID: id, Seed: int32(id), HadBye: byes[id]}
+		}
+		return standings
+	}
+	played := func(pairs ...[2]uint) map[[2]uint]bool {
+		set := make(map[[2]uint]bool)
+		for _, pair := range pairs {
+			set[PlayedKey(pair[0], pair[1])] = true
+		}
+		return set
+	}
+
+	tests := []struct {
+		name      string
+		standings []Standing
+		played    map[[2]uint]bool
+		want      []Pairing
+	}{
+		{"no players", nil, nil, nil},
+		{"lone player gets a bye", field(1), nil, []Pairing{{0, 1, 0}}},
+		{"ranked by points then seed", []Standing{
+			{UserID: 1, Points: 0, Seed: 1},
+			{UserID: 2, Points: 3, Seed: 2},
+			{UserID: 3, Points: 3, Seed: 1},
+			{UserID: 4, Points: 1, Seed: 3},
+		}, nil, []Pairing{{0, 3, 2}, {1, 4, 1}}},
+		{"odd count byes the lowest ranked", field(5), nil, []Pairing{{0, 1, 2}, {1, 3, 4}, {2, 5, 0}}},
+		{"no second bye", field(5, 5), nil, []Pairing{{0, 1, 2}, {1, 3, 5}, {2, 4, 0}}},
+		{"no second bye for anyone low", field(5, 4, 5), nil, []Pairing{{0, 1, 2}, {1, 4, 5}, {2, 3, 0}}},
+		{"everyone had a bye", field(3, 1, 2, 3), nil, []Pairing{{0, 1, 2}, {1, 3, 0}}},
+		{"avoids a rematch", field(4), played([2]uint{2, 1}), []Pairing{{0, 1, 3}, {1, 2, 4}}},
+		{"rematch when there is no one else", field(2), played([2]uint{1, 2}), []Pairing{{0, 1, 2}}},
+		{"rematch with the next ranked when forced", field(4), played([2]uint{1, 2}, [2]uint{1, 3}, [2]uint{1, 4}), []Pairing{{0, 1, 2}, {1, 3, 4}}},
+	}
+	for _, tt := range tests {
+		standings := append([]Standing(nil), tt.standings...)
+		if got := PairSwiss(tt.standings, tt.played); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: PairSwiss = %v, want %v", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(standings, tt.standings) {
+			t.Errorf("%s: PairSwiss reordered its input", tt.name)
+		}
+	}
+}
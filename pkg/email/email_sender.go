@@ -4,6 +4,7 @@ import (
 	"auth/internal/config"
 	"fmt"
 	"net/smtp"
+	"strings"
 )
 
 func SendVerificationCodeEmail(email, verificationCode string, config config.EmailConfig) error {
@@ -17,3 +18,20 @@ func SendVerificationCodeEmail(email, verificationCode string, config config.Ema
 	}
 	return nil
 }
+
+// SendNotificationDigestEmail lists the titles of a player's unread
+// notifications, newest first.
+func SendNotificationDigestEmail(email, username string, titles []string, config config.EmailConfig) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nHere is what you missed:\n\n", username)
+	for _, title := range titles {
+		fmt.Fprintf(&body, "- %s\n", title)
+	}
+	body.WriteString("\nYou can turn these emails off in your notification settings.")
+
+	msg := fmt.Sprintf("From: %s\nTo: %s\nSubject: Your notifications\n\n%s", config.From, email, body.String())
+
+	auth := smtp.PlainAuth("", config.From, config.Password, config.SMTPHost)
+
+	return smtp.SendMail(config.SMTPHost+":"+config.SMTPPort, auth, config.From, []string{email}, []byte(msg))
+}
@@ -9,51 +9,46 @@ import (
 )
 
 func RequireAuthMiddleware(c *gin.Context) {
-	logger := logger.GetLogger()
-
-	var token string
-	authHeader := c.GetHeader("Authorization")
-	cookie, err := c.Cookie("jwt")
-	if err != nil {
-		logger.Error("JWT token not found")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "JWT token not found"})
-		c.AbortWithStatus(http.StatusUnauthorized)
+	if !authenticate(c) {
 		return
 	}
+	c.Next()
+}
 
-	fields := strings.Fields(authHeader)
-	if len(fields) != 0 && fields[0] == "Bearer" {
+// authenticate verifies the JWT from the Authorization header or the jwt
+// cookie and stores its claims in the context. It aborts the request and
+// returns false when the token is missing or invalid.
+func authenticate(c *gin.Context) bool {
+	logger := logger.GetLogger()
+
+	var token string
+	fields := strings.Fields(c.GetHeader("Authorization"))
+	if len(fields) == 2 && fields[0] == "Bearer" {
 		token = fields[1]
-	} else if err == nil {
+	} else if cookie, err := c.Cookie("jwt"); err == nil {
 		token = cookie
 	}
 
-	if authHeader == "" {
-		logger.Error("Authorization header not found")
-		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "Try to sign in first"})
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
-
 	if token == "" {
-		logger.Error("Invalid token")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+		logger.Error("JWT token not found")
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "Try to sign in first"})
+		c.Abort()
+		return false
 	}
 
-	id, email, userType, err := utils.VerifyToken(token)
+	claims, err := utils.VerifyToken(token)
 	if err != nil {
 		logger.Error("Token verification failed:", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token verification failed"})
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+		c.Abort()
+		return false
 	}
 
-	c.Set("id", id)
-	c.Set("email", email)
-	c.Set("userType", userType)
+	c.Set("id", claims.Id)
+	c.Set("email", claims.Email)
+	c.Set("userType", claims.UserType)
+	c.Set("tokenVersion", claims.Version)
 
 	logger.Info("Authentication successful")
-	c.Next()
+	return true
 }
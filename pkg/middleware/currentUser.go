@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"auth/internal/rest/models"
+	"auth/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+const principalKey = "principal"
+
+// Principal is who a request is authenticated as. It carries only what
+// authorization needs; handlers that need more load the user themselves.
+type Principal struct {
+	ID       uint
+	Username string
+	Role     string
+}
+
+func NewPrincipal(user *models.User) *Principal {
+	return &Principal{ID: user.ID, Username: user.Username, Role: user.UserType}
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p.Role == "ADMIN"
+}
+
+// IsModerator reports whether the principal may moderate. Admins count as
+// moderators.
+func (p *Principal) IsModerator() bool {
+	return p.Role == "MODERATOR" || p.IsAdmin()
+}
+
+// UserLoader is the part of the user repository the middleware needs.
+type UserLoader interface {
+	GetUserByID(id uint) (*models.User, error)
+}
+
+// RequireUser authenticates the request, checks that the user named by the
+// token's id claim still exists and that the token wasn't revoked, and stores
+// their Principal, so handlers never look users up by email themselves.
+func RequireUser(users UserLoader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authenticate(c) {
+			return
+		}
+
+		id, err := strconv.ParseUint(c.GetString("id"), 10, 64)
+		if err != nil {
+			logger.GetLogger().Error("Invalid user ID in token:", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token verification failed"})
+			c.Abort()
+			return
+		}
+
+		user, err := users.GetUserByID(uint(id))
+		if err != nil {
+			logger.GetLogger().Error("User from token not found:", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		if version, _ := c.Get("tokenVersion"); version != user.TokenVersion {
+			logger.GetLogger().Warn("Revoked token for user:", user.ID)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token was revoked, sign in again"})
+			c.Abort()
+			return
+		}
+
+		if user.DeletedAt.Valid {
+			logger.GetLogger().Warn("Request from account scheduled for deletion:", user.ID)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is scheduled for deletion"})
+			c.Abort()
+			return
+		}
+
+		c.Set(principalKey, NewPrincipal(user))
+		c.Next()
+	}
+}
+
+// RequireAdmin must run after RequireUser.
+func RequireAdmin(c *gin.Context) {
+	principal, ok := CurrentPrincipal(c)
+	if !ok || !principal.IsAdmin() {
+		logger.GetLogger().Warn("Not enough rights to act")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not enough rights to act"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// RequireModerator must run after RequireUser. Admins count as moderators.
+func RequireModerator(c *gin.Context) {
+	principal, ok := CurrentPrincipal(c)
+	if !ok || !principal.IsModerator() {
+		logger.GetLogger().Warn("Not enough rights to moderate")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not enough rights to act"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func CurrentPrincipal(c *gin.Context) (*Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
+	}
+	principal, ok := value.(*Principal)
+	return principal, ok && principal != nil
+}
+
+func CurrentUserID(c *gin.Context) (uint, bool) {
+	principal, ok := CurrentPrincipal(c)
+	if !ok {
+		return 0, false
+	}
+	return principal.ID, true
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"auth/internal/config"
+	redis "auth/internal/db/redis"
+	"auth/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// TrackPresence marks the signed-in user as online once the request has been
+// handled. It is installed on the engine, so it only sees a current user on
+// routes that use RequireUser.
+func TrackPresence(rdbConfig config.RedisConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		userID, ok := CurrentUserID(c)
+		if !ok {
+			return
+		}
+		if err := redis.TouchPresence(c, rdbConfig, userID); err != nil {
+			logger.GetLogger().Warn("Failed to update presence:", err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package oauthtest runs a stand-in OpenID Connect provider, so external
+// logins can be tested offline. It implements the authorization code flow
+// with PKCE: authorize signs in whoever SignIn last set, the token endpoint
+// only redeems a code with the verifier matching its challenge, and userinfo
+// describes the signed-in user.
+package oauthtest
+
+import (
+	"auth/internal/config"
+	"auth/pkg/oauth"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	ClientID     = "test-client"
+	ClientSecret = "test-secret"
+)
+
+type grant struct {
+	identity      oauth.Identity
+	codeChallenge string
+	redirectURI   string
+}
+
+type Server struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	identity *oauth.Identity
+	codes    map[string]grant
+	tokens   map[string]oauth.Identity
+}
+
+// Start serves the provider until the test ends.
+func Start(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{codes: make(map[string]grant), tokens: make(map[string]oauth.Identity)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/userinfo", s.userInfo)
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// Config returns the provider config that points at the server.
+func (s *Server) Config(name, redirectURL string) config.OIDCProvider {
+	return config.OIDCProvider{
+		Name:         name,
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		AuthURL:      s.server.URL + "/authorize",
+		TokenURL:     s.server.URL + "/token",
+		UserInfoURL:  s.server.URL + "/userinfo",
+		RedirectURL:  redirectURL,
+	}
+}
+
+// SignIn sets who the next authorization is for.
+func (s *Server) SignIn(identity oauth.Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identity = &identity
+}
+
+// Authorize follows an authorization URL the way a browser would and
+// returns the redirect back to the app, carrying the code and state.
+func (s *Server) Authorize(t testing.TB, authURL string) *url.URL {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize answered %s", resp.Status)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize redirected to an invalid URL: %v", err)
+	}
+	return location
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") != ClientID ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.identity == nil {
+		http.Error(w, "nobody is signed in", http.StatusBadRequest)
+		return
+	}
+	code := randomString()
+	s.codes[code] = grant{identity: *s.identity, codeChallenge: query.Get("code_challenge"), redirectURI: query.Get("redirect_uri")}
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != ClientID || r.PostForm.Get("client_secret") != ClientSecret {
+		http.Error(w, "invalid token request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := r.PostForm.Get("code")
+	grant, ok := s.codes[code]
+	// Codes are single use, whether or not the exchange succeeds.
+	delete(s.codes, code)
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || grant.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != grant.codeChallenge {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	accessToken := randomString()
+	s.tokens[accessToken] = grant.identity
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"access_token": accessToken, "token_type": "Bearer"})
+}
+
+func (s *Server) userInfo(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	identity, ok := s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sub":            identity.Subject,
+		"email":          identity.Email,
+		"email_verified": identity.EmailVerified,
+		"name":           identity.Name,
+	})
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
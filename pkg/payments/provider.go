@@ -0,0 +1,43 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidReceipt      = errors.New("invalid store receipt")
+	ErrInvalidNotification = errors.New("invalid store notification")
+)
+
+// Events a store notifies us about after a purchase.
+const (
+	EventRefund     = "REFUND"
+	EventChargeback = "CHARGEBACK"
+)
+
+// Purchase is what a store confirms about a receipt. TransactionID is unique
+// within the store, so it is what a purchase is credited against.
+type Purchase struct {
+	TransactionID string
+	ProductID     string
+	PurchasedAt   time.Time
+}
+
+// Event is a store's server notification about an earlier purchase.
+type Event struct {
+	Type          string
+	TransactionID string
+}
+
+// Provider is one app store. VerifyReceipt returns ErrInvalidReceipt for
+// receipts the store rejects and any other error when the store couldn't be
+// asked; ParseNotification returns ErrInvalidNotification for notifications
+// that don't come from the store.
+type Provider interface {
+	Name() string
+	VerifyReceipt(ctx context.Context, receipt string) (*Purchase, error)
+	ParseNotification(ctx context.Context, header http.Header, body []byte) (*Event, error)
+}
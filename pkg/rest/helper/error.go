@@ -3,7 +3,106 @@ package helper
 import "errors"
 
 var (
+	ErrUsernameTaken     = errors.New("username already taken")
+	ErrEmailTaken        = errors.New("email already registered")
+	ErrTwoFactorNotFound = errors.New("two-factor authentication not set up")
+	ErrIdentityNotFound  = errors.New("identity not found")
+	ErrEmailNotVerified  = errors.New("identity provider has not verified the email")
+
 	ErrHeroNotFound  = errors.New("hero not found")
 	ErrSpellNotFound = errors.New("spell not found")
 	ErrDeckNotFound  = errors.New("deck not found")
+
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrInsufficientGems    = errors.New("insufficient gems")
+
+	ErrFriendshipNotFound = errors.New("friendship not found")
+	ErrFriendshipExists   = errors.New("friend request already exists")
+
+	ErrMatchNotFound        = errors.New("match not found")
+	ErrChallengeNotFound    = errors.New("challenge not found")
+	ErrChallengeUnavailable = errors.New("challenge is no longer pending")
+	ErrJoinCodeTaken        = errors.New("join code already in use")
+	ErrMatchFinished        = errors.New("match already finished")
+
+	ErrClanNotFound         = errors.New("clan not found")
+	ErrClanNameTaken        = errors.New("clan name already taken")
+	ErrClanFull             = errors.New("clan is full")
+	ErrNotInClan            = errors.New("user is not in a clan")
+	ErrAlreadyInClan        = errors.New("user is already in a clan")
+	ErrJoinRequestNotFound  = errors.New("join request not found")
+	ErrCardRequestNotFound  = errors.New("card request not found")
+	ErrCardRequestFilled    = errors.New("card request already filled")
+	ErrCardRequestCancelled = errors.New("card request was cancelled")
+	ErrNoSpareCard          = errors.New("no spare copy of this card")
+
+	ErrMessageNotFound  = errors.New("chat message not found")
+	ErrAlreadyReported  = errors.New("message already reported by this user")
+	ErrReportNotFound   = errors.New("chat report not found")
+	ErrReportResolved   = errors.New("chat report already resolved")
+	ErrSanctionNotFound = errors.New("chat sanction not found")
+
+	ErrNotificationNotFound = errors.New("notification not found")
+
+	ErrQuestNotFound     = errors.New("quest not found")
+	ErrQuestCodeTaken    = errors.New("quest code already in use")
+	ErrQuestNotCompleted = errors.New("quest not completed")
+	ErrQuestClaimed      = errors.New("quest reward already claimed")
+
+	ErrSeasonNotFound    = errors.New("season not found")
+	ErrSeasonOverlap     = errors.New("season overlaps another season")
+	ErrSeasonClosed      = errors.New("season already closed")
+	ErrSeasonNotEnded    = errors.New("season has not ended")
+	ErrMilestoneNotFound = errors.New("trophy road milestone not found")
+	ErrMilestoneLocked   = errors.New("trophy road milestone not reached")
+	ErrMilestoneClaimed  = errors.New("trophy road reward already claimed")
+
+	ErrOfferNotFound     = errors.New("shop offer not found")
+	ErrOfferExpired      = errors.New("shop offer has expired")
+	ErrOfferSoldOut      = errors.New("shop offer is sold out")
+	ErrOfferLimitReached = errors.New("shop offer purchase limit reached")
+
+	ErrPurchaseNotFound = errors.New("store purchase not found")
+	ErrReceiptClaimed   = errors.New("receipt already redeemed by another user")
+
+	ErrPromoCodeNotFound     = errors.New("promo code not found")
+	ErrPromoCodeTaken        = errors.New("promo code already exists")
+	ErrPromoCodeExpired      = errors.New("promo code has expired")
+	ErrPromoCodeUsedUp       = errors.New("promo code has no uses left")
+	ErrPromoCodeLimitReached = errors.New("promo code already redeemed by this user")
+
+	ErrArenaNotFound = errors.New("arena not found")
+	ErrArenaTaken    = errors.New("arena name or trophy threshold already in use")
+	ErrCardInArena   = errors.New("card already belongs to an arena")
+	ErrCardLocked    = errors.New("card is locked in a higher arena")
+
+	ErrTournamentNotFound = errors.New("tournament not found")
+	ErrRegistrationClosed = errors.New("tournament registration is closed")
+	ErrTournamentFull     = errors.New("tournament is full")
+	ErrAlreadyRegistered  = errors.New("already registered for this tournament")
+	ErrNotRegistered      = errors.New("not registered for this tournament")
+	ErrTournamentStarted  = errors.New("tournament has already started")
+
+	ErrPartyNotFound  = errors.New("party not found")
+	ErrAlreadyInParty = errors.New("already in a party")
+	ErrPartyFull      = errors.New("party is full")
+	ErrPartyNotFull   = errors.New("party has no member yet")
+	ErrNotInvited     = errors.New("not invited to this party")
+	ErrAlreadyQueued  = errors.New("already queued for a match")
+	ErrNotQueued      = errors.New("not queued for a match")
+
+	ErrDraftNotFound     = errors.New("draft not found")
+	ErrAlreadyDrafting   = errors.New("already in a draft")
+	ErrNotYourPick       = errors.New("not your turn to pick")
+	ErrCardNotOffered    = errors.New("card is not offered")
+	ErrPickTimedOut      = errors.New("pick time is up")
+	ErrDraftPoolTooSmall = errors.New("not enough cards in the catalog for a draft")
+
+	ErrReplayNotFound = errors.New("replay not found")
+	ErrReplayExists   = errors.New("match already has a replay")
+
+	ErrChestNotFound  = errors.New("chest not found")
+	ErrChestLocked    = errors.New("chest is still locked")
+	ErrChestUnlocking = errors.New("another chest is already unlocking")
+	ErrChestOpened    = errors.New("chest already opened")
 )
@@ -0,0 +1,16 @@
+package utils
+
+import "time"
+
+// DailyPeriod names the game day now falls in, as YYYY-MM-DD. Days start
+// resetOffset after midnight UTC.
+func DailyPeriod(now time.Time, resetOffset time.Duration) string {
+	return now.UTC().Add(-resetOffset).Format("2006-01-02")
+}
+
+// NextReset is when the game day after now begins.
+func NextReset(now time.Time, resetOffset time.Duration) time.Time {
+	shifted := now.UTC().Add(-resetOffset)
+	midnight := time.Date(shifted.Year(), shifted.Month(), shifted.Day(), 0, 0, 0, 0, time.UTC)
+	return midnight.AddDate(0, 0, 1).Add(resetOffset)
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ProfanityFilter masks whole-word, case-insensitive matches of a word list.
+// Words are delimited by anything but letters, digits and '_' in any script,
+// since \b only knows ASCII word characters.
+type ProfanityFilter struct {
+	pattern *regexp.Regexp
+}
+
+func NewProfanityFilter(words []string) *ProfanityFilter {
+	var quoted []string
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return &ProfanityFilter{}
+	}
+	return &ProfanityFilter{pattern: regexp.MustCompile(`(?i)(^|[^\p{L}\p{N}_])(` + strings.Join(quoted, "|") + `)($|[^\p{L}\p{N}_])`)}
+}
+
+// Clean replaces every banned word with asterisks of the same length.
+func (f *ProfanityFilter) Clean(text string) string {
+	if f == nil || f.pattern == nil {
+		return text
+	}
+	// A match takes the character after the word with it, so a banned word
+	// right after another one is only found by the next pass.
+	for {
+		cleaned := f.mask(text)
+		if cleaned == text {
+			return text
+		}
+		text = cleaned
+	}
+}
+
+// mask replaces the words matched in one pass, keeping the characters around
+// them.
+func (f *ProfanityFilter) mask(text string) string {
+	var cleaned strings.Builder
+	last := 0
+	for _, match := range f.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[4], match[5]
+		cleaned.WriteString(text[last:start])
+		cleaned.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[start:end])))
+		last = end
+	}
+	cleaned.WriteString(text[last:])
+	return cleaned.String()
+}
@@ -0,0 +1,39 @@
+package utils
+
+import "testing"
+
+func TestProfanityFilterClean(t *testing.T) {
+	filter := NewProfanityFilter([]string{"bad", " дурак ", "", "a.b"})
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"ты дурак, bad", "ты *****, ***"},
+		{"ДУРАК!", "*****!"},
+		{"дураки", "дураки"},
+		{"bad bad bad bad", "*** *** *** ***"},
+		{"(bad)", "(***)"},
+		{"Bad.", "***."},
+		{"badge", "badge"},
+		{"_bad", "_bad"},
+		{"bad1", "bad1"},
+		{"ébad", "ébad"},
+		{"a.b axb", "*** axb"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := filter.Clean(tt.text); got != tt.want {
+			t.Errorf("Clean(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestProfanityFilterWithoutWords(t *testing.T) {
+	var unset *ProfanityFilter
+	for _, filter := range []*ProfanityFilter{NewProfanityFilter(nil), NewProfanityFilter([]string{" "}), unset} {
+		if got := filter.Clean("bad"); got != "bad" {
+			t.Errorf("Clean(%q) = %q", "bad", got)
+		}
+	}
+}
@@ -1,55 +1,291 @@
 package utils
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
-	"github.com/dgrijalva/jwt-go"
-	"os"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"sort"
 	"time"
 )
 
+const (
+	tokenTTL        = time.Hour * 24
+	tokenLeeway     = time.Minute
+	minRSAKeyBits   = 2048
+	defaultIssuer   = "aitu-royale"
+	defaultAudience = "aitu-royale-api"
+)
+
+// Claims are what a token says about its user. Version is the user's token
+// version when it was issued; bumping the version revokes the token.
 type Claims struct {
-	Id    string `json:"id"`
-	Email string `json:"email"`
-	jwt.StandardClaims
+	Id       string `json:"id"`
+	Email    string `json:"email"`
 	UserType string `json:"userType"`
+	Version  int32  `json:"ver"`
+	jwt.RegisteredClaims
+}
+
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// TokenKey is a PEM encoded key. Private keys can sign and verify; public
+// keys are kept only to verify tokens signed by a retired key.
+type TokenKey struct {
+	ID  string
+	PEM []byte
+}
+
+// TokenKeySet is what tokens are signed and verified with. Build one with
+// NewTokenKeySet and install it with InitTokens.
+type TokenKeySet struct {
+	issuer   string
+	audience string
+	active   *signingKey
+	keys     map[string]*signingKey
+}
+
+var tokenKeys *TokenKeySet
+
+func newKeySet(issuer, audience string) *TokenKeySet {
+	set := &TokenKeySet{
+		issuer:   issuer,
+		audience: audience,
+		keys:     make(map[string]*signingKey),
+	}
+	if set.issuer == "" {
+		set.issuer = defaultIssuer
+	}
+	if set.audience == "" {
+		set.audience = defaultAudience
+	}
+	return set
+}
+
+// NewTokenKeySet parses the keys and signs with the one named activeKeyID.
+func NewTokenKeySet(issuer, audience, activeKeyID string, keys []TokenKey) (*TokenKeySet, error) {
+	set := newKeySet(issuer, audience)
+	for _, keyConfig := range keys {
+		key, err := parseSigningKey(keyConfig)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := set.keys[key.id]; exists {
+			return nil, fmt.Errorf("duplicate JWT key id %q", key.id)
+		}
+		set.keys[key.id] = key
+	}
+	if len(set.keys) == 0 {
+		return nil, errors.New("no JWT keys configured")
+	}
+
+	active, ok := set.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active JWT key %q is not configured", activeKeyID)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("active JWT key %q has no private key", activeKeyID)
+	}
+	set.active = active
+	return set, nil
+}
+
+// NewEphemeralTokenKeySet signs with a freshly generated Ed25519 key, so
+// tokens only survive until restart and every instance signs with its own
+// key. It is meant for development and tests.
+func NewEphemeralTokenKeySet(issuer, audience string) (*TokenKeySet, error) {
+	key, err := generateEphemeralKey()
+	if err != nil {
+		return nil, err
+	}
+	set := newKeySet(issuer, audience)
+	set.keys[key.id] = key
+	set.active = key
+	return set, nil
+}
+
+// InitTokens installs the key set once at startup.
+func InitTokens(set *TokenKeySet) {
+	tokenKeys = set
 }
 
-func CreateToken(id string, email string, userType string) (tokenString string, err error) {
+func CreateToken(id string, email string, userType string, version int32) (tokenString string, err error) {
+	if tokenKeys == nil {
+		return "", errors.New("token keys are not initialized")
+	}
+
+	now := time.Now()
 	claims := &Claims{
 		Id:       id,
 		Email:    email,
 		UserType: userType,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
+		Version:  version,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenKeys.issuer,
+			Subject:   id,
+			Audience:  jwt.ClaimStrings{tokenKeys.audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	if signedToken, err := token.SignedString([]byte(os.Getenv("JWT_SECRET"))); err != nil {
-		return "", err
-	} else {
-		return signedToken, nil
-	}
+	token := jwt.NewWithClaims(tokenKeys.active.method, claims)
+	token.Header["kid"] = tokenKeys.active.id
+	return token.SignedString(tokenKeys.active.private)
 }
 
-func VerifyToken(token string) (string, string, string, error) {
+func VerifyToken(token string) (*Claims, error) {
 	if token == "" {
-		return "", "", "", errors.New("token is empty")
+		return nil, errors.New("token is empty")
 	}
+	if tokenKeys == nil {
+		return nil, errors.New("token keys are not initialized")
+	}
+
 	claims := &Claims{}
 	parsedToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := tokenKeys.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.public, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(tokenKeys.issuer),
+		jwt.WithAudience(tokenKeys.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(tokenLeeway),
+	)
 	if err != nil {
-		if err == jwt.ErrSignatureInvalid {
-			return "", "", "", errors.New("signature is invalid")
+		if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			return nil, errors.New("signature is invalid")
 		}
-		return "", "", "", errors.New("token is invalid")
+		return nil, fmt.Errorf("token is invalid: %v", err)
 	}
 	if !parsedToken.Valid {
-		return "", "", "", errors.New("parsed token is invalid")
+		return nil, errors.New("parsed token is invalid")
+	}
+	return claims, nil
+}
+
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS publishes every verification key, including retired ones, so tokens
+// signed before a rotation stay verifiable by other services.
+func JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	if tokenKeys == nil {
+		return set
+	}
+
+	ids := make([]string, 0, len(tokenKeys.keys))
+	for id := range tokenKeys.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		key := tokenKeys.keys[id]
+		jwk := JSONWebKey{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
 	}
-	if claims == nil {
-		return "", "", "", errors.New("token claims are nil")
+	return set
+}
+
+func parseSigningKey(keyConfig TokenKey) (*signingKey, error) {
+	block, _ := pem.Decode(keyConfig.PEM)
+	if block == nil {
+		return nil, fmt.Errorf("JWT key %q is not PEM encoded", keyConfig.ID)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("JWT key %q has unsupported PEM type %q", keyConfig.ID, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key %q: %v", keyConfig.ID, err)
+	}
+
+	key := &signingKey{id: keyConfig.ID}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("JWT key %q must be RSA or Ed25519", keyConfig.ID)
+	}
+
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("JWT key %q is shorter than %d bits", keyConfig.ID, minRSAKeyBits)
+	}
+	return key, nil
+}
+
+func generateEphemeralKey() (*signingKey, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id, err := GenerateChallengeToken()
+	if err != nil {
+		return nil, err
 	}
-	return claims.Id, claims.Email, claims.UserType, nil
+	return &signingKey{
+		id:      "ephemeral-" + id[:8],
+		method:  jwt.SigningMethodEdDSA,
+		private: private,
+		public:  public,
+	}, nil
 }